@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"stocktopus/internal/config"
+	"stocktopus/internal/store"
+)
+
+const commandUsage = `usage: stocktopus [flags] [command]
+
+With no command, stocktopus runs the server.
+
+Commands:
+  migrate [status]   show each schema migration's state
+  migrate dry-run    show what the next start would apply, with its SQL
+  migrate up         apply pending migrations and exit
+  backup [file]      write watchlists, sketches, paper trading, alerts and
+                     intelligence to file (default stdout; .gz compresses)
+  restore [-mode merge|replace] file
+                     load a backup; merge (default) adds what's missing,
+                     replace swaps the user data for the bundle's
+`
+
+// runCommand runs a maintenance command against the configured store
+// instead of starting the server, returning the exit code.
+func runCommand(cfg *config.Config, args []string) int {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(cfg.Store, args[1:])
+	case "backup":
+		return runBackup(cfg.Store, args[1:])
+	case "restore":
+		return runRestore(cfg.Store, args[1:])
+	case "help":
+		fmt.Print(commandUsage)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], commandUsage)
+	return 2
+}
+
+func runMigrate(sc config.StoreConfig, args []string) int {
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	switch action {
+	case "status", "dry-run":
+	case "up":
+		st, err := store.Open(sc.Driver, sc.Source())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate %s: %v\n", sc.Name(), err)
+			return 1
+		}
+		st.Close()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate action %q\n\n%s", action, commandUsage)
+		return 2
+	}
+
+	plan, err := store.MigrationPlan(sc.Driver, sc.Source())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s: %v\n", sc.Name(), err)
+		return 1
+	}
+	if action == "dry-run" {
+		return printMigrationDryRun(sc.Name(), plan)
+	}
+
+	fmt.Printf("%s (latest version %d)\n", sc.Name(), store.LatestSchemaVersion())
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED")
+	failed := false
+	for _, m := range plan {
+		applied := ""
+		if !m.AppliedAt.IsZero() {
+			applied = m.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", m.Version, m.Name, m.State, applied)
+		failed = failed || m.State == store.MigrationModified
+	}
+	tw.Flush()
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func printMigrationDryRun(name string, plan []store.MigrationStatus) int {
+	var adopt, pending []store.MigrationStatus
+	for _, m := range plan {
+		switch m.State {
+		case store.MigrationAdopt:
+			adopt = append(adopt, m)
+		case store.MigrationPending:
+			pending = append(pending, m)
+		case store.MigrationModified:
+			fmt.Fprintf(os.Stderr, "migration %d (%s) has changed since it was applied; the store will refuse to open\n", m.Version, m.Name)
+			return 1
+		}
+	}
+	if len(adopt) == 0 && len(pending) == 0 {
+		fmt.Printf("%s is up to date at version %d\n", name, store.LatestSchemaVersion())
+		return 0
+	}
+	for _, m := range adopt {
+		fmt.Printf("-- adopt %d %s (already present in this unversioned database)\n", m.Version, m.Name)
+	}
+	for _, m := range pending {
+		fmt.Printf("\n-- apply %d %s\n%s\n", m.Version, m.Name, strings.TrimSpace(dedent(m.SQL)))
+	}
+	return 0
+}
+
+// dedent strips the common leading tabs the migrations' SQL is indented by.
+func dedent(s string) string {
+	lines := strings.Split(s, "\n")
+	min := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, "\t"))
+		if min < 0 || n < min {
+			min = n
+		}
+	}
+	for i, l := range lines {
+		if len(l) >= min && min > 0 {
+			lines[i] = l[min:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func runBackup(sc config.StoreConfig, args []string) int {
+	if len(args) > 1 {
+		fmt.Fprint(os.Stderr, commandUsage)
+		return 2
+	}
+	st, err := store.Open(sc.Driver, sc.Source())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open store %s: %v\n", sc.Name(), err)
+		return 1
+	}
+	defer st.Close()
+	b, err := st.Backup()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backup: %v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	path := "-"
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "backup: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := store.WriteBundle(w, b, strings.HasSuffix(path, ".gz")); err != nil {
+		fmt.Fprintf(os.Stderr, "backup: %v\n", err)
+		return 1
+	}
+	if path != "-" {
+		fmt.Fprintf(os.Stderr, "wrote %s (schema version %d)\n", path, b.SchemaVersion)
+	}
+	return 0
+}
+
+func runRestore(sc config.StoreConfig, args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	modeFlag := fs.String("mode", "merge", "merge or replace")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	mode, err := store.ParseRestoreMode(*modeFlag)
+	if err != nil || fs.NArg() != 1 {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		fmt.Fprint(os.Stderr, commandUsage)
+		return 2
+	}
+
+	var r io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	b, err := store.ReadBundle(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+		return 1
+	}
+
+	st, err := store.Open(sc.Driver, sc.Source())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open store %s: %v\n", sc.Name(), err)
+		return 1
+	}
+	defer st.Close()
+	res, err := st.Restore(b, mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("restored %s backup from %s (schema version %d)\n", mode, b.CreatedAt.Local().Format("2006-01-02 15:04"), b.SchemaVersion)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tINSERTED\tUPDATED\tSKIPPED\tDELETED")
+	for _, name := range store.BundleTables() {
+		c := res.Tables[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", name, c.Inserted, c.Updated, c.Skipped, c.Deleted)
+	}
+	tw.Flush()
+	return 0
+}
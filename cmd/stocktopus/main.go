@@ -4,14 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"flag"
+	"fmt"
 	"log/slog"
-	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"stocktopus/internal/agent"
+	"stocktopus/internal/alerts"
+	"stocktopus/internal/budget"
+	"stocktopus/internal/calendars"
+	"stocktopus/internal/cassette"
+	"stocktopus/internal/config"
 	"stocktopus/internal/agent/trading"
 	"stocktopus/internal/boe"
 	"stocktopus/internal/dbnomics"
@@ -21,6 +30,8 @@ import (
 	"stocktopus/internal/news"
 	"stocktopus/internal/newspoller"
 	"stocktopus/internal/poller"
+	"stocktopus/internal/screening"
+	"stocktopus/internal/screens"
 	"stocktopus/internal/sectorpoller"
 	"stocktopus/internal/provider"
 	"stocktopus/internal/provider/alphavantage"
@@ -28,9 +39,47 @@ import (
 	"stocktopus/internal/provider/polygon"
 	"stocktopus/internal/server"
 	"stocktopus/internal/store"
+	"stocktopus/internal/ticks"
+	"stocktopus/internal/watchlists"
+	"stocktopus/internal/webhook"
 )
 
 func main() {
+	configPath := flag.String("config", "config.yaml", "path to the YAML config file")
+	printConfig := flag.Bool("print-config", false, "print the effective config (secrets redacted) and exit")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), commandUsage, "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// config.yaml is optional unless named explicitly; everything it
+	// leaves out comes from the built-in defaults and their env vars.
+	explicit := false
+	flag.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "config" })
+	load := config.LoadOptional
+	if explicit {
+		load = config.Load
+	}
+	cfg, err := load(*configPath)
+	if err == nil {
+		err = cfg.ValidateProviders(provider.ListProviders())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config:\n%v\n", err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(cfg, flag.Args()))
+	}
+
 	// Debug broadcaster captures all logs for the /debug console
 	debug := server.NewDebugBroadcaster()
 	debugWriter := &server.DebugLogWriter{Debug: debug}
@@ -41,21 +90,81 @@ func main() {
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
-	// Create provider
-	apiKey := os.Getenv("STOCK_API_KEY")
-	providerName := os.Getenv("STOCK_PROVIDER")
-	if providerName == "" {
-		providerName = "fmp"
+	// HTTP cassettes — cassette.mode record saves every outbound exchange
+	// (FMP, FRED, DBnomics, BoE, SEC, Ollama, Gemini) under cassette.dir with
+	// API keys redacted; replay serves them back offline.
+	cassetteMode, err := cassette.ParseMode(cfg.Cassette.Mode)
+	if err == nil {
+		err = cassette.Default.Configure(cassette.Config{Mode: cassetteMode, Dir: cfg.Cassette.Dir})
+		if err == nil && cassetteMode != cassette.Passthrough {
+			slog.Info("http cassette enabled", "mode", cassetteMode, "dir", cfg.Cassette.Dir)
+		}
+	}
+	if err != nil {
+		slog.Error("invalid cassette config", "error", err)
+		os.Exit(1)
+	}
+
+	// Store — opened before the quote provider so the router can classify
+	// symbols by asset class.
+	st, err := store.Open(cfg.Store.Driver, cfg.Store.Source())
+	if err != nil {
+		slog.Warn("failed to open intelligence store (continuing without agents)", "driver", cfg.Store.Driver, "error", err)
+	}
+	// Asset classes and venues are asked for on every quote of every tick
+	// (validation, routing, market-hours back-off), so the store lookup
+	// is cached.
+	securityType := securityTypes(st)
+	venueOf := func(symbol string) *calendars.Venue {
+		return calendars.ForSymbol(symbol, securityType(symbol))
+	}
+
+	// Shared FMP request budget — every FMP call (quote provider, news
+	// client, pollers, agents) waits on it. rateLimit.maxRequests is the
+	// plan's calls per window.
+	requestBudget := budget.New(budget.Config{
+		Limit:   cfg.RateLimit.MaxRequests,
+		Window:  cfg.RateLimit.Window,
+		Reserve: cfg.RateLimit.Reserve,
+	})
+
+	// Create provider. provider.name serves every asset class unless
+	// routes (e.g. {crypto: polygon, forex: alphavantage}) sends a class
+	// elsewhere. Each provider gets its own middleware stack.
+	providerName := cfg.Provider.Name
+
+	// Quotes are validated against the model.Quote rules; staleness is
+	// only judged while the symbol's market is open.
+	validation := provider.DefaultValidationConfig()
+	validation.MaxFutureSkew = cfg.Validation.MaxFutureSkew
+	validation.StaleAfter = cfg.Validation.StaleAfter
+	validation.MaxJump = cfg.Validation.MaxJump
+	validation.Logger = logger
+	validation.IsOpen = func(symbol string, t time.Time) bool {
+		return venueOf(symbol).IsOpen(t)
 	}
 
-	p, err := createProvider(providerName, apiKey)
+	p, err := buildProviderStack(cfg, providerName, requestBudget, validation, logger)
+	// consensus.provider cross-checks the default provider's quotes against
+	// a second source; disagreements beyond consensus.tolerance (a fraction)
+	// are flagged in the UI.
+	if consensusName := cfg.Consensus.Provider; err == nil && consensusName != "" {
+		var secondary provider.StockProvider
+		if secondary, err = buildProviderStack(cfg, consensusName, requestBudget, validation, logger); err == nil {
+			p = provider.NewProviderBuilder(p).WithConsensus(secondary, cfg.Consensus.Tolerance).Build()
+			slog.Info("quote consensus enabled", "provider", providerName, "secondary", consensusName)
+		}
+	}
+	if err == nil && len(cfg.Routes) > 0 {
+		p, err = buildRouter(cfg, p, securityType, requestBudget, validation, logger)
+	}
 	if err != nil {
 		slog.Error("failed to create provider", "provider", providerName, "error", err)
 		os.Exit(1)
 	}
 
 	// Health check
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(budget.WithCaller(context.Background(), "startup", budget.Interactive), 10*time.Second)
 	defer cancel()
 	if err := p.HealthCheck(ctx); err != nil {
 		slog.Warn("provider health check failed (continuing anyway)", "error", err)
@@ -67,21 +176,29 @@ func main() {
 	h := hub.New(logger)
 	go h.Run()
 
-	// Poller
-	interval := 15 * time.Second
-	poll := poller.New(p, h, interval, logger)
+	// Poller — config tickers are polled with or without a page open.
+	poll := poller.New(p, h, cfg.RefreshInterval(), logger)
+	poll.SetBudget(requestBudget)
+	poll.SetClosedInterval(cfg.Polling.ClosedMarket)
+	poll.SetPinned(cfg.Tickers)
+
+	// Tick recorder — every polled quote is kept as intraday history.
+	tickRetention := time.Duration(cfg.Ticks.RetentionDays) * 24 * time.Hour
+	tickRecorder, err := ticks.New(cfg.Ticks.Dir, tickRetention, logger)
+	if err != nil {
+		slog.Warn("tick recorder disabled", "dir", cfg.Ticks.Dir, "error", err)
+	} else {
+		poll.SetQuotesCallback(tickRecorder.Record)
+	}
 
 	// News client + news poller
-	newsClient := news.New(apiKey, "https://financialmodelingprep.com")
-	newsClient.SetGeminiKey(os.Getenv("GEMINI_API_KEY"))
+	newsClient := news.New(cfg.ProviderAPIKey("fmp"), "https://financialmodelingprep.com")
+	newsClient.SetGeminiKey(cfg.AI.Gemini.APIKey)
+	newsClient.SetLimiter(requestBudget)
 
-	newsPollInterval := 2 * time.Minute
-	if envInterval := os.Getenv("NEWS_POLL_INTERVAL"); envInterval != "" {
-		if d, err := time.ParseDuration(envInterval); err == nil {
-			newsPollInterval = d
-		}
-	}
-	np := newspoller.New(newsClient, h, newsPollInterval, logger)
+	np := newspoller.New(newsClient, h, cfg.Polling.News, logger)
+	np.SetBudget(requestBudget)
+	np.SetClosedInterval(cfg.Polling.ClosedMarket)
 
 	// Composite subscription handler (sector poller added after store creation below)
 	composite := hub.NewCompositeHandler()
@@ -92,40 +209,39 @@ func main() {
 	defer stop()
 
 	go poll.Run(appCtx)
+	if tickRecorder != nil {
+		go tickRecorder.Run(appCtx)
+	}
 	go np.Run(appCtx)
 
-	// Agent pipeline
-	dbPath := "stocktopus.db"
-	if envDB := os.Getenv("STOCKTOPUS_DB"); envDB != "" {
-		dbPath = envDB
+	// Market hours — resolve each polled symbol's venue from its stored
+	// asset class so crypto/forex keep ticking while exchanges are shut.
+	if st != nil {
+		poll.SetVenueResolver(venueOf)
 	}
-	st, err := store.New(dbPath)
-	if err != nil {
-		slog.Warn("failed to open intelligence store (continuing without agents)", "error", err)
+
+	// News alerts — watches are evaluated as the news poller discovers new
+	// articles. The categories enabled watches need stay pinned, so they
+	// keep polling with no news page open; the server re-syncs the pins
+	// whenever a watch changes.
+	webhooks := webhook.New(logger)
+	var alertEngine *alerts.Engine
+	if st != nil {
+		alertEngine = alerts.New(st, h, webhooks, logger)
+		np.SetNewItemsCallback(alertEngine.HandleNews)
+		alertEngine.SetPinner(np)
+		alertEngine.SyncPins()
 	}
 
 	var pipeline *agent.Pipeline
 	if st != nil {
-		geminiKey := os.Getenv("GEMINI_API_KEY")
-		ollamaHost := os.Getenv("OLLAMA_HOST")
-		ollamaModel := os.Getenv("OLLAMA_MODEL")
-		if ollamaModel == "" {
-			ollamaModel = "gemma4"
-		}
-		agentWorkers := 3
-		cacheTTL := 24 * time.Hour
-		if envTTL := os.Getenv("AGENT_CACHE_TTL"); envTTL != "" {
-			if d, err := time.ParseDuration(envTTL); err == nil {
-				cacheTTL = d
-			}
-		}
-
+		ollama := cfg.AI.Ollama
 		pipeline = agent.NewPipeline(agent.PipelineConfig{
-			GeminiAPIKey: geminiKey,
-			OllamaHost:   ollamaHost,
-			OllamaModel:  ollamaModel,
-			NumWorkers:   agentWorkers,
-			CacheTTL:     cacheTTL,
+			GeminiAPIKey: cfg.AI.Gemini.APIKey,
+			OllamaHost:   ollama.Host,
+			OllamaModel:  ollama.Model,
+			NumWorkers:   ollama.Workers,
+			CacheTTL:     ollama.CacheTTL,
 			PythonPath:   "python3",
 			AgentsDir:    "agents",
 		}, st, logger)
@@ -140,19 +256,12 @@ func main() {
 			h.Publish("agent:"+status.Symbol, data)
 		})
 
-		slog.Info("agent pipeline ready", "ollamaModel", ollamaModel, "cacheTTL", cacheTTL)
+		slog.Info("agent pipeline ready", "ollamaModel", ollama.Model, "cacheTTL", ollama.CacheTTL)
 	}
 
 	// Warm up Ollama NER model so first article request is fast
 	go func() {
-		nerModel := os.Getenv("OLLAMA_NER_MODEL")
-		if nerModel == "" {
-			nerModel = "gemma3"
-		}
-		ollamaHost := os.Getenv("OLLAMA_HOST")
-		if ollamaHost == "" {
-			ollamaHost = "http://localhost:11434"
-		}
+		nerModel := cfg.AI.Ollama.NERModel
 		slog.Info("warming up NER model", "model", nerModel)
 		body, _ := json.Marshal(map[string]interface{}{
 			"model":      nerModel,
@@ -161,7 +270,7 @@ func main() {
 			"keep_alive": "30m",
 			"options":    map[string]interface{}{"num_predict": 1},
 		})
-		resp, err := http.Post(ollamaHost+"/api/generate", "application/json", bytes.NewReader(body))
+		resp, err := cassette.Client(0).Post(cfg.AI.Ollama.Host+"/api/generate", "application/json", bytes.NewReader(body))
 		if err != nil {
 			slog.Warn("NER model warm-up failed", "error", err)
 		} else {
@@ -173,7 +282,7 @@ func main() {
 	// Populate SIC codes on first boot
 	if st != nil && st.SICCodeCount() == 0 {
 		slog.Info("populating SIC codes...")
-		sicCtx, sicCancel := context.WithTimeout(context.Background(), 15*time.Second)
+		sicCtx, sicCancel := context.WithTimeout(budget.WithCaller(context.Background(), "startup", budget.Background), 15*time.Second)
 		sicData, err := newsClient.GetSICList(sicCtx)
 		sicCancel()
 		if err != nil {
@@ -190,8 +299,10 @@ func main() {
 	}
 
 	// Sector poller (needs store)
+	var sp *sectorpoller.Poller
 	if st != nil {
-		sp := sectorpoller.New(newsClient, h, st, 5*time.Minute, logger)
+		sp = sectorpoller.New(newsClient, h, st, cfg.Polling.Sectors, logger)
+		sp.SetBudget(requestBudget)
 		composite.Register("sector:", sp)
 		go sp.Run(appCtx)
 	}
@@ -200,12 +311,14 @@ func main() {
 	// behind a unified econ.Fetcher so the server doesn't know about
 	// providers — it just hands the fetcher a catalog entry. Prefetcher
 	// uses the same fetcher to keep all curated series warm.
-	fredClient := fred.New(os.Getenv("FRED_API_KEY"))
+	fredClient := fred.New(cfg.FRED.APIKey)
 	dbnomicsClient := dbnomics.New()
 	boeClient := boe.New()
 	econFetcher := econ.NewFetcher(fredClient, dbnomicsClient, boeClient)
+	var prefetcher *econ.Prefetcher
 	if st != nil {
-		go econ.NewPrefetcher(econFetcher, st, logger, 30*time.Minute).Run(appCtx)
+		prefetcher = econ.NewPrefetcher(econFetcher, st, logger, cfg.Polling.Econ)
+		go prefetcher.Run(appCtx)
 	}
 
 	h.SetSubscriptionHandler(composite)
@@ -213,13 +326,9 @@ func main() {
 	// Trading analysis pipeline (multi-agent, button-triggered)
 	var tradingPipeline *trading.TradingPipeline
 	if st != nil {
-		ollamaHost := os.Getenv("OLLAMA_HOST")
-		analystModel := os.Getenv("OLLAMA_NER_MODEL") // gemma3 — same lightweight model
-		if analystModel == "" {
-			analystModel = "gemma3"
-		}
+		analystModel := cfg.AI.Ollama.NERModel // same lightweight model as NER
 		tradingPipeline = trading.NewTradingPipeline(trading.TradingPipelineConfig{
-			OllamaHost:  ollamaHost,
+			OllamaHost:  cfg.AI.Ollama.Host,
 			OllamaModel: analystModel,
 			AgentsDir:   "agents",
 		}, newsClient, st, logger)
@@ -237,11 +346,84 @@ func main() {
 		slog.Info("trading pipeline ready", "model", analystModel)
 	}
 
-	srv, err := server.New(server.Config{Port: 8080, Host: "localhost"}, h, debug, poll, newsClient, econFetcher, pipeline, tradingPipeline, st, logger)
+	srvCfg := server.Config{
+		Port: cfg.Server.Port,
+		Host: cfg.Server.Host,
+		AgentEnv: []string{
+			"OLLAMA_HOST=" + cfg.AI.Ollama.Host,
+			"OLLAMA_MODEL=" + cfg.AI.Ollama.Model,
+			"OLLAMA_NER_MODEL=" + cfg.AI.Ollama.NERModel,
+			"GEMINI_API_KEY=" + cfg.AI.Gemini.APIKey,
+		},
+	}
+	srv, err := server.New(srvCfg, h, debug, poll, newsClient, econFetcher, pipeline, tradingPipeline, st, logger)
 	if err != nil {
 		slog.Error("failed to create server", "error", err)
 		os.Exit(1)
 	}
+	srv.SetBudget(requestBudget)
+	srv.SetQuoteProvider(p)
+	if alertEngine != nil {
+		srv.SetAlertEngine(alertEngine)
+	}
+	var wlRefresher *watchlists.Refresher
+	if st != nil {
+		wlRefresher = watchlists.New(st, h, srv.ScreenSymbols, cfg.Polling.Watchlists, logger)
+		srv.SetWatchlistRefresher(wlRefresher)
+		go wlRefresher.Run(appCtx)
+	}
+	if st != nil {
+		scheduler := screens.New(st, h, webhooks, srv.RankScreen, logger)
+		srv.SetScreenScheduler(scheduler)
+		go scheduler.Run(appCtx)
+	}
+	var snapshotter *screening.Snapshotter
+	if st != nil {
+		engine := screening.NewEngine()
+		snapshotter = screening.NewSnapshotter(newsClient, st, engine, cfg.Screener.Universe, cfg.Polling.Universe, logger)
+		srv.SetScreening(engine, snapshotter)
+		go snapshotter.Run(appCtx)
+	}
+	if tickRecorder != nil {
+		srv.SetTickRecorder(tickRecorder)
+	}
+
+	// Hot reload — the quote interval, watch tickers and poll periods
+	// follow edits to the config file (or SIGHUP) without a restart.
+	watcher := config.NewWatcher(*configPath, cfg, 2*time.Second, logger)
+	watcher.OnChange(func(old, next *config.Config) {
+		poll.SetInterval(next.RefreshInterval())
+		poll.SetPinned(next.Tickers)
+		poll.SetClosedInterval(next.Polling.ClosedMarket)
+		np.SetInterval(next.Polling.News)
+		np.SetClosedInterval(next.Polling.ClosedMarket)
+		if sp != nil {
+			sp.SetInterval(next.Polling.Sectors)
+		}
+		if prefetcher != nil {
+			prefetcher.SetInterval(next.Polling.Econ)
+		}
+		if wlRefresher != nil {
+			wlRefresher.SetInterval(next.Polling.Watchlists)
+		}
+		if snapshotter != nil {
+			snapshotter.SetUniverse(next.Screener.Universe)
+			snapshotter.SetInterval(next.Polling.Universe)
+		}
+	})
+	go watcher.Run(appCtx)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-appCtx.Done():
+				return
+			case <-hup:
+				watcher.Reload()
+			}
+		}
+	}()
 
 	go func() {
 		if err := srv.Start(); err != nil {
@@ -264,25 +446,133 @@ func main() {
 	slog.Info("server stopped")
 }
 
-func createProvider(name, apiKey string) (provider.StockProvider, error) {
-	switch name {
+// buildProviderStack creates a provider and wraps it in its own
+// middleware stack. FMP providers also draw on the shared request budget.
+// Each stack validates its own quotes, with its own last-value cache.
+func buildProviderStack(cfg *config.Config, name string, b *budget.Scheduler, validation provider.ValidationConfig, logger *slog.Logger) (provider.StockProvider, error) {
+	base, err := createProvider(cfg.ProviderSettings(name))
+	if err != nil {
+		return nil, err
+	}
+	builder := provider.NewProviderBuilder(base)
+	if name == "fmp" {
+		builder = builder.WithRateLimit(b)
+	}
+	builder = builder.WithValidation(validation)
+	if r := cfg.Retry; r.Enabled {
+		builder = builder.WithRetry(provider.RetryConfig{
+			MaxAttempts:    r.MaxAttempts,
+			InitialBackoff: r.InitialBackoff,
+			MaxBackoff:     r.MaxBackoff,
+			Multiplier:     r.Multiplier,
+			Jitter:         r.Jitter,
+		})
+	}
+	if cb := cfg.CircuitBreaker; cb.Enabled {
+		builder = builder.WithCircuitBreaker(provider.CircuitBreakerConfig{
+			MaxFailures:  cb.MaxFailures,
+			ResetTimeout: cb.ResetTimeout,
+		})
+	}
+	return builder.WithObservability(logger).Build(), nil
+}
+
+// buildRouter builds a routing provider over fallback from cfg.Routes. A
+// provider named in several routes shares one stack.
+func buildRouter(cfg *config.Config, fallback provider.StockProvider, securityType func(string) string, b *budget.Scheduler, validation provider.ValidationConfig, logger *slog.Logger) (provider.StockProvider, error) {
+	stacks := map[string]provider.StockProvider{cfg.Provider.Name: fallback}
+	router := provider.NewRoutingProvider(fallback, assetClassifier(securityType))
+	classes := make([]string, 0, len(cfg.Routes))
+	for class := range cfg.Routes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		name := cfg.Routes[class]
+		stack := stacks[name]
+		if stack == nil {
+			var err error
+			if stack, err = buildProviderStack(cfg, name, b, validation, logger); err != nil {
+				return nil, fmt.Errorf("routes.%s: %w", class, err)
+			}
+			stacks[name] = stack
+		}
+		router.Route(class, stack)
+		slog.Info("provider route", "class", class, "provider", name)
+	}
+	return router, nil
+}
+
+// securityTypes returns a cached lookup of a symbol's stored asset class.
+// A symbol the store hasn't typed yet is looked up again after a minute
+// in case it's been profiled since.
+func securityTypes(st *store.Store) func(symbol string) string {
+	type entry struct {
+		typ   string
+		retry time.Time // zero = resolved for good
+	}
+	var cache sync.Map // symbol -> entry
+	return func(symbol string) string {
+		if st == nil {
+			return ""
+		}
+		if e, ok := cache.Load(symbol); ok {
+			if e := e.(entry); e.retry.IsZero() || time.Now().Before(e.retry) {
+				return e.typ
+			}
+		}
+		e := entry{typ: st.GetSecurityType(symbol)}
+		if e.typ == "" {
+			e.retry = time.Now().Add(time.Minute)
+		}
+		cache.Store(symbol, e)
+		return e.typ
+	}
+}
+
+// assetClassifier resolves a symbol's asset class from security_types,
+// falling back to its shape for symbols the store hasn't seen yet.
+func assetClassifier(securityType func(string) string) provider.AssetClassifier {
+	return func(symbol string) string {
+		if t := securityType(symbol); t != "" {
+			return t
+		}
+		switch calendars.ForSymbol(symbol, "") {
+		case calendars.Crypto:
+			return "crypto"
+		case calendars.FX:
+			return "forex"
+		}
+		if strings.HasPrefix(symbol, "^") {
+			return "index"
+		}
+		return ""
+	}
+}
+
+func createProvider(pc config.ProviderConfig) (provider.StockProvider, error) {
+	switch pc.Name {
 	case "fmp":
 		return provider.Create("fmp", financialmodelingprep.Config{
-			APIKey:  apiKey,
-			Timeout: 30 * time.Second,
+			APIKey:  pc.APIKey,
+			BaseURL: pc.BaseURL,
+			Timeout: pc.Timeout,
+			Options: pc.Options,
 		})
 	case "polygon":
 		return provider.Create("polygon", polygon.Config{
-			APIKey:  apiKey,
-			Timeout: 30 * time.Second,
-			Options: map[string]string{"adjusted": "true"},
+			APIKey:  pc.APIKey,
+			BaseURL: pc.BaseURL,
+			Timeout: pc.Timeout,
+			Options: pc.Options,
 		})
 	case "alphavantage":
 		return provider.Create("alphavantage", alphavantage.Config{
-			APIKey:  apiKey,
-			Timeout: 30 * time.Second,
+			APIKey:  pc.APIKey,
+			BaseURL: pc.BaseURL,
+			Timeout: pc.Timeout,
 		})
 	default:
-		return provider.Create(name, nil)
+		return provider.Create(pc.Name, nil)
 	}
 }
@@ -4,7 +4,11 @@ go 1.23
 
 require (
 	github.com/coder/websocket v1.8.14
+	github.com/fergusstrange/embedded-postgres v1.25.0
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.42
 	golang.org/x/net v0.25.0
 	gopkg.in/yaml.v3 v3.0.1
 )
+
+require github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8 // indirect
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"stocktopus/internal/budget"
 	"stocktopus/internal/store"
 )
 
@@ -65,7 +66,7 @@ type StatusCallback func(status PipelineStatus)
 type Pipeline struct {
 	orchestrator *Orchestrator
 	workers      *WorkerPool
-	store        *store.Store
+	store        store.IntelligenceRepository
 	logger       *slog.Logger
 	cacheTTL     time.Duration
 
@@ -87,7 +88,7 @@ type PipelineConfig struct {
 	AgentsDir    string
 }
 
-func NewPipeline(cfg PipelineConfig, st *store.Store, logger *slog.Logger) *Pipeline {
+func NewPipeline(cfg PipelineConfig, st store.IntelligenceRepository, logger *slog.Logger) *Pipeline {
 	orch := NewOrchestrator(cfg.GeminiAPIKey, logger)
 	workers := NewWorkerPool(cfg.OllamaHost, cfg.OllamaModel, cfg.NumWorkers, cfg.PythonPath, cfg.AgentsDir, logger)
 
@@ -170,7 +171,7 @@ func (p *Pipeline) analyzeAtDepth(symbol string, fmpData json.RawMessage, depth
 	p.active[symbol] = status
 	p.mu.Unlock()
 
-	bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	bgCtx, cancel := context.WithTimeout(budget.WithCaller(context.Background(), "agent", budget.Background), 5*time.Minute)
 	go func() {
 		defer cancel()
 		p.runPipeline(bgCtx, symbol, fmpData, status, depth)
@@ -221,6 +222,7 @@ func (p *Pipeline) runPipeline(ctx context.Context, symbol string, fmpData json.
 				t.Status = StatusComplete
 				t.Result = result
 			}
+			ObserveStage("intelligence", t.ID, string(t.Status), t.Duration)
 			p.emitStatus(*status)
 		}(&tasks[i])
 	}
@@ -240,7 +242,13 @@ func (p *Pipeline) runPipeline(ctx context.Context, symbol string, fmpData json.
 	}
 
 	gatheredJSON, _ := json.Marshal(gathered)
+	synthStart := time.Now()
 	analysis, err := p.orchestrator.Synthesize(ctx, symbol, gatheredJSON)
+	synthStatus := StatusComplete
+	if err != nil {
+		synthStatus = StatusFailed
+	}
+	ObserveStage("intelligence", "synthesis", string(synthStatus), time.Since(synthStart))
 	if err != nil {
 		p.logger.Error("synthesis failed", "symbol", symbol, "error", err)
 		status.Status = StatusFailed
@@ -13,12 +13,12 @@ import (
 
 // FineTuner manages training data export and Ollama model creation.
 type FineTuner struct {
-	store       *store.Store
+	store       store.IntelligenceRepository
 	ollamaModel string
 	outputDir   string
 }
 
-func NewFineTuner(st *store.Store, baseModel, outputDir string) *FineTuner {
+func NewFineTuner(st store.IntelligenceRepository, baseModel, outputDir string) *FineTuner {
 	if baseModel == "" {
 		baseModel = "gemma4"
 	}
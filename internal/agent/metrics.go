@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"stocktopus/internal/metrics"
+)
+
+var (
+	stageDuration = metrics.NewHistogram("stocktopus_agent_stage_duration_seconds",
+		"Agent pipeline stage duration by pipeline, stage and final status.",
+		metrics.SlowBuckets, "pipeline", "stage", "status")
+	ollamaDuration = metrics.NewHistogram("stocktopus_ollama_request_duration_seconds",
+		"Ollama /api/generate latency by model, calling component and outcome.",
+		metrics.SlowBuckets, "model", "caller", "outcome")
+)
+
+// ObserveStage records a finished pipeline stage. Both the intelligence
+// pipeline and the trading pipeline report here.
+func ObserveStage(pipeline, stage, status string, d time.Duration) {
+	stageDuration.Observe(d.Seconds(), pipeline, stage, status)
+}
+
+// ObserveOllama records one Ollama call started at start. outcome is "ok",
+// "error" (transport failure) or "http_<status>".
+func ObserveOllama(model, caller string, start time.Time, resp *http.Response, err error) {
+	outcome := "ok"
+	switch {
+	case err != nil:
+		outcome = "error"
+	case resp.StatusCode != http.StatusOK:
+		outcome = "http_" + strconv.Itoa(resp.StatusCode)
+	}
+	ollamaDuration.ObserveSince(start, model, caller, outcome)
+}
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"stocktopus/internal/cassette"
 	"stocktopus/internal/store"
 )
 
@@ -42,7 +43,7 @@ func NewOrchestrator(geminiAPIKey string, logger *slog.Logger) *Orchestrator {
 	return &Orchestrator{
 		apiKey: geminiAPIKey,
 		logger: logger.With("component", "orchestrator"),
-		client: &http.Client{Timeout: 180 * time.Second},
+		client: cassette.Client(180 * time.Second),
 	}
 }
 
@@ -15,6 +15,9 @@ import (
 	"sync"
 	"time"
 
+	"stocktopus/internal/agent"
+	"stocktopus/internal/cassette"
+	"stocktopus/internal/indicators"
 	"stocktopus/internal/model"
 	"stocktopus/internal/news"
 	"stocktopus/internal/store"
@@ -33,14 +36,14 @@ type AnalystRunner struct {
 	// security_types row to skip SEC-filing fetches for non-stock
 	// symbols (crypto / forex / index / etf), saving an FMP roundtrip
 	// per analyst on every run.
-	store *store.Store
+	store store.ReferenceRepository
 }
 
 // SetStore wires an optional store on an already-constructed runner.
 // Used by the pipeline so SEC-skip lookups don't require a constructor
 // change. nil is allowed; a nil store means the runner falls back to
 // full-stock behaviour for every symbol.
-func (ar *AnalystRunner) SetStore(s *store.Store) { ar.store = s }
+func (ar *AnalystRunner) SetStore(s store.ReferenceRepository) { ar.store = s }
 
 // skipSECForSymbol returns true when the analyst should bypass FMP's
 // SEC filing fetch — crypto / forex / index / etf don't file with
@@ -72,7 +75,7 @@ func NewAnalystRunner(fmp *news.Client, ollamaHost, ollamaModel, agentsDir strin
 		ollamaHost:  ollamaHost,
 		ollamaModel: ollamaModel,
 		agentsDir:   agentsDir,
-		client:      &http.Client{Timeout: 120 * time.Second},
+		client:      cassette.Client(120 * time.Second),
 		logger:      logger.With("component", "analysts"),
 	}
 }
@@ -676,7 +679,9 @@ func (ar *AnalystRunner) callOllamaForReport(ctx context.Context, analyst, symbo
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	resp, err := ar.client.Do(req)
+	agent.ObserveOllama(ar.ollamaModel, "analyst", start, resp, err)
 	if err != nil {
 		return AnalystReport{}, fmt.Errorf("ollama call: %w", err)
 	}
@@ -884,78 +889,23 @@ func calcVolumeAnalysis(bars []model.OHLCV) string {
 }
 
 func pctChange(data []float64, periods int) float64 {
-	if len(data) <= periods {
-		return 0
-	}
-	old := data[len(data)-1-periods]
-	if old == 0 {
-		return 0
-	}
-	return (data[len(data)-1] - old) / old * 100
+	return indicators.PctChange(data, periods)
 }
 
 func atr(bars []model.OHLCV, period int) float64 {
-	if len(bars) < period+1 {
-		return 0
-	}
-	sum := 0.0
-	for i := len(bars) - period; i < len(bars); i++ {
-		tr := bars[i].High - bars[i].Low
-		if i > 0 {
-			prevClose := bars[i-1].Close
-			if bars[i].High-prevClose > tr {
-				tr = bars[i].High - prevClose
-			}
-			if prevClose-bars[i].Low > tr {
-				tr = prevClose - bars[i].Low
-			}
-		}
-		sum += tr
-	}
-	return sum / float64(period)
+	return indicators.ATR(bars, period)
 }
 
 func sma(data []float64, period int) float64 {
-	if len(data) < period {
-		return 0
-	}
-	sum := 0.0
-	for _, v := range data[len(data)-period:] {
-		sum += v
-	}
-	return sum / float64(period)
+	return indicators.SMA(data, period)
 }
 
 func ema(data []float64, period int) float64 {
-	if len(data) < period {
-		return 0
-	}
-	k := 2.0 / float64(period+1)
-	e := sma(data[:period], period)
-	for _, v := range data[period:] {
-		e = v*k + e*(1-k)
-	}
-	return e
+	return indicators.EMA(data, period)
 }
 
 func rsi(data []float64, period int) float64 {
-	if len(data) < period+1 {
-		return 50
-	}
-	gains, losses := 0.0, 0.0
-	for i := len(data) - period; i < len(data); i++ {
-		change := data[i] - data[i-1]
-		if change > 0 {
-			gains += change
-		} else {
-			losses -= change
-		}
-	}
-	if losses == 0 {
-		return 100
-	}
-	rs := (gains / float64(period)) / (losses / float64(period))
-	return 100 - 100/(1+rs)
+	return indicators.RSI(data, period)
 }
 
 func macd(data []float64) (float64, float64) {
@@ -10,6 +10,9 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"stocktopus/internal/agent"
+	"stocktopus/internal/cassette"
 )
 
 // Debater runs the bull/bear research debate using Ollama.
@@ -34,7 +37,7 @@ func NewDebater(ollamaHost, ollamaModel string, rounds int, logger *slog.Logger)
 	return &Debater{
 		ollamaHost:  ollamaHost,
 		ollamaModel: ollamaModel,
-		client:      &http.Client{Timeout: 120 * time.Second},
+		client:      cassette.Client(120 * time.Second),
 		logger:      logger.With("component", "debate"),
 		rounds:      rounds,
 	}
@@ -221,7 +224,9 @@ Debate:
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	resp, err := d.client.Do(req)
+	agent.ObserveOllama(d.ollamaModel, "debate", start, resp, err)
 	if err != nil {
 		return nil, fmt.Errorf("ollama: %w", err)
 	}
@@ -274,7 +279,9 @@ func (d *Debater) callOllama(ctx context.Context, prompt string) (string, error)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	resp, err := d.client.Do(req)
+	agent.ObserveOllama(d.ollamaModel, "debate", start, resp, err)
 	if err != nil {
 		return "", fmt.Errorf("ollama: %w", err)
 	}
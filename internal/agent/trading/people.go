@@ -12,10 +12,19 @@ import (
 	"sync"
 	"time"
 
+	"stocktopus/internal/agent"
+	"stocktopus/internal/cassette"
 	"stocktopus/internal/news"
 	"stocktopus/internal/store"
 )
 
+// PeopleStore is where the extractor tracks processed filings and writes
+// the people it finds.
+type PeopleStore interface {
+	store.SECRepository
+	store.PeopleRepository
+}
+
 // PeopleExtractor pulls leadership data out of SEC filings:
 //   - Form 3/4/5 (via FMP insider-trading) → instant snapshot of current
 //     directors and executive officers — schema'd JSON, no LLM, populates
@@ -29,7 +38,7 @@ import (
 type PeopleExtractor struct {
 	ollamaHost  string
 	ollamaModel string
-	store       *store.Store
+	store       PeopleStore
 	sec         *SECFetcher
 	fmp         *news.Client
 	client      *http.Client
@@ -39,7 +48,7 @@ type PeopleExtractor struct {
 	inFlight map[string]bool // symbol → currently being processed
 }
 
-func NewPeopleExtractor(ollamaHost, ollamaModel string, fmp *news.Client, st *store.Store, logger *slog.Logger) *PeopleExtractor {
+func NewPeopleExtractor(ollamaHost, ollamaModel string, fmp *news.Client, st PeopleStore, logger *slog.Logger) *PeopleExtractor {
 	if ollamaHost == "" {
 		ollamaHost = "http://localhost:11434"
 	}
@@ -52,7 +61,7 @@ func NewPeopleExtractor(ollamaHost, ollamaModel string, fmp *news.Client, st *st
 		fmp:         fmp,
 		store:       st,
 		sec:         NewSECFetcher(logger),
-		client:      &http.Client{Timeout: 5 * time.Minute},
+		client:      cassette.Client(5 * time.Minute),
 		logger:      logger.With("component", "people-extractor"),
 		inFlight:    make(map[string]bool),
 	}
@@ -569,7 +578,9 @@ func (pe *PeopleExtractor) callOllama(ctx context.Context, prompt string, schema
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	resp, err := pe.client.Do(req)
+	agent.ObserveOllama(pe.ollamaModel, "people", start, resp, err)
 	if err != nil {
 		return err
 	}
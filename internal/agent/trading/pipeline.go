@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	"stocktopus/internal/agent"
+	"stocktopus/internal/budget"
 	"stocktopus/internal/news"
 	"stocktopus/internal/store"
 )
@@ -14,12 +16,20 @@ import (
 // StatusCallback is called when pipeline status changes.
 type StatusCallback func(result PipelineResult)
 
+// PipelineStore is what the pipeline, its analysts and the people
+// extractor persist to.
+type PipelineStore interface {
+	store.IntelligenceRepository
+	store.ReferenceRepository
+	PeopleStore
+}
+
 // TradingPipeline orchestrates the full multi-agent trading analysis.
 type TradingPipeline struct {
 	analysts *AnalystRunner
 	debater  *Debater
 	People   *PeopleExtractor
-	store    *store.Store
+	store    PipelineStore
 	logger   *slog.Logger
 
 	// Active analyses
@@ -38,7 +48,7 @@ type TradingPipelineConfig struct {
 	RiskDebateRounds int
 }
 
-func NewTradingPipeline(cfg TradingPipelineConfig, fmp *news.Client, st *store.Store, logger *slog.Logger) *TradingPipeline {
+func NewTradingPipeline(cfg TradingPipelineConfig, fmp *news.Client, st PipelineStore, logger *slog.Logger) *TradingPipeline {
 	analysts := NewAnalystRunner(fmp, cfg.OllamaHost, cfg.OllamaModel, cfg.AgentsDir, logger)
 	analysts.SetStore(st) // enables SEC-skip for crypto / forex / index / etf
 	debater := NewDebater(cfg.OllamaHost, cfg.OllamaModel, cfg.DebateRounds, logger)
@@ -133,7 +143,7 @@ func (tp *TradingPipeline) Analyze(ctx context.Context, symbol string) {
 
 	tp.emit(result)
 
-	bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	bgCtx, cancel := context.WithTimeout(budget.WithCaller(context.Background(), "trading-agent", budget.Background), 5*time.Minute)
 	go func() {
 		defer cancel()
 		tp.runPipeline(bgCtx, symbol, result)
@@ -206,7 +216,9 @@ func (tp *TradingPipeline) updateStage(result *PipelineResult, name string, stat
 			}
 			if status == StageComplete || status == StageFailed || status == StageSkipped {
 				if !result.Stages[i].StartedAt.IsZero() {
-					result.Stages[i].Duration = time.Since(result.Stages[i].StartedAt).Seconds()
+					d := time.Since(result.Stages[i].StartedAt)
+					result.Stages[i].Duration = d.Seconds()
+					agent.ObserveStage("trading", name, string(status), d)
 				}
 			}
 			break
@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"golang.org/x/net/html"
+
+	"stocktopus/internal/cassette"
 )
 
 // SECFetcher fetches SEC filing documents in compliance with EDGAR's fair-access
@@ -33,7 +35,7 @@ func NewSECFetcher(logger *slog.Logger) *SECFetcher {
 	}
 	return &SECFetcher{
 		userAgent: ua,
-		client:    &http.Client{Timeout: 45 * time.Second},
+		client:    cassette.Client(45 * time.Second),
 		logger:    logger.With("component", "sec-fetcher"),
 	}
 }
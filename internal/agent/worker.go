@@ -11,6 +11,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"time"
+
+	"stocktopus/internal/cassette"
 )
 
 // WorkerPool manages Ollama workers and Python agent scripts.
@@ -44,7 +46,7 @@ func NewWorkerPool(ollamaHost, ollamaModel string, numWorkers int, pythonPath, a
 		pythonPath:  pythonPath,
 		agentsDir:   agentsDir,
 		logger:      logger.With("component", "worker-pool"),
-		client:      &http.Client{Timeout: 120 * time.Second},
+		client:      cassette.Client(120 * time.Second),
 		sem:         make(chan struct{}, numWorkers),
 	}
 }
@@ -129,7 +131,9 @@ func (wp *WorkerPool) callOllama(ctx context.Context, prompt string) (json.RawMe
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	resp, err := wp.client.Do(req)
+	ObserveOllama(wp.ollamaModel, "worker", start, resp, err)
 	if err != nil {
 		return nil, fmt.Errorf("ollama call: %w", err)
 	}
@@ -0,0 +1,424 @@
+// Package alerts evaluates user news watches against articles as the news
+// poller discovers them. Matches are written to the per-user inbox in the
+// store, pushed to the hub on "alerts:{userID}", and POSTed to the watch's
+// webhook if one is configured.
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"stocktopus/internal/hub"
+	"stocktopus/internal/model"
+	"stocktopus/internal/news"
+	"stocktopus/internal/store"
+	"stocktopus/internal/webhook"
+)
+
+// Watch kinds, persisted verbatim in news_watches.kind.
+const (
+	KindSymbols   = "symbols"
+	KindKeywords  = "keywords"
+	KindQuery     = "query"
+	KindWatchlist = "watchlist"
+)
+
+// MsgNewsAlert is the hub/webhook message type for a matched story.
+const MsgNewsAlert = "news_alert"
+
+// recentTTL bounds the in-memory cross-category dedupe set. The store's
+// UNIQUE(user_id, story_key) is the durable guard; this just saves a
+// round-trip per user when stock + general feeds carry the same story in
+// the same poll cycle.
+const recentTTL = 6 * time.Hour
+
+// Store is what the engine reads watches from and files matches into.
+type Store interface {
+	store.AlertRepository
+	store.WatchlistRepository
+}
+
+type Engine struct {
+	store    Store
+	hub      *hub.Hub
+	webhooks *webhook.Sender
+	logger   *slog.Logger
+
+	recent map[string]time.Time // user + story key → delivered at
+	mu     sync.Mutex
+
+	pinner Pinner
+	pinMu  sync.Mutex
+}
+
+// Pinner keeps news categories polling with no subscriber. The news
+// poller implements it.
+type Pinner interface {
+	Pin(cat news.Category)
+	Unpin(cat news.Category)
+}
+
+// pinnable are the categories watches can be matched against.
+var pinnable = []news.Category{news.Stock, news.General, news.PressReleases, news.Crypto}
+
+func New(st Store, h *hub.Hub, wh *webhook.Sender, logger *slog.Logger) *Engine {
+	return &Engine{
+		store:    st,
+		hub:      h,
+		webhooks: wh,
+		logger:   logger.With("component", "alerts"),
+		recent:   make(map[string]time.Time),
+	}
+}
+
+// SetPinner registers the poller whose categories follow the enabled
+// watches. Call SyncPins after.
+func (e *Engine) SetPinner(p Pinner) {
+	e.pinMu.Lock()
+	e.pinner = p
+	e.pinMu.Unlock()
+}
+
+// SyncPins pins the categories the enabled watches need and unpins the
+// rest, so no feed is polled around the clock for nobody. The server
+// calls it whenever a watch is created, toggled or deleted.
+func (e *Engine) SyncPins() {
+	e.pinMu.Lock()
+	defer e.pinMu.Unlock()
+	if e.pinner == nil || e.store == nil {
+		return
+	}
+	watches, err := e.store.GetEnabledNewsWatches()
+	if err != nil {
+		e.logger.Error("load watches failed", "error", err)
+		return
+	}
+	need := Categories(watches)
+	for _, cat := range pinnable {
+		if need[cat] {
+			e.pinner.Pin(cat)
+		} else {
+			e.pinner.Unpin(cat)
+		}
+	}
+}
+
+// Categories returns the feeds a set of watches can match on. Symbol and
+// watchlist watches match on an item's symbol tag, which the general
+// feed doesn't carry; keyword and query watches can match anywhere.
+func Categories(watches []store.NewsWatch) map[news.Category]bool {
+	need := map[news.Category]bool{}
+	for _, w := range watches {
+		if !w.Enabled {
+			continue
+		}
+		switch w.Kind {
+		case KindSymbols, KindWatchlist:
+			need[news.Stock], need[news.PressReleases], need[news.Crypto] = true, true, true
+		default:
+			for _, cat := range pinnable {
+				need[cat] = true
+			}
+		}
+	}
+	return need
+}
+
+// Topic is the hub topic a user's alert stream is published on.
+func Topic(userID int64) string {
+	return "alerts:" + strconv.FormatInt(userID, 10)
+}
+
+// Validate checks that a watch is well-formed before it's persisted, so
+// a typo'd query fails at creation rather than silently never matching.
+func Validate(w store.NewsWatch) error {
+	switch w.Kind {
+	case KindSymbols, KindKeywords:
+		if len(splitList(w.Expr)) == 0 {
+			return fmt.Errorf("%s watch needs at least one entry", w.Kind)
+		}
+	case KindQuery:
+		if _, err := ParseQuery(w.Expr); err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+	case KindWatchlist:
+		if w.WatchlistID == nil || *w.WatchlistID <= 0 {
+			return fmt.Errorf("watchlist watch needs watchlistId")
+		}
+	default:
+		return fmt.Errorf("unknown watch kind %q", w.Kind)
+	}
+	if w.WebhookURL != "" {
+		u, err := url.Parse(w.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhookUrl must be an absolute http(s) URL")
+		}
+	}
+	return nil
+}
+
+// HandleNews is the newspoller's new-items callback. Items have already
+// been deduped per category by the poller; this layer dedupes across
+// categories per user. Every copy of a story is matched — the general
+// feed's copy usually carries no symbol, so a symbol watch only fires on
+// the stock feed's copy — and a (user, story) pair is only marked once
+// it has been delivered.
+func (e *Engine) HandleNews(cat news.Category, items []model.NewsItem) {
+	if e.store == nil || len(items) == 0 {
+		return
+	}
+	watches, err := e.store.GetEnabledNewsWatches()
+	if err != nil {
+		e.logger.Error("load watches failed", "error", err)
+		return
+	}
+	if len(watches) == 0 {
+		return
+	}
+	matchers := e.compile(watches)
+
+	e.pruneRecent()
+	for _, item := range items {
+		key := storyKey(item)
+		if key == "" {
+			continue
+		}
+		doc := newDocument(item)
+
+		// Group matching watches by user — one inbox row per user per story.
+		byUser := map[int64][]compiled{}
+		for _, m := range matchers {
+			if m.match(doc) {
+				byUser[m.watch.UserID] = append(byUser[m.watch.UserID], m)
+			}
+		}
+		for userID, hits := range byUser {
+			userKey := strconv.FormatInt(userID, 10) + "|" + key
+			if e.deliveredRecently(userKey) {
+				continue
+			}
+			if e.deliver(cat, item, key, userID, hits) {
+				e.markDelivered(userKey)
+			}
+		}
+	}
+}
+
+// deliver files the story in the user's inbox and, when it's new there,
+// pushes it to the hub and the watches' webhooks. It reports whether the
+// story is now in the inbox, whether or not this call put it there.
+func (e *Engine) deliver(cat news.Category, item model.NewsItem, key string, userID int64, hits []compiled) bool {
+	ids := make([]int64, len(hits))
+	for i, h := range hits {
+		ids[i] = h.watch.ID
+	}
+	entry := store.InboxItem{
+		UserID:      userID,
+		StoryKey:    key,
+		WatchIDs:    ids,
+		Category:    string(cat),
+		Title:       item.Title,
+		URL:         item.URL,
+		Source:      item.Source,
+		Symbol:      item.Symbol,
+		PublishedAt: item.Date,
+		MatchedAt:   time.Now().UTC(),
+	}
+	inserted, err := e.store.PutInboxItem(entry)
+	if err != nil {
+		e.logger.Error("inbox write failed", "user", userID, "url", item.URL, "error", err)
+		return false
+	}
+	if !inserted {
+		return true
+	}
+	e.logger.Info("news alert", "user", userID, "watches", ids, "title", item.Title)
+
+	payload, _ := json.Marshal(entry)
+	msg, err := json.Marshal(hub.OutboundMessage{
+		Type:    MsgNewsAlert,
+		Topic:   Topic(userID),
+		Payload: payload,
+	})
+	if err == nil && e.hub != nil {
+		e.hub.Publish(Topic(userID), msg)
+	}
+
+	for _, h := range hits {
+		e.webhooks.Send(h.watch.WebhookURL, MsgNewsAlert, map[string]any{
+			"watch": h.watch,
+			"item":  entry,
+		})
+	}
+	return true
+}
+
+func (e *Engine) deliveredRecently(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.recent[key]
+	return ok
+}
+
+func (e *Engine) markDelivered(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recent[key] = time.Now()
+}
+
+func (e *Engine) pruneRecent() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cutoff := time.Now().Add(-recentTTL)
+	for k, t := range e.recent {
+		if t.Before(cutoff) {
+			delete(e.recent, k)
+		}
+	}
+}
+
+// ── matching ──
+
+type compiled struct {
+	watch store.NewsWatch
+	match func(d *document) bool
+}
+
+// compile turns persisted watches into matchers. Watchlist watches
+// resolve to the list's holdings at compile time, so adding a symbol to a
+// watchlist takes effect on the next news batch.
+func (e *Engine) compile(watches []store.NewsWatch) []compiled {
+	var holdings map[int64][]string
+	out := make([]compiled, 0, len(watches))
+	for _, w := range watches {
+		var fn func(d *document) bool
+		switch w.Kind {
+		case KindSymbols:
+			fn = symbolMatcher(splitList(w.Expr))
+		case KindKeywords:
+			fn = keywordMatcher(splitList(w.Expr))
+		case KindQuery:
+			q, err := ParseQuery(w.Expr)
+			if err != nil {
+				e.logger.Warn("skipping invalid watch query", "watch", w.ID, "error", err)
+				continue
+			}
+			fn = q.Match
+		case KindWatchlist:
+			if w.WatchlistID == nil {
+				continue
+			}
+			if holdings == nil {
+				holdings = e.loadHoldings()
+			}
+			fn = symbolMatcher(holdings[*w.WatchlistID])
+		default:
+			continue
+		}
+		out = append(out, compiled{watch: w, match: fn})
+	}
+	return out
+}
+
+func (e *Engine) loadHoldings() map[int64][]string {
+	out := map[int64][]string{}
+	lists, err := e.store.GetWatchlists()
+	if err != nil {
+		e.logger.Warn("load watchlists failed", "error", err)
+		return out
+	}
+	for _, l := range lists {
+		out[l.ID] = l.Symbols
+	}
+	return out
+}
+
+func symbolMatcher(symbols []string) func(d *document) bool {
+	want := make(map[string]bool, len(symbols))
+	for _, s := range symbols {
+		want[strings.ToUpper(s)] = true
+	}
+	return func(d *document) bool {
+		for s := range d.symbols {
+			if want[s] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func keywordMatcher(keywords []string) func(d *document) bool {
+	lowered := make([]string, len(keywords))
+	for i, k := range keywords {
+		lowered[i] = strings.ToLower(k)
+	}
+	return func(d *document) bool {
+		for _, k := range lowered {
+			if containsWord(d.text, k) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// document is the pre-lowered view of a news item every matcher reads.
+type document struct {
+	text    string
+	symbols map[string]bool
+}
+
+func newDocument(item model.NewsItem) *document {
+	return &document{
+		text:    strings.ToLower(item.Title + "\n" + item.Text),
+		symbols: itemSymbols(item.Symbol),
+	}
+}
+
+// itemSymbols parses the Symbol field, which is a bare ticker on the
+// standard feeds and a comma list of "EXCHANGE:TICKER" on fmp-articles.
+func itemSymbols(raw string) map[string]bool {
+	out := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if i := strings.LastIndex(part, ":"); i >= 0 {
+			part = part[i+1:]
+		}
+		if part != "" {
+			out[strings.ToUpper(part)] = true
+		}
+	}
+	return out
+}
+
+func splitList(expr string) []string {
+	var out []string
+	for _, part := range strings.Split(expr, ",") {
+		if p := strings.TrimSpace(part); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// storyKey normalizes the article URL (scheme, host case, query string,
+// fragment and trailing slash dropped) so syndicated copies of a story
+// collapse. Falls back to the lower-cased title when there's no URL.
+func storyKey(item model.NewsItem) string {
+	if item.URL == "" {
+		return strings.ToLower(strings.TrimSpace(item.Title))
+	}
+	u, err := url.Parse(item.URL)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(item.URL)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return host + strings.TrimSuffix(u.Path, "/")
+}
@@ -0,0 +1,232 @@
+package alerts
+
+import (
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stocktopus/internal/model"
+	"stocktopus/internal/news"
+	"stocktopus/internal/store"
+)
+
+func TestParseQuery(t *testing.T) {
+	item := model.NewsItem{
+		Title:  "Apple raises guidance after record iPhone quarter",
+		Text:   "Analysts said the AI push is working.",
+		Symbol: "AAPL",
+	}
+	doc := newDocument(item)
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"guidance", true},
+		{"Guidance", true},
+		{"guid", false}, // word boundary
+		{"ai", true},
+		{`"record iphone quarter"`, true},
+		{`"record android quarter"`, false},
+		{"$AAPL", true},
+		{"symbol:msft", false},
+		{"$AAPL AND guidance", true},
+		{"$AAPL guidance", true}, // implicit AND
+		{"$MSFT OR guidance", true},
+		{"$AAPL AND NOT guidance", false},
+		{"$AAPL -rumor", true},
+		{"($MSFT OR $AAPL) AND (cut OR raises)", true},
+		{"($MSFT OR $GOOG) AND raises", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := ParseQuery(tc.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q): %v", tc.query, err)
+			}
+			if got := q.Match(doc); got != tc.want {
+				t.Errorf("Match(%q) = %v, want %v", tc.query, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, q := range []string{"", "   ", "(apple", "apple)", "AND apple", "apple OR", `"unterminated`, `""`} {
+		if _, err := ParseQuery(q); err == nil {
+			t.Errorf("ParseQuery(%q): want error, got nil", q)
+		}
+	}
+}
+
+func TestItemSymbols(t *testing.T) {
+	got := itemSymbols("NASDAQ:AAPL, NYSE:brk.b,MSFT")
+	for _, want := range []string{"AAPL", "BRK.B", "MSFT"} {
+		if !got[want] {
+			t.Errorf("itemSymbols missing %s: %v", want, got)
+		}
+	}
+}
+
+func TestStoryKeyCollapsesSyndicatedURLs(t *testing.T) {
+	a := storyKey(model.NewsItem{URL: "https://www.Reuters.com/markets/apple-guidance/?utm_source=fmp"})
+	b := storyKey(model.NewsItem{URL: "http://reuters.com/markets/apple-guidance#top"})
+	if a != b {
+		t.Errorf("storyKey mismatch: %q vs %q", a, b)
+	}
+}
+
+// TestHandleNewsDedupesAcrossCategories runs the engine against a real
+// store: the same story arriving on the stock and general feeds must land
+// in the inbox once, and a restarted engine must not re-deliver it.
+func TestHandleNewsDedupesAcrossCategories(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "alerts.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer st.Close()
+
+	if _, err := st.CreateNewsWatch(store.NewsWatch{Kind: KindSymbols, Expr: "AAPL, MSFT"}); err != nil {
+		t.Fatalf("create watch: %v", err)
+	}
+	if _, err := st.CreateNewsWatch(store.NewsWatch{Kind: KindKeywords, Expr: "antitrust"}); err != nil {
+		t.Fatalf("create watch: %v", err)
+	}
+
+	story := model.NewsItem{
+		Title:  "Apple faces antitrust probe",
+		URL:    "https://example.com/apple-probe",
+		Symbol: "AAPL",
+		Date:   time.Now().UTC(),
+	}
+	other := model.NewsItem{Title: "Oil rallies", URL: "https://example.com/oil", Symbol: "XOM"}
+
+	e := New(st, nil, nil, slog.Default())
+	e.HandleNews(news.Stock, []model.NewsItem{story, other})
+	e.HandleNews(news.General, []model.NewsItem{story})
+
+	// Fresh engine (empty in-memory set) — the store's unique key must hold.
+	New(st, nil, nil, slog.Default()).HandleNews(news.General, []model.NewsItem{story})
+
+	inbox, err := st.GetInbox(0, false, 10)
+	if err != nil {
+		t.Fatalf("get inbox: %v", err)
+	}
+	if len(inbox) != 1 {
+		t.Fatalf("want 1 inbox item, got %d: %+v", len(inbox), inbox)
+	}
+	if got := inbox[0]; got.Category != "stock" || len(got.WatchIDs) != 2 {
+		t.Errorf("inbox item: category=%q watchIds=%v, want stock with both watches", got.Category, got.WatchIDs)
+	}
+}
+
+// TestHandleNewsMatchesLaterCopies: the general feed usually carries a
+// story before the stock feed tags it with a symbol. The untagged copy
+// must not use up the story, or symbol and watchlist watches never fire.
+func TestHandleNewsMatchesLaterCopies(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "alerts.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer st.Close()
+
+	if _, err := st.CreateNewsWatch(store.NewsWatch{Kind: KindSymbols, Expr: "AAPL"}); err != nil {
+		t.Fatalf("create watch: %v", err)
+	}
+
+	general := model.NewsItem{Title: "Apple unveils new chip", URL: "https://example.com/apple-chip"}
+	stock := general
+	stock.Symbol = "AAPL"
+
+	e := New(st, nil, nil, slog.Default())
+	e.HandleNews(news.General, []model.NewsItem{general})
+	e.HandleNews(news.Stock, []model.NewsItem{stock})
+	e.HandleNews(news.Stock, []model.NewsItem{stock})
+
+	inbox, err := st.GetInbox(0, false, 10)
+	if err != nil {
+		t.Fatalf("get inbox: %v", err)
+	}
+	if len(inbox) != 1 || inbox[0].Category != "stock" || inbox[0].Symbol != "AAPL" {
+		t.Fatalf("want the stock copy in the inbox once, got %+v", inbox)
+	}
+}
+
+type fakePinner map[news.Category]bool
+
+func (f fakePinner) Pin(cat news.Category)   { f[cat] = true }
+func (f fakePinner) Unpin(cat news.Category) { delete(f, cat) }
+
+// TestSyncPins: categories stay pinned only while an enabled watch can
+// match on them.
+func TestSyncPins(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "alerts.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer st.Close()
+
+	pins := fakePinner{}
+	e := New(st, nil, nil, slog.Default())
+	e.SetPinner(pins)
+	e.SyncPins()
+	if len(pins) != 0 {
+		t.Fatalf("no watches: want nothing pinned, got %v", pins)
+	}
+
+	symID, err := st.CreateNewsWatch(store.NewsWatch{Kind: KindSymbols, Expr: "AAPL"})
+	if err != nil {
+		t.Fatalf("create watch: %v", err)
+	}
+	e.SyncPins()
+	if !pins[news.Stock] || !pins[news.PressReleases] || pins[news.General] {
+		t.Errorf("symbol watch: pinned %v, want stock and press releases but not general", pins)
+	}
+
+	kwID, err := st.CreateNewsWatch(store.NewsWatch{Kind: KindKeywords, Expr: "antitrust"})
+	if err != nil {
+		t.Fatalf("create watch: %v", err)
+	}
+	e.SyncPins()
+	if !pins[news.General] {
+		t.Errorf("keyword watch: pinned %v, want general", pins)
+	}
+
+	if err := st.SetNewsWatchEnabled(0, kwID, false); err != nil {
+		t.Fatalf("disable watch: %v", err)
+	}
+	if err := st.DeleteNewsWatch(0, symID); err != nil {
+		t.Fatalf("delete watch: %v", err)
+	}
+	e.SyncPins()
+	if len(pins) != 0 {
+		t.Errorf("no enabled watches: want nothing pinned, got %v", pins)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	wl := int64(1)
+	tests := []struct {
+		name  string
+		watch store.NewsWatch
+		ok    bool
+	}{
+		{"symbols", store.NewsWatch{Kind: KindSymbols, Expr: "AAPL"}, true},
+		{"empty symbols", store.NewsWatch{Kind: KindSymbols, Expr: " , "}, false},
+		{"query", store.NewsWatch{Kind: KindQuery, Expr: "$AAPL AND (earnings OR guidance)"}, true},
+		{"bad query", store.NewsWatch{Kind: KindQuery, Expr: "($AAPL"}, false},
+		{"watchlist", store.NewsWatch{Kind: KindWatchlist, WatchlistID: &wl}, true},
+		{"watchlist without id", store.NewsWatch{Kind: KindWatchlist}, false},
+		{"unknown kind", store.NewsWatch{Kind: "regex", Expr: "x"}, false},
+		{"bad webhook", store.NewsWatch{Kind: KindSymbols, Expr: "AAPL", WebhookURL: "ftp://x"}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.watch)
+			if (err == nil) != tc.ok {
+				t.Errorf("Validate() error = %v, want ok=%v", err, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package alerts
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Query is a compiled boolean news query. Grammar (case-insensitive
+// operators, implicit AND between adjacent terms):
+//
+//	expr   := or
+//	or     := and ( "OR" and )*
+//	and    := unary ( ["AND"] unary )*
+//	unary  := "NOT" unary | "-" unary | "(" expr ")" | term
+//	term   := word | "quoted phrase" | $TICKER | symbol:TICKER
+//
+// Words and phrases match on word boundaries in title + text; $TICKER and
+// symbol:TICKER match the item's tagged symbols only.
+type Query struct {
+	root node
+	src  string
+}
+
+// ParseQuery compiles src. Errors carry the byte offset so the UI can
+// point at the offending token.
+func ParseQuery(src string) (*Query, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	p := &parser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		t := p.toks[p.pos]
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.off)
+	}
+	return &Query{root: root, src: src}, nil
+}
+
+// Match reports whether the document satisfies the query.
+func (q *Query) Match(d *document) bool { return q.root.eval(d) }
+
+func (q *Query) String() string { return q.src }
+
+// ── AST ──
+
+type node interface{ eval(d *document) bool }
+
+type andNode struct{ l, r node }
+type orNode struct{ l, r node }
+type notNode struct{ n node }
+type textNode struct{ needle string }
+type symbolNode struct{ symbol string }
+
+func (n andNode) eval(d *document) bool    { return n.l.eval(d) && n.r.eval(d) }
+func (n orNode) eval(d *document) bool     { return n.l.eval(d) || n.r.eval(d) }
+func (n notNode) eval(d *document) bool    { return !n.n.eval(d) }
+func (n textNode) eval(d *document) bool   { return containsWord(d.text, n.needle) }
+func (n symbolNode) eval(d *document) bool { return d.symbols[n.symbol] }
+
+// ── lexer ──
+
+type tokKind int
+
+const (
+	tokWord tokKind = iota
+	tokPhrase
+	tokLParen
+	tokRParen
+	tokMinus
+)
+
+type token struct {
+	kind tokKind
+	text string
+	off  int
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == '-' && (i+1 < len(src) && src[i+1] != ' '):
+			toks = append(toks, token{tokMinus, "-", i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(src[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated phrase at offset %d", i)
+			}
+			toks = append(toks, token{tokPhrase, src[i+1 : i+1+end], i})
+			i += end + 2
+		default:
+			start := i
+			for i < len(src) && !strings.ContainsRune(" \t\n()\"", rune(src[i])) {
+				i++
+			}
+			toks = append(toks, token{tokWord, src[start:i], start})
+		}
+	}
+	return toks, nil
+}
+
+// ── parser ──
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() *token {
+	if p.pos >= len(p.toks) {
+		return nil
+	}
+	return &p.toks[p.pos]
+}
+
+func isOp(t *token, op string) bool {
+	return t != nil && t.kind == tokWord && strings.EqualFold(t.text, op)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for isOp(p.peek(), "OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t == nil || t.kind == tokRParen || isOp(t, "OR") {
+			return left, nil
+		}
+		if isOp(t, "AND") {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	switch {
+	case isOp(t, "NOT") || t.kind == tokMinus:
+		p.pos++
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	case t.kind == tokLParen:
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.peek(); r == nil || r.kind != tokRParen {
+			return nil, fmt.Errorf("missing ')' for '(' at offset %d", t.off)
+		}
+		p.pos++
+		return n, nil
+	case t.kind == tokRParen:
+		return nil, fmt.Errorf("unexpected ')' at offset %d", t.off)
+	case isOp(t, "AND") || isOp(t, "OR"):
+		return nil, fmt.Errorf("operator %s at offset %d needs a left operand", strings.ToUpper(t.text), t.off)
+	}
+	p.pos++
+	if t.kind == tokPhrase {
+		if strings.TrimSpace(t.text) == "" {
+			return nil, fmt.Errorf("empty phrase at offset %d", t.off)
+		}
+		return textNode{strings.ToLower(t.text)}, nil
+	}
+	if sym, ok := symbolTerm(t.text); ok {
+		return symbolNode{sym}, nil
+	}
+	return textNode{strings.ToLower(t.text)}, nil
+}
+
+func symbolTerm(word string) (string, bool) {
+	switch {
+	case strings.HasPrefix(word, "$") && len(word) > 1:
+		return strings.ToUpper(word[1:]), true
+	case len(word) > 7 && strings.EqualFold(word[:7], "symbol:"):
+		return strings.ToUpper(word[7:]), true
+	}
+	return "", false
+}
+
+// containsWord reports whether needle occurs in haystack bounded by
+// non-alphanumerics on both sides, so "ai" doesn't match "said". Both
+// sides are expected to be lower-cased already.
+func containsWord(haystack, needle string) bool {
+	if needle == "" {
+		return false
+	}
+	from := 0
+	for {
+		i := strings.Index(haystack[from:], needle)
+		if i < 0 {
+			return false
+		}
+		start := from + i
+		end := start + len(needle)
+		if boundary(haystack, start-1) && boundary(haystack, end) {
+			return true
+		}
+		from = start + 1
+	}
+}
+
+func boundary(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return true
+	}
+	r := rune(s[i])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
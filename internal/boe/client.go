@@ -21,6 +21,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"stocktopus/internal/cassette"
 )
 
 const (
@@ -48,7 +50,7 @@ type Client struct {
 }
 
 func New() *Client {
-	return &Client{http: &http.Client{Timeout: 30 * time.Second}}
+	return &Client{http: cassette.Client(30 * time.Second)}
 }
 
 // GetSeries fetches the full observation history for an IADB series code.
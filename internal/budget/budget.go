@@ -0,0 +1,303 @@
+// Package budget is the shared request budget for the FMP plan. Every
+// outbound FMP call — quote provider, news client, pollers, agent runners
+// — waits on one Scheduler, so the app as a whole stays under the plan's
+// per-minute limit instead of each component guessing on its own.
+//
+// Callers tag their context with a name and priority (WithCaller).
+// Interactive requests (a page waiting on a response) may use the whole
+// window; background work is capped below it, leaving a reserve, and
+// yields while any interactive request is waiting. Pollers stretch their
+// intervals via Scale as the window fills up.
+package budget
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"stocktopus/internal/metrics"
+)
+
+var (
+	waitSeconds = metrics.NewHistogram("stocktopus_budget_wait_seconds",
+		"Time FMP calls spent waiting on the shared request budget, by caller.",
+		metrics.DefaultBuckets, "caller", "priority")
+	windowUsed = metrics.NewGauge("stocktopus_budget_window_used",
+		"FMP calls made within the current budget window.")
+)
+
+// Priority orders competing requests.
+type Priority int
+
+const (
+	Background Priority = iota
+	Interactive
+)
+
+func (p Priority) String() string {
+	if p == Interactive {
+		return "interactive"
+	}
+	return "background"
+}
+
+// Config describes the plan limit.
+type Config struct {
+	Limit   int           // requests per Window
+	Window  time.Duration // default 1 minute
+	Reserve float64       // fraction of Limit held back for interactive calls; default 0.2
+}
+
+// DefaultLimit matches FMP's Starter plan (300 calls/minute).
+const DefaultLimit = 300
+
+type stamp struct {
+	at     time.Time
+	caller string
+}
+
+type callerStats struct {
+	priority  Priority
+	total     int64
+	throttled int64
+	waited    time.Duration
+	lastCall  time.Time
+}
+
+// Scheduler is a sliding-window limiter shared by every FMP caller. A nil
+// *Scheduler is valid and never blocks.
+type Scheduler struct {
+	cfg Config
+
+	mu                 sync.Mutex
+	sent               []stamp // calls inside the current window, oldest first
+	interactiveWaiting int
+	callers            map[string]*callerStats
+}
+
+func New(cfg Config) *Scheduler {
+	if cfg.Limit <= 0 {
+		cfg.Limit = DefaultLimit
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Reserve <= 0 || cfg.Reserve >= 1 {
+		cfg.Reserve = 0.2
+	}
+	return &Scheduler{cfg: cfg, callers: make(map[string]*callerStats)}
+}
+
+type ctxKey struct{}
+
+type tag struct {
+	caller   string
+	priority Priority
+}
+
+// WithCaller tags ctx so calls made under it are attributed to caller and
+// scheduled at the given priority.
+func WithCaller(ctx context.Context, caller string, p Priority) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tag{caller, p})
+}
+
+// FromContext returns the caller tag on ctx. Untagged work is treated as
+// background so an unlabelled goroutine can never starve a page load.
+func FromContext(ctx context.Context) (string, Priority) {
+	if t, ok := ctx.Value(ctxKey{}).(tag); ok {
+		return t.caller, t.priority
+	}
+	return "untagged", Background
+}
+
+// Wait blocks until the caller on ctx may send one request, or ctx ends.
+// It satisfies provider.RateLimiter.
+func (s *Scheduler) Wait(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	caller, prio := FromContext(ctx)
+	start := time.Now()
+
+	if prio == Interactive {
+		s.mu.Lock()
+		s.interactiveWaiting++
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.interactiveWaiting--
+			s.mu.Unlock()
+		}()
+	}
+
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		wait, ok := s.admit(now, caller, prio)
+		if ok {
+			s.record(now, caller, prio, now.Sub(start))
+			s.mu.Unlock()
+			return nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Allow takes a slot without blocking, as an untagged background call.
+func (s *Scheduler) Allow() bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	caller, prio := FromContext(context.Background())
+	if _, ok := s.admit(now, caller, prio); !ok {
+		return false
+	}
+	s.record(now, caller, prio, 0)
+	return true
+}
+
+// admit decides whether a call may go now; if not it returns how long to
+// sleep before trying again. Caller holds s.mu.
+func (s *Scheduler) admit(now time.Time, caller string, prio Priority) (time.Duration, bool) {
+	s.prune(now)
+	limit := s.cfg.Limit
+	if prio == Background {
+		if s.interactiveWaiting > 0 {
+			return 25 * time.Millisecond, false
+		}
+		limit = s.backgroundLimit()
+	}
+	if len(s.sent) < limit {
+		return 0, true
+	}
+	// The slot frees when the call that pushed us over leaves the window.
+	oldest := s.sent[len(s.sent)-limit].at
+	return max(oldest.Add(s.cfg.Window).Sub(now), time.Millisecond), false
+}
+
+func (s *Scheduler) record(now time.Time, caller string, prio Priority, waited time.Duration) {
+	s.sent = append(s.sent, stamp{now, caller})
+	cs := s.callers[caller]
+	if cs == nil {
+		cs = &callerStats{}
+		s.callers[caller] = cs
+	}
+	cs.priority = prio
+	cs.total++
+	cs.lastCall = now
+	waitSeconds.Observe(waited.Seconds(), caller, prio.String())
+	windowUsed.Set(float64(len(s.sent)))
+	if waited > time.Millisecond {
+		cs.throttled++
+		cs.waited += waited
+	}
+}
+
+func (s *Scheduler) prune(now time.Time) {
+	cutoff := now.Add(-s.cfg.Window)
+	i := 0
+	for i < len(s.sent) && !s.sent[i].at.After(cutoff) {
+		i++
+	}
+	if i > 0 {
+		s.sent = append(s.sent[:0], s.sent[i:]...)
+	}
+}
+
+func (s *Scheduler) backgroundLimit() int {
+	return max(1, s.cfg.Limit-int(float64(s.cfg.Limit)*s.cfg.Reserve))
+}
+
+// Scale stretches a polling interval as the window fills: unchanged below
+// half the limit, rising linearly to 4× at the limit. Pollers call it
+// before arming each tick.
+func (s *Scheduler) Scale(base time.Duration) time.Duration {
+	if s == nil {
+		return base
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Duration(float64(base) * s.scaleFactor(time.Now()))
+}
+
+func (s *Scheduler) scaleFactor(now time.Time) float64 {
+	s.prune(now)
+	u := float64(len(s.sent)) / float64(s.cfg.Limit)
+	if u <= 0.5 {
+		return 1
+	}
+	return 1 + min(u-0.5, 0.5)*6
+}
+
+// Usage is a point-in-time view of the budget for the debug page.
+type Usage struct {
+	Limit              int           `json:"limit"`
+	WindowSeconds      float64       `json:"windowSeconds"`
+	Used               int           `json:"used"`
+	Remaining          int           `json:"remaining"`
+	InteractiveReserve int           `json:"interactiveReserve"`
+	IntervalScale      float64       `json:"intervalScale"`
+	Callers            []CallerUsage `json:"callers"`
+}
+
+type CallerUsage struct {
+	Caller    string    `json:"caller"`
+	Priority  string    `json:"priority"`
+	InWindow  int       `json:"inWindow"`
+	Total     int64     `json:"total"`
+	Throttled int64     `json:"throttled"`
+	WaitedMs  int64     `json:"waitedMs"`
+	LastCall  time.Time `json:"lastCall"`
+}
+
+// Snapshot reports window usage overall and per caller, busiest first.
+func (s *Scheduler) Snapshot() Usage {
+	if s == nil {
+		return Usage{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.prune(now)
+
+	inWindow := map[string]int{}
+	for _, st := range s.sent {
+		inWindow[st.caller]++
+	}
+	u := Usage{
+		Limit:              s.cfg.Limit,
+		WindowSeconds:      s.cfg.Window.Seconds(),
+		Used:               len(s.sent),
+		Remaining:          max(0, s.cfg.Limit-len(s.sent)),
+		InteractiveReserve: s.cfg.Limit - s.backgroundLimit(),
+		IntervalScale:      s.scaleFactor(now),
+	}
+	for name, cs := range s.callers {
+		u.Callers = append(u.Callers, CallerUsage{
+			Caller:    name,
+			Priority:  cs.priority.String(),
+			InWindow:  inWindow[name],
+			Total:     cs.total,
+			Throttled: cs.throttled,
+			WaitedMs:  cs.waited.Milliseconds(),
+			LastCall:  cs.lastCall,
+		})
+	}
+	sort.Slice(u.Callers, func(i, j int) bool {
+		if u.Callers[i].InWindow != u.Callers[j].InWindow {
+			return u.Callers[i].InWindow > u.Callers[j].InWindow
+		}
+		return u.Callers[i].Caller < u.Callers[j].Caller
+	})
+	return u
+}
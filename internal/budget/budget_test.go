@@ -0,0 +1,91 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackgroundLeavesInteractiveReserve(t *testing.T) {
+	s := New(Config{Limit: 10, Window: time.Minute, Reserve: 0.2})
+	bg := WithCaller(context.Background(), "poller", Background)
+
+	for i := 0; i < 8; i++ {
+		if err := s.Wait(bg); err != nil {
+			t.Fatalf("background call %d: %v", i, err)
+		}
+	}
+
+	// The ninth background call must wait for the window to roll.
+	ctx, cancel := context.WithTimeout(bg, 20*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx); err == nil {
+		t.Fatal("background call past the reserve should block")
+	}
+
+	// Interactive calls may still use the reserve.
+	page := WithCaller(context.Background(), "http", Interactive)
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(page, 20*time.Millisecond)
+		if err := s.Wait(ctx); err != nil {
+			t.Fatalf("interactive call %d should use the reserve: %v", i, err)
+		}
+		cancel()
+	}
+	ctx, cancel = context.WithTimeout(page, 20*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx); err == nil {
+		t.Fatal("interactive call past the limit should block")
+	}
+}
+
+func TestWindowSlides(t *testing.T) {
+	s := New(Config{Limit: 2, Window: 50 * time.Millisecond, Reserve: 0.5})
+	ctx := WithCaller(context.Background(), "poller", Interactive)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := s.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("third call returned after %s, want ~one window", elapsed)
+	}
+}
+
+func TestSnapshotByCaller(t *testing.T) {
+	s := New(Config{Limit: 100})
+	for i := 0; i < 3; i++ {
+		s.Wait(WithCaller(context.Background(), "newspoller", Background))
+	}
+	s.Wait(WithCaller(context.Background(), "http", Interactive))
+	s.Wait(context.Background())
+
+	u := s.Snapshot()
+	if u.Used != 5 || u.Remaining != 95 {
+		t.Errorf("used=%d remaining=%d, want 5/95", u.Used, u.Remaining)
+	}
+	if len(u.Callers) != 3 || u.Callers[0].Caller != "newspoller" || u.Callers[0].InWindow != 3 {
+		t.Errorf("callers = %+v, want newspoller first with 3", u.Callers)
+	}
+}
+
+func TestScale(t *testing.T) {
+	s := New(Config{Limit: 10})
+	if got := s.Scale(time.Second); got != time.Second {
+		t.Errorf("idle scale = %s, want 1s", got)
+	}
+	for i := 0; i < 8; i++ {
+		s.Wait(WithCaller(context.Background(), "http", Interactive))
+	}
+	if got := s.Scale(time.Second); got <= time.Second {
+		t.Errorf("scale at 80%% = %s, want > 1s", got)
+	}
+	var nilSched *Scheduler
+	if got := nilSched.Scale(time.Second); got != time.Second {
+		t.Errorf("nil scheduler scale = %s", got)
+	}
+	if err := nilSched.Wait(context.Background()); err != nil {
+		t.Errorf("nil scheduler wait: %v", err)
+	}
+}
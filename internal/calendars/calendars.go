@@ -0,0 +1,385 @@
+// Package calendars models trading sessions for the venues stocktopus
+// shows quotes from: regular hours, lunch breaks, pre/post-market windows,
+// holidays and shortened sessions, each in the venue's own time zone.
+// Pollers use it to back off when nothing can trade; the indices page uses
+// it to show which markets are open.
+package calendars
+
+import (
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // venues must resolve even on hosts without zoneinfo
+)
+
+// Session is the trading phase a venue is in at a given instant.
+type Session string
+
+const (
+	Closed  Session = "closed"
+	Pre     Session = "pre"
+	Regular Session = "regular"
+	Break   Session = "break" // midday break (Tokyo, Hong Kong)
+	Post    Session = "post"
+)
+
+// Active reports whether prices can move in this session. Extended hours
+// count: US pre/post-market prints do update quotes.
+func (s Session) Active() bool {
+	return s == Pre || s == Regular || s == Post
+}
+
+// hm is a wall-clock time as minutes past local midnight.
+type hm int
+
+func at(h, m int) hm { return hm(h*60 + m) }
+
+// Venue is one exchange's trading calendar.
+type Venue struct {
+	Code string // MIC-style code, e.g. "XNYS"
+	Name string
+	TZ   string
+
+	loc      *time.Location
+	open     hm
+	close    hm
+	lunch    [2]hm // zero value = no break
+	pre      hm    // extended-hours start; zero = none
+	post     hm    // extended-hours end; zero = none
+	halfDay  hm    // close on shortened sessions
+	holidays holidayRule
+	kind     venueKind
+
+	mu    sync.Mutex
+	years map[int]holidaySet
+}
+
+type venueKind int
+
+const (
+	exchange   venueKind = iota
+	continuous           // crypto: never closes
+	forex                // Sunday 17:00 – Friday 17:00 New York
+)
+
+// Status is a venue's state at an instant, shaped for the API.
+type Status struct {
+	Venue      string     `json:"venue"`
+	Name       string     `json:"name"`
+	Timezone   string     `json:"timezone"`
+	Session    Session    `json:"session"`
+	Open       bool       `json:"open"`
+	LocalTime  string     `json:"localTime"`
+	Holiday    string     `json:"holiday,omitempty"`
+	EarlyClose bool       `json:"earlyClose,omitempty"`
+	NextChange *time.Time `json:"nextChange,omitempty"`
+}
+
+// Session returns the trading phase at t.
+func (v *Venue) Session(t time.Time) Session {
+	if v.kind == continuous {
+		return Regular
+	}
+	lt := t.In(v.loc)
+	s := Closed
+	for _, tr := range v.transitions(midnight(lt)) {
+		if tr.at.After(lt) {
+			break
+		}
+		s = tr.session
+	}
+	return s
+}
+
+// IsOpen reports whether the regular session is running at t.
+func (v *Venue) IsOpen(t time.Time) bool { return v.Session(t) == Regular }
+
+// NextChange returns when the session at t next changes, or the zero time
+// for venues that never close. The search is bounded to two weeks, which
+// covers every holiday cluster in the calendars (Golden Week, New Year).
+func (v *Venue) NextChange(t time.Time) time.Time {
+	if v.kind == continuous {
+		return time.Time{}
+	}
+	lt := t.In(v.loc)
+	cur := v.Session(t)
+	day := midnight(lt)
+	for i := 0; i < 14; i++ {
+		for _, tr := range v.transitions(day) {
+			if tr.at.After(lt) && tr.session != cur {
+				return tr.at
+			}
+		}
+		day = nextDay(day)
+	}
+	return time.Time{}
+}
+
+// Status bundles the session, local clock and next transition at t.
+func (v *Venue) Status(t time.Time) Status {
+	lt := t.In(v.loc)
+	s := v.Session(t)
+	st := Status{
+		Venue:     v.Code,
+		Name:      v.Name,
+		Timezone:  v.TZ,
+		Session:   s,
+		Open:      s == Regular,
+		LocalTime: lt.Format("15:04"),
+	}
+	if next := v.NextChange(t); !next.IsZero() {
+		st.NextChange = &next
+	}
+	if v.kind == exchange {
+		key := lt.Format(dateLayout)
+		hs := v.holidaySet(lt.Year())
+		st.Holiday = hs.closed[key]
+		_, st.EarlyClose = hs.halfDay[key]
+	}
+	return st
+}
+
+// Holiday returns the holiday name if the venue is closed all day on the
+// local date of t.
+func (v *Venue) Holiday(t time.Time) (string, bool) {
+	if v.kind != exchange {
+		return "", false
+	}
+	lt := t.In(v.loc)
+	name, ok := v.holidaySet(lt.Year()).closed[lt.Format(dateLayout)]
+	return name, ok
+}
+
+type transition struct {
+	at      time.Time
+	session Session
+}
+
+// transitions lists the session changes on a local calendar day, in order.
+// A day with no transitions is closed throughout.
+func (v *Venue) transitions(day time.Time) []transition {
+	clock := func(m hm) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), int(m)/60, int(m)%60, 0, 0, v.loc)
+	}
+
+	if v.kind == forex {
+		switch day.Weekday() {
+		case time.Saturday:
+			return nil
+		case time.Sunday:
+			return []transition{{clock(at(17, 0)), Regular}}
+		case time.Friday:
+			return []transition{{clock(0), Regular}, {clock(at(17, 0)), Closed}}
+		default:
+			return []transition{{clock(0), Regular}}
+		}
+	}
+
+	if isWeekend(day) {
+		return nil
+	}
+	key := day.Format(dateLayout)
+	hs := v.holidaySet(day.Year())
+	if _, closed := hs.closed[key]; closed {
+		return nil
+	}
+
+	closeAt, postEnd := v.close, v.post
+	if _, half := hs.halfDay[key]; half && v.halfDay != 0 {
+		closeAt = v.halfDay
+		if postEnd != 0 {
+			postEnd = v.halfDay + (v.post - v.close)
+		}
+	}
+
+	var out []transition
+	if v.pre != 0 {
+		out = append(out, transition{clock(v.pre), Pre})
+	}
+	out = append(out, transition{clock(v.open), Regular})
+	if v.lunch[1] != 0 && closeAt > v.lunch[1] {
+		out = append(out,
+			transition{clock(v.lunch[0]), Break},
+			transition{clock(v.lunch[1]), Regular})
+	}
+	if postEnd != 0 {
+		out = append(out,
+			transition{clock(closeAt), Post},
+			transition{clock(postEnd), Closed})
+	} else {
+		out = append(out, transition{clock(closeAt), Closed})
+	}
+	return out
+}
+
+func (v *Venue) holidaySet(year int) holidaySet {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if hs, ok := v.years[year]; ok {
+		return hs
+	}
+	if v.years == nil {
+		v.years = make(map[int]holidaySet)
+	}
+	hs := v.holidays(year)
+	v.years[year] = hs
+	return hs
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nextDay steps one calendar day; AddDate keeps the wall clock at midnight
+// across DST changes.
+func nextDay(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+
+func newVenue(code, name, tz string, open, closeAt hm, holidays holidayRule) *Venue {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		panic("calendars: " + err.Error())
+	}
+	return &Venue{Code: code, Name: name, TZ: tz, loc: loc, open: open, close: closeAt, holidays: holidays, kind: exchange}
+}
+
+func (v *Venue) withLunch(from, to hm) *Venue {
+	v.lunch = [2]hm{from, to}
+	return v
+}
+
+func (v *Venue) withExtended(pre, post hm) *Venue {
+	v.pre, v.post = pre, post
+	return v
+}
+
+func (v *Venue) withHalfDay(closeAt hm) *Venue {
+	v.halfDay = closeAt
+	return v
+}
+
+// Venues. NYSE and NASDAQ share a calendar but are kept separate so the
+// UI can label them.
+var (
+	NYSE = newVenue("XNYS", "New York Stock Exchange", "America/New_York", at(9, 30), at(16, 0), usHolidays).
+		withExtended(at(4, 0), at(20, 0)).withHalfDay(at(13, 0))
+	NASDAQ = newVenue("XNAS", "Nasdaq", "America/New_York", at(9, 30), at(16, 0), usHolidays).
+		withExtended(at(4, 0), at(20, 0)).withHalfDay(at(13, 0))
+	LSE       = newVenue("XLON", "London Stock Exchange", "Europe/London", at(8, 0), at(16, 30), ukHolidays).withHalfDay(at(12, 30))
+	TSE       = newVenue("XTKS", "Tokyo Stock Exchange", "Asia/Tokyo", at(9, 0), at(15, 30), jpHolidays).withLunch(at(11, 30), at(12, 30))
+	Xetra     = newVenue("XETR", "Xetra", "Europe/Berlin", at(9, 0), at(17, 30), xetraHolidays)
+	Paris     = newVenue("XPAR", "Euronext Paris", "Europe/Paris", at(9, 0), at(17, 30), euronextHolidays).withHalfDay(at(14, 5))
+	Amsterdam = newVenue("XAMS", "Euronext Amsterdam", "Europe/Amsterdam", at(9, 0), at(17, 30), euronextHolidays).withHalfDay(at(14, 5))
+	Stockholm = newVenue("XSTO", "Nasdaq Stockholm", "Europe/Stockholm", at(9, 0), at(17, 30), nordicHolidays)
+	HKEX      = newVenue("XHKG", "Hong Kong Exchange", "Asia/Hong_Kong", at(9, 30), at(16, 0), commonHolidays(true)).
+			withLunch(at(12, 0), at(13, 0)).withHalfDay(at(12, 0))
+	KRX  = newVenue("XKRX", "Korea Exchange", "Asia/Seoul", at(9, 0), at(15, 30), commonHolidays(false))
+	TWSE = newVenue("XTAI", "Taiwan Stock Exchange", "Asia/Taipei", at(9, 0), at(13, 30), commonHolidays(false))
+	ASX  = newVenue("XASX", "Australian Securities Exchange", "Australia/Sydney", at(10, 0), at(16, 0), commonHolidays(true)).withHalfDay(at(14, 10))
+	SGX  = newVenue("XSES", "Singapore Exchange", "Asia/Singapore", at(9, 0), at(17, 0), commonHolidays(true))
+	TSX  = newVenue("XTSE", "Toronto Stock Exchange", "America/Toronto", at(9, 30), at(16, 0), commonHolidays(true))
+	B3   = newVenue("BVMF", "B3", "America/Sao_Paulo", at(10, 0), at(17, 0), commonHolidays(true))
+	BMV  = newVenue("XMEX", "Bolsa Mexicana de Valores", "America/Mexico_City", at(8, 30), at(15, 0), commonHolidays(true))
+
+	Crypto = &Venue{Code: "CRYPTO", Name: "Crypto", TZ: "UTC", loc: time.UTC, kind: continuous}
+	FX     = &Venue{Code: "FX", Name: "Foreign Exchange", TZ: "America/New_York", loc: NYSE.loc, kind: forex, holidays: noHolidays}
+)
+
+// All returns every known venue in display order.
+func All() []*Venue {
+	return []*Venue{NYSE, NASDAQ, LSE, Xetra, Paris, Amsterdam, Stockholm, TSE, HKEX, KRX, TWSE, ASX, SGX, TSX, B3, BMV, FX, Crypto}
+}
+
+// Lookup finds a venue by its code (case-insensitive).
+func Lookup(code string) (*Venue, bool) {
+	code = strings.ToUpper(code)
+	for _, v := range All() {
+		if v.Code == code {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// exchangeCodes maps the exchange labels FMP returns on quotes, profiles
+// and the index list to venues.
+var exchangeCodes = map[string]*Venue{
+	"NYSE": NYSE, "NYQ": NYSE, "AMEX": NYSE, "NYSEARCA": NYSE, "BATS": NYSE,
+	"SNP": NYSE, "DJI": NYSE, "CBOE": NYSE, "OTC": NYSE, "PNK": NYSE,
+	"NASDAQ": NASDAQ, "NMS": NASDAQ, "NGM": NASDAQ, "NCM": NASDAQ,
+	"LSE": LSE, "XETRA": Xetra, "GER": Xetra, "FRA": Xetra, "PAR": Paris, "EURONEXT": Paris,
+	"AMS": Amsterdam, "STO": Stockholm, "JPX": TSE, "TYO": TSE, "HKSE": HKEX, "KSC": KRX,
+	"KOE": KRX, "TAI": TWSE, "ASX": ASX, "SGX": SGX, "SES": SGX, "TSX": TSX, "TOR": TSX,
+	"SAO": B3, "MEX": BMV, "CRYPTO": Crypto, "CCC": Crypto, "FOREX": FX, "CCY": FX,
+}
+
+// ForExchange maps an FMP exchange label to a venue.
+func ForExchange(exchange string) (*Venue, bool) {
+	v, ok := exchangeCodes[strings.ToUpper(strings.TrimSpace(exchange))]
+	return v, ok
+}
+
+// indexVenues covers the index symbols on the indices page whose list
+// entries don't carry a usable exchange.
+var indexVenues = map[string]*Venue{
+	"^GSPC": NYSE, "^DJI": NYSE, "^IXIC": NASDAQ, "^NDX": NASDAQ, "^VIX": NYSE, "^RUT": NYSE,
+	"^FTSE": LSE, "^STOXX50E": Xetra, "^GDAXI": Xetra, "^FCHI": Paris, "^AEX": Amsterdam,
+	"^OMX": Stockholm, "^N225": TSE, "^HSI": HKEX, "^KS11": KRX, "^TWII": TWSE,
+	"^AXJO": ASX, "^STI": SGX, "^GSPTSE": TSX, "^BVSP": B3, "^MXX": BMV,
+}
+
+// symbolSuffixes maps Yahoo/FMP-style listing suffixes to venues.
+var symbolSuffixes = map[string]*Venue{
+	".L": LSE, ".DE": Xetra, ".F": Xetra, ".PA": Paris, ".AS": Amsterdam, ".ST": Stockholm,
+	".T": TSE, ".HK": HKEX, ".KS": KRX, ".KQ": KRX, ".TW": TWSE, ".AX": ASX,
+	".SI": SGX, ".TO": TSX, ".V": TSX, ".SA": B3, ".MX": BMV,
+}
+
+var currencies = map[string]bool{
+	"USD": true, "EUR": true, "GBP": true, "JPY": true, "CHF": true, "CAD": true,
+	"AUD": true, "NZD": true, "CNH": true, "CNY": true, "HKD": true, "SGD": true,
+	"SEK": true, "NOK": true, "DKK": true, "MXN": true, "ZAR": true, "TRY": true,
+}
+
+// ForIndex resolves an index by symbol first, then by its listed exchange.
+func ForIndex(symbol, exchange string) *Venue {
+	if v, ok := indexVenues[strings.ToUpper(symbol)]; ok {
+		return v
+	}
+	if v, ok := ForExchange(exchange); ok {
+		return v
+	}
+	return NYSE
+}
+
+// ForSymbol resolves the venue a symbol trades on. assetType is the
+// classification from store.GetSecurityType ("crypto", "forex", "index",
+// "etf", "stock", ...) and may be empty, in which case the symbol's shape
+// decides: a "^" prefix is an index, a listing suffix picks the exchange,
+// six letters of two ISO currencies is a forex pair, and a USD/USDT-quoted
+// ticker is crypto. Everything else is a US listing.
+func ForSymbol(symbol, assetType string) *Venue {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	switch strings.ToLower(assetType) {
+	case "crypto", "cryptocurrency":
+		return Crypto
+	case "forex", "fx", "currency":
+		return FX
+	case "index":
+		return ForIndex(symbol, "")
+	}
+	if strings.HasPrefix(symbol, "^") {
+		return ForIndex(symbol, "")
+	}
+	if i := strings.LastIndex(symbol, "."); i > 0 {
+		if v, ok := symbolSuffixes[symbol[i:]]; ok {
+			return v
+		}
+	}
+	if len(symbol) == 6 && currencies[symbol[:3]] && currencies[symbol[3:]] {
+		return FX
+	}
+	if assetType == "" && len(symbol) > 4 && (strings.HasSuffix(symbol, "USD") || strings.HasSuffix(symbol, "USDT")) {
+		return Crypto
+	}
+	return NYSE
+}
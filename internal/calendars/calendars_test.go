@@ -0,0 +1,151 @@
+package calendars
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, tz string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		t.Fatalf("load %s: %v", tz, err)
+	}
+	return loc
+}
+
+func TestUSHolidays2026(t *testing.T) {
+	h := usHolidays(2026)
+	for _, d := range []string{
+		"2026-01-01", "2026-01-19", "2026-02-16", "2026-04-03", "2026-05-25",
+		"2026-06-19", "2026-07-03", "2026-09-07", "2026-11-26", "2026-12-25",
+	} {
+		if _, ok := h.closed[d]; !ok {
+			t.Errorf("%s should be an NYSE holiday", d)
+		}
+	}
+	if len(h.closed) != 10 {
+		t.Errorf("want 10 NYSE holidays in 2026, got %d: %v", len(h.closed), h.closed)
+	}
+	for _, d := range []string{"2026-11-27", "2026-12-24"} {
+		if _, ok := h.halfDay[d]; !ok {
+			t.Errorf("%s should be an early close", d)
+		}
+	}
+	// July 4 2026 is a Saturday: Friday the 3rd closes, no half day before it.
+	if _, ok := h.halfDay["2026-07-02"]; ok {
+		t.Error("2026-07-02 should be a full session")
+	}
+}
+
+func TestNewYearOnSaturdayNotObserved(t *testing.T) {
+	// 2022-01-01 was a Saturday; NYSE traded on Friday 2021-12-31.
+	if _, ok := usHolidays(2022).closed["2021-12-31"]; ok {
+		t.Error("NYSE must not observe a Saturday New Year on the prior Friday")
+	}
+}
+
+func TestJapanSubstituteAndEquinox(t *testing.T) {
+	h := jpHolidays(2026)
+	for _, d := range []string{
+		"2026-01-02", "2026-03-20", "2026-05-06", // Constitution Day (Sun 3rd) → Wed 6th
+		"2026-09-23", "2026-12-31",
+	} {
+		if _, ok := h.closed[d]; !ok {
+			t.Errorf("%s should be a TSE holiday", d)
+		}
+	}
+}
+
+func TestUKBoxingDayRollsForward(t *testing.T) {
+	// Christmas 2021 fell on a Saturday: closures on Mon 27th and Tue 28th.
+	h := ukHolidays(2021)
+	for _, d := range []string{"2021-12-27", "2021-12-28"} {
+		if _, ok := h.closed[d]; !ok {
+			t.Errorf("%s should be an LSE holiday", d)
+		}
+	}
+}
+
+func TestNYSESessions(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	tests := []struct {
+		at   time.Time
+		want Session
+	}{
+		{time.Date(2026, 10, 16, 3, 59, 0, 0, ny), Closed},
+		{time.Date(2026, 10, 16, 4, 0, 0, 0, ny), Pre},
+		{time.Date(2026, 10, 16, 9, 30, 0, 0, ny), Regular},
+		{time.Date(2026, 10, 16, 15, 59, 0, 0, ny), Regular},
+		{time.Date(2026, 10, 16, 16, 0, 0, 0, ny), Post},
+		{time.Date(2026, 10, 16, 20, 0, 0, 0, ny), Closed},
+		{time.Date(2026, 10, 17, 12, 0, 0, 0, ny), Closed}, // Saturday
+		{time.Date(2026, 11, 26, 12, 0, 0, 0, ny), Closed}, // Thanksgiving
+		{time.Date(2026, 11, 27, 12, 59, 0, 0, ny), Regular},
+		{time.Date(2026, 11, 27, 13, 0, 0, 0, ny), Post}, // early close
+		{time.Date(2026, 11, 27, 17, 0, 0, 0, ny), Closed},
+	}
+	for _, tc := range tests {
+		if got := NYSE.Session(tc.at); got != tc.want {
+			t.Errorf("NYSE.Session(%s) = %s, want %s", tc.at.Format(time.DateTime), got, tc.want)
+		}
+	}
+}
+
+func TestTokyoLunchBreak(t *testing.T) {
+	tk := mustLoad(t, "Asia/Tokyo")
+	if got := TSE.Session(time.Date(2026, 10, 16, 12, 0, 0, 0, tk)); got != Break {
+		t.Errorf("TSE at 12:00 = %s, want break", got)
+	}
+	if got := TSE.Session(time.Date(2026, 10, 16, 15, 15, 0, 0, tk)); got != Regular {
+		t.Errorf("TSE at 15:15 = %s, want regular", got)
+	}
+}
+
+func TestNextChangeSkipsWeekendAndHoliday(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	// Friday after close → Monday premarket; Good Friday 2026 is Apr 3.
+	got := NYSE.NextChange(time.Date(2026, 4, 2, 21, 0, 0, 0, ny))
+	want := time.Date(2026, 4, 6, 4, 0, 0, 0, ny)
+	if !got.Equal(want) {
+		t.Errorf("NextChange = %s, want %s", got, want)
+	}
+}
+
+func TestForexWeek(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	if FX.IsOpen(time.Date(2026, 10, 18, 16, 59, 0, 0, ny)) {
+		t.Error("FX should be closed Sunday before 17:00 NY")
+	}
+	if !FX.IsOpen(time.Date(2026, 10, 18, 17, 0, 0, 0, ny)) {
+		t.Error("FX should open Sunday 17:00 NY")
+	}
+	if FX.IsOpen(time.Date(2026, 10, 16, 17, 0, 0, 0, ny)) {
+		t.Error("FX should be closed Friday 17:00 NY")
+	}
+	if !Crypto.IsOpen(time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC)) {
+		t.Error("crypto never closes")
+	}
+}
+
+func TestForSymbol(t *testing.T) {
+	tests := []struct {
+		symbol, assetType string
+		want              *Venue
+	}{
+		{"AAPL", "", NYSE},
+		{"BTCUSD", "", Crypto},
+		{"EURUSD", "", FX},
+		{"ETHUSD", "crypto", Crypto},
+		{"VOD.L", "", LSE},
+		{"7203.T", "", TSE},
+		{"^N225", "", TSE},
+		{"^FTSE", "index", LSE},
+		{"SHOP.TO", "stock", TSX},
+	}
+	for _, tc := range tests {
+		if got := ForSymbol(tc.symbol, tc.assetType); got != tc.want {
+			t.Errorf("ForSymbol(%q, %q) = %s, want %s", tc.symbol, tc.assetType, got.Code, tc.want.Code)
+		}
+	}
+}
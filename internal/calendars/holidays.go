@@ -0,0 +1,273 @@
+package calendars
+
+import "time"
+
+// holidaySet is a year's worth of exchange closures and shortened
+// sessions, keyed by local "2006-01-02" date.
+type holidaySet struct {
+	closed  map[string]string // date → holiday name
+	halfDay map[string]string // date → reason
+}
+
+// holidayRule generates the holiday set for a calendar year. Rules are
+// computed rather than listed so the calendar doesn't rot every January.
+type holidayRule func(year int) holidaySet
+
+func newSet() holidaySet {
+	return holidaySet{closed: map[string]string{}, halfDay: map[string]string{}}
+}
+
+func (h holidaySet) close(d time.Time, name string) { h.closed[d.Format(dateLayout)] = name }
+func (h holidaySet) half(d time.Time, name string)  { h.halfDay[d.Format(dateLayout)] = name }
+
+const dateLayout = "2006-01-02"
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// nthWeekday returns the nth (1-based) weekday of the month; n = -1 means
+// the last one.
+func nthWeekday(year int, month time.Month, wd time.Weekday, n int) time.Time {
+	if n < 0 {
+		d := date(year, month+1, 1).AddDate(0, 0, -1)
+		for d.Weekday() != wd {
+			d = d.AddDate(0, 0, -1)
+		}
+		return d
+	}
+	d := date(year, month, 1)
+	for d.Weekday() != wd {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d.AddDate(0, 0, 7*(n-1))
+}
+
+// easter returns Easter Sunday (Gregorian) via the anonymous algorithm.
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return date(year, time.Month(month), day)
+}
+
+func isWeekend(d time.Time) bool {
+	return d.Weekday() == time.Saturday || d.Weekday() == time.Sunday
+}
+
+// usObserved applies the NYSE rule: Saturday holidays move to Friday,
+// Sunday holidays to Monday.
+func usObserved(d time.Time) time.Time {
+	switch d.Weekday() {
+	case time.Saturday:
+		return d.AddDate(0, 0, -1)
+	case time.Sunday:
+		return d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// usHolidays is the NYSE/NASDAQ schedule. New Year's Day falling on a
+// Saturday is not observed on the preceding Friday (NYSE Rule 7.2), and
+// Juneteenth closes from 2022.
+func usHolidays(year int) holidaySet {
+	h := newSet()
+	if ny := date(year, time.January, 1); ny.Weekday() != time.Saturday {
+		h.close(usObserved(ny), "New Year's Day")
+	}
+	h.close(nthWeekday(year, time.January, time.Monday, 3), "Martin Luther King Jr. Day")
+	h.close(nthWeekday(year, time.February, time.Monday, 3), "Washington's Birthday")
+	h.close(easter(year).AddDate(0, 0, -2), "Good Friday")
+	h.close(nthWeekday(year, time.May, time.Monday, -1), "Memorial Day")
+	if year >= 2022 {
+		h.close(usObserved(date(year, time.June, 19)), "Juneteenth")
+	}
+	july4 := date(year, time.July, 4)
+	h.close(usObserved(july4), "Independence Day")
+	h.close(nthWeekday(year, time.September, time.Monday, 1), "Labor Day")
+	thanksgiving := nthWeekday(year, time.November, time.Thursday, 4)
+	h.close(thanksgiving, "Thanksgiving Day")
+	xmas := date(year, time.December, 25)
+	h.close(usObserved(xmas), "Christmas Day")
+
+	// Early closes (13:00 ET).
+	if d := july4.AddDate(0, 0, -1); !isWeekend(d) && july4.Weekday() != time.Saturday && july4.Weekday() != time.Monday {
+		h.half(d, "Independence Day eve")
+	}
+	h.half(thanksgiving.AddDate(0, 0, 1), "Day after Thanksgiving")
+	if d := xmas.AddDate(0, 0, -1); !isWeekend(d) && xmas.Weekday() != time.Saturday && xmas.Weekday() != time.Monday {
+		h.half(d, "Christmas Eve")
+	}
+	return h
+}
+
+// ukHolidays is the LSE schedule (England & Wales bank holidays). Weekend
+// Christmas / Boxing Day roll forward to the next free weekday.
+func ukHolidays(year int) holidaySet {
+	h := newSet()
+	ny := date(year, time.January, 1)
+	for isWeekend(ny) {
+		ny = ny.AddDate(0, 0, 1)
+	}
+	h.close(ny, "New Year's Day")
+	e := easter(year)
+	h.close(e.AddDate(0, 0, -2), "Good Friday")
+	h.close(e.AddDate(0, 0, 1), "Easter Monday")
+	h.close(nthWeekday(year, time.May, time.Monday, 1), "Early May Bank Holiday")
+	h.close(nthWeekday(year, time.May, time.Monday, -1), "Spring Bank Holiday")
+	h.close(nthWeekday(year, time.August, time.Monday, -1), "Summer Bank Holiday")
+
+	xmas := date(year, time.December, 25)
+	boxing := date(year, time.December, 26)
+	switch xmas.Weekday() {
+	case time.Friday: // Boxing Day Saturday → Monday
+		boxing = date(year, time.December, 28)
+	case time.Saturday:
+		xmas, boxing = date(year, time.December, 27), date(year, time.December, 28)
+	case time.Sunday:
+		xmas, boxing = date(year, time.December, 27), date(year, time.December, 26)
+	}
+	h.close(xmas, "Christmas Day")
+	h.close(boxing, "Boxing Day")
+
+	// 12:30 closes on the last trading days before Christmas and New Year.
+	for _, d := range []time.Time{date(year, time.December, 24), date(year, time.December, 31)} {
+		if !isWeekend(d) {
+			h.half(d, "Early close")
+		}
+	}
+	return h
+}
+
+// jpHolidays is the TSE schedule: national holidays plus the Jan 2–3 and
+// Dec 31 market closures. A national holiday on Sunday is substituted on
+// the next non-holiday weekday. Equinox dates use the standard
+// approximation, valid 1980–2099.
+func jpHolidays(year int) holidaySet {
+	h := newSet()
+	national := []struct {
+		d    time.Time
+		name string
+	}{
+		{date(year, time.January, 1), "New Year's Day"},
+		{nthWeekday(year, time.January, time.Monday, 2), "Coming of Age Day"},
+		{date(year, time.February, 11), "National Foundation Day"},
+		{date(year, time.February, 23), "Emperor's Birthday"},
+		{date(year, time.March, equinoxDay(year, 20.8431)), "Vernal Equinox Day"},
+		{date(year, time.April, 29), "Showa Day"},
+		{date(year, time.May, 3), "Constitution Memorial Day"},
+		{date(year, time.May, 4), "Greenery Day"},
+		{date(year, time.May, 5), "Children's Day"},
+		{nthWeekday(year, time.July, time.Monday, 3), "Marine Day"},
+		{date(year, time.August, 11), "Mountain Day"},
+		{nthWeekday(year, time.September, time.Monday, 3), "Respect for the Aged Day"},
+		{date(year, time.September, equinoxDay(year, 23.2488)), "Autumnal Equinox Day"},
+		{nthWeekday(year, time.October, time.Monday, 2), "Sports Day"},
+		{date(year, time.November, 3), "Culture Day"},
+		{date(year, time.November, 23), "Labor Thanksgiving Day"},
+	}
+	for _, n := range national {
+		h.close(n.d, n.name)
+	}
+	for _, n := range national {
+		if n.d.Weekday() != time.Sunday {
+			continue
+		}
+		sub := n.d.AddDate(0, 0, 1)
+		for _, taken := h.closed[sub.Format(dateLayout)]; taken; _, taken = h.closed[sub.Format(dateLayout)] {
+			sub = sub.AddDate(0, 0, 1)
+		}
+		h.close(sub, "Substitute Holiday")
+	}
+	h.close(date(year, time.January, 2), "Market Holiday")
+	h.close(date(year, time.January, 3), "Market Holiday")
+	h.close(date(year, time.December, 31), "Market Holiday")
+	return h
+}
+
+func equinoxDay(year int, base float64) int {
+	y := float64(year - 1980)
+	return int(base + 0.242194*y - float64(int(y/4)))
+}
+
+// xetraHolidays covers Deutsche Börse's Xetra schedule, which closes on
+// both Christmas and New Year's Eve rather than shortening them.
+func xetraHolidays(year int) holidaySet {
+	h := europeanCore(year)
+	h.close(date(year, time.December, 24), "Christmas Eve")
+	h.close(date(year, time.December, 31), "New Year's Eve")
+	return h
+}
+
+// euronextHolidays covers Paris / Amsterdam: the TARGET2 core with early
+// closes on Christmas and New Year's Eve.
+func euronextHolidays(year int) holidaySet {
+	h := europeanCore(year)
+	for _, d := range []time.Time{date(year, time.December, 24), date(year, time.December, 31)} {
+		if !isWeekend(d) {
+			h.half(d, "Early close")
+		}
+	}
+	return h
+}
+
+// nordicHolidays adds Nasdaq Stockholm's national days to the core.
+func nordicHolidays(year int) holidaySet {
+	h := europeanCore(year)
+	e := easter(year)
+	h.close(date(year, time.January, 6), "Epiphany")
+	h.close(e.AddDate(0, 0, 39), "Ascension Day")
+	h.close(date(year, time.June, 6), "National Day")
+	midsummerEve := date(year, time.June, 19)
+	for midsummerEve.Weekday() != time.Friday {
+		midsummerEve = midsummerEve.AddDate(0, 0, 1)
+	}
+	h.close(midsummerEve, "Midsummer Eve")
+	h.close(date(year, time.December, 24), "Christmas Eve")
+	h.close(date(year, time.December, 31), "New Year's Eve")
+	return h
+}
+
+// europeanCore is the TARGET2 closing-day set most continental venues
+// share: New Year, Good Friday, Easter Monday, Labour Day, Christmas and
+// St Stephen's Day.
+func europeanCore(year int) holidaySet {
+	h := newSet()
+	e := easter(year)
+	h.close(date(year, time.January, 1), "New Year's Day")
+	h.close(e.AddDate(0, 0, -2), "Good Friday")
+	h.close(e.AddDate(0, 0, 1), "Easter Monday")
+	h.close(date(year, time.May, 1), "Labour Day")
+	h.close(date(year, time.December, 25), "Christmas Day")
+	h.close(date(year, time.December, 26), "St Stephen's Day")
+	return h
+}
+
+// commonHolidays is the fallback for venues whose local calendars aren't
+// modelled in full (lunar-calendar holidays in Hong Kong, Seoul and Taipei,
+// provincial holidays in Toronto, carnival in São Paulo). It covers the
+// closures they all share; anything else will show as open.
+func commonHolidays(goodFriday bool) holidayRule {
+	return func(year int) holidaySet {
+		h := newSet()
+		h.close(date(year, time.January, 1), "New Year's Day")
+		if goodFriday {
+			h.close(easter(year).AddDate(0, 0, -2), "Good Friday")
+		}
+		h.close(date(year, time.December, 25), "Christmas Day")
+		return h
+	}
+}
+
+func noHolidays(int) holidaySet { return newSet() }
@@ -0,0 +1,400 @@
+// Package cassette is the HTTP transport shared by every outbound client
+// (FMP, quote providers, FRED, DBnomics, BoE, SEC EDGAR, Ollama, Gemini).
+// In passthrough mode it is a plain pooled transport. In record mode it
+// also writes each exchange to a cassette directory, and in replay mode it
+// serves exchanges from that directory without touching the network, so
+// local development and the e2e suite work offline and deterministically.
+//
+// Requests are matched on method, normalized URL (lower-cased host, sorted
+// query, credentials blanked) and a hash of the request body. API keys are
+// never written to disk: credential query parameters are stored as
+// REDACTED and their values are scrubbed from recorded response bodies.
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Mode selects what the transport does with a request.
+type Mode string
+
+const (
+	Passthrough Mode = "passthrough" // forward to the network only
+	Record      Mode = "record"      // forward, and save the exchange
+	Replay      Mode = "replay"      // serve from the cassette; never touch the network
+)
+
+// ParseMode accepts "", "off", "passthrough", "record" or "replay".
+func ParseMode(s string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", "off", Passthrough:
+		return Passthrough, nil
+	case Record:
+		return Record, nil
+	case Replay:
+		return Replay, nil
+	}
+	return "", fmt.Errorf("cassette: unknown mode %q (want passthrough, record or replay)", s)
+}
+
+// Redacted replaces credential values in stored cassettes.
+const Redacted = "REDACTED"
+
+// DefaultSecretParams are query parameters treated as credentials.
+var DefaultSecretParams = []string{"apikey", "api_key", "key", "token", "access_token"}
+
+// ErrNotRecorded is returned in replay mode when no cassette matches.
+var ErrNotRecorded = errors.New("cassette: no recorded response")
+
+// Config configures a Transport.
+type Config struct {
+	Mode Mode
+	Dir  string // cassette directory; required for record and replay
+
+	// SecretParams overrides DefaultSecretParams (case-insensitive).
+	SecretParams []string
+	// IgnoreParams are dropped from the match key, e.g. "from"/"to" date
+	// windows computed from the wall clock.
+	IgnoreParams []string
+}
+
+// Transport is an http.RoundTripper with record/replay support. It is
+// safe for concurrent use and may be reconfigured at any time.
+type Transport struct {
+	next http.RoundTripper
+
+	mu      sync.RWMutex
+	mode    Mode
+	dir     string
+	secrets map[string]bool
+	ignore  map[string]bool
+}
+
+// New creates a transport that forwards to next (http.DefaultTransport
+// when nil).
+func New(cfg Config, next http.RoundTripper) (*Transport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &Transport{next: next}
+	if err := t.Configure(cfg); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Configure switches mode, directory and matching rules.
+func (t *Transport) Configure(cfg Config) error {
+	if cfg.Mode == "" {
+		cfg.Mode = Passthrough
+	}
+	if cfg.Mode != Passthrough && cfg.Dir == "" {
+		return fmt.Errorf("cassette: %s mode needs a directory", cfg.Mode)
+	}
+	if cfg.SecretParams == nil {
+		cfg.SecretParams = DefaultSecretParams
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.mode = cfg.Mode
+	t.dir = cfg.Dir
+	t.secrets = lowerSet(cfg.SecretParams)
+	t.ignore = lowerSet(cfg.IgnoreParams)
+	return nil
+}
+
+// Mode reports the current mode.
+func (t *Transport) Mode() Mode {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.mode
+}
+
+// Default is the process-wide transport. It starts in passthrough mode;
+// main (or a test's TestMain) calls Default.Configure before any traffic.
+var Default = func() *Transport {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.MaxIdleConns = 100
+	base.MaxIdleConnsPerHost = 10
+	base.IdleConnTimeout = 90 * time.Second
+	t, _ := New(Config{}, base)
+	return t
+}()
+
+// Client returns an http.Client with the given timeout that sends through
+// Default.
+func Client(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: Default}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.RLock()
+	mode, dir, secrets, ignore := t.mode, t.dir, t.secrets, t.ignore
+	t.mu.RUnlock()
+
+	if mode == Passthrough {
+		return t.next.RoundTrip(req)
+	}
+
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(reqBody)), nil }
+	}
+	normURL, found := Normalize(req.URL, secrets, ignore)
+	key := matchKey(req.Method, normURL, reqBody)
+	path := filepath.Join(dir, hostDir(req.URL.Host), key+".json")
+
+	if mode == Replay {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		e, err := load(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("%w for %s %s", ErrNotRecorded, req.Method, normURL)
+			}
+			return nil, err
+		}
+		return e.response(req)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	e := newEntry(req.Method, normURL, reqBody, resp, scrub(body, found))
+	if err := save(path, e); err != nil {
+		return nil, fmt.Errorf("cassette: save %s: %w", path, err)
+	}
+	return resp, nil
+}
+
+// Normalize renders u for matching: lower-cased scheme and host, query
+// sorted by name then value, secret parameters set to REDACTED and ignored
+// parameters dropped. It also returns the secret values it blanked.
+func Normalize(u *url.URL, secrets, ignore map[string]bool) (string, []string) {
+	q := u.Query()
+	var found []string
+	names := make([]string, 0, len(q))
+	for name, vals := range q {
+		lname := strings.ToLower(name)
+		if ignore[lname] {
+			continue
+		}
+		if secrets[lname] {
+			for i, v := range vals {
+				if v != "" && v != Redacted {
+					found = append(found, v)
+				}
+				vals[i] = Redacted
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(strings.ToLower(u.Scheme))
+	b.WriteString("://")
+	b.WriteString(strings.ToLower(u.Host))
+	b.WriteString(u.EscapedPath())
+	for i, name := range names {
+		vals := append([]string(nil), q[name]...)
+		sort.Strings(vals)
+		for j, v := range vals {
+			if i == 0 && j == 0 {
+				b.WriteByte('?')
+			} else {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(name))
+			b.WriteByte('=')
+			b.WriteString(url.QueryEscape(v))
+		}
+	}
+	return b.String(), found
+}
+
+func matchKey(method, normURL string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, strings.ToUpper(method)+" "+normURL+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:20]
+}
+
+// scrub removes credential values that a server echoed back in its body.
+// Very short values are skipped; they'd match ordinary text.
+func scrub(body []byte, secrets []string) []byte {
+	for _, s := range secrets {
+		if len(s) < 8 {
+			continue
+		}
+		body = bytes.ReplaceAll(body, []byte(s), []byte(Redacted))
+	}
+	return body
+}
+
+// hostDir groups cassettes by upstream ("financialmodelingprep.com",
+// "localhost_11434").
+func hostDir(host string) string {
+	if host == "" {
+		return "_"
+	}
+	return strings.NewReplacer(":", "_", "/", "_").Replace(strings.ToLower(host))
+}
+
+func lowerSet(names []string) map[string]bool {
+	m := make(map[string]bool, len(names))
+	for _, n := range names {
+		m[strings.ToLower(n)] = true
+	}
+	return m
+}
+
+// ── On-disk format ──
+//
+// One pretty-printed JSON file per exchange so fixtures diff cleanly in
+// review. Text bodies are stored verbatim; binary bodies as base64.
+
+type entry struct {
+	Request  entryRequest  `json:"request"`
+	Response entryResponse `json:"response"`
+}
+
+type entryRequest struct {
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	BodyHash string `json:"bodySha256,omitempty"`
+}
+
+type entryResponse struct {
+	Status     int               `json:"status"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	BodyBase64 string            `json:"bodyBase64,omitempty"`
+}
+
+// keptHeaders are the response headers worth replaying; the rest (dates,
+// cookies, rate-limit counters) only add churn to fixtures.
+var keptHeaders = []string{"Content-Type", "Content-Encoding", "Retry-After"}
+
+func newEntry(method, normURL string, reqBody []byte, resp *http.Response, body []byte) *entry {
+	e := &entry{
+		Request:  entryRequest{Method: method, URL: normURL},
+		Response: entryResponse{Status: resp.StatusCode},
+	}
+	if len(reqBody) > 0 {
+		sum := sha256.Sum256(reqBody)
+		e.Request.BodyHash = hex.EncodeToString(sum[:])
+	}
+	for _, h := range keptHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			if e.Response.Header == nil {
+				e.Response.Header = make(map[string]string)
+			}
+			e.Response.Header[h] = v
+		}
+	}
+	if utf8.Valid(body) {
+		e.Response.Body = string(body)
+	} else {
+		e.Response.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+	return e
+}
+
+func (e *entry) response(req *http.Request) (*http.Response, error) {
+	body := []byte(e.Response.Body)
+	if e.Response.BodyBase64 != "" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(e.Response.BodyBase64); err != nil {
+			return nil, fmt.Errorf("cassette: corrupt body for %s: %w", e.Request.URL, err)
+		}
+	}
+	header := make(http.Header, len(e.Response.Header))
+	for k, v := range e.Response.Header {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Response.Status, http.StatusText(e.Response.Status)),
+		StatusCode:    e.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func load(path string) (*entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("cassette: %s: %w", path, err)
+	}
+	return &e, nil
+}
+
+func save(path string, e *entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(e); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cassette-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,134 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		// A misbehaving API echoing the caller's key back.
+		io.WriteString(w, `[{"symbol":"`+r.URL.Query().Get("symbol")+`","apikey":"`+r.URL.Query().Get("apikey")+`"}]`)
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	tr, err := New(Config{Mode: Record, Dir: dir, IgnoreParams: []string{"from"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(upstream.URL + "/stable/quote?symbol=AAPL&apikey=sk-live-123456&from=2026-10-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "sk-live-123456") {
+		t.Errorf("record mode must hand the caller the real response, got %s", body)
+	}
+
+	// Nothing on disk may contain the key.
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			data, _ := os.ReadFile(path)
+			if strings.Contains(string(data), "sk-live-123456") {
+				t.Errorf("%s leaks the API key:\n%s", path, data)
+			}
+		}
+		return nil
+	})
+
+	// Replay with a different key, reordered query and a new date window:
+	// still matches, and never reaches upstream.
+	if err := tr.Configure(Config{Mode: Replay, Dir: dir, IgnoreParams: []string{"from"}}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = client.Get(upstream.URL + "/stable/quote?from=2026-10-18&apikey=other&symbol=AAPL")
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("replayed status=%d content-type=%q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if want := `[{"symbol":"AAPL","apikey":"REDACTED"}]`; string(body) != want {
+		t.Errorf("replayed body = %s, want %s", body, want)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("upstream hit %d times, want 1", hits.Load())
+	}
+
+	_, err = client.Get(upstream.URL + "/stable/quote?symbol=MSFT&apikey=x")
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("unrecorded request: err = %v, want ErrNotRecorded", err)
+	}
+}
+
+func TestRequestBodyIsPartOfTheMatch(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		io.WriteString(w, "echo:"+string(b))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	tr, _ := New(Config{Mode: Record, Dir: dir}, nil)
+	client := &http.Client{Transport: tr}
+	for _, prompt := range []string{`{"prompt":"a"}`, `{"prompt":"b"}`} {
+		resp, err := client.Post(upstream.URL+"/api/generate", "application/json", strings.NewReader(prompt))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	tr.Configure(Config{Mode: Replay, Dir: dir})
+	resp, err := client.Post(upstream.URL+"/api/generate", "application/json", strings.NewReader(`{"prompt":"b"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `echo:{"prompt":"b"}` {
+		t.Errorf("replayed %q for prompt b", body)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	u, _ := url.Parse("HTTPS://API.Example.com/v1/series?b=2&A=1&api_key=secret&b=1")
+	got, found := Normalize(u, lowerSet(DefaultSecretParams), nil)
+	if want := "https://api.example.com/v1/series?A=1&api_key=REDACTED&b=1&b=2"; got != want {
+		t.Errorf("Normalize = %s, want %s", got, want)
+	}
+	if len(found) != 1 || found[0] != "secret" {
+		t.Errorf("found secrets = %v", found)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for in, want := range map[string]Mode{"": Passthrough, "off": Passthrough, "Record": Record, " replay ": Replay} {
+		if got, err := ParseMode(in); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseMode("rewind"); err == nil {
+		t.Error("unknown mode should fail")
+	}
+	if _, err := New(Config{Mode: Replay}, nil); err == nil {
+		t.Error("replay without a directory should fail")
+	}
+}
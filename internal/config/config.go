@@ -1,27 +1,325 @@
+// Package config is stocktopus's typed configuration. Built-in defaults
+// (defaults.yaml) are layered under an optional config.yaml, key by key,
+// with ${VAR} and ${VAR:-fallback} expanded from the environment. Load
+// reports every problem at once, each with its key path and the file and
+// line it came from.
 package config
 
 import (
-	"gopkg.in/yaml.v3"
+	_ "embed"
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+//go:embed defaults.yaml
+var defaultsYAML []byte
+
 // Config holds all configuration for the application.
 type Config struct {
-	APIKey         string   `yaml:"apiKey"`
+	Provider       ProviderConfig            `yaml:"provider"`
+	Providers      map[string]ProviderConfig `yaml:"providers"`
+	Routes         Routes                    `yaml:"routes"`
+	Consensus      ConsensusConfig           `yaml:"consensus"`
+	RateLimit      RateLimitConfig           `yaml:"rateLimit"`
+	Retry          RetryConfig               `yaml:"retry"`
+	CircuitBreaker CircuitBreakerConfig      `yaml:"circuitBreaker"`
+	Validation     ValidationConfig          `yaml:"validation"`
+
+	// Deprecated: use provider.apiKey. Still used when that is empty.
+	APIKey         string   `yaml:"apiKey" secret:"true"`
 	RefreshSeconds int      `yaml:"refreshSeconds"`
 	Tickers        []string `yaml:"tickers"`
+
+	Polling  PollingConfig  `yaml:"polling"`
+	Screener ScreenerConfig `yaml:"screener"`
+	AI       AIConfig       `yaml:"ai"`
+	FRED     FREDConfig     `yaml:"fred"`
+	Store    StoreConfig    `yaml:"store"`
+	Ticks    TicksConfig    `yaml:"ticks"`
+	Server   ServerConfig   `yaml:"server"`
+	Cassette CassetteConfig `yaml:"cassette"`
+
+	origins map[string]string // key path → "file:line" it was last set from
+}
+
+// ProviderConfig selects and configures a quote provider.
+type ProviderConfig struct {
+	Name    string            `yaml:"name,omitempty"` // only in the provider section
+	APIKey  string            `yaml:"apiKey" secret:"true"`
+	BaseURL string            `yaml:"baseURL"`
+	Timeout time.Duration     `yaml:"timeout"`
+	Options map[string]string `yaml:"options"`
+}
+
+// ConsensusConfig cross-checks the default provider against a second one.
+// Empty Provider disables it.
+type ConsensusConfig struct {
+	Provider  string  `yaml:"provider"`
+	Tolerance float64 `yaml:"tolerance"`
+}
+
+// RateLimitConfig is the shared FMP request budget.
+type RateLimitConfig struct {
+	MaxRequests int           `yaml:"maxRequests"`
+	Window      time.Duration `yaml:"window"`
+	Reserve     float64       `yaml:"reserve"`
+}
+
+// RetryConfig mirrors provider.RetryConfig.
+type RetryConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	MaxAttempts    int           `yaml:"maxAttempts"`
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+	MaxBackoff     time.Duration `yaml:"maxBackoff"`
+	Multiplier     float64       `yaml:"multiplier"`
+	Jitter         bool          `yaml:"jitter"`
+}
+
+// CircuitBreakerConfig mirrors provider.CircuitBreakerConfig.
+type CircuitBreakerConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	MaxFailures  int           `yaml:"maxFailures"`
+	ResetTimeout time.Duration `yaml:"resetTimeout"`
+}
+
+// ValidationConfig mirrors the thresholds of provider.ValidationConfig.
+type ValidationConfig struct {
+	MaxFutureSkew time.Duration `yaml:"maxFutureSkew"`
+	StaleAfter    time.Duration `yaml:"staleAfter"`
+	MaxJump       float64       `yaml:"maxJump"`
+}
+
+// PollingConfig holds the background loop periods other than quotes.
+type PollingConfig struct {
+	News         time.Duration `yaml:"news"`
+	Sectors      time.Duration `yaml:"sectors"`
+	ClosedMarket time.Duration `yaml:"closedMarket"` // 0 = poll closed venues every tick
+	Econ         time.Duration `yaml:"econ"`
+	Watchlists   time.Duration `yaml:"watchlists"` // dynamic watchlist re-evaluation
+	Universe     time.Duration `yaml:"universe"`   // local screener snapshot rebuild
+}
+
+// ScreenerConfig sets what the local screener's nightly snapshot covers:
+// index symbols (^GSPC, ^IXIC, ^DJI) expand to their constituents, other
+// entries are tickers. Watched symbols are always included.
+type ScreenerConfig struct {
+	Universe []string `yaml:"universe"`
+}
+
+// AIConfig covers the Gemini orchestrator and the Ollama workers.
+type AIConfig struct {
+	Gemini GeminiConfig `yaml:"gemini"`
+	Ollama OllamaConfig `yaml:"ollama"`
+}
+
+type GeminiConfig struct {
+	APIKey string `yaml:"apiKey" secret:"true"`
+}
+
+type OllamaConfig struct {
+	Host     string        `yaml:"host"`
+	Model    string        `yaml:"model"`    // company intelligence workers
+	NERModel string        `yaml:"nerModel"` // entity extraction and trading analysts
+	Workers  int           `yaml:"workers"`
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+}
+
+type FREDConfig struct {
+	APIKey string `yaml:"apiKey" secret:"true"`
+}
+
+// StoreConfig picks the database: a SQLite file (the default) or a
+// Postgres server for a shared instance.
+type StoreConfig struct {
+	Driver string `yaml:"driver"`            // sqlite or postgres
+	Path   string `yaml:"path"`              // sqlite database file
+	DSN    string `yaml:"dsn" secret:"true"` // postgres connection string
+}
+
+// Source is what store.Open takes for the configured driver: the file
+// path for sqlite, the connection string for postgres.
+func (s StoreConfig) Source() string {
+	if strings.EqualFold(s.Driver, "postgres") {
+		return s.DSN
+	}
+	return s.Path
+}
+
+// Name identifies the store in messages without exposing credentials.
+func (s StoreConfig) Name() string {
+	if strings.EqualFold(s.Driver, "postgres") {
+		return "postgres"
+	}
+	return s.Path
+}
+
+type TicksConfig struct {
+	Dir           string `yaml:"dir"`
+	RetentionDays int    `yaml:"retentionDays"`
 }
 
-// Load reads configuration from a file and unmarshals it.
+type ServerConfig struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+}
+
+type CassetteConfig struct {
+	Mode string `yaml:"mode"`
+	Dir  string `yaml:"dir"`
+}
+
+// Routes maps an asset class to a provider name. In YAML it is either a
+// mapping or the PROVIDER_ROUTES string form "crypto=polygon,forex=fmp".
+type Routes map[string]string
+
+func (r *Routes) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.MappingNode {
+		m := map[string]string{}
+		if err := n.Decode(&m); err != nil {
+			return err
+		}
+		*r = m
+		return nil
+	}
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return err
+	}
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		class, name, ok := strings.Cut(pair, "=")
+		class, name = strings.TrimSpace(class), strings.TrimSpace(name)
+		if !ok || class == "" || name == "" {
+			return fmt.Errorf("%q is not class=provider", strings.TrimSpace(pair))
+		}
+		m[class] = name
+	}
+	*r = m
+	return nil
+}
+
+// Default returns the built-in configuration, expanded against the
+// current environment.
+func Default() (*Config, error) {
+	cfg := &Config{origins: map[string]string{}}
+	if err := cfg.layer("defaults", defaultsYAML); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Load reads configuration from a file over the built-in defaults and
+// validates it. A missing file is an error; use LoadOptional for the
+// implicit ./config.yaml.
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return parse(path, data)
+}
+
+// LoadOptional is Load, except that a missing file yields the defaults.
+func LoadOptional(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, path = nil, "defaults"
+	} else if err != nil {
+		return nil, err
+	}
+	return parse(path, data)
+}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+func parse(name string, data []byte) (*Config, error) {
+	cfg, err := Default()
+	if err != nil {
+		return nil, fmt.Errorf("built-in defaults: %w", err)
+	}
+	if len(data) > 0 {
+		if err := cfg.layer(name, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
-	return &cfg, nil
+	return cfg, nil
+}
+
+// layer decodes one YAML document over cfg.
+func (c *Config) layer(name string, data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil // empty file
+	}
+	d := decoder{file: name, origins: c.origins}
+	d.decode(doc.Content[0], reflect.ValueOf(c).Elem(), "")
+	if len(d.errs) > 0 {
+		return d.errs
+	}
+	return nil
+}
+
+// ProviderAPIKey is the key for the named provider: its providers entry,
+// else provider.apiKey, else the legacy top-level apiKey.
+func (c *Config) ProviderAPIKey(name string) string {
+	if p, ok := c.Providers[name]; ok && p.APIKey != "" {
+		return p.APIKey
+	}
+	if c.Provider.APIKey != "" {
+		return c.Provider.APIKey
+	}
+	return c.APIKey
+}
+
+// ProviderSettings merges the named provider's entry over the provider
+// section, so a routed provider inherits timeout and base settings.
+func (c *Config) ProviderSettings(name string) ProviderConfig {
+	out := ProviderConfig{Name: name, APIKey: c.ProviderAPIKey(name), Timeout: c.Provider.Timeout}
+	if name == c.Provider.Name {
+		out.BaseURL = c.Provider.BaseURL
+		out.Options = c.Provider.Options
+	}
+	if p, ok := c.Providers[name]; ok {
+		if p.BaseURL != "" {
+			out.BaseURL = p.BaseURL
+		}
+		if p.Timeout > 0 {
+			out.Timeout = p.Timeout
+		}
+		if len(p.Options) > 0 {
+			merged := map[string]string{}
+			for k, v := range out.Options {
+				merged[k] = v
+			}
+			for k, v := range p.Options {
+				merged[k] = v
+			}
+			out.Options = merged
+		}
+	}
+	return out
+}
+
+// RefreshInterval is the quote poll interval.
+func (c *Config) RefreshInterval() time.Duration {
+	return time.Duration(c.RefreshSeconds) * time.Second
+}
+
+// Origin reports where the value at a key path (e.g. "retry.maxAttempts")
+// was set: "config.yaml:12", "defaults:40", or "" if never set.
+func (c *Config) Origin(path string) string {
+	return c.origins[path]
 }
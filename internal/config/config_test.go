@@ -1,9 +1,15 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestLoad creates and returns a new configuration object.
@@ -45,3 +51,235 @@ tickers:
 		t.Errorf("expected tickers to be [TEST1, TEST2], but got %v", cfg.Tickers)
 	}
 }
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file %v", err)
+	}
+	return path
+}
+
+func TestLoad_DefaultsAndEnvExpansion(t *testing.T) {
+	t.Setenv("STOCK_PROVIDER", "")
+	t.Setenv("TEST_NEWS_INTERVAL", "45s")
+	t.Setenv("TEST_KEY", "k123")
+
+	cfg, err := Load(writeConfig(t, `
+provider:
+  apiKey: ${TEST_KEY}
+polling:
+  news: ${TEST_NEWS_INTERVAL}
+  sectors: ${TEST_UNSET:-7m}
+server:
+  port: ${TEST_UNSET_PORT:-9090}
+`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Provider.Name != "fmp" {
+		t.Errorf("provider.name = %q, want default fmp", cfg.Provider.Name)
+	}
+	if cfg.Provider.APIKey != "k123" || cfg.ProviderAPIKey("polygon") != "k123" {
+		t.Errorf("apiKey = %q / %q, want k123", cfg.Provider.APIKey, cfg.ProviderAPIKey("polygon"))
+	}
+	if cfg.Polling.News != 45*time.Second || cfg.Polling.Sectors != 7*time.Minute {
+		t.Errorf("polling = %v / %v, want 45s / 7m", cfg.Polling.News, cfg.Polling.Sectors)
+	}
+	if cfg.Server.Port != 9090 {
+		t.Errorf("server.port = %d, want 9090", cfg.Server.Port)
+	}
+	if cfg.Retry.MaxAttempts != 3 || cfg.AI.Ollama.Model == "" {
+		t.Errorf("defaults not applied: %+v %+v", cfg.Retry, cfg.AI.Ollama)
+	}
+	if got := cfg.Origin("polling.news"); !strings.HasSuffix(got, "config.yaml:5") {
+		t.Errorf("Origin(polling.news) = %q", got)
+	}
+}
+
+func TestLoad_ErrorPaths(t *testing.T) {
+	path := writeConfig(t, `
+retry:
+  initialBackoff: soon
+bogus: true
+`)
+	_, err := Load(path)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("err = %v, want 2 field errors", err)
+	}
+	if errs[0].Path != "retry.initialBackoff" || errs[0].Origin != path+":3" {
+		t.Errorf("errs[0] = %+v", errs[0])
+	}
+	if errs[1].Path != "bogus" || errs[1].Msg != "unknown field" {
+		t.Errorf("errs[1] = %+v", errs[1])
+	}
+
+	path = writeConfig(t, `
+refreshSeconds: 0
+tickers: [AAPL, "bad ticker", aapl]
+routes: {crypto: polygon, bonds: fmp}
+`)
+	_, err = Load(path)
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want Errors", err)
+	}
+	var paths []string
+	for _, e := range errs {
+		paths = append(paths, e.Path)
+	}
+	want := []string{"routes.bonds", "refreshSeconds", "tickers[1]", "tickers[2]"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	if errs[1].Origin != path+":2" || errs[2].Origin != path+":3" {
+		t.Errorf("origins = %q, %q", errs[1].Origin, errs[2].Origin)
+	}
+}
+
+func TestLoadOptional_MissingFile(t *testing.T) {
+	cfg, err := LoadOptional(filepath.Join(t.TempDir(), "absent.yaml"))
+	if err != nil {
+		t.Fatalf("LoadOptional: %v", err)
+	}
+	if cfg.RefreshSeconds != 15 {
+		t.Errorf("refreshSeconds = %d, want default 15", cfg.RefreshSeconds)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "absent.yaml")); err == nil {
+		t.Error("Load of a missing file should fail")
+	}
+}
+
+func TestStore_Driver(t *testing.T) {
+	t.Setenv("STOCKTOPUS_DB_DSN", "")
+	path := writeConfig(t, `
+store:
+  driver: postgres
+`)
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "store.dsn") {
+		t.Errorf("postgres without dsn: err = %v, want store.dsn error", err)
+	}
+
+	path = writeConfig(t, `
+store:
+  driver: postgres
+  dsn: postgres://u:secret@db/stocktopus
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Store.Source() != "postgres://u:secret@db/stocktopus" || cfg.Store.Name() != "postgres" {
+		t.Errorf("source = %q, name = %q", cfg.Store.Source(), cfg.Store.Name())
+	}
+
+	path = writeConfig(t, `
+store:
+  driver: mysql
+`)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "store.driver") {
+		t.Errorf("mysql: err = %v, want store.driver error", err)
+	}
+}
+
+func TestRoutes_StringForm(t *testing.T) {
+	t.Setenv("PROVIDER_ROUTES", "crypto=polygon, forex=alphavantage")
+	cfg, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Routes["crypto"] != "polygon" || cfg.Routes["forex"] != "alphavantage" || len(cfg.Routes) != 2 {
+		t.Errorf("routes = %v", cfg.Routes)
+	}
+	if err := cfg.ValidateProviders([]string{"fmp", "polygon"}); err == nil || !strings.Contains(err.Error(), "routes.forex") {
+		t.Errorf("ValidateProviders = %v, want routes.forex error", err)
+	}
+}
+
+func TestProviderSettings_NamedEntryWins(t *testing.T) {
+	cfg := &Config{
+		Provider: ProviderConfig{
+			Name:    "fmp",
+			BaseURL: "https://section.example",
+			Timeout: 5 * time.Second,
+			Options: map[string]string{"plan": "starter", "region": "us"},
+		},
+		Providers: map[string]ProviderConfig{
+			"fmp": {BaseURL: "https://entry.example", Options: map[string]string{"plan": "ultimate"}},
+		},
+	}
+	got := cfg.ProviderSettings("fmp")
+	if got.BaseURL != "https://entry.example" || got.Timeout != 5*time.Second {
+		t.Errorf("baseURL/timeout = %q/%v", got.BaseURL, got.Timeout)
+	}
+	if got.Options["plan"] != "ultimate" || got.Options["region"] != "us" {
+		t.Errorf("options = %v, want the entry's plan over the section's, region inherited", got.Options)
+	}
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `
+provider:
+  apiKey: supersecret
+fred:
+  apiKey: alsosecret
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "secret") {
+		t.Errorf("secrets leaked:\n%s", out)
+	}
+	for _, want := range []string{"apiKey: " + Redacted, "window: 1m", "cacheTTL: 24h"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDiff_Reloadable(t *testing.T) {
+	a, _ := Default()
+	b, _ := Default()
+	b.RefreshSeconds = 5
+	b.Tickers = []string{"AAPL"}
+	b.Polling.News = time.Minute
+	b.Server.Port = 9000
+
+	changed, restart := Diff(a, b)
+	if strings.Join(changed, ",") != "polling.news,refreshSeconds,server.port,tickers" {
+		t.Errorf("changed = %v", changed)
+	}
+	if len(restart) != 1 || restart[0] != "server.port" {
+		t.Errorf("restart = %v, want [server.port]", restart)
+	}
+}
+
+func TestWatcher_ReloadsValidEdits(t *testing.T) {
+	path := writeConfig(t, "refreshSeconds: 15\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatcher(path, cfg, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var got *Config
+	w.OnChange(func(old, next *Config) { got = next })
+
+	os.WriteFile(path, []byte("refreshSeconds: nope\n"), 0644)
+	w.Reload()
+	if got != nil || w.Current() != cfg {
+		t.Fatal("invalid edit should keep the current config")
+	}
+
+	os.WriteFile(path, []byte("refreshSeconds: 5\ntickers: [SPY]\n"), 0644)
+	w.Reload()
+	if got == nil || got.RefreshSeconds != 5 || w.Current() != got {
+		t.Fatalf("valid edit not applied: %+v", got)
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a problem with one configuration key.
+type FieldError struct {
+	Path   string // dotted key path, e.g. "retry.maxAttempts" or "tickers[2]"
+	Origin string // "config.yaml:12"; empty for values nobody set
+	Msg    string
+}
+
+func (e *FieldError) Error() string {
+	if e.Origin != "" {
+		return e.Origin + ": " + e.Path + ": " + e.Msg
+	}
+	return e.Path + ": " + e.Msg
+}
+
+// Errors is every problem found in one load, in document order.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, fe := range e {
+		lines[i] = fe.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	envRef          = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+)
+
+// decoder walks a YAML node tree onto an existing value, so keys absent
+// from the document keep what an earlier layer set. yaml.v3's own
+// decoder stops at the first type error and doesn't know key paths;
+// doing the structure here gives one precise error per bad key.
+type decoder struct {
+	file    string
+	origins map[string]string
+	errs    Errors
+}
+
+func (d *decoder) fail(n *yaml.Node, path, format string, args ...any) {
+	d.errs = append(d.errs, &FieldError{Path: path, Origin: d.at(n), Msg: fmt.Sprintf(format, args...)})
+}
+
+func (d *decoder) at(n *yaml.Node) string {
+	return fmt.Sprintf("%s:%d", d.file, n.Line)
+}
+
+func (d *decoder) decode(n *yaml.Node, v reflect.Value, path string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if reflect.PointerTo(v.Type()).Implements(unmarshalerType) {
+		d.leaf(n, v, path)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			if !isNull(n) {
+				d.fail(n, path, "expected a mapping")
+			}
+			return
+		}
+		fields := yamlFields(v.Type())
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			p := joinPath(path, key.Value)
+			idx, ok := fields[key.Value]
+			if !ok {
+				d.fail(key, p, "unknown field")
+				continue
+			}
+			d.decode(val, v.Field(idx), p)
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			d.leaf(n, v, path) // null clears, anything else is a type error
+			return
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			k := reflect.ValueOf(key.Value).Convert(v.Type().Key())
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if old := v.MapIndex(k); old.IsValid() {
+				elem.Set(old)
+			}
+			d.decode(val, elem, joinPath(path, key.Value))
+			v.SetMapIndex(k, elem)
+		}
+	default:
+		d.leaf(n, v, path)
+	}
+}
+
+// leaf decodes a scalar, sequence or custom-unmarshalled value whole.
+func (d *decoder) leaf(n *yaml.Node, v reflect.Value, path string) {
+	if !d.expandAll(n, path) {
+		return
+	}
+	d.origins[path] = d.at(n)
+	if v.Type() == durationType {
+		if isNull(n) {
+			return
+		}
+		dur, err := time.ParseDuration(n.Value)
+		if n.Kind != yaml.ScalarNode || err != nil {
+			d.fail(n, path, "%q is not a duration (e.g. 30s, 2m, 1h)", n.Value)
+			return
+		}
+		v.SetInt(int64(dur))
+		return
+	}
+	tmp := reflect.New(v.Type())
+	tmp.Elem().Set(v)
+	if err := n.Decode(tmp.Interface()); err != nil {
+		d.fail(n, path, "%s", cleanYAMLError(err))
+		return
+	}
+	v.Set(tmp.Elem())
+}
+
+// expandAll substitutes ${VAR} and ${VAR:-fallback} in every scalar under
+// n. An expanded scalar loses its quoting so "30" from the environment
+// can still fill an int.
+func (d *decoder) expandAll(n *yaml.Node, path string) bool {
+	ok := true
+	if n.Kind == yaml.ScalarNode {
+		if !strings.Contains(n.Value, "${") {
+			return true
+		}
+		n.Value = envRef.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			m := envRef.FindStringSubmatch(ref)
+			if v := os.Getenv(m[1]); v != "" {
+				return v
+			}
+			return m[3]
+		})
+		if strings.Contains(n.Value, "${") {
+			d.fail(n, path, "malformed ${VAR} reference in %q", n.Value)
+			return false
+		}
+		n.Tag, n.Style = "", 0
+		return true
+	}
+	for _, c := range n.Content {
+		ok = d.expandAll(c, path) && ok
+	}
+	return ok
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+// cleanYAMLError drops yaml.v3's "yaml: unmarshal errors:\n  line N:"
+// preamble; the FieldError already carries the location.
+func cleanYAMLError(err error) string {
+	msg := err.Error()
+	msg = strings.TrimPrefix(msg, "yaml: unmarshal errors:\n")
+	msg = strings.TrimSpace(msg)
+	if strings.HasPrefix(msg, "line ") {
+		if _, rest, ok := strings.Cut(msg, ": "); ok {
+			msg = rest
+		}
+	}
+	return msg
+}
+
+// yamlFields maps YAML key → struct field index for t's tagged fields.
+func yamlFields(t reflect.Type) map[string]int {
+	out := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		out[name] = i
+	}
+	return out
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
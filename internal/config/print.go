@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces secret values in Print output.
+const Redacted = "REDACTED"
+
+// Print writes the effective configuration as YAML: every key, durations
+// in their string form, secrets (fields tagged secret:"true") redacted,
+// and a trailing comment naming where each value came from.
+func (c *Config) Print(w io.Writer) error {
+	n := c.encode(reflect.ValueOf(*c), "", true)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// flatten returns every leaf as path → rendered value, secrets included.
+// Reload diffs two of these to find what changed.
+func (c *Config) flatten() map[string]string {
+	out := map[string]string{}
+	var walk func(n *yaml.Node, path string)
+	walk = func(n *yaml.Node, path string) {
+		if n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				walk(n.Content[i+1], joinPath(path, n.Content[i].Value))
+			}
+			return
+		}
+		b, _ := yaml.Marshal(n)
+		out[path] = strings.TrimSpace(string(b))
+	}
+	walk(c.encode(reflect.ValueOf(*c), "", false), "")
+	return out
+}
+
+func (c *Config) encode(v reflect.Value, path string, redact bool) *yaml.Node {
+	switch {
+	case v.Type() == durationType:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: formatDuration(time.Duration(v.Int()))}
+	case v.Kind() == reflect.Struct:
+		m := &yaml.Node{Kind: yaml.MappingNode}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if opts == "omitempty" && v.Field(i).IsZero() {
+				continue
+			}
+			p := joinPath(path, name)
+			var val *yaml.Node
+			if f.Tag.Get("secret") == "true" && redact && v.Field(i).String() != "" {
+				val = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: Redacted}
+			} else {
+				val = c.encode(v.Field(i), p, redact)
+			}
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+			if o := c.origins[p]; o != "" && redact && (val.Kind == yaml.ScalarNode || val.Style == yaml.FlowStyle) {
+				val.LineComment = o
+			}
+			m.Content = append(m.Content, key, val)
+		}
+		return m
+	case v.Kind() == reflect.Map:
+		m := &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			val := c.encode(v.MapIndex(k), joinPath(path, k.String()), redact)
+			if val.Kind == yaml.MappingNode {
+				m.Style = 0
+			}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k.String()}, val)
+		}
+		return m
+	}
+	var n yaml.Node
+	if err := n.Encode(v.Interface()); err != nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(v.Interface())}
+	}
+	if n.Kind == yaml.SequenceNode {
+		n.Style = yaml.FlowStyle
+	}
+	return &n
+}
+
+// formatDuration drops the zero units time.Duration.String keeps: 1m, not
+// 1m0s; 24h, not 24h0m0s.
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
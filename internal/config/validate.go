@@ -0,0 +1,246 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AssetClasses are the routes keys the quote router understands — the
+// security types the store records.
+var AssetClasses = []string{"stock", "etf", "fund", "crypto", "forex", "index"}
+
+// validator accumulates FieldErrors, attributing each to the file and line
+// its key was last set from.
+type validator struct {
+	c    *Config
+	errs Errors
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	origin := v.c.origins[path]
+	if i := strings.IndexByte(path, '['); origin == "" && i > 0 {
+		origin = v.c.origins[path[:i]] // sequences are decoded whole
+	}
+	v.errs = append(v.errs, &FieldError{Path: path, Origin: origin, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) positive(path string, d time.Duration) {
+	if d <= 0 {
+		v.fail(path, "must be a positive duration")
+	}
+}
+
+func (v *validator) atLeast(path string, n, min int) {
+	if n < min {
+		v.fail(path, "must be at least %d, got %d", min, n)
+	}
+}
+
+func (v *validator) fraction(path string, f float64) {
+	if f < 0 || f >= 1 {
+		v.fail(path, "must be at least 0 and below 1, got %g", f)
+	}
+}
+
+// Validate checks every setting and returns all problems as Errors.
+func (c *Config) Validate() error {
+	v := &validator{c: c}
+
+	if strings.TrimSpace(c.Provider.Name) == "" {
+		v.fail("provider.name", "is required")
+	}
+	v.positive("provider.timeout", c.Provider.Timeout)
+	v.url("provider.baseURL", c.Provider.BaseURL, false)
+	for _, name := range sortedKeys(c.Providers) {
+		p := c.Providers[name]
+		if p.Timeout < 0 {
+			v.fail("providers."+name+".timeout", "must not be negative")
+		}
+		v.url("providers."+name+".baseURL", p.BaseURL, false)
+	}
+	for _, class := range sortedKeys(c.Routes) {
+		if !contains(AssetClasses, class) {
+			v.fail("routes."+class, "unknown asset class (want one of %s)", strings.Join(AssetClasses, ", "))
+		}
+	}
+	if c.Consensus.Provider != "" && c.Consensus.Provider == c.Provider.Name {
+		v.fail("consensus.provider", "must differ from provider.name %q", c.Provider.Name)
+	}
+	if c.Consensus.Tolerance < 0 {
+		v.fail("consensus.tolerance", "must not be negative")
+	}
+
+	v.atLeast("rateLimit.maxRequests", c.RateLimit.MaxRequests, 1)
+	v.positive("rateLimit.window", c.RateLimit.Window)
+	v.fraction("rateLimit.reserve", c.RateLimit.Reserve)
+
+	if c.Retry.Enabled {
+		v.atLeast("retry.maxAttempts", c.Retry.MaxAttempts, 1)
+		v.positive("retry.initialBackoff", c.Retry.InitialBackoff)
+		if c.Retry.MaxBackoff < c.Retry.InitialBackoff {
+			v.fail("retry.maxBackoff", "must be at least retry.initialBackoff (%s)", c.Retry.InitialBackoff)
+		}
+		if c.Retry.Multiplier < 1 {
+			v.fail("retry.multiplier", "must be at least 1, got %g", c.Retry.Multiplier)
+		}
+	}
+	if c.CircuitBreaker.Enabled {
+		v.atLeast("circuitBreaker.maxFailures", c.CircuitBreaker.MaxFailures, 1)
+		v.positive("circuitBreaker.resetTimeout", c.CircuitBreaker.ResetTimeout)
+	}
+
+	if c.Validation.MaxFutureSkew < 0 {
+		v.fail("validation.maxFutureSkew", "must not be negative")
+	}
+	if c.Validation.StaleAfter < 0 {
+		v.fail("validation.staleAfter", "must not be negative")
+	}
+	if c.Validation.MaxJump < 0 {
+		v.fail("validation.maxJump", "must not be negative")
+	}
+
+	v.atLeast("refreshSeconds", c.RefreshSeconds, 1)
+	seen := map[string]int{}
+	for i, t := range c.Tickers {
+		path := fmt.Sprintf("tickers[%d]", i)
+		switch {
+		case !validTicker(t):
+			v.fail(path, "%q is not a ticker", t)
+		case seen[strings.ToUpper(t)] > 0:
+			v.fail(path, "%q is already listed at tickers[%d]", t, seen[strings.ToUpper(t)]-1)
+		default:
+			seen[strings.ToUpper(t)] = i + 1
+		}
+	}
+
+	v.positive("polling.news", c.Polling.News)
+	v.positive("polling.sectors", c.Polling.Sectors)
+	if c.Polling.ClosedMarket < 0 {
+		v.fail("polling.closedMarket", "must not be negative")
+	}
+	v.positive("polling.econ", c.Polling.Econ)
+	v.positive("polling.watchlists", c.Polling.Watchlists)
+	v.positive("polling.universe", c.Polling.Universe)
+	for i, u := range c.Screener.Universe {
+		if !validTicker(u) {
+			v.fail(fmt.Sprintf("screener.universe[%d]", i), "%q is not a ticker or index symbol", u)
+		}
+	}
+
+	v.url("ai.ollama.host", c.AI.Ollama.Host, true)
+	if c.AI.Ollama.Model == "" {
+		v.fail("ai.ollama.model", "is required")
+	}
+	if c.AI.Ollama.NERModel == "" {
+		v.fail("ai.ollama.nerModel", "is required")
+	}
+	v.atLeast("ai.ollama.workers", c.AI.Ollama.Workers, 1)
+	v.positive("ai.ollama.cacheTTL", c.AI.Ollama.CacheTTL)
+
+	switch strings.ToLower(c.Store.Driver) {
+	case "sqlite":
+		if c.Store.Path == "" {
+			v.fail("store.path", "is required")
+		}
+	case "postgres":
+		if c.Store.DSN == "" {
+			v.fail("store.dsn", "is required for the postgres driver")
+		}
+	default:
+		v.fail("store.driver", "unknown driver %q (want sqlite or postgres)", c.Store.Driver)
+	}
+	if c.Ticks.Dir == "" {
+		v.fail("ticks.dir", "is required")
+	}
+	v.atLeast("ticks.retentionDays", c.Ticks.RetentionDays, 1)
+
+	if c.Server.Port < 0 || c.Server.Port > 65535 {
+		v.fail("server.port", "must be between 0 and 65535, got %d", c.Server.Port)
+	}
+
+	switch strings.ToLower(c.Cassette.Mode) {
+	case "", "off", "passthrough", "record", "replay":
+	default:
+		v.fail("cassette.mode", "unknown mode %q (want passthrough, record or replay)", c.Cassette.Mode)
+	}
+	if c.Cassette.Dir == "" {
+		v.fail("cassette.dir", "is required")
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// ValidateProviders checks every provider name the config refers to
+// against the registered ones. It's separate from Validate because the
+// registry lives with the provider packages main links in.
+func (c *Config) ValidateProviders(registered []string) error {
+	v := &validator{c: c}
+	check := func(path, name string) {
+		if name != "" && !contains(registered, name) {
+			v.fail(path, "unknown provider %q (available: %s)", name, strings.Join(registered, ", "))
+		}
+	}
+	check("provider.name", c.Provider.Name)
+	check("consensus.provider", c.Consensus.Provider)
+	for _, class := range sortedKeys(c.Routes) {
+		check("routes."+class, c.Routes[class])
+	}
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+func (v *validator) url(path, raw string, required bool) {
+	if raw == "" {
+		if required {
+			v.fail(path, "is required")
+		}
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.fail(path, "%q is not an http(s) URL", raw)
+	}
+}
+
+// validTicker matches the provider package's symbol rule: letters, digits
+// and . - ^ / =.
+func validTicker(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case strings.ContainsRune(".-^/=", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reloadable are the key paths (and path prefixes, ending in ".") that
+// take effect without a restart. Anything else that changes in the file is
+// logged as needing one.
+var Reloadable = []string{"refreshSeconds", "tickers", "polling.", "screener."}
+
+// Watcher re-reads a config file when its contents change, or on demand,
+// and hands every valid new version to its subscribers. An invalid edit is
+// logged with its errors and the running config stays in force.
+type Watcher struct {
+	path     string
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	current  *Config
+	data     []byte
+	onChange []func(old, new *Config)
+}
+
+// NewWatcher watches path, starting from cfg (what was loaded at boot).
+// interval is how often the file is checked.
+func NewWatcher(path string, cfg *Config, interval time.Duration, logger *slog.Logger) *Watcher {
+	data, _ := os.ReadFile(path)
+	return &Watcher{
+		path:     path,
+		interval: interval,
+		logger:   logger.With("component", "config"),
+		current:  cfg,
+		data:     data,
+	}
+}
+
+// OnChange registers fn to run after each successful reload.
+func (w *Watcher) OnChange(fn func(old, new *Config)) {
+	w.mu.Lock()
+	w.onChange = append(w.onChange, fn)
+	w.mu.Unlock()
+}
+
+// Current returns the config in force.
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Run polls the file until ctx is done.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check(false)
+		}
+	}
+}
+
+// Reload re-reads the file even if it hasn't changed — SIGHUP, so edits
+// to ${VAR} environment values are picked up too.
+func (w *Watcher) Reload() { w.check(true) }
+
+func (w *Watcher) check(force bool) {
+	data, err := os.ReadFile(w.path)
+	if err != nil && !os.IsNotExist(err) {
+		w.logger.Warn("config read failed", "path", w.path, "error", err)
+		return
+	}
+	w.mu.Lock()
+	if !force && bytes.Equal(data, w.data) {
+		w.mu.Unlock()
+		return
+	}
+	w.data = data
+	w.mu.Unlock()
+
+	next, err := LoadOptional(w.path)
+	if err != nil {
+		w.logger.Error("config reload rejected, keeping current config", "path", w.path, "error", err)
+		return
+	}
+
+	w.mu.Lock()
+	old := w.current
+	w.current = next
+	subs := append([]func(old, new *Config){}, w.onChange...)
+	w.mu.Unlock()
+
+	changed, restart := Diff(old, next)
+	if len(changed) == 0 {
+		return
+	}
+	w.logger.Info("config reloaded", "path", w.path, "changed", changed)
+	if len(restart) > 0 {
+		w.logger.Warn("config changes need a restart to take effect", "keys", restart)
+	}
+	for _, fn := range subs {
+		fn(old, next)
+	}
+}
+
+// Diff lists the key paths whose values differ between a and b, and the
+// subset of those that aren't Reloadable.
+func Diff(a, b *Config) (changed, restart []string) {
+	fa, fb := a.flatten(), b.flatten()
+	for k := range fb {
+		if _, ok := fa[k]; !ok {
+			fa[k] = ""
+		}
+	}
+	for k, va := range fa {
+		if fb[k] != va {
+			changed = append(changed, k)
+			if !isReloadable(k) {
+				restart = append(restart, k)
+			}
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(restart)
+	return changed, restart
+}
+
+func isReloadable(path string) bool {
+	for _, r := range Reloadable {
+		if path == r || (strings.HasSuffix(r, ".") && strings.HasPrefix(path, r)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+// Package corpactions turns raw end-of-day bars plus a symbol's splits and
+// dividends into the series charts and backtests actually want: split
+// adjusted (no fake crashes on a 4-for-1) or total return (dividends
+// reinvested, so a utility's chart isn't a slow bleed on every ex-date).
+//
+// Providers disagree on what "raw" means — FMP's EOD feed is already split
+// adjusted, Polygon's is when asked — so Adjust looks for each split's
+// jump in the series itself and only adjusts the ones still present.
+package corpactions
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"stocktopus/internal/model"
+)
+
+// Mode selects the adjustment Adjust applies.
+type Mode string
+
+const (
+	// Raw leaves prices as the provider sent them and only annotates
+	// ex-date bars with their dividend.
+	Raw Mode = "raw"
+	// Split removes split jumps: earlier prices divide by the ratio,
+	// volumes multiply by it, dividends scale to match.
+	Split Mode = "split"
+	// Total is Split plus dividend reinvestment: prices before each
+	// ex-date scale down by (1 - dividend/previous close), so the return
+	// between any two bars includes the cash paid in between. The last bar
+	// keeps its traded price and no bar carries a Dividend.
+	Total Mode = "total"
+)
+
+// ParseMode reads a query-string value. Empty means Raw.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", Raw:
+		return Raw, nil
+	case Split, Total:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("unknown adjustment %q (want raw, split or total)", s)
+}
+
+// splitTolerance is how close (in log terms) the close-to-close move over a
+// split date has to be to the split ratio to count as an unadjusted jump.
+// A 2-for-1 is ln 2 ≈ 0.69; ordinary days rarely move a quarter of that.
+const splitTolerance = 0.15
+
+// Adjust returns a copy of bars (oldest first) adjusted per mode. Splits
+// and dividends may be in any order; entries outside the series are
+// ignored except for the basis they imply (see below). The input is never
+// modified.
+//
+// The result is expressed in the share basis of the provider's data: if
+// the series carries no unadjusted split jumps it's taken to be adjusted
+// through today, so dividends are scaled by every later split too;
+// otherwise it's in the basis of its own last bar.
+func Adjust(bars []model.OHLCV, splits []model.Split, dividends []model.Dividend, mode Mode) []model.OHLCV {
+	out := make([]model.OHLCV, len(bars))
+	copy(out, bars)
+	if len(out) == 0 {
+		return out
+	}
+	for i := range out {
+		out[i].Dividend = 0
+	}
+
+	splits = append([]model.Split(nil), splits...)
+	sort.Slice(splits, func(i, j int) bool { return splits[i].Date < splits[j].Date })
+
+	first, last := out[0].Date, out[len(out)-1].Date
+	present := make([]bool, len(splits))
+	providerRaw := false
+	for i, s := range splits {
+		if s.Date <= first || s.Date > last || s.Ratio() == 1 {
+			continue
+		}
+		if k := indexOnOrAfter(out, s.Date); k > 0 && hasJump(out[k-1].Close, out[k].Close, s.Ratio()) {
+			present[i] = true
+			providerRaw = true
+		}
+	}
+
+	// basis[j]: how much the provider already divided bar j's price by.
+	// apply[j]: how much more Split/Total mode divides it by.
+	basis := make([]float64, len(out))
+	apply := make([]float64, len(out))
+	for j := range out {
+		basis[j], apply[j] = 1, 1
+		for i, s := range splits {
+			if out[j].Date >= s.Date {
+				continue
+			}
+			switch {
+			case present[i]:
+				apply[j] *= s.Ratio()
+			case s.Date > last && providerRaw:
+				// A raw series ending before the split hasn't seen it.
+			default:
+				basis[j] *= s.Ratio()
+			}
+		}
+	}
+
+	if mode == Split || mode == Total {
+		for j := range out {
+			if f := apply[j]; f != 1 {
+				out[j].Open /= f
+				out[j].High /= f
+				out[j].Low /= f
+				out[j].Close /= f
+				out[j].Volume = int64(math.Round(float64(out[j].Volume) * f))
+				basis[j] *= f
+			}
+		}
+	}
+
+	for _, d := range dividends {
+		if d.Amount <= 0 || d.ExDate < first || d.ExDate > last {
+			continue
+		}
+		if k := indexOnOrAfter(out, d.ExDate); k >= 0 {
+			out[k].Dividend += d.Amount / basis[k]
+		}
+	}
+
+	if mode == Total {
+		reinvest(out)
+	}
+	return out
+}
+
+// reinvest back-adjusts prices for the dividends annotated on out, newest
+// first, then clears them: the cash is now in the price.
+func reinvest(out []model.OHLCV) {
+	m := 1.0
+	for i := len(out) - 1; i >= 0; i-- {
+		if m != 1 {
+			out[i].Open *= m
+			out[i].High *= m
+			out[i].Low *= m
+			out[i].Close *= m
+		}
+		if d := out[i].Dividend; d > 0 && i > 0 {
+			if prev := out[i-1].Close; prev > d {
+				m *= 1 - d/prev
+			}
+		}
+		out[i].Dividend = 0
+	}
+}
+
+// indexOnOrAfter is the first bar dated on or after date, or -1. Ex-dates
+// and split dates that fall on a holiday land on the next session.
+func indexOnOrAfter(bars []model.OHLCV, date string) int {
+	k := sort.Search(len(bars), func(i int) bool { return bars[i].Date >= date })
+	if k == len(bars) {
+		return -1
+	}
+	return k
+}
+
+// hasJump reports whether the move from prev to next looks like the split
+// itself rather than a normal session. Ratios too small to tell apart from
+// an ordinary day (a 5% stock dividend) never match and are assumed to be
+// adjusted already.
+func hasJump(prev, next, ratio float64) bool {
+	if prev <= 0 || next <= 0 {
+		return false
+	}
+	moved := math.Log(prev / next)
+	want := math.Log(ratio)
+	return math.Abs(moved-want) < splitTolerance && math.Abs(moved) > splitTolerance
+}
@@ -0,0 +1,100 @@
+package corpactions
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"stocktopus/internal/model"
+)
+
+func series(prices ...float64) []model.OHLCV {
+	out := make([]model.OHLCV, len(prices))
+	for i, p := range prices {
+		out[i] = model.OHLCV{
+			Date: fmt.Sprintf("2026-03-%02d", i+1),
+			Open: p, High: p, Low: p, Close: p,
+			Volume: 1000,
+		}
+	}
+	return out
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+// A raw 4-for-1 shows up as a 75% crash; Split mode removes it.
+func TestSplitAdjust_UnadjustedSeries(t *testing.T) {
+	bars := series(400, 404, 101, 102)
+	splits := []model.Split{{Date: "2026-03-03", Numerator: 4, Denominator: 1}}
+
+	got := Adjust(bars, splits, nil, Split)
+	for i, want := range []float64{100, 101, 101, 102} {
+		if !near(got[i].Close, want) {
+			t.Errorf("bar %d close = %v, want %v", i, got[i].Close, want)
+		}
+	}
+	if got[0].Volume != 4000 || got[2].Volume != 1000 {
+		t.Errorf("volumes = %d, %d; want pre-split scaled ×4", got[0].Volume, got[2].Volume)
+	}
+	if bars[0].Close != 400 {
+		t.Error("Adjust must not modify its input")
+	}
+}
+
+// A series the provider already adjusted is left alone, but a dividend
+// declared before the split is scaled into the adjusted basis.
+func TestSplitAdjust_AlreadyAdjusted(t *testing.T) {
+	bars := series(100, 101, 101, 102)
+	splits := []model.Split{{Date: "2026-03-03", Numerator: 4, Denominator: 1}}
+	divs := []model.Dividend{{ExDate: "2026-03-02", Amount: 2}}
+
+	got := Adjust(bars, splits, divs, Split)
+	if !near(got[0].Close, 100) {
+		t.Errorf("adjusted series changed: %v", got[0].Close)
+	}
+	if !near(got[1].Dividend, 0.5) {
+		t.Errorf("dividend = %v, want 2/4 in post-split units", got[1].Dividend)
+	}
+}
+
+// Raw keeps traded prices and puts the declared amount on the ex-date bar,
+// or the next session when the ex-date isn't a trading day.
+func TestRaw_AnnotatesDividends(t *testing.T) {
+	bars := []model.OHLCV{
+		{Date: "2026-03-05", Close: 50},
+		{Date: "2026-03-09", Close: 49},
+	}
+	divs := []model.Dividend{{ExDate: "2026-03-07", Amount: 1}}
+	got := Adjust(bars, nil, divs, Raw)
+	if got[1].Dividend != 1 || got[0].Dividend != 0 {
+		t.Errorf("dividends = %v, %v; want weekend ex-date on the Monday bar", got[0].Dividend, got[1].Dividend)
+	}
+}
+
+// Total return: a stock that drops by exactly its dividend on the ex-date
+// has a flat total-return series, and the last bar keeps its price.
+func TestTotalReturn_ReinvestsDividends(t *testing.T) {
+	bars := series(100, 100, 98, 98)
+	divs := []model.Dividend{{ExDate: "2026-03-03", Amount: 2}}
+
+	got := Adjust(bars, nil, divs, Total)
+	for i, want := range []float64{98, 98, 98, 98} {
+		if !near(got[i].Close, want) {
+			t.Errorf("bar %d close = %v, want %v", i, got[i].Close, want)
+		}
+		if got[i].Dividend != 0 {
+			t.Errorf("bar %d still carries a dividend after reinvestment", i)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for in, want := range map[string]Mode{"": Raw, "raw": Raw, "split": Split, "total": Total} {
+		if got, err := ParseMode(in); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %v, %v", in, got, err)
+		}
+	}
+	if _, err := ParseMode("adjusted"); err == nil {
+		t.Error("unknown mode should error")
+	}
+}
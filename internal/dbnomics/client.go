@@ -18,6 +18,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"stocktopus/internal/cassette"
 )
 
 const baseURL = "https://api.db.nomics.world/v22"
@@ -46,7 +48,7 @@ type Client struct {
 }
 
 func New() *Client {
-	return &Client{http: &http.Client{Timeout: 30 * time.Second}}
+	return &Client{http: cassette.Client(30 * time.Second)}
 }
 
 // GetSeries fetches the full observation history for a DBnomics series.
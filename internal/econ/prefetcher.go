@@ -13,12 +13,13 @@ import (
 // stays provider-agnostic.
 type Prefetcher struct {
 	fetcher  *Fetcher
-	store    *store.Store
+	store    store.EconRepository
 	logger   *slog.Logger
 	interval time.Duration
+	reset    chan time.Duration
 }
 
-func NewPrefetcher(fetcher *Fetcher, st *store.Store, logger *slog.Logger, interval time.Duration) *Prefetcher {
+func NewPrefetcher(fetcher *Fetcher, st store.EconRepository, logger *slog.Logger, interval time.Duration) *Prefetcher {
 	if interval <= 0 {
 		interval = 30 * time.Minute
 	}
@@ -27,6 +28,23 @@ func NewPrefetcher(fetcher *Fetcher, st *store.Store, logger *slog.Logger, inter
 		store:    st,
 		logger:   logger.With("component", "econ-prefetcher"),
 		interval: interval,
+		reset:    make(chan time.Duration, 1),
+	}
+}
+
+// SetInterval changes the refresh tick of a running prefetcher. Only the
+// latest pending change is kept.
+func (p *Prefetcher) SetInterval(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	select {
+	case <-p.reset:
+	default:
+	}
+	select {
+	case p.reset <- d:
+	default:
 	}
 }
 
@@ -45,6 +63,9 @@ func (p *Prefetcher) Run(ctx context.Context) {
 			return
 		case <-ticker.C:
 			p.refreshAll(ctx)
+		case d := <-p.reset:
+			p.interval = d
+			ticker.Reset(d)
 		}
 	}
 }
@@ -19,6 +19,7 @@ type Decision struct {
 	Price    float64 `json:"price"`
 	Target   float64 `json:"target"`   // post-decision fraction of equity in the stock (0..1)
 	Traded   float64 `json:"traded"`   // signed shares traded this bar (+buy / -sell)
+	Dividend float64 `json:"dividend,omitempty"` // cash credited for shares held into this bar's ex-date
 	Cash     float64 `json:"cash"`     // cash after the trade
 	Shares   float64 `json:"shares"`   // shares held after the trade
 	Equity   float64 `json:"equity"`   // mark-to-market equity at this bar's close
@@ -29,6 +30,7 @@ type SimResult struct {
 	StartCash   float64    `json:"startCash"`
 	EndEquity   float64    `json:"endEquity"`
 	TotalReturn float64    `json:"totalReturn"` // EndEquity/StartCash - 1
+	Dividends   float64    `json:"dividends"`   // total dividend cash credited
 	Trace       []Decision `json:"trace"`
 }
 
@@ -47,11 +49,14 @@ func (f PolicyFunc) Target(bars []model.OHLCV, i int, equity float64) float64 {
 
 // Simulate walks the policy across every bar, rebalancing to its target
 // fraction (paying slippage on traded notional), and returns the trace +
-// ending equity.
+// ending equity. Shares carried into a bar with a Dividend earn it as cash
+// before the policy decides, so feed it corpactions.Adjust(…, Split) bars;
+// total-return bars already carry the dividend in price and have none.
 func Simulate(bars []model.OHLCV, p Policy, startCash, slippageBps float64) SimResult {
 	slip := slippageBps / 10000.0
 	cash := startCash
 	shares := 0.0
+	dividends := 0.0
 	trace := make([]Decision, 0, len(bars))
 
 	for i := range bars {
@@ -59,6 +64,9 @@ func Simulate(bars []model.OHLCV, p Policy, startCash, slippageBps float64) SimR
 		if px <= 0 {
 			continue
 		}
+		div := shares * bars[i].Dividend
+		cash += div
+		dividends += div
 		equity := cash + shares*px
 		target := clamp01(p.Target(bars, i, equity))
 		desired := target * equity / px
@@ -69,7 +77,7 @@ func Simulate(bars []model.OHLCV, p Policy, startCash, slippageBps float64) SimR
 		equity = cash + shares*px // re-mark after costs
 		trace = append(trace, Decision{
 			Index: i, Date: bars[i].Date, Price: px,
-			Target: target, Traded: traded, Cash: cash, Shares: shares, Equity: equity,
+			Target: target, Traded: traded, Dividend: div, Cash: cash, Shares: shares, Equity: equity,
 		})
 	}
 
@@ -77,7 +85,7 @@ func Simulate(bars []model.OHLCV, p Policy, startCash, slippageBps float64) SimR
 	if n := len(trace); n > 0 {
 		end = trace[n-1].Equity
 	}
-	return SimResult{StartCash: startCash, EndEquity: end, TotalReturn: end/startCash - 1, Trace: trace}
+	return SimResult{StartCash: startCash, EndEquity: end, TotalReturn: end/startCash - 1, Dividends: dividends, Trace: trace}
 }
 
 // BuyHold buys fully at the first bar and holds.
@@ -21,6 +21,23 @@ func TestBuyHold_EndingEquityExact(t *testing.T) {
 	}
 }
 
+// Holding through an ex-date credits the dividend to cash, so a stock that
+// drops by exactly its payout leaves buy-&-hold whole.
+func TestBuyHold_CreditsDividends(t *testing.T) {
+	bars := closes(100, 100, 98, 98)
+	bars[2].Dividend = 2
+	r := Simulate(bars, BuyHold(), start, 0)
+	if !approx(r.Dividends, 200, 1e-6) || !approx(r.Trace[2].Dividend, 200, 1e-6) {
+		t.Fatalf("100 shares × $2 should credit $200; got %.2f (trace %.2f)", r.Dividends, r.Trace[2].Dividend)
+	}
+	if !approx(r.EndEquity, start, 1e-6) {
+		t.Fatalf("price drop offset by dividend should end at $%.0f; got $%.2f", start, r.EndEquity)
+	}
+	if cash := Simulate(bars, AllCash(), start, 0); cash.Dividends != 0 {
+		t.Fatalf("a flat book earns no dividends; got %.2f", cash.Dividends)
+	}
+}
+
 func TestAllCash_PreservesCapital(t *testing.T) {
 	r := Simulate(closes(100, 50, 200), AllCash(), start, 50)
 	if !approx(r.EndEquity, start, 1e-6) {
@@ -15,6 +15,8 @@ import (
 	"net/url"
 	"strconv"
 	"time"
+
+	"stocktopus/internal/cassette"
 )
 
 const baseURL = "https://api.stlouisfed.org/fred"
@@ -54,7 +56,7 @@ type Client struct {
 func New(apiKey string) *Client {
 	return &Client{
 		apiKey: apiKey,
-		http:   &http.Client{Timeout: 15 * time.Second},
+		http:   cassette.Client(15 * time.Second),
 	}
 }
 
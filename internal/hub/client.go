@@ -130,6 +130,7 @@ func (c *Client) Send(data []byte) bool {
 	case c.send <- data:
 		return true
 	default:
+		dropped.Inc()
 		c.logger.Warn("send buffer full, dropping message")
 		return false
 	}
@@ -3,7 +3,21 @@ package hub
 import (
 	"encoding/json"
 	"log/slog"
+	"strings"
 	"sync"
+
+	"stocktopus/internal/metrics"
+)
+
+var (
+	clientsGauge = metrics.NewGauge("stocktopus_hub_clients", "Connected WebSocket clients.")
+	topicsGauge  = metrics.NewGauge("stocktopus_hub_topics", "Topics with at least one subscriber.")
+	queueDepth   = metrics.NewGauge("stocktopus_hub_publish_queue_depth",
+		"Messages waiting in the hub's publish queue, sampled as each is delivered.")
+	published = metrics.NewCounter("stocktopus_hub_messages_published_total",
+		"Messages published, by topic prefix (quote, news, alerts, ...).", "prefix")
+	dropped = metrics.NewCounter("stocktopus_hub_messages_dropped_total",
+		"Messages dropped because a client's send buffer was full.")
 )
 
 // SubscriptionHandler is called when the first client subscribes to a topic
@@ -59,6 +73,7 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.register:
 			h.clients[client] = true
+			clientsGauge.Set(float64(len(h.clients)))
 			h.logger.Info("client registered", "client", client.ID(), "total", len(h.clients))
 
 		case client := <-h.unregister:
@@ -69,6 +84,8 @@ func (h *Hub) Run() {
 				}
 				delete(h.clients, client)
 				close(client.send)
+				clientsGauge.Set(float64(len(h.clients)))
+				topicsGauge.Set(float64(len(h.topics)))
 				h.logger.Info("client unregistered", "client", client.ID(), "total", len(h.clients))
 			}
 
@@ -78,8 +95,12 @@ func (h *Hub) Run() {
 			} else {
 				h.removeSub(sub.client, sub.topic)
 			}
+			topicsGauge.Set(float64(len(h.topics)))
 
 		case pub := <-h.publish:
+			queueDepth.Set(float64(len(h.publish)))
+			prefix, _, _ := strings.Cut(pub.topic, ":")
+			published.Inc(prefix)
 			if subscribers, ok := h.topics[pub.topic]; ok {
 				for client := range subscribers {
 					client.Send(pub.data)
@@ -0,0 +1,131 @@
+// Package indicators holds the technical indicators shared by the analyst
+// agents, the watchlist columns and the screener. Every function reads a
+// chronological series and describes its most recent point.
+package indicators
+
+import "stocktopus/internal/model"
+
+// Closes returns the closing prices of bars.
+func Closes(bars []model.OHLCV) []float64 {
+	out := make([]float64, len(bars))
+	for i, b := range bars {
+		out[i] = b.Close
+	}
+	return out
+}
+
+// SMA is the simple moving average of the last period values, or 0 when
+// there are fewer.
+func SMA(data []float64, period int) float64 {
+	if period <= 0 || len(data) < period {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range data[len(data)-period:] {
+		sum += v
+	}
+	return sum / float64(period)
+}
+
+// EMA is the exponential moving average seeded with the SMA of the first
+// period values, or 0 when there are fewer.
+func EMA(data []float64, period int) float64 {
+	if period <= 0 || len(data) < period {
+		return 0
+	}
+	k := 2.0 / float64(period+1)
+	e := SMA(data[:period], period)
+	for _, v := range data[period:] {
+		e = v*k + e*(1-k)
+	}
+	return e
+}
+
+// RSI is the relative strength index over the last period changes, using
+// simple averages of gains and losses. It is 50 when the series is too
+// short.
+func RSI(data []float64, period int) float64 {
+	if period <= 0 || len(data) < period+1 {
+		return 50
+	}
+	gains, losses := 0.0, 0.0
+	for i := len(data) - period; i < len(data); i++ {
+		change := data[i] - data[i-1]
+		if change > 0 {
+			gains += change
+		} else {
+			losses -= change
+		}
+	}
+	if losses == 0 {
+		return 100
+	}
+	rs := (gains / float64(period)) / (losses / float64(period))
+	return 100 - 100/(1+rs)
+}
+
+// ATR is the average true range over the last period bars, or 0 when there
+// are not period+1 of them.
+func ATR(bars []model.OHLCV, period int) float64 {
+	if period <= 0 || len(bars) < period+1 {
+		return 0
+	}
+	sum := 0.0
+	for i := len(bars) - period; i < len(bars); i++ {
+		tr := bars[i].High - bars[i].Low
+		prevClose := bars[i-1].Close
+		if bars[i].High-prevClose > tr {
+			tr = bars[i].High - prevClose
+		}
+		if prevClose-bars[i].Low > tr {
+			tr = prevClose - bars[i].Low
+		}
+		sum += tr
+	}
+	return sum / float64(period)
+}
+
+// PctChange is the percent change of the last value against the one
+// periods earlier, or 0 when the series is too short.
+func PctChange(data []float64, periods int) float64 {
+	if periods <= 0 || len(data) <= periods {
+		return 0
+	}
+	old := data[len(data)-1-periods]
+	if old == 0 {
+		return 0
+	}
+	return (data[len(data)-1] - old) / old * 100
+}
+
+// HighLow is the highest high and lowest low of the last n bars.
+func HighLow(bars []model.OHLCV, n int) (high, low float64) {
+	if n > len(bars) {
+		n = len(bars)
+	}
+	for i, b := range bars[len(bars)-n:] {
+		if i == 0 || b.High > high {
+			high = b.High
+		}
+		if i == 0 || b.Low < low {
+			low = b.Low
+		}
+	}
+	return high, low
+}
+
+// RelativeVolume is the last bar's volume over the average volume of the
+// period bars before it, or 0 when there are not enough bars.
+func RelativeVolume(bars []model.OHLCV, period int) float64 {
+	if period <= 0 || len(bars) < period+1 {
+		return 0
+	}
+	var sum int64
+	for _, b := range bars[len(bars)-1-period : len(bars)-1] {
+		sum += b.Volume
+	}
+	if sum == 0 {
+		return 0
+	}
+	return float64(bars[len(bars)-1].Volume) / (float64(sum) / float64(period))
+}
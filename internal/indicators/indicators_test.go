@@ -0,0 +1,61 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"stocktopus/internal/model"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestMovingAverages(t *testing.T) {
+	data := []float64{1, 2, 3, 4, 5}
+	if got := SMA(data, 3); !near(got, 4) {
+		t.Errorf("SMA = %v, want 4", got)
+	}
+	if got := SMA(data, 6); got != 0 {
+		t.Errorf("short SMA = %v, want 0", got)
+	}
+	// Seeded at SMA(1,2,3)=2, then k=0.5: 3, 4.
+	if got := EMA(data, 3); !near(got, 4) {
+		t.Errorf("EMA = %v, want 4", got)
+	}
+	if got := PctChange(data, 4); !near(got, 400) {
+		t.Errorf("PctChange = %v, want 400", got)
+	}
+}
+
+func TestRSI(t *testing.T) {
+	if got := RSI([]float64{1, 2, 3, 4}, 3); got != 100 {
+		t.Errorf("all gains = %v, want 100", got)
+	}
+	// Gains 2, losses 1 over three changes: RS 2, RSI 66.67.
+	if got := RSI([]float64{10, 12, 11, 11}, 3); !near(got, 100-100.0/3) {
+		t.Errorf("RSI = %v", got)
+	}
+	if got := RSI([]float64{1}, 14); got != 50 {
+		t.Errorf("short RSI = %v, want 50", got)
+	}
+}
+
+func TestBarIndicators(t *testing.T) {
+	bars := []model.OHLCV{
+		{High: 11, Low: 9, Close: 10, Volume: 100},
+		{High: 12, Low: 10, Close: 11, Volume: 100},
+		{High: 15, Low: 11, Close: 14, Volume: 300},
+	}
+	// True ranges 2 and 4.
+	if got := ATR(bars, 2); !near(got, 3) {
+		t.Errorf("ATR = %v, want 3", got)
+	}
+	if high, low := HighLow(bars, 10); high != 15 || low != 9 {
+		t.Errorf("HighLow = %v, %v", high, low)
+	}
+	if high, low := HighLow(bars, 1); high != 15 || low != 11 {
+		t.Errorf("HighLow(1) = %v, %v", high, low)
+	}
+	if got := RelativeVolume(bars, 2); !near(got, 3) {
+		t.Errorf("RelativeVolume = %v, want 3", got)
+	}
+}
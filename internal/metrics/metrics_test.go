@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	r := NewRegistry()
+	errs := r.NewCounter("test_errors_total", "Errors by type.", "provider", "type")
+	depth := r.NewGauge("test_queue_depth", "Queue depth.")
+	lat := r.NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+	r.NewGaugeFunc("test_up", "Always up.", func() float64 { return 1 })
+
+	errs.Inc("fmp", "rate_limit")
+	errs.Add(2, "fmp", "rate_limit")
+	errs.Inc("polygon", `odd"type`)
+	depth.Set(7)
+	lat.Observe(0.05, "GetQuotes")
+	lat.Observe(0.1, "GetQuotes") // bounds are inclusive
+	lat.Observe(3, "GetQuotes")
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	got := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE test_errors_total counter\n",
+		`test_errors_total{provider="fmp",type="rate_limit"} 3` + "\n",
+		`test_errors_total{provider="polygon",type="odd\"type"} 1` + "\n",
+		"# TYPE test_queue_depth gauge\ntest_queue_depth 7\n",
+		"# TYPE test_latency_seconds histogram\n",
+		`test_latency_seconds_bucket{op="GetQuotes",le="0.1"} 2` + "\n",
+		`test_latency_seconds_bucket{op="GetQuotes",le="1"} 2` + "\n",
+		`test_latency_seconds_bucket{op="GetQuotes",le="+Inf"} 3` + "\n",
+		`test_latency_seconds_sum{op="GetQuotes"} 3.15` + "\n",
+		`test_latency_seconds_count{op="GetQuotes"} 3` + "\n",
+		"test_up 1\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("exposition missing %q\n%s", want, got)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type = %q", ct)
+	}
+	if strings.Index(got, "test_errors_total") > strings.Index(got, "test_latency_seconds") {
+		t.Error("families should be sorted by name")
+	}
+}
+
+func TestDuplicateAndArity(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("dup_total", "x", "a")
+	mustPanic(t, "duplicate name", func() { r.NewCounter("dup_total", "x") })
+	mustPanic(t, "wrong label count", func() { c.Inc() })
+}
+
+func mustPanic(t *testing.T, what string, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s should panic", what)
+		}
+	}()
+	fn()
+}
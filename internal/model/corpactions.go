@@ -0,0 +1,38 @@
+package model
+
+// Split is a stock split effective on Date: every Denominator shares
+// became Numerator shares (4-for-1 is 4/1, a 1-for-10 reverse split 1/10).
+type Split struct {
+	Date        string  `json:"date"`
+	Numerator   float64 `json:"numerator"`
+	Denominator float64 `json:"denominator"`
+}
+
+// Ratio is new shares per old share; prices before Date divide by it.
+// Zero or malformed ratios read as 1 so a bad row can't zero a series.
+func (s Split) Ratio() float64 {
+	if s.Numerator <= 0 || s.Denominator <= 0 {
+		return 1
+	}
+	return s.Numerator / s.Denominator
+}
+
+// Dividend is a cash distribution per share. ExDate is the first session
+// the stock trades without it — the date that matters for price
+// adjustment and for who gets paid. Amount is as declared, not adjusted
+// for later splits.
+type Dividend struct {
+	ExDate          string  `json:"exDate"`
+	PayDate         string  `json:"payDate,omitempty"`
+	RecordDate      string  `json:"recordDate,omitempty"`
+	DeclarationDate string  `json:"declarationDate,omitempty"`
+	Amount          float64 `json:"amount"`
+}
+
+// SymbolChange records a ticker rename effective on Date.
+type SymbolChange struct {
+	Date      string `json:"date"`
+	OldSymbol string `json:"oldSymbol"`
+	NewSymbol string `json:"newSymbol"`
+	Name      string `json:"name,omitempty"`
+}
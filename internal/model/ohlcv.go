@@ -8,4 +8,8 @@ type OHLCV struct {
 	Low    float64 `json:"low"`
 	Close  float64 `json:"close"`
 	Volume int64   `json:"volume"`
+
+	// Dividend is the cash per share going ex on this bar, in the bar's
+	// own price units. Providers don't fill it; corpactions.Adjust does.
+	Dividend float64 `json:"dividend,omitempty"`
 }
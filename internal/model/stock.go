@@ -17,14 +17,36 @@ import "time"
 // - Volume: Must be >= 0
 // - Timestamp: Must not be in future
 type Quote struct {
-	Symbol        string    // Stock ticker symbol (e.g., "AAPL")
-	Price         float64   // Current price in dollars
-	Bid           float64   // Bid price
-	Ask           float64   // Ask price
-	Volume        int64     // Trading volume in shares
-	Timestamp     time.Time // Quote timestamp (UTC)
-	Change        float64   // Absolute price change from previous close (dollars)
-	ChangePercent float64   // Percentage change as decimal (0.0123 = 1.23%)
+	Symbol        string      // Stock ticker symbol (e.g., "AAPL")
+	Price         float64     // Current price in dollars
+	Bid           float64     // Bid price
+	Ask           float64     // Ask price
+	Volume        int64       // Trading volume in shares
+	Timestamp     time.Time   // Quote timestamp (UTC)
+	Change        float64     // Absolute price change from previous close (dollars)
+	ChangePercent float64     // Percentage change as decimal (0.0123 = 1.23%)
+	Flags         []QuoteFlag // Data-quality warnings; empty for a clean quote
+}
+
+// QuoteFlag marks a quote that passed validation but looks suspect. The
+// UI shows flagged quotes with a warning instead of presenting them as
+// clean.
+type QuoteFlag string
+
+const (
+	FlagStale    QuoteFlag = "stale"    // timestamp too old while the market is open
+	FlagJump     QuoteFlag = "jump"     // implausible move from the last seen price
+	FlagDisagree QuoteFlag = "disagree" // consensus provider reports a different price
+)
+
+// Flag adds f to the quote's flags once.
+func (q *Quote) Flag(f QuoteFlag) {
+	for _, have := range q.Flags {
+		if have == f {
+			return
+		}
+	}
+	q.Flags = append(q.Flags, f)
 }
 
 // Snapshot represents an extended market snapshot with daily metrics.
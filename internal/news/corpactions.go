@@ -0,0 +1,96 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"stocktopus/internal/model"
+)
+
+// GetSplits returns a symbol's stock split history from /stable/splits.
+func (c *Client) GetSplits(ctx context.Context, symbol string) ([]model.Split, error) {
+	raw, err := c.fetchJSON(ctx, "/stable/splits", url.Values{"symbol": {symbol}})
+	if err != nil {
+		return nil, fmt.Errorf("splits fetch: %w", err)
+	}
+	var rows []struct {
+		Date        string  `json:"date"`
+		Numerator   float64 `json:"numerator"`
+		Denominator float64 `json:"denominator"`
+	}
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("splits parse: %w", err)
+	}
+	out := make([]model.Split, 0, len(rows))
+	for _, r := range rows {
+		if r.Date == "" {
+			continue
+		}
+		out = append(out, model.Split{Date: r.Date, Numerator: r.Numerator, Denominator: r.Denominator})
+	}
+	return out, nil
+}
+
+// GetDividends returns a symbol's cash dividend history from
+// /stable/dividends. FMP's `date` is the ex-date; `dividend` is the amount
+// as declared (we adjust for splits ourselves, so `adjDividend` is unused).
+func (c *Client) GetDividends(ctx context.Context, symbol string) ([]model.Dividend, error) {
+	raw, err := c.fetchJSON(ctx, "/stable/dividends", url.Values{"symbol": {symbol}})
+	if err != nil {
+		return nil, fmt.Errorf("dividends fetch: %w", err)
+	}
+	var rows []struct {
+		Date            string  `json:"date"`
+		RecordDate      string  `json:"recordDate"`
+		PaymentDate     string  `json:"paymentDate"`
+		DeclarationDate string  `json:"declarationDate"`
+		Dividend        float64 `json:"dividend"`
+	}
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("dividends parse: %w", err)
+	}
+	out := make([]model.Dividend, 0, len(rows))
+	for _, r := range rows {
+		if r.Date == "" {
+			continue
+		}
+		out = append(out, model.Dividend{
+			ExDate:          r.Date,
+			PayDate:         r.PaymentDate,
+			RecordDate:      r.RecordDate,
+			DeclarationDate: r.DeclarationDate,
+			Amount:          r.Dividend,
+		})
+	}
+	return out, nil
+}
+
+// GetSymbolChanges returns the ticker renames involving symbol, as either
+// the old or the new ticker. FMP only serves the market-wide list, so this
+// filters it client-side.
+func (c *Client) GetSymbolChanges(ctx context.Context, symbol string) ([]model.SymbolChange, error) {
+	raw, err := c.fetchJSON(ctx, "/stable/symbol-change", nil)
+	if err != nil {
+		return nil, fmt.Errorf("symbol changes fetch: %w", err)
+	}
+	var rows []struct {
+		Date        string `json:"date"`
+		CompanyName string `json:"companyName"`
+		OldSymbol   string `json:"oldSymbol"`
+		NewSymbol   string `json:"newSymbol"`
+	}
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("symbol changes parse: %w", err)
+	}
+	var out []model.SymbolChange
+	for _, r := range rows {
+		if !strings.EqualFold(r.OldSymbol, symbol) && !strings.EqualFold(r.NewSymbol, symbol) {
+			continue
+		}
+		out = append(out, model.SymbolChange{Date: r.Date, OldSymbol: r.OldSymbol, NewSymbol: r.NewSymbol, Name: r.CompanyName})
+	}
+	return out, nil
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"stocktopus/internal/cassette"
 	"stocktopus/internal/model"
 	"strconv"
 	"strings"
@@ -167,7 +168,7 @@ func (c *Client) searchEndpoint(ctx context.Context, endpoint, query string, lim
 		return nil, err
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -208,7 +209,7 @@ func (c *Client) GetHistoricalEOD(ctx context.Context, symbol, from, to string)
 		return nil, fmt.Errorf("eod request: %w", err)
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("eod fetch: %w", err)
 	}
@@ -249,7 +250,7 @@ func (c *Client) fetchJSON(ctx context.Context, endpoint string, params url.Valu
 		return nil, err
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -402,6 +403,25 @@ func (c *Client) GetCashFlow(ctx context.Context, symbol string, limit int) (jso
 	return c.fetchJSON(ctx, "/stable/cash-flow-statement", params)
 }
 
+// GetQuarterlyIncomeStatement returns quarterly income statements, newest
+// first. Rows carry `period` ("Q1".."Q4") and `fiscalYear`.
+func (c *Client) GetQuarterlyIncomeStatement(ctx context.Context, symbol string, limit int) (json.RawMessage, error) {
+	params := url.Values{"symbol": {symbol}, "period": {"quarter"}, "limit": {strconv.Itoa(limit)}}
+	return c.fetchJSON(ctx, "/stable/income-statement", params)
+}
+
+// GetQuarterlyBalanceSheet returns quarterly balance sheets.
+func (c *Client) GetQuarterlyBalanceSheet(ctx context.Context, symbol string, limit int) (json.RawMessage, error) {
+	params := url.Values{"symbol": {symbol}, "period": {"quarter"}, "limit": {strconv.Itoa(limit)}}
+	return c.fetchJSON(ctx, "/stable/balance-sheet-statement", params)
+}
+
+// GetQuarterlyCashFlow returns quarterly cash flow statements.
+func (c *Client) GetQuarterlyCashFlow(ctx context.Context, symbol string, limit int) (json.RawMessage, error) {
+	params := url.Values{"symbol": {symbol}, "period": {"quarter"}, "limit": {strconv.Itoa(limit)}}
+	return c.fetchJSON(ctx, "/stable/cash-flow-statement", params)
+}
+
 // GetAnalystEstimates returns forward analyst estimates.
 func (c *Client) GetAnalystEstimates(ctx context.Context, symbol string, limit int) (json.RawMessage, error) {
 	params := url.Values{"symbol": {symbol}, "period": {"annual"}, "limit": {strconv.Itoa(limit)}}
@@ -439,6 +459,13 @@ func (c *Client) GetFinancials(ctx context.Context, symbol, statementType string
 	}
 }
 
+// GetEarnings returns a symbol's earnings reports, newest first, including
+// scheduled ones that haven't happened yet (their epsActual is null).
+func (c *Client) GetEarnings(ctx context.Context, symbol string, limit int) (json.RawMessage, error) {
+	params := url.Values{"symbol": {symbol}, "limit": {strconv.Itoa(limit)}}
+	return c.fetchJSON(ctx, "/stable/earnings", params)
+}
+
 // GetSECFilings searches SEC filings for a symbol within a date range.
 func (c *Client) GetSECFilings(ctx context.Context, symbol, from, to string) (json.RawMessage, error) {
 	params := url.Values{"symbol": {symbol}}
@@ -484,7 +511,7 @@ func (c *Client) GetIntradayChart(ctx context.Context, symbol, interval, from, t
 		return nil, fmt.Errorf("intraday request: %w", err)
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("intraday fetch: %w", err)
 	}
@@ -562,6 +589,28 @@ type Client struct {
 	baseURL   string
 	geminiKey string
 	http      *http.Client
+	limiter   Limiter
+}
+
+// Limiter gates outbound FMP requests. budget.Scheduler implements it.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// SetLimiter routes every FMP request through l. Gemini calls made by the
+// search fallback are not FMP traffic and bypass it.
+func (c *Client) SetLimiter(l Limiter) {
+	c.limiter = l
+}
+
+// do sends an FMP request once the limiter admits it.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	return c.http.Do(req)
 }
 
 func New(apiKey, baseURL string) *Client {
@@ -571,9 +620,7 @@ func New(apiKey, baseURL string) *Client {
 	return &Client{
 		apiKey:  apiKey,
 		baseURL: baseURL,
-		http: &http.Client{
-			Timeout: 15 * time.Second,
-		},
+		http:    cassette.Client(15 * time.Second),
 	}
 }
 
@@ -650,7 +697,7 @@ func (c *Client) getNewsInternal(ctx context.Context, cat Category, symbol strin
 		return nil, fmt.Errorf("news request: %w", err)
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("news fetch: %w", err)
 	}
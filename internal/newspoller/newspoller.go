@@ -8,11 +8,17 @@ import (
 	"sync"
 	"time"
 
+	"stocktopus/internal/budget"
+	"stocktopus/internal/calendars"
 	"stocktopus/internal/hub"
+	"stocktopus/internal/metrics"
 	"stocktopus/internal/model"
 	"stocktopus/internal/news"
 )
 
+var cycleDuration = metrics.NewHistogram("stocktopus_news_poller_cycle_seconds",
+	"News poller cycle duration across all due categories.", metrics.DefaultBuckets)
+
 // Poller periodically fetches news for subscribed categories and publishes
 // new articles to the hub. All connected clients receive updates simultaneously.
 type Poller struct {
@@ -22,10 +28,22 @@ type Poller struct {
 	interval time.Duration
 
 	categories map[news.Category]bool
-	lastSeen   map[news.Category]map[string]bool // category → set of seen URLs
+	pinned     map[news.Category]bool            // polled regardless of subscribers
+	lastSeen   map[news.Category]map[string]bool // category → set of seen URLs; nil until its first poll
+	onNewItems func(news.Category, []model.NewsItem)
 	mu         sync.RWMutex
+
+	closedInterval time.Duration               // poll period while the category's market is shut
+	lastPolled     map[news.Category]time.Time // last scheduled poll per category
+
+	budget *budget.Scheduler // nil = fixed interval
+	wake   chan struct{}     // interval changed; re-arm the timer
 }
 
+// DefaultClosedInterval is the polling period for a category whose
+// market is closed. Overnight and weekend news still arrives, just slower.
+const DefaultClosedInterval = 15 * time.Minute
+
 func New(client *news.Client, h *hub.Hub, interval time.Duration, logger *slog.Logger) *Poller {
 	return &Poller{
 		client:     client,
@@ -33,8 +51,87 @@ func New(client *news.Client, h *hub.Hub, interval time.Duration, logger *slog.L
 		logger:     logger.With("component", "newspoller"),
 		interval:   interval,
 		categories: make(map[news.Category]bool),
+		pinned:     make(map[news.Category]bool),
 		lastSeen:   make(map[news.Category]map[string]bool),
+
+		closedInterval: DefaultClosedInterval,
+		lastPolled:     make(map[news.Category]time.Time),
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// SetBudget lets the shared FMP budget stretch the polling interval as
+// the plan's per-minute window fills.
+func (p *Poller) SetBudget(b *budget.Scheduler) {
+	p.budget = b
+}
+
+// SetInterval changes the polling period. A running loop re-arms with it
+// straight away rather than after the current wait.
+func (p *Poller) SetInterval(d time.Duration) {
+	p.mu.Lock()
+	p.interval = d
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Poller) currentInterval() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.interval
+}
+
+// SetClosedInterval sets how often categories are polled while their
+// market is closed. Zero polls every tick regardless of market hours.
+func (p *Poller) SetClosedInterval(d time.Duration) {
+	p.mu.Lock()
+	p.closedInterval = d
+	p.mu.Unlock()
+}
+
+// categoryVenue is the market whose hours govern a category's news flow.
+// Equity feeds follow the US session; crypto never closes.
+func categoryVenue(cat news.Category) *calendars.Venue {
+	switch cat {
+	case news.Crypto:
+		return calendars.Crypto
+	case news.Forex:
+		return calendars.FX
+	default:
+		return calendars.NYSE
+	}
+}
+
+// SetNewItemsCallback registers a function called with every batch of
+// newly-seen articles, after they've been published to the hub. The news
+// alert engine hangs off this.
+func (p *Poller) SetNewItemsCallback(fn func(news.Category, []model.NewsItem)) {
+	p.mu.Lock()
+	p.onNewItems = fn
+	p.mu.Unlock()
+}
+
+// Pin keeps a category on the polling loop even when no client is
+// subscribed — alert watches need the feed whether or not a news page is
+// open.
+func (p *Poller) Pin(cat news.Category) {
+	p.mu.Lock()
+	p.pinned[cat] = true
+	p.mu.Unlock()
+}
+
+// Unpin drops a pinned category; it keeps polling while a client is
+// subscribed to it.
+func (p *Poller) Unpin(cat news.Category) {
+	p.mu.Lock()
+	delete(p.pinned, cat)
+	if !p.categories[cat] {
+		delete(p.lastSeen, cat)
 	}
+	p.mu.Unlock()
 }
 
 // OnFirstSubscribe is called when a client subscribes to a news topic (e.g. "news:stock").
@@ -46,15 +143,13 @@ func (p *Poller) OnFirstSubscribe(topic string) {
 
 	p.mu.Lock()
 	p.categories[cat] = true
-	if p.lastSeen[cat] == nil {
-		p.lastSeen[cat] = make(map[string]bool)
-	}
 	p.mu.Unlock()
 
 	p.logger.Info("watching news category", "category", cat)
 
 	// Fetch immediately for the new subscriber
-	go p.pollCategory(context.Background(), cat)
+	ctx := budget.WithCaller(context.Background(), "newspoller", budget.Interactive)
+	go p.pollCategory(ctx, cat)
 }
 
 // OnLastUnsubscribe is called when the last client unsubscribes from a news topic.
@@ -66,6 +161,9 @@ func (p *Poller) OnLastUnsubscribe(topic string) {
 
 	p.mu.Lock()
 	delete(p.categories, cat)
+	if !p.pinned[cat] {
+		delete(p.lastSeen, cat)
+	}
 	p.mu.Unlock()
 
 	p.logger.Info("unwatching news category", "category", cat)
@@ -73,29 +171,51 @@ func (p *Poller) OnLastUnsubscribe(topic string) {
 
 // Run starts the polling loop.
 func (p *Poller) Run(ctx context.Context) {
-	ticker := time.NewTicker(p.interval)
-	defer ticker.Stop()
+	ctx = budget.WithCaller(ctx, "newspoller", budget.Background)
+	timer := time.NewTimer(p.budget.Scale(p.currentInterval()))
+	defer timer.Stop()
 
-	p.logger.Info("news poller started", "interval", p.interval)
+	p.logger.Info("news poller started", "interval", p.currentInterval())
 
 	for {
 		select {
 		case <-ctx.Done():
 			p.logger.Info("news poller stopped")
 			return
-		case <-ticker.C:
+		case <-timer.C:
+			start := time.Now()
 			p.pollAll(ctx)
+			cycleDuration.ObserveSince(start)
+			timer.Reset(p.budget.Scale(p.currentInterval()))
+		case <-p.wake:
+			timer.Reset(p.budget.Scale(p.currentInterval()))
 		}
 	}
 }
 
 func (p *Poller) pollAll(ctx context.Context) {
-	p.mu.RLock()
-	cats := make([]news.Category, 0, len(p.categories))
+	now := time.Now()
+	p.mu.Lock()
+	cats := make([]news.Category, 0, len(p.categories)+len(p.pinned))
+	due := func(cat news.Category) bool {
+		if p.closedInterval > 0 && !categoryVenue(cat).Session(now).Active() &&
+			now.Sub(p.lastPolled[cat]) < p.closedInterval {
+			return false
+		}
+		p.lastPolled[cat] = now
+		return true
+	}
 	for cat := range p.categories {
-		cats = append(cats, cat)
+		if due(cat) {
+			cats = append(cats, cat)
+		}
 	}
-	p.mu.RUnlock()
+	for cat := range p.pinned {
+		if !p.categories[cat] && due(cat) {
+			cats = append(cats, cat)
+		}
+	}
+	p.mu.Unlock()
 
 	for _, cat := range cats {
 		p.pollCategory(ctx, cat)
@@ -111,7 +231,8 @@ func (p *Poller) pollCategory(ctx context.Context, cat news.Category) {
 
 	p.mu.Lock()
 	seen := p.lastSeen[cat]
-	if seen == nil {
+	first := seen == nil
+	if first {
 		seen = make(map[string]bool)
 		p.lastSeen[cat] = seen
 	}
@@ -123,6 +244,13 @@ func (p *Poller) pollCategory(ctx context.Context, cat news.Category) {
 			newItems = append(newItems, item)
 		}
 	}
+	onNewItems := p.onNewItems
+	if first {
+		// A category's first poll after it starts being polled is backlog,
+		// not incoming news: it seeds seen and reaches subscribers, but
+		// not the alert engine.
+		onNewItems = nil
+	}
 	p.mu.Unlock()
 
 	if len(newItems) == 0 {
@@ -144,6 +272,10 @@ func (p *Poller) pollCategory(ctx context.Context, cat news.Category) {
 		return
 	}
 	p.hub.Publish("news:"+string(cat), data)
+
+	if onNewItems != nil {
+		onNewItems(cat, newItems)
+	}
 }
 
 func topicToCategory(topic string) news.Category {
@@ -0,0 +1,64 @@
+package newspoller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"stocktopus/internal/hub"
+	"stocktopus/internal/model"
+	"stocktopus/internal/news"
+)
+
+func TestFirstPollSeedsWithoutAlerting(t *testing.T) {
+	var mu sync.Mutex
+	stories := []string{"a", "b"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		items := make([]map[string]string, len(stories))
+		for i, s := range stories {
+			items[i] = map[string]string{"title": s, "url": "https://example.com/" + s, "publishedDate": "2026-01-02 15:04:05"}
+		}
+		json.NewEncoder(w).Encode(items)
+	}))
+	defer srv.Close()
+
+	p := New(news.New("key", srv.URL), hub.New(slog.Default()), 0, slog.Default())
+	var alerted []string
+	p.SetNewItemsCallback(func(_ news.Category, items []model.NewsItem) {
+		for _, it := range items {
+			alerted = append(alerted, it.Title)
+		}
+	})
+	ctx := context.Background()
+
+	p.Pin(news.General)
+	p.pollCategory(ctx, news.General)
+	if len(alerted) != 0 {
+		t.Fatalf("backlog reached the alert callback: %v", alerted)
+	}
+	mu.Lock()
+	stories = append([]string{"c"}, stories...)
+	mu.Unlock()
+	p.pollCategory(ctx, news.General)
+	if fmt.Sprint(alerted) != "[c]" {
+		t.Fatalf("alerted %v, want only the new story", alerted)
+	}
+
+	// Re-pinning after the category dropped off the loop starts over.
+	p.Unpin(news.General)
+	p.Pin(news.General)
+	mu.Lock()
+	stories = append([]string{"d"}, stories...)
+	mu.Unlock()
+	p.pollCategory(ctx, news.General)
+	if fmt.Sprint(alerted) != "[c]" {
+		t.Errorf("alerted %v after re-pinning, want no backlog", alerted)
+	}
+}
@@ -10,7 +10,10 @@ import (
 	"sync"
 	"time"
 
+	"stocktopus/internal/budget"
+	"stocktopus/internal/calendars"
 	"stocktopus/internal/hub"
+	"stocktopus/internal/metrics"
 	"stocktopus/internal/model"
 	"stocktopus/internal/provider"
 )
@@ -22,11 +25,35 @@ type Poller struct {
 	interval time.Duration
 	tmpl     *template.Template
 
-	symbols map[string]int // symbol -> ref count
+	symbols map[string]int  // symbol -> ref count
+	pinned  map[string]bool // polled with or without subscribers
 	mu      sync.RWMutex
 	cancel  context.CancelFunc
+	wake    chan struct{} // interval changed; re-arm the timer
+
+	// Market-hours back-off: symbols whose venue has no session running
+	// are refreshed every closedInterval instead of every tick.
+	venueOf        func(symbol string) *calendars.Venue
+	closedInterval time.Duration
+	lastFetch      map[string]time.Time
+
+	budget *budget.Scheduler // nil = fixed interval
+
+	onQuotes func([]*model.Quote)
 }
 
+var (
+	cycleDuration = metrics.NewHistogram("stocktopus_quote_poller_cycle_seconds",
+		"Quote poller cycle duration: fetch, publish and record.", metrics.DefaultBuckets)
+	cycleSymbols = metrics.NewGauge("stocktopus_quote_poller_symbols",
+		"Symbols fetched in the most recent quote poller cycle.")
+)
+
+// DefaultClosedInterval is how often a symbol is re-quoted while its
+// venue is closed. Prices don't move, but a slow refresh still picks up
+// late corrections and the official close.
+const DefaultClosedInterval = 15 * time.Minute
+
 func New(p provider.StockProvider, h *hub.Hub, interval time.Duration, logger *slog.Logger) *Poller {
 	tmpl := template.Must(template.New("quote_row").Parse(quoteRowTemplate))
 
@@ -37,11 +64,77 @@ func New(p provider.StockProvider, h *hub.Hub, interval time.Duration, logger *s
 		interval: interval,
 		tmpl:     tmpl,
 		symbols:  make(map[string]int),
+		pinned:   make(map[string]bool),
+		wake:     make(chan struct{}, 1),
+
+		venueOf:        func(symbol string) *calendars.Venue { return calendars.ForSymbol(symbol, "") },
+		closedInterval: DefaultClosedInterval,
+		lastFetch:      make(map[string]time.Time),
 	}
 
 	return poller
 }
 
+// SetVenueResolver overrides how a symbol maps to its trading venue. The
+// default only looks at the symbol's shape; main wires one that consults
+// the stored security type so crypto and forex are recognised reliably.
+func (p *Poller) SetVenueResolver(fn func(symbol string) *calendars.Venue) {
+	p.mu.Lock()
+	p.venueOf = fn
+	p.mu.Unlock()
+}
+
+// SetQuotesCallback registers a function called with every batch of
+// quotes after they've been published. The tick recorder hangs off this.
+func (p *Poller) SetQuotesCallback(fn func([]*model.Quote)) {
+	p.mu.Lock()
+	p.onQuotes = fn
+	p.mu.Unlock()
+}
+
+// SetBudget lets the shared FMP budget stretch the polling interval as
+// the plan's per-minute window fills.
+func (p *Poller) SetBudget(b *budget.Scheduler) {
+	p.budget = b
+}
+
+// SetInterval changes the polling period. A running loop re-arms with it
+// straight away rather than after the current wait.
+func (p *Poller) SetInterval(d time.Duration) {
+	p.mu.Lock()
+	p.interval = d
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Poller) currentInterval() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.interval
+}
+
+// SetPinned replaces the set of symbols polled whether or not anyone is
+// subscribed — the configured watch tickers.
+func (p *Poller) SetPinned(symbols []string) {
+	p.mu.Lock()
+	p.pinned = make(map[string]bool, len(symbols))
+	for _, s := range symbols {
+		p.pinned[strings.ToUpper(s)] = true
+	}
+	p.mu.Unlock()
+}
+
+// SetClosedInterval sets the refresh period for symbols whose venue is
+// closed. Zero disables the back-off.
+func (p *Poller) SetClosedInterval(d time.Duration) {
+	p.mu.Lock()
+	p.closedInterval = d
+	p.mu.Unlock()
+}
+
 // OnFirstSubscribe is called by the hub when a topic gets its first subscriber.
 func (p *Poller) OnFirstSubscribe(topic string) {
 	symbol := topicToSymbol(topic)
@@ -55,8 +148,9 @@ func (p *Poller) OnFirstSubscribe(topic string) {
 
 	p.logger.Info("watching symbol", "symbol", symbol)
 
-	// Fetch immediately for the new subscriber
-	go p.fetchAndPublish(context.Background(), []string{symbol})
+	// Fetch immediately for the new subscriber — a page is waiting on it.
+	ctx := budget.WithCaller(context.Background(), "quote-poller", budget.Interactive)
+	go p.fetchAndPublish(ctx, []string{symbol})
 }
 
 // OnLastUnsubscribe is called by the hub when a topic loses its last subscriber.
@@ -68,6 +162,9 @@ func (p *Poller) OnLastUnsubscribe(topic string) {
 
 	p.mu.Lock()
 	delete(p.symbols, symbol)
+	if !p.pinned[symbol] {
+		delete(p.lastFetch, symbol)
+	}
 	p.mu.Unlock()
 
 	p.logger.Info("unwatching symbol", "symbol", symbol)
@@ -76,22 +173,27 @@ func (p *Poller) OnLastUnsubscribe(topic string) {
 // Run starts the polling loop.
 func (p *Poller) Run(ctx context.Context) {
 	ctx, p.cancel = context.WithCancel(ctx)
-	ticker := time.NewTicker(p.interval)
-	defer ticker.Stop()
+	ctx = budget.WithCaller(ctx, "quote-poller", budget.Background)
+	timer := time.NewTimer(p.budget.Scale(p.currentInterval()))
+	defer timer.Stop()
 
-	p.logger.Info("poller started", "interval", p.interval)
+	p.logger.Info("poller started", "interval", p.currentInterval())
 
 	for {
 		select {
 		case <-ctx.Done():
 			p.logger.Info("poller stopped")
 			return
-		case <-ticker.C:
-			symbols := p.activeSymbols()
-			if len(symbols) == 0 {
-				continue
+		case <-timer.C:
+			if symbols := p.dueSymbols(time.Now()); len(symbols) > 0 {
+				start := time.Now()
+				p.fetchAndPublish(ctx, symbols)
+				cycleDuration.ObserveSince(start)
+				cycleSymbols.Set(float64(len(symbols)))
 			}
-			p.fetchAndPublish(ctx, symbols)
+			timer.Reset(p.budget.Scale(p.currentInterval()))
+		case <-p.wake:
+			timer.Reset(p.budget.Scale(p.currentInterval()))
 		}
 	}
 }
@@ -99,10 +201,38 @@ func (p *Poller) Run(ctx context.Context) {
 func (p *Poller) activeSymbols() []string {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	symbols := make([]string, 0, len(p.symbols))
+	return p.watchedLocked()
+}
+
+// watchedLocked is every subscribed or pinned symbol. Caller holds mu.
+func (p *Poller) watchedLocked() []string {
+	symbols := make([]string, 0, len(p.symbols)+len(p.pinned))
 	for s := range p.symbols {
 		symbols = append(symbols, s)
 	}
+	for s := range p.pinned {
+		if _, dup := p.symbols[s]; !dup {
+			symbols = append(symbols, s)
+		}
+	}
+	return symbols
+}
+
+// dueSymbols narrows the active set to what's worth a request this tick:
+// every symbol whose venue is in a session (extended hours included), plus
+// closed-venue symbols not refreshed within closedInterval.
+func (p *Poller) dueSymbols(now time.Time) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	symbols := make([]string, 0, len(p.symbols)+len(p.pinned))
+	for _, s := range p.watchedLocked() {
+		if p.closedInterval > 0 && p.venueOf != nil && !p.venueOf(s).Session(now).Active() {
+			if now.Sub(p.lastFetch[s]) < p.closedInterval {
+				continue
+			}
+		}
+		symbols = append(symbols, s)
+	}
 	return symbols
 }
 
@@ -115,7 +245,24 @@ func (p *Poller) fetchAndPublish(ctx context.Context, symbols []string) {
 	quotes, err := p.provider.GetQuotes(ctx, symbols)
 	if err != nil {
 		p.logger.Error("fetch failed", "error", err, "symbols", symbols)
-		return
+		// A routed batch can fail for one asset class and still carry
+		// quotes for the rest.
+		if quotes == nil {
+			return
+		}
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	for _, s := range symbols {
+		if _, ok := p.symbols[s]; ok || p.pinned[s] {
+			p.lastFetch[s] = now
+		}
+	}
+	onQuotes := p.onQuotes
+	p.mu.Unlock()
+	if onQuotes != nil {
+		defer onQuotes(quotes)
 	}
 
 	for _, q := range quotes {
@@ -147,6 +294,13 @@ func (p *Poller) renderQuoteRow(q *model.Quote) (string, error) {
 		ChangePercent: fmt.Sprintf("%+.2f%%", q.ChangePercent*100),
 		PriceClass:    priceClass(q.Change),
 	}
+	if len(q.Flags) > 0 {
+		flags := make([]string, len(q.Flags))
+		for i, f := range q.Flags {
+			flags[i] = string(f)
+		}
+		data.Flags = strings.Join(flags, ", ")
+	}
 
 	var buf bytes.Buffer
 	if err := p.tmpl.Execute(&buf, data); err != nil {
@@ -162,9 +316,10 @@ type quoteRowData struct {
 	Change        string
 	ChangePercent string
 	PriceClass    string
+	Flags         string // comma-separated model.QuoteFlag values; empty when clean
 }
 
-const quoteRowTemplate = `<td id="quote-{{.Symbol}}-price" class="{{.PriceClass}}" hx-swap-oob="true">{{.Price}}</td><td id="quote-{{.Symbol}}-change" class="{{.PriceClass}}" hx-swap-oob="true">{{.Change}}</td><td id="quote-{{.Symbol}}-changepct" class="{{.PriceClass}}" hx-swap-oob="true">{{.ChangePercent}}</td>`
+const quoteRowTemplate = `<td id="quote-{{.Symbol}}-price" class="{{.PriceClass}}{{if .Flags}} quote-flagged{{end}}"{{if .Flags}} title="Suspect quote: {{.Flags}}"{{end}} hx-swap-oob="true">{{.Price}}</td><td id="quote-{{.Symbol}}-change" class="{{.PriceClass}}" hx-swap-oob="true">{{.Change}}</td><td id="quote-{{.Symbol}}-changepct" class="{{.PriceClass}}" hx-swap-oob="true">{{.ChangePercent}}</td>`
 
 func topicToSymbol(topic string) string {
 	if strings.HasPrefix(topic, "quote:") {
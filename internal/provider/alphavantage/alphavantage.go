@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"stocktopus/internal/cassette"
 	"stocktopus/internal/model"
 	"stocktopus/internal/provider"
 	"strings"
@@ -43,14 +44,7 @@ func NewProvider(config Config) *Provider {
 
 	return &Provider{
 		config: config,
-		client: &http.Client{
-			Timeout: config.Timeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
-			},
-		},
+		client: cassette.Client(config.Timeout),
 	}
 }
 
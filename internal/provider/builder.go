@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"stocktopus/internal/model"
+	"time"
 )
 
 // ProviderBuilder composes middleware around a base provider
@@ -13,6 +14,7 @@ import (
 //
 //	provider := NewProviderBuilder(baseProvider).
 //	    WithRateLimit(limiter).
+//	    WithValidation(validationConfig).
 //	    WithRetry(retryConfig).
 //	    WithCircuitBreaker(breakerConfig).
 //	    WithObservability(logger).
@@ -46,6 +48,20 @@ func (b *ProviderBuilder) WithCircuitBreaker(config CircuitBreakerConfig) *Provi
 	return b
 }
 
+// WithValidation wraps the provider with quote validation, rejecting
+// malformed quotes and flagging stale or implausible ones
+func (b *ProviderBuilder) WithValidation(config ValidationConfig) *ProviderBuilder {
+	b.provider = NewValidatingProvider(b.provider, config)
+	return b
+}
+
+// WithConsensus cross-checks quotes against a second provider and flags
+// disagreements beyond tolerance
+func (b *ProviderBuilder) WithConsensus(secondary StockProvider, tolerance float64) *ProviderBuilder {
+	b.provider = NewConsensusProvider(b.provider, secondary, tolerance)
+	return b
+}
+
 // WithObservability wraps the provider with structured logging and metrics
 func (b *ProviderBuilder) WithObservability(logger *slog.Logger) *ProviderBuilder {
 	b.provider = NewObservableProvider(b.provider, logger)
@@ -75,7 +91,7 @@ func NewRateLimitedProvider(provider StockProvider, limiter RateLimiter) *RateLi
 // GetQuote implements StockProvider with rate limiting
 func (r *RateLimitedProvider) GetQuote(ctx context.Context, symbol string) (*model.Quote, error) {
 	// Wait for rate limit token
-	if err := r.limiter.Wait(ctx); err != nil {
+	if err := r.wait(ctx); err != nil {
 		return nil, err
 	}
 
@@ -85,7 +101,7 @@ func (r *RateLimitedProvider) GetQuote(ctx context.Context, symbol string) (*mod
 // GetQuotes implements StockProvider with rate limiting
 func (r *RateLimitedProvider) GetQuotes(ctx context.Context, symbols []string) ([]*model.Quote, error) {
 	// Wait for rate limit token (counts as single batch request)
-	if err := r.limiter.Wait(ctx); err != nil {
+	if err := r.wait(ctx); err != nil {
 		return nil, err
 	}
 
@@ -100,9 +116,17 @@ func (r *RateLimitedProvider) Name() string {
 // HealthCheck implements StockProvider with rate limiting
 func (r *RateLimitedProvider) HealthCheck(ctx context.Context) error {
 	// Wait for rate limit token
-	if err := r.limiter.Wait(ctx); err != nil {
+	if err := r.wait(ctx); err != nil {
 		return err
 	}
 
 	return r.provider.HealthCheck(ctx)
 }
+
+// wait blocks on the limiter and records how long it took
+func (r *RateLimitedProvider) wait(ctx context.Context) error {
+	start := time.Now()
+	err := r.limiter.Wait(ctx)
+	rateLimitWait.ObserveSince(start, r.provider.Name())
+	return err
+}
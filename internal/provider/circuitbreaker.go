@@ -43,11 +43,13 @@ type CircuitBreakerProvider struct {
 
 // NewCircuitBreakerProvider creates a provider wrapper with circuit breaker
 func NewCircuitBreakerProvider(provider StockProvider, config CircuitBreakerConfig) *CircuitBreakerProvider {
-	return &CircuitBreakerProvider{
+	cb := &CircuitBreakerProvider{
 		provider: provider,
 		config:   config,
 		state:    StateClosed,
 	}
+	cb.report()
+	return cb
 }
 
 // GetQuote implements StockProvider with circuit breaker logic
@@ -95,6 +97,7 @@ func (cb *CircuitBreakerProvider) HealthCheck(ctx context.Context) error {
 func (cb *CircuitBreakerProvider) beforeRequest() error {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
+	defer cb.report()
 
 	switch cb.state {
 	case StateClosed:
@@ -123,6 +126,7 @@ func (cb *CircuitBreakerProvider) beforeRequest() error {
 func (cb *CircuitBreakerProvider) afterRequest(err error) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
+	defer cb.report()
 
 	if err == nil {
 		// Success
@@ -181,7 +185,14 @@ func (cb *CircuitBreakerProvider) GetState() CircuitState {
 func (cb *CircuitBreakerProvider) Reset() {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
+	defer cb.report()
 
 	cb.state = StateClosed
 	cb.failures = 0
 }
+
+// report publishes the current state to /metrics. Caller holds cb.mu
+// (or owns cb exclusively).
+func (cb *CircuitBreakerProvider) report() {
+	circuitState.Set(float64(cb.state), cb.provider.Name())
+}
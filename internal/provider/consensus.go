@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"math"
+	"stocktopus/internal/model"
+)
+
+// DefaultConsensusTolerance flags providers more than 1% apart.
+const DefaultConsensusTolerance = 0.01
+
+// ConsensusProvider cross-checks every quote against a second provider
+// and flags disagreements. The primary's quote is always the one
+// returned; the secondary only adds model.FlagDisagree when the two
+// prices differ by more than the tolerance (a fraction of the secondary's
+// price). If the secondary fails or lacks a symbol, the primary's quote is
+// returned unflagged.
+//
+// The secondary should carry its own middleware stack; consensus doubles
+// quote traffic, so it is opt-in.
+type ConsensusProvider struct {
+	primary   StockProvider
+	secondary StockProvider
+	tolerance float64
+}
+
+// NewConsensusProvider creates a provider that checks primary against
+// secondary. tolerance <= 0 uses DefaultConsensusTolerance.
+func NewConsensusProvider(primary, secondary StockProvider, tolerance float64) *ConsensusProvider {
+	if tolerance <= 0 {
+		tolerance = DefaultConsensusTolerance
+	}
+	return &ConsensusProvider{primary: primary, secondary: secondary, tolerance: tolerance}
+}
+
+// GetQuote implements StockProvider with a consensus check
+func (c *ConsensusProvider) GetQuote(ctx context.Context, symbol string) (*model.Quote, error) {
+	check := make(chan *model.Quote, 1)
+	go func() {
+		q, _ := c.secondary.GetQuote(ctx, symbol)
+		check <- q
+	}()
+
+	quote, err := c.primary.GetQuote(ctx, symbol)
+	other := <-check
+	if err != nil {
+		return nil, err
+	}
+	c.compare(quote, other)
+	return quote, nil
+}
+
+// GetQuotes implements StockProvider with a consensus check per symbol
+func (c *ConsensusProvider) GetQuotes(ctx context.Context, symbols []string) ([]*model.Quote, error) {
+	check := make(chan []*model.Quote, 1)
+	go func() {
+		qs, _ := c.secondary.GetQuotes(ctx, symbols)
+		check <- qs
+	}()
+
+	quotes, err := c.primary.GetQuotes(ctx, symbols)
+	others := <-check
+
+	bySymbol := make(map[string]*model.Quote, len(others))
+	for _, q := range others {
+		if q != nil {
+			bySymbol[q.Symbol] = q
+		}
+	}
+	for _, q := range quotes {
+		if q != nil {
+			c.compare(q, bySymbol[q.Symbol])
+		}
+	}
+	return quotes, err
+}
+
+func (c *ConsensusProvider) compare(q, other *model.Quote) {
+	if q == nil || other == nil || other.Price <= 0 {
+		return
+	}
+	if math.Abs(q.Price-other.Price)/other.Price > c.tolerance {
+		q.Flag(model.FlagDisagree)
+	}
+}
+
+// Name implements StockProvider
+func (c *ConsensusProvider) Name() string {
+	return c.primary.Name()
+}
+
+// HealthCheck implements StockProvider. Only the primary must be healthy;
+// a missing secondary just disables the check.
+func (c *ConsensusProvider) HealthCheck(ctx context.Context) error {
+	return c.primary.HealthCheck(ctx)
+}
@@ -42,6 +42,7 @@ var (
 	ErrServerError          = errors.New("provider server error")
 	ErrNetworkTimeout       = errors.New("network timeout")
 	ErrCircuitOpen          = errors.New("circuit breaker open")
+	ErrInvalidQuote         = errors.New("invalid quote")
 )
 
 // NewProviderError creates a new ProviderError with retry semantics based on status code
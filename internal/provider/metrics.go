@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"stocktopus/internal/metrics"
+)
+
+var (
+	requestDuration = metrics.NewHistogram("stocktopus_provider_request_duration_seconds",
+		"Quote provider call latency, including retries below the observability layer.",
+		metrics.DefaultBuckets, "provider", "operation")
+	requestErrors = metrics.NewCounter("stocktopus_provider_errors_total",
+		"Quote provider call failures by error type.", "provider", "operation", "type")
+	circuitState = metrics.NewGauge("stocktopus_provider_circuit_state",
+		"Circuit breaker state: 0 closed, 1 open, 2 half-open.", "provider")
+	rateLimitWait = metrics.NewHistogram("stocktopus_provider_ratelimit_wait_seconds",
+		"Time spent waiting on the rate limiter before a provider call.",
+		metrics.DefaultBuckets, "provider")
+)
+
+// ErrorType classifies err for metrics labels: the ProviderError sentinel
+// it wraps, else its HTTP status class.
+func ErrorType(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrInvalidQuote):
+		return "invalid_quote"
+	case errors.Is(err, ErrRateLimitExceeded):
+		return "rate_limit"
+	case errors.Is(err, ErrAuthenticationFailed):
+		return "auth"
+	case errors.Is(err, ErrSymbolNotFound):
+		return "not_found"
+	case errors.Is(err, ErrInvalidRequest):
+		return "invalid_request"
+	case errors.Is(err, ErrNetworkTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrServerError):
+		return "server"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	var perr *ProviderError
+	if errors.As(err, &perr) {
+		switch code := perr.StatusCode; {
+		case code == 429:
+			return "rate_limit"
+		case code == 401 || code == 403:
+			return "auth"
+		case code == 404:
+			return "not_found"
+		case code >= 500:
+			return "server"
+		case code >= 400:
+			return "invalid_request"
+		}
+		return "provider"
+	}
+	return "other"
+}
@@ -8,7 +8,8 @@ import (
 )
 
 // ObservableProvider wraps a StockProvider with structured logging and metrics
-// Logs all API calls with provider name, operation, symbol(s), duration, and errors
+// Logs all API calls with provider name, operation, symbol(s), duration, and errors,
+// and records latency and error-type counts for /metrics
 type ObservableProvider struct {
 	provider StockProvider
 	logger   *slog.Logger
@@ -36,6 +37,7 @@ func (o *ObservableProvider) GetQuote(ctx context.Context, symbol string) (*mode
 
 	quote, err := o.provider.GetQuote(ctx, symbol)
 	duration := time.Since(start)
+	o.record("GetQuote", duration, err)
 
 	if err != nil {
 		o.logger.Error("failed to fetch quote",
@@ -67,6 +69,7 @@ func (o *ObservableProvider) GetQuotes(ctx context.Context, symbols []string) ([
 
 	quotes, err := o.provider.GetQuotes(ctx, symbols)
 	duration := time.Since(start)
+	o.record("GetQuotes", duration, err)
 
 	if err != nil {
 		o.logger.Error("failed to fetch quotes",
@@ -94,6 +97,15 @@ func (o *ObservableProvider) GetQuotes(ctx context.Context, symbols []string) ([
 	return quotes, nil
 }
 
+// record feeds the /metrics latency histogram and error counter
+func (o *ObservableProvider) record(operation string, duration time.Duration, err error) {
+	name := o.provider.Name()
+	requestDuration.Observe(duration.Seconds(), name, operation)
+	if err != nil {
+		requestErrors.Inc(name, operation, ErrorType(err))
+	}
+}
+
 // Name implements StockProvider
 func (o *ObservableProvider) Name() string {
 	return o.provider.Name()
@@ -108,6 +120,7 @@ func (o *ObservableProvider) HealthCheck(ctx context.Context) error {
 
 	err := o.provider.HealthCheck(ctx)
 	duration := time.Since(start)
+	o.record("HealthCheck", duration, err)
 
 	if err != nil {
 		o.logger.Error("health check failed",
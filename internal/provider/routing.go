@@ -0,0 +1,187 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"stocktopus/internal/model"
+	"strings"
+	"sync"
+)
+
+// AssetClassifier returns a symbol's asset class ("stock", "etf", "crypto",
+// "forex", "index", "fund"), or "" when unknown. store.GetSecurityType
+// fits directly.
+type AssetClassifier func(symbol string) string
+
+// RoutingProvider dispatches each symbol to the provider configured for
+// its asset class, falling back to a default for unrouted or unknown
+// classes. Sub-providers are full StockProviders, so each carries its own
+// middleware stack (rate limit, retry, circuit breaker) built with
+// ProviderBuilder — a tripped breaker on the crypto feed doesn't stall
+// equities.
+//
+// Example usage:
+//
+//	router := NewRoutingProvider(fmpStack, store.GetSecurityType).
+//	    Route("crypto", polygonStack).
+//	    Route("forex", polygonStack)
+type RoutingProvider struct {
+	fallback StockProvider
+	classify AssetClassifier
+	routes   map[string]StockProvider
+}
+
+// NewRoutingProvider creates a router that sends everything to fallback
+// until routes are added.
+func NewRoutingProvider(fallback StockProvider, classify AssetClassifier) *RoutingProvider {
+	return &RoutingProvider{
+		fallback: fallback,
+		classify: classify,
+		routes:   make(map[string]StockProvider),
+	}
+}
+
+// Route sends symbols of assetClass to p. Call during setup only; the
+// router is not safe for concurrent reconfiguration.
+func (r *RoutingProvider) Route(assetClass string, p StockProvider) *RoutingProvider {
+	r.routes[strings.ToLower(assetClass)] = p
+	return r
+}
+
+// ProviderFor returns the provider a symbol would be dispatched to.
+func (r *RoutingProvider) ProviderFor(symbol string) StockProvider {
+	if r.classify != nil {
+		if p, ok := r.routes[strings.ToLower(r.classify(symbol))]; ok {
+			return p
+		}
+	}
+	return r.fallback
+}
+
+// GetQuote implements StockProvider by delegating to the symbol's route
+func (r *RoutingProvider) GetQuote(ctx context.Context, symbol string) (*model.Quote, error) {
+	return r.ProviderFor(symbol).GetQuote(ctx, symbol)
+}
+
+// GetQuotes implements StockProvider. The batch is split by destination,
+// sub-batches run concurrently, and results are merged back into input
+// order. If only some sub-batches fail, the successful quotes are
+// returned alongside a *RoutingError; failed positions are nil.
+func (r *RoutingProvider) GetQuotes(ctx context.Context, symbols []string) ([]*model.Quote, error) {
+	if len(symbols) == 0 {
+		return []*model.Quote{}, nil
+	}
+
+	type batch struct {
+		provider StockProvider
+		symbols  []string
+		indexes  []int
+	}
+	var batches []*batch
+	byProvider := make(map[StockProvider]*batch)
+	for i, sym := range symbols {
+		p := r.ProviderFor(sym)
+		b := byProvider[p]
+		if b == nil {
+			b = &batch{provider: p}
+			byProvider[p] = b
+			batches = append(batches, b)
+		}
+		b.symbols = append(b.symbols, sym)
+		b.indexes = append(b.indexes, i)
+	}
+
+	// Single destination: no split, pass the provider's result through.
+	if len(batches) == 1 {
+		return batches[0].provider.GetQuotes(ctx, symbols)
+	}
+
+	out := make([]*model.Quote, len(symbols))
+	errs := make([]error, len(batches))
+	var wg sync.WaitGroup
+	for bi, b := range batches {
+		wg.Add(1)
+		go func(bi int, b *batch) {
+			defer wg.Done()
+			quotes, err := b.provider.GetQuotes(ctx, b.symbols)
+			if err != nil {
+				errs[bi] = err
+				return
+			}
+			for j, q := range quotes {
+				if j < len(b.indexes) {
+					out[b.indexes[j]] = q
+				}
+			}
+		}(bi, b)
+	}
+	wg.Wait()
+
+	routeErr := &RoutingError{}
+	for bi, err := range errs {
+		if err != nil {
+			routeErr.Failed = append(routeErr.Failed, RouteFailure{
+				Provider: batches[bi].provider.Name(),
+				Symbols:  batches[bi].symbols,
+				Err:      err,
+			})
+		}
+	}
+	switch len(routeErr.Failed) {
+	case 0:
+		return out, nil
+	case len(batches):
+		return nil, routeErr
+	default:
+		return out, routeErr
+	}
+}
+
+// Name implements StockProvider
+func (r *RoutingProvider) Name() string {
+	return "router"
+}
+
+// HealthCheck implements StockProvider by checking every distinct
+// sub-provider
+func (r *RoutingProvider) HealthCheck(ctx context.Context) error {
+	seen := map[StockProvider]bool{r.fallback: true}
+	errs := []error{r.fallback.HealthCheck(ctx)}
+	for _, p := range r.routes {
+		if !seen[p] {
+			seen[p] = true
+			errs = append(errs, p.HealthCheck(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RouteFailure is one sub-batch that failed inside a routed GetQuotes.
+type RouteFailure struct {
+	Provider string
+	Symbols  []string
+	Err      error
+}
+
+// RoutingError reports the sub-batches that failed. Unwrap exposes each
+// underlying error, so errors.Is / errors.As see through to e.g.
+// ErrCircuitOpen or a *ProviderError.
+type RoutingError struct {
+	Failed []RouteFailure
+}
+
+func (e *RoutingError) Error() string {
+	parts := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		parts[i] = f.Provider + " [" + strings.Join(f.Symbols, ",") + "]: " + f.Err.Error()
+	}
+	return "routing: " + strings.Join(parts, "; ")
+}
+
+func (e *RoutingError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f.Err
+	}
+	return errs
+}
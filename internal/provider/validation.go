@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"stocktopus/internal/model"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ValidationConfig holds configuration for quote validation
+type ValidationConfig struct {
+	MaxFutureSkew time.Duration // Clock drift tolerated before a timestamp counts as "in the future"
+	StaleAfter    time.Duration // Flag quotes older than this while their market is open (0 disables)
+	MaxJump       float64       // Flag moves larger than this fraction of the last seen price (0 disables)
+
+	// IsOpen reports whether symbol's market is trading at t, so closed
+	// markets' last-trade timestamps aren't flagged stale. Nil means always.
+	IsOpen func(symbol string, t time.Time) bool
+
+	Logger *slog.Logger // Where rejections are logged (default slog.Default())
+}
+
+// DefaultValidationConfig returns production-ready validation settings
+// Flags quotes more than 15 minutes old during market hours, or more than
+// 25% away from the previous poll
+func DefaultValidationConfig() ValidationConfig {
+	return ValidationConfig{
+		MaxFutureSkew: time.Minute,
+		StaleAfter:    15 * time.Minute,
+		MaxJump:       0.25,
+	}
+}
+
+// ValidatingProvider enforces the model.Quote validation rules. Quotes
+// that break a rule (bad symbol, non-positive price, negative volume,
+// future timestamp) are rejected; quotes that are well-formed but suspect
+// (stale, implausible jump) are passed through with model.Quote.Flags set.
+//
+// Jumps are measured against a last-value cache of the most recent
+// accepted price per symbol. The cache always advances, so a genuine gap
+// is flagged once rather than on every poll after it.
+type ValidatingProvider struct {
+	provider StockProvider
+	config   ValidationConfig
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu   sync.Mutex
+	last map[string]float64 // symbol → last accepted price
+}
+
+// NewValidatingProvider creates a provider wrapper with quote validation
+func NewValidatingProvider(provider StockProvider, config ValidationConfig) *ValidatingProvider {
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &ValidatingProvider{
+		provider: provider,
+		config:   config,
+		logger:   logger.With(slog.String("provider", provider.Name())),
+		now:      time.Now,
+		last:     make(map[string]float64),
+	}
+}
+
+// GetQuote implements StockProvider. A quote that fails validation is
+// returned as a non-retryable *ProviderError wrapping ErrInvalidQuote.
+func (v *ValidatingProvider) GetQuote(ctx context.Context, symbol string) (*model.Quote, error) {
+	quote, err := v.provider.GetQuote(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.check(quote); err != nil {
+		return nil, &ProviderError{
+			Provider:  v.provider.Name(),
+			Operation: "GetQuote",
+			Err:       err,
+		}
+	}
+	return quote, nil
+}
+
+// GetQuotes implements StockProvider. Invalid quotes are logged and their
+// positions set to nil, so one bad row doesn't fail the batch.
+func (v *ValidatingProvider) GetQuotes(ctx context.Context, symbols []string) ([]*model.Quote, error) {
+	quotes, err := v.provider.GetQuotes(ctx, symbols)
+	for i, q := range quotes {
+		if q == nil {
+			continue
+		}
+		if verr := v.check(q); verr != nil {
+			v.logger.Warn("rejected quote",
+				slog.String("operation", "GetQuotes"),
+				slog.String("symbol", q.Symbol),
+				slog.Any("error", verr))
+			quotes[i] = nil
+		}
+	}
+	return quotes, err
+}
+
+// Name implements StockProvider
+func (v *ValidatingProvider) Name() string {
+	return v.provider.Name()
+}
+
+// HealthCheck implements StockProvider
+func (v *ValidatingProvider) HealthCheck(ctx context.Context) error {
+	return v.provider.HealthCheck(ctx)
+}
+
+// check rejects rule violations and flags suspect quotes in place
+func (v *ValidatingProvider) check(q *model.Quote) error {
+	if err := ValidateQuote(q, v.now().Add(v.config.MaxFutureSkew)); err != nil {
+		return err
+	}
+
+	now := v.now()
+	if v.config.StaleAfter > 0 && !q.Timestamp.IsZero() && now.Sub(q.Timestamp) > v.config.StaleAfter &&
+		(v.config.IsOpen == nil || v.config.IsOpen(q.Symbol, now)) {
+		q.Flag(model.FlagStale)
+	}
+
+	v.mu.Lock()
+	prev, seen := v.last[q.Symbol]
+	v.last[q.Symbol] = q.Price
+	v.mu.Unlock()
+	if v.config.MaxJump > 0 && seen && math.Abs(q.Price/prev-1) > v.config.MaxJump {
+		q.Flag(model.FlagJump)
+	}
+	return nil
+}
+
+// ValidateQuote checks q against the rules documented on model.Quote.
+// Timestamps after notAfter count as in the future; a zero timestamp is
+// allowed (not every provider reports one).
+func ValidateQuote(q *model.Quote, notAfter time.Time) error {
+	if err := validateSymbol(q.Symbol); err != nil {
+		return err
+	}
+	switch {
+	case math.IsNaN(q.Price) || math.IsInf(q.Price, 0) || q.Price <= 0:
+		return fmt.Errorf("%w: %s price %v must be > 0", ErrInvalidQuote, q.Symbol, q.Price)
+	case q.Volume < 0:
+		return fmt.Errorf("%w: %s volume %d must be >= 0", ErrInvalidQuote, q.Symbol, q.Volume)
+	case q.Timestamp.After(notAfter):
+		return fmt.Errorf("%w: %s timestamp %s is in the future", ErrInvalidQuote, q.Symbol, q.Timestamp.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// validateSymbol requires a non-empty, uppercase symbol. Besides letters
+// and digits it allows the punctuation real tickers use: class shares
+// (BRK.B, BF-B), indices (^GSPC), pairs (EUR/USD) and suffixes (EURUSD=X).
+func validateSymbol(symbol string) error {
+	if symbol == "" {
+		return fmt.Errorf("%w: empty symbol", ErrInvalidQuote)
+	}
+	for _, r := range symbol {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.' || r == '-' || r == '^' || r == '/' || r == '=':
+		case unicode.IsLower(r):
+			return fmt.Errorf("%w: symbol %q must be uppercase", ErrInvalidQuote, symbol)
+		default:
+			return fmt.Errorf("%w: symbol %q contains %q", ErrInvalidQuote, symbol, r)
+		}
+	}
+	return nil
+}
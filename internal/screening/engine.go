@@ -0,0 +1,379 @@
+// Package screening is the local screener: a nightly snapshot of a stock
+// universe (profile, fundamentals and a year of daily bars) held in memory,
+// and an expression engine that filters, ranks and scores it without
+// calling FMP per query.
+package screening
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"stocktopus/internal/indicators"
+	"stocktopus/internal/store"
+)
+
+// Security is one universe member as expressions see it.
+type Security struct {
+	store.UniverseRow
+	closes []float64
+	fields map[string]float64 // Fields keyed by lower-cased name
+}
+
+func newSecurity(r store.UniverseRow) *Security {
+	sec := &Security{UniverseRow: r, closes: indicators.Closes(r.Bars), fields: make(map[string]float64, len(r.Fields))}
+	for k, v := range r.Fields {
+		sec.fields[strings.ToLower(k)] = v
+	}
+	return sec
+}
+
+// textFields are the string-valued names every security has.
+var textFields = []string{"symbol", "name", "sector", "industry", "exchange"}
+
+// barFields are derived from the last bar rather than stored.
+var barFields = []string{"close", "open", "high", "low", "volume"}
+
+// Field returns a named value: the text fields, the last bar's OHLCV, or
+// a stored fundamental. Unknown or missing names are null.
+func (sec *Security) Field(name string) Value {
+	switch strings.ToLower(name) {
+	case "symbol":
+		return Value{Kind: String, Str: sec.Symbol}
+	case "name":
+		return Value{Kind: String, Str: sec.Name}
+	case "sector":
+		return Value{Kind: String, Str: sec.Sector}
+	case "industry":
+		return Value{Kind: String, Str: sec.Industry}
+	case "exchange":
+		return Value{Kind: String, Str: sec.Exchange}
+	}
+	if n := len(sec.Bars); n > 0 {
+		last := sec.Bars[n-1]
+		switch strings.ToLower(name) {
+		case "close", "price":
+			return num(last.Close)
+		case "open":
+			return num(last.Open)
+		case "high":
+			return num(last.High)
+		case "low":
+			return num(last.Low)
+		case "volume":
+			return num(float64(last.Volume))
+		}
+	}
+	if v, ok := sec.fields[strings.ToLower(name)]; ok {
+		return num(v)
+	}
+	return null
+}
+
+// Factor is one leg of a multi-factor score. Each security's Expr value
+// is turned into a percentile (0-100, higher value = higher percentile)
+// across the matched set; the score is the Weight-weighted average of
+// those percentiles. Use a negated expression, e.g. "-peRatio", where
+// lower is better.
+type Factor struct {
+	Name   string  `json:"name"`
+	Expr   string  `json:"expr"`
+	Weight float64 `json:"weight"`
+}
+
+// Query is one screen over the universe.
+type Query struct {
+	// Filter keeps securities it is true for; empty keeps everything.
+	Filter string `json:"filter"`
+	// Rank orders the results by an expression, highest first unless
+	// Ascending. Without it results are ordered by Score when there are
+	// Factors, else by symbol.
+	Rank      string   `json:"rank,omitempty"`
+	Ascending bool     `json:"ascending,omitempty"`
+	Factors   []Factor `json:"factors,omitempty"`
+	// Columns are extra expressions reported for each result.
+	Columns []string `json:"columns,omitempty"`
+	Limit   int      `json:"limit,omitempty"`
+}
+
+// DefaultLimit and MaxLimit bound Query.Limit.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Result is one security that passed the filter.
+type Result struct {
+	Symbol  string              `json:"symbol"`
+	Name    string              `json:"name"`
+	Sector  string              `json:"sector"`
+	Close   float64             `json:"close"`
+	Rank    *float64            `json:"rank,omitempty"`
+	Score   *float64            `json:"score,omitempty"`
+	Factors map[string]*float64 `json:"factors,omitempty"`
+	Columns map[string]*float64 `json:"columns,omitempty"`
+}
+
+// Response is a screen's outcome. Matched counts everything that passed
+// the filter, before Limit.
+type Response struct {
+	Results  []Result  `json:"results"`
+	Matched  int       `json:"matched"`
+	Universe int       `json:"universe"`
+	AsOf     time.Time `json:"asOf"`
+}
+
+// Engine screens the in-memory snapshot. It is safe for concurrent use;
+// Load swaps the snapshot atomically.
+type Engine struct {
+	mu     sync.RWMutex
+	secs   []*Security
+	known  map[string]bool
+	asOf   time.Time
+	loaded bool
+}
+
+// NewEngine returns an empty engine; Load fills it.
+func NewEngine() *Engine { return &Engine{known: map[string]bool{}} }
+
+// Load replaces the snapshot. Its as-of time is the latest row refresh.
+func (e *Engine) Load(rows []store.UniverseRow) {
+	secs := make([]*Security, len(rows))
+	known := map[string]bool{"price": true}
+	for _, f := range textFields {
+		known[f] = true
+	}
+	for _, f := range barFields {
+		known[f] = true
+	}
+	var asOf time.Time
+	for i, r := range rows {
+		secs[i] = newSecurity(r)
+		for k := range secs[i].fields {
+			known[k] = true
+		}
+		if r.RefreshedAt.After(asOf) {
+			asOf = r.RefreshedAt
+		}
+	}
+	e.mu.Lock()
+	e.secs, e.known, e.asOf, e.loaded = secs, known, asOf, true
+	e.mu.Unlock()
+}
+
+// Status describes the loaded snapshot.
+type Status struct {
+	Loaded    bool      `json:"loaded"`
+	Symbols   int       `json:"symbols"`
+	AsOf      time.Time `json:"asOf"`
+	Fields    []string  `json:"fields"`
+	Functions []string  `json:"functions"`
+}
+
+// Status reports the snapshot size, age and the field names expressions
+// can use.
+func (e *Engine) Status() Status {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	fields := make([]string, 0, len(e.known))
+	seen := map[string]bool{}
+	for _, sec := range e.secs {
+		for k := range sec.Fields {
+			if !seen[strings.ToLower(k)] {
+				seen[strings.ToLower(k)] = true
+				fields = append(fields, k)
+			}
+		}
+	}
+	sort.Strings(fields)
+	fields = append(append(append([]string{}, textFields...), barFields...), fields...)
+	return Status{Loaded: e.loaded, Symbols: len(e.secs), AsOf: e.asOf, Fields: fields, Functions: Functions()}
+}
+
+// compile parses src and checks every field it names exists somewhere in
+// the snapshot, so a typo is an error rather than an empty result.
+func (e *Engine) compile(what, src string) (*Expr, error) {
+	x, err := Compile(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+	for _, id := range x.Idents() {
+		if !e.known[strings.ToLower(id)] {
+			return nil, fmt.Errorf("%s: unknown field %q", what, id)
+		}
+	}
+	return x, nil
+}
+
+// Run screens the snapshot.
+func (e *Engine) Run(q Query) (*Response, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if !e.loaded {
+		return nil, fmt.Errorf("universe snapshot not loaded yet")
+	}
+
+	var filter, rank *Expr
+	var err error
+	if strings.TrimSpace(q.Filter) != "" {
+		if filter, err = e.compile("filter", q.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(q.Rank) != "" {
+		if rank, err = e.compile("rank", q.Rank); err != nil {
+			return nil, err
+		}
+	}
+	factors := make([]*Expr, len(q.Factors))
+	for i, f := range q.Factors {
+		if f.Name == "" {
+			q.Factors[i].Name = f.Expr
+		}
+		if f.Weight < 0 {
+			return nil, fmt.Errorf("factor %q: weight must not be negative", q.Factors[i].Name)
+		}
+		if factors[i], err = e.compile("factor "+q.Factors[i].Name, f.Expr); err != nil {
+			return nil, err
+		}
+	}
+	columns := make([]*Expr, len(q.Columns))
+	for i, c := range q.Columns {
+		if columns[i], err = e.compile("column "+c, c); err != nil {
+			return nil, err
+		}
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	var matched []*Security
+	for _, sec := range e.secs {
+		if filter == nil || filter.Eval(sec).Truthy() {
+			matched = append(matched, sec)
+		}
+	}
+
+	results := make([]Result, len(matched))
+	for i, sec := range matched {
+		r := Result{Symbol: sec.Symbol, Name: sec.Name, Sector: sec.Sector}
+		if v := sec.Field("close"); v.Kind == Number {
+			r.Close = v.Num
+		}
+		if rank != nil {
+			r.Rank = number(rank.Eval(sec))
+		}
+		if len(columns) > 0 {
+			r.Columns = make(map[string]*float64, len(columns))
+			for j, c := range columns {
+				r.Columns[q.Columns[j]] = number(c.Eval(sec))
+			}
+		}
+		results[i] = r
+	}
+	if len(factors) > 0 {
+		score(results, matched, q.Factors, factors)
+	}
+
+	switch {
+	case rank != nil:
+		sortBy(results, func(r Result) *float64 { return r.Rank }, !q.Ascending)
+	case len(factors) > 0:
+		sortBy(results, func(r Result) *float64 { return r.Score }, !q.Ascending)
+	}
+	resp := &Response{Matched: len(results), Universe: len(e.secs), AsOf: e.asOf}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	resp.Results = results
+	return resp, nil
+}
+
+// number reports a numeric (or boolean) value as a float, rounded for
+// display; anything else is nil.
+func number(v Value) *float64 {
+	if v.Kind != Number && v.Kind != Bool {
+		return nil
+	}
+	f := math.Round(v.Num*1e4) / 1e4
+	return &f
+}
+
+// score fills in each result's factor percentiles and weighted score. A
+// security missing a factor is scored on the factors it has.
+func score(results []Result, secs []*Security, defs []Factor, factors []*Expr) {
+	for i := range results {
+		results[i].Factors = make(map[string]*float64, len(defs))
+	}
+	for j, x := range factors {
+		type pair struct {
+			idx int
+			v   float64
+		}
+		var vals []pair
+		for i, sec := range secs {
+			if v := x.Eval(sec); v.Kind == Number || v.Kind == Bool {
+				vals = append(vals, pair{i, v.Num})
+			}
+		}
+		sort.Slice(vals, func(a, b int) bool { return vals[a].v < vals[b].v })
+		// Ties share the average of the ranks they span.
+		for lo := 0; lo < len(vals); {
+			hi := lo
+			for hi+1 < len(vals) && vals[hi+1].v == vals[lo].v {
+				hi++
+			}
+			pct := 100.0
+			if len(vals) > 1 {
+				pct = (float64(lo+hi) / 2) / float64(len(vals)-1) * 100
+			}
+			pct = math.Round(pct*100) / 100
+			for k := lo; k <= hi; k++ {
+				p := pct
+				results[vals[k].idx].Factors[defs[j].Name] = &p
+			}
+			lo = hi + 1
+		}
+	}
+	for i := range results {
+		var sum, weights float64
+		for j, d := range defs {
+			w := d.Weight
+			if w == 0 {
+				w = 1
+			}
+			if p := results[i].Factors[defs[j].Name]; p != nil {
+				sum += w * *p
+				weights += w
+			}
+		}
+		if weights > 0 {
+			s := math.Round(sum/weights*100) / 100
+			results[i].Score = &s
+		}
+	}
+}
+
+// sortBy orders results by key, keeping nils last either way.
+func sortBy(results []Result, key func(Result) *float64, desc bool) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := key(results[i]), key(results[j])
+		switch {
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		case desc:
+			return *a > *b
+		default:
+			return *a < *b
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package screening
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"stocktopus/internal/model"
+	"stocktopus/internal/store"
+)
+
+func testUniverse() []store.UniverseRow {
+	now := time.Now()
+	return []store.UniverseRow{
+		{Symbol: "AAA", Sector: "Technology", Fields: map[string]float64{"peRatio": 10, "roe": 0.30}, Bars: rising(210, 100), RefreshedAt: now},
+		{Symbol: "BBB", Sector: "Technology", Fields: map[string]float64{"peRatio": 40, "roe": 0.10}, Bars: rising(210, 50), RefreshedAt: now},
+		{Symbol: "CCC", Sector: "Energy", Fields: map[string]float64{"peRatio": 20, "roe": 0.20}, Bars: rising(10, 30), RefreshedAt: now},
+		{Symbol: "DDD", Sector: "Energy", Fields: map[string]float64{"roe": 0.05}, Bars: rising(210, 10), RefreshedAt: now},
+	}
+}
+
+func symbols(rs []Result) string {
+	var out []string
+	for _, r := range rs {
+		out = append(out, r.Symbol)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestEngineRun(t *testing.T) {
+	e := NewEngine()
+	if _, err := e.Run(Query{}); err == nil {
+		t.Error("an unloaded engine should refuse to run")
+	}
+	e.Load(testUniverse())
+
+	resp, err := e.Run(Query{Filter: "close > sma(200)", Rank: "peRatio", Ascending: true, Columns: []string{"rsi(14)"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// CCC lacks 200 bars; DDD has no P/E and sorts last.
+	if symbols(resp.Results) != "AAA,BBB,DDD" || resp.Matched != 3 || resp.Universe != 4 {
+		t.Errorf("ranked = %s (%d of %d)", symbols(resp.Results), resp.Matched, resp.Universe)
+	}
+	if c := resp.Results[0].Columns["rsi(14)"]; c == nil || *c != 100 {
+		t.Errorf("rsi column = %v", c)
+	}
+
+	resp, err = e.Run(Query{
+		Factors: []Factor{{Name: "value", Expr: "-peRatio", Weight: 2}, {Name: "quality", Expr: "roe", Weight: 1}},
+		Limit:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// AAA is cheapest and best quality; DDD is scored on quality alone.
+	if symbols(resp.Results) != "AAA,CCC" || resp.Matched != 4 {
+		t.Errorf("scored = %s", symbols(resp.Results))
+	}
+	if s := resp.Results[0].Score; s == nil || *s != 100 {
+		t.Errorf("top score = %v", s)
+	}
+	if p := resp.Results[1].Factors["value"]; p == nil || *p != 50 {
+		t.Errorf("CCC value percentile = %v", p)
+	}
+
+	if _, err := e.Run(Query{Filter: "peRatoi < 10"}); err == nil || !strings.Contains(err.Error(), "peRatoi") {
+		t.Errorf("typo should name the field: %v", err)
+	}
+	if _, err := e.Run(Query{Factors: []Factor{{Expr: "roe", Weight: -1}}}); err == nil {
+		t.Error("negative weight should fail")
+	}
+	if st := e.Status(); st.Symbols != 4 || st.Fields[0] != "symbol" {
+		t.Errorf("status = %+v", st)
+	}
+}
+
+type fakeFMP struct {
+	fail map[string]bool
+}
+
+func (f fakeFMP) GetIndexConstituents(ctx context.Context, symbol string) (json.RawMessage, error) {
+	return json.RawMessage(`[{"symbol":"AAA"},{"symbol":"BBB"}]`), nil
+}
+
+func (f fakeFMP) GetProfile(ctx context.Context, symbol string) (json.RawMessage, error) {
+	if f.fail[symbol] {
+		return nil, errors.New("down")
+	}
+	return json.RawMessage(`[{"symbol":"` + symbol + `","companyName":"` + symbol + ` Inc","sector":"Technology","marketCap":1e9,"isEtf":false,"price":9}]`), nil
+}
+
+func (f fakeFMP) GetKeyMetrics(ctx context.Context, symbol string) (json.RawMessage, error) {
+	return json.RawMessage(`[{"returnOnEquity":0.25,"evToEBITDA":12,"date":"2025-12-31"}]`), nil
+}
+
+func (f fakeFMP) GetRatiosTTM(ctx context.Context, symbol string) (json.RawMessage, error) {
+	return json.RawMessage(`[{"priceToEarningsRatioTTM":18.5}]`), nil
+}
+
+func (f fakeFMP) GetIncomeStatement(ctx context.Context, symbol string, limit int) (json.RawMessage, error) {
+	return json.RawMessage(`[{"revenue":133.1,"eps":2.2},{"revenue":121,"eps":2},{"revenue":110},{"revenue":100}]`), nil
+}
+
+func (f fakeFMP) GetHistoricalEOD(ctx context.Context, symbol, from, to string) ([]model.OHLCV, error) {
+	return rising(300, 10), nil
+}
+
+func TestSnapshotterRebuild(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "u.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	st.AddToWatchlist(0, "ZZZ")
+	st.PutUniverseRow(store.UniverseRow{Symbol: "OLD"})
+
+	e := NewEngine()
+	s := NewSnapshotter(fakeFMP{fail: map[string]bool{"BBB": true}}, st, e, []string{"^GSPC", "aaa"}, time.Hour, slog.Default())
+	n, err := s.Rebuild(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("rebuild = %d, %v", n, err)
+	}
+	rows, _ := st.GetUniverse()
+	if len(rows) != 2 || rows[0].Symbol != "AAA" || rows[1].Symbol != "ZZZ" {
+		t.Fatalf("rows = %+v", rows)
+	}
+	f := rows[0].Fields
+	if f["peRatio"] != 18.5 || f["roe"] != 0.25 || f["evToEbitda"] != 12 || f["isEtf"] != 0 || len(rows[0].Bars) != 260 {
+		t.Errorf("fields = %v, %d bars", f, len(rows[0].Bars))
+	}
+	if g := f["revenueGrowth3y"]; g < 0.0999 || g > 0.1001 {
+		t.Errorf("revenueGrowth3y = %v", g)
+	}
+
+	resp, err := e.Run(Query{Filter: "peRatio < 20 and revenueGrowth3y > 0.09 and close > sma(200)"})
+	if err != nil || symbols(resp.Results) != "AAA,ZZZ" {
+		t.Errorf("screen after rebuild = %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,618 @@
+package screening
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"stocktopus/internal/indicators"
+)
+
+// Expr is a compiled screening expression such as
+// "peRatio < 20 and close > sma(200)". It evaluates against one security
+// at a time.
+type Expr struct {
+	src  string
+	root node
+}
+
+// String returns the source the expression was compiled from.
+func (e *Expr) String() string { return e.src }
+
+// Idents returns the bare field names the expression reads.
+func (e *Expr) Idents() []string {
+	var out []string
+	seen := map[string]bool{}
+	walk(e.root, func(n node) {
+		if id, ok := n.(ident); ok && !seen[string(id)] {
+			seen[string(id)] = true
+			out = append(out, string(id))
+		}
+	})
+	return out
+}
+
+// Compile parses src. Keywords and function names are case-insensitive;
+// field names are matched case-insensitively when evaluated.
+func Compile(src string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Value is an expression result: a number, a string, a boolean, or null
+// when the data it needs is missing.
+type Value struct {
+	Kind Kind
+	Num  float64
+	Str  string
+}
+
+// Kind tags a Value.
+type Kind int
+
+const (
+	Null Kind = iota
+	Number
+	String
+	Bool
+)
+
+var null = Value{}
+
+func num(f float64) Value {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return null
+	}
+	return Value{Kind: Number, Num: f}
+}
+
+func boolean(b bool) Value {
+	if b {
+		return Value{Kind: Bool, Num: 1}
+	}
+	return Value{Kind: Bool}
+}
+
+// Truthy is how a Value reads as a filter: true booleans and non-zero
+// numbers pass, anything null does not.
+func (v Value) Truthy() bool {
+	switch v.Kind {
+	case Bool, Number:
+		return v.Num != 0
+	case String:
+		return v.Str != ""
+	}
+	return false
+}
+
+// Eval evaluates the expression for sec.
+func (e *Expr) Eval(sec *Security) Value { return e.root.eval(sec) }
+
+// ── lexer ──
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokStr
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	num  float64
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1]):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || rs[j] == 'e' || rs[j] == 'E' ||
+				(rs[j] == '-' || rs[j] == '+') && j > i && (rs[j-1] == 'e' || rs[j-1] == 'E')) {
+				j++
+			}
+			text := string(rs[i:j])
+			mult := 1.0
+			if j < len(rs) {
+				switch rs[j] {
+				case '%':
+					mult, j = 0.01, j+1
+				case 'k', 'K':
+					mult, j = 1e3, j+1
+				case 'm', 'M':
+					mult, j = 1e6, j+1
+				case 'b', 'B':
+					mult, j = 1e9, j+1
+				case 't', 'T':
+					mult, j = 1e12, j+1
+				}
+			}
+			f, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad number %q at %d", text, i)
+			}
+			toks = append(toks, token{kind: tokNum, text: string(rs[i:j]), num: f * mult, pos: i})
+			i = j
+		case r == '\'' || r == '"':
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				j++
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			toks = append(toks, token{kind: tokStr, text: string(rs[i+1 : j]), pos: i})
+			i = j + 1
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: string(rs[i:j]), pos: i})
+			i = j
+		default:
+			op := string(r)
+			if i+1 < len(rs) {
+				switch two := string(rs[i : i+2]); two {
+				case "<=", ">=", "==", "!=", "<>", "&&", "||":
+					op = two
+				}
+			}
+			if !strings.Contains("+-*/()<>=!,", string(r)) && len(op) == 1 {
+				return nil, fmt.Errorf("unexpected %q at %d", op, i)
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(rs)}), nil
+}
+
+// ── parser ──
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+func (p *parser) next() token { t := p.toks[p.i]; p.i++; return t }
+
+// keyword reports whether the next token is the word or operator w, and
+// consumes it if so.
+func (p *parser) keyword(ws ...string) bool {
+	t := p.peek()
+	for _, w := range ws {
+		if (t.kind == tokIdent && strings.EqualFold(t.text, w)) || (t.kind == tokOp && t.text == w) {
+			p.i++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) or() (node, error) {
+	l, err := p.and()
+	for err == nil && p.keyword("or", "||") {
+		var r node
+		if r, err = p.and(); err == nil {
+			l = logic{op: "or", l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) and() (node, error) {
+	l, err := p.not()
+	for err == nil && p.keyword("and", "&&") {
+		var r node
+		if r, err = p.not(); err == nil {
+			l = logic{op: "and", l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) not() (node, error) {
+	if p.keyword("not", "!") {
+		x, err := p.not()
+		return negate{x}, err
+	}
+	return p.cmp()
+}
+
+func (p *parser) cmp() (node, error) {
+	l, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokOp {
+		switch t.text {
+		case "<", "<=", ">", ">=", "=", "==", "!=", "<>":
+			p.i++
+			r, err := p.sum()
+			if err != nil {
+				return nil, err
+			}
+			op := t.text
+			switch op {
+			case "==":
+				op = "="
+			case "<>":
+				op = "!="
+			}
+			return compare{op: op, l: l, r: r}, nil
+		}
+	}
+	return l, nil
+}
+
+func (p *parser) sum() (node, error) {
+	l, err := p.prod()
+	for err == nil {
+		t := p.peek()
+		if t.kind != tokOp || (t.text != "+" && t.text != "-") {
+			break
+		}
+		p.i++
+		var r node
+		if r, err = p.prod(); err == nil {
+			l = arith{op: t.text[0], l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) prod() (node, error) {
+	l, err := p.unary()
+	for err == nil {
+		t := p.peek()
+		if t.kind != tokOp || (t.text != "*" && t.text != "/") {
+			break
+		}
+		p.i++
+		var r node
+		if r, err = p.unary(); err == nil {
+			l = arith{op: t.text[0], l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) unary() (node, error) {
+	if t := p.peek(); t.kind == tokOp && t.text == "-" {
+		p.i++
+		x, err := p.unary()
+		return arith{op: '-', l: literal{num(0)}, r: x}, err
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNum:
+		return literal{num(t.num)}, nil
+	case tokStr:
+		return literal{Value{Kind: String, Str: t.text}}, nil
+	case tokIdent:
+		switch strings.ToLower(t.text) {
+		case "true":
+			return literal{boolean(true)}, nil
+		case "false":
+			return literal{boolean(false)}, nil
+		}
+		if p.peek().kind != tokOp || p.peek().text != "(" {
+			return ident(t.text), nil
+		}
+		p.i++
+		fn, ok := funcs[strings.ToLower(t.text)]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %s() at %d", t.text, t.pos)
+		}
+		var args []node
+		if !p.keyword(")") {
+			for {
+				a, err := p.or()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, a)
+				if p.keyword(")") {
+					break
+				}
+				if !p.keyword(",") {
+					return nil, fmt.Errorf("want , or ) at %d", p.peek().pos)
+				}
+			}
+		}
+		if len(args) < fn.min || len(args) > fn.max {
+			return nil, fmt.Errorf("%s() takes %s", strings.ToLower(t.text), fn.arity())
+		}
+		if fn.periodArg {
+			for _, a := range args {
+				lit, ok := a.(literal)
+				if !ok || lit.v.Kind != Number || lit.v.Num < 1 || lit.v.Num != math.Trunc(lit.v.Num) {
+					return nil, fmt.Errorf("%s() needs a whole-number period", strings.ToLower(t.text))
+				}
+			}
+		}
+		return call{name: strings.ToLower(t.text), fn: fn, args: args}, nil
+	case tokOp:
+		if t.text == "(" {
+			x, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if !p.keyword(")") {
+				return nil, fmt.Errorf("missing ) at %d", p.peek().pos)
+			}
+			return x, nil
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("expression ends early")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// ── nodes ──
+
+type node interface {
+	eval(sec *Security) Value
+}
+
+type literal struct{ v Value }
+
+func (n literal) eval(*Security) Value { return n.v }
+
+type ident string
+
+func (n ident) eval(sec *Security) Value { return sec.Field(string(n)) }
+
+type negate struct{ x node }
+
+func (n negate) eval(sec *Security) Value { return boolean(!n.x.eval(sec).Truthy()) }
+
+type logic struct {
+	op   string
+	l, r node
+}
+
+func (n logic) eval(sec *Security) Value {
+	l := n.l.eval(sec).Truthy()
+	if n.op == "and" {
+		return boolean(l && n.r.eval(sec).Truthy())
+	}
+	return boolean(l || n.r.eval(sec).Truthy())
+}
+
+type compare struct {
+	op   string
+	l, r node
+}
+
+// eval compares numbers numerically and strings case-insensitively. A
+// null on either side is false whatever the operator, as in SQL.
+func (n compare) eval(sec *Security) Value {
+	l, r := n.l.eval(sec), n.r.eval(sec)
+	if l.Kind == Null || r.Kind == Null {
+		return boolean(false)
+	}
+	var c int
+	switch {
+	case l.Kind == String && r.Kind == String:
+		c = strings.Compare(strings.ToLower(l.Str), strings.ToLower(r.Str))
+	case l.Kind != String && r.Kind != String:
+		switch {
+		case l.Num < r.Num:
+			c = -1
+		case l.Num > r.Num:
+			c = 1
+		}
+	default:
+		return boolean(false)
+	}
+	switch n.op {
+	case "<":
+		return boolean(c < 0)
+	case "<=":
+		return boolean(c <= 0)
+	case ">":
+		return boolean(c > 0)
+	case ">=":
+		return boolean(c >= 0)
+	case "=":
+		return boolean(c == 0)
+	}
+	return boolean(c != 0)
+}
+
+type arith struct {
+	op   byte
+	l, r node
+}
+
+func (n arith) eval(sec *Security) Value {
+	l, r := n.l.eval(sec), n.r.eval(sec)
+	if l.Kind != Number && l.Kind != Bool || r.Kind != Number && r.Kind != Bool {
+		return null
+	}
+	switch n.op {
+	case '+':
+		return num(l.Num + r.Num)
+	case '-':
+		return num(l.Num - r.Num)
+	case '*':
+		return num(l.Num * r.Num)
+	}
+	if r.Num == 0 {
+		return null
+	}
+	return num(l.Num / r.Num)
+}
+
+type call struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n call) eval(sec *Security) Value {
+	args := make([]Value, len(n.args))
+	for i, a := range n.args {
+		args[i] = a.eval(sec)
+		if args[i].Kind == Null {
+			return null
+		}
+	}
+	return n.fn.eval(sec, args)
+}
+
+func walk(n node, fn func(node)) {
+	fn(n)
+	switch n := n.(type) {
+	case negate:
+		walk(n.x, fn)
+	case logic:
+		walk(n.l, fn)
+		walk(n.r, fn)
+	case compare:
+		walk(n.l, fn)
+		walk(n.r, fn)
+	case arith:
+		walk(n.l, fn)
+		walk(n.r, fn)
+	case call:
+		for _, a := range n.args {
+			walk(a, fn)
+		}
+	}
+}
+
+// ── functions ──
+
+type function struct {
+	min, max int
+	// periodArg means every argument is a literal look-back in bars.
+	periodArg bool
+	eval      func(sec *Security, args []Value) Value
+}
+
+func (f function) arity() string {
+	switch {
+	case f.min == f.max && f.min == 1:
+		return "1 argument"
+	case f.min == f.max:
+		return fmt.Sprintf("%d arguments", f.min)
+	}
+	return fmt.Sprintf("%d to %d arguments", f.min, f.max)
+}
+
+func period(args []Value) int { return int(args[0].Num) }
+
+// enough returns the closes when there are at least n of them.
+func enough(sec *Security, n int) ([]float64, bool) {
+	return sec.closes, len(sec.closes) >= n
+}
+
+var funcs = map[string]function{
+	"sma": {1, 1, true, func(sec *Security, a []Value) Value {
+		if c, ok := enough(sec, period(a)); ok {
+			return num(indicators.SMA(c, period(a)))
+		}
+		return null
+	}},
+	"ema": {1, 1, true, func(sec *Security, a []Value) Value {
+		if c, ok := enough(sec, period(a)); ok {
+			return num(indicators.EMA(c, period(a)))
+		}
+		return null
+	}},
+	"rsi": {1, 1, true, func(sec *Security, a []Value) Value {
+		if c, ok := enough(sec, period(a)+1); ok {
+			return num(indicators.RSI(c, period(a)))
+		}
+		return null
+	}},
+	"atr": {1, 1, true, func(sec *Security, a []Value) Value {
+		if len(sec.Bars) > period(a) {
+			return num(indicators.ATR(sec.Bars, period(a)))
+		}
+		return null
+	}},
+	// change(n) is the n-bar percent change.
+	"change": {1, 1, true, func(sec *Security, a []Value) Value {
+		if c, ok := enough(sec, period(a)+1); ok {
+			return num(indicators.PctChange(c, period(a)))
+		}
+		return null
+	}},
+	"high": {1, 1, true, func(sec *Security, a []Value) Value {
+		if len(sec.Bars) < period(a) {
+			return null
+		}
+		h, _ := indicators.HighLow(sec.Bars, period(a))
+		return num(h)
+	}},
+	"low": {1, 1, true, func(sec *Security, a []Value) Value {
+		if len(sec.Bars) < period(a) {
+			return null
+		}
+		_, l := indicators.HighLow(sec.Bars, period(a))
+		return num(l)
+	}},
+	"avgvolume": {1, 1, true, func(sec *Security, a []Value) Value {
+		n := period(a)
+		if len(sec.Bars) < n {
+			return null
+		}
+		var sum float64
+		for _, b := range sec.Bars[len(sec.Bars)-n:] {
+			sum += float64(b.Volume)
+		}
+		return num(sum / float64(n))
+	}},
+	"relvolume": {1, 1, true, func(sec *Security, a []Value) Value {
+		if rv := indicators.RelativeVolume(sec.Bars, period(a)); rv > 0 {
+			return num(rv)
+		}
+		return null
+	}},
+	"abs": {1, 1, false, func(_ *Security, a []Value) Value { return num(math.Abs(a[0].Num)) }},
+	"log": {1, 1, false, func(_ *Security, a []Value) Value {
+		if a[0].Num <= 0 {
+			return null
+		}
+		return num(math.Log(a[0].Num))
+	}},
+	"min": {2, 2, false, func(_ *Security, a []Value) Value { return num(math.Min(a[0].Num, a[1].Num)) }},
+	"max": {2, 2, false, func(_ *Security, a []Value) Value { return num(math.Max(a[0].Num, a[1].Num)) }},
+}
+
+// Functions lists the function names expressions can call.
+func Functions() []string {
+	return []string{"sma", "ema", "rsi", "atr", "change", "high", "low", "avgVolume", "relVolume", "abs", "log", "min", "max"}
+}
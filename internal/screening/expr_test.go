@@ -0,0 +1,76 @@
+package screening
+
+import (
+	"testing"
+
+	"stocktopus/internal/model"
+	"stocktopus/internal/store"
+)
+
+func rising(n int, start float64) []model.OHLCV {
+	bars := make([]model.OHLCV, n)
+	for i := range bars {
+		c := start + float64(i)
+		bars[i] = model.OHLCV{Open: c, High: c + 1, Low: c - 1, Close: c, Volume: 1000}
+	}
+	return bars
+}
+
+func TestExprEval(t *testing.T) {
+	sec := newSecurity(store.UniverseRow{
+		Symbol: "ACME", Sector: "Technology",
+		Fields: map[string]float64{"peRatio": 15, "revenueGrowth3y": 0.12, "marketCap": 5e9, "isEtf": 0},
+		Bars:   rising(250, 100),
+	})
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"peRatio < 20 and revenueGrowth3y > 0.1 and close > sma(200)", true},
+		{"PERATIO < 20 AND close < sma(200)", false},
+		{"sector = 'technology' and not isEtf", true},
+		{"marketCap >= 5B and marketCap < 1T", true},
+		{"revenueGrowth3y > 10%", true},
+		{"(peRatio > 30 or rsi(14) >= 70) && change(20) > 0", true},
+		{"close / sma(50) - 1 > 0.05", true},
+		{"high(20) - low(20) = 21", true},
+		{"dividendYield > 0", false},    // missing is null, and null compares false
+		{"not dividendYield > 0", true}, // so its negation is true
+		{"sma(400) > 0", false},         // not enough bars
+		{"peRatio / 0 > 1", false},      // division by zero is null
+		{"-peRatio < -10", true},
+		{"max(peRatio, 40) = 40 and abs(-3) = 3", true},
+		{"relVolume(20) = 1 and avgVolume(10) = 1000", true},
+	}
+	for _, tt := range tests {
+		x, err := Compile(tt.src)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.src, err)
+			continue
+		}
+		if got := x.Eval(sec).Truthy(); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		"peRatio <",
+		"sma(close) > 1",
+		"sma(200, 3) > 1",
+		"foo(3)",
+		"(peRatio > 1",
+		"peRatio > 1 extra",
+		"sector = 'tech",
+		"peRatio # 3",
+	} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("Compile(%q) should fail", src)
+		}
+	}
+	x, _ := Compile("peRatio < 20 and sma(200) < close and peRatio > 1")
+	if ids := x.Idents(); len(ids) != 2 || ids[0] != "peRatio" || ids[1] != "close" {
+		t.Errorf("Idents = %v", ids)
+	}
+}
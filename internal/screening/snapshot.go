@@ -0,0 +1,356 @@
+package screening
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"stocktopus/internal/budget"
+	"stocktopus/internal/model"
+	"stocktopus/internal/store"
+)
+
+// Source is the FMP surface the snapshot is built from.
+type Source interface {
+	GetIndexConstituents(ctx context.Context, symbol string) (json.RawMessage, error)
+	GetProfile(ctx context.Context, symbol string) (json.RawMessage, error)
+	GetKeyMetrics(ctx context.Context, symbol string) (json.RawMessage, error)
+	GetRatiosTTM(ctx context.Context, symbol string) (json.RawMessage, error)
+	GetIncomeStatement(ctx context.Context, symbol string, limit int) (json.RawMessage, error)
+	GetHistoricalEOD(ctx context.Context, symbol, from, to string) ([]model.OHLCV, error)
+}
+
+// Store is the part of the store the snapshot lives in. Watched symbols
+// always join the universe.
+type Store interface {
+	store.UniverseRepository
+	GetAllWatchedSymbols() ([]string, error)
+}
+
+const (
+	snapshotConcurrency = 4
+	// snapshotLookback covers 252 sessions plus slack for holidays.
+	snapshotLookback = 400 * 24 * time.Hour
+)
+
+// aliases give the FMP field names the short forms people type. The
+// original names stay available too.
+var aliases = map[string][]string{
+	"peRatio":         {"priceToEarningsRatio"},
+	"pbRatio":         {"priceToBookRatio"},
+	"psRatio":         {"priceToSalesRatio"},
+	"pegRatio":        {"priceToEarningsGrowthRatio"},
+	"roe":             {"returnOnEquity"},
+	"roa":             {"returnOnAssets"},
+	"roic":            {"returnOnInvestedCapital"},
+	"grossMargin":     {"grossProfitMargin"},
+	"operatingMargin": {"operatingProfitMargin"},
+	"netMargin":       {"netProfitMargin"},
+	"debtToEquity":    {"debtToEquityRatio"},
+	"evToEbitda":      {"evToEBITDA", "enterpriseValueMultiple"},
+}
+
+// Snapshotter rebuilds the universe snapshot on an interval (nightly by
+// default) and loads it into the engine.
+type Snapshotter struct {
+	src    Source
+	store  Store
+	engine *Engine
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	universe []string
+	interval time.Duration
+	wake     chan struct{} // interval changed; reset the ticker
+	busy     sync.Mutex    // one rebuild at a time
+}
+
+// NewSnapshotter builds snapshots of universe — index symbols such as
+// ^GSPC expand to their constituents, anything else is taken as a ticker
+// — plus every watched symbol.
+func NewSnapshotter(src Source, st Store, engine *Engine, universe []string, interval time.Duration, logger *slog.Logger) *Snapshotter {
+	return &Snapshotter{
+		src:      src,
+		store:    st,
+		engine:   engine,
+		logger:   logger.With("component", "screening"),
+		universe: universe,
+		interval: interval,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// SetInterval changes how often the snapshot is rebuilt.
+func (s *Snapshotter) SetInterval(d time.Duration) {
+	s.mu.Lock()
+	s.interval = d
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// SetUniverse changes what the next rebuild covers.
+func (s *Snapshotter) SetUniverse(universe []string) {
+	s.mu.Lock()
+	s.universe = universe
+	s.mu.Unlock()
+}
+
+func (s *Snapshotter) settings() ([]string, time.Duration) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.universe, s.interval
+}
+
+// Run loads the stored snapshot, rebuilds it straight away if it is
+// missing or older than the interval, and then on each tick.
+func (s *Snapshotter) Run(ctx context.Context) {
+	ctx = budget.WithCaller(ctx, "screening", budget.Background)
+	_, interval := s.settings()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	s.logger.Info("universe snapshot started", "interval", interval)
+
+	rows, err := s.store.GetUniverse()
+	if err != nil {
+		s.logger.Error("load universe snapshot", "error", err)
+	}
+	s.engine.Load(rows)
+	if st := s.engine.Status(); st.Symbols == 0 || time.Since(st.AsOf) >= interval {
+		s.rebuild(ctx)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("universe snapshot stopped")
+			return
+		case <-ticker.C:
+			s.rebuild(ctx)
+		case <-s.wake:
+			_, interval := s.settings()
+			ticker.Reset(interval)
+		}
+	}
+}
+
+func (s *Snapshotter) rebuild(ctx context.Context) {
+	if n, err := s.Rebuild(ctx); err != nil {
+		s.logger.Error("universe snapshot failed", "error", err)
+	} else {
+		s.logger.Info("universe snapshot rebuilt", "symbols", n)
+	}
+}
+
+// Rebuild fetches every universe member, stores the rows, drops members
+// that left, and reloads the engine. A symbol that fails keeps its
+// previous row. It returns how many symbols were refreshed.
+func (s *Snapshotter) Rebuild(ctx context.Context) (int, error) {
+	if !s.busy.TryLock() {
+		return 0, fmt.Errorf("a rebuild is already running")
+	}
+	defer s.busy.Unlock()
+
+	symbols, err := s.members(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var failed []string
+	sem := make(chan struct{}, snapshotConcurrency)
+	for _, sym := range symbols {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(sym string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			row, err := s.fetch(ctx, sym)
+			if err == nil {
+				err = s.store.PutUniverseRow(row)
+			}
+			if err != nil {
+				s.logger.Debug("universe member not refreshed", "symbol", sym, "error", err)
+				mu.Lock()
+				failed = append(failed, sym)
+				mu.Unlock()
+			}
+		}(sym)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if len(failed) == len(symbols) && len(symbols) > 0 {
+		return 0, fmt.Errorf("every member failed, e.g. %s", failed[0])
+	}
+	if len(failed) > 0 {
+		s.logger.Warn("universe members not refreshed", "count", len(failed))
+	}
+	if n, err := s.store.PruneUniverse(symbols); err != nil {
+		s.logger.Warn("prune universe", "error", err)
+	} else if n > 0 {
+		s.logger.Info("universe members dropped", "count", n)
+	}
+	rows, err := s.store.GetUniverse()
+	if err != nil {
+		return 0, err
+	}
+	s.engine.Load(rows)
+	return len(symbols) - len(failed), nil
+}
+
+// members expands the configured universe and adds the watched symbols.
+func (s *Snapshotter) members(ctx context.Context) ([]string, error) {
+	universe, _ := s.settings()
+	seen := map[string]bool{}
+	var out []string
+	add := func(sym string) {
+		sym = strings.ToUpper(strings.TrimSpace(sym))
+		if sym != "" && !seen[sym] {
+			seen[sym] = true
+			out = append(out, sym)
+		}
+	}
+	for _, u := range universe {
+		if !strings.HasPrefix(u, "^") {
+			add(u)
+			continue
+		}
+		raw, err := s.src.GetIndexConstituents(ctx, u)
+		if err != nil {
+			return nil, fmt.Errorf("constituents of %s: %w", u, err)
+		}
+		var members []struct {
+			Symbol string `json:"symbol"`
+		}
+		if err := json.Unmarshal(raw, &members); err != nil {
+			return nil, fmt.Errorf("constituents of %s: %w", u, err)
+		}
+		for _, m := range members {
+			add(m.Symbol)
+		}
+	}
+	watched, err := s.store.GetAllWatchedSymbols()
+	if err != nil {
+		return nil, err
+	}
+	for _, sym := range watched {
+		add(sym)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// fetch builds one symbol's row. The profile is required; the other
+// sources only add fields when they answer.
+func (s *Snapshotter) fetch(ctx context.Context, sym string) (store.UniverseRow, error) {
+	row := store.UniverseRow{Symbol: sym, Fields: map[string]float64{}}
+	raw, err := s.src.GetProfile(ctx, sym)
+	if err != nil {
+		return row, fmt.Errorf("profile: %w", err)
+	}
+	profile := first(raw)
+	if profile == nil {
+		return row, fmt.Errorf("no profile")
+	}
+	row.Name, _ = profile["companyName"].(string)
+	row.Sector, _ = profile["sector"].(string)
+	row.Industry, _ = profile["industry"].(string)
+	row.Exchange, _ = profile["exchange"].(string)
+	flatten(row.Fields, profile)
+
+	if raw, err := s.src.GetKeyMetrics(ctx, sym); err == nil {
+		flatten(row.Fields, first(raw))
+	}
+	if raw, err := s.src.GetRatiosTTM(ctx, sym); err == nil {
+		flatten(row.Fields, first(raw))
+	}
+	if raw, err := s.src.GetIncomeStatement(ctx, sym, 4); err == nil {
+		growth(row.Fields, raw)
+	}
+	for short, names := range aliases {
+		for _, n := range names {
+			if v, ok := row.Fields[n]; ok {
+				row.Fields[short] = v
+				break
+			}
+		}
+	}
+
+	now := time.Now()
+	bars, err := s.src.GetHistoricalEOD(ctx, sym, now.Add(-snapshotLookback).Format("2006-01-02"), now.Format("2006-01-02"))
+	if err != nil {
+		return row, fmt.Errorf("bars: %w", err)
+	}
+	if len(bars) > 260 {
+		bars = bars[len(bars)-260:]
+	}
+	row.Bars = bars
+	return row, nil
+}
+
+// first returns the first object of an FMP array response.
+func first(raw json.RawMessage) map[string]any {
+	var arr []map[string]any
+	if json.Unmarshal(raw, &arr) != nil || len(arr) == 0 {
+		return nil
+	}
+	return arr[0]
+}
+
+// flatten copies obj's numeric and boolean fields into fields, dropping
+// the TTM suffix FMP puts on trailing-twelve-month ratios.
+func flatten(fields map[string]float64, obj map[string]any) {
+	for k, v := range obj {
+		k = strings.TrimSuffix(k, "TTM")
+		switch v := v.(type) {
+		case float64:
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				fields[k] = v
+			}
+		case bool:
+			if v {
+				fields[k] = 1
+			} else {
+				fields[k] = 0
+			}
+		}
+	}
+}
+
+// growth derives revenue and EPS growth from annual income statements,
+// newest first: revenueGrowth and epsGrowth year on year, and
+// revenueGrowth3y as a compound annual rate.
+func growth(fields map[string]float64, raw json.RawMessage) {
+	var stmts []struct {
+		Revenue   float64 `json:"revenue"`
+		NetIncome float64 `json:"netIncome"`
+		EPS       float64 `json:"eps"`
+	}
+	if json.Unmarshal(raw, &stmts) != nil || len(stmts) == 0 {
+		return
+	}
+	fields["revenue"] = stmts[0].Revenue
+	fields["netIncome"] = stmts[0].NetIncome
+	fields["eps"] = stmts[0].EPS
+	if len(stmts) > 1 && stmts[1].Revenue > 0 {
+		fields["revenueGrowth"] = stmts[0].Revenue/stmts[1].Revenue - 1
+	}
+	if len(stmts) > 1 && stmts[1].EPS > 0 {
+		fields["epsGrowth"] = stmts[0].EPS/stmts[1].EPS - 1
+	}
+	if len(stmts) > 3 && stmts[3].Revenue > 0 && stmts[0].Revenue > 0 {
+		fields["revenueGrowth3y"] = math.Pow(stmts[0].Revenue/stmts[3].Revenue, 1.0/3) - 1
+	}
+}
@@ -0,0 +1,58 @@
+package screens
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	at := func(y int, m time.Month, d, h, min int) time.Time { return time.Date(y, m, d, h, min, 0, 0, ny) }
+	fri := at(2026, 10, 16, 9, 40) // a Friday
+
+	cases := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"35 9 * * 1-5", at(2026, 10, 16, 9, 0), at(2026, 10, 16, 9, 35)},
+		{"35 9 * * mon-fri", fri, at(2026, 10, 19, 9, 35)}, // skips the weekend
+		{"*/15 * * * *", at(2026, 10, 16, 9, 30), at(2026, 10, 16, 9, 45)},
+		{"0 9-16/2 * * *", fri, at(2026, 10, 16, 11, 0)},
+		{"0 0 1 jan *", fri, at(2027, 1, 1, 0, 0)},
+		{"0 12 1 * sun", fri, at(2026, 10, 18, 12, 0)}, // either day field matches
+		{"@weekly", fri, at(2026, 10, 18, 0, 0)},
+		{"0 8 * * 7", fri, at(2026, 10, 18, 8, 0)},
+	}
+	for _, c := range cases {
+		s, err := ParseSchedule(c.spec)
+		if err != nil {
+			t.Errorf("%s: %v", c.spec, err)
+			continue
+		}
+		if got := s.Next(c.from); !got.Equal(c.want) {
+			t.Errorf("%s after %s = %s, want %s", c.spec, c.from, got, c.want)
+		}
+	}
+
+	// Across the spring-forward gap the wall clock still reads 9:35.
+	s, _ := ParseSchedule("35 9 * * *")
+	if got := s.Next(at(2026, 3, 7, 12, 0)); !got.Equal(at(2026, 3, 8, 9, 35)) {
+		t.Errorf("DST next = %s", got)
+	}
+	never, _ := ParseSchedule("0 0 30 2 *")
+	if got := never.Next(fri); !got.IsZero() {
+		t.Errorf("30 February fired at %s", got)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8", "5-1 * * * *", "*/0 * * * *", "0 0 * * funday"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("%q parsed", spec)
+		}
+	}
+}
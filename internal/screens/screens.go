@@ -0,0 +1,254 @@
+// Package screens runs saved screener queries on their cron schedules,
+// keeps each run's ranked result set and announces how it differs from
+// the previous run — new entrants, dropouts and rank changes — over the
+// hub and the screen's webhook.
+package screens
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"stocktopus/internal/budget"
+	"stocktopus/internal/hub"
+	"stocktopus/internal/store"
+	"stocktopus/internal/webhook"
+)
+
+// Topic carries the diffs of every saved screen.
+const Topic = "screens"
+
+// MsgScreenDiff is the hub/webhook message type for a run that changed
+// the result set.
+const MsgScreenDiff = "screen_diff"
+
+// DefaultTimezone is where schedules are read when a screen names none:
+// "35 9 * * 1-5" means five past the US open.
+const DefaultTimezone = "America/New_York"
+
+// tick is how often schedules are checked; cron's resolution is a minute.
+const tick = time.Minute
+
+// Screener runs a screener query and returns its rows ranked by sortBy
+// (the screener's own order when empty).
+type Screener func(ctx context.Context, query url.Values, sortBy string, desc bool) ([]store.ScreenRow, error)
+
+// Publisher is the part of the hub the scheduler needs.
+type Publisher interface {
+	Publish(topic string, data []byte)
+}
+
+// Event is what a changed run publishes.
+type Event struct {
+	ScreenID int64            `json:"screenId"`
+	Name     string           `json:"name"`
+	RunID    int64            `json:"runId"`
+	RanAt    time.Time        `json:"ranAt"`
+	Count    int              `json:"count"`
+	Diff     store.ScreenDiff `json:"diff"`
+}
+
+// Validate checks a screen's schedule and timezone before it's saved, so
+// a typo fails at creation rather than never firing.
+func Validate(sc store.SavedScreen) error {
+	if strings.TrimSpace(sc.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if sc.Schedule != "" {
+		sched, err := ParseSchedule(sc.Schedule)
+		if err != nil {
+			return err
+		}
+		if sched.Next(time.Now()).IsZero() {
+			return fmt.Errorf("schedule %q never fires", sc.Schedule)
+		}
+	}
+	if sc.Timezone != "" {
+		if _, err := time.LoadLocation(sc.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", sc.Timezone)
+		}
+	}
+	if sc.WebhookURL != "" {
+		u, err := url.Parse(sc.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhookUrl must be an absolute http(s) URL")
+		}
+	}
+	return nil
+}
+
+// Compare diffs two ranked result sets.
+func Compare(prev, cur []store.ScreenRow) store.ScreenDiff {
+	d := store.ScreenDiff{Entrants: []store.ScreenRow{}, Dropouts: []store.ScreenRow{}, Moves: []store.ScreenMove{}}
+	before := make(map[string]int, len(prev))
+	for _, r := range prev {
+		before[r.Symbol] = r.Rank
+	}
+	now := make(map[string]bool, len(cur))
+	for _, r := range cur {
+		now[r.Symbol] = true
+		from, ok := before[r.Symbol]
+		switch {
+		case !ok:
+			d.Entrants = append(d.Entrants, r)
+		case from != r.Rank:
+			d.Moves = append(d.Moves, store.ScreenMove{Symbol: r.Symbol, From: from, To: r.Rank})
+		}
+	}
+	for _, r := range prev {
+		if !now[r.Symbol] {
+			d.Dropouts = append(d.Dropouts, r)
+		}
+	}
+	return d
+}
+
+// Scheduler runs saved screens when their schedules fire.
+type Scheduler struct {
+	store    store.ScreenRepository
+	hub      Publisher
+	webhooks *webhook.Sender
+	screen   Screener
+	logger   *slog.Logger
+	now      func() time.Time
+
+	busy sync.Mutex // one run at a time, so diffs see each other
+}
+
+func New(st store.ScreenRepository, h Publisher, wh *webhook.Sender, screen Screener, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		store:    st,
+		hub:      h,
+		webhooks: wh,
+		screen:   screen,
+		logger:   logger.With("component", "screens"),
+		now:      time.Now,
+	}
+}
+
+// Run checks the schedules every minute until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ctx = budget.WithCaller(ctx, "screens", budget.Background)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	s.logger.Info("screen scheduler started")
+
+	s.RunDue(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("screen scheduler stopped")
+			return
+		case <-ticker.C:
+			s.RunDue(ctx)
+		}
+	}
+}
+
+// RunDue runs every scheduled screen whose next fire time, counted from
+// its last run (or creation), has passed. A screen that missed several
+// fires while the app was down runs once.
+func (s *Scheduler) RunDue(ctx context.Context) {
+	screens, err := s.store.GetScheduledScreens()
+	if err != nil {
+		s.logger.Error("list scheduled screens", "error", err)
+		return
+	}
+	now := s.now()
+	for _, sc := range screens {
+		if ctx.Err() != nil {
+			return
+		}
+		if !s.due(sc, now) {
+			continue
+		}
+		if _, err := s.Execute(ctx, sc); err != nil {
+			s.logger.Warn("saved screen failed", "screen", sc.Name, "error", err)
+		}
+	}
+}
+
+func (s *Scheduler) due(sc store.SavedScreen, now time.Time) bool {
+	sched, err := ParseSchedule(sc.Schedule)
+	if err != nil {
+		s.logger.Warn("bad screen schedule", "screen", sc.Name, "error", err)
+		return false
+	}
+	tz := sc.Timezone
+	if tz == "" {
+		tz = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		loc = time.UTC
+	}
+	from := sc.CreatedAt
+	if sc.LastRunAt != nil {
+		from = *sc.LastRunAt
+	}
+	next := sched.Next(from.In(loc))
+	return !next.IsZero() && !next.After(now)
+}
+
+// Execute runs a screen now, records the run and, when the result set
+// changed, publishes the diff. A failing query is recorded too, and the
+// next run diffs against the last one that worked.
+func (s *Scheduler) Execute(ctx context.Context, sc store.SavedScreen) (*store.ScreenRun, error) {
+	s.busy.Lock()
+	defer s.busy.Unlock()
+
+	run := store.ScreenRun{ScreenID: sc.ID, RanAt: s.now().UTC()}
+	query, err := url.ParseQuery(sc.Query)
+	if err == nil {
+		run.Results, err = s.screen(ctx, query, sc.SortBy, sc.Descending)
+	}
+	if err != nil {
+		run.Error = err.Error()
+		if _, serr := s.store.AddScreenRun(run); serr != nil {
+			s.logger.Error("record screen run", "screen", sc.Name, "error", serr)
+		}
+		return nil, err
+	}
+	if run.Results == nil {
+		run.Results = []store.ScreenRow{}
+	}
+
+	prev, err := s.store.GetLastScreenResults(sc.ID)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		d := Compare(prev.Results, run.Results)
+		run.Diff = &d
+	}
+	if run.ID, err = s.store.AddScreenRun(run); err != nil {
+		return nil, err
+	}
+	if run.Diff != nil && !run.Diff.Empty() {
+		s.logger.Info("saved screen changed", "screen", sc.Name,
+			"entrants", len(run.Diff.Entrants), "dropouts", len(run.Diff.Dropouts), "moves", len(run.Diff.Moves))
+		s.publish(sc, run)
+	}
+	return &run, nil
+}
+
+func (s *Scheduler) publish(sc store.SavedScreen, run store.ScreenRun) {
+	ev := Event{ScreenID: sc.ID, Name: sc.Name, RunID: run.ID, RanAt: run.RanAt, Count: len(run.Results), Diff: *run.Diff}
+	if s.hub != nil {
+		payload, _ := json.Marshal(ev)
+		msg, err := json.Marshal(hub.OutboundMessage{
+			Type:    MsgScreenDiff,
+			Topic:   Topic,
+			Payload: payload,
+		})
+		if err == nil {
+			s.hub.Publish(Topic, msg)
+		}
+	}
+	s.webhooks.Send(sc.WebhookURL, MsgScreenDiff, ev)
+}
@@ -0,0 +1,150 @@
+package screens
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stocktopus/internal/hub"
+	"stocktopus/internal/store"
+)
+
+type recorder struct{ msgs []hub.OutboundMessage }
+
+func (r *recorder) Publish(topic string, data []byte) {
+	var m hub.OutboundMessage
+	json.Unmarshal(data, &m)
+	r.msgs = append(r.msgs, m)
+}
+
+func rows(symbols ...string) []store.ScreenRow {
+	out := make([]store.ScreenRow, len(symbols))
+	for i, s := range symbols {
+		out[i] = store.ScreenRow{Rank: i + 1, Symbol: s}
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	d := Compare(rows("NVDA", "AMD", "INTC"), rows("AMD", "NVDA", "SMCI"))
+	if len(d.Entrants) != 1 || d.Entrants[0].Symbol != "SMCI" || d.Entrants[0].Rank != 3 {
+		t.Errorf("entrants = %+v", d.Entrants)
+	}
+	if len(d.Dropouts) != 1 || d.Dropouts[0].Symbol != "INTC" || d.Dropouts[0].Rank != 3 {
+		t.Errorf("dropouts = %+v", d.Dropouts)
+	}
+	if len(d.Moves) != 2 || d.Moves[0] != (store.ScreenMove{Symbol: "AMD", From: 2, To: 1}) {
+		t.Errorf("moves = %+v", d.Moves)
+	}
+	if !Compare(rows("A", "B"), rows("A", "B")).Empty() {
+		t.Error("identical runs differ")
+	}
+}
+
+func TestSchedulerRunsDueScreensAndPublishesDiffs(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "screens.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer st.Close()
+
+	id, err := st.CreateSavedScreen(store.SavedScreen{
+		Name: "Gappers", Query: "changeFromOpenMin=2", SortBy: "changeFromOpen", Descending: true,
+		Schedule: "35 9 * * 1-5", Timezone: "UTC", Enabled: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := rows("NVDA", "AMD")
+	var gotQuery url.Values
+	var gotSort string
+	screen := func(ctx context.Context, q url.Values, sortBy string, desc bool) ([]store.ScreenRow, error) {
+		gotQuery, gotSort = q, sortBy
+		if results == nil {
+			return nil, errors.New("upstream down")
+		}
+		return results, nil
+	}
+	pub := &recorder{}
+	s := New(st, pub, nil, screen, slog.Default())
+
+	// Created now, the 09:35 fire is still ahead of us.
+	s.now = func() time.Time { return time.Now().Add(-time.Minute) }
+	s.RunDue(context.Background())
+	if runs, _ := st.GetScreenRuns(id, 10); len(runs) != 0 {
+		t.Fatalf("ran before the schedule fired: %+v", runs)
+	}
+
+	// A week on, it has fired at least once: one catch-up run.
+	clock := time.Now().Add(7 * 24 * time.Hour)
+	s.now = func() time.Time { return clock }
+	s.RunDue(context.Background())
+	s.RunDue(context.Background())
+	runs, _ := st.GetScreenRuns(id, 10)
+	if len(runs) != 1 || runs[0].Diff != nil || len(runs[0].Results) != 2 {
+		t.Fatalf("first runs = %+v", runs)
+	}
+	if gotQuery.Get("changeFromOpenMin") != "2" || gotSort != "changeFromOpen" {
+		t.Errorf("screen got %v sorted by %q", gotQuery, gotSort)
+	}
+	if len(pub.msgs) != 0 {
+		t.Errorf("first run published %+v", pub.msgs)
+	}
+
+	sc, _ := st.GetSavedScreen(0, id)
+	results = nil
+	if _, err := s.Execute(context.Background(), *sc); err == nil {
+		t.Fatal("failing screen returned no error")
+	}
+
+	results = rows("AMD", "SMCI")
+	run, err := s.Execute(context.Background(), *sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Diff == nil || len(run.Diff.Entrants) != 1 || len(run.Diff.Dropouts) != 1 || len(run.Diff.Moves) != 1 {
+		t.Fatalf("diff against the last good run = %+v", run.Diff)
+	}
+	if len(pub.msgs) != 1 || pub.msgs[0].Type != MsgScreenDiff || pub.msgs[0].Topic != Topic {
+		t.Fatalf("messages = %+v", pub.msgs)
+	}
+	var ev Event
+	json.Unmarshal(pub.msgs[0].Payload, &ev)
+	if ev.ScreenID != id || ev.RunID != run.ID || ev.Diff.Entrants[0].Symbol != "SMCI" || ev.Diff.Dropouts[0].Symbol != "NVDA" {
+		t.Errorf("event = %+v", ev)
+	}
+
+	if _, err := s.Execute(context.Background(), *sc); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.msgs) != 1 {
+		t.Errorf("unchanged run published %d messages", len(pub.msgs)-1)
+	}
+	if runs, _ := st.GetScreenRuns(id, 10); len(runs) != 4 || runs[2].Error != "upstream down" {
+		t.Errorf("history = %+v", runs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	good := store.SavedScreen{Name: "x", Schedule: "0 9 * * 1-5", Timezone: "Europe/London", WebhookURL: "https://example.com/hook"}
+	if err := Validate(good); err != nil {
+		t.Errorf("valid screen: %v", err)
+	}
+	for _, bad := range []store.SavedScreen{
+		{Name: " "},
+		{Name: "x", Schedule: "every morning"},
+		{Name: "x", Schedule: "0 0 31 2 *"},
+		{Name: "x", Timezone: "Mars/Olympus"},
+		{Name: "x", WebhookURL: "ftp://example.com"},
+	} {
+		if err := Validate(bad); err == nil {
+			t.Errorf("%+v validated", bad)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"stocktopus/internal/budget"
 	"stocktopus/internal/hub"
 	"stocktopus/internal/news"
 	"stocktopus/internal/store"
@@ -17,15 +18,19 @@ import (
 type Poller struct {
 	client   *news.Client
 	hub      *hub.Hub
-	store    *store.Store
+	store    store.IntelligenceRepository
 	logger   *slog.Logger
 	interval time.Duration
 
 	sectors map[string]bool
 	mu      sync.RWMutex
+	wake    chan struct{}   // interval changed; re-arm the timer
+	ctx     context.Context // Run's context; on-demand polls share it
+
+	budget *budget.Scheduler // nil = fixed interval
 }
 
-func New(client *news.Client, h *hub.Hub, st *store.Store, interval time.Duration, logger *slog.Logger) *Poller {
+func New(client *news.Client, h *hub.Hub, st store.IntelligenceRepository, interval time.Duration, logger *slog.Logger) *Poller {
 	return &Poller{
 		client:   client,
 		hub:      h,
@@ -33,9 +38,41 @@ func New(client *news.Client, h *hub.Hub, st *store.Store, interval time.Duratio
 		logger:   logger.With("component", "sectorpoller"),
 		interval: interval,
 		sectors:  make(map[string]bool),
+		wake:     make(chan struct{}, 1),
+		ctx:      budget.WithCaller(context.Background(), "sectors", budget.Background),
+	}
+}
+
+// SetBudget lets the shared FMP budget stretch the refresh interval as
+// the plan's per-minute window fills.
+func (p *Poller) SetBudget(b *budget.Scheduler) {
+	p.budget = b
+}
+
+// SetInterval changes the refresh period, which is also how long a
+// sector's stored intelligence counts as fresh.
+func (p *Poller) SetInterval(d time.Duration) {
+	p.mu.Lock()
+	p.interval = d
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
 	}
 }
 
+func (p *Poller) currentInterval() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.interval
+}
+
+func (p *Poller) runCtx() context.Context {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.ctx
+}
+
 // OnFirstSubscribe is called when a client subscribes to a sector topic.
 func (p *Poller) OnFirstSubscribe(topic string) {
 	sector := topicToSector(topic)
@@ -47,7 +84,7 @@ func (p *Poller) OnFirstSubscribe(topic string) {
 	p.mu.Unlock()
 	p.logger.Info("sector bot 9000: activated", "sector", sector, "trigger", "on-demand")
 
-	go p.pollSector(context.Background(), sector)
+	go p.pollSector(p.runCtx(), sector)
 }
 
 // OnLastUnsubscribe is called when the last client unsubscribes.
@@ -64,17 +101,24 @@ func (p *Poller) OnLastUnsubscribe(topic string) {
 
 // Run starts the polling loop.
 func (p *Poller) Run(ctx context.Context) {
-	ticker := time.NewTicker(p.interval)
-	defer ticker.Stop()
-	p.logger.Info("sector poller started", "interval", p.interval)
+	ctx = budget.WithCaller(ctx, "sectors", budget.Background)
+	p.mu.Lock()
+	p.ctx = ctx
+	p.mu.Unlock()
+	timer := time.NewTimer(p.budget.Scale(p.currentInterval()))
+	defer timer.Stop()
+	p.logger.Info("sector poller started", "interval", p.currentInterval())
 
 	for {
 		select {
 		case <-ctx.Done():
 			p.logger.Info("sector poller stopped")
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			p.pollAll(ctx)
+			timer.Reset(p.budget.Scale(p.currentInterval()))
+		case <-p.wake:
+			timer.Reset(p.budget.Scale(p.currentInterval()))
 		}
 	}
 }
@@ -93,7 +137,7 @@ func (p *Poller) pollAll(ctx context.Context) {
 }
 
 func (p *Poller) pollSector(ctx context.Context, sector string) {
-	if p.store != nil && p.store.IsSectorFresh(sector, p.interval) {
+	if p.store != nil && p.store.IsSectorFresh(sector, p.currentInterval()) {
 		p.logger.Debug("sector fresh, skipping", "sector", sector)
 		return
 	}
@@ -131,7 +175,7 @@ func (p *Poller) TriggerSector(sector string) {
 	p.mu.Lock()
 	p.sectors[sector] = true
 	p.mu.Unlock()
-	go p.pollSector(context.Background(), sector)
+	go p.pollSector(p.runCtx(), sector)
 }
 
 func topicToSector(topic string) string {
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stocktopus/internal/alerts"
+	"stocktopus/internal/store"
+)
+
+// SetAlertEngine attaches the news alert engine so watch edits re-pin the
+// news categories the enabled watches need.
+func (s *Server) SetAlertEngine(e *alerts.Engine) {
+	s.alerts = e
+}
+
+// syncAlertPins re-evaluates the pinned news categories after a watch
+// changes.
+func (s *Server) syncAlertPins() {
+	if s.alerts != nil {
+		s.alerts.SyncPins()
+	}
+}
+
+// handleListNewsWatches returns the global user's news watches.
+func (s *Server) handleListNewsWatches(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		json.NewEncoder(w).Encode([]any{})
+		return
+	}
+	watches, err := s.store.ListNewsWatches(globalOwnerID)
+	if err != nil {
+		s.logger.Error("list news watches", "error", err)
+		http.Error(w, "list failed", http.StatusInternalServerError)
+		return
+	}
+	if watches == nil {
+		watches = []store.NewsWatch{}
+	}
+	json.NewEncoder(w).Encode(watches)
+}
+
+// handleCreateNewsWatch validates and persists a watch. Query watches are
+// parsed here so syntax errors come back as 400 with the offset.
+func (s *Server) handleCreateNewsWatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var req store.NewsWatch
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	req.UserID = globalOwnerID
+	req.Kind = strings.ToLower(strings.TrimSpace(req.Kind))
+	req.Expr = strings.TrimSpace(req.Expr)
+	req.WebhookURL = strings.TrimSpace(req.WebhookURL)
+	if err := alerts.Validate(req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	id, err := s.store.CreateNewsWatch(req)
+	if err != nil {
+		s.logger.Error("create news watch", "error", err)
+		http.Error(w, "create failed", http.StatusInternalServerError)
+		return
+	}
+	s.syncAlertPins()
+	json.NewEncoder(w).Encode(map[string]any{"id": id})
+}
+
+// handleUpdateNewsWatch toggles a watch on/off.
+func (s *Server) handleUpdateNewsWatch(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	if err := s.store.SetNewsWatchEnabled(globalOwnerID, id, req.Enabled); err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	s.syncAlertPins()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDeleteNewsWatch(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	if err := s.store.DeleteNewsWatch(globalOwnerID, id); err != nil {
+		http.Error(w, "delete failed", http.StatusInternalServerError)
+		return
+	}
+	s.syncAlertPins()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleNewsInbox returns matched stories, newest first. ?unread=1 limits
+// to unread rows; ?limit caps the page (default 100).
+func (s *Server) handleNewsInbox(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		json.NewEncoder(w).Encode([]any{})
+		return
+	}
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 && n <= 500 {
+			limit = n
+		}
+	}
+	unread := r.URL.Query().Get("unread") == "1"
+	items, err := s.store.GetInbox(globalOwnerID, unread, limit)
+	if err != nil {
+		s.logger.Error("news inbox", "error", err)
+		http.Error(w, "inbox failed", http.StatusInternalServerError)
+		return
+	}
+	if items == nil {
+		items = []store.InboxItem{}
+	}
+	json.NewEncoder(w).Encode(items)
+}
+
+func (s *Server) handleMarkInboxRead(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	if err := s.store.MarkInboxRead(globalOwnerID, id); err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"stocktopus/internal/corpactions"
 	"stocktopus/internal/engine/backtest"
 )
 
@@ -22,11 +23,14 @@ type backtestResponse struct {
 	Policy     backtest.SimResult   `json:"policy"`      // the $10k lookahead-free walk + decision trace
 	BuyHold    float64              `json:"buyHoldEquity"`
 	Hindsight  float64              `json:"hindsightEquity"`
+	Adjustment string               `json:"adjustment"`  // corpactions mode the bars were run on
 }
 
 // GET /api/backtest/optimal-entry/{symbol}?from=&to=&horizon=
 // from/to bound the *selected window*; we fetch through to the latest bar so the
 // holding-horizon outcomes (the bars past `to`) are available to score entries.
+// Bars are split-adjusted with dividends on their ex-dates so the $10k walk is
+// paid them; if corporate actions can't be loaded it runs on raw bars.
 func (s *Server) handleBacktestOptimalEntry(w http.ResponseWriter, r *http.Request) {
 	symbol := r.PathValue("symbol")
 	from := r.URL.Query().Get("from")
@@ -53,6 +57,13 @@ func (s *Server) handleBacktestOptimalEntry(w http.ResponseWriter, r *http.Reque
 		writeErr(http.StatusUnprocessableEntity, "not enough price history for "+symbol)
 		return
 	}
+	adjustment := corpactions.Raw
+	if actions, err := s.fetchOrLoadCorporateActions(r, symbol); err != nil {
+		s.logger.Warn("backtest corporate actions unavailable, using raw bars", "symbol", symbol, "error", err)
+	} else {
+		adjustment = corpactions.Split
+		bars = corpactions.Adjust(bars, actions.Splits, actions.Dividends, adjustment)
+	}
 
 	// windowEnd = last bar at or before `to` (the selection end); bars after it
 	// are the forward outcome window. Empty `to` → use all but the last bar.
@@ -95,5 +106,6 @@ func (s *Server) handleBacktestOptimalEntry(w http.ResponseWriter, r *http.Reque
 		Symbol: symbol, From: from, To: to, Horizon: horizon, Bars: len(bars),
 		StartCash: startCash, Optimal: res.Optimal, Candidates: cands,
 		Policy: policy, BuyHold: buyHold, Hindsight: hindsight,
+		Adjustment: string(adjustment),
 	})
 }
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"stocktopus/internal/store"
+)
+
+// maxBundleBytes caps a restore upload. Bundles hold user data only, so
+// even a long-lived install is a few megabytes.
+const maxBundleBytes = 64 << 20
+
+// handleBackup downloads the user-data bundle; ?gzip=1 compresses it.
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	b, err := s.store.Backup()
+	if err != nil {
+		s.logger.Error("backup", "error", err)
+		http.Error(w, "backup failed", http.StatusInternalServerError)
+		return
+	}
+	compress := r.URL.Query().Get("gzip") == "1"
+	name := "stocktopus-backup-" + b.CreatedAt.Format("20060102-150405") + ".json"
+	w.Header().Set("Content-Type", "application/json")
+	if compress {
+		name += ".gz"
+		w.Header().Set("Content-Type", "application/gzip")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	if err := store.WriteBundle(w, b, compress); err != nil {
+		s.logger.Warn("backup write", "error", err)
+	}
+}
+
+// handleRestore loads a bundle (JSON or gzipped JSON) from the request
+// body. ?mode=merge (default) or ?mode=replace; incompatible bundles are
+// rejected with 400 before anything is written; a failure while writing
+// is a 500.
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	mode, err := store.ParseRestoreMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := store.ReadBundle(http.MaxBytesReader(w, r.Body, maxBundleBytes))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err := b.Check(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	start := time.Now()
+	res, err := s.store.Restore(b, mode)
+	if err != nil {
+		s.logger.Error("restore", "mode", mode, "error", err)
+		http.Error(w, "restore failed", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("restored backup", "mode", mode, "schemaVersion", b.SchemaVersion, "createdAt", b.CreatedAt, "took", time.Since(start))
+	json.NewEncoder(w).Encode(res)
+}
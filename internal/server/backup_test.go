@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"stocktopus/internal/store"
+)
+
+func TestRestoreStatus(t *testing.T) {
+	srv, mux := testServer(t)
+	st, err := store.New(filepath.Join(t.TempDir(), "backup.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	srv.store = st
+
+	for _, tc := range []struct {
+		name, body string
+		code       int
+	}{
+		{"foreign format", `{"format": "other", "version": 1}`, http.StatusBadRequest},
+		{"unknown table", `{"format": "stocktopus-backup", "version": 1, "tables": {"nope": []}}`, http.StatusBadRequest},
+		// Passes Check but fails inside the transaction: a server error,
+		// without the driver's text.
+		{"bad row", `{"format": "stocktopus-backup", "version": 1, "tables": {"watchlists": [{"bogus": 1}]}}`, http.StatusInternalServerError},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/restore", strings.NewReader(tc.body)))
+		if w.Code != tc.code {
+			t.Errorf("%s: status %d, want %d (%s)", tc.name, w.Code, tc.code, w.Body)
+		}
+		if tc.code == http.StatusInternalServerError && strings.TrimSpace(w.Body.String()) != "restore failed" {
+			t.Errorf("%s: leaked %q", tc.name, w.Body)
+		}
+	}
+}
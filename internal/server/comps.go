@@ -0,0 +1,629 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// comps.go builds a comparable company analysis: the security's peer set
+// (FMP /stock-peers, or a list the user edits) laid side by side on the
+// same fundamentalFields metrics the sketchpad uses, the peer median /
+// mean / quartiles of each, where the subject sits against them, and the
+// share prices implied by applying the peer multiples to the subject.
+//
+// Metrics are the latest fiscal year's, as /key-metrics and /ratios report
+// them; revenue growth comes off the last two annual income statements.
+
+const (
+	compsMaxPeers    = 25
+	compsConcurrency = 4
+	// compsTTL bounds how long a fetched company row or peer list is
+	// reused. A table is ~4 FMP calls per company; with the cache, the CSV
+	// export of the table just shown, or a peer toggle, costs nothing.
+	compsTTL = 15 * time.Minute
+)
+
+// Multiple kinds — how an implied price is backed out of a multiple.
+const (
+	compsEV    = "ev"    // enterprise-value multiple: bridge through net debt
+	compsPrice = "price" // equity multiple: scales market cap directly
+)
+
+// compsMetric is one column of the comps table. Key is a fundamentalFields
+// name (or revenueGrowth, which isn't one); Multiple is set for valuation
+// multiples, which are the ones that imply a price.
+type compsMetric struct {
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	Multiple string `json:"multiple,omitempty"`
+	Percent  bool   `json:"percent,omitempty"`
+}
+
+var compsMetrics = []compsMetric{
+	{Key: "evToEBITDA", Label: "EV/EBITDA", Multiple: compsEV},
+	{Key: "evToSales", Label: "EV/Sales", Multiple: compsEV},
+	{Key: "peRatio", Label: "P/E", Multiple: compsPrice},
+	{Key: "priceToSalesRatio", Label: "P/S", Multiple: compsPrice},
+	{Key: "priceToBookRatio", Label: "P/B", Multiple: compsPrice},
+	{Key: "grossProfitMargin", Label: "Gross margin", Percent: true},
+	{Key: "operatingProfitMargin", Label: "Operating margin", Percent: true},
+	{Key: "netProfitMargin", Label: "Net margin", Percent: true},
+	{Key: "revenueGrowth", Label: "Revenue growth", Percent: true},
+	{Key: "returnOnEquity", Label: "ROE", Percent: true},
+}
+
+// CompsCompany is one row of the table. Price and MarketCap are current
+// (from /profile); FYMarketCap and FYEnterpriseValue are as of the fiscal
+// year the metrics describe, so the implied-price bridge uses the same
+// balance sheet the multiples were struck on.
+type CompsCompany struct {
+	Symbol            string             `json:"symbol"`
+	Name              string             `json:"name,omitempty"`
+	Price             float64            `json:"price"`
+	MarketCap         float64            `json:"marketCap"`
+	FYMarketCap       float64            `json:"fyMarketCap,omitempty"`
+	FYEnterpriseValue float64            `json:"fyEnterpriseValue,omitempty"`
+	Metrics           map[string]float64 `json:"metrics"`
+	// Excluded is set when the user dropped the company from the set;
+	// Outliers lists the metrics on which it was fenced out.
+	Excluded bool     `json:"excluded,omitempty"`
+	Outliers []string `json:"outliers,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// CompsStat summarises one metric across the included peers and places
+// the subject against them. Rank is the subject's percentile among the
+// peers (50 = at the median); Premium is subject / median − 1.
+type CompsStat struct {
+	compsMetric
+	N       int      `json:"n"`
+	Min     float64  `json:"min"`
+	Q1      float64  `json:"q1"`
+	Median  float64  `json:"median"`
+	Mean    float64  `json:"mean"`
+	Q3      float64  `json:"q3"`
+	Max     float64  `json:"max"`
+	Subject *float64 `json:"subject,omitempty"`
+	Rank    *float64 `json:"rank,omitempty"`
+	Premium *float64 `json:"premium,omitempty"`
+}
+
+// CompsImplied is the subject's share price at the peer quartiles of one
+// multiple. Upside is the median-implied price against the current one.
+type CompsImplied struct {
+	compsMetric
+	Q1     float64 `json:"q1"`
+	Median float64 `json:"median"`
+	Mean   float64 `json:"mean"`
+	Q3     float64 `json:"q3"`
+	Upside float64 `json:"upside"`
+}
+
+// CompsOptions are the user's edits to the peer set.
+type CompsOptions struct {
+	Exclude  []string `json:"exclude,omitempty"`
+	Outliers bool     `json:"outliers"` // fence each metric at 1.5×IQR
+}
+
+// CompsTable is the full analysis as served and exported.
+type CompsTable struct {
+	Subject  CompsCompany   `json:"subject"`
+	Peers    []CompsCompany `json:"peers"`
+	Metrics  []compsMetric  `json:"metrics"`
+	Stats    []CompsStat    `json:"stats"`
+	Implied  []CompsImplied `json:"implied"`
+	Outliers bool           `json:"outliers"`
+	AsOf     string         `json:"asOf"`
+}
+
+// compsValue returns a company's value for a metric, treating a
+// non-positive multiple as not meaningful (a loss-maker's P/E says
+// nothing about what the market pays for earnings).
+func compsValue(c CompsCompany, m compsMetric) (float64, bool) {
+	v, ok := c.Metrics[m.Key]
+	if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	if m.Multiple != "" && v <= 0 {
+		return 0, false
+	}
+	return v, true
+}
+
+// BuildComps computes the table from already-fetched companies. Peers
+// named in opts.Exclude are kept in the table but left out of every
+// statistic; with opts.Outliers, a peer's value outside the Tukey fences
+// (1.5×IQR beyond the quartiles) of a metric is left out of that metric
+// only. Fences need at least four values to be meaningful.
+func BuildComps(subject CompsCompany, peers []CompsCompany, opts CompsOptions) CompsTable {
+	excluded := make(map[string]bool, len(opts.Exclude))
+	for _, s := range opts.Exclude {
+		excluded[strings.ToUpper(strings.TrimSpace(s))] = true
+	}
+	t := CompsTable{
+		Subject:  subject,
+		Peers:    make([]CompsCompany, len(peers)),
+		Metrics:  compsMetrics,
+		Outliers: opts.Outliers,
+	}
+	for i, p := range peers {
+		p.Excluded = excluded[strings.ToUpper(p.Symbol)]
+		p.Outliers = nil
+		t.Peers[i] = p
+	}
+
+	for _, m := range compsMetrics {
+		var vals []float64
+		var idx []int
+		for i, p := range t.Peers {
+			if p.Excluded {
+				continue
+			}
+			if v, ok := compsValue(p, m); ok {
+				vals, idx = append(vals, v), append(idx, i)
+			}
+		}
+		if opts.Outliers && len(vals) >= 4 {
+			sorted := append([]float64(nil), vals...)
+			sort.Float64s(sorted)
+			q1, q3 := quantile(sorted, 0.25), quantile(sorted, 0.75)
+			lo, hi := q1-1.5*(q3-q1), q3+1.5*(q3-q1)
+			kept := vals[:0]
+			for j, v := range vals {
+				if v < lo || v > hi {
+					t.Peers[idx[j]].Outliers = append(t.Peers[idx[j]].Outliers, m.Key)
+					continue
+				}
+				kept = append(kept, v)
+			}
+			vals = kept
+		}
+
+		st := CompsStat{compsMetric: m, N: len(vals)}
+		if len(vals) > 0 {
+			sort.Float64s(vals)
+			var sum float64
+			for _, v := range vals {
+				sum += v
+			}
+			st.Min, st.Max = vals[0], vals[len(vals)-1]
+			st.Q1, st.Median, st.Q3 = quantile(vals, 0.25), quantile(vals, 0.5), quantile(vals, 0.75)
+			st.Mean = sum / float64(len(vals))
+		}
+		if v, ok := compsValue(subject, m); ok {
+			st.Subject = &v
+			if len(vals) > 0 {
+				var below float64
+				for _, pv := range vals {
+					switch {
+					case pv < v:
+						below++
+					case pv == v:
+						below += 0.5
+					}
+				}
+				rank := below / float64(len(vals)) * 100
+				st.Rank = &rank
+				if st.Median != 0 {
+					prem := v/st.Median - 1
+					st.Premium = &prem
+				}
+			}
+		}
+		t.Stats = append(t.Stats, st)
+
+		if m.Multiple != "" && st.N > 0 && st.Subject != nil {
+			if imp, ok := impliedPrices(subject, st); ok {
+				t.Implied = append(t.Implied, imp)
+			}
+		}
+	}
+	return t
+}
+
+// impliedPrices applies the peer quartiles of one multiple to the
+// subject. The subject's own multiple recovers the underlying figure
+// (EBITDA = EV / EV/EBITDA, earnings = market cap / P/E); an EV multiple
+// is bridged to equity through the subject's net debt (EV − market cap),
+// and equity is spread over the current share count.
+func impliedPrices(subject CompsCompany, st CompsStat) (CompsImplied, bool) {
+	if subject.Price <= 0 || subject.MarketCap <= 0 || subject.FYMarketCap <= 0 {
+		return CompsImplied{}, false
+	}
+	shares := subject.MarketCap / subject.Price
+	own := *st.Subject
+	price := func(mult float64) float64 {
+		switch st.Multiple {
+		case compsEV:
+			netDebt := subject.FYEnterpriseValue - subject.FYMarketCap
+			return (mult*subject.FYEnterpriseValue/own - netDebt) / shares
+		default:
+			return mult * subject.FYMarketCap / own / shares
+		}
+	}
+	if st.Multiple == compsEV && subject.FYEnterpriseValue <= 0 {
+		return CompsImplied{}, false
+	}
+	imp := CompsImplied{
+		compsMetric: st.compsMetric,
+		Q1:          price(st.Q1),
+		Median:      price(st.Median),
+		Mean:        price(st.Mean),
+		Q3:          price(st.Q3),
+	}
+	imp.Upside = imp.Median/subject.Price - 1
+	return imp, true
+}
+
+// WriteCompsCSV writes the table as one block per section — companies,
+// then peer statistics, then implied prices — so it pastes straight into
+// a spreadsheet. Percent metrics are written as fractions, as FMP
+// reports them.
+func WriteCompsCSV(w *csv.Writer, t CompsTable) error {
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	cell := func(c CompsCompany, m compsMetric) string {
+		if v, ok := compsValue(c, m); ok {
+			return num(v)
+		}
+		return ""
+	}
+	header := []string{"symbol", "name", "price", "marketCap"}
+	for _, m := range t.Metrics {
+		header = append(header, m.Label)
+	}
+	header = append(header, "status")
+	rows := [][]string{header}
+	company := func(c CompsCompany, status string) {
+		row := []string{c.Symbol, c.Name, num(c.Price), num(c.MarketCap)}
+		for _, m := range t.Metrics {
+			row = append(row, cell(c, m))
+		}
+		rows = append(rows, append(row, status))
+	}
+	company(t.Subject, "subject")
+	for _, p := range t.Peers {
+		status := ""
+		switch {
+		case p.Error != "":
+			status = "error: " + p.Error
+		case p.Excluded:
+			status = "excluded"
+		case len(p.Outliers) > 0:
+			status = "outlier: " + strings.Join(p.Outliers, " ")
+		}
+		company(p, status)
+	}
+
+	stat := func(label string, f func(CompsStat) (float64, bool)) {
+		row := []string{label, "", "", ""}
+		for _, st := range t.Stats {
+			if v, ok := f(st); ok {
+				row = append(row, num(v))
+			} else {
+				row = append(row, "")
+			}
+		}
+		rows = append(rows, append(row, ""))
+	}
+	rows = append(rows, nil)
+	stat("n", func(s CompsStat) (float64, bool) { return float64(s.N), true })
+	for _, q := range []struct {
+		label string
+		get   func(CompsStat) float64
+	}{
+		{"min", func(s CompsStat) float64 { return s.Min }},
+		{"q1", func(s CompsStat) float64 { return s.Q1 }},
+		{"median", func(s CompsStat) float64 { return s.Median }},
+		{"mean", func(s CompsStat) float64 { return s.Mean }},
+		{"q3", func(s CompsStat) float64 { return s.Q3 }},
+		{"max", func(s CompsStat) float64 { return s.Max }},
+	} {
+		get := q.get
+		stat(q.label, func(s CompsStat) (float64, bool) { return get(s), s.N > 0 })
+	}
+	stat("subject percentile", func(s CompsStat) (float64, bool) {
+		if s.Rank == nil {
+			return 0, false
+		}
+		return *s.Rank, true
+	})
+	stat("subject vs median", func(s CompsStat) (float64, bool) {
+		if s.Premium == nil {
+			return 0, false
+		}
+		return *s.Premium, true
+	})
+
+	rows = append(rows, nil, []string{"implied price", "multiple", "q1", "median", "mean", "q3", "upside"})
+	for _, imp := range t.Implied {
+		rows = append(rows, []string{t.Subject.Symbol, imp.Label, num(imp.Q1), num(imp.Median), num(imp.Mean), num(imp.Q3), num(imp.Upside)})
+	}
+	for _, row := range rows {
+		if row == nil {
+			row = []string{}
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// compsCache holds recently fetched company rows and peer lists. Expired
+// entries are dropped when read and swept at most once a TTL on write, so
+// symbols that are never viewed again don't stay resident. The zero value
+// is ready to use.
+type compsCache struct {
+	mu        sync.Mutex
+	companies map[string]compsCachedCompany
+	peers     map[string]compsCachedPeers
+	swept     time.Time
+}
+
+type compsCachedCompany struct {
+	c  CompsCompany
+	at time.Time
+}
+
+type compsCachedPeers struct {
+	syms []string
+	at   time.Time
+}
+
+func (c *compsCache) company(sym string) (CompsCompany, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.companies[sym]
+	if !ok || time.Since(e.at) > compsTTL {
+		delete(c.companies, sym)
+		return CompsCompany{}, false
+	}
+	return e.c, true
+}
+
+func (c *compsCache) putCompany(co CompsCompany) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.companies == nil {
+		c.companies = make(map[string]compsCachedCompany)
+	}
+	c.sweepLocked(time.Now())
+	c.companies[co.Symbol] = compsCachedCompany{c: co, at: time.Now()}
+}
+
+func (c *compsCache) peerList(sym string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.peers[sym]
+	if !ok || time.Since(e.at) > compsTTL {
+		delete(c.peers, sym)
+		return nil, false
+	}
+	return e.syms, true
+}
+
+func (c *compsCache) putPeers(sym string, syms []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.peers == nil {
+		c.peers = make(map[string]compsCachedPeers)
+	}
+	c.sweepLocked(time.Now())
+	c.peers[sym] = compsCachedPeers{syms: syms, at: time.Now()}
+}
+
+// sweepLocked drops every expired entry, at most once per TTL.
+func (c *compsCache) sweepLocked(now time.Time) {
+	if now.Sub(c.swept) < compsTTL {
+		return
+	}
+	c.swept = now
+	for sym, e := range c.companies {
+		if now.Sub(e.at) > compsTTL {
+			delete(c.companies, sym)
+		}
+	}
+	for sym, e := range c.peers {
+		if now.Sub(e.at) > compsTTL {
+			delete(c.peers, sym)
+		}
+	}
+}
+
+// compsCompany returns a company's row from the cache, fetching and
+// caching it on a miss. Failed fetches aren't cached.
+func (s *Server) compsCompany(ctx context.Context, symbol string) (CompsCompany, error) {
+	if c, ok := s.comps.company(symbol); ok {
+		return c, nil
+	}
+	c, err := s.fetchCompsCompany(ctx, symbol)
+	if err == nil {
+		s.comps.putCompany(c)
+	}
+	return c, err
+}
+
+// fetchCompsCompany assembles one company's row from /profile,
+// /key-metrics, /ratios and the last two income statements. A failed
+// profile is the only fatal error; a missing metric is just a blank cell.
+func (s *Server) fetchCompsCompany(ctx context.Context, symbol string) (CompsCompany, error) {
+	c := CompsCompany{Symbol: symbol, Metrics: map[string]float64{}}
+	first := func(raw json.RawMessage) map[string]any {
+		var rows []map[string]any
+		if json.Unmarshal(raw, &rows) != nil || len(rows) == 0 {
+			return nil
+		}
+		return rows[0]
+	}
+
+	raw, err := s.news.GetProfile(ctx, symbol)
+	if err != nil {
+		return c, err
+	}
+	profile := first(raw)
+	if profile == nil {
+		return c, fmt.Errorf("no profile for %s", symbol)
+	}
+	c.Price = pickFloat(profile, "price")
+	c.MarketCap = pickFloat(profile, "marketCap", "mktCap")
+	c.Name, _ = profile["companyName"].(string)
+
+	byKind := map[string]map[string]any{}
+	if raw, err := s.news.GetKeyMetrics(ctx, symbol); err == nil {
+		byKind["keymetric"] = first(raw)
+	}
+	if raw, err := s.news.GetRatiosHistorical(ctx, symbol, 1); err == nil {
+		byKind["ratio"] = first(raw)
+	}
+	if km := byKind["keymetric"]; km != nil {
+		c.FYMarketCap = pickFloat(km, "marketCap")
+		c.FYEnterpriseValue = pickFloat(km, "enterpriseValue")
+	}
+	for _, m := range compsMetrics {
+		ep, ok := fundamentalFields[m.Key]
+		if !ok {
+			continue
+		}
+		row := byKind[ep.kind]
+		if row == nil {
+			continue
+		}
+		if v, ok := row[fmpFieldFor(m.Key, &ep)].(float64); ok {
+			c.Metrics[m.Key] = v
+		}
+	}
+
+	if raw, err := s.news.GetIncomeStatement(ctx, symbol, 2); err == nil {
+		var rows []map[string]any
+		if json.Unmarshal(raw, &rows) == nil && len(rows) >= 2 {
+			if prev := pickFloat(rows[1], "revenue"); prev > 0 {
+				c.Metrics["revenueGrowth"] = pickFloat(rows[0], "revenue")/prev - 1
+			}
+		}
+	}
+	return c, nil
+}
+
+// compsPeers returns the peer symbols: the caller's list when given
+// (?peers=A,B,C), FMP's peer set otherwise. The subject itself is
+// dropped and the list is capped at compsMaxPeers.
+func (s *Server) compsPeers(ctx context.Context, symbol, override string) ([]string, error) {
+	var syms []string
+	if strings.TrimSpace(override) != "" {
+		syms = strings.Split(override, ",")
+	} else if cached, ok := s.comps.peerList(symbol); ok {
+		syms = cached
+	} else {
+		raw, err := s.news.GetPeers(ctx, symbol)
+		if err != nil {
+			return nil, err
+		}
+		var rows []struct {
+			Symbol string `json:"symbol"`
+		}
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			syms = append(syms, r.Symbol)
+		}
+		s.comps.putPeers(symbol, syms)
+	}
+	seen := map[string]bool{symbol: true}
+	var out []string
+	for _, sym := range syms {
+		sym = strings.ToUpper(strings.TrimSpace(sym))
+		if sym == "" || seen[sym] {
+			continue
+		}
+		seen[sym] = true
+		out = append(out, sym)
+		if len(out) == compsMaxPeers {
+			break
+		}
+	}
+	return out, nil
+}
+
+// handleSecurityComps: GET /api/security/{symbol}/comps
+//
+//	?peers=A,B,C     replace FMP's peer set
+//	?exclude=A,B     keep in the table, leave out of the statistics
+//	?outliers=iqr    fence each metric at 1.5×IQR
+//	?format=csv      download instead of JSON
+func (s *Server) handleSecurityComps(w http.ResponseWriter, r *http.Request) {
+	if s.news == nil {
+		http.Error(w, "news client unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	symbol := strings.ToUpper(r.PathValue("symbol"))
+	q := r.URL.Query()
+	ctx, cancel := contextWithTimeout(r, 60*time.Second)
+	defer cancel()
+
+	peerSyms, err := s.compsPeers(ctx, symbol, q.Get("peers"))
+	if err != nil {
+		s.logger.Error("comps peers fetch failed", "symbol", symbol, "error", err)
+		http.Error(w, "peers fetch failed", http.StatusBadGateway)
+		return
+	}
+
+	all := append([]string{symbol}, peerSyms...)
+	companies := make([]CompsCompany, len(all))
+	sem := make(chan struct{}, compsConcurrency)
+	var wg sync.WaitGroup
+	for i, sym := range all {
+		wg.Add(1)
+		go func(i int, sym string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			c, err := s.compsCompany(ctx, sym)
+			if err != nil {
+				c.Error = err.Error()
+			}
+			companies[i] = c
+		}(i, sym)
+	}
+	wg.Wait()
+
+	if companies[0].Error != "" {
+		s.logger.Error("comps subject fetch failed", "symbol", symbol, "error", companies[0].Error)
+		http.Error(w, "subject fetch failed", http.StatusBadGateway)
+		return
+	}
+	var exclude []string
+	if raw := q.Get("exclude"); raw != "" {
+		exclude = strings.Split(raw, ",")
+	}
+	t := BuildComps(companies[0], companies[1:], CompsOptions{
+		Exclude:  exclude,
+		Outliers: strings.EqualFold(q.Get("outliers"), "iqr"),
+	})
+	t.AsOf = time.Now().UTC().Format("2006-01-02")
+
+	if strings.EqualFold(q.Get("format"), "csv") {
+		var buf bytes.Buffer
+		if err := WriteCompsCSV(csv.NewWriter(&buf), t); err != nil {
+			http.Error(w, "export failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", symbol+"-comps-"+t.AsOf+".csv"))
+		w.Write(buf.Bytes())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
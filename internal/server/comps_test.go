@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func compsCo(sym string, ev, pe, margin float64) CompsCompany {
+	return CompsCompany{
+		Symbol:  sym,
+		Price:   10,
+		Metrics: map[string]float64{"evToEBITDA": ev, "peRatio": pe, "netProfitMargin": margin},
+	}
+}
+
+func compsStat(t *testing.T, tbl CompsTable, key string) CompsStat {
+	t.Helper()
+	for _, st := range tbl.Stats {
+		if st.Key == key {
+			return st
+		}
+	}
+	t.Fatalf("no stat for %s", key)
+	return CompsStat{}
+}
+
+func TestCompsCache(t *testing.T) {
+	var c compsCache
+	if _, ok := c.company("AAPL"); ok {
+		t.Fatal("empty cache hit")
+	}
+	c.putCompany(CompsCompany{Symbol: "AAPL", Price: 10})
+	if got, ok := c.company("AAPL"); !ok || got.Price != 10 {
+		t.Fatalf("cached company = %+v, %v", got, ok)
+	}
+	c.companies["AAPL"] = compsCachedCompany{c: CompsCompany{Symbol: "AAPL"}, at: time.Now().Add(-compsTTL - time.Second)}
+	if _, ok := c.company("AAPL"); ok {
+		t.Error("expired row served from cache")
+	}
+	if _, ok := c.companies["AAPL"]; ok {
+		t.Error("expired row kept after a miss")
+	}
+	c.putPeers("AAPL", []string{"MSFT"})
+	if got, ok := c.peerList("AAPL"); !ok || len(got) != 1 {
+		t.Errorf("cached peers = %v, %v", got, ok)
+	}
+
+	// Entries that are never read again are swept on a later write.
+	stale := time.Now().Add(-compsTTL - time.Second)
+	c.companies["OLD"] = compsCachedCompany{c: CompsCompany{Symbol: "OLD"}, at: stale}
+	c.peers["OLD"] = compsCachedPeers{syms: []string{"X"}, at: stale}
+	c.swept = stale
+	c.putCompany(CompsCompany{Symbol: "NEW"})
+	if _, ok := c.companies["OLD"]; ok {
+		t.Error("expired company not swept")
+	}
+	if _, ok := c.peers["OLD"]; ok {
+		t.Error("expired peers not swept")
+	}
+}
+
+func TestBuildComps(t *testing.T) {
+	// Subject: 100 shares at $10, EV 1200 (net debt 200) at 12× EBITDA
+	// (EBITDA 100), 20× earnings (earnings 50).
+	subject := CompsCompany{
+		Symbol: "SUBJ", Price: 10, MarketCap: 1000,
+		FYMarketCap: 1000, FYEnterpriseValue: 1200,
+		Metrics: map[string]float64{"evToEBITDA": 12, "peRatio": 20, "netProfitMargin": 0.10},
+	}
+	peers := []CompsCompany{
+		compsCo("A", 8, 10, 0.05),
+		compsCo("B", 10, 15, -0.02),
+		compsCo("C", 12, -4, 0.08), // loss-maker: P/E is n/m
+		compsCo("D", 14, 25, 0.12),
+		compsCo("E", 90, 30, 0.15), // EV/EBITDA outlier
+	}
+
+	tbl := BuildComps(subject, peers, CompsOptions{})
+	ev := compsStat(t, tbl, "evToEBITDA")
+	if ev.N != 5 || ev.Median != 12 || ev.Q1 != 10 || ev.Q3 != 14 || ev.Mean != 26.8 {
+		t.Fatalf("evToEBITDA stats = %+v", ev)
+	}
+	if *ev.Rank != 50 || *ev.Premium != 0 {
+		t.Errorf("subject at the median: rank %v premium %v", *ev.Rank, *ev.Premium)
+	}
+	pe := compsStat(t, tbl, "peRatio")
+	if pe.N != 4 || pe.Median != 20 {
+		t.Errorf("negative P/E should be n/m: %+v", pe)
+	}
+	if m := compsStat(t, tbl, "netProfitMargin"); m.N != 5 || m.Min != -0.02 {
+		t.Errorf("margins keep negatives: %+v", m)
+	}
+
+	// Implied prices: EV/EBITDA at the peer Q1 of 10× → EV 1000, equity
+	// 800, $8/share; P/E at the median of 20× → the current $10.
+	var evImp, peImp CompsImplied
+	for _, imp := range tbl.Implied {
+		switch imp.Key {
+		case "evToEBITDA":
+			evImp = imp
+		case "peRatio":
+			peImp = imp
+		}
+	}
+	if math.Abs(evImp.Q1-8) > 1e-9 || math.Abs(evImp.Median-10) > 1e-9 || evImp.Upside != 0 {
+		t.Errorf("EV/EBITDA implied = %+v", evImp)
+	}
+	if math.Abs(peImp.Median-10) > 1e-9 || math.Abs(peImp.Q3-13.125) > 1e-9 {
+		t.Errorf("P/E implied = %+v", peImp)
+	}
+
+	// Outlier fences drop E from EV/EBITDA only; manual exclusion drops A
+	// from everything but keeps it in the table.
+	tbl = BuildComps(subject, peers, CompsOptions{Outliers: true, Exclude: []string{"a"}})
+	ev = compsStat(t, tbl, "evToEBITDA")
+	if ev.N != 3 || ev.Mean != 12 {
+		t.Errorf("fenced evToEBITDA = %+v", ev)
+	}
+	if got := tbl.Peers[4].Outliers; len(got) != 1 || got[0] != "evToEBITDA" {
+		t.Errorf("E outliers = %v", got)
+	}
+	if !tbl.Peers[0].Excluded || len(tbl.Peers) != 5 {
+		t.Errorf("A should stay listed as excluded: %+v", tbl.Peers[0])
+	}
+	if pe := compsStat(t, tbl, "peRatio"); pe.N != 3 {
+		t.Errorf("excluded peer counted in P/E: %+v", pe)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCompsCSV(csv.NewWriter(&buf), tbl); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"symbol,name,price,marketCap,EV/EBITDA",
+		"SUBJ,,10,1000,12,",
+		"A,,10,0,8,,10,",
+		"outlier: evToEBITDA",
+		"median,,,,12,",
+		"implied price,multiple,q1,median,mean,q3,upside",
+		"SUBJ,EV/EBITDA,",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("csv missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"stocktopus/internal/store"
+)
+
+// corporateActionsTTL bounds how stale a symbol's splits and dividends may
+// get. New dividends are declared weeks ahead, so daily is plenty.
+const corporateActionsTTL = 24 * time.Hour
+
+// GET /api/corporate-actions/{symbol}
+func (s *Server) handleCorporateActions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	a, err := s.fetchOrLoadCorporateActions(r, r.PathValue("symbol"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(a)
+}
+
+// fetchOrLoadCorporateActions returns the stored actions if fresh, otherwise
+// fetches splits, dividends and symbol changes from FMP, persists, and
+// returns the fresh set. Soft-fails to a stale copy like economic series.
+func (s *Server) fetchOrLoadCorporateActions(r *http.Request, symbol string) (*store.CorporateActions, error) {
+	symbol = strings.ToUpper(symbol)
+	if s.store == nil {
+		return nil, httpErr("store unavailable")
+	}
+	cached, err := s.store.GetCorporateActions(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if !cached.FetchedAt.IsZero() && time.Since(cached.FetchedAt) < corporateActionsTTL {
+		return cached, nil
+	}
+	if s.news == nil {
+		return cached, nil
+	}
+
+	ctx, cancel := contextWithTimeout(r, 30*time.Second)
+	defer cancel()
+	fresh := store.CorporateActions{Symbol: symbol}
+	fresh.Splits, err = s.news.GetSplits(ctx, symbol)
+	if err == nil {
+		fresh.Dividends, err = s.news.GetDividends(ctx, symbol)
+	}
+	if err != nil {
+		if !cached.FetchedAt.IsZero() {
+			s.logger.Warn("corporate actions refresh failed, serving stale", "symbol", symbol, "error", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	// Renames are a nice-to-have; don't drop the price-relevant actions
+	// because the market-wide list failed.
+	if fresh.SymbolChanges, err = s.news.GetSymbolChanges(ctx, symbol); err != nil {
+		s.logger.Warn("symbol changes fetch failed", "symbol", symbol, "error", err)
+	}
+	if err := s.store.PutCorporateActions(fresh); err != nil {
+		s.logger.Warn("corporate actions put failed", "symbol", symbol, "error", err)
+	}
+	return s.store.GetCorporateActions(symbol)
+}
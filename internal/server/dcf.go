@@ -0,0 +1,434 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"stocktopus/internal/econ"
+)
+
+// dcf.go values the three-statement forecast by discounting unlevered
+// free cash flow at WACC:
+//
+//	EBIT  = EBT + Interest
+//	UFCF  = EBIT × (1 − t) + D&A − Capex − ΔNWC
+//	Ke    = Rf + β × ERP                       (CAPM)
+//	WACC  = E/(D+E) × Ke + D/(D+E) × Kd × (1 − t)
+//	EV    = Σ UFCFₜ / (1+WACC)ᵗ + TV / (1+WACC)ᴺ
+//
+// Capex and ΔNWC are read back out of the projected cash flow statement
+// (Investing CF = −Capex, Operating CF = NE + D&A − ΔNWC), so the
+// valuation always agrees with the forecast rows the user is looking at.
+// Cash flows are discounted end-of-year.
+
+// DCF defaults, used when the request and the market data supply nothing.
+const (
+	defaultERP            = 0.055 // Damodaran-style implied US ERP
+	defaultTerminalGrowth = 0.025
+	defaultExitMultiple   = 12.0 // EV / EBITDA
+	defaultRiskFree       = 0.04
+	defaultBeta           = 1.0
+)
+
+// Terminal value methods.
+const (
+	TerminalGordon = "gordon"
+	TerminalExit   = "exit"
+)
+
+// riskFreeSeries is the econ catalog series used for Rf. Its units are
+// percent, so the last observation is divided by 100.
+const riskFreeSeries = "US.DGS10"
+
+// dcfGridSteps is the half-width of the sensitivity grid; each axis has
+// 2×steps+1 points spaced dcfWACCStep / dcfGrowthStep apart.
+const (
+	dcfGridSteps  = 2
+	dcfWACCStep   = 0.005
+	dcfGrowthStep = 0.005
+)
+
+// DCFInputs are the user-editable valuation inputs. Every field is
+// optional: a nil Beta, RiskFreeRate or CostOfDebt is filled from market
+// data and the forecast, the rest from the defaults above.
+type DCFInputs struct {
+	Method            string   `json:"method,omitempty"` // "gordon" (default) or "exit"
+	EquityRiskPremium *float64 `json:"equityRiskPremium,omitempty"`
+	TerminalGrowth    *float64 `json:"terminalGrowth,omitempty"`
+	ExitMultiple      *float64 `json:"exitMultiple,omitempty"` // EV / terminal-year EBITDA
+	Beta              *float64 `json:"beta,omitempty"`
+	RiskFreeRate      *float64 `json:"riskFreeRate,omitempty"`
+	CostOfDebt        *float64 `json:"costOfDebt,omitempty"` // pre-tax
+}
+
+// DCFMarket is what the valuation needs from outside the statements.
+// Zero values mean "unknown"; the Source strings say where each came from
+// so the UI can show it.
+type DCFMarket struct {
+	Beta           float64
+	BetaSource     string
+	RiskFree       float64
+	RiskFreeSource string
+	Shares         float64 // diluted
+	Price          float64
+	MarketCap      float64
+}
+
+// DCFYear is one forecast year's free cash flow build. Period is the
+// discount time in years from the last reported balance sheet — 1, 2, 3…
+// for annual models; a quarterly model's first fiscal year can be a stub
+// of Quarters quarters, which pulls every later period in by the missing
+// fraction.
+type DCFYear struct {
+	Year           int     `json:"year"`
+	Quarters       int     `json:"quarters,omitempty"`
+	Period         float64 `json:"period"`
+	EBIT           float64 `json:"ebit"`
+	TaxRate        float64 `json:"taxRate"`
+	NOPAT          float64 `json:"nopat"`
+	DA             float64 `json:"da"`
+	Capex          float64 `json:"capex"`
+	ChangeNWC      float64 `json:"changeNWC"`
+	UFCF           float64 `json:"ufcf"`
+	DiscountFactor float64 `json:"discountFactor"`
+	PresentValue   float64 `json:"presentValue"`
+}
+
+// DCFSensitivity is equity value per share over a WACC × terminal growth
+// grid. Values[i][j] is at WACC[i] and Growth[j]; a cell is null where
+// growth ≥ WACC and the Gordon formula has no answer. The grid always
+// uses Gordon growth, whatever Method the headline value used.
+type DCFSensitivity struct {
+	WACC   []float64    `json:"wacc"`
+	Growth []float64    `json:"growth"`
+	Values [][]*float64 `json:"values"`
+}
+
+// DCFResult is the valuation returned in ModelingResponse.DCF.
+type DCFResult struct {
+	Method string    `json:"method"`
+	Years  []DCFYear `json:"years"`
+
+	// Discount rate
+	RiskFreeRate       float64 `json:"riskFreeRate"`
+	RiskFreeSource     string  `json:"riskFreeSource"`
+	Beta               float64 `json:"beta"`
+	BetaSource         string  `json:"betaSource"`
+	EquityRiskPremium  float64 `json:"equityRiskPremium"`
+	CostOfEquity       float64 `json:"costOfEquity"`
+	CostOfDebt         float64 `json:"costOfDebt"` // pre-tax
+	TaxRate            float64 `json:"taxRate"`
+	EquityWeight       float64 `json:"equityWeight"`
+	DebtWeight         float64 `json:"debtWeight"`
+	WACC               float64 `json:"wacc"`
+	TerminalGrowth     float64 `json:"terminalGrowth"`
+	ExitMultiple       float64 `json:"exitMultiple"`
+	TerminalValue      float64 `json:"terminalValue"`
+	PVTerminalValue    float64 `json:"pvTerminalValue"`
+	SumPVCashFlows     float64 `json:"sumPvCashFlows"`
+	ImpliedExitMult    float64 `json:"impliedExitMultiple"`   // TV / EBITDA under Gordon
+	ImpliedGrowth      float64 `json:"impliedTerminalGrowth"` // g implied by the exit multiple
+	EnterpriseValue    float64 `json:"enterpriseValue"`
+	NetDebt            float64 `json:"netDebt"`
+	EquityValue        float64 `json:"equityValue"`
+	SharesOutstanding  float64 `json:"sharesOutstanding"`
+	ValuePerShare      float64 `json:"valuePerShare"`
+	Price              float64 `json:"price,omitempty"`
+	Upside             float64 `json:"upside,omitempty"`   // ValuePerShare / Price − 1
+	TerminalValueShare float64 `json:"terminalValueShare"` // PV(TV) / EV
+
+	Sensitivity DCFSensitivity `json:"sensitivity"`
+}
+
+// ValueDCF discounts the forecast's unlevered free cash flow. hist seeds
+// the capital structure (last period's debt and cash), a supplies the
+// tax and interest rates, and m the market inputs. forecast is fiscal
+// years: for a quarterly model pass AggregateFiscalYears of the quarters
+// and the quarterly history. It errors when the inputs leave no
+// valuation: no forecast, a final year short of four quarters, WACC ≤
+// terminal growth under Gordon, or no share count.
+func ValueDCF(hist, forecast []ModelingPeriod, a ModelingAssumptions, in DCFInputs, m DCFMarket) (*DCFResult, error) {
+	if len(hist) == 0 || len(forecast) == 0 {
+		return nil, errors.New("no forecast to value")
+	}
+	last := hist[len(hist)-1]
+	if q := forecast[len(forecast)-1].Quarters; q != 0 && q != 4 {
+		return nil, fmt.Errorf("final forecast year %d has only %d quarters", forecast[len(forecast)-1].Year, q)
+	}
+
+	res := &DCFResult{
+		Method:            TerminalGordon,
+		RiskFreeRate:      m.RiskFree,
+		RiskFreeSource:    m.RiskFreeSource,
+		Beta:              m.Beta,
+		BetaSource:        m.BetaSource,
+		EquityRiskPremium: orDefault(in.EquityRiskPremium, defaultERP),
+		TerminalGrowth:    orDefault(in.TerminalGrowth, defaultTerminalGrowth),
+		ExitMultiple:      orDefault(in.ExitMultiple, defaultExitMultiple),
+		SharesOutstanding: m.Shares,
+		Price:             m.Price,
+	}
+	switch in.Method {
+	case "", TerminalGordon:
+	case TerminalExit:
+		res.Method = TerminalExit
+	default:
+		return nil, fmt.Errorf("unknown terminal value method %q (gordon, exit)", in.Method)
+	}
+	if in.RiskFreeRate != nil {
+		res.RiskFreeRate, res.RiskFreeSource = *in.RiskFreeRate, "input"
+	} else if res.RiskFreeSource == "" {
+		res.RiskFreeRate, res.RiskFreeSource = defaultRiskFree, "default"
+	}
+	if in.Beta != nil {
+		res.Beta, res.BetaSource = *in.Beta, "input"
+	} else if res.BetaSource == "" {
+		res.Beta, res.BetaSource = defaultBeta, "default"
+	}
+
+	// The average forecast tax rate sets the debt tax shield; each year's
+	// NOPAT uses that year's effective rate.
+	var taxSum float64
+	for _, t := range a.TaxRate {
+		taxSum += t
+	}
+	if len(a.TaxRate) > 0 {
+		res.TaxRate = taxSum / float64(len(a.TaxRate))
+	}
+
+	// Pre-tax Kd defaults to the forecast's first-period interest rate on
+	// opening debt, which is exactly the InterestPct driver — annualised
+	// when the periods are quarters.
+	kd := driverAt(a.InterestPct, 0)
+	if last.Quarter != 0 {
+		kd *= 4
+	}
+	res.CostOfDebt = orDefault(in.CostOfDebt, kd)
+
+	res.CostOfEquity = res.RiskFreeRate + res.Beta*res.EquityRiskPremium
+	debt := math.Max(last.Debt, 0)
+	equity := m.MarketCap
+	if equity <= 0 && m.Price > 0 && m.Shares > 0 {
+		equity = m.Price * m.Shares
+	}
+	if equity <= 0 {
+		// No market value: fall back to book equity so the weights are
+		// at least the right order of magnitude.
+		equity = math.Max(last.Equity+last.RetainedEarnings, 0)
+	}
+	if debt+equity > 0 {
+		res.EquityWeight = equity / (debt + equity)
+		res.DebtWeight = debt / (debt + equity)
+	} else {
+		res.EquityWeight = 1
+	}
+	res.WACC = res.EquityWeight*res.CostOfEquity + res.DebtWeight*res.CostOfDebt*(1-res.TaxRate)
+	if res.WACC <= 0 {
+		return nil, fmt.Errorf("WACC %.4f is not positive", res.WACC)
+	}
+
+	res.Years = unleveredFCF(forecast, a)
+	res.NetDebt = last.Debt - last.Cash
+
+	final := forecast[len(forecast)-1]
+	finalEBITDA := final.EBT + final.Interest + final.DA
+	finalFCF := res.Years[len(res.Years)-1].UFCF
+	n := res.Years[len(res.Years)-1].Period
+
+	var err error
+	res.SumPVCashFlows = discountYears(res.Years, res.WACC)
+	switch res.Method {
+	case TerminalGordon:
+		if res.TerminalValue, err = gordonValue(finalFCF, res.WACC, res.TerminalGrowth); err != nil {
+			return nil, err
+		}
+		if finalEBITDA != 0 {
+			res.ImpliedExitMult = res.TerminalValue / finalEBITDA
+		}
+	case TerminalExit:
+		res.TerminalValue = res.ExitMultiple * finalEBITDA
+		// Solve TV = FCF(1+g)/(WACC−g) for g.
+		if res.TerminalValue+finalFCF != 0 {
+			res.ImpliedGrowth = (res.TerminalValue*res.WACC - finalFCF) / (res.TerminalValue + finalFCF)
+		}
+	}
+	res.PVTerminalValue = res.TerminalValue / math.Pow(1+res.WACC, n)
+	res.EnterpriseValue = res.SumPVCashFlows + res.PVTerminalValue
+	if res.EnterpriseValue != 0 {
+		res.TerminalValueShare = res.PVTerminalValue / res.EnterpriseValue
+	}
+	res.EquityValue = res.EnterpriseValue - res.NetDebt
+	if m.Shares <= 0 {
+		return nil, errors.New("no share count to value per share")
+	}
+	res.ValuePerShare = res.EquityValue / m.Shares
+	if m.Price > 0 {
+		res.Upside = res.ValuePerShare/m.Price - 1
+	}
+
+	res.Sensitivity = dcfSensitivity(res.Years, res.WACC, res.TerminalGrowth, res.NetDebt, m.Shares)
+	return res, nil
+}
+
+// unleveredFCF builds each forecast year's free cash flow. The tax rate
+// is the year's effective one (the driver itself for an annual model;
+// the quarters' blend for a rolled-up one), falling back to the driver
+// when there's no pre-tax income to read it from. Discount factors are
+// filled in by discountYears.
+func unleveredFCF(forecast []ModelingPeriod, a ModelingAssumptions) []DCFYear {
+	out := make([]DCFYear, len(forecast))
+	var t float64
+	for i, p := range forecast {
+		y := DCFYear{Year: p.Year, DA: p.DA, TaxRate: driverAt(a.TaxRate, i), Quarters: p.Quarters}
+		if p.EBT != 0 {
+			y.TaxRate = p.Tax / p.EBT
+		}
+		if p.Quarters != 0 {
+			t += float64(p.Quarters) / 4
+		} else {
+			t++
+		}
+		y.Period = t
+		y.EBIT = p.EBT + p.Interest
+		y.NOPAT = y.EBIT * (1 - y.TaxRate)
+		y.Capex = -p.InvestingCF
+		y.ChangeNWC = p.NetEarnings + p.DA - p.OperatingCF
+		y.UFCF = y.NOPAT + y.DA - y.Capex - y.ChangeNWC
+		out[i] = y
+	}
+	return out
+}
+
+// discountYears fills in discount factors and present values at wacc and
+// returns their sum.
+func discountYears(years []DCFYear, wacc float64) float64 {
+	var sum float64
+	for i := range years {
+		years[i].DiscountFactor = 1 / math.Pow(1+wacc, years[i].Period)
+		years[i].PresentValue = years[i].UFCF * years[i].DiscountFactor
+		sum += years[i].PresentValue
+	}
+	return sum
+}
+
+// gordonValue is the terminal value at the end of the final year of a
+// cash flow growing at g forever.
+func gordonValue(finalFCF, wacc, g float64) (float64, error) {
+	if wacc <= g {
+		return 0, fmt.Errorf("terminal growth %.2f%% must be below WACC %.2f%%", g*100, wacc*100)
+	}
+	return finalFCF * (1 + g) / (wacc - g), nil
+}
+
+// dcfSensitivity reprices per-share equity value across a grid centred on
+// the base WACC and terminal growth.
+func dcfSensitivity(years []DCFYear, wacc, g, netDebt, shares float64) DCFSensitivity {
+	s := DCFSensitivity{}
+	for i := -dcfGridSteps; i <= dcfGridSteps; i++ {
+		s.WACC = append(s.WACC, wacc+float64(i)*dcfWACCStep)
+		s.Growth = append(s.Growth, g+float64(i)*dcfGrowthStep)
+	}
+	scratch := make([]DCFYear, len(years))
+	finalFCF := years[len(years)-1].UFCF
+	for _, w := range s.WACC {
+		row := make([]*float64, len(s.Growth))
+		if w > 0 {
+			copy(scratch, years)
+			pv := discountYears(scratch, w)
+			for j, gr := range s.Growth {
+				tv, err := gordonValue(finalFCF, w, gr)
+				if err != nil {
+					continue
+				}
+				v := (pv + tv/math.Pow(1+w, years[len(years)-1].Period) - netDebt) / shares
+				row[j] = &v
+			}
+		}
+		s.Values = append(s.Values, row)
+	}
+	return s
+}
+
+// driverAt reads a per-year driver, holding the last value when the slice
+// is shorter than the forecast.
+func driverAt(xs []float64, i int) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	if i >= len(xs) {
+		i = len(xs) - 1
+	}
+	return xs[i]
+}
+
+func orDefault(p *float64, def float64) float64 {
+	if p != nil {
+		return *p
+	}
+	return def
+}
+
+// dcfMarket gathers the valuation's market inputs: beta from the same
+// rolling regression as SYMBOL.beta (falling back to the profile's
+// beta), Rf from the 10-year Treasury series, and price, market cap and
+// diluted shares from the profile and the latest income statement. Each
+// lookup is best-effort; ValueDCF fills the gaps with defaults.
+func (s *Server) dcfMarket(r *http.Request, symbol string, income []map[string]any) DCFMarket {
+	var m DCFMarket
+	ctx := r.Context()
+
+	latest := 0
+	for _, row := range income {
+		if y := statementYear(row); y > latest {
+			latest = y
+			m.Shares = pickFloat(row, "weightedAverageShsOutDil", "weightedAverageShsOut")
+		}
+	}
+
+	var profile struct {
+		Price     float64 `json:"price"`
+		MarketCap float64 `json:"marketCap"`
+		Beta      float64 `json:"beta"`
+	}
+	if raw, err := s.news.GetProfile(ctx, symbol); err == nil {
+		var rows []json.RawMessage
+		if json.Unmarshal(raw, &rows) == nil && len(rows) > 0 {
+			_ = json.Unmarshal(rows[0], &profile)
+		}
+	}
+	m.Price, m.MarketCap = profile.Price, profile.MarketCap
+
+	prices := func(sym string) []pricePoint {
+		raw, err := s.news.GetHistoricalPriceLight(ctx, sym)
+		if err != nil {
+			return nil
+		}
+		var rows []pricePoint
+		_ = json.Unmarshal(raw, &rows)
+		return rows
+	}
+	if target := prices(symbol); len(target) > 0 {
+		if series, err := rollingBeta(target, prices(betaBenchmark), betaWindow); err == nil && len(series) > 0 {
+			m.Beta, m.BetaSource = series[len(series)-1].Value, "rolling 1y vs "+betaBenchmark
+		}
+	}
+	if m.BetaSource == "" && profile.Beta != 0 {
+		m.Beta, m.BetaSource = profile.Beta, "profile"
+	}
+
+	if entry := econ.LookupCatalog(riskFreeSeries); entry != nil {
+		if series, err := s.fetchOrLoadSeries(r, entry); err == nil && len(series.Observations) > 0 {
+			obs := series.Observations[0]
+			for _, o := range series.Observations[1:] {
+				if o.Date > obs.Date {
+					obs = o
+				}
+			}
+			m.RiskFree, m.RiskFreeSource = obs.Value/100, riskFreeSeries+" "+obs.Date
+		}
+	}
+	return m
+}
@@ -95,6 +95,15 @@ func (s *Server) handleDebug(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleBudget reports FMP request budget usage by caller.
+func (s *Server) handleBudget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"available": s.budget != nil,
+		"usage":     s.budget.Snapshot(),
+	})
+}
+
 func (s *Server) handleDebugWS(w http.ResponseWriter, r *http.Request) {
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
 		InsecureSkipVerify: true,
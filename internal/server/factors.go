@@ -0,0 +1,502 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// factors.go extends the single-factor rollingBeta to a multi-factor
+// regression of a security's daily returns on ETF-proxied factors:
+//
+//	rₜ = α + Σ βⱼ fⱼₜ + εₜ
+//
+// Style factors are long/short spreads between two ETFs so they are
+// roughly orthogonal to the market leg — momentum and sector are measured
+// over SPY for the same reason, otherwise MTUM and the sector SPDR would
+// mostly re-estimate market beta. Rates is TLT's raw return.
+
+// factorDef is one regressor: the return of Long, less Short when set.
+type factorDef struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Long  string `json:"long"`
+	Short string `json:"short,omitempty"`
+}
+
+var baseFactors = []factorDef{
+	{Key: "market", Label: "Market", Long: "SPY"},
+	{Key: "size", Label: "Size", Long: "IWM", Short: "SPY"},
+	{Key: "value", Label: "Value", Long: "IWD", Short: "IWF"},
+	{Key: "momentum", Label: "Momentum", Long: "MTUM", Short: "SPY"},
+	{Key: "rates", Label: "Rates", Long: "TLT"},
+}
+
+// factorsFor returns the factor set for a security: the base five plus
+// its sector SPDR over SPY when the sector maps to one.
+func factorsFor(sector string) []factorDef {
+	out := append([]factorDef(nil), baseFactors...)
+	if etf := sectorETF(sector); etf != "" {
+		out = append(out, factorDef{Key: "sector", Label: "Sector", Long: etf, Short: "SPY"})
+	}
+	return out
+}
+
+// tradingDays annualises daily residual volatility and alpha.
+const tradingDays = 252
+
+// FactorLoading is one factor's estimated exposure over the window.
+// Contribution is βⱼ × Σ fⱼₜ: the part of the window's summed daily
+// return the regression assigns to that factor.
+type FactorLoading struct {
+	factorDef
+	Beta         float64 `json:"beta"`
+	StdErr       float64 `json:"stdErr"`
+	TStat        float64 `json:"tStat"`
+	Contribution float64 `json:"contribution"`
+}
+
+// FactorPoint is one rolling-window estimate, dated on the window's last
+// day.
+type FactorPoint struct {
+	Date        string             `json:"date"`
+	Betas       map[string]float64 `json:"betas"`
+	Alpha       float64            `json:"alpha"` // annualised
+	R2          float64            `json:"r2"`
+	ResidualVol float64            `json:"residualVol"` // annualised
+}
+
+// FactorRegression is the latest window's fit in full plus the rolling
+// history of the estimates. Attribution is additive over the window:
+// TotalReturn = Σ Contribution + Specific, where Specific is the
+// intercept's share (α × N) — OLS residuals sum to zero.
+type FactorRegression struct {
+	Symbol      string          `json:"symbol"`
+	Window      int             `json:"window"`
+	From        string          `json:"from"`
+	To          string          `json:"to"`
+	N           int             `json:"n"`
+	Alpha       float64         `json:"alpha"` // annualised
+	AlphaTStat  float64         `json:"alphaTStat"`
+	Loadings    []FactorLoading `json:"loadings"`
+	R2          float64         `json:"r2"`
+	AdjR2       float64         `json:"adjR2"`
+	ResidualVol float64         `json:"residualVol"` // annualised
+	TotalReturn float64         `json:"totalReturn"` // Σ daily returns
+	Specific    float64         `json:"specific"`
+	Rolling     []FactorPoint   `json:"rolling"`
+}
+
+// olsFit is an ordinary least squares fit of y on X (rows are
+// observations; the caller includes the intercept column). It returns the
+// coefficients, their standard errors, R² and the residual variance.
+func olsFit(y []float64, X [][]float64) (coef, se []float64, r2, sigma2 float64, err error) {
+	n, k := len(y), len(X[0])
+	if n <= k {
+		return nil, nil, 0, 0, errors.New("not enough observations for the factor regression")
+	}
+	xtx := make([][]float64, k)
+	xty := make([]float64, k)
+	for i := range xtx {
+		xtx[i] = make([]float64, k)
+	}
+	for t, row := range X {
+		for i := 0; i < k; i++ {
+			xty[i] += row[i] * y[t]
+			for j := i; j < k; j++ {
+				xtx[i][j] += row[i] * row[j]
+			}
+		}
+	}
+	for i := 0; i < k; i++ {
+		for j := 0; j < i; j++ {
+			xtx[i][j] = xtx[j][i]
+		}
+	}
+	inv, err := invertMatrix(xtx)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	coef = make([]float64, k)
+	for i := 0; i < k; i++ {
+		for j := 0; j < k; j++ {
+			coef[i] += inv[i][j] * xty[j]
+		}
+	}
+
+	var mean float64
+	for _, v := range y {
+		mean += v
+	}
+	mean /= float64(n)
+	var ssr, sst float64
+	for t, row := range X {
+		fit := 0.0
+		for i, c := range coef {
+			fit += c * row[i]
+		}
+		ssr += (y[t] - fit) * (y[t] - fit)
+		sst += (y[t] - mean) * (y[t] - mean)
+	}
+	// A security that is itself a factor leg (SPY, IWM, the sector SPDR)
+	// is fitted exactly; its t-stats would be infinite.
+	if ssr <= 1e-12*sst {
+		return nil, nil, 0, 0, errors.New("returns are an exact combination of the factors")
+	}
+	sigma2 = ssr / float64(n-k)
+	se = make([]float64, k)
+	for i := range se {
+		se[i] = math.Sqrt(sigma2 * inv[i][i])
+	}
+	if sst > 0 {
+		r2 = 1 - ssr/sst
+	}
+	return coef, se, r2, sigma2, nil
+}
+
+// invertMatrix inverts a small symmetric matrix by Gauss-Jordan
+// elimination with partial pivoting. A near-zero pivot means two factors
+// are collinear over the window (e.g. a leg with a gap of flat prices).
+func invertMatrix(a [][]float64) ([][]float64, error) {
+	k := len(a)
+	m := make([][]float64, k)
+	for i := range a {
+		m[i] = make([]float64, 2*k)
+		copy(m[i], a[i])
+		m[i][k+i] = 1
+	}
+	var scale float64
+	for i := range a {
+		scale = math.Max(scale, math.Abs(a[i][i]))
+	}
+	for col := 0; col < k; col++ {
+		pivot := col
+		for r := col + 1; r < k; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][col]) <= 1e-12*scale {
+			return nil, errors.New("factors are collinear over the window")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		p := m[col][col]
+		for j := range m[col] {
+			m[col][j] /= p
+		}
+		for r := 0; r < k; r++ {
+			if r == col || m[r][col] == 0 {
+				continue
+			}
+			f := m[r][col]
+			for j := range m[r] {
+				m[r][j] -= f * m[col][j]
+			}
+		}
+	}
+	out := make([][]float64, k)
+	for i := range m {
+		out[i] = m[i][k:]
+	}
+	return out, nil
+}
+
+// factorReturns aligns the target and every ETF on common dates and
+// returns the dates, the target's daily simple returns and one factor
+// return column per factorDef (rows are days).
+func factorReturns(target []pricePoint, etfs map[string][]pricePoint, factors []factorDef) ([]string, []float64, [][]float64, error) {
+	byDate := make(map[string][]float64, len(etfs))
+	syms := make([]string, 0, len(etfs))
+	for sym := range etfs {
+		syms = append(syms, sym)
+	}
+	idx := make(map[string]int, len(syms))
+	for i, sym := range syms {
+		idx[sym] = i
+		for _, p := range sortedAscending(etfs[sym]) {
+			row, ok := byDate[p.Date]
+			if !ok {
+				row = make([]float64, len(syms))
+				byDate[p.Date] = row
+			}
+			row[i] = p.Price
+		}
+	}
+
+	type day struct {
+		date   string
+		target float64
+		etf    []float64
+	}
+	var days []day
+	for _, p := range sortedAscending(target) {
+		row, ok := byDate[p.Date]
+		if !ok {
+			continue
+		}
+		complete := true
+		for _, v := range row {
+			if v <= 0 {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			days = append(days, day{p.Date, p.Price, row})
+		}
+	}
+	if len(days) < 2 {
+		return nil, nil, nil, errors.New("not enough aligned history for the factor regression")
+	}
+
+	ret := func(a, b float64) float64 { return (b - a) / a }
+	dates := make([]string, 0, len(days)-1)
+	y := make([]float64, 0, len(days)-1)
+	F := make([][]float64, 0, len(days)-1)
+	for i := 1; i < len(days); i++ {
+		prev, cur := days[i-1], days[i]
+		row := make([]float64, len(factors))
+		for j, f := range factors {
+			l := idx[f.Long]
+			row[j] = ret(prev.etf[l], cur.etf[l])
+			if f.Short != "" {
+				s := idx[f.Short]
+				row[j] -= ret(prev.etf[s], cur.etf[s])
+			}
+		}
+		dates = append(dates, cur.date)
+		y = append(y, ret(prev.target, cur.target))
+		F = append(F, row)
+	}
+	return dates, y, F, nil
+}
+
+// FactorRegress fits the regression over each trailing window of returns
+// and reports the last window in full.
+func FactorRegress(symbol string, dates []string, y []float64, F [][]float64, factors []factorDef, window int) (*FactorRegression, error) {
+	if len(y) < window {
+		return nil, fmt.Errorf("need %d days of returns for the factor regression, have %d", window, len(y))
+	}
+	X := make([][]float64, len(F))
+	for t, row := range F {
+		X[t] = append([]float64{1}, row...)
+	}
+
+	out := &FactorRegression{Symbol: symbol, Window: window}
+	for end := window; end <= len(y); end++ {
+		ys, xs := y[end-window:end], X[end-window:end]
+		coef, se, r2, sigma2, err := olsFit(ys, xs)
+		if err != nil {
+			if end == len(y) {
+				return nil, err
+			}
+			continue
+		}
+		pt := FactorPoint{
+			Date:        dates[end-1],
+			Betas:       make(map[string]float64, len(factors)),
+			Alpha:       coef[0] * tradingDays,
+			R2:          r2,
+			ResidualVol: math.Sqrt(sigma2 * tradingDays),
+		}
+		for j, f := range factors {
+			pt.Betas[f.Key] = coef[j+1]
+		}
+		out.Rolling = append(out.Rolling, pt)
+		if end < len(y) {
+			continue
+		}
+
+		n, k := len(ys), len(coef)
+		out.From, out.To, out.N = dates[end-window], dates[end-1], n
+		out.Alpha, out.AlphaTStat = pt.Alpha, coef[0]/se[0]
+		out.R2, out.ResidualVol = r2, pt.ResidualVol
+		out.AdjR2 = 1 - (1-r2)*float64(n-1)/float64(n-k)
+		for _, v := range ys {
+			out.TotalReturn += v
+		}
+		out.Specific = coef[0] * float64(n)
+		for j, f := range factors {
+			var sum float64
+			for _, row := range xs {
+				sum += row[j+1]
+			}
+			out.Loadings = append(out.Loadings, FactorLoading{
+				factorDef:    f,
+				Beta:         coef[j+1],
+				StdErr:       se[j+1],
+				TStat:        coef[j+1] / se[j+1],
+				Contribution: coef[j+1] * sum,
+			})
+		}
+	}
+	return out, nil
+}
+
+// factorRegression fetches the target and every factor ETF's daily
+// closes in parallel and runs FactorRegress.
+func (s *Server) factorRegression(ctx context.Context, symbol string, window int) (*FactorRegression, error) {
+	var sector string
+	if raw, err := s.news.GetProfile(ctx, symbol); err == nil {
+		var rows []struct {
+			Sector string `json:"sector"`
+		}
+		if json.Unmarshal(raw, &rows) == nil && len(rows) > 0 {
+			sector = rows[0].Sector
+		}
+	}
+	factors := factorsFor(sector)
+
+	syms := []string{symbol}
+	seen := map[string]bool{symbol: true}
+	for _, f := range factors {
+		for _, sym := range []string{f.Long, f.Short} {
+			if sym != "" && !seen[sym] {
+				seen[sym] = true
+				syms = append(syms, sym)
+			}
+		}
+	}
+	series := make([][]pricePoint, len(syms))
+	errs := make([]error, len(syms))
+	var wg sync.WaitGroup
+	for i, sym := range syms {
+		wg.Add(1)
+		go func(i int, sym string) {
+			defer wg.Done()
+			raw, err := s.news.GetHistoricalPriceLight(ctx, sym)
+			if err == nil {
+				err = json.Unmarshal(raw, &series[i])
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("%s prices: %w", sym, err)
+			}
+		}(i, sym)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, &upstreamError{stage: "factor prices", msg: "fmp error", err: err}
+	}
+
+	// syms[0] is the target; when it is also a factor leg (SPY, XLK, …)
+	// it was fetched once and serves both roles.
+	etfs := make(map[string][]pricePoint, len(syms))
+	for i, sym := range syms {
+		if i > 0 || symbolIsFactorLeg(sym, factors) {
+			etfs[sym] = series[i]
+		}
+	}
+	dates, y, F, err := factorReturns(series[0], etfs, factors)
+	if err != nil {
+		return nil, err
+	}
+	return FactorRegress(symbol, dates, y, F, factors, window)
+}
+
+func symbolIsFactorLeg(symbol string, factors []factorDef) bool {
+	for _, f := range factors {
+		if f.Long == symbol || f.Short == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// factorWindow reads ?window=, defaulting to betaWindow.
+func factorWindow(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("window")
+	if raw == "" {
+		return betaWindow, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 60 || n > 1260 {
+		return 0, errors.New("window must be 60–1260 trading days")
+	}
+	return n, nil
+}
+
+// handleSecurityFactors: GET /api/security/{symbol}/factors?window=252
+func (s *Server) handleSecurityFactors(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.news == nil {
+		http.Error(w, "news client unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	window, err := factorWindow(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	symbol := strings.ToUpper(r.PathValue("symbol"))
+	ctx, cancel := contextWithTimeout(r, 60*time.Second)
+	defer cancel()
+	reg, err := s.factorRegression(ctx, symbol, window)
+	if err != nil {
+		writeFactorError(w, s, symbol, err)
+		return
+	}
+	json.NewEncoder(w).Encode(reg)
+}
+
+func writeFactorError(w http.ResponseWriter, s *Server, symbol string, err error) {
+	var ue *upstreamError
+	if errors.As(err, &ue) {
+		s.logger.Error("factor regression: "+ue.stage, "symbol", symbol, "error", ue.err)
+		http.Error(w, ue.msg, http.StatusBadGateway)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+}
+
+// factorFields maps the sketch handles (`:add AAPL.sizeLoading`) to the
+// rolling series they chart.
+var factorFields = map[string]func(FactorPoint) (float64, bool){
+	"marketLoading":   factorBeta("market"),
+	"sizeLoading":     factorBeta("size"),
+	"valueLoading":    factorBeta("value"),
+	"momentumLoading": factorBeta("momentum"),
+	"ratesLoading":    factorBeta("rates"),
+	"sectorLoading":   factorBeta("sector"),
+	"factorAlpha":     func(p FactorPoint) (float64, bool) { return p.Alpha, true },
+	"factorR2":        func(p FactorPoint) (float64, bool) { return p.R2, true },
+	"residualVol":     func(p FactorPoint) (float64, bool) { return p.ResidualVol, true },
+}
+
+func factorBeta(key string) func(FactorPoint) (float64, bool) {
+	return func(p FactorPoint) (float64, bool) {
+		v, ok := p.Betas[key]
+		return v, ok
+	}
+}
+
+// serveFactorField emits one rolling factor estimate as the chart layer's
+// [{date, value}] series.
+func (s *Server) serveFactorField(w http.ResponseWriter, r *http.Request, sym, field string) {
+	pick, ok := factorFields[field]
+	if !ok {
+		http.Error(w, "unknown factor field", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := contextWithTimeout(r, 60*time.Second)
+	defer cancel()
+	reg, err := s.factorRegression(ctx, strings.ToUpper(sym), betaWindow)
+	if err != nil {
+		writeFactorError(w, s, sym, err)
+		return
+	}
+	out := make([]betaPoint, 0, len(reg.Rolling))
+	for _, p := range reg.Rolling {
+		if v, ok := pick(p); ok {
+			out = append(out, betaPoint{Date: p.Date, Value: v})
+		}
+	}
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// synthPrices compounds daily returns into a descending (FMP-order) price
+// series starting at 100.
+func synthPrices(dates []string, rets []float64) []pricePoint {
+	out := make([]pricePoint, len(dates))
+	price := 100.0
+	for i := range dates {
+		if i > 0 {
+			price *= 1 + rets[i]
+		}
+		out[len(dates)-1-i] = pricePoint{Date: dates[i], Price: price}
+	}
+	return out
+}
+
+func TestFactorRegress(t *testing.T) {
+	const days = 400
+	rng := rand.New(rand.NewSource(7))
+	dates := make([]string, days)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range dates {
+		dates[i] = start.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	etfRets := map[string][]float64{}
+	for _, sym := range []string{"SPY", "IWM", "IWD", "IWF", "MTUM", "TLT", "XLK"} {
+		r := make([]float64, days)
+		for i := range r {
+			r[i] = rng.NormFloat64() * 0.01
+		}
+		etfRets[sym] = r
+	}
+	factors := factorsFor("Technology")
+	if len(factors) != 6 || factors[5].Long != "XLK" {
+		t.Fatalf("factorsFor(Technology) = %+v", factors)
+	}
+
+	// Target returns are built from the factor returns themselves (long
+	// minus short), so the regression should recover these loadings.
+	want := map[string]float64{"market": 1.2, "size": 0.4, "value": -0.3, "momentum": 0.2, "rates": -0.15, "sector": 0.8}
+	const alpha = 0.0002
+	target := make([]float64, days)
+	for i := range target {
+		target[i] = alpha + rng.NormFloat64()*0.002
+		for _, f := range factors {
+			fr := etfRets[f.Long][i]
+			if f.Short != "" {
+				fr -= etfRets[f.Short][i]
+			}
+			target[i] += want[f.Key] * fr
+		}
+	}
+
+	etfs := map[string][]pricePoint{}
+	for sym, r := range etfRets {
+		etfs[sym] = synthPrices(dates, r)
+	}
+	// Compounding makes the recovered returns exact: drop one ETF day to
+	// check the alignment skips it.
+	etfs["TLT"] = append(etfs["TLT"][:10], etfs["TLT"][11:]...)
+	dates2, y, F, err := factorReturns(synthPrices(dates, target), etfs, factors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(y) != days-2 || len(dates2) != len(y) {
+		t.Fatalf("aligned %d returns, want %d", len(y), days-2)
+	}
+
+	reg, err := FactorRegress("TEST", dates2, y, F, factors, 252)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reg.N != 252 || reg.To != dates2[len(dates2)-1] || len(reg.Rolling) != len(y)-252+1 {
+		t.Fatalf("window bookkeeping: n=%d to=%s rolling=%d", reg.N, reg.To, len(reg.Rolling))
+	}
+	var sum float64
+	for _, l := range reg.Loadings {
+		// The missing TLT day merges two days' moves into one return on
+		// every leg, so recovery is close but not exact.
+		if math.Abs(l.Beta-want[l.Key]) > 0.05 {
+			t.Errorf("%s loading = %.3f, want %.3f", l.Key, l.Beta, want[l.Key])
+		}
+		if math.Abs(l.TStat) < 10 {
+			t.Errorf("%s t-stat = %.1f, want a decisive estimate", l.Key, l.TStat)
+		}
+		sum += l.Contribution
+	}
+	if reg.R2 < 0.95 || reg.AdjR2 > reg.R2 {
+		t.Errorf("R² = %.3f adj %.3f", reg.R2, reg.AdjR2)
+	}
+	if math.Abs(reg.ResidualVol-0.002*math.Sqrt(252)) > 0.01 {
+		t.Errorf("residual vol = %.4f", reg.ResidualVol)
+	}
+	if math.Abs(sum+reg.Specific-reg.TotalReturn) > 1e-9 {
+		t.Errorf("attribution doesn't add up: %.6f + %.6f != %.6f", sum, reg.Specific, reg.TotalReturn)
+	}
+	last := reg.Rolling[len(reg.Rolling)-1]
+	if last.Betas["market"] != reg.Loadings[0].Beta || last.R2 != reg.R2 {
+		t.Errorf("last rolling point %+v disagrees with the summary", last)
+	}
+
+	// The market ETF is fitted exactly by its own factor; that's an error
+	// rather than infinite t-stats.
+	etfs["SPY"] = synthPrices(dates, etfRets["SPY"])
+	dates2, y, F, _ = factorReturns(etfs["SPY"], etfs, factors)
+	if _, err := FactorRegress("SPY", dates2, y, F, factors, 252); err == nil {
+		t.Error("expected an error regressing SPY on the market factor")
+	}
+	if _, err := FactorRegress("TEST", dates2[:100], y[:100], F[:100], factors, 252); err == nil {
+		t.Error("expected an error for a window longer than the history")
+	}
+}
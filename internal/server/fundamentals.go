@@ -16,7 +16,7 @@ import (
 // totalAssets, …) stay on the existing income/balance/cashflow path in
 // ideas.go and aren't listed here.
 type fieldEndpoint struct {
-	kind string // "keymetric" | "ratio" | "marketcap" | "beta" | "sectorBeta"
+	kind string // "keymetric" | "ratio" | "marketcap" | "beta" | "sectorBeta" | "factor"
 	// fmpField overrides the FMP JSON field name when it differs from the
 	// user-facing handle. Empty = use the map key. FMP's naming is wildly
 	// inconsistent — e.g. our friendly "peRatio" comes off /ratios as
@@ -60,6 +60,18 @@ var fundamentalFields = map[string]fieldEndpoint{
 	"marketCap":  {kind: "marketcap"},
 	"beta":       {kind: "beta"},
 	"sectorBeta": {kind: "sectorBeta"},
+
+	// Rolling multi-factor regression (factors.go) — one series per
+	// estimate, so `:add AAPL.sizeLoading` charts the 1y size loading.
+	"marketLoading":   {kind: "factor"},
+	"sizeLoading":     {kind: "factor"},
+	"valueLoading":    {kind: "factor"},
+	"momentumLoading": {kind: "factor"},
+	"ratesLoading":    {kind: "factor"},
+	"sectorLoading":   {kind: "factor"},
+	"factorAlpha":     {kind: "factor"},
+	"factorR2":        {kind: "factor"},
+	"residualVol":     {kind: "factor"},
 }
 
 // sectorETFs maps GICS sector names (and the variants FMP returns on
@@ -124,6 +136,8 @@ func (s *Server) serveFundamentalField(w http.ResponseWriter, r *http.Request, s
 		s.serveRollingBeta(w, r, sym)
 	case "sectorBeta":
 		s.serveSectorBeta(w, r, sym)
+	case "factor":
+		s.serveFactorField(w, r, sym, field)
 	case "keymetric":
 		s.serveAnnualField(w, r, sym, fmpFieldFor(field, ep), "keymetric")
 	case "ratio":
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"stocktopus/internal/calendars"
+)
+
+// handleMarkets returns the current session for every known venue, or for
+// ?venue=XNYS,XLON / ?symbol=VOD.L when given. Purely calendar-derived —
+// no upstream calls — so the indices page can refresh it freely.
+func (s *Server) handleMarkets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	now := time.Now()
+
+	if sym := strings.TrimSpace(r.URL.Query().Get("symbol")); sym != "" {
+		assetType := ""
+		if s.store != nil {
+			assetType = s.store.GetSecurityType(strings.ToUpper(sym))
+		}
+		json.NewEncoder(w).Encode(calendars.ForSymbol(sym, assetType).Status(now))
+		return
+	}
+
+	venues := calendars.All()
+	if q := r.URL.Query().Get("venue"); q != "" {
+		venues = venues[:0:0]
+		for _, code := range strings.Split(q, ",") {
+			if v, ok := calendars.Lookup(strings.TrimSpace(code)); ok {
+				venues = append(venues, v)
+			}
+		}
+	}
+	out := make([]calendars.Status, 0, len(venues))
+	for _, v := range venues {
+		out = append(out, v.Status(now))
+	}
+	json.NewEncoder(w).Encode(out)
+}
@@ -2,9 +2,11 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 // modeling.go implements a CFI-style three-statement model for a security.
@@ -18,10 +20,21 @@ import (
 // line ties to a driver, the cash row is the closing balance plug from
 // the cash flow, and `Total Assets - Total L&E` is emitted as `bsCheck`
 // (which must round to zero on a balanced model).
-
-// ModelingPeriod is one year of statements, historical or forecast.
+//
+// The model also runs quarterly: history is FMP's quarterly statements,
+// drivers are derived per fiscal quarter from the same quarter a year
+// earlier (so seasonality carries forward), revenue grows year over year
+// against that quarter, and day-count drivers are read over a 91¼-day
+// period. Quarterly forecasts are rolled up into fiscal years for the
+// DCF, and the last four reported quarters into a TTM period.
+
+// ModelingPeriod is one period of statements, historical or forecast: a
+// fiscal year, or a fiscal quarter when Quarter is set. A fiscal-year
+// roll-up of quarters has Quarters set to how many it covers.
 type ModelingPeriod struct {
 	Year       int  `json:"year"`
+	Quarter    int  `json:"quarter,omitempty"`  // 1–4 on quarterly periods
+	Quarters   int  `json:"quarters,omitempty"` // quarters in a fiscal-year roll-up
 	Historical bool `json:"historical"`
 
 	// Income statement
@@ -57,8 +70,9 @@ type ModelingPeriod struct {
 }
 
 // ModelingAssumptions is the 12-driver vector. Each slice has one entry
-// per forecast year. The frontend can submit a partial override; missing
-// years fall back to the derived default for that index.
+// per forecast period (year or quarter). The frontend can submit a
+// partial override: a driver left out falls back to its derived default,
+// but one that's given must cover the whole horizon.
 type ModelingAssumptions struct {
 	RevenueGrowth  []float64 `json:"revenueGrowth"`
 	COGSPct        []float64 `json:"cogsPct"`
@@ -75,17 +89,82 @@ type ModelingAssumptions struct {
 }
 
 // ModelingResponse is the wire shape returned by /api/security/{sym}/modeling.
+// Annual and TTM are set on quarterly models only: Annual is the forecast
+// rolled up into fiscal years (what the DCF values), TTM the last four
+// reported quarters.
 type ModelingResponse struct {
 	Symbol      string              `json:"symbol"`
+	Period      string              `json:"period"`
+	Horizon     int                 `json:"horizon"`
 	Historical  []ModelingPeriod    `json:"historical"`
 	Forecast    []ModelingPeriod    `json:"forecast"`
+	Annual      []ModelingPeriod    `json:"annual,omitempty"`
+	TTM         *ModelingPeriod     `json:"ttm,omitempty"`
 	Assumptions ModelingAssumptions `json:"assumptions"`
+	DCF         *DCFResult          `json:"dcf,omitempty"`
+	DCFError    string              `json:"dcfError,omitempty"` // why DCF is missing
+}
+
+// modelingRequest is the POST body: driver overrides at the top level (the
+// original wire shape), the period and horizon, and optional valuation
+// inputs under "dcf".
+type modelingRequest struct {
+	ModelingAssumptions
+	Period  string    `json:"period,omitempty"`  // "annual" (default) or "quarterly"
+	Horizon int       `json:"horizon,omitempty"` // forecast periods; 0 = the period's default
+	DCF     DCFInputs `json:"dcf"`
+}
+
+// Model periods.
+const (
+	PeriodAnnual    = "annual"
+	PeriodQuarterly = "quarterly"
+)
+
+// ForecastYears is the default annual horizon, mirroring the CFI case
+// study; ForecastQuarters the quarterly one. Requests can pick any
+// horizon up to maxForecastYears / maxForecastQuarters.
+const (
+	ForecastYears       = 5
+	ForecastQuarters    = 8
+	maxForecastYears    = 15
+	maxForecastQuarters = 40
+)
+
+// resolvePeriod validates a request's period and horizon and fills in
+// the defaults.
+func resolvePeriod(period string, horizon int) (string, int, error) {
+	switch strings.ToLower(period) {
+	case "", PeriodAnnual:
+		period = PeriodAnnual
+		if horizon == 0 {
+			horizon = ForecastYears
+		}
+		if horizon < 1 || horizon > maxForecastYears {
+			return "", 0, fmt.Errorf("horizon must be 1-%d years", maxForecastYears)
+		}
+	case PeriodQuarterly, "quarter":
+		period = PeriodQuarterly
+		if horizon == 0 {
+			horizon = ForecastQuarters
+		}
+		if horizon < 1 || horizon > maxForecastQuarters {
+			return "", 0, fmt.Errorf("horizon must be 1-%d quarters", maxForecastQuarters)
+		}
+	default:
+		return "", 0, fmt.Errorf("unknown period %q (annual, quarterly)", period)
+	}
+	return period, horizon, nil
 }
 
-// ForecastYears is fixed at 5 to mirror the CFI case study. A future
-// pass can parameterise this, but every driver slice currently assumes
-// `len == ForecastYears`.
-const ForecastYears = 5
+// periodDays is the day count the AR/inventory/AP day drivers are read
+// over: a year, or a quarter for quarterly periods.
+func periodDays(p ModelingPeriod) float64 {
+	if p.Quarter != 0 {
+		return 365.0 / 4
+	}
+	return 365
+}
 
 // pickFloat extracts a numeric field from an FMP statement row. FMP
 // statements come back with mixed types (raw ints for whole numbers,
@@ -131,14 +210,25 @@ func statementYear(row map[string]any) int {
 	return 0
 }
 
+// statementQuarter returns the fiscal quarter of a quarterly statement
+// row (FMP's `period` is "Q1".."Q4"), or 0 for an annual one ("FY").
+func statementQuarter(row map[string]any) int {
+	if p, ok := row["period"].(string); ok && len(p) == 2 && (p[0] == 'Q' || p[0] == 'q') && p[1] >= '1' && p[1] <= '4' {
+		return int(p[1] - '0')
+	}
+	return 0
+}
+
 // buildHistorical assembles a slice of ModelingPeriods from the three
-// FMP statement arrays. Statements are aligned by fiscal year; years
-// missing from any of the three are dropped. The result is sorted
-// ascending so index 0 is the oldest year — the forecast loop reads
-// `historical[last]` to seed its first projection.
+// FMP statement arrays. Statements are aligned by fiscal year, and by
+// quarter too for quarterly statements; periods missing from any of the
+// three are dropped. The result is sorted ascending so index 0 is the
+// oldest period — the forecast loop reads `historical[last]` to seed its
+// first projection.
 func buildHistorical(income, balance, cashflow []map[string]any) []ModelingPeriod {
 	type yearRow struct {
-		inc, bal, cf map[string]any
+		year, quarter int
+		inc, bal, cf  map[string]any
 	}
 	byYear := map[int]*yearRow{}
 	add := func(rows []map[string]any, kind string) {
@@ -147,10 +237,11 @@ func buildHistorical(income, balance, cashflow []map[string]any) []ModelingPerio
 			if y == 0 {
 				continue
 			}
-			yr, ok := byYear[y]
+			q := statementQuarter(r)
+			yr, ok := byYear[y*10+q]
 			if !ok {
-				yr = &yearRow{}
-				byYear[y] = yr
+				yr = &yearRow{year: y, quarter: q}
+				byYear[y*10+q] = yr
 			}
 			switch kind {
 			case "inc":
@@ -167,11 +258,11 @@ func buildHistorical(income, balance, cashflow []map[string]any) []ModelingPerio
 	add(cashflow, "cf")
 
 	out := make([]ModelingPeriod, 0, len(byYear))
-	for y, yr := range byYear {
+	for _, yr := range byYear {
 		if yr.inc == nil || yr.bal == nil || yr.cf == nil {
 			continue
 		}
-		p := ModelingPeriod{Year: y, Historical: true}
+		p := ModelingPeriod{Year: yr.year, Quarter: yr.quarter, Historical: true}
 
 		// Income statement — FMP fields. `operatingExpenses` includes D&A
 		// for some filers and excludes it for others; we subtract D&A
@@ -240,126 +331,171 @@ func buildHistorical(income, balance, cashflow []map[string]any) []ModelingPerio
 
 		out = append(out, p)
 	}
-	sort.Slice(out, func(i, j int) bool { return out[i].Year < out[j].Year })
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Year != out[j].Year {
+			return out[i].Year < out[j].Year
+		}
+		return out[i].Quarter < out[j].Quarter
+	})
 	return out
 }
 
-// DeriveDefaults reads the last historical year and the year before it to
-// produce a flat 5-year assumption vector. Each driver projects forward
-// at the last observed value — the user's job in the UI is to edit these
-// to taste before the LLM call. Where a ratio's denominator is zero, the
-// driver falls back to a neutral default (0 growth, 0 days, etc.).
-func DeriveDefaults(hist []ModelingPeriod) ModelingAssumptions {
+// DeriveDefaults produces a flat assumption vector of `horizon` periods.
+// For annual history it reads the last year and the year before it; each
+// driver projects forward at the last observed value — the user's job
+// in the UI is to edit these to taste before the LLM call. For quarterly
+// history each forecast quarter takes its drivers from the same fiscal
+// quarter a year earlier, so a Q4-heavy business keeps its Q4 margins
+// and a seasonal inventory build repeats. Where a ratio's denominator is
+// zero, the driver falls back to a neutral default (0 growth, 0 days,
+// etc.).
+func DeriveDefaults(hist []ModelingPeriod, horizon int) ModelingAssumptions {
 	a := ModelingAssumptions{
-		RevenueGrowth:  make([]float64, ForecastYears),
-		COGSPct:        make([]float64, ForecastYears),
-		OpExPct:        make([]float64, ForecastYears),
-		DAPct:          make([]float64, ForecastYears),
-		InterestPct:    make([]float64, ForecastYears),
-		TaxRate:        make([]float64, ForecastYears),
-		ARDays:         make([]float64, ForecastYears),
-		InventoryDays:  make([]float64, ForecastYears),
-		APDays:         make([]float64, ForecastYears),
-		Capex:          make([]float64, ForecastYears),
-		DebtIssuance:   make([]float64, ForecastYears),
-		EquityIssuance: make([]float64, ForecastYears),
+		RevenueGrowth:  make([]float64, horizon),
+		COGSPct:        make([]float64, horizon),
+		OpExPct:        make([]float64, horizon),
+		DAPct:          make([]float64, horizon),
+		InterestPct:    make([]float64, horizon),
+		TaxRate:        make([]float64, horizon),
+		ARDays:         make([]float64, horizon),
+		InventoryDays:  make([]float64, horizon),
+		APDays:         make([]float64, horizon),
+		Capex:          make([]float64, horizon),
+		DebtIssuance:   make([]float64, horizon),
+		EquityIssuance: make([]float64, horizon),
 	}
 	if len(hist) == 0 {
 		return a
 	}
-	last := hist[len(hist)-1]
+	n := len(hist)
+	seasonal := hist[n-1].Quarter != 0 && n >= 4
+
+	for i := 0; i < horizon; i++ {
+		// ref is the period the drivers are read from: the last one, or
+		// for quarters the same quarter a year before the forecast one.
+		ref := n - 1
+		if seasonal {
+			ref = n - 4 + i%4
+		}
+		d := periodDrivers(hist, ref)
+		a.RevenueGrowth[i] = d.growth
+		a.COGSPct[i] = d.cogsPct
+		a.OpExPct[i] = d.opexPct
+		a.DAPct[i] = d.daPct
+		a.InterestPct[i] = d.interestPct
+		a.TaxRate[i] = d.taxRate
+		a.ARDays[i] = d.arDays
+		a.InventoryDays[i] = d.invDays
+		a.APDays[i] = d.apDays
+		a.Capex[i] = d.capex
+		// Debt/equity issuance default to zero — projecting forward last
+		// year's one-off financing action would be a bad default.
+		a.DebtIssuance[i] = 0
+		a.EquityIssuance[i] = 0
+	}
+	return a
+}
 
-	var growth float64
-	if len(hist) >= 2 {
-		prev := hist[len(hist)-2]
-		if prev.Revenue > 0 {
-			growth = (last.Revenue - prev.Revenue) / prev.Revenue
+type drivers struct {
+	growth, cogsPct, opexPct, daPct, interestPct, taxRate float64
+	arDays, invDays, apDays, capex                        float64
+}
+
+// periodDrivers reads the drivers off hist[i]. Growth is against the
+// period a year earlier — the previous one for annual history, four back
+// for quarterly — and the D&A and interest rates are against the opening
+// PP&E and debt, i.e. the previous period's closing balances.
+func periodDrivers(hist []ModelingPeriod, i int) drivers {
+	var d drivers
+	last := hist[i]
+	days := periodDays(last)
+
+	back := 1
+	if last.Quarter != 0 {
+		back = 4
+	}
+	if i >= back {
+		if yearAgo := hist[i-back]; yearAgo.Revenue > 0 {
+			d.growth = (last.Revenue - yearAgo.Revenue) / yearAgo.Revenue
 		}
 	}
 
-	var cogsPct, opexPct, taxRate, arDays, invDays, apDays float64
 	if last.Revenue > 0 {
-		cogsPct = last.COGS / last.Revenue
-		opexPct = last.OpEx / last.Revenue
-		arDays = last.AR * 365 / last.Revenue
+		d.cogsPct = last.COGS / last.Revenue
+		d.opexPct = last.OpEx / last.Revenue
+		d.arDays = last.AR * days / last.Revenue
 	}
 	if last.COGS > 0 {
-		invDays = last.Inventory * 365 / last.COGS
-		apDays = last.AP * 365 / last.COGS
+		d.invDays = last.Inventory * days / last.COGS
+		d.apDays = last.AP * days / last.COGS
 	}
 	if last.EBT > 0 {
-		taxRate = last.Tax / last.EBT
+		d.taxRate = last.Tax / last.EBT
 	}
 
-	var daPct, interestPct float64
-	if len(hist) >= 2 {
-		prev := hist[len(hist)-2]
+	if i >= 1 {
+		prev := hist[i-1]
 		if prev.PPE > 0 {
-			daPct = last.DA / prev.PPE
+			d.daPct = last.DA / prev.PPE
 		}
 		if prev.Debt > 0 {
-			interestPct = last.Interest / prev.Debt
+			d.interestPct = last.Interest / prev.Debt
 		}
 	}
 
-	capex := last.NetChangeCash // overwritten below from cashflow if available
+	d.capex = last.NetChangeCash // overwritten below from cashflow if available
 	// Better: the capex driver should be the absolute investing-side
 	// outflow. We stored InvestingCF (signed) and lost capex itself; for
 	// the default, approximate as |InvestingCF| since CFI treats
 	// investing as pure capex.
 	if last.InvestingCF < 0 {
-		capex = -last.InvestingCF
-	}
-
-	for i := 0; i < ForecastYears; i++ {
-		a.RevenueGrowth[i] = growth
-		a.COGSPct[i] = cogsPct
-		a.OpExPct[i] = opexPct
-		a.DAPct[i] = daPct
-		a.InterestPct[i] = interestPct
-		a.TaxRate[i] = taxRate
-		a.ARDays[i] = arDays
-		a.InventoryDays[i] = invDays
-		a.APDays[i] = apDays
-		a.Capex[i] = capex
-		// Debt/equity issuance default to zero — projecting forward last
-		// year's one-off financing action would be a bad default.
-		a.DebtIssuance[i] = 0
-		a.EquityIssuance[i] = 0
+		d.capex = -last.InvestingCF
 	}
-	return a
+	return d
 }
 
 // mergeAssumptions overlays user-supplied assumptions on top of defaults.
-// Any slice on the override that's the right length wins; otherwise the
-// default for that driver is used. This lets the frontend POST a partial
-// edit (e.g. "I only changed Revenue Growth and Tax Rate").
-func mergeAssumptions(defaults, override ModelingAssumptions) ModelingAssumptions {
-	pick := func(d, o []float64) []float64 {
-		if len(o) == ForecastYears {
+// A driver left out of the override keeps its default, which lets the
+// frontend POST a partial edit (e.g. "I only changed Revenue Growth and
+// Tax Rate"). A driver that's given must have one value per forecast
+// period — a short or long slice is an error rather than a silent
+// fallback, since it usually means the horizon changed under it.
+func mergeAssumptions(defaults, override ModelingAssumptions) (ModelingAssumptions, error) {
+	horizon := len(defaults.RevenueGrowth)
+	var errs []string
+	pick := func(name string, d, o []float64) []float64 {
+		switch len(o) {
+		case 0:
+			return d
+		case horizon:
 			return o
 		}
+		errs = append(errs, fmt.Sprintf("%s has %d values, want %d", name, len(o), horizon))
 		return d
 	}
-	return ModelingAssumptions{
-		RevenueGrowth:  pick(defaults.RevenueGrowth, override.RevenueGrowth),
-		COGSPct:        pick(defaults.COGSPct, override.COGSPct),
-		OpExPct:        pick(defaults.OpExPct, override.OpExPct),
-		DAPct:          pick(defaults.DAPct, override.DAPct),
-		InterestPct:    pick(defaults.InterestPct, override.InterestPct),
-		TaxRate:        pick(defaults.TaxRate, override.TaxRate),
-		ARDays:         pick(defaults.ARDays, override.ARDays),
-		InventoryDays:  pick(defaults.InventoryDays, override.InventoryDays),
-		APDays:         pick(defaults.APDays, override.APDays),
-		Capex:          pick(defaults.Capex, override.Capex),
-		DebtIssuance:   pick(defaults.DebtIssuance, override.DebtIssuance),
-		EquityIssuance: pick(defaults.EquityIssuance, override.EquityIssuance),
+	a := ModelingAssumptions{
+		RevenueGrowth:  pick("revenueGrowth", defaults.RevenueGrowth, override.RevenueGrowth),
+		COGSPct:        pick("cogsPct", defaults.COGSPct, override.COGSPct),
+		OpExPct:        pick("opexPct", defaults.OpExPct, override.OpExPct),
+		DAPct:          pick("daPct", defaults.DAPct, override.DAPct),
+		InterestPct:    pick("interestPct", defaults.InterestPct, override.InterestPct),
+		TaxRate:        pick("taxRate", defaults.TaxRate, override.TaxRate),
+		ARDays:         pick("arDays", defaults.ARDays, override.ARDays),
+		InventoryDays:  pick("inventoryDays", defaults.InventoryDays, override.InventoryDays),
+		APDays:         pick("apDays", defaults.APDays, override.APDays),
+		Capex:          pick("capex", defaults.Capex, override.Capex),
+		DebtIssuance:   pick("debtIssuance", defaults.DebtIssuance, override.DebtIssuance),
+		EquityIssuance: pick("equityIssuance", defaults.EquityIssuance, override.EquityIssuance),
+	}
+	if len(errs) > 0 {
+		return a, fmt.Errorf("%s", strings.Join(errs, "; "))
 	}
+	return a, nil
 }
 
-// Project rolls forward `ForecastYears` years from the last historical
-// period using the supplied assumption vector. The math is the CFI
-// case-study model verbatim:
+// Project rolls forward one period per driver value from the last
+// historical period using the supplied assumption vector. The math is
+// the CFI case-study model verbatim:
 //
 //	Revenueₜ        = Revenueₜ₋₁ × (1 + g)
 //	COGSₜ           = Revenueₜ × COGS%
@@ -385,12 +521,30 @@ func mergeAssumptions(defaults, override ModelingAssumptions) ModelingAssumption
 // The balance-sheet check `Total Assets − Total L&E` is computed but not
 // enforced — for a well-behaved driver set it sits at zero (within FP
 // noise), and divergence flags a driver inconsistency to the user.
+//
+// Quarterly periods differ in two places: g is year-over-year, so
+// Revenueₜ = Revenueₜ₋₄ × (1 + g) and the seasonal shape carries
+// forward, and the day drivers divide by 91¼ rather than 365.
 func Project(hist []ModelingPeriod, a ModelingAssumptions) []ModelingPeriod {
 	if len(hist) == 0 {
 		return nil
 	}
-	out := make([]ModelingPeriod, 0, ForecastYears)
+	horizon := len(a.RevenueGrowth)
+	out := make([]ModelingPeriod, 0, horizon)
 	prior := hist[len(hist)-1]
+	quarterly := prior.Quarter != 0
+	days := periodDays(prior)
+	// yearAgo is the period four quarters before forecast i.
+	yearAgo := func(i int) *ModelingPeriod {
+		switch k := len(hist) + i - 4; {
+		case k < 0:
+			return nil
+		case k < len(hist):
+			return &hist[k]
+		default:
+			return &out[k-len(hist)]
+		}
+	}
 	// The forecast tracks only four asset and four L&E lines; everything
 	// else (LT investments, goodwill, deferred taxes, AOCI, …) collapses
 	// into a constant plug. The accounting identity guarantees Δ TA_subset
@@ -400,11 +554,21 @@ func Project(hist []ModelingPeriod, a ModelingAssumptions) []ModelingPeriod {
 	// is invariant. Subtracting it from BSCheck means a self-consistent
 	// model displays zero and a broken driver set surfaces the drift.
 	plug := prior.TotalAssets - prior.TotalLE
-	for i := 0; i < ForecastYears; i++ {
+	for i := 0; i < horizon; i++ {
 		p := ModelingPeriod{Year: prior.Year + 1, Historical: false}
+		base := prior.Revenue
+		if quarterly {
+			p.Year, p.Quarter = prior.Year, prior.Quarter+1
+			if p.Quarter > 4 {
+				p.Year, p.Quarter = prior.Year+1, 1
+			}
+			if ya := yearAgo(i); ya != nil {
+				base = ya.Revenue
+			}
+		}
 
 		// Income statement
-		p.Revenue = prior.Revenue * (1 + a.RevenueGrowth[i])
+		p.Revenue = base * (1 + a.RevenueGrowth[i])
 		p.COGS = p.Revenue * a.COGSPct[i]
 		p.GrossProfit = p.Revenue - p.COGS
 		p.OpEx = p.Revenue * a.OpExPct[i]
@@ -417,11 +581,11 @@ func Project(hist []ModelingPeriod, a ModelingAssumptions) []ModelingPeriod {
 
 		// Balance sheet (everything except cash, which is the CF plug)
 		if p.Revenue > 0 {
-			p.AR = p.Revenue * a.ARDays[i] / 365
+			p.AR = p.Revenue * a.ARDays[i] / days
 		}
 		if p.COGS > 0 {
-			p.Inventory = p.COGS * a.InventoryDays[i] / 365
-			p.AP = p.COGS * a.APDays[i] / 365
+			p.Inventory = p.COGS * a.InventoryDays[i] / days
+			p.AP = p.COGS * a.APDays[i] / days
 		}
 		p.PPE = prior.PPE + a.Capex[i] - p.DA
 		p.Debt = prior.Debt + a.DebtIssuance[i]
@@ -448,74 +612,241 @@ func Project(hist []ModelingPeriod, a ModelingAssumptions) []ModelingPeriod {
 	return out
 }
 
-// handleSecurityModeling fetches 5y income/balance/cashflow from FMP,
+// addFlows and setBalances split ModelingPeriod's lines for roll-ups:
+// flows add up, balances are the closing ones.
+func addFlows(dst *ModelingPeriod, p ModelingPeriod) {
+	dst.Revenue += p.Revenue
+	dst.COGS += p.COGS
+	dst.GrossProfit += p.GrossProfit
+	dst.OpEx += p.OpEx
+	dst.DA += p.DA
+	dst.Interest += p.Interest
+	dst.TotalExp += p.TotalExp
+	dst.EBT += p.EBT
+	dst.Tax += p.Tax
+	dst.NetEarnings += p.NetEarnings
+	dst.OperatingCF += p.OperatingCF
+	dst.InvestingCF += p.InvestingCF
+	dst.FinancingCF += p.FinancingCF
+	dst.NetChangeCash += p.NetChangeCash
+}
+
+func setBalances(dst *ModelingPeriod, p ModelingPeriod) {
+	dst.Cash = p.Cash
+	dst.AR = p.AR
+	dst.Inventory = p.Inventory
+	dst.PPE = p.PPE
+	dst.TotalAssets = p.TotalAssets
+	dst.AP = p.AP
+	dst.Debt = p.Debt
+	dst.Equity = p.Equity
+	dst.RetainedEarnings = p.RetainedEarnings
+	dst.TotalLE = p.TotalLE
+	dst.BSCheck = p.BSCheck
+}
+
+// AggregateFiscalYears rolls quarterly periods up into fiscal years:
+// flows summed, balances and the balance-sheet check as of the year's
+// last quarter. Quarters says how many quarters each year covers — the
+// first can be a stub when the quarters start mid-year. A trailing year
+// that doesn't reach Q4 is left out, so the last year is always a whole
+// one a terminal value can be grown from.
+func AggregateFiscalYears(quarters []ModelingPeriod) []ModelingPeriod {
+	var out []ModelingPeriod
+	for _, q := range quarters {
+		if len(out) == 0 || out[len(out)-1].Year != q.Year {
+			out = append(out, ModelingPeriod{Year: q.Year, Historical: q.Historical})
+		}
+		y := &out[len(out)-1]
+		addFlows(y, q)
+		setBalances(y, q)
+		y.Quarters++
+		y.Historical = y.Historical && q.Historical
+	}
+	if n := len(out); n > 0 && quarters[len(quarters)-1].Quarter != 4 {
+		out = out[:n-1]
+	}
+	return out
+}
+
+// TrailingTwelveMonths sums the last four quarters' flows over the latest
+// quarter's balance sheet, or returns nil with fewer than four quarters.
+func TrailingTwelveMonths(quarters []ModelingPeriod) *ModelingPeriod {
+	if len(quarters) < 4 {
+		return nil
+	}
+	last := quarters[len(quarters)-1]
+	ttm := ModelingPeriod{Year: last.Year, Quarter: last.Quarter, Quarters: 4, Historical: true}
+	for _, q := range quarters[len(quarters)-4:] {
+		addFlows(&ttm, q)
+	}
+	setBalances(&ttm, last)
+	return &ttm
+}
+
+// modelRun is one projected and valued set of inputs.
+type modelRun struct {
+	Assumptions ModelingAssumptions
+	Forecast    []ModelingPeriod
+	Annual      []ModelingPeriod // quarterly forecasts rolled up
+	DCF         *DCFResult
+	DCFError    string
+}
+
+// runModel merges req's overrides into the defaults derived from hist,
+// projects `horizon` periods and values them. Quarterly forecasts are
+// valued on their fiscal-year roll-up. An error means the overrides
+// don't fit the horizon; a valuation that can't be done is reported in
+// DCFError instead.
+func runModel(hist []ModelingPeriod, req modelingRequest, horizon int, m DCFMarket) (modelRun, error) {
+	a, err := mergeAssumptions(DeriveDefaults(hist, horizon), req.ModelingAssumptions)
+	if err != nil {
+		return modelRun{}, err
+	}
+	run := modelRun{Assumptions: a, Forecast: Project(hist, a)}
+	valued := run.Forecast
+	if len(hist) > 0 && hist[len(hist)-1].Quarter != 0 {
+		run.Annual = AggregateFiscalYears(run.Forecast)
+		valued = run.Annual
+		if len(valued) == 0 && len(run.Forecast) > 0 {
+			run.DCFError = "the forecast doesn't reach a fiscal year end; lengthen the horizon"
+			return run, nil
+		}
+	}
+	if len(valued) > 0 {
+		if run.DCF, err = ValueDCF(hist, valued, a, req.DCF, m); err != nil {
+			run.DCFError = err.Error()
+		}
+	}
+	return run, nil
+}
+
+// handleSecurityModeling fetches income/balance/cashflow from FMP,
 // builds the historical periods, derives default drivers, applies any
-// user-supplied override from the request body, projects 5y forward,
-// and emits the combined response.
+// user-supplied override from the request body, projects forward, and
+// emits the combined response.
 //
-//	GET  /api/security/{sym}/modeling                 → defaults + projection
-//	POST /api/security/{sym}/modeling  body=overrides → user assumptions
+//	GET  /api/security/{sym}/modeling?period=quarterly&horizon=8 → defaults + projection
+//	POST /api/security/{sym}/modeling  body=overrides            → user assumptions
 //
-// Overrides arrive as a `ModelingAssumptions` JSON; any slice of length
-// other than ForecastYears falls back to the default for that driver.
+// Overrides arrive as a `ModelingAssumptions` JSON, optionally with
+// "period" and "horizon" (which win over the query string); a driver
+// slice whose length isn't the horizon is a 400. An optional "dcf"
+// object carries DCFInputs for the valuation; when the valuation can't
+// be computed the model is still returned, with dcfError saying why.
 func (s *Server) handleSecurityModeling(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	resp, ok := s.modelingResponse(w, r)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// modelingResponse runs the model for a modeling request — overrides in
+// a POST body, period and horizon in the body or the query string. It
+// writes the error and returns false when the request can't be run.
+func (s *Server) modelingResponse(w http.ResponseWriter, r *http.Request) (ModelingResponse, bool) {
 	symbol := r.PathValue("symbol")
+
+	var req modelingRequest
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return ModelingResponse{}, false
+		}
+	}
+	if req.Period == "" {
+		req.Period = r.URL.Query().Get("period")
+	}
+	if req.Horizon == 0 {
+		req.Horizon, _ = strconv.Atoi(r.URL.Query().Get("horizon"))
+	}
+	period, horizon, err := resolvePeriod(req.Period, req.Horizon)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return ModelingResponse{}, false
+	}
+
+	hist, income, ok := s.loadModelingHistory(w, r, symbol, period)
+	if !ok {
+		return ModelingResponse{}, false
+	}
+	run, err := runModel(hist, req, horizon, s.dcfMarket(r, symbol, income))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return ModelingResponse{}, false
+	}
+
+	resp := ModelingResponse{
+		Symbol:      symbol,
+		Period:      period,
+		Horizon:     horizon,
+		Historical:  hist,
+		Forecast:    run.Forecast,
+		Annual:      run.Annual,
+		Assumptions: run.Assumptions,
+		DCF:         run.DCF,
+		DCFError:    run.DCFError,
+	}
+	if period == PeriodQuarterly {
+		resp.TTM = TrailingTwelveMonths(hist)
+	}
+	return resp, true
+}
+
+// loadModelingHistory fetches income, balance and cash flow statements
+// for the period and builds the historical periods. It writes a 502 and
+// returns false when a statement can't be had. The raw income rows are
+// returned too, for the DCF's share count.
+func (s *Server) loadModelingHistory(w http.ResponseWriter, r *http.Request, symbol, period string) ([]ModelingPeriod, []map[string]any, bool) {
 	ctx := r.Context()
 
 	// 10y of history — the upgraded FMP plan returns the full window;
 	// the lower tier silently caps at 5y, so this is a strict
 	// non-regression. The forecast loop only reads `historical[last]`
-	// so the additional years just enrich the displayed time line.
+	// (and the four quarters before it, for seasonality) so the
+	// additional periods just enrich the displayed time line.
 	const HistYears = 10
-	incomeRaw, err := s.news.GetIncomeStatement(ctx, symbol, HistYears)
+	// 5y of quarters: enough to see each quarter's seasonality twice
+	// without the table running off the screen.
+	const HistQuarters = 20
+	fetchIncome, fetchBalance, fetchCashflow, limit := s.news.GetIncomeStatement, s.news.GetBalanceSheet, s.news.GetCashFlow, HistYears
+	if period == PeriodQuarterly {
+		fetchIncome, fetchBalance, fetchCashflow, limit = s.news.GetQuarterlyIncomeStatement, s.news.GetQuarterlyBalanceSheet, s.news.GetQuarterlyCashFlow, HistQuarters
+	}
+	incomeRaw, err := fetchIncome(ctx, symbol, limit)
 	if err != nil {
 		http.Error(w, "income statement fetch failed", http.StatusBadGateway)
-		return
+		return nil, nil, false
 	}
-	balanceRaw, err := s.news.GetBalanceSheet(ctx, symbol, HistYears)
+	balanceRaw, err := fetchBalance(ctx, symbol, limit)
 	if err != nil {
 		http.Error(w, "balance sheet fetch failed", http.StatusBadGateway)
-		return
+		return nil, nil, false
 	}
-	cashflowRaw, err := s.news.GetCashFlow(ctx, symbol, HistYears)
+	cashflowRaw, err := fetchCashflow(ctx, symbol, limit)
 	if err != nil {
 		http.Error(w, "cash flow fetch failed", http.StatusBadGateway)
-		return
+		return nil, nil, false
 	}
 
 	var income, balance, cashflow []map[string]any
 	if err := json.Unmarshal(incomeRaw, &income); err != nil {
 		http.Error(w, "bad income response", http.StatusBadGateway)
-		return
+		return nil, nil, false
 	}
 	if err := json.Unmarshal(balanceRaw, &balance); err != nil {
 		http.Error(w, "bad balance response", http.StatusBadGateway)
-		return
+		return nil, nil, false
 	}
 	if err := json.Unmarshal(cashflowRaw, &cashflow); err != nil {
 		http.Error(w, "bad cashflow response", http.StatusBadGateway)
-		return
-	}
-
-	hist := buildHistorical(income, balance, cashflow)
-	defaults := DeriveDefaults(hist)
-
-	assumptions := defaults
-	if r.Method == http.MethodPost {
-		var override ModelingAssumptions
-		if err := json.NewDecoder(r.Body).Decode(&override); err == nil {
-			assumptions = mergeAssumptions(defaults, override)
-		}
-	}
-
-	forecast := Project(hist, assumptions)
-
-	resp := ModelingResponse{
-		Symbol:      symbol,
-		Historical:  hist,
-		Forecast:    forecast,
-		Assumptions: assumptions,
+		return nil, nil, false
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	return buildHistorical(income, balance, cashflow), income, true
 }
@@ -112,7 +112,7 @@ func TestDeriveDefaultsZeroEdgeCases(t *testing.T) {
 		{Year: 2023, Revenue: 0, COGS: 0, Debt: 0, PPE: 0, EBT: 0},
 		{Year: 2024, Revenue: 0, COGS: 0, Debt: 0, PPE: 0, EBT: 0},
 	}
-	a := DeriveDefaults(hist)
+	a := DeriveDefaults(hist, ForecastYears)
 	for i, v := range a.RevenueGrowth {
 		if math.IsNaN(v) || math.IsInf(v, 0) {
 			t.Errorf("RevenueGrowth[%d] is %v", i, v)
@@ -129,3 +129,270 @@ func checkClose(t *testing.T, name string, got, want float64) {
 		t.Errorf("%s: got %f, want %f", name, got, want)
 	}
 }
+
+// TestValueDCF values a flat two-year forecast by hand: UFCF is
+// 100×(1−25%) + 20 − 30 − 5 = 60 each year, Ke = 4% + 1.2×5% = 10% and
+// WACC = 90%×10% + 10%×10%×(1−25%) = 9.75%.
+func TestValueDCF(t *testing.T) {
+	hist := []ModelingPeriod{{Year: 2024, Debt: 100, Cash: 20}}
+	year := func(y int) ModelingPeriod {
+		return ModelingPeriod{Year: y, EBT: 90, Tax: 22.5, Interest: 10, DA: 20, NetEarnings: 67.5,
+			OperatingCF: 67.5 + 20 - 5, InvestingCF: -30}
+	}
+	forecast := []ModelingPeriod{year(2025), year(2026)}
+	a := ModelingAssumptions{TaxRate: []float64{0.25, 0.25}, InterestPct: []float64{0.1, 0.1}}
+	m := DCFMarket{Beta: 1.2, BetaSource: "test", RiskFree: 0.04, RiskFreeSource: "test", Shares: 10, MarketCap: 900, Price: 90}
+	erp, g := 0.05, 0.02
+	in := DCFInputs{EquityRiskPremium: &erp, TerminalGrowth: &g}
+
+	res, err := ValueDCF(hist, forecast, a, in, m)
+	if err != nil {
+		t.Fatalf("ValueDCF: %v", err)
+	}
+	for _, y := range res.Years {
+		if math.Abs(y.UFCF-60) > 1e-9 {
+			t.Errorf("%d UFCF = %f, want 60", y.Year, y.UFCF)
+		}
+	}
+	if math.Abs(res.WACC-0.0975) > 1e-12 {
+		t.Fatalf("WACC = %f, want 0.0975", res.WACC)
+	}
+	w := 0.0975
+	pv := 60/(1+w) + 60/((1+w)*(1+w))
+	tv := 60 * (1 + g) / (w - g)
+	ev := pv + tv/((1+w)*(1+w))
+	if math.Abs(res.EnterpriseValue-ev) > 1e-6 {
+		t.Errorf("EV = %f, want %f", res.EnterpriseValue, ev)
+	}
+	if math.Abs(res.ValuePerShare-(ev-80)/10) > 1e-6 {
+		t.Errorf("value per share = %f, want %f", res.ValuePerShare, (ev-80)/10)
+	}
+
+	// The grid's centre is the headline value.
+	c := dcfGridSteps
+	if got := res.Sensitivity.Values[c][c]; got == nil || math.Abs(*got-res.ValuePerShare) > 1e-9 {
+		t.Errorf("grid centre = %v, want %f", got, res.ValuePerShare)
+	}
+	// Higher WACC, lower value; higher growth, higher value.
+	if *res.Sensitivity.Values[c+1][c] >= res.ValuePerShare || *res.Sensitivity.Values[c][c+1] <= res.ValuePerShare {
+		t.Errorf("grid is not monotone: %v", res.Sensitivity.Values)
+	}
+
+	// Exit multiple: TV = 12 × EBITDA (100 + 20).
+	exit := 12.0
+	res, err = ValueDCF(hist, forecast, a, DCFInputs{Method: TerminalExit, ExitMultiple: &exit}, m)
+	if err != nil {
+		t.Fatalf("ValueDCF exit: %v", err)
+	}
+	if res.TerminalValue != 1440 {
+		t.Errorf("exit TV = %f, want 1440", res.TerminalValue)
+	}
+
+	// Growth at or above WACC has no Gordon value.
+	high := 0.2
+	if _, err := ValueDCF(hist, forecast, a, DCFInputs{TerminalGrowth: &high}, m); err == nil {
+		t.Error("expected an error for terminal growth above WACC")
+	}
+}
+
+// TestMergeAssumptionsHorizon checks an override has to cover the
+// horizon: a missing driver keeps its default, a wrong-length one errors.
+func TestMergeAssumptionsHorizon(t *testing.T) {
+	defaults := DeriveDefaults(nil, 8)
+	a, err := mergeAssumptions(defaults, ModelingAssumptions{TaxRate: make([]float64, 8)})
+	if err != nil || len(a.RevenueGrowth) != 8 || len(a.TaxRate) != 8 {
+		t.Fatalf("full-length override: %v %+v", err, a)
+	}
+	if _, err := mergeAssumptions(defaults, ModelingAssumptions{RevenueGrowth: make([]float64, 5)}); err == nil {
+		t.Error("expected an error for a 5-value driver on an 8-quarter horizon")
+	}
+	if _, _, err := resolvePeriod(PeriodQuarterly, maxForecastQuarters+1); err == nil {
+		t.Error("expected an error for an over-long horizon")
+	}
+	if p, h, err := resolvePeriod("", 0); err != nil || p != PeriodAnnual || h != ForecastYears {
+		t.Errorf("resolvePeriod defaults = %q %d %v", p, h, err)
+	}
+}
+
+// TestQuarterlyModel projects a seasonal business — Q4 is twice the size
+// of the other quarters — and checks the shape carries forward, the
+// balance sheet ties, and the fiscal-year roll-up and TTM add up.
+func TestQuarterlyModel(t *testing.T) {
+	var hist []ModelingPeriod
+	for y := 2023; y <= 2024; y++ {
+		for q := 1; q <= 4; q++ {
+			rev := 100.0
+			if q == 4 {
+				rev = 200
+			}
+			if y == 2024 {
+				rev *= 1.1
+			}
+			hist = append(hist, ModelingPeriod{Year: y, Quarter: q, Historical: true,
+				Revenue: rev, COGS: rev * 0.5, OpEx: rev * 0.2, DA: 5, EBT: rev*0.3 - 5, Tax: (rev*0.3 - 5) * 0.2,
+				AR: rev * 0.3, Inventory: rev * 0.2, AP: rev * 0.1, PPE: 100, Debt: 50, Cash: 40,
+				Equity: 100, RetainedEarnings: 30, InvestingCF: -5})
+		}
+	}
+	for i := range hist {
+		p := &hist[i]
+		p.TotalAssets = p.Cash + p.AR + p.Inventory + p.PPE
+		p.TotalLE = p.AP + p.Debt + p.Equity + p.RetainedEarnings
+	}
+
+	a := DeriveDefaults(hist, ForecastQuarters)
+	if len(a.RevenueGrowth) != ForecastQuarters {
+		t.Fatalf("horizon = %d", len(a.RevenueGrowth))
+	}
+	checkClose(t, "Q1 growth", a.RevenueGrowth[0]*100, 10)
+	forecast := Project(hist, a)
+	if len(forecast) != ForecastQuarters || forecast[0].Year != 2025 || forecast[0].Quarter != 1 || forecast[7].Quarter != 4 {
+		t.Fatalf("forecast periods: %+v", forecast[0])
+	}
+	// Year-over-year growth keeps Q4 twice the other quarters.
+	checkClose(t, "2025 Q1 revenue", forecast[0].Revenue, 121)
+	checkClose(t, "2025 Q4 revenue", forecast[3].Revenue, 242)
+	checkClose(t, "2026 Q4 revenue", forecast[7].Revenue, 266.2)
+	for _, p := range forecast {
+		if math.Abs(p.BSCheck) > 1e-6 {
+			t.Errorf("%d Q%d BS check = %f", p.Year, p.Quarter, p.BSCheck)
+		}
+	}
+
+	years := AggregateFiscalYears(forecast)
+	if len(years) != 2 || years[0].Quarters != 4 {
+		t.Fatalf("fiscal years = %+v", years)
+	}
+	checkClose(t, "2025 revenue", years[0].Revenue, 605)
+	if years[1].Cash != forecast[7].Cash || years[1].BSCheck != forecast[7].BSCheck {
+		t.Error("fiscal-year balances should be the Q4 ones")
+	}
+	// A trailing part-year is left out of the roll-up.
+	if got := AggregateFiscalYears(forecast[:6]); len(got) != 1 {
+		t.Errorf("part-year roll-up = %d years, want 1", len(got))
+	}
+
+	ttm := TrailingTwelveMonths(hist)
+	if ttm == nil || ttm.Quarter != 4 {
+		t.Fatalf("ttm = %+v", ttm)
+	}
+	checkClose(t, "TTM revenue", ttm.Revenue, 550)
+
+	// A stub first year pulls the discount time in: two quarters, then a
+	// full year ending 1½ years out.
+	res, err := ValueDCF(hist, AggregateFiscalYears(forecast[2:]), a, DCFInputs{}, DCFMarket{Shares: 10, MarketCap: 1000})
+	if err != nil {
+		t.Fatalf("ValueDCF: %v", err)
+	}
+	if len(res.Years) != 2 || res.Years[0].Period != 0.5 || res.Years[1].Period != 1.5 {
+		t.Errorf("discount periods = %+v", res.Years)
+	}
+	checkClose(t, "annualised Kd", res.CostOfDebt*100, a.InterestPct[0]*400)
+}
+
+// TestCompareScenarios diffs a faster-growth scenario against the derived
+// defaults: the base's deltas are all zero and revenue moves by exactly
+// the growth difference compounded.
+func TestCompareScenarios(t *testing.T) {
+	hist := []ModelingPeriod{
+		{Year: 2023, Historical: true, Revenue: 1000, COGS: 400, OpEx: 200, DA: 50, PPE: 500, EBT: 350, Tax: 70, NetEarnings: 280, Debt: 100, Cash: 50, Equity: 300, RetainedEarnings: 100},
+		{Year: 2024, Historical: true, Revenue: 1100, COGS: 440, OpEx: 220, DA: 55, PPE: 520, EBT: 385, Tax: 77, NetEarnings: 308, Debt: 100, Cash: 60, Equity: 300, RetainedEarnings: 200},
+	}
+	bull := scenarioInputs{ID: 7, Name: "Bull"}
+	bull.Req.RevenueGrowth = []float64{0.2, 0.2, 0.2, 0.2, 0.2}
+
+	cmp := CompareScenarios(hist, []scenarioInputs{{Name: "Derived defaults"}, bull}, DCFMarket{Shares: 100, MarketCap: 10000})
+	if len(cmp.Scenarios) != 2 || len(cmp.Years) != ForecastYears || cmp.Years[0] != 2025 {
+		t.Fatalf("comparison = %+v", cmp)
+	}
+	var revenue *ScenarioLine
+	for i := range cmp.Lines {
+		for _, d := range cmp.Lines[i].Deltas[0] {
+			if d != 0 {
+				t.Errorf("%s: base delta %f, want 0", cmp.Lines[i].Field, d)
+			}
+		}
+		if cmp.Lines[i].Field == "revenue" {
+			revenue = &cmp.Lines[i]
+		}
+	}
+	if revenue == nil {
+		t.Fatal("no revenue line")
+	}
+	// Defaults hold last year's 10% growth.
+	base, fast := 1100.0, 1100.0
+	for y := 0; y < ForecastYears; y++ {
+		base *= 1.1
+		fast *= 1.2
+		checkClose(t, "revenue delta", revenue.Deltas[1][y], fast-base)
+	}
+	if v := cmp.Scenarios[1].ValueDelta; v == nil || *v <= 0 {
+		t.Errorf("faster growth should add value per share, got %v (%s)", v, cmp.Scenarios[1].DCFError)
+	}
+}
+
+// TestSimulateModel runs correlated draws on growth and margin: the
+// median path sits on the deterministic one, the fan widens with the
+// horizon, every balance sheet ties, and the same seed repeats.
+func TestSimulateModel(t *testing.T) {
+	hist := []ModelingPeriod{
+		{Year: 2023, Historical: true, Revenue: 1000, COGS: 400, OpEx: 200, DA: 50, PPE: 500, EBT: 350, Tax: 70, NetEarnings: 280, Debt: 100, Cash: 50, Equity: 300, RetainedEarnings: 100},
+		{Year: 2024, Historical: true, Revenue: 1100, COGS: 440, OpEx: 220, DA: 55, PPE: 520, EBT: 385, Tax: 77, NetEarnings: 308, Debt: 100, Cash: 60, Equity: 300, RetainedEarnings: 200},
+	}
+	for i := range hist {
+		p := &hist[i]
+		p.TotalAssets = p.Cash + p.AR + p.Inventory + p.PPE
+		p.TotalLE = p.AP + p.Debt + p.Equity + p.RetainedEarnings
+	}
+	a := DeriveDefaults(hist, ForecastYears)
+	spec := MonteCarloSpec{Sims: 4000, Seed: 7,
+		Distributions: []DriverDistribution{
+			{Driver: "revenueGrowth", Dist: DistNormal, StdDev: 0.05},
+			{Driver: "cogsPct", Dist: DistTriangular, Min: -0.05, Mode: 0, Max: 0.05},
+			{Driver: "capex", Dist: DistUniform, Min: -0.2, Max: 0.2, Relative: true},
+		},
+		Correlations: []DriverCorrelation{{A: "revenueGrowth", B: "cogsPct", Rho: -0.5}},
+	}
+	m := DCFMarket{Shares: 100, MarketCap: 10000, Price: 100}
+	res, err := SimulateModel(hist, a, DCFInputs{}, m, spec)
+	if err != nil {
+		t.Fatalf("SimulateModel: %v", err)
+	}
+	if res.MaxBSCheck > 1e-6 {
+		t.Errorf("max BS check = %g", res.MaxBSCheck)
+	}
+	base := Project(hist, a)
+	revenue := res.Fans[0]
+	if revenue.Field != "revenue" || len(revenue.Values) != ForecastYears {
+		t.Fatalf("revenue fan = %+v", revenue)
+	}
+	median := 3 // monteCarloPercentiles[3] is the 50th
+	if got, want := revenue.Values[0][median], base[0].Revenue; math.Abs(got/want-1) > 0.01 {
+		t.Errorf("year-1 median revenue = %f, want ≈ %f", got, want)
+	}
+	width := func(t int) float64 { return revenue.Values[t][6] - revenue.Values[t][0] }
+	if width(ForecastYears-1) <= width(0) {
+		t.Errorf("fan should widen: %f then %f", width(0), width(ForecastYears-1))
+	}
+	v := res.ValuePerShare
+	if v == nil || v.Valued != spec.Sims || v.Base == nil || v.ProbAbovePrice == nil {
+		t.Fatalf("value per share = %+v", v)
+	}
+	if v.Values[0] >= v.Values[6] {
+		t.Errorf("value percentiles not increasing: %v", v.Values)
+	}
+
+	again, _ := SimulateModel(hist, a, DCFInputs{}, m, spec)
+	if again.Fans[0].Values[2][median] != revenue.Values[2][median] {
+		t.Error("the same seed should repeat the run")
+	}
+
+	spec.Correlations = []DriverCorrelation{
+		{A: "revenueGrowth", B: "cogsPct", Rho: 0.9},
+		{A: "revenueGrowth", B: "capex", Rho: 0.9},
+		{A: "cogsPct", B: "capex", Rho: -0.9},
+	}
+	if _, err := SimulateModel(hist, a, DCFInputs{}, m, spec); err == nil {
+		t.Error("expected an error for inconsistent correlations")
+	}
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"stocktopus/internal/xlsx"
+)
+
+// modeling_xlsx.go lays the three-statement model out as a workbook whose
+// forecast columns are formulas, so it recalculates in Excel when a driver
+// changes. Every sheet shares one column layout — labels in A, then the
+// historical periods, then the forecast — so a formula on one sheet
+// refers to the same column on another. Historical columns are the
+// reported numbers; the Assumptions sheet holds the drivers (blue, as
+// inputs) and the period's day count.
+
+// xlsxLine is one statement row: its label, the ModelingPeriod field it
+// shows, and whether it's a total.
+type xlsxLine struct {
+	label, field string
+	total        bool
+}
+
+var (
+	xlsxIncome = []xlsxLine{
+		{"Revenue", "revenue", false},
+		{"Cost of Revenue", "cogs", false},
+		{"Gross Profit", "grossProfit", true},
+		{"Operating Expenses", "opex", false},
+		{"Depreciation & Amortization", "da", false},
+		{"Interest Expense", "interest", false},
+		{"Total Expenses", "totalExp", false},
+		{"Earnings Before Tax", "ebt", false},
+		{"Tax", "tax", false},
+		{"Net Earnings", "netEarnings", true},
+	}
+	xlsxBalance = []xlsxLine{
+		{"Cash", "cash", false},
+		{"Accounts Receivable", "ar", false},
+		{"Inventory", "inventory", false},
+		{"Property & Equipment", "ppe", false},
+		{"Total Assets", "totalAssets", true},
+		{"Accounts Payable", "ap", false},
+		{"Debt", "debt", false},
+		{"Equity Capital", "equity", false},
+		{"Retained Earnings", "retainedEarnings", false},
+		{"Total Liabilities & Equity", "totalLE", true},
+		{"Balance Check (Δ)", "bsCheck", false},
+	}
+	xlsxCashFlow = []xlsxLine{
+		{"Operating Cash Flow", "operatingCF", false},
+		{"Investing Cash Flow", "investingCF", false},
+		{"Financing Cash Flow", "financingCF", false},
+		{"Net Change in Cash", "netChangeCash", true},
+	}
+	xlsxDrivers = []xlsxLine{
+		{"Revenue Growth", "revenueGrowth", false},
+		{"COGS % of Revenue", "cogsPct", false},
+		{"OpEx % of Revenue", "opexPct", false},
+		{"D&A % of Opening PP&E", "daPct", false},
+		{"Interest % of Opening Debt", "interestPct", false},
+		{"Tax Rate", "taxRate", false},
+		{"A/R Days", "arDays", false},
+		{"Inventory Days", "inventoryDays", false},
+		{"A/P Days", "apDays", false},
+		{"Capex", "capex", false},
+		{"Debt Issuance", "debtIssuance", false},
+		{"Equity Issuance", "equityIssuance", false},
+		{"Days in Period", "days", false},
+	}
+)
+
+// xlsxFirstRow is the first statement row; rows 1–2 are the period
+// header and the actual/forecast marker.
+const xlsxFirstRow = 3
+
+// xlsxCell locates a line on a sheet.
+type xlsxCell struct {
+	sheet *xlsx.Sheet
+	row   int
+}
+
+// ModelWorkbook builds the workbook for a model.
+func ModelWorkbook(resp ModelingResponse) *xlsx.Workbook {
+	wb := xlsx.New()
+	assumptions := wb.AddSheet("Assumptions")
+	income := wb.AddSheet("Income Statement")
+	balance := wb.AddSheet("Balance Sheet")
+	cashflow := wb.AddSheet("Cash Flow")
+
+	lines := map[string]xlsxCell{}
+	for _, sh := range []struct {
+		sheet *xlsx.Sheet
+		lines []xlsxLine
+	}{{assumptions, xlsxDrivers}, {income, xlsxIncome}, {balance, xlsxBalance}, {cashflow, xlsxCashFlow}} {
+		sh.sheet.SetText(1, 1, resp.Symbol, xlsx.StyleHeader)
+		sh.sheet.SetWidth(1, 30)
+		sh.sheet.Freeze(xlsxFirstRow, 2)
+		for i, l := range sh.lines {
+			style := xlsx.StyleDefault
+			if l.total {
+				style = xlsx.StyleBold
+			}
+			sh.sheet.SetText(xlsxFirstRow+i, 1, l.label, style)
+			lines[l.field] = xlsxCell{sh.sheet, xlsxFirstRow + i}
+		}
+	}
+
+	hist, forecast := resp.Historical, resp.Forecast
+	periods := append(append([]ModelingPeriod{}, hist...), forecast...)
+	for i, p := range periods {
+		col := 2 + i
+		label, kind := fmt.Sprint(p.Year), "Actual"
+		if p.Quarter != 0 {
+			label = fmt.Sprintf("%d Q%d", p.Year, p.Quarter)
+		}
+		if !p.Historical {
+			kind = "Forecast"
+		}
+		for _, sh := range []*xlsx.Sheet{assumptions, income, balance, cashflow} {
+			sh.SetText(1, col, label, xlsx.StyleHeader)
+			sh.SetText(2, col, kind, xlsx.StyleDefault)
+			sh.SetWidth(col, 14)
+		}
+	}
+
+	// Historical columns are values.
+	for i, p := range hist {
+		col := 2 + i
+		v := reflect.ValueOf(p)
+		for _, ls := range [][]xlsxLine{xlsxIncome, xlsxBalance, xlsxCashFlow} {
+			for _, l := range ls {
+				if l.field == "bsCheck" {
+					// Reported statements carry lines the model's subset
+					// doesn't, so their gap isn't a check of anything.
+					continue
+				}
+				c := lines[l.field]
+				c.sheet.SetNumber(c.row, col, v.Field(periodFieldIndex(l.field)).Float(), moneyStyle(l.total))
+			}
+		}
+	}
+
+	// at refers to a line in a column from a cell on sheet `from`.
+	at := func(from *xlsx.Sheet, field string, col int) string {
+		c := lines[field]
+		if c.sheet == from {
+			return xlsx.Ref(c.row, col)
+		}
+		return c.sheet.Ref(c.row, col)
+	}
+	days := periodDays(ModelingPeriod{})
+	if len(hist) > 0 {
+		days = periodDays(hist[len(hist)-1])
+	}
+	lastHist := 1 + len(hist)
+	av := reflect.ValueOf(resp.Assumptions)
+	for i := range forecast {
+		col := 2 + len(hist) + i
+		prev := col - 1
+
+		for _, l := range xlsxDrivers {
+			c := lines[l.field]
+			if l.field == "days" {
+				assumptions.SetNumber(c.row, col, days, xlsx.StyleNumber)
+				continue
+			}
+			vals := av.Field(assumptionFields[l.field]).Interface().([]float64)
+			if i >= len(vals) {
+				continue
+			}
+			style := xlsx.StyleNumInput
+			if strings.HasSuffix(l.field, "Pct") || l.field == "revenueGrowth" || l.field == "taxRate" {
+				style = xlsx.StylePctInput
+			}
+			assumptions.SetNumber(c.row, col, vals[i], style)
+		}
+
+		// Revenue grows off the prior period — for quarters, the same
+		// quarter a year earlier, as Project does.
+		base := prev
+		if forecast[i].Quarter != 0 && len(hist)+i-4 >= 0 {
+			base = 2 + len(hist) + i - 4
+		}
+		drv := func(from *xlsx.Sheet, field string) string { return at(from, field, col) }
+		is, bs, cf := income, balance, cashflow
+		set := func(sh *xlsx.Sheet, field string, total bool, f string, args ...any) {
+			sh.SetFormula(lines[field].row, col, fmt.Sprintf(f, args...), moneyStyle(total))
+		}
+
+		set(is, "revenue", false, "%s*(1+%s)", at(is, "revenue", base), drv(is, "revenueGrowth"))
+		set(is, "cogs", false, "%s*%s", at(is, "revenue", col), drv(is, "cogsPct"))
+		set(is, "grossProfit", true, "%s-%s", at(is, "revenue", col), at(is, "cogs", col))
+		set(is, "opex", false, "%s*%s", at(is, "revenue", col), drv(is, "opexPct"))
+		set(is, "da", false, "%s*%s", at(is, "ppe", prev), drv(is, "daPct"))
+		set(is, "interest", false, "%s*%s", at(is, "debt", prev), drv(is, "interestPct"))
+		set(is, "totalExp", false, "%s+%s+%s", at(is, "opex", col), at(is, "da", col), at(is, "interest", col))
+		set(is, "ebt", false, "%s-%s", at(is, "grossProfit", col), at(is, "totalExp", col))
+		set(is, "tax", false, "%s*%s", at(is, "ebt", col), drv(is, "taxRate"))
+		set(is, "netEarnings", true, "%s-%s", at(is, "ebt", col), at(is, "tax", col))
+
+		rev, cogs, d := at(bs, "revenue", col), at(bs, "cogs", col), drv(bs, "days")
+		set(bs, "cash", false, "%s+%s", at(bs, "cash", prev), at(bs, "netChangeCash", col))
+		set(bs, "ar", false, "IF(%s>0,%s*%s/%s,0)", rev, rev, drv(bs, "arDays"), d)
+		set(bs, "inventory", false, "IF(%s>0,%s*%s/%s,0)", cogs, cogs, drv(bs, "inventoryDays"), d)
+		set(bs, "ppe", false, "%s+%s-%s", at(bs, "ppe", prev), drv(bs, "capex"), at(bs, "da", col))
+		set(bs, "totalAssets", true, "%s+%s+%s+%s", at(bs, "cash", col), at(bs, "ar", col), at(bs, "inventory", col), at(bs, "ppe", col))
+		set(bs, "ap", false, "IF(%s>0,%s*%s/%s,0)", cogs, cogs, drv(bs, "apDays"), d)
+		set(bs, "debt", false, "%s+%s", at(bs, "debt", prev), drv(bs, "debtIssuance"))
+		set(bs, "equity", false, "%s+%s", at(bs, "equity", prev), drv(bs, "equityIssuance"))
+		set(bs, "retainedEarnings", false, "%s+%s", at(bs, "retainedEarnings", prev), at(bs, "netEarnings", col))
+		set(bs, "totalLE", true, "%s+%s+%s+%s", at(bs, "ap", col), at(bs, "debt", col), at(bs, "equity", col), at(bs, "retainedEarnings", col))
+		// The gap less the last reported one: lines the model doesn't
+		// track are a constant plug, as in Project.
+		set(bs, "bsCheck", false, "(%s-%s)-(%s-%s)", at(bs, "totalAssets", col), at(bs, "totalLE", col),
+			absolute(at(bs, "totalAssets", lastHist)), absolute(at(bs, "totalLE", lastHist)))
+
+		nwc := func(c int) string {
+			return fmt.Sprintf("(%s+%s-%s)", at(cf, "ar", c), at(cf, "inventory", c), at(cf, "ap", c))
+		}
+		set(cf, "operatingCF", false, "%s+%s-(%s-%s)", at(cf, "netEarnings", col), at(cf, "da", col), nwc(col), nwc(prev))
+		set(cf, "investingCF", false, "-%s", drv(cf, "capex"))
+		set(cf, "financingCF", false, "%s+%s", drv(cf, "debtIssuance"), drv(cf, "equityIssuance"))
+		set(cf, "netChangeCash", true, "%s+%s+%s", at(cf, "operatingCF", col), at(cf, "investingCF", col), at(cf, "financingCF", col))
+	}
+
+	if len(forecast) > 0 && len(hist) > 0 {
+		row := lines["bsCheck"].row
+		balance.Highlight(xlsx.Ref(row, 2+len(hist))+":"+xlsx.Ref(row, 1+len(hist)+len(forecast)), -0.5, 0.5)
+	}
+	return wb
+}
+
+// absolute pins a reference's column and row: 'Sheet'!C5 → 'Sheet'!$C$5.
+func absolute(ref string) string {
+	sheet, cell := "", ref
+	if i := strings.LastIndex(ref, "!"); i >= 0 {
+		sheet, cell = ref[:i+1], ref[i+1:]
+	}
+	i := strings.IndexAny(cell, "0123456789")
+	return sheet + "$" + cell[:i] + "$" + cell[i:]
+}
+
+func moneyStyle(total bool) xlsx.Style {
+	if total {
+		return xlsx.StyleMoneyTot
+	}
+	return xlsx.StyleMoney
+}
+
+// periodFieldIndex returns ModelingPeriod's field index for a JSON name.
+func periodFieldIndex(name string) int {
+	for _, f := range modelingLineFields {
+		if f.name == name {
+			return f.index
+		}
+	}
+	panic("modeling: no period field " + name)
+}
+
+// handleModelingXLSX: GET|POST /api/security/{symbol}/modeling/xlsx takes
+// the same request as the modeling endpoint and downloads the model as a
+// workbook with live formulas.
+func (s *Server) handleModelingXLSX(w http.ResponseWriter, r *http.Request) {
+	resp, ok := s.modelingResponse(w, r)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := ModelWorkbook(resp).Write(&buf); err != nil {
+		s.logger.Error("modeling xlsx", "symbol", resp.Symbol, "error", err)
+		http.Error(w, "export failed", http.StatusInternalServerError)
+		return
+	}
+	name := strings.ToUpper(resp.Symbol) + "-model.xlsx"
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,273 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+
+	"stocktopus/internal/xlsx"
+)
+
+// TestModelWorkbookRecalculates evaluates every formula in the exported
+// workbook — as Excel would on open — and checks the forecast columns
+// land on Project's numbers, for annual and seasonal quarterly models.
+// It then edits a driver cell and checks the change flows through.
+func TestModelWorkbookRecalculates(t *testing.T) {
+	annual := []ModelingPeriod{
+		{Year: 2023, Historical: true, Revenue: 1000, COGS: 400, OpEx: 200, DA: 50, PPE: 500, EBT: 350, Tax: 70, NetEarnings: 280, AR: 80, Inventory: 40, AP: 30, Debt: 100, Cash: 50, Equity: 300, RetainedEarnings: 100},
+		{Year: 2024, Historical: true, Revenue: 1100, COGS: 440, OpEx: 220, DA: 55, PPE: 520, EBT: 385, Tax: 77, NetEarnings: 308, AR: 90, Inventory: 45, AP: 35, Debt: 120, Cash: 60, Equity: 300, RetainedEarnings: 200},
+	}
+	var quarterly []ModelingPeriod
+	for q := 1; q <= 6; q++ {
+		rev := 100.0 + float64(q%4)*20
+		quarterly = append(quarterly, ModelingPeriod{Year: 2023 + (q-1)/4, Quarter: (q-1)%4 + 1, Historical: true,
+			Revenue: rev, COGS: rev * 0.5, OpEx: rev * 0.2, DA: 5, EBT: rev*0.3 - 5, Tax: (rev*0.3 - 5) * 0.2,
+			AR: rev * 0.3, Inventory: rev * 0.2, AP: rev * 0.1, PPE: 100, Debt: 50, Cash: 40, Equity: 100, RetainedEarnings: 30, InvestingCF: -5})
+	}
+	for name, hist := range map[string][]ModelingPeriod{"annual": annual, "quarterly": quarterly} {
+		for i := range hist {
+			p := &hist[i]
+			p.TotalAssets = p.Cash + p.AR + p.Inventory + p.PPE
+			p.TotalLE = p.AP + p.Debt + p.Equity + p.RetainedEarnings + 7 // an untracked line
+		}
+		a := DeriveDefaults(hist, 6)
+		a.DebtIssuance[1] = 25
+		resp := ModelingResponse{Symbol: "TEST", Historical: hist, Forecast: Project(hist, a), Assumptions: a}
+		wb := ModelWorkbook(resp)
+		ev := newXLSXEval(t, wb)
+
+		check := func(forecast []ModelingPeriod) {
+			for _, sheet := range []struct {
+				name  string
+				lines []xlsxLine
+			}{{"Income Statement", xlsxIncome}, {"Balance Sheet", xlsxBalance}, {"Cash Flow", xlsxCashFlow}} {
+				for r, l := range sheet.lines {
+					for i, p := range forecast {
+						got := ev.cell(sheet.name, xlsxFirstRow+r, 2+len(hist)+i)
+						want := reflectFloat(p, l.field)
+						if math.Abs(got-want) > 1e-6*math.Max(1, math.Abs(want)) {
+							t.Errorf("%s %s %s period %d = %f, want %f", name, sheet.name, l.field, i, got, want)
+						}
+					}
+				}
+			}
+		}
+		check(resp.Forecast)
+		var buf bytes.Buffer
+		if err := wb.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		z, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		for _, f := range z.File {
+			if f.Name != "xl/worksheets/sheet3.xml" {
+				continue
+			}
+			rc, _ := f.Open()
+			raw, _ := io.ReadAll(rc)
+			rc.Close()
+			row := xlsxFirstRow + len(xlsxBalance) - 1
+			want := fmt.Sprintf(`<conditionalFormatting sqref="%s:%s">`, xlsx.Ref(row, 2+len(hist)), xlsx.Ref(row, 1+len(hist)+len(resp.Forecast)))
+			if !strings.Contains(string(raw), want) {
+				t.Errorf("%s: balance check row should be highlighted: want %s", name, want)
+			}
+		}
+
+		// Edit year-1 growth in the workbook and the model follows.
+		a.RevenueGrowth[0] += 0.1
+		wb.Sheet("Assumptions").SetNumber(xlsxFirstRow, 2+len(hist), a.RevenueGrowth[0], xlsx.StylePctInput)
+		ev = newXLSXEval(t, wb)
+		check(Project(hist, a))
+	}
+}
+
+func reflectFloat(p ModelingPeriod, field string) float64 {
+	return reflect.ValueOf(p).Field(periodFieldIndex(field)).Float()
+}
+
+// xlsxEval is just enough of a spreadsheet engine for the model's
+// formulas: + - * /, comparisons, parentheses, IF and (sheet-qualified,
+// optionally absolute) cell references.
+type xlsxEval struct {
+	t     *testing.T
+	wb    *xlsx.Workbook
+	memo  map[string]float64
+	stack map[string]bool
+}
+
+func newXLSXEval(t *testing.T, wb *xlsx.Workbook) *xlsxEval {
+	return &xlsxEval{t: t, wb: wb, memo: map[string]float64{}, stack: map[string]bool{}}
+}
+
+func (e *xlsxEval) cell(sheet string, row, col int) float64 {
+	key := sheet + "!" + xlsx.Ref(row, col)
+	if v, ok := e.memo[key]; ok {
+		return v
+	}
+	if e.stack[key] {
+		e.t.Fatalf("circular reference at %s", key)
+	}
+	sh := e.wb.Sheet(sheet)
+	if sh == nil {
+		e.t.Fatalf("no sheet %q", sheet)
+	}
+	c, _ := sh.Get(row, col)
+	var v float64
+	switch {
+	case c.Formula != "":
+		e.stack[key] = true
+		p := &formulaParser{e: e, sheet: sheet, src: c.Formula}
+		v = p.expr()
+		if p.pos != len(p.src) {
+			e.t.Fatalf("%s: trailing input in %q", key, c.Formula)
+		}
+		delete(e.stack, key)
+	case c.Number != nil:
+		v = *c.Number
+	}
+	e.memo[key] = v
+	return v
+}
+
+type formulaParser struct {
+	e     *xlsxEval
+	sheet string
+	src   string
+	pos   int
+}
+
+func (p *formulaParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *formulaParser) expect(c byte) {
+	if p.peek() != c {
+		p.e.t.Fatalf("formula %q: want %q at %d", p.src, c, p.pos)
+	}
+	p.pos++
+}
+
+func (p *formulaParser) expr() float64 {
+	v := p.sum()
+	switch p.peek() {
+	case '>':
+		p.pos++
+		if p.sum() < v {
+			return 1
+		}
+		return 0
+	case '<':
+		p.pos++
+		if v < p.sum() {
+			return 1
+		}
+		return 0
+	}
+	return v
+}
+
+func (p *formulaParser) sum() float64 {
+	v := p.product()
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			v += p.product()
+		case '-':
+			p.pos++
+			v -= p.product()
+		default:
+			return v
+		}
+	}
+}
+
+func (p *formulaParser) product() float64 {
+	v := p.unary()
+	for {
+		switch p.peek() {
+		case '*':
+			p.pos++
+			v *= p.unary()
+		case '/':
+			p.pos++
+			v /= p.unary()
+		default:
+			return v
+		}
+	}
+}
+
+func (p *formulaParser) unary() float64 {
+	switch c := p.peek(); {
+	case c == '-':
+		p.pos++
+		return -p.unary()
+	case c == '(':
+		p.pos++
+		v := p.expr()
+		p.expect(')')
+		return v
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		v, _ := strconv.ParseFloat(p.src[start:p.pos], 64)
+		return v
+	case strings.HasPrefix(p.src[p.pos:], "IF("):
+		p.pos += 3
+		cond := p.expr()
+		p.expect(',')
+		a := p.expr()
+		p.expect(',')
+		b := p.expr()
+		p.expect(')')
+		if cond != 0 {
+			return a
+		}
+		return b
+	}
+	return p.ref()
+}
+
+func (p *formulaParser) ref() float64 {
+	sheet := p.sheet
+	if p.peek() == '\'' {
+		end := strings.Index(p.src[p.pos+1:], "'!")
+		sheet = p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 3
+	} else if i := strings.IndexByte(p.src[p.pos:], '!'); i > 0 && !strings.ContainsAny(p.src[p.pos:p.pos+i], "+-*/(),<>") {
+		sheet = p.src[p.pos : p.pos+i]
+		p.pos += i + 1
+	}
+	if p.peek() == '$' {
+		p.pos++
+	}
+	col := 0
+	for p.pos < len(p.src) && unicode.IsUpper(rune(p.src[p.pos])) {
+		col = col*26 + int(p.src[p.pos]-'A'+1)
+		p.pos++
+	}
+	if p.peek() == '$' {
+		p.pos++
+	}
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	row, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil || col == 0 {
+		p.e.t.Fatalf("formula %q: bad reference at %d", p.src, start)
+	}
+	return p.e.cell(sheet, row, col)
+}
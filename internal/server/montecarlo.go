@@ -0,0 +1,464 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// montecarlo.go runs the three-statement model many times over drawn
+// driver values. Each simulation draws one value per uncertain driver and
+// shifts that driver's whole path by it, so the point assumptions stay
+// the centre of the cloud and a quarterly model keeps its seasonal shape.
+// Draws are tied together with a Gaussian copula: correlated standard
+// normals (via the Cholesky factor of the correlation matrix) are mapped
+// to uniforms and then through each driver's own inverse CDF.
+
+// Distribution kinds.
+const (
+	DistNormal     = "normal"
+	DistTriangular = "triangular"
+	DistUniform    = "uniform"
+)
+
+const (
+	defaultSims = 2000
+	maxSims     = 20000
+)
+
+// monteCarloPercentiles are the fan chart's bands.
+var monteCarloPercentiles = []float64{5, 10, 25, 50, 75, 90, 95}
+
+// monteCarloFanFields are the statement lines the fan charts follow.
+var monteCarloFanFields = []string{"revenue", "netEarnings", "cash"}
+
+// DriverDistribution is the uncertainty on one driver. The draw is a
+// shift added to every forecast period of the driver — or, when
+// Relative, a fractional change (0.1 = 10% more) — so a normal with
+// StdDev 0.02 on revenueGrowth means ±2pp around the assumed path.
+//
+//	normal:     Mean (default 0), StdDev
+//	triangular: Min, Mode, Max
+//	uniform:    Min, Max
+type DriverDistribution struct {
+	Driver   string  `json:"driver"` // ModelingAssumptions JSON name, e.g. "revenueGrowth"
+	Dist     string  `json:"dist"`
+	Mean     float64 `json:"mean,omitempty"`
+	StdDev   float64 `json:"stdDev,omitempty"`
+	Min      float64 `json:"min,omitempty"`
+	Mode     float64 `json:"mode,omitempty"`
+	Max      float64 `json:"max,omitempty"`
+	Relative bool    `json:"relative,omitempty"`
+}
+
+// DriverCorrelation is the correlation between two drivers' draws.
+type DriverCorrelation struct {
+	A   string  `json:"a"`
+	B   string  `json:"b"`
+	Rho float64 `json:"rho"`
+}
+
+// MonteCarloSpec is what to simulate. Seed fixes the draws so a run can
+// be repeated; 0 is a valid seed.
+type MonteCarloSpec struct {
+	Sims          int                  `json:"sims"`
+	Seed          int64                `json:"seed"`
+	Distributions []DriverDistribution `json:"distributions"`
+	Correlations  []DriverCorrelation  `json:"correlations,omitempty"`
+}
+
+// MonteCarloFan is one statement line's percentiles per forecast period:
+// Values[t][k] is monteCarloPercentiles[k] in period t.
+type MonteCarloFan struct {
+	Field  string      `json:"field"`
+	Values [][]float64 `json:"values"`
+	Mean   []float64   `json:"mean"`
+}
+
+// MonteCarloValue is the distribution of DCF value per share over the
+// simulations that could be valued.
+type MonteCarloValue struct {
+	Values []float64 `json:"values"` // at monteCarloPercentiles
+	Mean   float64   `json:"mean"`
+	Valued int       `json:"valued"`
+	Base   *float64  `json:"base,omitempty"` // the point assumptions' value
+	Price  float64   `json:"price,omitempty"`
+	// ProbAbovePrice is the share of valued simulations worth more than
+	// the current price.
+	ProbAbovePrice *float64 `json:"probAbovePrice,omitempty"`
+}
+
+// MonteCarloResult is the simulation endpoint's response. Quarters is
+// set alongside Years for quarterly models. MaxBSCheck is the largest
+// balance-sheet gap seen across every simulated period.
+type MonteCarloResult struct {
+	Symbol        string           `json:"symbol"`
+	Period        string           `json:"period"`
+	Sims          int              `json:"sims"`
+	Seed          int64            `json:"seed"`
+	Percentiles   []float64        `json:"percentiles"`
+	Years         []int            `json:"years"`
+	Quarters      []int            `json:"quarters,omitempty"`
+	Fans          []MonteCarloFan  `json:"fans"`
+	ValuePerShare *MonteCarloValue `json:"valuePerShare,omitempty"`
+	MaxBSCheck    float64          `json:"maxBsCheck"`
+}
+
+// monteCarloRequest is the simulation POST body: the modeling request
+// (driver overrides, period, horizon, DCF inputs) plus the spec.
+type monteCarloRequest struct {
+	modelingRequest
+	MonteCarloSpec
+}
+
+// assumptionFields maps ModelingAssumptions' JSON names to field indexes.
+var assumptionFields = func() map[string]int {
+	out := map[string]int{}
+	t := reflect.TypeOf(ModelingAssumptions{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		out[name] = i
+	}
+	return out
+}()
+
+// driverFloor is the lowest value a simulated driver can take: revenue
+// can't fall by more than all of it, financing can go either way, and
+// every rate, day count and capex is non-negative.
+func driverFloor(name string) float64 {
+	switch name {
+	case "revenueGrowth":
+		return -1
+	case "debtIssuance", "equityIssuance":
+		return math.Inf(-1)
+	}
+	return 0
+}
+
+// validate fills in the default simulation count and checks the spec,
+// returning the Cholesky factor of the draws' correlation matrix.
+func (spec *MonteCarloSpec) validate() ([][]float64, error) {
+	if spec.Sims == 0 {
+		spec.Sims = defaultSims
+	}
+	if spec.Sims < 1 || spec.Sims > maxSims {
+		return nil, fmt.Errorf("sims must be 1-%d", maxSims)
+	}
+	if len(spec.Distributions) == 0 {
+		return nil, fmt.Errorf("at least one driver distribution is required")
+	}
+	index := map[string]int{}
+	for i, d := range spec.Distributions {
+		if _, ok := assumptionFields[d.Driver]; !ok {
+			return nil, fmt.Errorf("unknown driver %q", d.Driver)
+		}
+		if _, dup := index[d.Driver]; dup {
+			return nil, fmt.Errorf("driver %q has two distributions", d.Driver)
+		}
+		index[d.Driver] = i
+		switch d.Dist {
+		case DistNormal:
+			if d.StdDev < 0 {
+				return nil, fmt.Errorf("%s: stdDev must not be negative", d.Driver)
+			}
+		case DistTriangular:
+			if d.Min > d.Mode || d.Mode > d.Max {
+				return nil, fmt.Errorf("%s: want min ≤ mode ≤ max", d.Driver)
+			}
+		case DistUniform:
+			if d.Min > d.Max {
+				return nil, fmt.Errorf("%s: want min ≤ max", d.Driver)
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown distribution %q (normal, triangular, uniform)", d.Driver, d.Dist)
+		}
+	}
+
+	n := len(spec.Distributions)
+	corr := make([][]float64, n)
+	for i := range corr {
+		corr[i] = make([]float64, n)
+		corr[i][i] = 1
+	}
+	for _, c := range spec.Correlations {
+		i, ok := index[c.A]
+		j, ok2 := index[c.B]
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("correlation %s/%s: both drivers need a distribution", c.A, c.B)
+		}
+		if i == j || c.Rho < -1 || c.Rho > 1 {
+			return nil, fmt.Errorf("correlation %s/%s: want two drivers and -1 ≤ rho ≤ 1", c.A, c.B)
+		}
+		corr[i][j], corr[j][i] = c.Rho, c.Rho
+	}
+	return cholesky(corr)
+}
+
+// cholesky returns the lower-triangular L with L·Lᵀ = m, or an error when
+// m isn't positive definite — a set of correlations no joint distribution
+// can have.
+func cholesky(m [][]float64) ([][]float64, error) {
+	n := len(m)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+		for j := 0; j <= i; j++ {
+			sum := m[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 1e-12 {
+					return nil, fmt.Errorf("the correlations are inconsistent (the matrix isn't positive definite)")
+				}
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l, nil
+}
+
+// quantile is the distribution's inverse CDF at u ∈ (0,1).
+func (d DriverDistribution) quantile(u float64) float64 {
+	switch d.Dist {
+	case DistNormal:
+		return d.Mean + d.StdDev*math.Sqrt2*math.Erfinv(2*u-1)
+	case DistUniform:
+		return d.Min + (d.Max-d.Min)*u
+	case DistTriangular:
+		span := d.Max - d.Min
+		if span == 0 {
+			return d.Min
+		}
+		if c := (d.Mode - d.Min) / span; u < c {
+			return d.Min + math.Sqrt(u*span*(d.Mode-d.Min))
+		}
+		return d.Max - math.Sqrt((1-u)*span*(d.Max-d.Mode))
+	}
+	return 0
+}
+
+// SimulateModel projects the point assumptions `spec.Sims` times with the
+// specified drivers drawn, and values each run when the market inputs
+// allow. hist and a are as for Project; in and m as for ValueDCF. It
+// errors on a bad spec, or if any simulated balance sheet fails to tie.
+func SimulateModel(hist []ModelingPeriod, a ModelingAssumptions, in DCFInputs, m DCFMarket, spec MonteCarloSpec) (*MonteCarloResult, error) {
+	chol, err := spec.validate()
+	if err != nil {
+		return nil, err
+	}
+	if len(hist) == 0 || len(a.RevenueGrowth) == 0 {
+		return nil, fmt.Errorf("no history to simulate from")
+	}
+	quarterly := hist[len(hist)-1].Quarter != 0
+	res := &MonteCarloResult{Sims: spec.Sims, Seed: spec.Seed, Percentiles: monteCarloPercentiles, Years: []int{}}
+
+	value := func(forecast []ModelingPeriod, a ModelingAssumptions) (float64, bool) {
+		if quarterly {
+			forecast = AggregateFiscalYears(forecast)
+		}
+		if len(forecast) == 0 {
+			return 0, false
+		}
+		d, err := ValueDCF(hist, forecast, a, in, m)
+		if err != nil {
+			return 0, false
+		}
+		return d.ValuePerShare, true
+	}
+
+	base := Project(hist, a)
+	for _, p := range base {
+		res.Years = append(res.Years, p.Year)
+		if quarterly {
+			res.Quarters = append(res.Quarters, p.Quarter)
+		}
+	}
+
+	periods := len(base)
+	samples := make([][][]float64, len(monteCarloFanFields)) // [field][period][sim]
+	for f := range samples {
+		samples[f] = make([][]float64, periods)
+		for t := range samples[f] {
+			samples[f][t] = make([]float64, spec.Sims)
+		}
+	}
+	fanIndex := make([]int, len(monteCarloFanFields))
+	pt := reflect.TypeOf(ModelingPeriod{})
+	for f, name := range monteCarloFanFields {
+		for i := 0; i < pt.NumField(); i++ {
+			if tag, _, _ := strings.Cut(pt.Field(i).Tag.Get("json"), ","); tag == name {
+				fanIndex[f] = i
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(spec.Seed))
+	k := len(spec.Distributions)
+	z := make([]float64, k)
+	var values []float64
+	for s := 0; s < spec.Sims; s++ {
+		for i := range z {
+			z[i] = rng.NormFloat64()
+		}
+		sim := cloneAssumptions(a)
+		sv := reflect.ValueOf(&sim).Elem()
+		for i, d := range spec.Distributions {
+			var corr float64
+			for j := 0; j <= i; j++ {
+				corr += chol[i][j] * z[j]
+			}
+			u := 0.5 * math.Erfc(-corr/math.Sqrt2)
+			u = math.Min(math.Max(u, 1e-12), 1-1e-12)
+			x := d.quantile(u)
+			path := sv.Field(assumptionFields[d.Driver]).Interface().([]float64)
+			floor := driverFloor(d.Driver)
+			for t := range path {
+				if d.Relative {
+					path[t] *= 1 + x
+				} else {
+					path[t] += x
+				}
+				path[t] = math.Max(path[t], floor)
+			}
+		}
+
+		forecast := Project(hist, sim)
+		for t, p := range forecast {
+			scale := math.Max(1, math.Abs(p.TotalAssets))
+			if gap := math.Abs(p.BSCheck); gap > res.MaxBSCheck {
+				res.MaxBSCheck = gap
+			}
+			if math.Abs(p.BSCheck) > 1e-6*scale {
+				return nil, fmt.Errorf("simulation %d: %d balance sheet is off by %.2f", s, p.Year, p.BSCheck)
+			}
+			pv := reflect.ValueOf(p)
+			for f := range monteCarloFanFields {
+				samples[f][t][s] = pv.Field(fanIndex[f]).Float()
+			}
+		}
+		if v, ok := value(forecast, sim); ok {
+			values = append(values, v)
+		}
+	}
+
+	for f, name := range monteCarloFanFields {
+		fan := MonteCarloFan{Field: name, Values: make([][]float64, periods), Mean: make([]float64, periods)}
+		for t := range samples[f] {
+			fan.Values[t], fan.Mean[t] = percentiles(samples[f][t])
+		}
+		res.Fans = append(res.Fans, fan)
+	}
+	if len(values) > 0 {
+		v := &MonteCarloValue{Valued: len(values), Price: m.Price}
+		if b, ok := value(base, a); ok {
+			v.Base = &b
+		}
+		if m.Price > 0 {
+			var above int
+			for _, x := range values {
+				if x > m.Price {
+					above++
+				}
+			}
+			p := float64(above) / float64(len(values))
+			v.ProbAbovePrice = &p
+		}
+		v.Values, v.Mean = percentiles(values)
+		res.ValuePerShare = v
+	}
+	return res, nil
+}
+
+// percentiles sorts xs in place and returns it at monteCarloPercentiles,
+// interpolating between order statistics, and its mean.
+func percentiles(xs []float64) ([]float64, float64) {
+	sort.Float64s(xs)
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	out := make([]float64, len(monteCarloPercentiles))
+	for i, p := range monteCarloPercentiles {
+		out[i] = quantile(xs, p/100)
+	}
+	return out, sum / float64(len(xs))
+}
+
+// quantile is the linearly interpolated p-quantile (0 ≤ p ≤ 1) of sorted
+// xs. The comps table's quartiles use it too.
+func quantile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(pos)), int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+// cloneAssumptions deep-copies the driver slices so a simulation can
+// shift them in place.
+func cloneAssumptions(a ModelingAssumptions) ModelingAssumptions {
+	out := a
+	v := reflect.ValueOf(&out).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		src := v.Field(i).Interface().([]float64)
+		v.Field(i).Set(reflect.ValueOf(append([]float64(nil), src...)))
+	}
+	return out
+}
+
+// handleModelingMonteCarlo: POST /api/security/{symbol}/modeling/montecarlo
+// runs the model under uncertain drivers:
+//
+//	{"revenueGrowth": [...], "period": "annual", "dcf": {...},
+//	 "sims": 5000, "seed": 1,
+//	 "distributions": [{"driver": "revenueGrowth", "dist": "normal", "stdDev": 0.03},
+//	                   {"driver": "cogsPct", "dist": "triangular", "min": -0.02, "mode": 0, "max": 0.04}],
+//	 "correlations": [{"a": "revenueGrowth", "b": "cogsPct", "rho": -0.4}]}
+//
+// The point assumptions are the same as the modeling endpoint's.
+func (s *Server) handleModelingMonteCarlo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	symbol := r.PathValue("symbol")
+
+	var req monteCarloRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	fail := func(err error) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+	period, horizon, err := resolvePeriod(req.Period, req.Horizon)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if _, err := req.MonteCarloSpec.validate(); err != nil {
+		fail(err)
+		return
+	}
+
+	hist, income, ok := s.loadModelingHistory(w, r, symbol, period)
+	if !ok {
+		return
+	}
+	a, err := mergeAssumptions(DeriveDefaults(hist, horizon), req.ModelingAssumptions)
+	if err != nil {
+		fail(err)
+		return
+	}
+	res, err := SimulateModel(hist, a, req.DCF, s.dcfMarket(r, symbol, income), req.MonteCarloSpec)
+	if err != nil {
+		fail(err)
+		return
+	}
+	res.Symbol, res.Period = symbol, period
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+
+	"stocktopus/internal/model"
+	"stocktopus/internal/provider"
+)
+
+// SetQuoteProvider attaches the wrapped quote provider (validation and,
+// when configured, consensus) so watchlist and screener quotes are checked
+// and flagged the same way as the poller's.
+func (s *Server) SetQuoteProvider(p provider.StockProvider) {
+	s.quotes = p
+}
+
+// watchlistQuote is a validated quote in the shape the watchlist view
+// reads: FMP's batch-quote field names, changePercentage in percent.
+type watchlistQuote struct {
+	Symbol           string            `json:"symbol"`
+	Price            float64           `json:"price"`
+	Change           float64           `json:"change"`
+	ChangePercentage float64           `json:"changePercentage"`
+	Volume           int64             `json:"volume"`
+	Flags            []model.QuoteFlag `json:"flags,omitempty"`
+}
+
+// validatedQuotes fetches symbols through the quote provider. Rejected
+// quotes are dropped; an error only matters when nothing came back.
+func (s *Server) validatedQuotes(ctx context.Context, symbols []string) ([]watchlistQuote, error) {
+	quotes, err := s.quotes.GetQuotes(ctx, symbols)
+	out := make([]watchlistQuote, 0, len(quotes))
+	for _, q := range quotes {
+		if q == nil {
+			continue
+		}
+		out = append(out, watchlistQuote{
+			Symbol:           q.Symbol,
+			Price:            q.Price,
+			Change:           q.Change,
+			ChangePercentage: q.ChangePercent * 100,
+			Volume:           q.Volume,
+			Flags:            q.Flags,
+		})
+	}
+	if len(out) == 0 && err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// flagScreenerResults copies the quote provider's flags onto screener
+// rows. The rows' prices come from the raw batch quote, which carries the
+// open and previous close the derived columns need.
+func (s *Server) flagScreenerResults(ctx context.Context, results []screenerResult) {
+	if s.quotes == nil || len(results) == 0 {
+		return
+	}
+	symbols := make([]string, len(results))
+	for i, r := range results {
+		symbols[i] = r.Symbol
+	}
+	quotes, err := s.validatedQuotes(ctx, symbols)
+	if err != nil {
+		s.logger.Warn("screener: quote validation failed", "error", err)
+		return
+	}
+	flags := make(map[string][]model.QuoteFlag, len(quotes))
+	for _, q := range quotes {
+		flags[q.Symbol] = q.Flags
+	}
+	for i := range results {
+		results[i].Flags = flags[results[i].Symbol]
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stocktopus/internal/model"
+	"stocktopus/internal/provider"
+	"stocktopus/internal/store"
+)
+
+// fixedQuotes serves canned quotes by symbol.
+type fixedQuotes map[string]*model.Quote
+
+func (f fixedQuotes) GetQuote(ctx context.Context, symbol string) (*model.Quote, error) {
+	return f[symbol], nil
+}
+
+func (f fixedQuotes) GetQuotes(ctx context.Context, symbols []string) ([]*model.Quote, error) {
+	out := make([]*model.Quote, len(symbols))
+	for i, s := range symbols {
+		if q := f[s]; q != nil {
+			c := *q
+			out[i] = &c
+		}
+	}
+	return out, nil
+}
+
+func (f fixedQuotes) Name() string                          { return "fixed" }
+func (f fixedQuotes) HealthCheck(ctx context.Context) error { return nil }
+
+func TestWatchlistQuotesValidated(t *testing.T) {
+	srv, mux := testServer(t)
+	st, err := store.New(filepath.Join(t.TempDir(), "quotes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	srv.store = st
+	wl, err := st.CreateWatchlist("Core")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sym := range []string{"AAPL", "MSFT", "BAD"} {
+		if err := st.AddToWatchlist(wl.ID, sym); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	srv.SetQuoteProvider(provider.NewValidatingProvider(fixedQuotes{
+		"AAPL": {Symbol: "AAPL", Price: 200, Change: 2, ChangePercent: 0.01, Volume: 10, Timestamp: now},
+		"MSFT": {Symbol: "MSFT", Price: 400, Timestamp: now.Add(-time.Hour)},
+		"BAD":  {Symbol: "BAD", Price: 0, Timestamp: now},
+	}, provider.DefaultValidationConfig()))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/watchlists/quotes", nil))
+	var got []watchlistQuote
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("%v: %s", err, w.Body)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d quotes, want the invalid one dropped: %+v", len(got), got)
+	}
+	for _, q := range got {
+		switch q.Symbol {
+		case "AAPL":
+			if len(q.Flags) != 0 || q.ChangePercentage != 1 {
+				t.Errorf("AAPL = %+v", q)
+			}
+		case "MSFT":
+			if len(q.Flags) != 1 || q.Flags[0] != model.FlagStale {
+				t.Errorf("MSFT flags = %v, want stale", q.Flags)
+			}
+		}
+	}
+
+	results := []screenerResult{{Symbol: "AAPL"}, {Symbol: "MSFT"}}
+	srv.flagScreenerResults(context.Background(), results)
+	if len(results[0].Flags) != 0 || len(results[1].Flags) != 1 {
+		t.Errorf("screener flags = %v / %v", results[0].Flags, results[1].Flags)
+	}
+}
@@ -0,0 +1,450 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"stocktopus/internal/store"
+)
+
+// scenarios.go persists named modeling scenarios — a bull, base or bear
+// case's driver overrides, DCF inputs and notes — and projects several
+// side by side. A scenario's assumptions are exactly the modeling POST
+// body, so loading one into the Assumptions tab is a plain POST.
+
+// scenarioRequest is the body of a create or update.
+type scenarioRequest struct {
+	Name        string          `json:"name"`
+	Notes       string          `json:"notes"`
+	Assumptions json.RawMessage `json:"assumptions"`
+	SketchID    *int64          `json:"sketchId"`
+}
+
+// cloneRequest names the copy.
+type cloneRequest struct {
+	Name string `json:"name"`
+}
+
+// ScenarioProjection is one scenario's forecast in a comparison. ID 0 is
+// the derived defaults.
+type ScenarioProjection struct {
+	ID          int64               `json:"id"`
+	Name        string              `json:"name"`
+	Notes       string              `json:"notes,omitempty"`
+	Assumptions ModelingAssumptions `json:"assumptions"`
+	Forecast    []ModelingPeriod    `json:"forecast"`
+	DCF         *DCFResult          `json:"dcf,omitempty"`
+	DCFError    string              `json:"dcfError,omitempty"`
+	Error       string              `json:"error,omitempty"` // why there's no forecast
+	// ValueDelta is value per share less the base scenario's, when both
+	// were valued.
+	ValueDelta *float64 `json:"valueDelta,omitempty"`
+}
+
+// ScenarioLine is one statement line across the compared scenarios.
+// Values[i][y] is scenario i's value in forecast year y, and Deltas[i][y]
+// that less the base (first) scenario's.
+type ScenarioLine struct {
+	Field  string      `json:"field"`
+	Values [][]float64 `json:"values"`
+	Deltas [][]float64 `json:"deltas"`
+}
+
+// ScenarioComparison is the compare endpoint's response. Quarters is set
+// alongside Years when the scenarios are quarterly.
+type ScenarioComparison struct {
+	Symbol    string               `json:"symbol"`
+	Period    string               `json:"period"`
+	Years     []int                `json:"years"`
+	Quarters  []int                `json:"quarters,omitempty"`
+	Scenarios []ScenarioProjection `json:"scenarios"`
+	Lines     []ScenarioLine       `json:"lines"`
+}
+
+// scenarioInputs is a scenario ready to project.
+type scenarioInputs struct {
+	ID    int64
+	Name  string
+	Notes string
+	Req   modelingRequest
+}
+
+// modelingLineFields are ModelingPeriod's numeric lines by JSON name, in
+// declaration order — the statement order the forecast table shows.
+var modelingLineFields = func() []struct {
+	name  string
+	index int
+} {
+	var out []struct {
+		name  string
+		index int
+	}
+	t := reflect.TypeOf(ModelingPeriod{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() != reflect.Float64 {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		out = append(out, struct {
+			name  string
+			index int
+		}{name, i})
+	}
+	return out
+}()
+
+// CompareScenarios projects each scenario from the same history and
+// diffs every statement line against the first. Values per share are
+// diffed too when the market inputs allow a valuation. Each scenario
+// keeps its own horizon; the history fixes the period, so a scenario
+// saved for the other period, or whose drivers don't fit its horizon,
+// comes back with Error set and no forecast.
+func CompareScenarios(hist []ModelingPeriod, scenarios []scenarioInputs, m DCFMarket) ScenarioComparison {
+	cmp := ScenarioComparison{Years: []int{}, Scenarios: []ScenarioProjection{}, Lines: []ScenarioLine{}}
+	for _, sc := range scenarios {
+		p := ScenarioProjection{ID: sc.ID, Name: sc.Name, Notes: sc.Notes, Forecast: []ModelingPeriod{}}
+		_, horizon, err := resolvePeriod(sc.Req.Period, sc.Req.Horizon)
+		var run modelRun
+		if err == nil {
+			run, err = runModel(hist, sc.Req, horizon, m)
+		}
+		if err != nil {
+			p.Error = err.Error()
+		} else {
+			p.Assumptions, p.Forecast, p.DCF, p.DCFError = run.Assumptions, run.Forecast, run.DCF, run.DCFError
+		}
+		cmp.Scenarios = append(cmp.Scenarios, p)
+	}
+	if len(cmp.Scenarios) == 0 {
+		return cmp
+	}
+
+	base := cmp.Scenarios[0]
+	for _, p := range base.Forecast {
+		cmp.Years = append(cmp.Years, p.Year)
+		if p.Quarter != 0 {
+			cmp.Quarters = append(cmp.Quarters, p.Quarter)
+		}
+	}
+	for i := range cmp.Scenarios {
+		if cmp.Scenarios[i].DCF != nil && base.DCF != nil {
+			d := cmp.Scenarios[i].DCF.ValuePerShare - base.DCF.ValuePerShare
+			cmp.Scenarios[i].ValueDelta = &d
+		}
+	}
+	for _, f := range modelingLineFields {
+		line := ScenarioLine{Field: f.name}
+		for _, sc := range cmp.Scenarios {
+			vals := make([]float64, len(sc.Forecast))
+			deltas := make([]float64, len(sc.Forecast))
+			for y, p := range sc.Forecast {
+				vals[y] = reflect.ValueOf(p).Field(f.index).Float()
+				if y < len(base.Forecast) {
+					deltas[y] = vals[y] - reflect.ValueOf(base.Forecast[y]).Field(f.index).Float()
+				}
+			}
+			line.Values = append(line.Values, vals)
+			line.Deltas = append(line.Deltas, deltas)
+		}
+		cmp.Lines = append(cmp.Lines, line)
+	}
+	return cmp
+}
+
+// scenario validates a create/update body into the scenario to store, or
+// writes a 400 and returns false.
+func (s *Server) scenario(w http.ResponseWriter, symbol string, req scenarioRequest) (store.ModelingScenario, bool) {
+	sc := store.ModelingScenario{
+		UserID:      globalOwnerID,
+		Symbol:      strings.ToUpper(symbol),
+		Name:        strings.TrimSpace(req.Name),
+		Notes:       strings.TrimSpace(req.Notes),
+		Assumptions: req.Assumptions,
+		SketchID:    req.SketchID,
+	}
+	var err error
+	if sc.Name == "" {
+		err = fmt.Errorf("name is required")
+	}
+	if err == nil && len(sc.Assumptions) > 0 {
+		var body modelingRequest
+		if jerr := json.Unmarshal(sc.Assumptions, &body); jerr != nil {
+			err = fmt.Errorf("assumptions: %v", jerr)
+		} else if m := body.DCF.Method; m != "" && m != TerminalGordon && m != TerminalExit {
+			err = fmt.Errorf("unknown terminal value method %q (gordon, exit)", m)
+		} else if _, horizon, perr := resolvePeriod(body.Period, body.Horizon); perr != nil {
+			err = perr
+		} else if _, merr := mergeAssumptions(DeriveDefaults(nil, horizon), body.ModelingAssumptions); merr != nil {
+			err = fmt.Errorf("assumptions: %v", merr)
+		}
+	}
+	if err == nil && sc.SketchID != nil {
+		if sk, serr := s.store.GetSketch(*sc.SketchID); serr != nil || sk == nil {
+			err = fmt.Errorf("sketch %d not found", *sc.SketchID)
+		}
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return sc, false
+	}
+	return sc, true
+}
+
+// handleListScenarios: GET /api/security/{symbol}/scenarios returns the
+// symbol's saved scenarios.
+func (s *Server) handleListScenarios(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		json.NewEncoder(w).Encode([]any{})
+		return
+	}
+	list, err := s.store.ListScenarios(globalOwnerID, r.PathValue("symbol"))
+	if err != nil {
+		s.logger.Error("list scenarios", "error", err)
+		http.Error(w, "list failed", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []store.ModelingScenario{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleCreateScenario: POST /api/security/{symbol}/scenarios saves a
+// scenario:
+//
+//	{"name": "Bear", "notes": "China slowdown",
+//	 "assumptions": {"revenueGrowth": [0.02, 0.02, 0.03, 0.03, 0.03],
+//	                 "dcf": {"terminalGrowth": 0.015}},
+//	 "sketchId": 4}
+func (s *Server) handleCreateScenario(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var req scenarioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	sc, ok := s.scenario(w, r.PathValue("symbol"), req)
+	if !ok {
+		return
+	}
+	id, err := s.store.CreateScenario(sc)
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	saved, _ := s.store.GetScenario(globalOwnerID, id)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// handleUpdateScenario: PUT /api/security/{symbol}/scenarios/{id}
+// replaces a scenario's name, notes, assumptions and sketch.
+func (s *Server) handleUpdateScenario(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var req scenarioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	if !s.ownScenario(w, r, id) {
+		return
+	}
+	sc, ok := s.scenario(w, r.PathValue("symbol"), req)
+	if !ok {
+		return
+	}
+	sc.ID = id
+	if err := s.store.UpdateScenario(sc); err != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	saved, _ := s.store.GetScenario(globalOwnerID, id)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// handleCloneScenario: POST /api/security/{symbol}/scenarios/{id}/clone
+// {"name": "Bear"} copies a scenario under a new name.
+func (s *Server) handleCloneScenario(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var req cloneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "name is required"})
+		return
+	}
+	if !s.ownScenario(w, r, id) {
+		return
+	}
+	cid, err := s.store.CloneScenario(globalOwnerID, id, strings.TrimSpace(req.Name))
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	saved, _ := s.store.GetScenario(globalOwnerID, cid)
+	json.NewEncoder(w).Encode(saved)
+}
+
+func (s *Server) handleDeleteScenario(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	if !s.ownScenario(w, r, id) {
+		return
+	}
+	if err := s.store.DeleteScenario(globalOwnerID, id); err != nil {
+		http.Error(w, "delete failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownScenario reports whether scenario id exists and belongs to the
+// path's symbol, writing a 404 when it doesn't.
+func (s *Server) ownScenario(w http.ResponseWriter, r *http.Request, id int64) bool {
+	symbol := strings.ToUpper(r.PathValue("symbol"))
+	sc, err := s.store.GetScenario(globalOwnerID, id)
+	if err != nil || sc == nil || sc.Symbol != symbol {
+		http.Error(w, fmt.Sprintf("scenario %d not found for %s", id, symbol), http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// handleCompareScenarios: GET /api/security/{symbol}/scenarios/compare?ids=0,3,5
+// projects the listed scenarios side by side; the first is the base the
+// deltas are taken against. Id 0 is the derived defaults.
+func (s *Server) handleCompareScenarios(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	symbol := strings.ToUpper(r.PathValue("symbol"))
+	var inputs []scenarioInputs
+	for _, part := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			http.Error(w, "bad id "+part, http.StatusBadRequest)
+			return
+		}
+		in := scenarioInputs{ID: id, Name: "Derived defaults"}
+		if id != 0 {
+			sc, err := s.store.GetScenario(globalOwnerID, id)
+			if err != nil || sc == nil || sc.Symbol != symbol {
+				http.Error(w, fmt.Sprintf("scenario %d not found for %s", id, symbol), http.StatusNotFound)
+				return
+			}
+			if err := json.Unmarshal(sc.Assumptions, &in.Req); err != nil {
+				http.Error(w, fmt.Sprintf("scenario %d: bad assumptions", id), http.StatusInternalServerError)
+				return
+			}
+			in.Name, in.Notes = sc.Name, sc.Notes
+		}
+		inputs = append(inputs, in)
+	}
+	if len(inputs) < 2 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ids must list at least two scenarios"})
+		return
+	}
+
+	// The saved scenarios fix the period (and the defaults take the first
+	// one's horizon); annual and quarterly cases don't line up.
+	period := PeriodAnnual
+	var pinned *scenarioInputs
+	for i := range inputs {
+		if inputs[i].ID == 0 {
+			continue
+		}
+		p, _, _ := resolvePeriod(inputs[i].Req.Period, 0)
+		if p == "" {
+			p = inputs[i].Req.Period
+		}
+		if pinned == nil {
+			pinned, period = &inputs[i], p
+		} else if p != period {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "can't compare annual and quarterly scenarios"})
+			return
+		}
+	}
+	for i := range inputs {
+		if inputs[i].ID == 0 && pinned != nil {
+			inputs[i].Req.Period, inputs[i].Req.Horizon = pinned.Req.Period, pinned.Req.Horizon
+		}
+	}
+
+	hist, income, ok := s.loadModelingHistory(w, r, symbol, period)
+	if !ok {
+		return
+	}
+	cmp := CompareScenarios(hist, inputs, s.dcfMarket(r, symbol, income))
+	cmp.Symbol, cmp.Period = symbol, period
+	json.NewEncoder(w).Encode(cmp)
+}
+
+// handleSketchScenarios: GET /api/sketches/{id}/scenarios returns the
+// modeling scenarios attached to a sketch.
+func (s *Server) handleSketchScenarios(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		json.NewEncoder(w).Encode([]any{})
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	list, err := s.store.ListSketchScenarios(id)
+	if err != nil {
+		s.logger.Error("sketch scenarios", "id", id, "error", err)
+		http.Error(w, "list failed", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []store.ModelingScenario{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
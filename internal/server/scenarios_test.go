@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"stocktopus/internal/store"
+)
+
+func TestScenarioRoutesCheckSymbol(t *testing.T) {
+	srv, mux := testServer(t)
+	st, err := store.New(filepath.Join(t.TempDir(), "scenarios.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	srv.store = st
+	id, err := st.CreateScenario(store.ModelingScenario{Symbol: "AAPL", Name: "Bear"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/security/MSFT/scenarios/" + strconv.FormatInt(id, 10)
+
+	for _, tc := range []struct{ method, path, body string }{
+		{"PUT", path, `{"name": "Bull"}`},
+		{"POST", path + "/clone", `{"name": "Copy"}`},
+		{"DELETE", path, ""},
+		{"PUT", "/api/security/AAPL/scenarios/999", `{"name": "Bull"}`},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s %s = %d, want 404", tc.method, tc.path, w.Code)
+		}
+	}
+	if sc, _ := st.GetScenario(0, id); sc == nil || sc.Name != "Bear" {
+		t.Errorf("scenario changed through another symbol's path: %+v", sc)
+	}
+}
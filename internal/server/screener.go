@@ -3,11 +3,16 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+
+	"stocktopus/internal/model"
+	"stocktopus/internal/store"
 )
 
 // nativeScreenerParams lists the FMP company-screener query parameters we pass
@@ -55,6 +60,8 @@ type screenerResult struct {
 	ChangeFromOpen     float64 `json:"changeFromOpen"`     // percent
 	ChangeFromPrevDay  float64 `json:"changeFromPrevDay"`  // percent (== FMP changePercentage)
 	ChangeVsMarket     float64 `json:"changeVsMarket"`     // percent points (own change - SPY change)
+
+	Flags []model.QuoteFlag `json:"flags,omitempty"` // from the quote provider; interactive runs only
 }
 
 // batchQuoteRow mirrors the relevant subset of FMP's /stable/batch-quote item.
@@ -99,7 +106,40 @@ func (s *Server) handleScreenerAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	q := r.URL.Query()
+	results, err := s.screen(r.Context(), r.URL.Query(), "", false)
+	if err != nil {
+		var ue *upstreamError
+		if errors.As(err, &ue) {
+			s.logger.Error("screener: "+ue.stage, "error", ue.err)
+			http.Error(w, ue.msg, http.StatusBadGateway)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.flagScreenerResults(r.Context(), results)
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+// upstreamError is an FMP failure during a screen; msg is what the client
+// sees, the rest is for the log.
+type upstreamError struct {
+	stage, msg string
+	err        error
+}
+
+func (e *upstreamError) Error() string { return e.stage + ": " + e.err.Error() }
+func (e *upstreamError) Unwrap() error { return e.err }
+
+// screen runs a screener query: the native params go to FMP's company
+// screener, the candidates are batch-quoted with SPY, and the custom
+// filters and displayLimit cut the result. Rows keep FMP's order unless
+// sortBy names a screenerSortFields key, in which case they are ordered
+// by it before the cut.
+func (s *Server) screen(ctx context.Context, q url.Values, sortBy string, desc bool) ([]screenerResult, error) {
+	if s.news == nil {
+		return nil, fmt.Errorf("news client unavailable")
+	}
 
 	// Split native vs custom; build the FMP request.
 	fmpParams := url.Values{}
@@ -113,22 +153,16 @@ func (s *Server) handleScreenerAPI(w http.ResponseWriter, r *http.Request) {
 		fmpParams.Set("limit", "250")
 	}
 
-	ctx := r.Context()
 	rawCandidates, err := s.news.GetCompanyScreener(ctx, fmpParams)
 	if err != nil {
-		s.logger.Error("screener: company-screener fetch", "error", err)
-		http.Error(w, "screener fetch failed", http.StatusBadGateway)
-		return
+		return nil, &upstreamError{"company-screener fetch", "screener fetch failed", err}
 	}
 	var candidates []screenerCandidate
 	if err := json.Unmarshal(rawCandidates, &candidates); err != nil {
-		s.logger.Error("screener: candidates unmarshal", "error", err)
-		http.Error(w, "bad upstream response", http.StatusBadGateway)
-		return
+		return nil, &upstreamError{"candidates unmarshal", "bad upstream response", err}
 	}
 	if len(candidates) == 0 {
-		_ = json.NewEncoder(w).Encode([]any{})
-		return
+		return []screenerResult{}, nil
 	}
 
 	// Batch-quote the candidates + SPY for the market-relative calc.
@@ -139,9 +173,7 @@ func (s *Server) handleScreenerAPI(w http.ResponseWriter, r *http.Request) {
 	}
 	quotes, err := s.batchQuotes(ctx, symbols)
 	if err != nil {
-		s.logger.Error("screener: batch-quote fetch", "error", err)
-		http.Error(w, "quote fetch failed", http.StatusBadGateway)
-		return
+		return nil, &upstreamError{"batch-quote fetch", "quote fetch failed", err}
 	}
 	spy, hasSPY := quotes["SPY"]
 
@@ -161,6 +193,15 @@ func (s *Server) handleScreenerAPI(w http.ResponseWriter, r *http.Request) {
 		results = append(results, row)
 	}
 
+	if key, ok := screenerSortFields[sortBy]; ok {
+		sort.SliceStable(results, func(i, j int) bool {
+			if desc {
+				return key(results[i]) > key(results[j])
+			}
+			return key(results[i]) < key(results[j])
+		})
+	}
+
 	// Final limit cap (after custom filters drop rows). Default 50.
 	displayLimit := 50
 	if l, err := strconv.Atoi(q.Get("displayLimit")); err == nil && l > 0 && l <= 250 {
@@ -169,8 +210,99 @@ func (s *Server) handleScreenerAPI(w http.ResponseWriter, r *http.Request) {
 	if len(results) > displayLimit {
 		results = results[:displayLimit]
 	}
+	return results, nil
+}
 
-	_ = json.NewEncoder(w).Encode(results)
+// ScreenSymbols runs a saved screener query for a dynamic watchlist and
+// returns the symbols it matched.
+func (s *Server) ScreenSymbols(ctx context.Context, q url.Values) ([]string, error) {
+	results, err := s.screen(ctx, q, "", false)
+	if err != nil {
+		return nil, err
+	}
+	symbols := make([]string, len(results))
+	for i, r := range results {
+		symbols[i] = r.Symbol
+	}
+	return symbols, nil
+}
+
+// screenerSortFields are the result fields a saved screen can be ranked
+// by, keyed by their JSON names.
+var screenerSortFields = map[string]func(screenerResult) float64{
+	"price":             func(r screenerResult) float64 { return r.Price },
+	"marketCap":         func(r screenerResult) float64 { return r.MarketCap },
+	"beta":              func(r screenerResult) float64 { return r.Beta },
+	"volume":            func(r screenerResult) float64 { return r.Volume },
+	"changeFromOpen":    func(r screenerResult) float64 { return r.ChangeFromOpen },
+	"changeFromPrevDay": func(r screenerResult) float64 { return r.ChangeFromPrevDay },
+	"changeVsMarket":    func(r screenerResult) float64 { return r.ChangeVsMarket },
+}
+
+// RankScreen runs a saved screen's query for the scheduler and returns
+// its rows ranked by sortBy, each carrying the sort field's value.
+func (s *Server) RankScreen(ctx context.Context, q url.Values, sortBy string, desc bool) ([]store.ScreenRow, error) {
+	results, err := s.screen(ctx, q, sortBy, desc)
+	if err != nil {
+		return nil, err
+	}
+	key := screenerSortFields[sortBy]
+	rows := make([]store.ScreenRow, len(results))
+	for i, r := range results {
+		rows[i] = store.ScreenRow{Rank: i + 1, Symbol: r.Symbol, Name: r.CompanyName}
+		if key != nil {
+			v := key(r)
+			rows[i].Value = &v
+		}
+	}
+	return rows, nil
+}
+
+// customScreenerParams are the post-fetch filters parseCustomFilters reads,
+// plus the output cap.
+var customScreenerParams = map[string]bool{
+	"changeFromOpenMin":    true,
+	"changeFromOpenMax":    true,
+	"changeFromPrevDayMin": true,
+	"changeFromPrevDayMax": true,
+	"changeVsMarketMin":    true,
+	"changeVsMarketMax":    true,
+	"displayLimit":         true,
+}
+
+// normalizeScreenerQuery checks a query to be saved — URL-encoded
+// /api/screener params — and returns it in canonical form. Unknown params
+// and non-numeric custom bounds are errors here, where the screener
+// itself would silently ignore them.
+func normalizeScreenerQuery(raw string) (string, error) {
+	q, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(raw), "?"))
+	if err != nil {
+		return "", fmt.Errorf("bad query: %w", err)
+	}
+	out := url.Values{}
+	for key, vals := range q {
+		v := ""
+		if len(vals) > 0 {
+			v = strings.TrimSpace(vals[0])
+		}
+		if v == "" {
+			continue
+		}
+		switch {
+		case nativeScreenerParams[key]:
+		case customScreenerParams[key]:
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return "", fmt.Errorf("%s: %q is not a number", key, v)
+			}
+		default:
+			return "", fmt.Errorf("unknown screener param %q", key)
+		}
+		out.Set(key, v)
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("query has no filters")
+	}
+	return out.Encode(), nil
 }
 
 // batchQuotes fetches /stable/batch-quote for the given symbols and returns
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeScreenerQuery(t *testing.T) {
+	tests := []struct {
+		raw, want string
+		ok        bool
+	}{
+		{"?sector=Technology&changeFromOpenMin=2", "changeFromOpenMin=2&sector=Technology", true},
+		{"changeVsMarketMin=1.5&displayLimit=20&betaMoreThan=", "changeVsMarketMin=1.5&displayLimit=20", true},
+		{"marketCapMoreThan=1000000000", "marketCapMoreThan=1000000000", true},
+		{"changeFromOpenMin=lots", "", false},
+		{"peMoreThan=10", "", false},
+		{"limit=", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := normalizeScreenerQuery(tt.raw)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("normalizeScreenerQuery(%q) = %q, %v; want %q ok=%v", tt.raw, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSavedScreenRequest(t *testing.T) {
+	s := &Server{}
+	sc, ok := s.savedScreen(httptest.NewRecorder(), screenRequest{
+		Name: " Gappers ", Query: "?sector=Technology&changeFromOpenMin=2", SortBy: "changeFromOpen", Schedule: "35 9 * * 1-5",
+	})
+	if !ok || sc.Name != "Gappers" || sc.Query != "changeFromOpenMin=2&sector=Technology" || !sc.Enabled || sc.Timezone != "America/New_York" {
+		t.Errorf("saved screen = %+v, ok=%v", sc, ok)
+	}
+	off := false
+	if sc, _ := s.savedScreen(httptest.NewRecorder(), screenRequest{Name: "x", Query: "sector=Energy", Enabled: &off}); sc.Enabled {
+		t.Error("enabled=false ignored")
+	}
+	for _, req := range []screenRequest{
+		{Name: "x", Query: "peMoreThan=10"},
+		{Name: "x", Query: "sector=Energy", SortBy: "peRatio"},
+		{Name: "x", Query: "sector=Energy", Schedule: "9am"},
+		{Name: "", Query: "sector=Energy"},
+	} {
+		rec := httptest.NewRecorder()
+		if _, ok := s.savedScreen(rec, req); ok || rec.Code != 400 {
+			t.Errorf("%+v accepted (status %d)", req, rec.Code)
+		}
+	}
+}
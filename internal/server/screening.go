@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"stocktopus/internal/budget"
+	"stocktopus/internal/screening"
+)
+
+// SetScreening attaches the local screener: the engine answers queries
+// and the snapshotter rebuilds its universe on demand.
+func (s *Server) SetScreening(e *screening.Engine, snap *screening.Snapshotter) {
+	s.screening = e
+	s.snapshots = snap
+}
+
+// handleLocalScreenerStatus: GET /api/screener/local reports the snapshot
+// size and age, and the fields and functions expressions can use.
+func (s *Server) handleLocalScreenerStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.screening == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	json.NewEncoder(w).Encode(s.screening.Status())
+}
+
+// handleLocalScreener: POST /api/screener/local runs a screen over the
+// universe snapshot without calling FMP:
+//
+//	{"filter": "peRatio < 20 and revenueGrowth3y > 0.1 and close > sma(200)",
+//	 "rank": "change(126)", "ascending": false,
+//	 "factors": [{"name": "value", "expr": "-peRatio", "weight": 2}],
+//	 "columns": ["rsi(14)"], "limit": 50}
+func (s *Server) handleLocalScreener(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.screening == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var q screening.Query
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	resp, err := s.screening.Run(q)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleLocalScreenerRefresh: POST /api/screener/local/refresh starts a
+// snapshot rebuild in the background.
+func (s *Server) handleLocalScreenerRefresh(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.snapshots == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(budget.WithCaller(context.Background(), "screening", budget.Background), 2*time.Hour)
+		defer cancel()
+		if n, err := s.snapshots.Rebuild(ctx); err != nil {
+			s.logger.Warn("universe snapshot rebuild", "error", err)
+		} else {
+			s.logger.Info("universe snapshot rebuilt", "symbols", n)
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "rebuilding"})
+}
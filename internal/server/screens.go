@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"stocktopus/internal/budget"
+	"stocktopus/internal/screens"
+	"stocktopus/internal/store"
+)
+
+// SetScreenScheduler attaches the saved screen scheduler, which the run
+// endpoint uses to run a screen on demand.
+func (s *Server) SetScreenScheduler(sch *screens.Scheduler) {
+	s.screens = sch
+}
+
+// screenRequest is the body of a create or update. Enabled defaults to
+// true on create.
+type screenRequest struct {
+	Name       string `json:"name"`
+	Query      string `json:"query"`
+	SortBy     string `json:"sortBy"`
+	Descending bool   `json:"descending"`
+	Schedule   string `json:"schedule"`
+	Timezone   string `json:"timezone"`
+	WebhookURL string `json:"webhookUrl"`
+	Enabled    *bool  `json:"enabled"`
+}
+
+// savedScreen validates the request and returns the screen to store, or
+// writes a 400 and returns false.
+func (s *Server) savedScreen(w http.ResponseWriter, req screenRequest) (store.SavedScreen, bool) {
+	sc := store.SavedScreen{
+		UserID:     globalOwnerID,
+		Name:       strings.TrimSpace(req.Name),
+		SortBy:     strings.TrimSpace(req.SortBy),
+		Descending: req.Descending,
+		Schedule:   strings.TrimSpace(req.Schedule),
+		Timezone:   strings.TrimSpace(req.Timezone),
+		WebhookURL: strings.TrimSpace(req.WebhookURL),
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+	if sc.Timezone == "" {
+		sc.Timezone = screens.DefaultTimezone
+	}
+	query, err := normalizeScreenerQuery(req.Query)
+	if err == nil {
+		sc.Query = query
+		if _, ok := screenerSortFields[sc.SortBy]; sc.SortBy != "" && !ok {
+			err = fmt.Errorf("unknown sort field %q (price, marketCap, beta, volume, changeFromOpen, changeFromPrevDay, changeVsMarket)", sc.SortBy)
+		}
+	}
+	if err == nil {
+		err = screens.Validate(sc)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return sc, false
+	}
+	return sc, true
+}
+
+// handleListScreens: GET /api/screens returns the saved screens.
+func (s *Server) handleListScreens(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		json.NewEncoder(w).Encode([]any{})
+		return
+	}
+	list, err := s.store.ListSavedScreens(globalOwnerID)
+	if err != nil {
+		s.logger.Error("list saved screens", "error", err)
+		http.Error(w, "list failed", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []store.SavedScreen{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleCreateScreen: POST /api/screens saves a screen:
+//
+//	{"name": "Morning gappers", "query": "changeFromOpenMin=2&sector=Technology",
+//	 "sortBy": "changeFromOpen", "descending": true,
+//	 "schedule": "35 9 * * 1-5", "timezone": "America/New_York",
+//	 "webhookUrl": "https://example.com/hooks/screens"}
+//
+// The schedule is optional; without one the screen only runs on demand.
+func (s *Server) handleCreateScreen(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var req screenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	sc, ok := s.savedScreen(w, req)
+	if !ok {
+		return
+	}
+	id, err := s.store.CreateSavedScreen(sc)
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	saved, _ := s.store.GetSavedScreen(globalOwnerID, id)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// handleUpdateScreen: PUT /api/screens/{id} replaces a screen's
+// definition. Its history is kept.
+func (s *Server) handleUpdateScreen(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var req screenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	sc, ok := s.savedScreen(w, req)
+	if !ok {
+		return
+	}
+	sc.ID = id
+	if err := s.store.UpdateSavedScreen(sc); err != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	saved, _ := s.store.GetSavedScreen(globalOwnerID, id)
+	json.NewEncoder(w).Encode(saved)
+}
+
+func (s *Server) handleDeleteScreen(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	if err := s.store.DeleteSavedScreen(globalOwnerID, id); err != nil {
+		http.Error(w, "delete failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRunScreen: POST /api/screens/{id}/run runs a screen now, records
+// it in the history and returns the run with its diff.
+func (s *Server) handleRunScreen(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil || s.screens == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	sc, err := s.store.GetSavedScreen(globalOwnerID, id)
+	if err != nil || sc == nil {
+		http.Error(w, "screen not found", http.StatusNotFound)
+		return
+	}
+	// The run is recorded even if the client goes away mid-screen.
+	ctx, cancel := context.WithTimeout(budget.WithCaller(context.WithoutCancel(r.Context()), "screens", budget.Interactive), time.Minute)
+	defer cancel()
+	run, err := s.screens.Execute(ctx, *sc)
+	if err != nil {
+		s.logger.Error("run saved screen", "id", id, "error", err)
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(run)
+}
+
+// handleScreenRuns: GET /api/screens/{id}/runs?limit=N returns a screen's
+// history, newest first (default 30 runs).
+func (s *Server) handleScreenRuns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	if sc, err := s.store.GetSavedScreen(globalOwnerID, id); err != nil || sc == nil {
+		http.Error(w, "screen not found", http.StatusNotFound)
+		return
+	}
+	limit := 30
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 && n <= 250 {
+			limit = n
+		}
+	}
+	runs, err := s.store.GetScreenRuns(id, limit)
+	if err != nil {
+		s.logger.Error("screen runs", "id", id, "error", err)
+		http.Error(w, "history failed", http.StatusInternalServerError)
+		return
+	}
+	if runs == nil {
+		runs = []store.ScreenRun{}
+	}
+	json.NewEncoder(w).Encode(runs)
+}
@@ -8,10 +8,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
-	"io"
 	"log/slog"
 	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
 	"sync"
@@ -20,17 +18,31 @@ import (
 	"strings"
 	"time"
 
+	"stocktopus/internal/alerts"
 	"stocktopus/internal/agent"
 	"stocktopus/internal/agent/trading"
+	"stocktopus/internal/budget"
+	"stocktopus/internal/calendars"
+	"stocktopus/internal/corpactions"
 	"stocktopus/internal/econ"
 	"stocktopus/internal/hub"
+	"stocktopus/internal/metrics"
 	"stocktopus/internal/news"
+	"stocktopus/internal/provider"
+	"stocktopus/internal/screening"
+	"stocktopus/internal/screens"
 	"stocktopus/internal/store"
+	"stocktopus/internal/ticks"
+	"stocktopus/internal/watchlists"
 )
 
 type Config struct {
 	Port int    `yaml:"port"`
 	Host string `yaml:"host"`
+
+	// AgentEnv is KEY=value pairs added to the Python agent scripts'
+	// environment. Nil passes through OLLAMA_* and GEMINI_API_KEY.
+	AgentEnv []string `yaml:"-"`
 }
 
 func (c Config) Addr() string {
@@ -63,6 +75,16 @@ type Server struct {
 	pipeline     *agent.Pipeline
 	trading      *trading.TradingPipeline
 	store        *store.Store
+	budget       *budget.Scheduler
+	ticks        *ticks.Recorder
+	watchlists   *watchlists.Refresher
+	columns      *watchlists.Calculator
+	screening    *screening.Engine
+	snapshots    *screening.Snapshotter
+	screens      *screens.Scheduler
+	alerts       *alerts.Engine
+	comps        compsCache
+	quotes       provider.StockProvider // nil = raw FMP batch quotes
 	assetVersion string
 }
 
@@ -84,13 +106,16 @@ func New(cfg Config, h *hub.Hub, debug *DebugBroadcaster, symbols SymbolLister,
 	if err := s.loadTemplates(); err != nil {
 		return nil, fmt.Errorf("loading templates: %w", err)
 	}
+	if newsClient != nil {
+		s.columns = watchlists.NewCalculator(newsClient, logger)
+	}
 
 	mux := http.NewServeMux()
 	s.registerRoutes(mux)
 
 	s.httpServer = &http.Server{
 		Addr:         cfg.Addr(),
-		Handler:      mux,
+		Handler:      interactive(mux),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -99,6 +124,19 @@ func New(cfg Config, h *hub.Hub, debug *DebugBroadcaster, symbols SymbolLister,
 	return s, nil
 }
 
+// interactive tags every inbound request so FMP calls made while serving
+// it are scheduled ahead of background polling.
+func interactive(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(budget.WithCaller(r.Context(), "http", budget.Interactive)))
+	})
+}
+
+// SetBudget attaches the shared FMP request budget for the debug page.
+func (s *Server) SetBudget(b *budget.Scheduler) {
+	s.budget = b
+}
+
 func (s *Server) Start() error {
 	s.logger.Info("server starting", "addr", s.httpServer.Addr)
 	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -144,6 +182,7 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/news/{category}", s.handleNewsAPI)
 	mux.HandleFunc("GET /api/search", s.handleSearch)
 	mux.HandleFunc("GET /api/chart/eod/{symbol}", s.handleChartEOD)
+	mux.HandleFunc("GET /api/corporate-actions/{symbol}", s.handleCorporateActions)
 	mux.HandleFunc("GET /api/article", s.handleArticle)
 	mux.HandleFunc("GET /api/article/entities", s.handleArticleEntities)
 	mux.HandleFunc("GET /api/chart/intraday/{interval}/{symbol}", s.handleChartIntraday)
@@ -158,16 +197,35 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/security/{symbol}/financials", s.handleSecurityFinancials)
 	mux.HandleFunc("GET /api/security/{symbol}/modeling", s.handleSecurityModeling)
 	mux.HandleFunc("POST /api/security/{symbol}/modeling", s.handleSecurityModeling)
+	mux.HandleFunc("GET /api/security/{symbol}/modeling/xlsx", s.handleModelingXLSX)
+	mux.HandleFunc("POST /api/security/{symbol}/modeling/xlsx", s.handleModelingXLSX)
+	mux.HandleFunc("POST /api/security/{symbol}/modeling/montecarlo", s.handleModelingMonteCarlo)
+	mux.HandleFunc("GET /api/security/{symbol}/scenarios", s.handleListScenarios)
+	mux.HandleFunc("POST /api/security/{symbol}/scenarios", s.handleCreateScenario)
+	mux.HandleFunc("GET /api/security/{symbol}/scenarios/compare", s.handleCompareScenarios)
+	mux.HandleFunc("PUT /api/security/{symbol}/scenarios/{id}", s.handleUpdateScenario)
+	mux.HandleFunc("DELETE /api/security/{symbol}/scenarios/{id}", s.handleDeleteScenario)
+	mux.HandleFunc("POST /api/security/{symbol}/scenarios/{id}/clone", s.handleCloneScenario)
 	mux.HandleFunc("GET /api/security/{symbol}/estimates", s.handleSecurityEstimates)
 	mux.HandleFunc("GET /api/security/{symbol}/peers", s.handleSecurityPeers)
+	mux.HandleFunc("GET /api/security/{symbol}/comps", s.handleSecurityComps)
+	mux.HandleFunc("GET /api/security/{symbol}/factors", s.handleSecurityFactors)
 	mux.HandleFunc("GET /api/security/{symbol}/intelligence", s.handleIntelligence)
 	mux.HandleFunc("GET /api/security/{symbol}/intelligence/status", s.handleIntelligenceStatus)
 	mux.HandleFunc("POST /api/security/{symbol}/intelligence/refresh", s.handleIntelligenceRefresh)
 	mux.HandleFunc("GET /api/agent/status", s.handleAgentStatus)
 	mux.HandleFunc("GET /api/sic", s.handleSICCodes)
 	mux.HandleFunc("GET /api/indices", s.handleIndices)
+	mux.HandleFunc("GET /api/markets", s.handleMarkets)
 	mux.HandleFunc("GET /api/watchlists", s.handleGetWatchlists)
 	mux.HandleFunc("POST /api/watchlists", s.handleCreateWatchlist)
+	mux.HandleFunc("GET /api/watchlists/export", s.handleExportWatchlists)
+	mux.HandleFunc("POST /api/watchlists/import", s.handleImportWatchlists)
+	mux.HandleFunc("GET /api/watchlists/columns", s.handleWatchlistColumnKinds)
+	mux.HandleFunc("PUT /api/watchlists/{id}/columns", s.handleSetWatchlistColumns)
+	mux.HandleFunc("GET /api/watchlists/{id}/table", s.handleWatchlistTable)
+	mux.HandleFunc("PUT /api/watchlists/{id}/query", s.handleSetWatchlistQuery)
+	mux.HandleFunc("POST /api/watchlists/{id}/refresh", s.handleRefreshWatchlist)
 	mux.HandleFunc("POST /api/watchlists/{id}/symbols", s.handleAddToWatchlist)
 	mux.HandleFunc("DELETE /api/watchlists/{id}/symbols/{symbol}", s.handleRemoveFromWatchlist)
 	mux.HandleFunc("GET /api/watchlists/quotes", s.handleWatchlistQuotes)
@@ -192,6 +250,7 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/sketches/{id}/metrics", s.handleAddSketchMetric)
 	mux.HandleFunc("DELETE /api/sketches/{id}/metrics/{metricId}", s.handleRemoveSketchMetric)
 	mux.HandleFunc("GET /api/sketches/{id}/info-panels", s.handleSketchInfoPanels)
+	mux.HandleFunc("GET /api/sketches/{id}/scenarios", s.handleSketchScenarios)
 	mux.HandleFunc("GET /api/historical/{kind}/{symbol}", s.handleHistorical)
 
 	// Economics
@@ -223,10 +282,21 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /ideas/{id}", s.handleIdeas)
 	mux.HandleFunc("GET /economics", s.handleEconomics)
 	mux.HandleFunc("GET /debug", s.handleDebug)
+	mux.HandleFunc("GET /api/budget", s.handleBudget)
+	mux.Handle("GET /metrics", metrics.Handler())
 	mux.HandleFunc("GET /paper", s.handlePaperPage)
 
 	// Screener
 	mux.HandleFunc("GET /api/screener", s.handleScreenerAPI)
+	mux.HandleFunc("GET /api/screener/local", s.handleLocalScreenerStatus)
+	mux.HandleFunc("POST /api/screener/local", s.handleLocalScreener)
+	mux.HandleFunc("POST /api/screener/local/refresh", s.handleLocalScreenerRefresh)
+	mux.HandleFunc("GET /api/screens", s.handleListScreens)
+	mux.HandleFunc("POST /api/screens", s.handleCreateScreen)
+	mux.HandleFunc("PUT /api/screens/{id}", s.handleUpdateScreen)
+	mux.HandleFunc("DELETE /api/screens/{id}", s.handleDeleteScreen)
+	mux.HandleFunc("POST /api/screens/{id}/run", s.handleRunScreen)
+	mux.HandleFunc("GET /api/screens/{id}/runs", s.handleScreenRuns)
 
 	// Paper trading
 	mux.HandleFunc("GET /api/paper/accounts", s.handleListPaperAccounts)
@@ -237,6 +307,22 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/paper/trades/open", s.handleListOpenPaperTrades)
 	mux.HandleFunc("GET /api/paper/trades/closed", s.handleListClosedPaperTrades)
 	mux.HandleFunc("POST /api/paper/trades/{id}/close", s.handleClosePaperTrade)
+
+	// News alerts
+	mux.HandleFunc("GET /api/alerts/watches", s.handleListNewsWatches)
+	mux.HandleFunc("POST /api/alerts/watches", s.handleCreateNewsWatch)
+	mux.HandleFunc("PATCH /api/alerts/watches/{id}", s.handleUpdateNewsWatch)
+	mux.HandleFunc("DELETE /api/alerts/watches/{id}", s.handleDeleteNewsWatch)
+	mux.HandleFunc("GET /api/alerts/inbox", s.handleNewsInbox)
+	mux.HandleFunc("POST /api/alerts/inbox/{id}/read", s.handleMarkInboxRead)
+
+	// User data backup / restore
+	mux.HandleFunc("GET /api/backup", s.handleBackup)
+	mux.HandleFunc("POST /api/restore", s.handleRestore)
+
+	// Recorded intraday ticks
+	mux.HandleFunc("GET /api/ticks", s.handleTickSymbols)
+	mux.HandleFunc("GET /api/ticks/{symbol}", s.handleTicks)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -811,7 +897,20 @@ func (s *Server) handleIndices(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode([]any{})
 		return
 	}
-	w.Write(data)
+	// Annotate each index with its venue's session so the page doesn't have
+	// to guess trading hours client-side.
+	var rows []map[string]any
+	if err := json.Unmarshal(data, &rows); err != nil {
+		w.Write(data)
+		return
+	}
+	now := time.Now()
+	for _, row := range rows {
+		sym, _ := row["symbol"].(string)
+		exch, _ := row["exchange"].(string)
+		row["market"] = calendars.ForIndex(sym, exch).Status(now)
+	}
+	json.NewEncoder(w).Encode(rows)
 }
 
 func (s *Server) handleSICCodes(w http.ResponseWriter, r *http.Request) {
@@ -861,7 +960,8 @@ func (s *Server) handleCreateWatchlist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		Name string `json:"name"`
+		Name  string `json:"name"`
+		Query string `json:"query"` // saved screener query; makes the list dynamic
 	}
 	json.NewDecoder(r.Body).Decode(&req)
 	if req.Name == "" {
@@ -869,6 +969,10 @@ func (s *Server) handleCreateWatchlist(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"error": "name required"})
 		return
 	}
+	if strings.TrimSpace(req.Query) != "" {
+		s.createDynamicWatchlist(w, req.Name, req.Query)
+		return
+	}
 	wl, err := s.store.CreateWatchlist(req.Name)
 	if err != nil {
 		w.WriteHeader(http.StatusConflict)
@@ -885,6 +989,9 @@ func (s *Server) handleAddToWatchlist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if s.rejectDynamicEdit(w, id) {
+		return
+	}
 	var req struct {
 		Symbol string `json:"symbol"`
 	}
@@ -943,6 +1050,9 @@ func (s *Server) handleRemoveFromWatchlist(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if s.rejectDynamicEdit(w, id) {
+		return
+	}
 	symbol := r.PathValue("symbol")
 	s.store.RemoveFromWatchlist(id, symbol)
 	json.NewEncoder(w).Encode(map[string]string{"status": "removed"})
@@ -961,26 +1071,21 @@ func (s *Server) handleWatchlistQuotes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Batch fetch from FMP
-	params := url.Values{}
-	params.Set("symbols", strings.Join(symbols, ","))
-	params.Set("apikey", s.news.APIKey())
-
-	reqURL := "https://financialmodelingprep.com/stable/batch-quote?" + params.Encode()
-	req, err := http.NewRequestWithContext(r.Context(), "GET", reqURL, nil)
-	if err != nil {
-		json.NewEncoder(w).Encode([]any{})
+	if s.quotes != nil {
+		quotes, err := s.validatedQuotes(r.Context(), symbols)
+		if err != nil {
+			s.logger.Error("watchlist quotes", "error", err)
+			json.NewEncoder(w).Encode([]any{})
+			return
+		}
+		json.NewEncoder(w).Encode(quotes)
 		return
 	}
-
-	resp, err := http.DefaultClient.Do(req)
+	body, err := s.news.GetBatchQuote(r.Context(), symbols)
 	if err != nil {
 		json.NewEncoder(w).Encode([]any{})
 		return
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
 	w.Write(body)
 }
 
@@ -1032,6 +1137,14 @@ func (s *Server) handleChartEOD(w http.ResponseWriter, r *http.Request) {
 	from := r.URL.Query().Get("from")
 	to := r.URL.Query().Get("to")
 
+	adjust, err := corpactions.ParseMode(r.URL.Query().Get("adjust"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
 	items, err := s.news.GetHistoricalEOD(r.Context(), symbol, from, to)
 	if err != nil {
 		s.logger.Error("chart eod failed", "symbol", symbol, "error", err)
@@ -1041,6 +1154,20 @@ func (s *Server) handleChartEOD(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ?adjust=split|total asks for corporate-action adjusted bars. Plain
+	// requests stay as the provider sent them, with no extra fetches.
+	if adjust != corpactions.Raw {
+		actions, err := s.fetchOrLoadCorporateActions(r, symbol)
+		if err != nil {
+			s.logger.Error("chart corporate actions failed", "symbol", symbol, "error", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		items = corpactions.Adjust(items, actions.Splits, actions.Dividends, adjust)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(items)
 }
@@ -1141,12 +1268,16 @@ func (s *Server) runArticleScript(w http.ResponseWriter, r *http.Request, python
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, pythonCmd, args...)
-	cmd.Env = append(cmd.Environ(),
-		"OLLAMA_HOST="+getEnvOr("OLLAMA_HOST", "http://localhost:11434"),
-		"OLLAMA_MODEL="+getEnvOr("OLLAMA_MODEL", "gemma4"),
-		"OLLAMA_NER_MODEL="+getEnvOr("OLLAMA_NER_MODEL", "gemma3"),
-		"GEMINI_API_KEY="+getEnvOr("GEMINI_API_KEY", ""),
-	)
+	agentEnv := s.config.AgentEnv
+	if agentEnv == nil {
+		agentEnv = []string{
+			"OLLAMA_HOST=" + getEnvOr("OLLAMA_HOST", "http://localhost:11434"),
+			"OLLAMA_MODEL=" + getEnvOr("OLLAMA_MODEL", "gemma4"),
+			"OLLAMA_NER_MODEL=" + getEnvOr("OLLAMA_NER_MODEL", "gemma3"),
+			"GEMINI_API_KEY=" + getEnvOr("GEMINI_API_KEY", ""),
+		}
+	}
+	cmd.Env = append(cmd.Environ(), agentEnv...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
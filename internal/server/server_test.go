@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"stocktopus/internal/hub"
@@ -77,3 +78,21 @@ func TestWatchlistPage(t *testing.T) {
 		t.Error("expected non-empty response body")
 	}
 }
+
+func TestMetricsEndpoint(t *testing.T) {
+	_, mux := testServer(t)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"# TYPE stocktopus_hub_clients gauge", "# TYPE stocktopus_budget_wait_seconds histogram", "go_goroutines "} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
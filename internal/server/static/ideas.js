@@ -281,6 +281,9 @@
     var infoPanelsEl = document.getElementById('ideas-info-panels');
     var infoPanelsTimer = null;
     var lastInfoPanels = [];
+    // Modeling scenarios attached to the sketch, by symbol — shown as
+    // chips on that company's card, linking to its Scenarios tab.
+    var sketchScenarios = {};
 
     function refreshInfoPanels() {
         if (!currentSketch || !currentSketch.id) {
@@ -297,6 +300,18 @@
                 renderInfoPanels();
             })
             .catch(function () { /* swallow — UI keeps last good render */ });
+        fetch('/api/sketches/' + sketchID + '/scenarios')
+            .then(function (r) { return r.ok ? r.json() : []; })
+            .then(function (list) {
+                if (!currentSketch || currentSketch.id !== sketchID) return;
+                var bySymbol = {};
+                (list || []).forEach(function (sc) {
+                    (bySymbol[sc.symbol] = bySymbol[sc.symbol] || []).push(sc.name);
+                });
+                sketchScenarios = bySymbol;
+                renderInfoPanels();
+            })
+            .catch(function () {});
     }
 
     function renderInfoPanels() {
@@ -319,6 +334,9 @@
                 var label = id.indexOf('.') >= 0 ? id.split('.').slice(1).join('.') : id;
                 return '<span class="ideas-info-chip">' + esc(label) + '</span>';
             }).join('');
+            chips += (sketchScenarios[String(p.symbol).toUpperCase()] || []).map(function (name) {
+                return '<a class="ideas-info-chip ideas-scenario-chip" href="/security/' + encodeURIComponent(p.symbol) + '#financials-scenarios" title="modeling scenario">' + esc(name) + '</a>';
+            }).join('');
             var sparkKey = String(p.symbol).replace(/[^a-zA-Z0-9]/g, '_');
             var panelIds = (currentSketch.metrics || []).filter(function (m) {
                 return metricCompanySymbol(m) === String(p.symbol).toUpperCase();
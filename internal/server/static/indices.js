@@ -14,33 +14,27 @@
         '^N225', '^HSI',                                       // Asia-Pacific
     ];
 
-    // Exchange → timezone mapping for local time
-    var EXCHANGE_TZ = {
-        'SNP': 'America/New_York', 'DJI': 'America/New_York', 'NASDAQ': 'America/New_York',
-        'NYQ': 'America/New_York', 'CBOE': 'America/Chicago',
-        'LSE': 'Europe/London', 'XETRA': 'Europe/Berlin', 'PAR': 'Europe/Paris',
-        'STO': 'Europe/Stockholm', 'AMS': 'Europe/Amsterdam',
-        'JPX': 'Asia/Tokyo', 'HKSE': 'Asia/Hong_Kong', 'KSC': 'Asia/Seoul',
-        'TAI': 'Asia/Taipei', 'ASX': 'Australia/Sydney', 'SGX': 'Asia/Singapore',
-        'TSX': 'America/Toronto', 'SAO': 'America/Sao_Paulo', 'MEX': 'America/Mexico_City',
-    };
-
-    // Market hours (approximate, local time)
-    var MARKET_HOURS = { open: 9, close: 16 };
-
-    function getLocalTime(exchange) {
-        var tz = EXCHANGE_TZ[exchange] || 'America/New_York';
+    // Session state comes from the server's exchange calendars
+    // (/api/indices attaches it as idx.market; /api/markets refreshes it).
+    function getLocalTime(market) {
+        if (!market) return { time: '--:--', open: false, title: '' };
+        var tz = market.timezone || 'America/New_York';
+        var timeStr;
         try {
-            var now = new Date();
-            var timeStr = now.toLocaleTimeString('en-GB', { hour: '2-digit', minute: '2-digit', timeZone: tz, hour12: false });
-            var hour = parseInt(now.toLocaleString('en-GB', { hour: '2-digit', hour12: false, timeZone: tz }));
-            var day = now.toLocaleDateString('en-US', { weekday: 'short', timeZone: tz });
-            var isWeekend = day === 'Sat' || day === 'Sun';
-            var isOpen = !isWeekend && hour >= MARKET_HOURS.open && hour < MARKET_HOURS.close;
-            return { time: timeStr, open: isOpen };
+            timeStr = new Date().toLocaleTimeString('en-GB', { hour: '2-digit', minute: '2-digit', timeZone: tz, hour12: false });
         } catch (e) {
-            return { time: '--:--', open: false };
+            timeStr = market.localTime || '--:--';
         }
+        var title = market.name + ' — ' + (market.holiday ? 'closed (' + market.holiday + ')' : market.session);
+        if (market.earlyClose) title += ', early close';
+        return { time: timeStr, open: market.open, title: title };
+    }
+
+    function timeCellHtml(market) {
+        var local = getLocalTime(market);
+        var statusClass = local.open ? 'idx-open' : 'idx-closed';
+        var statusLabel = local.open ? 'O' : 'C';
+        return '<span class="' + statusClass + '" title="' + esc(local.title) + '">' + statusLabel + '</span> ' + local.time;
     }
 
     function fmt(n) {
@@ -100,9 +94,6 @@
             html += '<th>Index</th><th>Trend</th><th>Price</th><th>Change</th><th>% Change</th><th>Local Time</th>';
             html += '</tr></thead><tbody>';
             indices.forEach(function (idx) {
-                var local = getLocalTime(idx.exchange);
-                var statusClass = local.open ? 'idx-open' : 'idx-closed';
-                var statusLabel = local.open ? 'O' : 'C';
                 var safeId = idx.symbol.replace('^', '');
                 html += '<tr class="idx-row" data-symbol="' + esc(idx.symbol) + '" data-exchange="' + esc(idx.exchange) + '">'
                     + '<td class="idx-name"><span class="idx-sym">' + esc(idx.symbol) + '</span> <span class="idx-label">' + esc(idx.name) + '</span></td>'
@@ -110,7 +101,7 @@
                     + '<td class="idx-price" id="price-' + safeId + '">—</td>'
                     + '<td class="idx-change" id="change-' + safeId + '">—</td>'
                     + '<td class="idx-pct" id="pct-' + safeId + '">—</td>'
-                    + '<td class="idx-time"><span class="' + statusClass + '">' + statusLabel + '</span> ' + local.time + '</td>'
+                    + '<td class="idx-time">' + timeCellHtml(idx.market) + '</td>'
                     + '</tr>';
             });
             html += '</tbody></table>';
@@ -156,15 +147,22 @@
             .catch(function () {});
     }
 
+    // Refresh sessions from /api/markets (calendar-only, no upstream
+    // calls) and repaint the clock column.
     function updateLocalTimes(indices) {
-        indices.forEach(function (idx) {
-            var row = document.querySelector('[data-symbol="' + idx.symbol + '"] .idx-time');
-            if (row) {
-                var local = getLocalTime(idx.exchange);
-                var statusClass = local.open ? 'idx-open' : 'idx-closed';
-                var statusLabel = local.open ? 'O' : 'C';
-                row.innerHTML = '<span class="' + statusClass + '">' + statusLabel + '</span> ' + local.time;
-            }
-        });
+        var venues = {};
+        indices.forEach(function (idx) { if (idx.market) venues[idx.market.venue] = true; });
+        fetch('/api/markets?venue=' + encodeURIComponent(Object.keys(venues).join(',')))
+            .then(function (r) { return r.json(); })
+            .then(function (statuses) {
+                var byVenue = {};
+                (statuses || []).forEach(function (st) { byVenue[st.venue] = st; });
+                indices.forEach(function (idx) {
+                    if (idx.market && byVenue[idx.market.venue]) idx.market = byVenue[idx.market.venue];
+                    var row = document.querySelector('[data-symbol="' + idx.symbol + '"] .idx-time');
+                    if (row) row.innerHTML = timeCellHtml(idx.market);
+                });
+            })
+            .catch(function () {});
     }
 })();
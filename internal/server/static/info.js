@@ -230,7 +230,7 @@
         securityType = type;
         var hideTabs = [];
         if (type === 'crypto' || type === 'forex' || type === 'index') {
-            hideTabs = ['financials', 'estimates', 'sec'];
+            hideTabs = ['financials', 'estimates', 'comps', 'sec'];
         }
         document.querySelectorAll('#info-tabs .info-tab').forEach(function (tab) {
             if (hideTabs.indexOf(tab.dataset.tab) >= 0) {
@@ -273,6 +273,7 @@
                 case 'news': loadNews(); break;
                 case 'ai': loadAI(); break;
                 case 'sector': loadSector(); break;
+                case 'comps': loadComps(); break;
                 case 'sec': loadSEC(); break;
             }
         } catch (e) {
@@ -487,6 +488,9 @@
         { key: 'cashflow',    label: 'Cash Flow',      hotkey: 'c' },
         { key: 'assumptions', label: 'Assumptions',    hotkey: 'a' },
         { key: 'forecast',    label: 'Forecast',       hotkey: 'f' },
+        { key: 'valuation',   label: 'Valuation',      hotkey: 'v' },
+        { key: 'scenarios',   label: 'Scenarios',      hotkey: 's' },
+        { key: 'simulation',  label: 'Monte Carlo',    hotkey: 'm' },
     ];
 
     function loadFinancials(type) {
@@ -677,10 +681,18 @@
     function loadFinancialTable(type) {
         var tc = document.getElementById('fin-table-container');
         if (!tc) return;
-        if (type === 'assumptions' || type === 'forecast') {
+        if (type === 'assumptions' || type === 'forecast' || type === 'valuation') {
             loadModeling(type);
             return;
         }
+        if (type === 'scenarios') {
+            loadScenarios();
+            return;
+        }
+        if (type === 'simulation') {
+            loadMonteCarlo();
+            return;
+        }
         tc.innerHTML = '<p class="empty-state">Loading...</p>';
 
         fetch('/api/security/' + symbol + '/financials?type=' + type)
@@ -806,11 +818,12 @@
 
     // ── Financial Modeling (CFI three-statement projection) ──
     //
-    // Drivers + 5y projection live in /api/security/SYMBOL/modeling.
+    // Drivers + projection live in /api/security/SYMBOL/modeling.
     // Defaults are derived server-side from history; the Assumptions
     // sub-tab edits them in place and POSTs the override to recompute
     // the Forecast sub-tab. Edits persist to localStorage per symbol so
-    // a refresh keeps your scenario.
+    // a refresh keeps your scenario. The period (annual or quarterly)
+    // and horizon ride along in the same stored object.
 
     // Drivers, in render order. Format: 'pct' (0.10 → "10.0%"),
     // 'days' (raw number suffixed with " d"), 'money' (compact $).
@@ -890,6 +903,59 @@
         try { localStorage.removeItem(modelingStorageKey()); } catch (e) {}
     }
 
+    // DCF inputs are stored beside the drivers, holding only the fields
+    // the user typed: anything absent (beta, Rf, …) keeps tracking the
+    // market data the server looks up.
+    function readStoredDCF() {
+        try {
+            var raw = localStorage.getItem(modelingStorageKey() + '.dcf');
+            return raw ? JSON.parse(raw) : null;
+        } catch (e) {
+            return null;
+        }
+    }
+
+    function writeStoredDCF(d) {
+        try {
+            localStorage.setItem(modelingStorageKey() + '.dcf', JSON.stringify(d));
+        } catch (e) {}
+    }
+
+    function clearStoredDCF() {
+        try { localStorage.removeItem(modelingStorageKey() + '.dcf'); } catch (e) {}
+    }
+
+    // modelingBody is the POST body: driver overrides at the top level,
+    // DCF inputs under "dcf".
+    function modelingBody(assumptions, dcf) {
+        var body = JSON.parse(JSON.stringify(assumptions || {}));
+        if (dcf) body.dcf = dcf;
+        return JSON.stringify(body);
+    }
+
+    // periodLabel names a model column: "2025", "2025 Q3", or "2025 (2Q)"
+    // for a fiscal year the quarterly forecast only partly covers.
+    function periodLabel(p) {
+        if (p.label) return p.label;
+        if (p.quarter) return p.year + ' Q' + p.quarter;
+        if (p.quarters && p.quarters < 4) return p.year + ' (' + p.quarters + 'Q)';
+        return String(p.year);
+    }
+
+    // modelingError shows a rejected request (say, stored drivers that
+    // no longer fit the horizon) with a way back to the defaults.
+    function modelingError(view, msg) {
+        var tc = document.getElementById('fin-table-container');
+        if (!tc) return;
+        tc.innerHTML = '<p class="empty-state">' + esc(msg) + '</p>' +
+            '<div class="modeling-actions"><button type="button" id="modeling-reset" class="modeling-btn">Reset to derived defaults</button></div>';
+        document.getElementById('modeling-reset').addEventListener('click', function () {
+            clearStoredAssumptions();
+            window._modelingData = null;
+            loadModeling(view);
+        });
+    }
+
     function loadModeling(view) {
         var tc = document.getElementById('fin-table-container');
         if (!tc) return;
@@ -903,18 +969,23 @@
         }
 
         var stored = readStoredAssumptions();
+        var storedDCF = readStoredDCF();
         var url = '/api/security/' + symbol + '/modeling';
         var opts = { method: 'GET' };
-        if (stored) {
+        if (stored || storedDCF) {
             opts = {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify(stored),
+                body: modelingBody(stored, storedDCF),
             };
         }
         fetch(url, opts)
             .then(function (r) { return r.json(); })
             .then(function (data) {
+                if (data.error) {
+                    modelingError(view, data.error);
+                    return;
+                }
                 window._modelingData = data;
                 window._modelingData.symbol = symbol;
                 renderModeling(view, data);
@@ -930,11 +1001,42 @@
         if (view === 'assumptions') {
             tc.innerHTML = renderAssumptions(data);
             wireAssumptionInputs();
+        } else if (view === 'valuation') {
+            tc.innerHTML = renderValuation(data);
+            wireValuationInputs();
         } else {
             tc.innerHTML = renderForecast(data);
+            var dl = document.getElementById('modeling-xlsx');
+            if (dl) dl.addEventListener('click', downloadModelXLSX);
         }
     }
 
+    // downloadModelXLSX posts the stored inputs to the export endpoint
+    // and saves the workbook it returns.
+    function downloadModelXLSX() {
+        setModelingStatus('Building workbook…');
+        fetch('/api/security/' + symbol + '/modeling/xlsx', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: modelingBody(readStoredAssumptions(), readStoredDCF()),
+        })
+            .then(function (r) {
+                if (!r.ok) throw new Error('Export failed');
+                return r.blob();
+            })
+            .then(function (blob) {
+                var a = document.createElement('a');
+                a.href = URL.createObjectURL(blob);
+                a.download = symbol + '-model.xlsx';
+                document.body.appendChild(a);
+                a.click();
+                a.remove();
+                setTimeout(function () { URL.revokeObjectURL(a.href); }, 1000);
+                setModelingStatus('');
+            })
+            .catch(function (e) { setModelingStatus(e.message); });
+    }
+
     function formatDriver(format, v) {
         if (v == null || isNaN(v)) return '';
         if (format === 'pct')   return (v * 100).toFixed(1);
@@ -951,14 +1053,23 @@
     }
 
     function renderAssumptions(data) {
-        var forecastYears = (data.forecast || []).map(function (p) { return p.year; });
+        var quarterly = data.period === 'quarterly';
         var html = '<div class="modeling-explainer help-tip' + (helpVisible ? '' : ' hidden') + '">';
-        html += 'Assumptions drive the 5-year projection. Defaults are derived from the most recent historical year (e.g. revenue growth ≈ last YoY change, A/R days ≈ last year\'s receivables ÷ revenue × 365). Edit any cell to override; changes persist to this browser and recompute the Forecast tab.';
+        html += 'Assumptions drive the projection. Defaults are derived from the most recent historical year (e.g. revenue growth ≈ last YoY change, A/R days ≈ last year\'s receivables ÷ revenue × 365). Quarterly models take each quarter\'s drivers from the same quarter a year earlier, so seasonality carries forward; growth is year-over-year and days are per 91¼-day quarter. Edit any cell to override; changes persist to this browser and recompute the Forecast tab. Changing the period or horizon resets the drivers.';
+        html += '</div>';
+
+        html += '<div class="modeling-actions dcf-inputs" data-vim-row>';
+        html += '<label class="dcf-input">Period <select id="modeling-period" class="modeling-shape" data-vim-item>';
+        html += '<option value="annual"' + (quarterly ? '' : ' selected') + '>Annual</option>';
+        html += '<option value="quarterly"' + (quarterly ? ' selected' : '') + '>Quarterly</option>';
+        html += '</select></label>';
+        html += '<label class="dcf-input">Horizon <span class="modeling-unit">(' + (quarterly ? 'quarters' : 'years') + ')</span>';
+        html += '<input id="modeling-horizon" class="modeling-shape" type="number" min="1" step="1" value="' + (data.horizon || '') + '" data-vim-item></label>';
         html += '</div>';
 
         html += '<table class="fin-table modeling-table"><thead><tr><th>Driver</th>';
-        forecastYears.forEach(function (y) {
-            html += '<th>' + y + '</th>';
+        (data.forecast || []).forEach(function (p) {
+            html += '<th>' + periodLabel(p) + '</th>';
         });
         html += '</tr></thead><tbody>';
 
@@ -990,6 +1101,23 @@
         inputs.forEach(function (inp) {
             inp.addEventListener('change', onAssumptionChange);
         });
+        var period = document.getElementById('modeling-period');
+        var horizon = document.getElementById('modeling-horizon');
+        var onShape = function () {
+            // The driver slices are per period, so a new shape starts
+            // from the derived defaults. A period switch takes that
+            // period's default horizon.
+            var stored = { period: period.value };
+            var prev = window._modelingData || {};
+            if (period.value === prev.period) {
+                stored.horizon = parseInt(horizon.value, 10) || 0;
+            }
+            writeStoredAssumptions(stored);
+            window._modelingData = null;
+            loadModeling('assumptions');
+        };
+        if (period) period.addEventListener('change', onShape);
+        if (horizon) horizon.addEventListener('change', onShape);
         var reset = document.getElementById('modeling-reset');
         if (reset) reset.addEventListener('click', function () {
             clearStoredAssumptions();
@@ -1001,6 +1129,8 @@
     function onAssumptionChange() {
         if (!window._modelingData) return;
         var assumptions = JSON.parse(JSON.stringify(window._modelingData.assumptions));
+        assumptions.period = window._modelingData.period;
+        assumptions.horizon = window._modelingData.horizon;
         document.querySelectorAll('.modeling-row').forEach(function (row) {
             var driverKey = row.dataset.driver;
             var format = row.dataset.format;
@@ -1014,10 +1144,14 @@
         fetch('/api/security/' + symbol + '/modeling', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify(assumptions),
+            body: modelingBody(assumptions, readStoredDCF()),
         })
             .then(function (r) { return r.json(); })
             .then(function (data) {
+                if (data.error) {
+                    setModelingStatus(data.error);
+                    return;
+                }
                 window._modelingData = data;
                 window._modelingData.symbol = symbol;
                 setModelingStatus('Saved · forecast updated');
@@ -1035,17 +1169,40 @@
     function renderForecast(data) {
         var hist = data.historical || [];
         var fc = data.forecast || [];
-        var allPeriods = hist.concat(fc);
         var hiddenClass = helpVisible ? '' : ' hidden';
 
         var html = '<div class="modeling-explainer help-tip' + hiddenClass + '">';
-        html += 'Five years of history (left) and five years of projection (right, italic) computed from the assumption drivers. The Balance Check row is the gap between Total Assets and Total Liabilities + Equity — well-formed models tie to zero (within rounding).';
+        html += 'History (left) and the projection (right, italic) computed from the assumption drivers. The Balance Check row is the gap between Total Assets and Total Liabilities + Equity — well-formed models tie to zero (within rounding). Quarterly models add a fiscal-year roll-up — flows summed, balances as of the year\'s last quarter — led by the trailing twelve months; the valuation runs on those years.';
         html += '</div>';
 
-        html += '<table class="fin-table modeling-table modeling-forecast"><thead><tr><th></th>';
+        html += '<div class="modeling-actions" data-vim-row>';
+        html += '<button type="button" id="modeling-xlsx" class="modeling-btn" data-vim-item>Download .xlsx</button>';
+        html += '<span class="modeling-status" id="modeling-status"></span>';
+        html += '</div>';
+        html += modelingTable(hist.concat(fc));
+        if (data.period === 'quarterly') {
+            var years = [];
+            if (data.ttm) {
+                var ttm = JSON.parse(JSON.stringify(data.ttm));
+                ttm.label = 'TTM';
+                years.push(ttm);
+            }
+            years = years.concat(data.annual || []);
+            if (years.length) {
+                html += '<h4 class="modeling-subhead">Fiscal years</h4>';
+                html += modelingTable(years);
+            }
+        }
+        return html;
+    }
+
+    // modelingTable renders periods as statement columns, forecast ones
+    // in italics.
+    function modelingTable(allPeriods) {
+        var html = '<table class="fin-table modeling-table modeling-forecast"><thead><tr><th></th>';
         allPeriods.forEach(function (p) {
             var cls = p.historical ? '' : ' class="forecast-col"';
-            html += '<th' + cls + '>' + p.year + '</th>';
+            html += '<th' + cls + '>' + periodLabel(p) + '</th>';
         });
         html += '</tr></thead><tbody>';
 
@@ -1098,6 +1255,680 @@
         return html;
     }
 
+    // Valuation inputs, in render order. 'pct' inputs are edited in
+    // percent; 'num' ones as typed. The value shown is what the server
+    // used, so an untouched beta shows the looked-up one.
+    var DCF_INPUTS = [
+        { key: 'equityRiskPremium', field: 'equityRiskPremium', label: 'Equity Risk Premium', format: 'pct' },
+        { key: 'riskFreeRate',      field: 'riskFreeRate',      label: 'Risk-Free Rate',      format: 'pct' },
+        { key: 'beta',              field: 'beta',              label: 'Beta',                format: 'num' },
+        { key: 'costOfDebt',        field: 'costOfDebt',        label: 'Pre-Tax Cost of Debt', format: 'pct' },
+        { key: 'terminalGrowth',    field: 'terminalGrowth',    label: 'Terminal Growth',     format: 'pct' },
+        { key: 'exitMultiple',      field: 'exitMultiple',      label: 'Exit EV/EBITDA',      format: 'num' },
+    ];
+
+    // Free cash flow build rows: [label, DCFYear field, format].
+    var DCF_YEAR_ROWS = [
+        ['EBIT',                     'ebit'],
+        ['Tax Rate',                 'taxRate',        'pct'],
+        ['NOPAT',                    'nopat'],
+        ['+ D&A',                    'da'],
+        ['− Capex',                  'capex'],
+        ['− Δ Net Working Capital',  'changeNWC'],
+        ['Unlevered FCF',            'ufcf'],
+        ['Discount Factor',          'discountFactor', 'factor'],
+        ['Present Value',            'presentValue'],
+    ];
+
+    function renderValuation(data) {
+        var d = data.dcf;
+        var html = '<div class="modeling-explainer help-tip' + (helpVisible ? '' : ' hidden') + '">';
+        html += 'Discounted cash flow on the forecast. Unlevered free cash flow (EBIT after tax, plus D&A, less capex and the working-capital build) is discounted at WACC, with the cost of equity from CAPM: risk-free rate (10-year Treasury) + beta (1-year rolling vs SPY) × equity risk premium. The terminal value is either Gordon growth or an exit EV/EBITDA multiple on the final forecast year. Quarterly models are valued on their fiscal years; a first year marked (2Q) is a stub and discounted by the part-year. Edit any input to override it; cleared inputs go back to market data.';
+        html += '</div>';
+        if (!d) {
+            html += '<p class="empty-state">No valuation' + (data.dcfError ? ': ' + esc(data.dcfError) : '') + '</p>';
+            return html;
+        }
+
+        html += '<div class="modeling-actions dcf-inputs" data-vim-row>';
+        html += '<label class="dcf-input">Terminal Value <select id="dcf-method" data-vim-item>';
+        html += '<option value="gordon"' + (d.method === 'gordon' ? ' selected' : '') + '>Gordon growth</option>';
+        html += '<option value="exit"' + (d.method === 'exit' ? ' selected' : '') + '>Exit multiple</option>';
+        html += '</select></label>';
+        DCF_INPUTS.forEach(function (inp) {
+            var v = d[inp.field];
+            var shown = inp.format === 'pct' ? (v * 100).toFixed(2) : v.toFixed(2);
+            html += '<label class="dcf-input">' + inp.label + (inp.format === 'pct' ? ' <span class="modeling-unit">(%)</span>' : '');
+            html += '<input class="modeling-input dcf-field" type="number" step="any" data-key="' + inp.key + '" data-format="' + inp.format + '" value="' + shown + '" data-vim-item></label>';
+        });
+        html += '</div>';
+
+        // Summary — discount rate on the left, value bridge on the right.
+        var rows = [
+            ['Risk-Free Rate', pct(d.riskFreeRate), d.riskFreeSource],
+            ['Beta', d.beta.toFixed(2), d.betaSource],
+            ['Equity Risk Premium', pct(d.equityRiskPremium), ''],
+            ['Cost of Equity', pct(d.costOfEquity), 'Rf + β × ERP'],
+            ['Cost of Debt (pre-tax)', pct(d.costOfDebt), ''],
+            ['Tax Rate', pct(d.taxRate), 'forecast average'],
+            ['Equity / Debt Weight', pct(d.equityWeight) + ' / ' + pct(d.debtWeight), ''],
+            ['WACC', pct(d.wacc), ''],
+        ];
+        var bridge = [
+            ['PV of Cash Flows', fmt(d.sumPvCashFlows)],
+            ['Terminal Value', fmt(d.terminalValue)],
+            [d.method === 'exit' ? 'Implied Terminal Growth' : 'Implied Exit Multiple',
+                d.method === 'exit' ? pct(d.impliedTerminalGrowth) : d.impliedExitMultiple.toFixed(1) + 'x'],
+            ['PV of Terminal Value', fmt(d.pvTerminalValue) + ' <span class="modeling-unit">(' + pct(d.terminalValueShare) + ' of EV)</span>'],
+            ['Enterprise Value', fmt(d.enterpriseValue)],
+            ['− Net Debt', fmt(d.netDebt)],
+            ['Equity Value', fmt(d.equityValue)],
+            ['Diluted Shares', fmt(d.sharesOutstanding)],
+            ['Value per Share', d.valuePerShare.toFixed(2)],
+        ];
+        if (d.price) {
+            bridge.push(['Price', d.price.toFixed(2)]);
+            bridge.push(['Upside', '<span class="' + (d.upside < 0 ? 'price-down' : 'price-up') + '">' + pct(d.upside) + '</span>']);
+        }
+        html += '<div class="dcf-summary">';
+        html += '<table class="fin-table modeling-table"><tbody>';
+        rows.forEach(function (r) {
+            var total = r[0] === 'WACC' ? ' fin-row--total' : '';
+            html += '<tr class="fin-row' + total + '" data-vim-row><td class="fin-label">' + r[0] + '</td><td>' + r[1] + '</td><td class="modeling-unit">' + esc(r[2]) + '</td></tr>';
+        });
+        html += '</tbody></table>';
+        html += '<table class="fin-table modeling-table"><tbody>';
+        bridge.forEach(function (r) {
+            var total = (r[0] === 'Enterprise Value' || r[0] === 'Value per Share') ? ' fin-row--total' : '';
+            html += '<tr class="fin-row' + total + '" data-vim-row><td class="fin-label">' + r[0] + '</td><td>' + r[1] + '</td></tr>';
+        });
+        html += '</tbody></table>';
+        html += '</div>';
+
+        // Free cash flow build
+        html += '<table class="fin-table modeling-table modeling-forecast"><thead><tr><th>Free Cash Flow</th>';
+        d.years.forEach(function (y) {
+            html += '<th class="forecast-col">' + periodLabel(y) + '</th>';
+        });
+        html += '</tr></thead><tbody>';
+        DCF_YEAR_ROWS.forEach(function (row) {
+            var total = row[1] === 'ufcf' ? ' fin-row--total' : '';
+            html += '<tr class="fin-row modeling-row' + total + '" data-vim-row><td class="fin-label">' + row[0] + '</td>';
+            d.years.forEach(function (y) {
+                var v = y[row[1]];
+                var val = row[2] === 'pct' ? pct(v) : row[2] === 'factor' ? v.toFixed(4) : fmt(v);
+                html += '<td class="forecast-col">' + val + '</td>';
+            });
+            html += '</tr>';
+        });
+        html += '</tbody></table>';
+
+        // Sensitivity: per-share value, WACC down the side, g across.
+        var s = d.sensitivity || {};
+        var mid = Math.floor((s.wacc || []).length / 2);
+        html += '<table class="fin-table modeling-table dcf-sensitivity"><thead><tr><th>WACC \\ g</th>';
+        (s.growth || []).forEach(function (g) {
+            html += '<th>' + pct(g) + '</th>';
+        });
+        html += '</tr></thead><tbody>';
+        (s.wacc || []).forEach(function (w, i) {
+            html += '<tr class="fin-row" data-vim-row><td class="fin-label">' + pct(w) + '</td>';
+            (s.values[i] || []).forEach(function (v, j) {
+                var cls = (i === mid && j === mid) ? ' class="dcf-base"' : '';
+                html += '<td' + cls + '>' + (v == null ? '—' : v.toFixed(2)) + '</td>';
+            });
+            html += '</tr>';
+        });
+        html += '</tbody></table>';
+
+        html += '<div class="modeling-actions" data-vim-row>';
+        html += '<button type="button" id="dcf-reset" class="modeling-btn" data-vim-item>Reset valuation inputs</button>';
+        html += '<span class="modeling-status" id="modeling-status"></span>';
+        html += '</div>';
+        return html;
+    }
+
+    function wireValuationInputs() {
+        document.querySelectorAll('.dcf-field').forEach(function (inp) {
+            inp.addEventListener('change', onValuationChange);
+        });
+        var method = document.getElementById('dcf-method');
+        if (method) method.addEventListener('change', onValuationChange);
+        var reset = document.getElementById('dcf-reset');
+        if (reset) reset.addEventListener('click', function () {
+            clearStoredDCF();
+            window._modelingData = null;
+            loadModeling('valuation');
+        });
+    }
+
+    function onValuationChange(e) {
+        var dcf = readStoredDCF() || {};
+        var el = e.target;
+        if (el.id === 'dcf-method') {
+            dcf.method = el.value;
+        } else if (el.value === '') {
+            delete dcf[el.dataset.key];
+        } else {
+            dcf[el.dataset.key] = parseDriver(el.dataset.format, el.value);
+        }
+        writeStoredDCF(dcf);
+        setModelingStatus('Revaluing…');
+        fetch('/api/security/' + symbol + '/modeling', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: modelingBody(readStoredAssumptions(), dcf),
+        })
+            .then(function (r) { return r.json(); })
+            .then(function (data) {
+                window._modelingData = data;
+                window._modelingData.symbol = symbol;
+                renderModeling('valuation', data);
+            })
+            .catch(function () {
+                setModelingStatus('Revalue failed');
+            });
+    }
+
+    // ── Modeling scenarios ──
+    // Named sets of drivers + DCF inputs, saved server-side per symbol.
+    // Loading one writes it into the same localStorage slots the
+    // Assumptions and Valuation tabs read, so those tabs then show it.
+
+    function scenariosURL(suffix) {
+        return '/api/security/' + symbol + '/scenarios' + (suffix || '');
+    }
+
+    function currentModelingInputs() {
+        return JSON.parse(modelingBody(readStoredAssumptions(), readStoredDCF()));
+    }
+
+    function loadScenarios() {
+        var tc = document.getElementById('fin-table-container');
+        if (!tc) return;
+        tc.innerHTML = '<p class="empty-state">Loading scenarios…</p>';
+        Promise.all([
+            fetch(scenariosURL()).then(function (r) { return r.json(); }),
+            fetch('/api/sketches').then(function (r) { return r.json(); }).catch(function () { return []; }),
+        ]).then(function (res) {
+            tc.innerHTML = renderScenarios(res[0] || [], res[1] || []);
+            wireScenarios(res[0] || []);
+        }).catch(function () {
+            tc.innerHTML = '<p class="empty-state">Failed to load scenarios</p>';
+        });
+    }
+
+    function renderScenarios(list, sketches) {
+        var html = '<div class="modeling-explainer help-tip' + (helpVisible ? '' : ' hidden') + '">';
+        html += 'Save the current Assumptions and Valuation inputs as a named case — bull, base, bear — with notes. Load one to edit it, clone it as the start of another, or tick two or more and Compare to project them side by side with each line\'s difference from the first ticked (the derived defaults are always available as a base). A scenario can be attached to an ideas sketch.';
+        html += '</div>';
+
+        html += '<div class="modeling-actions" data-vim-row>';
+        html += '<input type="text" id="scenario-name" class="modeling-input scenario-name" placeholder="Scenario name" data-vim-item>';
+        html += '<input type="text" id="scenario-notes" class="modeling-input scenario-notes" placeholder="Notes" data-vim-item>';
+        html += '<button type="button" id="scenario-save" class="modeling-btn" data-vim-item>Save current inputs</button>';
+        html += '<span class="modeling-status" id="modeling-status"></span>';
+        html += '</div>';
+
+        html += '<table class="fin-table modeling-table scenario-table"><thead><tr><th></th><th>Scenario</th><th>Notes</th><th>Sketch</th><th>Updated</th><th></th></tr></thead><tbody>';
+        html += '<tr class="fin-row" data-vim-row><td><input type="checkbox" class="scenario-pick" value="0" checked data-vim-item></td><td class="fin-label">Derived defaults</td><td class="modeling-unit">from history</td><td></td><td></td><td></td></tr>';
+        list.forEach(function (sc) {
+            html += '<tr class="fin-row" data-id="' + sc.id + '" data-vim-row>';
+            html += '<td><input type="checkbox" class="scenario-pick" value="' + sc.id + '" data-vim-item></td>';
+            html += '<td class="fin-label">' + esc(sc.name) + '</td>';
+            html += '<td>' + esc(sc.notes) + '</td>';
+            html += '<td><select class="scenario-sketch" data-id="' + sc.id + '"><option value="">—</option>';
+            sketches.forEach(function (sk) {
+                var sel = sc.sketchId === sk.id ? ' selected' : '';
+                html += '<option value="' + sk.id + '"' + sel + '>' + esc(sk.name) + '</option>';
+            });
+            html += '</select></td>';
+            html += '<td class="modeling-unit">' + (sc.updatedAt || '').slice(0, 10) + '</td>';
+            html += '<td class="scenario-actions">';
+            html += '<button type="button" class="modeling-btn scenario-load" data-id="' + sc.id + '" data-vim-item>Load</button>';
+            html += '<button type="button" class="modeling-btn scenario-clone" data-id="' + sc.id + '" data-vim-item>Clone</button>';
+            html += '<button type="button" class="modeling-btn scenario-delete" data-id="' + sc.id + '" data-vim-item>Delete</button>';
+            html += '</td></tr>';
+        });
+        html += '</tbody></table>';
+
+        html += '<div class="modeling-actions" data-vim-row>';
+        html += '<button type="button" id="scenario-compare" class="modeling-btn" data-vim-item>Compare ticked</button>';
+        html += '<select id="scenario-line">';
+        MODELING_FORECAST_ROWS.forEach(function (row) {
+            if (row[1]) html += '<option value="' + row[1] + '">' + row[0] + '</option>';
+        });
+        html += '</select>';
+        html += '</div>';
+        html += '<div id="scenario-comparison"></div>';
+        return html;
+    }
+
+    function scenarioError(r) {
+        if (r.ok) return r.json();
+        return r.json().then(function (e) { throw new Error(e.error || r.statusText); }, function () { throw new Error(r.statusText); });
+    }
+
+    function wireScenarios(list) {
+        var byId = {};
+        list.forEach(function (sc) { byId[sc.id] = sc; });
+
+        var save = document.getElementById('scenario-save');
+        if (save) save.addEventListener('click', function () {
+            var name = document.getElementById('scenario-name').value.trim();
+            if (!name) { setModelingStatus('Name the scenario first'); return; }
+            var body = {
+                name: name,
+                notes: document.getElementById('scenario-notes').value,
+                assumptions: currentModelingInputs(),
+            };
+            // Saving under an existing name overwrites that scenario.
+            var existing = list.filter(function (sc) { return sc.name === name; })[0];
+            if (existing) body.sketchId = existing.sketchId;
+            fetch(existing ? scenariosURL('/' + existing.id) : scenariosURL(), {
+                method: existing ? 'PUT' : 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(body),
+            }).then(scenarioError).then(loadScenarios).catch(function (e) {
+                setModelingStatus(e.message);
+            });
+        });
+
+        document.querySelectorAll('.scenario-load').forEach(function (btn) {
+            btn.addEventListener('click', function () {
+                var a = JSON.parse(JSON.stringify(byId[btn.dataset.id].assumptions || {}));
+                var dcf = a.dcf;
+                delete a.dcf;
+                writeStoredAssumptions(a);
+                if (dcf) writeStoredDCF(dcf); else clearStoredDCF();
+                window._modelingData = null;
+                setModelingStatus('Loaded ' + byId[btn.dataset.id].name + ' into Assumptions and Valuation');
+            });
+        });
+
+        document.querySelectorAll('.scenario-clone').forEach(function (btn) {
+            btn.addEventListener('click', function () {
+                var name = prompt('Name for the copy of ' + byId[btn.dataset.id].name);
+                if (!name) return;
+                fetch(scenariosURL('/' + btn.dataset.id + '/clone'), {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ name: name }),
+                }).then(scenarioError).then(loadScenarios).catch(function (e) {
+                    setModelingStatus(e.message);
+                });
+            });
+        });
+
+        document.querySelectorAll('.scenario-delete').forEach(function (btn) {
+            btn.addEventListener('click', function () {
+                if (!confirm('Delete scenario ' + byId[btn.dataset.id].name + '?')) return;
+                fetch(scenariosURL('/' + btn.dataset.id), { method: 'DELETE' }).then(loadScenarios);
+            });
+        });
+
+        document.querySelectorAll('.scenario-sketch').forEach(function (sel) {
+            sel.addEventListener('change', function () {
+                var sc = byId[sel.dataset.id];
+                fetch(scenariosURL('/' + sc.id), {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({
+                        name: sc.name, notes: sc.notes, assumptions: sc.assumptions,
+                        sketchId: sel.value ? parseInt(sel.value, 10) : null,
+                    }),
+                }).then(scenarioError).then(function (saved) {
+                    byId[sc.id] = saved;
+                    setModelingStatus(sel.value ? 'Attached to sketch' : 'Detached from sketch');
+                }).catch(function (e) {
+                    setModelingStatus(e.message);
+                });
+            });
+        });
+
+        var lastComparison = null;
+        var lineSel = document.getElementById('scenario-line');
+        if (lineSel) lineSel.addEventListener('change', function () {
+            if (lastComparison) renderComparison(lastComparison, lineSel.value);
+        });
+        var compare = document.getElementById('scenario-compare');
+        if (compare) compare.addEventListener('click', function () {
+            var ids = [];
+            document.querySelectorAll('.scenario-pick').forEach(function (cb) {
+                if (cb.checked) ids.push(cb.value);
+            });
+            if (ids.length < 2) { setModelingStatus('Tick at least two scenarios'); return; }
+            setModelingStatus('Projecting…');
+            fetch(scenariosURL('/compare?ids=' + ids.join(',')))
+                .then(scenarioError)
+                .then(function (cmp) {
+                    lastComparison = cmp;
+                    setModelingStatus('');
+                    renderComparison(cmp, lineSel ? lineSel.value : 'revenue');
+                })
+                .catch(function (e) { setModelingStatus(e.message); });
+        });
+    }
+
+    // renderComparison shows one statement line per scenario across the
+    // forecast years, each cell with its difference from the first
+    // scenario, then value per share.
+    function renderComparison(cmp, field) {
+        var el = document.getElementById('scenario-comparison');
+        if (!el) return;
+        var line = (cmp.lines || []).filter(function (l) { return l.field === field; })[0];
+        if (!line) { el.innerHTML = ''; return; }
+        var html = '<table class="fin-table modeling-table modeling-forecast"><thead><tr><th></th>';
+        cmp.years.forEach(function (y, i) {
+            html += '<th class="forecast-col">' + periodLabel({ year: y, quarter: (cmp.quarters || [])[i] }) + '</th>';
+        });
+        html += '<th>Value / Share</th></tr></thead><tbody>';
+        cmp.scenarios.forEach(function (sc, i) {
+            html += '<tr class="fin-row" data-vim-row><td class="fin-label">' + esc(sc.name) + '</td>';
+            line.values[i].forEach(function (v, y) {
+                html += '<td class="forecast-col">' + fmt(v);
+                if (i > 0) html += ' <span class="scenario-delta">' + (line.deltas[i][y] >= 0 ? '+' : '') + fmt(line.deltas[i][y]) + '</span>';
+                html += '</td>';
+            });
+            var vps = sc.dcf ? sc.dcf.valuePerShare.toFixed(2) : '<span class="modeling-unit">' + esc(sc.error || sc.dcfError || '—') + '</span>';
+            if (i > 0 && sc.valueDelta != null) {
+                vps += ' <span class="scenario-delta">' + (sc.valueDelta >= 0 ? '+' : '') + sc.valueDelta.toFixed(2) + '</span>';
+            }
+            html += '<td>' + vps + '</td></tr>';
+        });
+        html += '</tbody></table>';
+        el.innerHTML = html;
+    }
+
+    // ── Monte Carlo ──
+    //
+    // Distributions on drivers, run through the model a few thousand
+    // times server-side. The spec lives beside the assumptions in
+    // localStorage; the point assumptions are the stored ones, so the
+    // cloud centres on whatever the Assumptions tab shows.
+
+    var MC_DISTS = [
+        { key: 'normal',     label: 'Normal',     params: ['mean', 'stdDev'] },
+        { key: 'triangular', label: 'Triangular', params: ['min', 'mode', 'max'] },
+        { key: 'uniform',    label: 'Uniform',    params: ['min', 'max'] },
+    ];
+
+    var MC_FAN_LABELS = { revenue: 'Revenue', netEarnings: 'Net Earnings', cash: 'Cash' };
+
+    function readStoredMC() {
+        try {
+            var raw = localStorage.getItem(modelingStorageKey() + '.mc');
+            return raw ? JSON.parse(raw) : null;
+        } catch (e) {
+            return null;
+        }
+    }
+
+    function writeStoredMC(spec) {
+        try {
+            localStorage.setItem(modelingStorageKey() + '.mc', JSON.stringify(spec));
+        } catch (e) {}
+    }
+
+    function defaultMCSpec() {
+        return {
+            sims: 2000,
+            seed: 1,
+            distributions: [
+                { driver: 'revenueGrowth', dist: 'normal', stdDev: 0.03 },
+                { driver: 'cogsPct', dist: 'triangular', min: -0.02, mode: 0, max: 0.03 },
+                { driver: 'capex', dist: 'uniform', min: -0.2, max: 0.2, relative: true },
+            ],
+            correlations: [],
+        };
+    }
+
+    // mcInPct says whether a distribution's parameters are edited in
+    // percent: relative shifts always, absolute ones on percentage drivers.
+    function mcInPct(d) {
+        if (d.relative) return true;
+        var drv = MODELING_DRIVERS.filter(function (x) { return x.key === d.driver; })[0];
+        return !!(drv && drv.format === 'pct');
+    }
+
+    function loadMonteCarlo() {
+        var tc = document.getElementById('fin-table-container');
+        if (!tc) return;
+        tc.innerHTML = renderMonteCarlo(readStoredMC() || defaultMCSpec());
+        wireMonteCarlo();
+    }
+
+    function renderMonteCarlo(spec) {
+        var html = '<div class="modeling-explainer help-tip' + (helpVisible ? '' : ' hidden') + '">';
+        html += 'Put a distribution on any driver and run the model thousands of times. Each run draws one shift per driver and applies it to every forecast period — e.g. Normal with std dev 3 on Revenue Growth moves the whole growth path up or down by about 3pp — or, ticked Relative, scales it (Uniform −20…20 on Capex is ±20%). Correlations tie draws together. The fan charts show the 5th–95th percentile bands of each line; value per share is the DCF of each run. Every simulated balance sheet ties.';
+        html += '</div>';
+
+        html += '<table class="fin-table modeling-table mc-table"><thead><tr><th>Driver</th><th>Distribution</th><th>Mean / Min</th><th>Std Dev / Mode</th><th>Max</th><th>Relative</th><th></th></tr></thead><tbody>';
+        spec.distributions.forEach(function (d, i) {
+            var pct = mcInPct(d);
+            var show = function (v) { return v == null ? '' : (pct ? +(v * 100).toFixed(4) : v); };
+            var p1 = d.dist === 'normal' ? d.mean : d.min;
+            var p2 = d.dist === 'normal' ? d.stdDev : (d.dist === 'triangular' ? d.mode : null);
+            var p3 = d.dist === 'normal' ? null : d.max;
+            html += '<tr class="fin-row mc-dist" data-idx="' + i + '" data-vim-row>';
+            html += '<td><select class="modeling-shape mc-driver" data-vim-item>';
+            MODELING_DRIVERS.forEach(function (drv) {
+                html += '<option value="' + drv.key + '"' + (drv.key === d.driver ? ' selected' : '') + '>' + drv.label + '</option>';
+            });
+            html += '</select></td><td><select class="modeling-shape mc-kind">';
+            MC_DISTS.forEach(function (k) {
+                html += '<option value="' + k.key + '"' + (k.key === d.dist ? ' selected' : '') + '>' + k.label + '</option>';
+            });
+            html += '</select></td>';
+            html += '<td><input class="modeling-shape mc-p1" type="number" step="any" value="' + show(p1) + '"></td>';
+            html += '<td><input class="modeling-shape mc-p2" type="number" step="any" value="' + show(p2) + '"' + (d.dist === 'uniform' ? ' disabled' : '') + '></td>';
+            html += '<td><input class="modeling-shape mc-p3" type="number" step="any" value="' + show(p3) + '"' + (d.dist === 'normal' ? ' disabled' : '') + '></td>';
+            html += '<td><input type="checkbox" class="mc-relative"' + (d.relative ? ' checked' : '') + '>' + (pct ? ' <span class="modeling-unit">(%)</span>' : '') + '</td>';
+            html += '<td><button type="button" class="modeling-btn mc-remove">×</button></td></tr>';
+        });
+        html += '</tbody></table>';
+
+        html += '<table class="fin-table modeling-table mc-table"><thead><tr><th>Correlated driver</th><th>with</th><th>ρ</th><th></th></tr></thead><tbody>';
+        (spec.correlations || []).forEach(function (c, i) {
+            html += '<tr class="fin-row mc-corr" data-idx="' + i + '" data-vim-row>';
+            ['a', 'b'].forEach(function (side) {
+                html += '<td><select class="modeling-shape mc-corr-' + side + '" data-vim-item>';
+                spec.distributions.forEach(function (d) {
+                    html += '<option value="' + d.driver + '"' + (d.driver === c[side] ? ' selected' : '') + '>' + d.driver + '</option>';
+                });
+                html += '</select></td>';
+            });
+            html += '<td><input class="modeling-shape mc-rho" type="number" step="0.1" min="-1" max="1" value="' + c.rho + '"></td>';
+            html += '<td><button type="button" class="modeling-btn mc-corr-remove">×</button></td></tr>';
+        });
+        html += '</tbody></table>';
+
+        html += '<div class="modeling-actions dcf-inputs" data-vim-row>';
+        html += '<button type="button" id="mc-add" class="modeling-btn" data-vim-item>Add driver</button>';
+        html += '<button type="button" id="mc-add-corr" class="modeling-btn" data-vim-item>Add correlation</button>';
+        html += '<label class="dcf-input">Simulations <input id="mc-sims" class="modeling-shape" type="number" min="1" step="100" value="' + spec.sims + '" data-vim-item></label>';
+        html += '<label class="dcf-input">Seed <input id="mc-seed" class="modeling-shape" type="number" step="1" value="' + (spec.seed || 0) + '" data-vim-item></label>';
+        html += '<button type="button" id="mc-run" class="modeling-btn" data-vim-item>Run</button>';
+        html += '<span class="modeling-status" id="modeling-status"></span>';
+        html += '</div>';
+        html += '<div id="mc-results"></div>';
+        return html;
+    }
+
+    // readMCSpec reads the form back into a spec, converting percent
+    // inputs to fractions.
+    function readMCSpec() {
+        var spec = {
+            sims: parseInt(document.getElementById('mc-sims').value, 10) || 0,
+            seed: parseInt(document.getElementById('mc-seed').value, 10) || 0,
+            distributions: [],
+            correlations: [],
+        };
+        document.querySelectorAll('.mc-dist').forEach(function (row) {
+            var d = {
+                driver: row.querySelector('.mc-driver').value,
+                dist: row.querySelector('.mc-kind').value,
+                relative: row.querySelector('.mc-relative').checked,
+            };
+            var scale = mcInPct(d) ? 0.01 : 1;
+            var num = function (cls) {
+                var v = parseFloat(row.querySelector(cls).value);
+                return isNaN(v) ? 0 : v * scale;
+            };
+            if (d.dist === 'normal') {
+                d.mean = num('.mc-p1');
+                d.stdDev = num('.mc-p2');
+            } else {
+                d.min = num('.mc-p1');
+                d.max = num('.mc-p3');
+                if (d.dist === 'triangular') d.mode = num('.mc-p2');
+            }
+            spec.distributions.push(d);
+        });
+        document.querySelectorAll('.mc-corr').forEach(function (row) {
+            spec.correlations.push({
+                a: row.querySelector('.mc-corr-a').value,
+                b: row.querySelector('.mc-corr-b').value,
+                rho: parseFloat(row.querySelector('.mc-rho').value) || 0,
+            });
+        });
+        return spec;
+    }
+
+    function wireMonteCarlo() {
+        var tc = document.getElementById('fin-table-container');
+        // Structural edits (driver, kind, relative, add/remove) re-render
+        // so the parameter columns and units follow.
+        var rerender = function (mutate) {
+            var spec = readMCSpec();
+            if (mutate) mutate(spec);
+            writeStoredMC(spec);
+            tc.innerHTML = renderMonteCarlo(spec);
+            wireMonteCarlo();
+        };
+        tc.querySelectorAll('.mc-driver, .mc-kind, .mc-relative').forEach(function (el) {
+            el.addEventListener('change', function () { rerender(); });
+        });
+        tc.querySelectorAll('.mc-remove').forEach(function (btn) {
+            btn.addEventListener('click', function () {
+                var i = parseInt(btn.closest('tr').dataset.idx, 10);
+                rerender(function (spec) {
+                    var gone = spec.distributions.splice(i, 1)[0];
+                    spec.correlations = spec.correlations.filter(function (c) {
+                        return c.a !== gone.driver && c.b !== gone.driver;
+                    });
+                });
+            });
+        });
+        tc.querySelectorAll('.mc-corr-remove').forEach(function (btn) {
+            btn.addEventListener('click', function () {
+                var i = parseInt(btn.closest('tr').dataset.idx, 10);
+                rerender(function (spec) { spec.correlations.splice(i, 1); });
+            });
+        });
+        document.getElementById('mc-add').addEventListener('click', function () {
+            rerender(function (spec) {
+                var used = {};
+                spec.distributions.forEach(function (d) { used[d.driver] = true; });
+                var next = MODELING_DRIVERS.filter(function (x) { return !used[x.key]; })[0];
+                if (next) spec.distributions.push({ driver: next.key, dist: 'normal', stdDev: 0 });
+            });
+        });
+        document.getElementById('mc-add-corr').addEventListener('click', function () {
+            rerender(function (spec) {
+                if (spec.distributions.length < 2) return;
+                spec.correlations.push({ a: spec.distributions[0].driver, b: spec.distributions[1].driver, rho: 0 });
+            });
+        });
+        document.getElementById('mc-run').addEventListener('click', runMonteCarlo);
+    }
+
+    function runMonteCarlo() {
+        var spec = readMCSpec();
+        writeStoredMC(spec);
+        var body = JSON.parse(modelingBody(readStoredAssumptions(), readStoredDCF()));
+        body.sims = spec.sims;
+        body.seed = spec.seed;
+        body.distributions = spec.distributions;
+        body.correlations = spec.correlations;
+        setModelingStatus('Simulating…');
+        fetch('/api/security/' + symbol + '/modeling/montecarlo', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body),
+        })
+            .then(scenarioError)
+            .then(function (res) {
+                setModelingStatus(res.sims + ' runs · max balance gap ' + res.maxBsCheck.toExponential(1));
+                renderMCResults(res);
+            })
+            .catch(function (e) { setModelingStatus(e.message); });
+    }
+
+    function renderMCResults(res) {
+        var el = document.getElementById('mc-results');
+        if (!el) return;
+        var pcts = res.percentiles;
+        var html = '';
+        var v = res.valuePerShare;
+        if (v) {
+            html += '<h4 class="modeling-subhead">Value per share</h4>';
+            html += '<table class="fin-table modeling-table"><thead><tr>';
+            pcts.forEach(function (p) { html += '<th>P' + p + '</th>'; });
+            html += '<th>Mean</th><th>Point</th><th>P(&gt; price)</th></tr></thead><tbody><tr class="fin-row" data-vim-row>';
+            v.values.forEach(function (x) { html += '<td>' + x.toFixed(2) + '</td>'; });
+            html += '<td>' + v.mean.toFixed(2) + '</td>';
+            html += '<td>' + (v.base != null ? v.base.toFixed(2) : '—') + '</td>';
+            html += '<td>' + (v.probAbovePrice != null ? (v.probAbovePrice * 100).toFixed(0) + '% vs ' + v.price.toFixed(2) : '—') + '</td>';
+            html += '</tr></tbody></table>';
+            if (v.valued < res.sims) {
+                html += '<p class="modeling-unit">' + (res.sims - v.valued) + ' runs could not be valued (e.g. terminal growth above WACC).</p>';
+            }
+        }
+        var labels = res.years.map(function (y, i) {
+            return periodLabel({ year: y, quarter: (res.quarters || [])[i] });
+        });
+        res.fans.forEach(function (fan) {
+            html += '<h4 class="modeling-subhead">' + (MC_FAN_LABELS[fan.field] || fan.field) + '</h4>';
+            html += fanChartSVG(fan, labels);
+        });
+        el.innerHTML = html;
+    }
+
+    // fanChartSVG draws the outer (P5–P95), middle (P10–P90) and inner
+    // (P25–P75) bands with the median line. Percentile columns follow
+    // the server's [5, 10, 25, 50, 75, 90, 95].
+    function fanChartSVG(fan, labels) {
+        var W = 640, H = 180, L = 56, R = 8, T = 8, B = 20;
+        var lo = Infinity, hi = -Infinity;
+        fan.values.forEach(function (row) {
+            lo = Math.min(lo, row[0]);
+            hi = Math.max(hi, row[row.length - 1]);
+        });
+        if (!isFinite(lo) || !isFinite(hi)) return '';
+        if (hi === lo) { hi += 1; lo -= 1; }
+        var n = fan.values.length;
+        var x = function (i) { return L + (n === 1 ? (W - L - R) / 2 : i * (W - L - R) / (n - 1)); };
+        var y = function (v) { return T + (hi - v) * (H - T - B) / (hi - lo); };
+        var band = function (a, b, cls) {
+            var pts = [];
+            fan.values.forEach(function (row, i) { pts.push(x(i) + ',' + y(row[b])); });
+            for (var i = n - 1; i >= 0; i--) pts.push(x(i) + ',' + y(fan.values[i][a]));
+            return '<polygon class="' + cls + '" points="' + pts.join(' ') + '"/>';
+        };
+        var svg = '<svg class="mc-fan" viewBox="0 0 ' + W + ' ' + H + '" preserveAspectRatio="none">';
+        svg += band(0, 6, 'mc-band-outer') + band(1, 5, 'mc-band-mid') + band(2, 4, 'mc-band-inner');
+        svg += '<polyline class="mc-median" points="' + fan.values.map(function (row, i) { return x(i) + ',' + y(row[3]); }).join(' ') + '"/>';
+        [hi, (hi + lo) / 2, lo].forEach(function (v) {
+            svg += '<text class="mc-axis" x="' + (L - 4) + '" y="' + (y(v) + 3) + '" text-anchor="end">' + fmtAxisValue(v) + '</text>';
+        });
+        labels.forEach(function (lb, i) {
+            svg += '<text class="mc-axis" x="' + x(i) + '" y="' + (H - 4) + '" text-anchor="middle">' + esc(lb) + '</text>';
+        });
+        svg += '</svg>';
+        return svg;
+    }
+
     // ── Estimates ──
 
     function loadEstimates() {
@@ -1415,6 +2246,10 @@
                 html += '</tbody></table>';
             }
 
+            // Multi-factor exposure — filled in asynchronously
+            html += '<div class="sector-section-title st-section-title">Factor Exposure (1y daily)</div>';
+            html += '<div id="sector-factors" class="sector-factors"><p class="empty-state">Loading...</p></div>';
+
             // Sector news
             html += '<div class="sector-section-title">Sector News</div>';
             html += '<div id="sector-news" class="sector-news"><p class="empty-state">Loading...</p></div>';
@@ -1432,6 +2267,8 @@
             // Hover emphasis on peer rows
             wireSectorPeerHover();
 
+            loadFactorExposure();
+
             // Load sector news
             loadSectorNews(peers.slice(0, 5));
 
@@ -1441,6 +2278,53 @@
         });
     }
 
+    // Factor loadings from /factors: one row per factor with its t-stat
+    // and share of the window's return, then fit statistics. Each loading
+    // is also a sketch metric (SYMBOL.sizeLoading, …) for the rolling view.
+    function loadFactorExposure() {
+        var el = document.getElementById('sector-factors');
+        if (!el) return;
+        fetch('/api/security/' + symbol + '/factors')
+            .then(function (r) {
+                if (!r.ok) return r.text().then(function (t) { throw new Error(t || 'Factor regression failed'); });
+                return r.json();
+            })
+            .then(function (reg) {
+                function signed(v, digits) {
+                    var cls = v > 0 ? 'price-up' : v < 0 ? 'price-down' : '';
+                    return '<span class="' + cls + '">' + (v > 0 ? '+' : '') + v.toFixed(digits) + '</span>';
+                }
+                var html = '<table class="fin-table factor-table st-table"><thead><tr>'
+                    + '<th>Factor</th><th>Proxy</th><th>Loading</th><th>t-stat</th><th>Return contribution</th><th>Sketch</th>'
+                    + '</tr></thead><tbody>';
+                (reg.loadings || []).forEach(function (l) {
+                    var sig = Math.abs(l.tStat) >= 2;
+                    html += '<tr><td>' + esc(l.label) + '</td>'
+                        + '<td>' + esc(l.long) + (l.short ? ' − ' + esc(l.short) : '') + '</td>'
+                        + '<td' + (sig ? ' class="factor-significant"' : '') + '>' + l.beta.toFixed(2) + '</td>'
+                        + '<td>' + l.tStat.toFixed(1) + '</td>'
+                        + '<td>' + signed(l.contribution * 100, 1) + '%</td>'
+                        + '<td class="factor-sketch">' + esc(symbol + '.' + l.key + 'Loading') + '</td></tr>';
+                });
+                html += '<tr class="factor-specific"><td>Alpha</td><td>—</td>'
+                    + '<td>' + signed(reg.alpha * 100, 1) + '%/yr</td>'
+                    + '<td>' + reg.alphaTStat.toFixed(1) + '</td>'
+                    + '<td>' + signed(reg.specific * 100, 1) + '%</td>'
+                    + '<td class="factor-sketch">' + esc(symbol + '.factorAlpha') + '</td></tr>';
+                html += '</tbody></table>';
+                html += '<div class="factor-fit">'
+                    + 'R² ' + reg.r2.toFixed(2) + ' (adj ' + reg.adjR2.toFixed(2) + ')'
+                    + ' · residual vol ' + (reg.residualVol * 100).toFixed(1) + '%'
+                    + ' · summed return ' + (reg.totalReturn * 100).toFixed(1) + '%'
+                    + ' · ' + esc(reg.from) + ' → ' + esc(reg.to) + ' (' + reg.n + ' days)'
+                    + '</div>';
+                el.innerHTML = html;
+            })
+            .catch(function (e) {
+                el.innerHTML = '<p class="empty-state">' + esc(e.message) + '</p>';
+            });
+    }
+
     // Peer sparks reuse the shared mini-spark helper + canonical interval set
     // so they render the same 3 ranges (2d / 6M / 1y), the same green/red
     // series colour, and the same inset white-glow badge as everywhere else.
@@ -1538,6 +2422,169 @@
             .catch(function () { newsEl.innerHTML = '<p class="empty-state">Failed to load</p>'; });
     }
 
+    // ── Comparable Companies ──
+    //
+    // Peer multiples and margins side by side, with the peer quartiles
+    // and the prices they imply for this security. The peer list, the
+    // excluded peers and the outlier toggle persist per symbol; a blank
+    // peer list means FMP's peer set.
+
+    function compsStorageKey() {
+        return 'stocktopus.comps.' + symbol;
+    }
+
+    function readStoredComps() {
+        try {
+            var raw = localStorage.getItem(compsStorageKey());
+            return raw ? JSON.parse(raw) : { peers: '', exclude: [], outliers: false };
+        } catch (e) {
+            return { peers: '', exclude: [], outliers: false };
+        }
+    }
+
+    function writeStoredComps(c) {
+        try {
+            localStorage.setItem(compsStorageKey(), JSON.stringify(c));
+        } catch (e) {}
+    }
+
+    function compsQuery(c) {
+        var q = [];
+        if (c.peers) q.push('peers=' + encodeURIComponent(c.peers));
+        if (c.exclude && c.exclude.length) q.push('exclude=' + encodeURIComponent(c.exclude.join(',')));
+        if (c.outliers) q.push('outliers=iqr');
+        return q.length ? '?' + q.join('&') : '';
+    }
+
+    function compsCell(m, v) {
+        if (v == null || isNaN(v)) return '—';
+        if (m.percent) return (v * 100).toFixed(1) + '%';
+        return v.toFixed(1) + '×';
+    }
+
+    function compsValue(c, m) {
+        var v = c.metrics ? c.metrics[m.key] : null;
+        if (v == null || (m.multiple && v <= 0)) return null;
+        return v;
+    }
+
+    function loadComps() {
+        var state = readStoredComps();
+        container.innerHTML = '<p class="empty-state">Loading comparable companies...</p>';
+        fetch('/api/security/' + symbol + '/comps' + compsQuery(state))
+            .then(function (r) {
+                if (!r.ok) return r.text().then(function (t) { throw new Error(t || 'Comps failed'); });
+                return r.json();
+            })
+            .then(function (t) {
+                container.innerHTML = renderComps(t, state);
+                wireComps(state);
+            })
+            .catch(function (e) {
+                container.innerHTML = '<p class="empty-state">' + esc(e.message) + '</p>';
+            });
+    }
+
+    function renderComps(t, state) {
+        var metrics = t.metrics || [];
+        var html = '<div class="comps-view">';
+        html += '<div class="comps-toolbar">'
+            + '<label>Peers <input type="text" id="comps-peers" class="comps-peers" placeholder="FMP peer set" value="' + esc(state.peers || '') + '"></label>'
+            + '<button class="st-btn" id="comps-apply">Apply</button>'
+            + '<button class="st-btn" id="comps-reset">Reset</button>'
+            + '<label class="comps-toggle"><input type="checkbox" id="comps-outliers"' + (state.outliers ? ' checked' : '') + '> Exclude outliers (1.5×IQR)</label>'
+            + '<button class="st-btn" id="comps-csv">Download CSV</button>'
+            + '<span class="comps-asof">Latest fiscal year · ' + esc(t.asOf || '') + '</span>'
+            + '</div>';
+
+        html += '<table class="fin-table comps-table st-table"><thead><tr>'
+            + '<th></th><th>Security</th><th>Price</th><th>Market Cap</th>';
+        metrics.forEach(function (m) { html += '<th>' + esc(m.label) + '</th>'; });
+        html += '</tr></thead><tbody>';
+
+        function row(c, subject) {
+            var outliers = c.outliers || [];
+            var cls = 'comps-row' + (subject ? ' peer-current' : '') + (c.excluded ? ' comps-excluded' : '');
+            var h = '<tr class="' + cls + '" data-vim-row data-vim-action="navigate" data-vim-href="/security/' + encodeURIComponent(c.symbol) + '">';
+            h += '<td>' + (subject ? '' : '<input type="checkbox" class="comps-include" data-symbol="' + esc(c.symbol) + '"' + (c.excluded ? '' : ' checked') + ' title="Include in peer statistics">') + '</td>';
+            h += '<td><span class="sym-link st-link-sym">' + esc(c.symbol) + '</span>'
+                + (c.name ? ' <span class="comps-name">' + esc(c.name) + '</span>' : '')
+                + (c.error ? ' <span class="comps-error" title="' + esc(c.error) + '">!</span>' : '') + '</td>';
+            h += '<td>' + (c.price ? c.price.toFixed(2) : '—') + '</td><td>' + fmt(c.marketCap) + '</td>';
+            metrics.forEach(function (m) {
+                var out = outliers.indexOf(m.key) >= 0;
+                h += '<td' + (out ? ' class="comps-outlier" title="Outside 1.5×IQR — left out of the statistics"' : '') + '>' + compsCell(m, compsValue(c, m)) + '</td>';
+            });
+            return h + '</tr>';
+        }
+        html += row(t.subject, true);
+        (t.peers || []).forEach(function (p) { html += row(p, false); });
+        html += '</tbody><tfoot>';
+
+        var stats = t.stats || [];
+        [['Median', 'median'], ['Mean', 'mean'], ['Q1', 'q1'], ['Q3', 'q3']].forEach(function (s) {
+            html += '<tr class="comps-stat"><td></td><td>Peer ' + s[0] + '</td><td></td><td></td>';
+            stats.forEach(function (st) { html += '<td>' + (st.n ? compsCell(st, st[s[1]]) : '—') + '</td>'; });
+            html += '</tr>';
+        });
+        html += '<tr class="comps-stat"><td></td><td>' + esc(symbol) + ' percentile</td><td></td><td></td>';
+        stats.forEach(function (st) { html += '<td>' + (st.rank != null ? st.rank.toFixed(0) : '—') + '</td>'; });
+        html += '</tr><tr class="comps-stat"><td></td><td>' + esc(symbol) + ' vs median</td><td></td><td></td>';
+        stats.forEach(function (st) {
+            var p = st.premium;
+            html += '<td class="' + (p > 0 ? 'price-up' : p < 0 ? 'price-down' : '') + '">' + (p != null ? (p > 0 ? '+' : '') + (p * 100).toFixed(0) + '%' : '—') + '</td>';
+        });
+        html += '</tr></tfoot></table>';
+
+        var implied = t.implied || [];
+        if (implied.length) {
+            html += '<div class="sector-section-title st-section-title">Implied Share Price</div>';
+            html += '<table class="fin-table comps-implied st-table"><thead><tr>'
+                + '<th>Multiple</th><th>Q1</th><th>Median</th><th>Mean</th><th>Q3</th><th>vs ' + (t.subject.price ? t.subject.price.toFixed(2) : '—') + '</th>'
+                + '</tr></thead><tbody>';
+            implied.forEach(function (imp) {
+                html += '<tr><td>' + esc(imp.label) + '</td>'
+                    + '<td>' + imp.q1.toFixed(2) + '</td><td>' + imp.median.toFixed(2) + '</td>'
+                    + '<td>' + imp.mean.toFixed(2) + '</td><td>' + imp.q3.toFixed(2) + '</td>'
+                    + '<td class="' + (imp.upside >= 0 ? 'price-up' : 'price-down') + '">' + (imp.upside >= 0 ? '+' : '') + (imp.upside * 100).toFixed(1) + '%</td></tr>';
+            });
+            html += '</tbody></table>';
+        }
+        return html + '</div>';
+    }
+
+    function wireComps(state) {
+        function reload() {
+            writeStoredComps(state);
+            loadComps();
+        }
+        document.getElementById('comps-apply').addEventListener('click', function () {
+            state.peers = document.getElementById('comps-peers').value.toUpperCase().replace(/\s+/g, '');
+            reload();
+        });
+        document.getElementById('comps-reset').addEventListener('click', function () {
+            state = { peers: '', exclude: [], outliers: false };
+            reload();
+        });
+        document.getElementById('comps-outliers').addEventListener('change', function (e) {
+            state.outliers = e.target.checked;
+            reload();
+        });
+        document.getElementById('comps-csv').addEventListener('click', function () {
+            var q = compsQuery(state);
+            location.href = '/api/security/' + symbol + '/comps' + q + (q ? '&' : '?') + 'format=csv';
+        });
+        container.querySelectorAll('.comps-include').forEach(function (cb) {
+            cb.addEventListener('click', function (e) { e.stopPropagation(); });
+            cb.addEventListener('change', function () {
+                var sym = cb.dataset.symbol;
+                state.exclude = (state.exclude || []).filter(function (s) { return s !== sym; });
+                if (!cb.checked) state.exclude.push(sym);
+                reload();
+            });
+        });
+    }
+
     // ── SEC Filings ──
 
     var secFormTypes = null; // cached form type reference data
@@ -2491,7 +3538,7 @@
     if (hash) {
         var parts = hash.split('-');
         var mainTab = parts[0];
-        if (['overview', 'financials', 'estimates', 'news', 'ai', 'sector', 'sec'].indexOf(mainTab) >= 0) {
+        if (['overview', 'financials', 'estimates', 'news', 'ai', 'sector', 'comps', 'sec'].indexOf(mainTab) >= 0) {
             initTab = mainTab;
             if (parts.length > 1) initSubTab = parts.slice(1).join('-');
         }
@@ -66,10 +66,40 @@
         lastResults = [];
     });
 
-    async function runScreen() {
+    // Save the current filters as a dynamic watchlist: the server re-runs
+    // them on a schedule and the list's members follow the results.
+    $('screener-save').addEventListener('click', async () => {
+        const params = screenParams();
+        if (![...params.keys()].length) {
+            meta.textContent = 'set at least one filter to save';
+            return;
+        }
+        const name = (window.prompt('Watchlist name for this screen') || '').trim();
+        if (!name) return;
+        let res;
+        try {
+            res = await fetch('/api/watchlists', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ name, query: params.toString() }),
+            });
+        } catch (err) {
+            meta.textContent = `network error: ${err.message}`;
+            return;
+        }
+        const body = await res.json().catch(() => ({}));
+        meta.textContent = res.ok ? `saved as watchlist "${name}"` : (body.error || `HTTP ${res.status}`);
+    });
+
+    // Form fields that aren't /api/screener params: the local expression
+    // screen and the saved-screen settings.
+    const nonQueryFields = new Set(['localFilter', 'localRank', 'localFactors', 'screenName', 'screenSchedule', 'screenWebhook']);
+
+    function screenParams() {
         const params = new URLSearchParams();
         const fd = new FormData(form);
         for (const [k, v] of fd.entries()) {
+            if (nonQueryFields.has(k)) continue;
             let trimmed = String(v).trim();
             if (trimmed === '') continue;
             // Market-cap fields accept shorthand (1bn, 250m, …); convert
@@ -79,6 +109,11 @@
             }
             if (trimmed !== '') params.set(k, trimmed);
         }
+        return params;
+    }
+
+    async function runScreen() {
+        const params = screenParams();
 
         meta.textContent = 'running…';
         tbody.innerHTML = '<tr><td colspan="10" class="empty-state">Loading…</td></tr>';
@@ -104,7 +139,222 @@
         renderRows();
     }
 
+    // ── Local screen over the universe snapshot ──
+    //
+    // Results land in a second table: its columns depend on the rank and
+    // factors asked for, and the server has already ordered the rows.
+    const localTable = $('screener-local-table');
+
+    async function loadLocalStatus() {
+        try {
+            const res = await fetch('/api/screener/local');
+            if (!res.ok) return;
+            const st = await res.json();
+            $('screener-local-status').textContent = st.symbols
+                ? `${st.symbols} symbols · ${new Date(st.asOf).toLocaleDateString()}`
+                : 'snapshot building…';
+        } catch (err) { /* status is decoration */ }
+    }
+    loadLocalStatus();
+
+    function localQuery() {
+        const factors = String(form.elements['localFactors'].value || '').split('\n')
+            .map((line) => line.trim()).filter(Boolean)
+            .map((line) => {
+                const parts = line.split(':').map((p) => p.trim());
+                if (parts.length === 1) return { name: parts[0], expr: parts[0], weight: 1 };
+                const weight = parts.length > 2 ? parseFloat(parts[parts.length - 1]) : 1;
+                const expr = parts.slice(1, parts.length > 2 ? -1 : undefined).join(':');
+                return { name: parts[0], expr, weight: isNaN(weight) ? 1 : weight };
+            });
+        return {
+            filter: form.elements['localFilter'].value.trim(),
+            rank: form.elements['localRank'].value.trim(),
+            factors,
+            limit: parseInt(form.elements['displayLimit'].value, 10) || 50,
+        };
+    }
+
+    $('screener-run-local').addEventListener('click', async () => {
+        const q = localQuery();
+        meta.textContent = 'running on snapshot…';
+        const t0 = performance.now();
+        let res, body;
+        try {
+            res = await fetch('/api/screener/local', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(q),
+            });
+            body = await res.json().catch(() => ({}));
+        } catch (err) {
+            meta.textContent = `network error: ${err.message}`;
+            return;
+        }
+        if (!res.ok) {
+            meta.textContent = body.error || `HTTP ${res.status}`;
+            return;
+        }
+        const elapsed = (performance.now() - t0) / 1000;
+        meta.textContent = `${body.matched} of ${body.universe} · showing ${body.results.length} · ${elapsed.toFixed(2)}s`;
+        renderLocalRows(q, body.results);
+    });
+
+    function renderLocalRows(q, results) {
+        $('screener-table').hidden = true;
+        localTable.hidden = false;
+        historyPane.hidden = true;
+        const heads = ['Security', 'Company', 'Sector', 'Price'];
+        if (q.rank) heads.push(q.rank);
+        if (q.factors.length) heads.push('Score', ...q.factors.map((f) => f.name));
+        localTable.querySelector('thead tr').innerHTML = heads.map((h, i) =>
+            `<th${i >= 3 ? ' class="num"' : ''}>${escape(h)}</th>`).join('');
+        const body = localTable.querySelector('tbody');
+        if (!results.length) {
+            body.innerHTML = `<tr><td colspan="${heads.length}" class="empty-state">No matches. Loosen the filter.</td></tr>`;
+            return;
+        }
+        const cell = (v) => `<td class="num">${v == null ? '—' : fmt(v)}</td>`;
+        body.innerHTML = results.map((r) => `<tr>
+                <td><a href="/security/${encodeURIComponent(r.symbol)}">${escape(r.symbol)}</a></td>
+                <td title="${escape(r.name)}">${truncate(escape(r.name), 28)}</td>
+                <td>${escape(r.sector || '')}</td>
+                ${cell(r.close)}
+                ${q.rank ? cell(r.rank) : ''}
+                ${q.factors.length ? cell(r.score) + q.factors.map((f) => cell((r.factors || {})[f.name])).join('') : ''}
+            </tr>`).join('');
+    }
+
+    // ── Saved screens ──
+    //
+    // A saved screen is the current filters plus the table's sort column,
+    // rerun on a cron schedule. History shows each run's diff against the
+    // one before.
+    const savedList = $('screener-saved-list');
+    const historyPane = $('screener-history');
+    const rankableKeys = new Set(['price', 'changeFromOpen', 'changeFromPrevDay', 'changeVsMarket', 'volume', 'marketCap', 'beta']);
+    let savedScreens = [];
+
+    async function loadSavedScreens() {
+        try {
+            const res = await fetch('/api/screens');
+            savedScreens = res.ok ? await res.json() : [];
+        } catch (err) {
+            savedScreens = [];
+        }
+        if (!savedScreens.length) {
+            savedList.innerHTML = '';
+            return;
+        }
+        savedList.innerHTML = savedScreens.map((sc) => `<li data-id="${sc.id}">
+                <span class="saved-name" title="${escape(sc.query)}">${escape(sc.name)}</span>
+                <span class="saved-schedule">${escape(sc.schedule || 'on demand')}</span>
+                <button type="button" data-action="run">run</button>
+                <button type="button" data-action="history">history</button>
+                <button type="button" data-action="delete">×</button>
+            </li>`).join('');
+    }
+    loadSavedScreens();
+
+    $('screener-save-screen').addEventListener('click', async () => {
+        const params = screenParams();
+        if (![...params.keys()].length) {
+            meta.textContent = 'set at least one filter to save';
+            return;
+        }
+        const name = form.elements['screenName'].value.trim();
+        if (!name) {
+            meta.textContent = 'name the screen to save it';
+            return;
+        }
+        const body = {
+            name,
+            query: params.toString(),
+            sortBy: rankableKeys.has(sortKey) ? sortKey : '',
+            descending: sortDir < 0,
+            schedule: form.elements['screenSchedule'].value.trim(),
+            webhookUrl: form.elements['screenWebhook'].value.trim(),
+        };
+        let res, out;
+        try {
+            res = await fetch('/api/screens', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(body),
+            });
+            out = await res.json().catch(() => ({}));
+        } catch (err) {
+            meta.textContent = `network error: ${err.message}`;
+            return;
+        }
+        meta.textContent = res.ok ? `saved screen "${name}"` : (out.error || `HTTP ${res.status}`);
+        if (res.ok) loadSavedScreens();
+    });
+
+    savedList.addEventListener('click', async (e) => {
+        const btn = e.target.closest('button[data-action]');
+        if (!btn) return;
+        const id = btn.closest('li').dataset.id;
+        const sc = savedScreens.find((x) => String(x.id) === id);
+        if (!sc) return;
+        switch (btn.dataset.action) {
+            case 'run': {
+                meta.textContent = `running "${sc.name}"…`;
+                const res = await fetch(`/api/screens/${id}/run`, { method: 'POST' });
+                const out = await res.json().catch(() => ({}));
+                meta.textContent = res.ok ? `"${sc.name}": ${out.results.length} results` : (out.error || `HTTP ${res.status}`);
+                if (res.ok) showHistory(sc);
+                break;
+            }
+            case 'history':
+                showHistory(sc);
+                break;
+            case 'delete':
+                if (!window.confirm(`Delete saved screen "${sc.name}" and its history?`)) return;
+                await fetch(`/api/screens/${id}`, { method: 'DELETE' });
+                loadSavedScreens();
+                break;
+        }
+    });
+
+    async function showHistory(sc) {
+        $('screener-table').hidden = true;
+        localTable.hidden = true;
+        historyPane.hidden = false;
+        historyPane.innerHTML = `<h3>${escape(sc.name)} — history</h3><div class="empty-state">Loading…</div>`;
+        let runs = [];
+        try {
+            const res = await fetch(`/api/screens/${sc.id}/runs`);
+            if (res.ok) runs = await res.json();
+        } catch (err) { /* shown as empty */ }
+        const rows = runs.map((run) => {
+            const when = new Date(run.ranAt).toLocaleString();
+            if (run.error) {
+                return `<details><summary>${escape(when)}<span class="run-meta run-error">${escape(run.error)}</span></summary></details>`;
+            }
+            const d = run.diff;
+            const summary = d
+                ? `+${d.entrants.length} −${d.dropouts.length} ↕${d.moves.length}`
+                : 'first run';
+            const diffLines = d ? [
+                d.entrants.length ? `<div><span class="price-up">new</span> ${d.entrants.map((r) => `${escape(r.symbol)} (#${r.rank})`).join(', ')}</div>` : '',
+                d.dropouts.length ? `<div><span class="price-down">out</span> ${d.dropouts.map((r) => `${escape(r.symbol)} (was #${r.rank})`).join(', ')}</div>` : '',
+                d.moves.length ? `<div>moved ${d.moves.map((m) => `${escape(m.symbol)} ${m.from}→${m.to}`).join(', ')}</div>` : '',
+            ].join('') : '';
+            return `<details>
+                <summary>${escape(when)}<span class="run-meta">${run.results.length} results · ${summary}</span></summary>
+                <div class="run-diff">${diffLines}</div>
+                <ol>${run.results.map((r) => `<li><a href="/security/${encodeURIComponent(r.symbol)}">${escape(r.symbol)}</a>${r.value == null ? '' : ' ' + fmt(r.value)}</li>`).join('')}</ol>
+            </details>`;
+        });
+        historyPane.innerHTML = `<h3>${escape(sc.name)} — history</h3>` +
+            (rows.length ? rows.join('') : '<div class="empty-state">No runs yet.</div>');
+    }
+
     function renderRows() {
+        $('screener-table').hidden = false;
+        localTable.hidden = true;
+        historyPane.hidden = true;
         if (!lastResults.length) {
             tbody.innerHTML = '<tr><td colspan="10" class="empty-state">No matches. Loosen a filter.</td></tr>';
             return;
@@ -117,7 +367,7 @@
                 <td><a href="/security/${encodeURIComponent(r.symbol)}">${escape(r.symbol)}</a></td>
                 <td title="${escape(r.companyName)}">${truncate(escape(r.companyName), 28)}</td>
                 <td>${escape(r.sector || '')}</td>
-                <td class="num">${fmt(r.price)}</td>
+                <td class="num${r.flags ? ' quote-flagged' : ''}"${r.flags ? ` title="Suspect quote: ${escape(r.flags.join(', '))}"` : ''}>${fmt(r.price)}</td>
                 <td class="num ${sign(r.changeFromOpen)}">${pct(r.changeFromOpen)}</td>
                 <td class="num ${sign(r.changeFromPrevDay)}">${pct(r.changeFromPrevDay)}</td>
                 <td class="num ${sign(r.changeVsMarket)}">${pct(r.changeVsMarket)}</td>
@@ -282,6 +282,9 @@ window.onerror = function (msg, src, line, col, err) {
             if (newsCurrentTopic) {
                 ws.send(JSON.stringify({ type: 'subscribe', topic: newsCurrentTopic }));
             }
+            // Dynamic (screener-backed) watchlists announce who joined and
+            // left so quote subscriptions can follow them.
+            ws.send(JSON.stringify({ type: 'subscribe', topic: 'watchlists' }));
         };
 
         ws.onclose = function () {
@@ -298,6 +301,8 @@ window.onerror = function (msg, src, line, col, err) {
                 handleQuoteHTML(msg.html);
             } else if (msg.type === 'news_update' && msg.payload) {
                 handleNewsUpdate(msg.topic, msg.payload);
+            } else if (msg.type === 'watchlist_membership' && msg.payload) {
+                handleWatchlistMembership(msg.payload);
             }
         };
     }
@@ -401,6 +406,8 @@ window.onerror = function (msg, src, line, col, err) {
         if (form) initWatchlistAddForm(form);
 
         initWatchlistColumnCycles();
+        initWatchlistTransfer();
+        initWatchlistComputedColumns();
         watchlistPaneFocus = 'main';
         highlightWatchlistPane();
         renderWatchlistTabs();
@@ -409,6 +416,232 @@ window.onerror = function (msg, src, line, col, err) {
         fetchWatchlistQuotes();
     }
 
+    // ── Watchlist import / export ──
+    //
+    // Imports run twice: a dry run that resolves every ticker and reports
+    // the ones needing a decision, then the real import with the user's
+    // picks passed back as resolve=INPUT=SYMBOL.
+    function initWatchlistTransfer() {
+        var btn = document.getElementById('wl-import-btn');
+        var file = document.getElementById('wl-import-file');
+        var fmt = document.getElementById('wl-export-format');
+        var link = document.getElementById('wl-export-link');
+        if (!btn || !file || !fmt || !link) return;
+        fmt.onchange = function () {
+            link.href = '/api/watchlists/export?format=' + encodeURIComponent(fmt.value);
+        };
+        btn.onclick = function () { file.value = ''; file.click(); };
+        file.onchange = function () {
+            if (!file.files || !file.files[0]) return;
+            file.files[0].text().then(function (text) {
+                runWatchlistImport(text, {}, true);
+            });
+        };
+    }
+
+    function runWatchlistImport(text, picks, dryRun) {
+        var params = new URLSearchParams();
+        params.set('watchlist', getActiveWatchlistId());
+        if (dryRun) params.set('dryRun', '1');
+        Object.keys(picks).forEach(function (input) {
+            params.append('resolve', input + '=' + picks[input]);
+        });
+        fetch('/api/watchlists/import?' + params.toString(), { method: 'POST', body: text })
+            .then(function (r) { return r.json(); })
+            .then(function (rep) {
+                if (rep.error) { flashError('Import: ' + rep.error); return; }
+                if (dryRun) {
+                    renderWatchlistImportReport(text, rep);
+                    return;
+                }
+                hideWatchlistImportReport();
+                var added = rep.lists.reduce(function (n, l) { return n + l.added.length; }, 0);
+                var failed = rep.lists.filter(function (l) { return l.error; });
+                flashError('Imported ' + added + ' securities' + (failed.length ? ' — ' + failed[0].error : ''));
+                loadWatchlists().then(refreshWatchlistView);
+            })
+            .catch(function () { flashError('Import failed — server unreachable'); });
+    }
+
+    function renderWatchlistImportReport(text, rep) {
+        var el = document.getElementById('wl-import-report');
+        if (!el) return;
+        var html = '<h4>Import preview</h4>';
+        rep.lists.forEach(function (l) {
+            html += '<div class="wl-import-row"><span>' + escapeHtml(l.name || 'active list') + (l.created ? ' (new)' : '') + '</span>'
+                + '<span>' + (l.error ? escapeHtml(l.error) : '+' + l.added.length + ' · ' + l.existing.length + ' already there') + '</span></div>';
+        });
+        if (rep.ambiguous.length) {
+            html += '<h4>Which one?</h4>';
+            rep.ambiguous.forEach(function (a) {
+                html += '<div class="wl-import-row"><span>' + escapeHtml(a.input) + '</span><select data-input="' + escapeHtml(a.input) + '">'
+                    + a.candidates.map(function (c) {
+                        return '<option value="' + escapeHtml(c.symbol) + '">' + escapeHtml(c.symbol + ' ' + (c.name || '')) + '</option>';
+                    }).join('')
+                    + '<option value="">skip</option></select></div>';
+            });
+        }
+        if (rep.unresolved.length) {
+            html += '<h4>Not found — type a ticker or leave blank to skip</h4>';
+            rep.unresolved.forEach(function (u) {
+                html += '<div class="wl-import-row"><span>' + escapeHtml(u.input) + '</span><input type="text" spellcheck="false" data-input="' + escapeHtml(u.input) + '"></div>';
+            });
+        }
+        html += '<div class="wl-import-actions"><button type="button" id="wl-import-confirm">import</button><button type="button" id="wl-import-cancel">cancel</button></div>';
+        el.innerHTML = html;
+        el.classList.remove('hidden');
+        document.getElementById('wl-import-cancel').onclick = hideWatchlistImportReport;
+        document.getElementById('wl-import-confirm').onclick = function () {
+            var picks = {};
+            el.querySelectorAll('[data-input]').forEach(function (f) {
+                picks[f.dataset.input] = f.value.trim().toUpperCase();
+            });
+            runWatchlistImport(text, picks, false);
+        };
+    }
+
+    function hideWatchlistImportReport() {
+        var el = document.getElementById('wl-import-report');
+        if (el) { el.classList.add('hidden'); el.innerHTML = ''; }
+    }
+
+    // ── Watchlist computed columns ──
+    //
+    // Each list saves its own column specs (rsi(14), sma(200), ...). The
+    // server computes them from daily bars and does the sorting and
+    // filtering; the page appends one cell per column to the rows it
+    // already has, reorders them to match, and hides the rows filtered out.
+    var watchlistTableSort = { key: '', desc: false };
+    var watchlistTableSeq = 0;
+
+    function initWatchlistComputedColumns() {
+        var form = document.getElementById('wl-columns-form');
+        var colsInput = document.getElementById('wl-columns-input');
+        var filterInput = document.getElementById('wl-filter-input');
+        if (!form || !colsInput || !filterInput) return;
+        // Two text fields and no submit button: Enter doesn't submit the
+        // form on its own, so each field handles it.
+        form.onsubmit = function (e) { e.preventDefault(); };
+        colsInput.onkeydown = function (e) {
+            if (e.key === 'Enter') { e.preventDefault(); saveWatchlistColumns(colsInput.value); }
+        };
+        filterInput.onkeydown = function (e) {
+            if (e.key === 'Enter') { e.preventDefault(); filterWatchlistView(); }
+        };
+    }
+
+    function saveWatchlistColumns(text) {
+        var id = getActiveWatchlistId();
+        var specs = (text.match(/[A-Za-z0-9]+(\([^)]*\))?/g) || []);
+        fetch('/api/watchlists/' + id + '/columns', {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ columns: specs })
+        })
+            .then(function (r) { return r.json(); })
+            .then(function (wl) {
+                if (wl.error) { flashError('Columns: ' + wl.error); return; }
+                watchlistData.forEach(function (l) {
+                    if (l.id === wl.id) l.columns = wl.columns || [];
+                });
+                renderWatchlistComputedColumns();
+            })
+            .catch(function () { flashError('Columns: server unreachable'); });
+    }
+
+    function watchlistFilters() {
+        var input = document.getElementById('wl-filter-input');
+        if (!input) return [];
+        return input.value.split(',').map(function (f) { return f.trim(); })
+            .filter(function (f) { return f !== ''; });
+    }
+
+    function renderWatchlistComputedColumns() {
+        var tbody = document.getElementById('quote-body');
+        var headRow = document.querySelector('.quote-table thead tr');
+        if (!tbody || !headRow) return;
+        var activeWl = watchlistData.find(function (wl) { return wl.id === activeWatchlistId; });
+        var cols = activeWl && activeWl.columns ? activeWl.columns : [];
+        var colsInput = document.getElementById('wl-columns-input');
+        if (colsInput && document.activeElement !== colsInput) colsInput.value = cols.join(' ');
+
+        headRow.querySelectorAll('th.wl-computed').forEach(function (th) { th.remove(); });
+        Array.from(tbody.children).forEach(function (row) {
+            row.querySelectorAll('td.wl-computed').forEach(function (td) { td.remove(); });
+        });
+        if (cols.indexOf(watchlistTableSort.key) < 0) watchlistTableSort = { key: '', desc: false };
+        cols.forEach(function (spec) {
+            var th = document.createElement('th');
+            th.className = 'wl-computed' + (watchlistTableSort.key === spec ? ' wl-sorted' : '');
+            th.dataset.col = spec;
+            th.textContent = spec + (watchlistTableSort.key === spec ? (watchlistTableSort.desc ? ' ▼' : ' ▲') : '');
+            th.onclick = function () {
+                if (watchlistTableSort.key === spec) {
+                    watchlistTableSort.desc = !watchlistTableSort.desc;
+                } else {
+                    watchlistTableSort = { key: spec, desc: true };
+                }
+                renderWatchlistComputedColumns();
+            };
+            headRow.appendChild(th);
+        });
+
+        var filters = watchlistFilters();
+        if (!activeWl || (cols.length === 0 && filters.length === 0)) return;
+        Array.from(tbody.children).forEach(function (row) {
+            if (row.tagName !== 'TR') return;
+            cols.forEach(function (spec) {
+                var td = document.createElement('td');
+                td.className = 'wl-computed';
+                td.dataset.col = spec;
+                row.appendChild(td);
+            });
+        });
+
+        var params = new URLSearchParams();
+        if (watchlistTableSort.key) {
+            params.set('sort', watchlistTableSort.key);
+            if (watchlistTableSort.desc) params.set('order', 'desc');
+        }
+        filters.forEach(function (f) { params.append('filter', f); });
+        var seq = ++watchlistTableSeq;
+        fetch('/api/watchlists/' + activeWl.id + '/table?' + params.toString())
+            .then(function (r) { return r.json(); })
+            .then(function (table) {
+                if (seq !== watchlistTableSeq || activeWl.id !== activeWatchlistId) return;
+                if (table.error) { flashError('Filter: ' + table.error); return; }
+                applyWatchlistTable(tbody, table);
+            })
+            .catch(function () {});
+    }
+
+    function applyWatchlistTable(tbody, table) {
+        (table.columns || []).forEach(function (c) {
+            var th = document.querySelector('.quote-table th.wl-computed[data-col="' + c.spec + '"]');
+            if (th) th.title = c.label;
+        });
+        var kept = {};
+        table.rows.forEach(function (r) {
+            kept[r.symbol] = true;
+            var row = document.getElementById('quote-' + r.symbol);
+            if (!row) return;
+            row.querySelectorAll('td.wl-computed').forEach(function (td) {
+                var v = r.values[td.dataset.col];
+                td.textContent = v === null || v === undefined ? '—' : v.toFixed(td.dataset.col === 'daysToEarnings' ? 0 : 2);
+                td.classList.toggle('price-up', v > 0 && td.dataset.col.indexOf('rsi') !== 0);
+                td.classList.toggle('price-down', v < 0);
+            });
+            // Server order wins: appending an existing row moves it.
+            if (watchlistTableSort.key) tbody.appendChild(row);
+        });
+        if (watchlistFilters().length === 0) return;
+        Array.from(tbody.children).forEach(function (row) {
+            if (row.tagName !== 'TR' || row.style.display === 'none') return;
+            var sym = row.querySelector('[data-symbol]');
+            if (sym && !kept[sym.dataset.symbol]) row.style.display = 'none';
+        });
+    }
+
     function ensureWatchlistRow(symbol) {
         var tbody = document.getElementById('quote-body');
         if (!tbody) return null;
@@ -465,6 +698,12 @@ window.onerror = function (msg, src, line, col, err) {
                     var chg = q.change ? (q.change >= 0 ? '+' : '') + q.change.toFixed(2) : '';
                     var vol = q.volume ? formatWatchlistVolume(q.volume) : '';
                     var sym = q.symbol;
+                    // Same marking as the poller's quote row for quotes
+                    // validation or consensus found suspect.
+                    var flags = (q.flags || []).join(', ');
+                    var priceAttrs = flags
+                        ? ' class="' + chgClass + ' quote-flagged" title="Suspect quote: ' + flags + '"'
+                        : ' class="' + chgClass + '"';
 
                     if (!watchlistMetricsCache[sym]) watchlistMetricsCache[sym] = {};
                     watchlistMetricsCache[sym].change1d = q.change;
@@ -480,7 +719,7 @@ window.onerror = function (msg, src, line, col, err) {
                     // hydrateWatchlistHistorical() once the row is in the DOM.
                     var html = '<tr id="quote-' + sym + '">'
                         + '<td><span class="sym-link" data-symbol="' + sym + '">' + sym + '</span></td>'
-                        + '<td id="quote-' + sym + '-price" class="' + chgClass + '">' + (q.price ? q.price.toFixed(2) : '') + '</td>'
+                        + '<td id="quote-' + sym + '-price"' + priceAttrs + '>' + (q.price ? q.price.toFixed(2) : '') + '</td>'
                         + '<td id="quote-' + sym + '-change" class="' + chgClass + '">' + chg + '</td>'
                         + '<td id="quote-' + sym + '-changepct" class="' + chgClass + '">' + chgPct + '</td>'
                         + '<td id="quote-' + sym + '-change1w" class="wl-static"></td>'
@@ -773,9 +1012,11 @@ window.onerror = function (msg, src, line, col, err) {
         listEl.innerHTML = watchlistData.map(function (wl) {
             var active = wl.id === activeWatchlistId ? ' wl-tab-active active' : '';
             var count = wl.symbols ? wl.symbols.length : 0;
-            return '<li class="watchlist-list-item wl-tab' + active + '" data-id="' + wl.id + '" style="--wl-color:' + wl.color + '">'
+            var meta = count + ' securities' + (wl.dynamic ? ' · screen' : '');
+            return '<li class="watchlist-list-item wl-tab' + active + '" data-id="' + wl.id + '" style="--wl-color:' + wl.color + '"'
+                + (wl.dynamic ? ' title="' + escapeHtml(wl.query) + '"' : '') + '>'
                 + '<span class="watchlist-list-name">' + escapeHtml(wl.name) + '</span>'
-                + '<span class="watchlist-list-meta">' + count + ' securities</span>'
+                + '<span class="watchlist-list-meta">' + meta + '</span>'
                 + '</li>';
         }).join('');
 
@@ -850,6 +1091,7 @@ window.onerror = function (msg, src, line, col, err) {
         // while the row was display:none. Defer one frame so the row's
         // layout has settled before we read clientWidth/Height.
         requestAnimationFrame(resizeAllWatchlistSparklines);
+        renderWatchlistComputedColumns();
     }
 
     // Refresh the entire watchlist view after a mutation (delete / paste / copy).
@@ -1030,6 +1272,35 @@ window.onerror = function (msg, src, line, col, err) {
         }
     }
 
+    function unsubscribeSecurity(sec) {
+        if (!subscribedSecurities.has(sec) || sec === selectedSecurity) return;
+        subscribedSecurities.delete(sec);
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({ type: 'unsubscribe', topic: 'quote:' + sec }));
+        }
+    }
+
+    // handleWatchlistMembership applies a dynamic watchlist's re-evaluation:
+    // joiners get quote subscriptions, leavers lose theirs unless another
+    // list still holds them.
+    function handleWatchlistMembership(ch) {
+        var wl = watchlistData.find(function (w) { return w.id === ch.watchlistId; });
+        if (wl) wl.symbols = ch.symbols || [];
+        (ch.joined || []).forEach(subscribeSecurity);
+        (ch.left || []).forEach(function (sym) {
+            var held = watchlistData.some(function (w) { return (w.symbols || []).indexOf(sym) >= 0; });
+            if (!held) unsubscribeSecurity(sym);
+        });
+        if (currentView === 'watchlist') {
+            refreshWatchlistView();
+            var parts = (ch.joined || []).map(function (s) { return '+' + s; })
+                .concat((ch.left || []).map(function (s) { return '−' + s; }));
+            if (parts.length) flashError(ch.name + ': ' + parts.join(' '));
+        } else {
+            renderWatchlistTabs();
+        }
+    }
+
     function handleQuoteHTML(html) {
         if (currentView !== 'watchlist') return;
 
@@ -2654,7 +2925,10 @@ window.onerror = function (msg, src, line, col, err) {
                 if (!sym) return false;
                 var wlId = getActiveWatchlistId();
                 fetch('/api/watchlists/' + wlId + '/symbols/' + encodeURIComponent(sym), { method: 'DELETE' })
-                    .then(function () {
+                    .then(function (r) {
+                        if (!r.ok) {
+                            return r.json().then(function (body) { flashError(body.error || 'Remove failed'); });
+                        }
                         watchlistBuffer = sym;
                         flashError('Cut ' + sym + ' — press P to paste into another watchlist');
                         refreshWatchlistView();
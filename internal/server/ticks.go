@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"stocktopus/internal/ticks"
+)
+
+// maxTickRange bounds a single tick query; raw ticks for a month of
+// 15-second polls is already ~50k rows.
+const maxTickRange = 31 * 24 * time.Hour
+
+// SetTickRecorder attaches the intraday tick store.
+func (s *Server) SetTickRecorder(r *ticks.Recorder) {
+	s.ticks = r
+}
+
+// handleTickSymbols lists symbols with recorded ticks.
+func (s *Server) handleTickSymbols(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.ticks == nil {
+		json.NewEncoder(w).Encode([]string{})
+		return
+	}
+	syms, err := s.ticks.Symbols()
+	if err != nil {
+		s.logger.Error("tick symbols", "error", err)
+		http.Error(w, "list failed", http.StatusInternalServerError)
+		return
+	}
+	if syms == nil {
+		syms = []string{}
+	}
+	json.NewEncoder(w).Encode(syms)
+}
+
+// handleTicks returns recorded ticks for a symbol, or bars when
+// ?interval=1m|5m. from/to take RFC 3339 or YYYY-MM-DD (UTC) and default
+// to the current UTC day.
+func (s *Server) handleTicks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.ticks == nil {
+		http.Error(w, "tick recorder unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	symbol := strings.ToUpper(r.PathValue("symbol"))
+	q := r.URL.Query()
+
+	now := time.Now().UTC()
+	from, err := parseTickTime(q.Get("from"), now.Truncate(24*time.Hour))
+	if err != nil {
+		http.Error(w, "bad from", http.StatusBadRequest)
+		return
+	}
+	to, err := parseTickTime(q.Get("to"), now)
+	if err != nil {
+		http.Error(w, "bad to", http.StatusBadRequest)
+		return
+	}
+	if !to.After(from) || to.Sub(from) > maxTickRange {
+		http.Error(w, "range must be positive and at most 31 days", http.StatusBadRequest)
+		return
+	}
+
+	var interval time.Duration
+	switch q.Get("interval") {
+	case "", "tick":
+	case "1m", "1min":
+		interval = time.Minute
+	case "5m", "5min":
+		interval = 5 * time.Minute
+	default:
+		http.Error(w, "interval must be 1m or 5m", http.StatusBadRequest)
+		return
+	}
+
+	if interval > 0 {
+		bars, err := s.ticks.Bars(symbol, from, to, interval)
+		if err != nil {
+			s.logger.Error("tick bars", "symbol", symbol, "error", err)
+			http.Error(w, "query failed", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(bars)
+		return
+	}
+	rows, err := s.ticks.Ticks(symbol, from, to)
+	if err != nil {
+		s.logger.Error("ticks", "symbol", symbol, "error", err)
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	if rows == nil {
+		rows = []ticks.Tick{}
+	}
+	json.NewEncoder(w).Encode(rows)
+}
+
+// parseTickTime accepts RFC 3339 or a bare UTC date; date-only values mean
+// midnight at the start of that day.
+func parseTickTime(v string, def time.Time) (time.Time, error) {
+	if v == "" {
+		return def, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", v)
+}
@@ -0,0 +1,375 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"stocktopus/internal/budget"
+	"stocktopus/internal/store"
+	"stocktopus/internal/watchlists"
+)
+
+// SetWatchlistRefresher attaches the dynamic watchlist refresher, so new or
+// edited screener-backed lists fill straight away instead of on its next
+// tick.
+func (s *Server) SetWatchlistRefresher(r *watchlists.Refresher) {
+	s.watchlists = r
+}
+
+func (s *Server) createDynamicWatchlist(w http.ResponseWriter, name, rawQuery string) {
+	query, err := normalizeScreenerQuery(rawQuery)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	wl, err := s.store.CreateDynamicWatchlist(name, query)
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	s.refreshWatchlistLater(wl.ID)
+	json.NewEncoder(w).Encode(wl)
+}
+
+// handleSetWatchlistQuery: PUT /api/watchlists/{id}/query {"query": "..."}
+// replaces the screener query behind a watchlist. An empty query turns it
+// back into a static list with the symbols it has now.
+func (s *Server) handleSetWatchlistQuery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	query := ""
+	if req.Query != "" {
+		if query, err = normalizeScreenerQuery(req.Query); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if err := s.store.SetWatchlistQuery(id, query); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if query != "" {
+		s.refreshWatchlistLater(id)
+	}
+	wl, _ := s.store.GetWatchlist(id)
+	json.NewEncoder(w).Encode(wl)
+}
+
+// handleRefreshWatchlist: POST /api/watchlists/{id}/refresh re-runs a
+// dynamic watchlist's query now and returns who joined and left.
+func (s *Server) handleRefreshWatchlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil || s.watchlists == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	wl, err := s.store.GetWatchlist(id)
+	if err != nil || wl == nil {
+		http.Error(w, "watchlist not found", http.StatusNotFound)
+		return
+	}
+	if !wl.Dynamic {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "watchlist has no screener query"})
+		return
+	}
+	change, err := s.watchlists.Refresh(r.Context(), id)
+	if err != nil {
+		s.logger.Error("refresh dynamic watchlist", "id", id, "error", err)
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(change)
+}
+
+// rejectDynamicEdit answers 409 when the watchlist's membership belongs to
+// its screener query. It reports whether it did.
+func (s *Server) rejectDynamicEdit(w http.ResponseWriter, id int64) bool {
+	wl, err := s.store.GetWatchlist(id)
+	if err != nil || wl == nil || !wl.Dynamic {
+		return false
+	}
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]string{"error": "\"" + wl.Name + "\" follows a screener query; edit the query instead"})
+	return true
+}
+
+func (s *Server) refreshWatchlistLater(id int64) {
+	if s.watchlists == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(budget.WithCaller(context.Background(), "watchlists", budget.Background), time.Minute)
+		defer cancel()
+		if _, err := s.watchlists.Refresh(ctx, id); err != nil {
+			s.logger.Warn("initial dynamic watchlist refresh", "id", id, "error", err)
+		}
+	}()
+}
+
+// maxImportBytes caps a watchlist import upload.
+const maxImportBytes = 1 << 20
+
+// handleExportWatchlists: GET /api/watchlists/export?format=csv|tradingview|json
+// downloads every watchlist, or just ?id=N.
+func (s *Server) handleExportWatchlists(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = watchlists.FormatCSV
+	}
+	lists, err := s.store.GetWatchlists()
+	if err != nil {
+		s.logger.Error("export watchlists", "error", err)
+		http.Error(w, "export failed", http.StatusInternalServerError)
+		return
+	}
+	if raw := r.URL.Query().Get("id"); raw != "" {
+		id, _ := strconv.ParseInt(raw, 10, 64)
+		var one []store.Watchlist
+		for _, l := range lists {
+			if l.ID == id {
+				one = append(one, l)
+			}
+		}
+		if len(one) == 0 {
+			http.Error(w, "watchlist not found", http.StatusNotFound)
+			return
+		}
+		lists = one
+	}
+
+	var buf bytes.Buffer
+	if err := watchlists.Export(format, &buf, lists); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name, ctype := "watchlists.csv", "text/csv"
+	switch format {
+	case watchlists.FormatTradingView:
+		name, ctype = "watchlists.txt", "text/plain; charset=utf-8"
+	case watchlists.FormatJSON:
+		name, ctype = "watchlists.json", "application/json"
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Write(buf.Bytes())
+}
+
+// handleImportWatchlists: POST /api/watchlists/import with the file as the
+// body. Query params:
+//   - format: csv, tradingview or json; guessed from the content if unset
+//   - watchlist: id receiving symbols the file doesn't put in a named list
+//     (default: the first list)
+//   - dryRun=1: resolve and report without writing
+//   - resolve=INPUT=SYMBOL (repeatable): the user's pick for an ambiguous
+//     or unresolved entry; an empty SYMBOL skips it
+//
+// The response reports what was added, what was already there, and which
+// entries still need a decision.
+func (s *Server) handleImportWatchlists(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil || s.news == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	q := r.URL.Query()
+	opt := watchlists.ImportOptions{
+		Format:  strings.ToLower(q.Get("format")),
+		DryRun:  q.Get("dryRun") == "1" || q.Get("dryRun") == "true",
+		Choices: map[string]string{},
+	}
+	if raw := q.Get("watchlist"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "bad watchlist id", http.StatusBadRequest)
+			return
+		}
+		opt.Target = id
+	}
+	for _, pick := range q["resolve"] {
+		i := strings.LastIndex(pick, "=")
+		if i <= 0 {
+			http.Error(w, fmt.Sprintf("bad resolve %q, want INPUT=SYMBOL", pick), http.StatusBadRequest)
+			return
+		}
+		opt.Choices[pick[:i]] = pick[i+1:]
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		http.Error(w, "import too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	report, err := watchlists.Import(r.Context(), s.store, s.news, data, opt)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !report.DryRun {
+		s.logger.Info("imported watchlists", "format", report.Format, "lists", len(report.Lists),
+			"unresolved", len(report.Unresolved), "ambiguous", len(report.Ambiguous))
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleWatchlistColumnKinds: GET /api/watchlists/columns lists the
+// computed columns a watchlist can show.
+func (s *Server) handleWatchlistColumnKinds(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(watchlists.ColumnKinds())
+}
+
+// handleSetWatchlistColumns: PUT /api/watchlists/{id}/columns
+// {"columns": ["rsi(14)", "sma(200)", ...]} saves the computed columns a
+// watchlist shows, in display order.
+func (s *Server) handleSetWatchlistColumns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Columns []string `json:"columns"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	cols, err := watchlists.ParseColumns(req.Columns)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	specs := make([]string, len(cols))
+	for i, c := range cols {
+		specs[i] = c.Spec
+	}
+	if err := s.store.SetWatchlistColumns(id, specs); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	wl, _ := s.store.GetWatchlist(id)
+	json.NewEncoder(w).Encode(wl)
+}
+
+// handleWatchlistTable: GET /api/watchlists/{id}/table computes the
+// watchlist's columns for each member from daily bars. Query params:
+//   - sort: "symbol" or a column spec; rows keep watchlist order if unset
+//   - order: asc (default) or desc
+//   - filter (repeatable): "spec op number", e.g. "rsi(14)<30"; a filter
+//     or sort on a column the list doesn't show is computed anyway
+func (s *Server) handleWatchlistTable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.store == nil || s.columns == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	wl, err := s.store.GetWatchlist(id)
+	if err != nil || wl == nil {
+		http.Error(w, "watchlist not found", http.StatusNotFound)
+		return
+	}
+	shown, err := watchlists.ParseColumns(wl.Columns)
+	if err != nil {
+		s.logger.Warn("stored watchlist columns", "id", id, "error", err)
+	}
+
+	q := r.URL.Query()
+	var filters []watchlists.Filter
+	for _, raw := range q["filter"] {
+		f, err := watchlists.ParseFilter(raw)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		filters = append(filters, f)
+	}
+	compute := append([]watchlists.Column(nil), shown...)
+	extra := func(c watchlists.Column) {
+		for _, have := range compute {
+			if have.Spec == c.Spec {
+				return
+			}
+		}
+		compute = append(compute, c)
+	}
+	for _, f := range filters {
+		extra(f.Column)
+	}
+	sortKey := q.Get("sort")
+	if sortKey != "" && sortKey != "symbol" {
+		c, err := watchlists.ParseColumn(sortKey)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		sortKey = c.Spec
+		extra(c)
+	}
+
+	rows := s.columns.Rows(r.Context(), wl.Symbols, compute)
+	rows = watchlists.FilterRows(rows, filters)
+	if sortKey != "" {
+		watchlists.SortRows(rows, sortKey, strings.EqualFold(q.Get("order"), "desc"))
+	}
+	if rows == nil {
+		rows = []watchlists.Row{}
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"watchlistId": id,
+		"columns":     shown,
+		"rows":        rows,
+		"total":       len(wl.Symbols),
+	})
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// NewsWatch is one user-registered news alert rule. Kind decides how Expr
+// is read: a comma list of tickers ('symbols'), a comma list of phrases
+// ('keywords'), a boolean query ('query'), or ignored in favour of the
+// watchlist's current holdings ('watchlist').
+type NewsWatch struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"userId"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Expr        string    `json:"expr"`
+	WatchlistID *int64    `json:"watchlistId,omitempty"`
+	WebhookURL  string    `json:"webhookUrl"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// InboxItem is a matched story in a user's alert inbox.
+type InboxItem struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"userId"`
+	StoryKey    string    `json:"storyKey"`
+	WatchIDs    []int64   `json:"watchIds"`
+	Category    string    `json:"category"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Source      string    `json:"source"`
+	Symbol      string    `json:"symbol"`
+	PublishedAt time.Time `json:"publishedAt"`
+	MatchedAt   time.Time `json:"matchedAt"`
+	Read        bool      `json:"read"`
+}
+
+// CreateNewsWatch inserts a watch and returns its id. UserID 0 maps to the
+// global user, same as sketches.
+func (s *Store) CreateNewsWatch(w NewsWatch) (int64, error) {
+	if w.UserID == 0 {
+		w.UserID = 1
+	}
+	var id int64
+	err := s.db.QueryRow(`
+		INSERT INTO news_watches (user_id, name, kind, expr, watchlist_id, webhook_url, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, 1) RETURNING id`,
+		w.UserID, w.Name, w.Kind, w.Expr, w.WatchlistID, w.WebhookURL).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert news_watch: %w", err)
+	}
+	return id, nil
+}
+
+// ListNewsWatches returns a user's watches, oldest first.
+func (s *Store) ListNewsWatches(userID int64) ([]NewsWatch, error) {
+	if userID == 0 {
+		userID = 1
+	}
+	return s.queryNewsWatches(`WHERE user_id = ? ORDER BY id`, userID)
+}
+
+// GetEnabledNewsWatches returns every enabled watch across all users. The
+// alert engine calls this once per news batch.
+func (s *Store) GetEnabledNewsWatches() ([]NewsWatch, error) {
+	return s.queryNewsWatches(`WHERE enabled = 1 ORDER BY id`)
+}
+
+// SetNewsWatchEnabled pauses or resumes a watch without deleting it.
+func (s *Store) SetNewsWatchEnabled(userID, id int64, enabled bool) error {
+	if userID == 0 {
+		userID = 1
+	}
+	v := 0
+	if enabled {
+		v = 1
+	}
+	_, err := s.db.Exec(`UPDATE news_watches SET enabled = ? WHERE id = ? AND user_id = ?`, v, id, userID)
+	return err
+}
+
+// DeleteNewsWatch removes a watch. Inbox rows it produced are kept.
+func (s *Store) DeleteNewsWatch(userID, id int64) error {
+	if userID == 0 {
+		userID = 1
+	}
+	_, err := s.db.Exec(`DELETE FROM news_watches WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+// PutInboxItem records a matched story. Returns false without error when
+// the user already has this story — that's the cross-category dedupe, and
+// callers use it to skip hub/webhook delivery.
+func (s *Store) PutInboxItem(it InboxItem) (bool, error) {
+	if it.UserID == 0 {
+		it.UserID = 1
+	}
+	watchIDs, _ := json.Marshal(it.WatchIDs)
+	var published any
+	if !it.PublishedAt.IsZero() {
+		published = it.PublishedAt.UTC()
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO news_inbox
+			(user_id, story_key, watch_ids, category, title, url, source, symbol, published_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`,
+		it.UserID, it.StoryKey, string(watchIDs), it.Category, it.Title, it.URL, it.Source, it.Symbol, published)
+	if err != nil {
+		return false, fmt.Errorf("insert news_inbox: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetInbox returns a user's matched stories, newest first.
+func (s *Store) GetInbox(userID int64, unreadOnly bool, limit int) ([]InboxItem, error) {
+	if userID == 0 {
+		userID = 1
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	q := `SELECT id, user_id, story_key, watch_ids, category, title, url, source, symbol,
+	             published_at, matched_at, read
+	      FROM news_inbox WHERE user_id = ?`
+	if unreadOnly {
+		q += ` AND read = 0`
+	}
+	q += ` ORDER BY matched_at DESC, id DESC LIMIT ?`
+
+	rows, err := s.db.Query(q, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []InboxItem
+	for rows.Next() {
+		var it InboxItem
+		var watchIDs, matchedAt string
+		var publishedAt sql.NullString
+		var read int
+		if err := rows.Scan(&it.ID, &it.UserID, &it.StoryKey, &watchIDs, &it.Category,
+			&it.Title, &it.URL, &it.Source, &it.Symbol, &publishedAt, &matchedAt, &read); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(watchIDs), &it.WatchIDs)
+		it.PublishedAt = parseSQLiteTime(publishedAt.String)
+		it.MatchedAt = parseSQLiteTime(matchedAt)
+		it.Read = read != 0
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+// MarkInboxRead flags one inbox row as read.
+func (s *Store) MarkInboxRead(userID, id int64) error {
+	if userID == 0 {
+		userID = 1
+	}
+	_, err := s.db.Exec(`UPDATE news_inbox SET read = 1 WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+func (s *Store) queryNewsWatches(whereClause string, args ...any) ([]NewsWatch, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, kind, expr, watchlist_id, webhook_url, enabled, created_at
+		FROM news_watches `+whereClause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []NewsWatch
+	for rows.Next() {
+		var w NewsWatch
+		var watchlistID sql.NullInt64
+		var enabled int
+		var createdAt string
+		if err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Kind, &w.Expr, &watchlistID,
+			&w.WebhookURL, &enabled, &createdAt); err != nil {
+			return nil, err
+		}
+		if watchlistID.Valid {
+			w.WatchlistID = &watchlistID.Int64
+		}
+		w.Enabled = enabled != 0
+		w.CreatedAt = parseSQLiteTime(createdAt)
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
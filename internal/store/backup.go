@@ -0,0 +1,575 @@
+package store
+
+import (
+	"bufio"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bundle identification. BundleVersion changes only when the envelope
+// does; table contents follow SchemaVersion.
+const (
+	BundleFormat  = "stocktopus-backup"
+	BundleVersion = 1
+)
+
+// Bundle is a portable copy of the user-owned data: watchlists, sketches
+// and their metrics, paper accounts and trades, news watches, saved
+// screens and their runs, modeling scenarios, and the generated
+// intelligence. Cached market data (prices, filings, economic series,
+// corporate actions, SIC codes) is left out — it's re-fetched.
+//
+// Tables hold rows column → value exactly as stored, so a bundle is
+// independent of the Go structs and survives additive schema changes.
+type Bundle struct {
+	Format        string                      `json:"format"`
+	Version       int                         `json:"version"`
+	SchemaVersion int                         `json:"schemaVersion"`
+	CreatedAt     time.Time                   `json:"createdAt"`
+	Tables        map[string][]map[string]any `json:"tables"`
+}
+
+// backupTable describes one table in a bundle. Tables are listed parents
+// first; restore inserts in this order and replace deletes in reverse.
+type backupTable struct {
+	name string
+	// key is the natural key a merge matches existing rows on, after
+	// parent ids are remapped.
+	key []string
+	// parents maps a column to the bundle table whose id it references.
+	parents map[string]string
+	// optional is the parent column cleared, rather than the row dropped,
+	// when its parent isn't in the bundle.
+	optional string
+	// newer, if set, is the timestamp column that decides whether a merge
+	// overwrites a matching row; otherwise matching rows are left alone.
+	newer string
+}
+
+var backupTables = []backupTable{
+	{name: "watchlists", key: []string{"name"}},
+	{name: "watchlist_symbols", key: []string{"watchlist_id", "symbol"}, parents: map[string]string{"watchlist_id": "watchlists"}},
+	{name: "sketches", key: []string{"owner_id", "name", "created_at"}},
+	{name: "sketch_metrics", key: []string{"sketch_id", "kind", "identifier", "position"}, parents: map[string]string{"sketch_id": "sketches"}},
+	{name: "paper_accounts", key: []string{"name", "created_at"}},
+	{name: "paper_trades", key: []string{"account_id", "symbol", "side", "opened_at"}, parents: map[string]string{"account_id": "paper_accounts", "sketch_id": "sketches"}, optional: "sketch_id"},
+	{name: "paper_trade_events", key: []string{"trade_id", "event_type", "created_at"}, parents: map[string]string{"trade_id": "paper_trades"}},
+	{name: "news_watches", key: []string{"user_id", "kind", "expr", "name"}, parents: map[string]string{"watchlist_id": "watchlists"}},
+	{name: "saved_screens", key: []string{"user_id", "name"}},
+	{name: "screen_runs", key: []string{"screen_id", "ran_at"}, parents: map[string]string{"screen_id": "saved_screens"}},
+	{name: "modeling_scenarios", key: []string{"user_id", "symbol", "name"}, parents: map[string]string{"sketch_id": "sketches"}, optional: "sketch_id"},
+	{name: "company_intelligence", key: []string{"symbol"}, newer: "generated_at"},
+	{name: "sector_intelligence", key: []string{"sector"}, newer: "generated_at"},
+	{name: "training_data", key: []string{"symbol", "prompt", "completion"}},
+}
+
+// BundleTables lists the tables a bundle carries, parents first.
+func BundleTables() []string {
+	names := make([]string, len(backupTables))
+	for i, t := range backupTables {
+		names[i] = t.name
+	}
+	return names
+}
+
+// RestoreMode is how a bundle combines with what's already in the store.
+type RestoreMode string
+
+const (
+	// RestoreMerge keeps existing data and adds what the bundle has that
+	// the store doesn't, matching rows on natural keys; restoring the same
+	// bundle twice changes nothing. Intelligence is replaced when the
+	// bundle's copy is newer.
+	RestoreMerge RestoreMode = "merge"
+	// RestoreReplace deletes the user-owned data first, so the store ends
+	// up holding exactly the bundle's.
+	RestoreReplace RestoreMode = "replace"
+)
+
+// ParseRestoreMode maps "" to RestoreMerge.
+func ParseRestoreMode(s string) (RestoreMode, error) {
+	switch RestoreMode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", RestoreMerge:
+		return RestoreMerge, nil
+	case RestoreReplace:
+		return RestoreReplace, nil
+	}
+	return "", fmt.Errorf("unknown restore mode %q (want merge or replace)", s)
+}
+
+// RestoreCounts is what a restore did to one table.
+type RestoreCounts struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+	Deleted  int `json:"deleted,omitempty"`
+}
+
+// RestoreResult summarises a restore.
+type RestoreResult struct {
+	Mode   RestoreMode              `json:"mode"`
+	Tables map[string]RestoreCounts `json:"tables"`
+}
+
+// Backup exports the user-owned tables as a Bundle.
+func (s *Store) Backup() (*Bundle, error) {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{
+		Format:        BundleFormat,
+		Version:       BundleVersion,
+		SchemaVersion: version,
+		CreatedAt:     time.Now().UTC(),
+		Tables:        map[string][]map[string]any{},
+	}
+	for _, t := range backupTables {
+		rows, err := s.dumpTable(t)
+		if err != nil {
+			return nil, fmt.Errorf("backup %s: %w", t.name, err)
+		}
+		b.Tables[t.name] = rows
+	}
+	return b, nil
+}
+
+type tableColumn struct {
+	name     string
+	declType string
+	pk       bool
+}
+
+// tableColumns lists a table's columns. Postgres types are reported under
+// their SQLite names (DATETIME, JSON) so callers needn't care which
+// backend they're on.
+func tableColumns(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	sqlDialect() dialect
+}, table string) ([]tableColumn, error) {
+	query := `SELECT name, type, pk FROM pragma_table_info(?)`
+	if q.sqlDialect() == postgresDialect {
+		query = `
+			SELECT c.column_name,
+			       CASE c.data_type
+			           WHEN 'timestamp with time zone' THEN 'DATETIME'
+			           WHEN 'json' THEN 'JSON'
+			           ELSE upper(c.data_type)
+			       END,
+			       CASE WHEN EXISTS (
+			           SELECT 1 FROM information_schema.table_constraints tc
+			           JOIN information_schema.key_column_usage k
+			             ON k.constraint_schema = tc.constraint_schema AND k.constraint_name = tc.constraint_name
+			           WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema
+			             AND tc.table_name = c.table_name AND k.column_name = c.column_name
+			       ) THEN 1 ELSE 0 END
+			FROM information_schema.columns c
+			WHERE c.table_schema = current_schema() AND c.table_name = ?
+			ORDER BY c.ordinal_position`
+	}
+	rows, err := q.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cols []tableColumn
+	for rows.Next() {
+		var c tableColumn
+		var pk int
+		if err := rows.Scan(&c.name, &c.declType, &pk); err != nil {
+			return nil, err
+		}
+		c.pk = pk > 0
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// dumpTable reads every row as column → value. SQLite DATETIME columns
+// are read as their stored text so they restore byte for byte; Postgres
+// timestamps are written the way SQLite's CURRENT_TIMESTAMP would have
+// them, so a bundle restores into either backend.
+func (s *Store) dumpTable(t backupTable) ([]map[string]any, error) {
+	cols, err := tableColumns(s.db, t.name)
+	if err != nil {
+		return nil, err
+	}
+	exprs := make([]string, len(cols))
+	order := make([]string, len(t.key))
+	for i, k := range t.key {
+		order[i] = quoteIdent(k)
+	}
+	for i, c := range cols {
+		exprs[i] = quoteIdent(c.name)
+		if strings.EqualFold(c.declType, "DATETIME") && s.db.dialect == sqliteDialect {
+			exprs[i] = "CAST(" + quoteIdent(c.name) + " AS TEXT)"
+		}
+		if c.name == "id" && c.pk {
+			order = []string{quoteIdent("id")}
+		}
+	}
+	rows, err := s.db.Query(`SELECT ` + strings.Join(exprs, ", ") + ` FROM ` + quoteIdent(t.name) + ` ORDER BY ` + strings.Join(order, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []map[string]any{}
+	for rows.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(cols))
+		for i, c := range cols {
+			switch v := vals[i].(type) {
+			case []byte:
+				vals[i] = string(v)
+			case time.Time:
+				vals[i] = v.UTC().Format("2006-01-02 15:04:05.999999999")
+			}
+			row[c.name] = vals[i]
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// Check rejects bundles this build can't restore: another format, a newer
+// envelope, or a schema newer than the store's migrations know.
+func (b *Bundle) Check() error {
+	if b.Format != BundleFormat {
+		return fmt.Errorf("not a stocktopus backup (format %q)", b.Format)
+	}
+	if b.Version < 1 || b.Version > BundleVersion {
+		return fmt.Errorf("bundle version %d is not supported (this build reads up to %d)", b.Version, BundleVersion)
+	}
+	if b.SchemaVersion > LatestSchemaVersion() {
+		return fmt.Errorf("bundle is from schema version %d, newer than this build's %d; upgrade stocktopus first",
+			b.SchemaVersion, LatestSchemaVersion())
+	}
+	known := map[string]bool{}
+	for _, t := range backupTables {
+		known[t.name] = true
+	}
+	for name := range b.Tables {
+		if !known[name] {
+			return fmt.Errorf("bundle has unknown table %q", name)
+		}
+	}
+	return nil
+}
+
+// Restore loads a bundle in one transaction: either all of it lands or
+// none of it does.
+func (s *Store) Restore(b *Bundle, mode RestoreMode) (*RestoreResult, error) {
+	if err := b.Check(); err != nil {
+		return nil, err
+	}
+	if mode != RestoreMerge && mode != RestoreReplace {
+		return nil, fmt.Errorf("unknown restore mode %q", mode)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res := &RestoreResult{Mode: mode, Tables: map[string]RestoreCounts{}}
+	if mode == RestoreReplace {
+		for i := len(backupTables) - 1; i >= 0; i-- {
+			name := backupTables[i].name
+			r, err := tx.Exec(`DELETE FROM ` + quoteIdent(name))
+			if err != nil {
+				return nil, fmt.Errorf("clear %s: %w", name, err)
+			}
+			n, _ := r.RowsAffected()
+			res.Tables[name] = RestoreCounts{Deleted: int(n)}
+		}
+	}
+
+	ids := map[string]map[int64]int64{} // table → bundle id → store id
+	for _, t := range backupTables {
+		counts := res.Tables[t.name]
+		if err := restoreTable(tx, t, b.Tables[t.name], mode, ids, &counts); err != nil {
+			return nil, fmt.Errorf("restore %s: %w", t.name, err)
+		}
+		res.Tables[t.name] = counts
+	}
+	if tx.dialect == postgresDialect {
+		if err := resetSequences(tx); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func restoreTable(tx *txn, t backupTable, rows []map[string]any, mode RestoreMode, ids map[string]map[int64]int64, counts *RestoreCounts) error {
+	cols, err := tableColumns(tx, t.name)
+	if err != nil {
+		return err
+	}
+	colType := map[string]string{}
+	hasID := false
+	for _, c := range cols {
+		colType[c.name] = strings.ToUpper(c.declType)
+		hasID = hasID || (c.name == "id" && c.pk)
+	}
+	ids[t.name] = map[int64]int64{}
+
+rows:
+	for _, src := range rows {
+		row := make(map[string]any, len(src))
+		for k, v := range src {
+			typ, ok := colType[k]
+			if !ok {
+				return fmt.Errorf("unknown column %q", k)
+			}
+			// The store writes JSON columns as blobs, and json.RawMessage
+			// only scans from one.
+			if str, isStr := v.(string); isStr && typ == "JSON" {
+				v = []byte(str)
+			}
+			row[k] = v
+		}
+		for col, parent := range t.parents {
+			old, ok := asInt(row[col])
+			if !ok {
+				continue // NULL reference
+			}
+			id, mapped := ids[parent][old]
+			if !mapped {
+				if col == t.optional {
+					row[col] = nil
+					continue
+				}
+				counts.Skipped++ // orphan: its parent isn't in the bundle
+				continue rows
+			}
+			row[col] = id
+		}
+		bundleID, _ := asInt(row["id"])
+
+		if mode == RestoreMerge {
+			existing, found, err := findByKey(tx, t, row, hasID)
+			if err != nil {
+				return err
+			}
+			if found {
+				if hasID {
+					ids[t.name][bundleID] = existing
+				}
+				if t.newer != "" && isNewer(row[t.newer], tx, t, row) {
+					if err := updateRow(tx, t, row); err != nil {
+						return err
+					}
+					counts.Updated++
+				} else {
+					counts.Skipped++
+				}
+				continue
+			}
+			delete(row, "id") // new rows get fresh ids
+		}
+
+		id, err := insertRow(tx, t.name, row, hasID)
+		if err != nil {
+			return err
+		}
+		if hasID {
+			ids[t.name][bundleID] = id
+		}
+		counts.Inserted++
+	}
+	return nil
+}
+
+// keyWhere matches row's natural key. IS NOT DISTINCT FROM compares NULLs
+// as equal on both backends.
+func keyWhere(t backupTable, row map[string]any) (string, []any) {
+	where := make([]string, len(t.key))
+	args := make([]any, len(t.key))
+	for i, k := range t.key {
+		where[i] = quoteIdent(k) + " IS NOT DISTINCT FROM ?"
+		args[i] = row[k]
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// findByKey reports whether a store row matches row's natural key, and
+// its id when the table has one.
+func findByKey(tx *txn, t backupTable, row map[string]any, hasID bool) (int64, bool, error) {
+	where, args := keyWhere(t, row)
+	sel := "1"
+	if hasID {
+		sel = "id"
+	}
+	var id int64
+	err := tx.QueryRow(`SELECT `+sel+` FROM `+quoteIdent(t.name)+` WHERE `+where+` LIMIT 1`, args...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if !hasID {
+		id = 0
+	}
+	return id, err == nil, err
+}
+
+func isNewer(v any, tx *txn, t backupTable, row map[string]any) bool {
+	incoming, _ := v.(string)
+	where, args := keyWhere(t, row)
+	var current sql.NullString
+	if err := tx.QueryRow(`SELECT `+quoteIdent(t.newer)+` FROM `+quoteIdent(t.name)+` WHERE `+where, args...).Scan(&current); err != nil {
+		return false
+	}
+	return parseSQLiteTime(incoming).After(parseSQLiteTime(current.String))
+}
+
+func insertRow(tx *txn, table string, row map[string]any, hasID bool) (int64, error) {
+	cols := sortedKeys(row)
+	names := make([]string, len(cols))
+	marks := make([]string, len(cols))
+	args := make([]any, len(cols))
+	for i, c := range cols {
+		names[i], marks[i], args[i] = quoteIdent(c), "?", row[c]
+	}
+	query := `INSERT INTO ` + quoteIdent(table) + ` (` + strings.Join(names, ", ") + `) VALUES (` + strings.Join(marks, ", ") + `)`
+	if !hasID {
+		_, err := tx.Exec(query, args...)
+		return 0, err
+	}
+	var id int64
+	err := tx.QueryRow(query+` RETURNING id`, args...).Scan(&id)
+	return id, err
+}
+
+func updateRow(tx *txn, t backupTable, row map[string]any) error {
+	var sets []string
+	var args []any
+	for _, c := range sortedKeys(row) {
+		if c == "id" {
+			continue
+		}
+		sets = append(sets, quoteIdent(c)+" = ?")
+		args = append(args, row[c])
+	}
+	where, keyArgs := keyWhere(t, row)
+	_, err := tx.Exec(`UPDATE `+quoteIdent(t.name)+` SET `+strings.Join(sets, ", ")+` WHERE `+where, append(args, keyArgs...)...)
+	return err
+}
+
+// resetSequences moves each restored table's id sequence past the ids a
+// replace wrote explicitly, so the next insert doesn't collide. SQLite
+// tracks this itself.
+func resetSequences(tx *txn) error {
+	for _, t := range backupTables {
+		var seq sql.NullString
+		err := tx.QueryRow(`
+			SELECT pg_get_serial_sequence(table_name::text, column_name::text) FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = ? AND column_name = 'id'`, t.name).Scan(&seq)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && !seq.Valid) {
+			continue // no id column
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`SELECT setval(?, COALESCE(MAX(id), 0) + 1, false) FROM `+quoteIdent(t.name), seq.String); err != nil {
+			return fmt.Errorf("reset %s id sequence: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(row map[string]any) []string {
+	keys := make([]string, 0, len(row))
+	for k := range row {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// asInt reads an id from a decoded bundle or a live row.
+func asInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// WriteBundle encodes b as indented JSON, gzipped when compress is set.
+func WriteBundle(w io.Writer, b *Bundle, compress bool) error {
+	if compress {
+		zw := gzip.NewWriter(w)
+		if err := WriteBundle(zw, b, false); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// ReadBundle decodes a bundle, gzipped or not, and checks it. Whole
+// numbers decode as int64 so ids survive beyond float64 precision.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return decodeBundle(zr)
+	}
+	return decodeBundle(br)
+}
+
+func decodeBundle(r io.Reader) (*Bundle, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var b Bundle
+	if err := dec.Decode(&b); err != nil {
+		return nil, fmt.Errorf("decode bundle: %w", err)
+	}
+	for _, rows := range b.Tables {
+		for _, row := range rows {
+			for k, v := range row {
+				if n, ok := v.(json.Number); ok {
+					if i, err := n.Int64(); err == nil {
+						row[k] = i
+					} else if f, err := n.Float64(); err == nil {
+						row[k] = f
+					}
+				}
+			}
+		}
+	}
+	if err := b.Check(); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
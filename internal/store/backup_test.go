@@ -0,0 +1,220 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// seedUserData gives s one of everything a bundle carries, plus cached
+// market data that must stay out of it.
+func seedUserData(t *testing.T, s *Store) {
+	t.Helper()
+	wl, err := s.CreateWatchlist("Tech")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddToWatchlist(wl.ID, "AAPL")
+	s.AddToWatchlist(wl.ID, "MSFT")
+
+	sk, err := s.CreateSketch(1, "Rates vs banks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddSketchMetric(SketchMetric{SketchID: sk, Kind: "price", Identifier: "JPM"})
+	s.AddSketchMetric(SketchMetric{SketchID: sk, Kind: "economic", Identifier: "DGS10"})
+
+	acct, err := s.CreatePaperAccount("Swing", "USD", 10000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.OpenPaperTrade(PaperTrade{
+		AccountID: acct, SketchID: &sk, Symbol: "JPM", InstrumentType: "stock", Multiplier: 1,
+		Side: "long", EntryPrice: 200, StopPrice: 190, Size: 10, RiskPctAtEntry: 0.01, RiskAmount: 100,
+		OpenedAt: time.Date(2026, 3, 2, 15, 30, 0, 0, time.UTC),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put(&CompanyIntelligence{Symbol: "JPM", Summary: "old take", Analysis: []byte(`{}`), GeneratedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	s.PutSECFilings([]SECFiling{{Symbol: "JPM", FormType: "8-K", FilingDate: "2026-01-05"}})
+}
+
+func count(t *testing.T, s *Store, table string) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func roundTrip(t *testing.T, b *Bundle, compress bool) *Bundle {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, b, compress); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReadBundle(&buf)
+	if err != nil {
+		t.Fatalf("ReadBundle: %v", err)
+	}
+	return out
+}
+
+func testBackupExcludesMarketData(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	seedUserData(t, s)
+
+	b, err := s.Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.SchemaVersion != LatestSchemaVersion() || b.Format != BundleFormat {
+		t.Errorf("envelope = %s v%d schema %d", b.Format, b.Version, b.SchemaVersion)
+	}
+	if _, ok := b.Tables["sec_filings"]; ok {
+		t.Error("bundle should not carry cached SEC filings")
+	}
+	if len(b.Tables["watchlists"]) != 2 || len(b.Tables["paper_trades"]) != 1 || len(b.Tables["company_intelligence"]) != 1 {
+		t.Errorf("unexpected table sizes: %d watchlists, %d trades, %d intelligence",
+			len(b.Tables["watchlists"]), len(b.Tables["paper_trades"]), len(b.Tables["company_intelligence"]))
+	}
+}
+
+func testRestoreReplaceIntoFreshStore(t *testing.T, newStore openStore) {
+	src := newStore(t)
+	seedUserData(t, src)
+	b, err := src.Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newStore(t)
+	dst.CreateWatchlist("Scratch")
+	res, err := dst.Restore(roundTrip(t, b, true), RestoreReplace)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if res.Tables["watchlists"].Deleted != 2 || res.Tables["watchlists"].Inserted != 2 {
+		t.Errorf("watchlists = %+v", res.Tables["watchlists"])
+	}
+
+	lists, _ := dst.GetWatchlists()
+	var names []string
+	for _, wl := range lists {
+		names = append(names, wl.Name)
+	}
+	if strings.Join(names, ",") != "Default,Tech" {
+		t.Errorf("watchlists = %v", names)
+	}
+	accts, _ := dst.GetPaperAccounts()
+	if len(accts) != 1 {
+		t.Fatalf("accounts = %+v", accts)
+	}
+	trades, err := dst.GetOpenPaperTrades(accts[0].ID)
+	if err != nil || len(trades) != 1 || trades[0].SketchID == nil || trades[0].EntryPrice != 200 {
+		t.Fatalf("trades = %+v, %v", trades, err)
+	}
+	if !trades[0].OpenedAt.Equal(time.Date(2026, 3, 2, 15, 30, 0, 0, time.UTC)) {
+		t.Errorf("openedAt = %v", trades[0].OpenedAt)
+	}
+	sk, err := dst.GetSketch(*trades[0].SketchID)
+	if err != nil || sk.Name != "Rates vs banks" || len(sk.Metrics) != 2 {
+		t.Errorf("sketch = %+v, %v", sk, err)
+	}
+	if count(t, dst, "sec_filings") != 0 {
+		t.Error("replace should not touch market data tables")
+	}
+}
+
+func testRestoreMergeIsIdempotentAndRemapsIDs(t *testing.T, newStore openStore) {
+	src := newStore(t)
+	seedUserData(t, src)
+	b, err := src.Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = roundTrip(t, b, false)
+
+	// dst already has its own sketch and account, so bundle ids collide.
+	dst := newStore(t)
+	mine, _ := dst.CreateSketch(1, "Mine")
+	dst.CreatePaperAccount("Local", "USD", 5000, 0.02)
+
+	if _, err := dst.Restore(b, RestoreMerge); err != nil {
+		t.Fatalf("first merge: %v", err)
+	}
+	res, err := dst.Restore(b, RestoreMerge)
+	if err != nil {
+		t.Fatalf("second merge: %v", err)
+	}
+	for table, c := range res.Tables {
+		if c.Inserted != 0 || c.Updated != 0 {
+			t.Errorf("second merge changed %s: %+v", table, c)
+		}
+	}
+
+	if count(t, dst, "sketches") != 2 || count(t, dst, "paper_accounts") != 2 || count(t, dst, "paper_trade_events") != 1 {
+		t.Errorf("counts: %d sketches, %d accounts, %d events",
+			count(t, dst, "sketches"), count(t, dst, "paper_accounts"), count(t, dst, "paper_trade_events"))
+	}
+	if sk, _ := dst.GetSketch(mine); sk == nil || len(sk.Metrics) != 0 {
+		t.Errorf("existing sketch picked up bundle metrics: %+v", sk)
+	}
+	var trade struct{ account, sketch string }
+	dst.db.QueryRow(`SELECT a.name, s.name FROM paper_trades t JOIN paper_accounts a ON a.id = t.account_id JOIN sketches s ON s.id = t.sketch_id`).
+		Scan(&trade.account, &trade.sketch)
+	if trade.account != "Swing" || trade.sketch != "Rates vs banks" {
+		t.Errorf("trade references %+v, want Swing / Rates vs banks", trade)
+	}
+}
+
+func testRestoreMergeKeepsNewerIntelligence(t *testing.T, newStore openStore) {
+	src := newStore(t)
+	seedUserData(t, src)
+	b, _ := src.Backup()
+	b = roundTrip(t, b, false)
+
+	dst := newStore(t)
+	dst.Put(&CompanyIntelligence{Symbol: "JPM", Summary: "fresh take", Analysis: []byte(`{}`), GeneratedAt: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)})
+	dst.Restore(b, RestoreMerge)
+	if ci, _ := dst.Get("JPM"); ci == nil || ci.Summary != "fresh take" {
+		t.Errorf("older bundle intelligence overwrote newer: %+v", ci)
+	}
+
+	src.Put(&CompanyIntelligence{Symbol: "JPM", Summary: "newest take", Analysis: []byte(`{}`), GeneratedAt: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)})
+	b, _ = src.Backup()
+	res, err := dst.Restore(roundTrip(t, b, false), RestoreMerge)
+	if err != nil || res.Tables["company_intelligence"].Updated != 1 {
+		t.Fatalf("restore = %+v, %v", res, err)
+	}
+	if ci, _ := dst.Get("JPM"); ci == nil || ci.Summary != "newest take" {
+		t.Errorf("newer bundle intelligence not applied: %+v", ci)
+	}
+}
+
+func testRestoreRejectsIncompatibleBundles(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	b, _ := s.Backup()
+
+	newer := *b
+	newer.SchemaVersion = LatestSchemaVersion() + 1
+	if _, err := s.Restore(&newer, RestoreMerge); err == nil || !strings.Contains(err.Error(), "upgrade stocktopus") {
+		t.Errorf("newer schema: %v", err)
+	}
+	if _, err := ReadBundle(strings.NewReader(`{"format":"something-else","version":1}`)); err == nil {
+		t.Error("foreign format should be rejected")
+	}
+	bad := *b
+	bad.Tables = map[string][]map[string]any{"watchlists": {{"name": "X", "owner": "me"}}}
+	if _, err := s.Restore(&bad, RestoreMerge); err == nil || !strings.Contains(err.Error(), `unknown column "owner"`) {
+		t.Errorf("unknown column: %v", err)
+	}
+	if lists, _ := s.GetWatchlists(); len(lists) != 1 {
+		t.Errorf("failed restore left changes behind: %+v", lists)
+	}
+}
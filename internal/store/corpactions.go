@@ -0,0 +1,143 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"stocktopus/internal/model"
+)
+
+// CorporateActions is everything stored for one symbol: splits and
+// dividends oldest first, plus any ticker renames it took part in.
+// FetchedAt is zero for a symbol that was never fetched.
+type CorporateActions struct {
+	Symbol        string               `json:"symbol"`
+	Splits        []model.Split        `json:"splits"`
+	Dividends     []model.Dividend     `json:"dividends"`
+	SymbolChanges []model.SymbolChange `json:"symbolChanges"`
+	FetchedAt     time.Time            `json:"fetchedAt"`
+}
+
+// PutCorporateActions replaces a symbol's splits and dividends with a
+// freshly fetched set, merges its symbol changes, and stamps the fetch
+// time — all in one txn so a reader never sees half a refresh.
+func (s *Store) PutCorporateActions(a CorporateActions) error {
+	symbol := strings.ToUpper(a.Symbol)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM corporate_splits WHERE symbol = ?`, symbol); err != nil {
+		return fmt.Errorf("clear splits: %w", err)
+	}
+	for _, sp := range a.Splits {
+		if _, err := tx.Exec(`
+			INSERT INTO corporate_splits (symbol, date, numerator, denominator)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(symbol, date) DO UPDATE SET
+				numerator = excluded.numerator, denominator = excluded.denominator`, symbol, sp.Date, sp.Numerator, sp.Denominator); err != nil {
+			return fmt.Errorf("insert split: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM corporate_dividends WHERE symbol = ?`, symbol); err != nil {
+		return fmt.Errorf("clear dividends: %w", err)
+	}
+	for _, d := range a.Dividends {
+		if _, err := tx.Exec(`
+			INSERT INTO corporate_dividends (symbol, ex_date, pay_date, record_date, declaration_date, amount)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(symbol, ex_date) DO UPDATE SET
+				pay_date = excluded.pay_date, record_date = excluded.record_date,
+				declaration_date = excluded.declaration_date, amount = excluded.amount`,
+			symbol, d.ExDate, d.PayDate, d.RecordDate, d.DeclarationDate, d.Amount); err != nil {
+			return fmt.Errorf("insert dividend: %w", err)
+		}
+	}
+
+	for _, c := range a.SymbolChanges {
+		if _, err := tx.Exec(`
+			INSERT INTO symbol_changes (date, old_symbol, new_symbol, name)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(old_symbol, new_symbol, date) DO UPDATE SET name = excluded.name`,
+			c.Date, strings.ToUpper(c.OldSymbol), strings.ToUpper(c.NewSymbol), c.Name); err != nil {
+			return fmt.Errorf("insert symbol change: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO corporate_actions_fetched (symbol, fetched_at) VALUES (?, CURRENT_TIMESTAMP)
+		ON CONFLICT(symbol) DO UPDATE SET fetched_at = CURRENT_TIMESTAMP`, symbol); err != nil {
+		return fmt.Errorf("stamp fetch: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetCorporateActions returns what's stored for symbol. A symbol never
+// fetched comes back empty, not as an error.
+func (s *Store) GetCorporateActions(symbol string) (*CorporateActions, error) {
+	symbol = strings.ToUpper(symbol)
+	a := &CorporateActions{
+		Symbol:        symbol,
+		Splits:        []model.Split{},
+		Dividends:     []model.Dividend{},
+		SymbolChanges: []model.SymbolChange{},
+	}
+
+	var fetchedAt string
+	if err := s.db.QueryRow(`SELECT fetched_at FROM corporate_actions_fetched WHERE symbol = ?`,
+		symbol).Scan(&fetchedAt); err == nil {
+		a.FetchedAt = parseSQLiteTime(fetchedAt)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT date, numerator, denominator FROM corporate_splits
+		WHERE symbol = ? ORDER BY date`, symbol)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var sp model.Split
+		if err := rows.Scan(&sp.Date, &sp.Numerator, &sp.Denominator); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.Splits = append(a.Splits, sp)
+	}
+	rows.Close()
+
+	rows, err = s.db.Query(`
+		SELECT ex_date, pay_date, record_date, declaration_date, amount FROM corporate_dividends
+		WHERE symbol = ? ORDER BY ex_date`, symbol)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var d model.Dividend
+		if err := rows.Scan(&d.ExDate, &d.PayDate, &d.RecordDate, &d.DeclarationDate, &d.Amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.Dividends = append(a.Dividends, d)
+	}
+	rows.Close()
+
+	rows, err = s.db.Query(`
+		SELECT date, old_symbol, new_symbol, name FROM symbol_changes
+		WHERE old_symbol = ? OR new_symbol = ? ORDER BY date`, symbol, symbol)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c model.SymbolChange
+		if err := rows.Scan(&c.Date, &c.OldSymbol, &c.NewSymbol, &c.Name); err != nil {
+			return nil, err
+		}
+		a.SymbolChanges = append(a.SymbolChanges, c)
+	}
+	return a, rows.Err()
+}
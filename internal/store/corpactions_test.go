@@ -0,0 +1,51 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"stocktopus/internal/model"
+)
+
+func testCorporateActionsRoundtrip(t *testing.T, newStore openStore) {
+	s := newStore(t)
+
+	if a, err := s.GetCorporateActions("AAPL"); err != nil || !a.FetchedAt.IsZero() {
+		t.Fatalf("never-fetched symbol: %+v, %v", a, err)
+	}
+	err := s.PutCorporateActions(CorporateActions{
+		Symbol:        "aapl",
+		Splits:        []model.Split{{Date: "2020-08-31", Numerator: 4, Denominator: 1}},
+		Dividends:     []model.Dividend{{ExDate: "2024-05-10", Amount: 0.25}, {ExDate: "2024-02-09", Amount: 0.24}},
+		SymbolChanges: []model.SymbolChange{{Date: "2022-06-09", OldSymbol: "FB", NewSymbol: "META"}},
+	})
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	a, err := s.GetCorporateActions("AAPL")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if time.Since(a.FetchedAt) > time.Minute {
+		t.Errorf("fetchedAt = %v, want just now", a.FetchedAt)
+	}
+	if len(a.Splits) != 1 || a.Splits[0].Ratio() != 4 {
+		t.Errorf("splits = %+v", a.Splits)
+	}
+	if len(a.Dividends) != 2 || a.Dividends[0].ExDate != "2024-02-09" {
+		t.Errorf("dividends should come back oldest first: %+v", a.Dividends)
+	}
+
+	// A refresh replaces the set rather than appending to it.
+	if err := s.PutCorporateActions(CorporateActions{Symbol: "AAPL"}); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if a, _ := s.GetCorporateActions("AAPL"); len(a.Splits) != 0 || len(a.Dividends) != 0 {
+		t.Errorf("refresh left stale rows: %+v", a)
+	}
+
+	if m, _ := s.GetCorporateActions("FB"); len(m.SymbolChanges) != 1 || m.SymbolChanges[0].NewSymbol != "META" {
+		t.Errorf("symbol change lookup by old ticker = %+v", m.SymbolChanges)
+	}
+}
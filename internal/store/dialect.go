@@ -0,0 +1,242 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Backends Open accepts.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Drivers lists the backends Open accepts, the default first.
+func Drivers() []string { return []string{DriverSQLite, DriverPostgres} }
+
+// dialect is the SQL flavour of the database behind a Store. Queries are
+// written once, with ? placeholders and syntax both backends share
+// (ON CONFLICT, RETURNING); conn and txn rebind them for Postgres.
+type dialect int
+
+const (
+	sqliteDialect dialect = iota
+	postgresDialect
+)
+
+func (d dialect) String() string {
+	if d == postgresDialect {
+		return DriverPostgres
+	}
+	return DriverSQLite
+}
+
+// openDB opens the database for driver without touching the schema.
+// readOnly applies to SQLite files; Postgres callers just don't write.
+func openDB(driver, dsn string, readOnly bool) (*conn, error) {
+	switch strings.ToLower(driver) {
+	case "", DriverSQLite, "sqlite3":
+		src := dsn + "?_journal_mode=WAL"
+		if readOnly {
+			src = "file:" + dsn + "?mode=ro"
+		}
+		db, err := sql.Open("sqlite3", src)
+		if err != nil {
+			return nil, err
+		}
+		return &conn{DB: db, dialect: sqliteDialect}, nil
+	case DriverPostgres, "postgresql":
+		if dsn == "" {
+			return nil, fmt.Errorf("postgres needs a connection string")
+		}
+		db, err := sql.Open("postgres", withUTC(dsn))
+		if err != nil {
+			return nil, err
+		}
+		return &conn{DB: db, dialect: postgresDialect}, nil
+	}
+	return nil, fmt.Errorf("unknown store driver %q (want %s)", driver, strings.Join(Drivers(), " or "))
+}
+
+// withUTC pins the session time zone. Timestamps are written as UTC wall
+// clock strings, the way SQLite's CURRENT_TIMESTAMP stores them, and
+// Postgres reads those in the session zone.
+func withUTC(dsn string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		q := u.Query()
+		if q.Get("timezone") == "" {
+			q.Set("timezone", "UTC")
+		}
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	if strings.Contains(strings.ToLower(dsn), "timezone=") {
+		return dsn
+	}
+	return strings.TrimSpace(dsn + " timezone=UTC")
+}
+
+// rebind rewrites ? placeholders as $1, $2, … for Postgres. Question
+// marks inside quoted strings, quoted identifiers and -- comments are
+// left alone.
+func (d dialect) rebind(query string) string {
+	if d != postgresDialect || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch c {
+		case '\'', '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case '-':
+			if i+1 < len(query) && query[i+1] == '-' {
+				end := strings.IndexByte(query[i:], '\n')
+				if end < 0 {
+					b.WriteString(query[i:])
+					return b.String()
+				}
+				b.WriteString(query[i : i+end])
+				i += end - 1
+				continue
+			}
+			b.WriteByte(c)
+		case '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// args adapts Go values to the schema's column types. Flags are INTEGER
+// columns on both backends, and JSON arrives as bytes, which lib/pq would
+// otherwise send as bytea.
+func (d dialect) args(args []any) []any {
+	if d != postgresDialect {
+		return args
+	}
+	out := make([]any, len(args))
+	for i, a := range args {
+		switch v := a.(type) {
+		case bool:
+			if v {
+				out[i] = int64(1)
+			} else {
+				out[i] = int64(0)
+			}
+		case json.RawMessage:
+			if v == nil {
+				out[i] = nil
+			} else {
+				out[i] = string(v)
+			}
+		case []byte:
+			if v == nil {
+				out[i] = nil
+			} else {
+				out[i] = string(v)
+			}
+		default:
+			out[i] = a
+		}
+	}
+	return out
+}
+
+// conn is the store's connection pool. It has *sql.DB's query methods,
+// rebound for the dialect.
+type conn struct {
+	*sql.DB
+	dialect dialect
+}
+
+func (c *conn) sqlDialect() dialect { return c.dialect }
+
+func (c *conn) Exec(query string, args ...any) (sql.Result, error) {
+	return c.DB.Exec(c.dialect.rebind(query), c.dialect.args(args)...)
+}
+
+func (c *conn) Query(query string, args ...any) (*sql.Rows, error) {
+	return c.DB.Query(c.dialect.rebind(query), c.dialect.args(args)...)
+}
+
+func (c *conn) QueryRow(query string, args ...any) *sql.Row {
+	return c.DB.QueryRow(c.dialect.rebind(query), c.dialect.args(args)...)
+}
+
+func (c *conn) Prepare(query string) (*stmt, error) {
+	st, err := c.DB.Prepare(c.dialect.rebind(query))
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: st, dialect: c.dialect}, nil
+}
+
+func (c *conn) Begin() (*txn, error) {
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &txn{Tx: tx, dialect: c.dialect}, nil
+}
+
+// txn is a transaction on a conn.
+type txn struct {
+	*sql.Tx
+	dialect dialect
+}
+
+func (t *txn) sqlDialect() dialect { return t.dialect }
+
+func (t *txn) Exec(query string, args ...any) (sql.Result, error) {
+	return t.Tx.Exec(t.dialect.rebind(query), t.dialect.args(args)...)
+}
+
+func (t *txn) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.Query(t.dialect.rebind(query), t.dialect.args(args)...)
+}
+
+func (t *txn) QueryRow(query string, args ...any) *sql.Row {
+	return t.Tx.QueryRow(t.dialect.rebind(query), t.dialect.args(args)...)
+}
+
+func (t *txn) Prepare(query string) (*stmt, error) {
+	st, err := t.Tx.Prepare(t.dialect.rebind(query))
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: st, dialect: t.dialect}, nil
+}
+
+// stmt is a prepared statement whose arguments are adapted like conn's.
+type stmt struct {
+	*sql.Stmt
+	dialect dialect
+}
+
+func (s *stmt) Exec(args ...any) (sql.Result, error) {
+	return s.Stmt.Exec(s.dialect.args(args)...)
+}
@@ -0,0 +1,1045 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// migration is one numbered, forward-only schema change. Up runs in a
+// transaction together with the schema_migrations row recording it, so a
+// failed migration leaves nothing behind. Shipped migrations must never be
+// edited — their checksum is stored, and New refuses a database whose
+// recorded checksum no longer matches. Add a new migration instead.
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+
+	// Postgres is Up in Postgres's dialect, when it differs. Versions line
+	// up across backends, so a bundle's schema version means the same
+	// thing on both.
+	Postgres string
+
+	// present reports whether a database from before schema_migrations
+	// existed already has this change. Those are adopted (recorded without
+	// running) rather than applied.
+	present func(q querier) (bool, error)
+}
+
+// querier is the read side shared by conn and txn.
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+	sqlDialect() dialect
+}
+
+// migrations is the schema history, in order. Append only.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: `
+
+		CREATE TABLE IF NOT EXISTS company_intelligence (
+			symbol TEXT PRIMARY KEY,
+			company_name TEXT,
+			sector TEXT,
+			analysis JSON,
+			sentiment REAL DEFAULT 0,
+			risk_score REAL DEFAULT 0,
+			summary TEXT DEFAULT '',
+			key_risks JSON DEFAULT '[]',
+			opportunities JSON DEFAULT '[]',
+			competitors JSON DEFAULT '[]',
+			sources JSON DEFAULT '[]',
+			generated_at DATETIME,
+			model_version TEXT DEFAULT '',
+			confidence REAL DEFAULT 0,
+			raw_data JSON DEFAULT '{}'
+		);
+
+		CREATE TABLE IF NOT EXISTS training_data (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			symbol TEXT,
+			prompt TEXT,
+			completion TEXT,
+			source TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			quality_score REAL DEFAULT 0
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_training_symbol ON training_data(symbol);
+
+		CREATE TABLE IF NOT EXISTS watchlists (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			color TEXT NOT NULL DEFAULT '#ff8800',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS watchlist_symbols (
+			watchlist_id INTEGER NOT NULL,
+			symbol TEXT NOT NULL,
+			added_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (watchlist_id, symbol),
+			FOREIGN KEY (watchlist_id) REFERENCES watchlists(id) ON DELETE CASCADE
+		);
+
+		INSERT OR IGNORE INTO watchlists (name, color) VALUES ('Default', '#ff8800');
+
+		CREATE TABLE IF NOT EXISTS sic_codes (
+			sic_code TEXT PRIMARY KEY,
+			industry_title TEXT NOT NULL,
+			office TEXT DEFAULT ''
+		);
+
+		CREATE TABLE IF NOT EXISTS sector_intelligence (
+			sector TEXT PRIMARY KEY,
+			industry TEXT DEFAULT '',
+			peers JSON DEFAULT '[]',
+			news JSON DEFAULT '[]',
+			performance JSON DEFAULT '{}',
+			generated_at DATETIME,
+			model_version TEXT DEFAULT ''
+		);
+
+		CREATE TABLE IF NOT EXISTS sec_filings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			symbol TEXT NOT NULL,
+			cik TEXT NOT NULL DEFAULT '',
+			form_type TEXT NOT NULL,
+			filing_date TEXT NOT NULL,
+			accepted_date TEXT DEFAULT '',
+			link TEXT DEFAULT '',
+			final_link TEXT DEFAULT '',
+			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(symbol, form_type, filing_date, link)
+		);
+		CREATE INDEX IF NOT EXISTS idx_sec_symbol ON sec_filings(symbol);
+		CREATE INDEX IF NOT EXISTS idx_sec_form ON sec_filings(form_type);
+
+		CREATE TABLE IF NOT EXISTS sec_form_types (
+			form_type TEXT PRIMARY KEY,
+			title TEXT NOT NULL DEFAULT '',
+			purpose TEXT NOT NULL DEFAULT '',
+			timing TEXT NOT NULL DEFAULT '',
+			category TEXT NOT NULL DEFAULT ''
+		);
+
+		CREATE TABLE IF NOT EXISTS key_people (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			symbol TEXT NOT NULL,
+			name TEXT NOT NULL,
+			title TEXT DEFAULT '',
+			event_type TEXT DEFAULT '',
+			event_date TEXT DEFAULT '',
+			source TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_people_symbol ON key_people(symbol);
+
+		CREATE TABLE IF NOT EXISTS users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			handle TEXT NOT NULL UNIQUE,
+			display_name TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Single global user for now — every sketch belongs to this row.
+		-- Refactored to per-user later by adding more rows + auth.
+		INSERT OR IGNORE INTO users (id, handle, display_name) VALUES (1, 'global', 'Global');
+
+		CREATE TABLE IF NOT EXISTS sketches (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			owner_id INTEGER NOT NULL DEFAULT 1,
+			name TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (owner_id) REFERENCES users(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_sketches_owner ON sketches(owner_id);
+
+		CREATE TABLE IF NOT EXISTS sketch_metrics (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			sketch_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,        -- 'price' | 'financial' | 'commodity' | 'forex' | 'crypto' | 'index' | 'economic'
+			identifier TEXT NOT NULL,  -- 'AAPL' | 'AAPL.revenue' | 'GCUSD' | 'EURUSD' | 'BTCUSD' | 'SPX' | 'UNRATE'
+			label TEXT NOT NULL DEFAULT '',
+			color TEXT NOT NULL DEFAULT '',
+			position INTEGER NOT NULL DEFAULT 0,
+			FOREIGN KEY (sketch_id) REFERENCES sketches(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_sketch_metrics_sketch ON sketch_metrics(sketch_id);
+
+		CREATE TABLE IF NOT EXISTS economic_series (
+			code TEXT PRIMARY KEY,        -- FRED series ID (e.g. UNRATE)
+			title TEXT NOT NULL DEFAULT '',
+			category TEXT NOT NULL DEFAULT '',
+			frequency TEXT NOT NULL DEFAULT '', -- D / W / M / Q / A — from FRED
+			units TEXT NOT NULL DEFAULT '',
+			observations TEXT NOT NULL DEFAULT '[]', -- JSON [{date, value}, ...] in ascending date order
+			source_updated_at TEXT NOT NULL DEFAULT '', -- FRED's last_updated (string, parse on display)
+			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS security_types (
+			symbol TEXT PRIMARY KEY,
+			type TEXT NOT NULL,           -- stock / crypto / forex / index / etf
+			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS paper_accounts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			base_currency TEXT NOT NULL DEFAULT 'USD',
+			starting_balance REAL NOT NULL,
+			cash_balance REAL NOT NULL,
+			risk_pct REAL NOT NULL DEFAULT 0.02,
+			settled INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS paper_trades (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			account_id INTEGER NOT NULL,
+			sketch_id INTEGER,
+			symbol TEXT NOT NULL,
+			instrument_type TEXT NOT NULL,
+			multiplier REAL NOT NULL DEFAULT 1.0,
+			side TEXT NOT NULL,
+			entry_price REAL NOT NULL,
+			stop_price REAL NOT NULL,
+			target_price REAL,
+			size REAL NOT NULL,
+			risk_pct_at_entry REAL NOT NULL,
+			risk_amount REAL NOT NULL,
+			status TEXT NOT NULL DEFAULT 'open',
+			opened_at DATETIME NOT NULL,
+			closed_at DATETIME,
+			exit_price REAL,
+			realized_pnl REAL,
+			thesis TEXT NOT NULL DEFAULT '',
+			notes TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (account_id) REFERENCES paper_accounts(id),
+			FOREIGN KEY (sketch_id) REFERENCES sketches(id) ON DELETE SET NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_paper_trades_account ON paper_trades(account_id);
+		CREATE INDEX IF NOT EXISTS idx_paper_trades_status ON paper_trades(status);
+
+		CREATE TABLE IF NOT EXISTS paper_trade_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trade_id INTEGER NOT NULL,
+			event_type TEXT NOT NULL,
+			payload TEXT NOT NULL DEFAULT '{}',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (trade_id) REFERENCES paper_trades(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_paper_trade_events_trade ON paper_trade_events(trade_id);
+		`,
+		Postgres: `
+		CREATE TABLE IF NOT EXISTS company_intelligence (
+			symbol TEXT PRIMARY KEY,
+			company_name TEXT,
+			sector TEXT,
+			analysis JSON,
+			sentiment DOUBLE PRECISION DEFAULT 0,
+			risk_score DOUBLE PRECISION DEFAULT 0,
+			summary TEXT DEFAULT '',
+			key_risks JSON DEFAULT '[]',
+			opportunities JSON DEFAULT '[]',
+			competitors JSON DEFAULT '[]',
+			sources JSON DEFAULT '[]',
+			generated_at TIMESTAMPTZ,
+			model_version TEXT DEFAULT '',
+			confidence DOUBLE PRECISION DEFAULT 0,
+			raw_data JSON DEFAULT '{}'
+		);
+
+		CREATE TABLE IF NOT EXISTS training_data (
+			id BIGSERIAL PRIMARY KEY,
+			symbol TEXT,
+			prompt TEXT,
+			completion TEXT,
+			source TEXT,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			quality_score DOUBLE PRECISION DEFAULT 0
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_training_symbol ON training_data(symbol);
+
+		CREATE TABLE IF NOT EXISTS watchlists (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			color TEXT NOT NULL DEFAULT '#ff8800',
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS watchlist_symbols (
+			watchlist_id BIGINT NOT NULL REFERENCES watchlists(id) ON DELETE CASCADE,
+			symbol TEXT NOT NULL,
+			added_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (watchlist_id, symbol)
+		);
+
+		INSERT INTO watchlists (name, color) VALUES ('Default', '#ff8800') ON CONFLICT DO NOTHING;
+
+		CREATE TABLE IF NOT EXISTS sic_codes (
+			sic_code TEXT PRIMARY KEY,
+			industry_title TEXT NOT NULL,
+			office TEXT DEFAULT ''
+		);
+
+		CREATE TABLE IF NOT EXISTS sector_intelligence (
+			sector TEXT PRIMARY KEY,
+			industry TEXT DEFAULT '',
+			peers JSON DEFAULT '[]',
+			news JSON DEFAULT '[]',
+			performance JSON DEFAULT '{}',
+			generated_at TIMESTAMPTZ,
+			model_version TEXT DEFAULT ''
+		);
+
+		CREATE TABLE IF NOT EXISTS sec_filings (
+			id BIGSERIAL PRIMARY KEY,
+			symbol TEXT NOT NULL,
+			cik TEXT NOT NULL DEFAULT '',
+			form_type TEXT NOT NULL,
+			filing_date TEXT NOT NULL,
+			accepted_date TEXT DEFAULT '',
+			link TEXT DEFAULT '',
+			final_link TEXT DEFAULT '',
+			fetched_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(symbol, form_type, filing_date, link)
+		);
+		CREATE INDEX IF NOT EXISTS idx_sec_symbol ON sec_filings(symbol);
+		CREATE INDEX IF NOT EXISTS idx_sec_form ON sec_filings(form_type);
+
+		CREATE TABLE IF NOT EXISTS sec_form_types (
+			form_type TEXT PRIMARY KEY,
+			title TEXT NOT NULL DEFAULT '',
+			purpose TEXT NOT NULL DEFAULT '',
+			timing TEXT NOT NULL DEFAULT '',
+			category TEXT NOT NULL DEFAULT ''
+		);
+
+		CREATE TABLE IF NOT EXISTS key_people (
+			id BIGSERIAL PRIMARY KEY,
+			symbol TEXT NOT NULL,
+			name TEXT NOT NULL,
+			title TEXT DEFAULT '',
+			event_type TEXT DEFAULT '',
+			event_date TEXT DEFAULT '',
+			source TEXT DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_people_symbol ON key_people(symbol);
+
+		CREATE TABLE IF NOT EXISTS users (
+			id BIGSERIAL PRIMARY KEY,
+			handle TEXT NOT NULL UNIQUE,
+			display_name TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- The global user takes id 1 explicitly, so move the sequence past it.
+		INSERT INTO users (id, handle, display_name) VALUES (1, 'global', 'Global') ON CONFLICT DO NOTHING;
+		SELECT setval(pg_get_serial_sequence('users', 'id'), (SELECT MAX(id) FROM users));
+
+		CREATE TABLE IF NOT EXISTS sketches (
+			id BIGSERIAL PRIMARY KEY,
+			owner_id BIGINT NOT NULL DEFAULT 1 REFERENCES users(id),
+			name TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_sketches_owner ON sketches(owner_id);
+
+		CREATE TABLE IF NOT EXISTS sketch_metrics (
+			id BIGSERIAL PRIMARY KEY,
+			sketch_id BIGINT NOT NULL REFERENCES sketches(id) ON DELETE CASCADE,
+			kind TEXT NOT NULL,
+			identifier TEXT NOT NULL,
+			label TEXT NOT NULL DEFAULT '',
+			color TEXT NOT NULL DEFAULT '',
+			position INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS idx_sketch_metrics_sketch ON sketch_metrics(sketch_id);
+
+		CREATE TABLE IF NOT EXISTS economic_series (
+			code TEXT PRIMARY KEY,
+			title TEXT NOT NULL DEFAULT '',
+			category TEXT NOT NULL DEFAULT '',
+			frequency TEXT NOT NULL DEFAULT '',
+			units TEXT NOT NULL DEFAULT '',
+			observations TEXT NOT NULL DEFAULT '[]',
+			source_updated_at TEXT NOT NULL DEFAULT '',
+			fetched_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS security_types (
+			symbol TEXT PRIMARY KEY,
+			type TEXT NOT NULL,
+			fetched_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS paper_accounts (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			base_currency TEXT NOT NULL DEFAULT 'USD',
+			starting_balance DOUBLE PRECISION NOT NULL,
+			cash_balance DOUBLE PRECISION NOT NULL,
+			risk_pct DOUBLE PRECISION NOT NULL DEFAULT 0.02,
+			settled INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS paper_trades (
+			id BIGSERIAL PRIMARY KEY,
+			account_id BIGINT NOT NULL REFERENCES paper_accounts(id),
+			sketch_id BIGINT REFERENCES sketches(id) ON DELETE SET NULL,
+			symbol TEXT NOT NULL,
+			instrument_type TEXT NOT NULL,
+			multiplier DOUBLE PRECISION NOT NULL DEFAULT 1.0,
+			side TEXT NOT NULL,
+			entry_price DOUBLE PRECISION NOT NULL,
+			stop_price DOUBLE PRECISION NOT NULL,
+			target_price DOUBLE PRECISION,
+			size DOUBLE PRECISION NOT NULL,
+			risk_pct_at_entry DOUBLE PRECISION NOT NULL,
+			risk_amount DOUBLE PRECISION NOT NULL,
+			status TEXT NOT NULL DEFAULT 'open',
+			opened_at TIMESTAMPTZ NOT NULL,
+			closed_at TIMESTAMPTZ,
+			exit_price DOUBLE PRECISION,
+			realized_pnl DOUBLE PRECISION,
+			thesis TEXT NOT NULL DEFAULT '',
+			notes TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_paper_trades_account ON paper_trades(account_id);
+		CREATE INDEX IF NOT EXISTS idx_paper_trades_status ON paper_trades(status);
+
+		CREATE TABLE IF NOT EXISTS paper_trade_events (
+			id BIGSERIAL PRIMARY KEY,
+			trade_id BIGINT NOT NULL REFERENCES paper_trades(id) ON DELETE CASCADE,
+			event_type TEXT NOT NULL,
+			payload TEXT NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_paper_trade_events_trade ON paper_trade_events(trade_id);
+		`,
+		present: tablesExist("company_intelligence", "training_data", "watchlists", "watchlist_symbols",
+			"sic_codes", "sector_intelligence", "sec_filings", "sec_form_types", "key_people", "users",
+			"sketches", "sketch_metrics", "economic_series", "security_types",
+			"paper_accounts", "paper_trades", "paper_trade_events"),
+	},
+	{
+		Version: 2,
+		Name:    "sec_filings_processed_for_people",
+		Up:      `ALTER TABLE sec_filings ADD COLUMN processed_for_people INTEGER NOT NULL DEFAULT 0;`,
+		present: columnExists("sec_filings", "processed_for_people"),
+	},
+	{
+		// Current-role tracking arrived with the SEC-compliant Go fetcher.
+		// Filings the old Python fetcher marked as processed never produced
+		// usable people, so they get a real extraction pass.
+		Version: 3,
+		Name:    "key_people_current_roles",
+		Up: `
+		ALTER TABLE key_people ADD COLUMN is_current INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE key_people ADD COLUMN as_of_date TEXT DEFAULT '';
+		ALTER TABLE key_people ADD COLUMN form_type TEXT DEFAULT '';
+		UPDATE sec_filings SET processed_for_people = 0;
+		`,
+		present: columnExists("key_people", "is_current"),
+	},
+	{
+		Version: 4,
+		Name:    "sketch_notes",
+		Up:      `ALTER TABLE sketches ADD COLUMN notes TEXT NOT NULL DEFAULT '';`,
+		present: columnExists("sketches", "notes"),
+	},
+	{
+		Version: 5,
+		Name:    "news_alerts",
+		Up: `
+		CREATE TABLE IF NOT EXISTS news_watches (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL DEFAULT 1,
+			name TEXT NOT NULL DEFAULT '',
+			kind TEXT NOT NULL,             -- 'symbols' | 'keywords' | 'query' | 'watchlist'
+			expr TEXT NOT NULL DEFAULT '',  -- comma list (symbols/keywords) or boolean query
+			watchlist_id INTEGER,           -- only for kind = 'watchlist'
+			webhook_url TEXT NOT NULL DEFAULT '',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (watchlist_id) REFERENCES watchlists(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_news_watches_user ON news_watches(user_id);
+
+		-- One row per story per user. story_key is the normalized URL so the
+		-- same article arriving via stock + general feeds lands once.
+		CREATE TABLE IF NOT EXISTS news_inbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL DEFAULT 1,
+			story_key TEXT NOT NULL,
+			watch_ids TEXT NOT NULL DEFAULT '[]',
+			category TEXT NOT NULL DEFAULT '',
+			title TEXT NOT NULL DEFAULT '',
+			url TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL DEFAULT '',
+			symbol TEXT NOT NULL DEFAULT '',
+			published_at DATETIME,
+			matched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			read INTEGER NOT NULL DEFAULT 0,
+			UNIQUE(user_id, story_key),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_news_inbox_user ON news_inbox(user_id, matched_at);
+		`,
+		Postgres: `
+		CREATE TABLE IF NOT EXISTS news_watches (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL DEFAULT 1 REFERENCES users(id),
+			name TEXT NOT NULL DEFAULT '',
+			kind TEXT NOT NULL,
+			expr TEXT NOT NULL DEFAULT '',
+			watchlist_id BIGINT REFERENCES watchlists(id) ON DELETE CASCADE,
+			webhook_url TEXT NOT NULL DEFAULT '',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_news_watches_user ON news_watches(user_id);
+
+		CREATE TABLE IF NOT EXISTS news_inbox (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL DEFAULT 1 REFERENCES users(id),
+			story_key TEXT NOT NULL,
+			watch_ids TEXT NOT NULL DEFAULT '[]',
+			category TEXT NOT NULL DEFAULT '',
+			title TEXT NOT NULL DEFAULT '',
+			url TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL DEFAULT '',
+			symbol TEXT NOT NULL DEFAULT '',
+			published_at TIMESTAMPTZ,
+			matched_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			read INTEGER NOT NULL DEFAULT 0,
+			UNIQUE(user_id, story_key)
+		);
+		CREATE INDEX IF NOT EXISTS idx_news_inbox_user ON news_inbox(user_id, matched_at);
+		`,
+		present: tablesExist("news_watches", "news_inbox"),
+	},
+	{
+		Version: 6,
+		Name:    "corporate_actions",
+		Up: `
+		-- Corporate actions, refreshed as a set per symbol. Amounts and ratios
+		-- are as declared; adjustment happens on read (internal/corpactions).
+		CREATE TABLE IF NOT EXISTS corporate_splits (
+			symbol TEXT NOT NULL,
+			date TEXT NOT NULL,
+			numerator REAL NOT NULL,
+			denominator REAL NOT NULL,
+			PRIMARY KEY (symbol, date)
+		);
+
+		CREATE TABLE IF NOT EXISTS corporate_dividends (
+			symbol TEXT NOT NULL,
+			ex_date TEXT NOT NULL,
+			pay_date TEXT NOT NULL DEFAULT '',
+			record_date TEXT NOT NULL DEFAULT '',
+			declaration_date TEXT NOT NULL DEFAULT '',
+			amount REAL NOT NULL,
+			PRIMARY KEY (symbol, ex_date)
+		);
+
+		CREATE TABLE IF NOT EXISTS symbol_changes (
+			date TEXT NOT NULL,
+			old_symbol TEXT NOT NULL,
+			new_symbol TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (old_symbol, new_symbol, date)
+		);
+		CREATE INDEX IF NOT EXISTS idx_symbol_changes_new ON symbol_changes(new_symbol);
+
+		CREATE TABLE IF NOT EXISTS corporate_actions_fetched (
+			symbol TEXT PRIMARY KEY,
+			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		`,
+		Postgres: `
+		CREATE TABLE IF NOT EXISTS corporate_splits (
+			symbol TEXT NOT NULL,
+			date TEXT NOT NULL,
+			numerator DOUBLE PRECISION NOT NULL,
+			denominator DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (symbol, date)
+		);
+
+		CREATE TABLE IF NOT EXISTS corporate_dividends (
+			symbol TEXT NOT NULL,
+			ex_date TEXT NOT NULL,
+			pay_date TEXT NOT NULL DEFAULT '',
+			record_date TEXT NOT NULL DEFAULT '',
+			declaration_date TEXT NOT NULL DEFAULT '',
+			amount DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (symbol, ex_date)
+		);
+
+		CREATE TABLE IF NOT EXISTS symbol_changes (
+			date TEXT NOT NULL,
+			old_symbol TEXT NOT NULL,
+			new_symbol TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (old_symbol, new_symbol, date)
+		);
+		CREATE INDEX IF NOT EXISTS idx_symbol_changes_new ON symbol_changes(new_symbol);
+
+		CREATE TABLE IF NOT EXISTS corporate_actions_fetched (
+			symbol TEXT PRIMARY KEY,
+			fetched_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+		`,
+		present: tablesExist("corporate_splits", "corporate_dividends", "symbol_changes", "corporate_actions_fetched"),
+	},
+	{
+		// A watchlist with a query is dynamic: its symbols are whatever the
+		// saved screener query (URL-encoded /api/screener params) last
+		// returned, as of evaluated_at.
+		Version: 7,
+		Name:    "dynamic_watchlists",
+		Up: `
+		ALTER TABLE watchlists ADD COLUMN query TEXT NOT NULL DEFAULT '';
+		ALTER TABLE watchlists ADD COLUMN evaluated_at DATETIME;
+		`,
+		Postgres: `
+		ALTER TABLE watchlists ADD COLUMN query TEXT NOT NULL DEFAULT '';
+		ALTER TABLE watchlists ADD COLUMN evaluated_at TIMESTAMPTZ;
+		`,
+		present: columnExists("watchlists", "query"),
+	},
+	{
+		// Computed columns shown for a watchlist, as a JSON array of column
+		// specs such as "rsi(14)" or "vsSPY(63)".
+		Version: 8,
+		Name:    "watchlist_columns",
+		Up: `
+		ALTER TABLE watchlists ADD COLUMN columns TEXT NOT NULL DEFAULT '';
+		`,
+		Postgres: `
+		ALTER TABLE watchlists ADD COLUMN columns TEXT NOT NULL DEFAULT '';
+		`,
+		present: columnExists("watchlists", "columns"),
+	},
+	{
+		// The local screener's universe: one row per symbol with its
+		// profile, flattened numeric fundamentals (JSON object) and a year
+		// of daily bars (JSON array), rebuilt nightly.
+		Version: 9,
+		Name:    "universe_snapshot",
+		Up: `
+		CREATE TABLE IF NOT EXISTS universe_snapshot (
+			symbol TEXT PRIMARY KEY,
+			name TEXT NOT NULL DEFAULT '',
+			sector TEXT NOT NULL DEFAULT '',
+			industry TEXT NOT NULL DEFAULT '',
+			exchange TEXT NOT NULL DEFAULT '',
+			fields TEXT NOT NULL DEFAULT '{}',
+			bars TEXT NOT NULL DEFAULT '[]',
+			refreshed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		`,
+		Postgres: `
+		CREATE TABLE IF NOT EXISTS universe_snapshot (
+			symbol TEXT PRIMARY KEY,
+			name TEXT NOT NULL DEFAULT '',
+			sector TEXT NOT NULL DEFAULT '',
+			industry TEXT NOT NULL DEFAULT '',
+			exchange TEXT NOT NULL DEFAULT '',
+			fields TEXT NOT NULL DEFAULT '{}',
+			bars TEXT NOT NULL DEFAULT '[]',
+			refreshed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+		`,
+		present: tablesExist("universe_snapshot"),
+	},
+	{
+		// Saved screens rerun on a cron schedule. Each run keeps its ranked
+		// result set and the diff against the previous successful run as
+		// JSON.
+		Version: 10,
+		Name:    "saved_screens",
+		Up: `
+		CREATE TABLE IF NOT EXISTS saved_screens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL DEFAULT 1,
+			name TEXT NOT NULL,
+			query TEXT NOT NULL,            -- canonical /api/screener params
+			sort_by TEXT NOT NULL DEFAULT '',
+			descending INTEGER NOT NULL DEFAULT 1,
+			schedule TEXT NOT NULL DEFAULT '',
+			timezone TEXT NOT NULL DEFAULT '',
+			webhook_url TEXT NOT NULL DEFAULT '',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_run_at DATETIME,
+			UNIQUE(user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		);
+
+		CREATE TABLE IF NOT EXISTS screen_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			screen_id INTEGER NOT NULL,
+			ran_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			results TEXT NOT NULL DEFAULT '[]',
+			diff TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (screen_id) REFERENCES saved_screens(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_screen_runs_screen ON screen_runs(screen_id, id);
+		`,
+		Postgres: `
+		CREATE TABLE IF NOT EXISTS saved_screens (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL DEFAULT 1 REFERENCES users(id),
+			name TEXT NOT NULL,
+			query TEXT NOT NULL,
+			sort_by TEXT NOT NULL DEFAULT '',
+			descending INTEGER NOT NULL DEFAULT 1,
+			schedule TEXT NOT NULL DEFAULT '',
+			timezone TEXT NOT NULL DEFAULT '',
+			webhook_url TEXT NOT NULL DEFAULT '',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			last_run_at TIMESTAMPTZ,
+			UNIQUE(user_id, name)
+		);
+
+		CREATE TABLE IF NOT EXISTS screen_runs (
+			id BIGSERIAL PRIMARY KEY,
+			screen_id BIGINT NOT NULL REFERENCES saved_screens(id) ON DELETE CASCADE,
+			ran_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			results TEXT NOT NULL DEFAULT '[]',
+			diff TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_screen_runs_screen ON screen_runs(screen_id, id);
+		`,
+		present: tablesExist("saved_screens", "screen_runs"),
+	},
+	{
+		// Named three-statement model scenarios per symbol. assumptions is
+		// the modeling POST body (driver overrides plus DCF inputs) as JSON.
+		Version: 11,
+		Name:    "modeling_scenarios",
+		Up: `
+		CREATE TABLE IF NOT EXISTS modeling_scenarios (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL DEFAULT 1,
+			symbol TEXT NOT NULL,
+			name TEXT NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			assumptions TEXT NOT NULL DEFAULT '{}',
+			sketch_id INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, symbol, name),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (sketch_id) REFERENCES sketches(id) ON DELETE SET NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_modeling_scenarios_sketch ON modeling_scenarios(sketch_id);
+		`,
+		Postgres: `
+		CREATE TABLE IF NOT EXISTS modeling_scenarios (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL DEFAULT 1 REFERENCES users(id),
+			symbol TEXT NOT NULL,
+			name TEXT NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			assumptions TEXT NOT NULL DEFAULT '{}',
+			sketch_id BIGINT REFERENCES sketches(id) ON DELETE SET NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, symbol, name)
+		);
+		CREATE INDEX IF NOT EXISTS idx_modeling_scenarios_sketch ON modeling_scenarios(sketch_id);
+		`,
+		present: tablesExist("modeling_scenarios"),
+	},
+}
+
+func tablesExist(names ...string) func(q querier) (bool, error) {
+	return func(q querier) (bool, error) {
+		query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+		if q.sqlDialect() == postgresDialect {
+			query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+		}
+		for _, name := range names {
+			var n int
+			if err := q.QueryRow(query, name).Scan(&n); err != nil {
+				return false, err
+			}
+			if n == 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+func columnExists(table, column string) func(q querier) (bool, error) {
+	return func(q querier) (bool, error) {
+		query := `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+		if q.sqlDialect() == postgresDialect {
+			query = `SELECT COUNT(*) FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`
+		}
+		var n int
+		err := q.QueryRow(query, table, column).Scan(&n)
+		return n > 0, err
+	}
+}
+
+// sql is the migration's statements for d.
+func (m migration) sql(d dialect) string {
+	if d == postgresDialect && m.Postgres != "" {
+		return m.Postgres
+	}
+	return m.Up
+}
+
+func (m migration) checksum(d dialect) string {
+	sum := sha256.Sum256([]byte(m.sql(d)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Migration states reported by MigrationPlan.
+const (
+	MigrationApplied  = "applied"
+	MigrationAdopted  = "adopted"  // recorded from a pre-versioning database without running
+	MigrationPending  = "pending"  // will run on the next start
+	MigrationAdopt    = "adopt"    // will be adopted on the next start
+	MigrationModified = "modified" // applied, but the code's SQL has changed since
+)
+
+// MigrationStatus is one migration as it stands against a database.
+type MigrationStatus struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	AppliedAt time.Time `json:"appliedAt,omitempty"`
+	SQL       string    `json:"sql"`
+}
+
+// LatestSchemaVersion is the version a fully migrated database is at.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion is the highest migration recorded in the database.
+func (s *Store) SchemaVersion() (int, error) {
+	var v sql.NullInt64
+	err := s.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&v)
+	return int(v.Int64), err
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+	adopted   bool
+}
+
+// applyMigrations brings the database up to date. A database with tables
+// but no schema_migrations predates versioning: each migration it already
+// has is adopted first, then the rest run in order.
+func (s *Store) applyMigrations() error {
+	legacy, err := needsAdoption(s.db)
+	if err != nil {
+		return err
+	}
+	ddl := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			adopted INTEGER NOT NULL DEFAULT 0,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`
+	if s.db.dialect == postgresDialect {
+		ddl = strings.Replace(ddl, "DATETIME", "TIMESTAMPTZ", 1)
+	}
+	if _, err := s.db.Exec(ddl); err != nil {
+		return err
+	}
+	if legacy {
+		if err := s.adopt(); err != nil {
+			return err
+		}
+	}
+
+	applied, err := loadApplied(s.db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if a, ok := applied[m.Version]; ok {
+			if a.checksum != m.checksum(s.db.dialect) {
+				return fmt.Errorf("migration %d (%s) has changed since it was applied; add a new migration instead", m.Version, m.Name)
+			}
+			continue
+		}
+		if err := s.apply(m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) apply(m migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(m.sql(s.db.dialect)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)`,
+		m.Version, m.Name, m.checksum(s.db.dialect)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// adopt records the migrations a pre-versioning database already has.
+func (s *Store) adopt() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, m := range migrations {
+		ok, err := m.present(tx)
+		if err != nil {
+			return fmt.Errorf("adopt migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, checksum, adopted) VALUES (?, ?, ?, 1)`,
+			m.Version, m.Name, m.checksum(tx.dialect)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// needsAdoption reports whether the database has application tables but
+// no schema_migrations — it was created before versioning. Postgres
+// support arrived after, so only SQLite files can be that old.
+func needsAdoption(q querier) (bool, error) {
+	if q.sqlDialect() != sqliteDialect {
+		return false, nil
+	}
+	versioned, err := tablesExist("schema_migrations")(q)
+	if err != nil || versioned {
+		return false, err
+	}
+	var n int
+	err = q.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`).Scan(&n)
+	return n > 0, err
+}
+
+func loadApplied(db *conn) (map[int]appliedMigration, error) {
+	rows, err := db.Query(`SELECT version, checksum, adopted, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int]appliedMigration{}
+	for rows.Next() {
+		var (
+			v       int
+			a       appliedMigration
+			at      sql.NullString
+			adopted int
+		)
+		if err := rows.Scan(&v, &a.checksum, &adopted, &at); err != nil {
+			return nil, err
+		}
+		a.adopted = adopted == 1
+		a.appliedAt = parseSQLiteTime(at.String)
+		out[v] = a
+	}
+	return out, rows.Err()
+}
+
+// MigrationPlan reports every migration's state against the database
+// without changing it — what Open would apply, adopt or reject. A missing
+// SQLite file is a new database: everything is pending.
+func MigrationPlan(driver, dsn string) ([]MigrationStatus, error) {
+	db, err := openDB(driver, dsn, true)
+	if err != nil {
+		return nil, fmt.Errorf("open db: %w", err)
+	}
+	defer db.Close()
+
+	plan := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		plan[i] = MigrationStatus{Version: m.Version, Name: m.Name, State: MigrationPending, SQL: m.sql(db.dialect)}
+	}
+	if db.dialect == sqliteDialect {
+		if _, err := os.Stat(dsn); errors.Is(err, os.ErrNotExist) {
+			return plan, nil
+		}
+	}
+
+	legacy, err := needsAdoption(db)
+	if err != nil {
+		return nil, err
+	}
+	if legacy {
+		for i, m := range migrations {
+			ok, err := m.present(db)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				plan[i].State = MigrationAdopt
+			}
+		}
+		return plan, nil
+	}
+	if versioned, err := tablesExist("schema_migrations")(db); err != nil || !versioned {
+		return plan, err // empty database
+	}
+
+	applied, err := loadApplied(db)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range migrations {
+		a, ok := applied[m.Version]
+		if !ok {
+			continue
+		}
+		plan[i].AppliedAt = a.appliedAt
+		switch {
+		case a.checksum != m.checksum(db.dialect):
+			plan[i].State = MigrationModified
+		case a.adopted:
+			plan[i].State = MigrationAdopted
+		default:
+			plan[i].State = MigrationApplied
+		}
+	}
+	return plan, nil
+}
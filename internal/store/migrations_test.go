@@ -0,0 +1,156 @@
+package store
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrations_Ordered(t *testing.T) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migrations[%d] is version %d, want %d", i, m.Version, i+1)
+		}
+		if m.Name == "" || strings.TrimSpace(m.Up) == "" || m.present == nil {
+			t.Errorf("migration %d is incomplete", m.Version)
+		}
+	}
+}
+
+func testMigrateFreshDatabase(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	v, err := s.SchemaVersion()
+	if err != nil || v != LatestSchemaVersion() {
+		t.Fatalf("SchemaVersion = %d, %v; want %d", v, err, LatestSchemaVersion())
+	}
+	applied, err := loadApplied(s.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations {
+		if a, ok := applied[m.Version]; !ok || a.adopted {
+			t.Errorf("migration %d: %+v, want applied", m.Version, a)
+		}
+	}
+}
+
+// legacyDB builds a database the pre-versioning migrate would have left
+// after running the first n migrations, with one processed filing.
+func legacyDB(t *testing.T, n int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, m := range migrations[:n] {
+		if _, err := db.Exec(m.Up); err != nil {
+			t.Fatalf("migration %d: %v", m.Version, err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO sec_filings (symbol, form_type, filing_date, processed_for_people) VALUES ('AAPL', '8-K', '2024-01-02', 1)`); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func processedFilings(t *testing.T, s *Store) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sec_filings WHERE processed_for_people = 1`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMigrate_AdoptsLegacyDatabase(t *testing.T) {
+	// Current legacy schema: everything is adopted and nothing re-runs, so
+	// processed filings stay processed.
+	path := legacyDB(t, len(migrations))
+	plan, err := MigrationPlan(DriverSQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range plan {
+		if p.State != MigrationAdopt {
+			t.Errorf("plan %d = %s, want %s", p.Version, p.State, MigrationAdopt)
+		}
+	}
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+	if v, _ := s.SchemaVersion(); v != LatestSchemaVersion() {
+		t.Errorf("adopted at version %d, want %d", v, LatestSchemaVersion())
+	}
+	if processedFilings(t, s) != 1 {
+		t.Error("adoption must not reset processed_for_people")
+	}
+	plan, _ = MigrationPlan(DriverSQLite, path)
+	if plan[0].State != MigrationAdopted {
+		t.Errorf("after New, plan[0] = %s, want %s", plan[0].State, MigrationAdopted)
+	}
+}
+
+func TestMigrate_UpgradesOlderLegacyDatabase(t *testing.T) {
+	// From before the SEC fetcher: key_people has no is_current, so
+	// migration 3 runs and filings get a fresh extraction pass.
+	path := legacyDB(t, 2)
+	plan, err := MigrationPlan(DriverSQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range plan {
+		want := MigrationPending
+		if i < 2 {
+			want = MigrationAdopt
+		}
+		if p.State != want {
+			t.Errorf("plan %d = %s, want %s", p.Version, p.State, want)
+		}
+	}
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+	if processedFilings(t, s) != 0 {
+		t.Error("upgrading past migration 3 should reset processed_for_people")
+	}
+	applied, _ := loadApplied(s.db)
+	if !applied[2].adopted || applied[3].adopted || len(applied) != len(migrations) {
+		t.Errorf("applied = %+v", applied)
+	}
+}
+
+func TestMigrate_RejectsEditedMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec(`UPDATE schema_migrations SET checksum = 'stale' WHERE version = 4`); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	plan, err := MigrationPlan(DriverSQLite, path)
+	if err != nil || plan[3].State != MigrationModified {
+		t.Fatalf("plan[3] = %+v, %v; want %s", plan[3], err, MigrationModified)
+	}
+	if _, err := New(path); err == nil || !strings.Contains(err.Error(), "migration 4 (sketch_notes)") {
+		t.Errorf("New = %v, want checksum error for migration 4", err)
+	}
+}
+
+func TestMigrationPlan_MissingFile(t *testing.T) {
+	plan, err := MigrationPlan(DriverSQLite, filepath.Join(t.TempDir(), "absent.db"))
+	if err != nil || len(plan) != len(migrations) || plan[0].State != MigrationPending {
+		t.Errorf("plan = %+v, %v", plan, err)
+	}
+}
@@ -46,14 +46,15 @@ type PaperTrade struct {
 
 // CreatePaperAccount inserts a new account and returns its id.
 func (s *Store) CreatePaperAccount(name, currency string, startingBalance, riskPct float64) (int64, error) {
-	res, err := s.db.Exec(`
+	var id int64
+	err := s.db.QueryRow(`
 		INSERT INTO paper_accounts (name, base_currency, starting_balance, cash_balance, risk_pct)
-		VALUES (?, ?, ?, ?, ?)`,
-		name, currency, startingBalance, startingBalance, riskPct)
+		VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		name, currency, startingBalance, startingBalance, riskPct).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("insert paper_account: %w", err)
 	}
-	return res.LastInsertId()
+	return id, nil
 }
 
 // GetPaperAccounts returns every account, newest first.
@@ -121,23 +122,21 @@ func (s *Store) OpenPaperTrade(t PaperTrade) (int64, error) {
 	}
 	defer tx.Rollback()
 
-	res, err := tx.Exec(`
+	var tradeID int64
+	err = tx.QueryRow(`
 		INSERT INTO paper_trades (
 			account_id, sketch_id, symbol, instrument_type, multiplier, side,
 			entry_price, stop_price, target_price, size,
 			risk_pct_at_entry, risk_amount, status, opened_at, thesis, notes
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'open', ?, ?, ?)`,
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'open', ?, ?, ?)
+		RETURNING id`,
 		t.AccountID, t.SketchID, t.Symbol, t.InstrumentType, t.Multiplier, t.Side,
 		t.EntryPrice, t.StopPrice, t.TargetPrice, t.Size,
 		t.RiskPctAtEntry, t.RiskAmount, t.OpenedAt.UTC(), t.Thesis, t.Notes,
-	)
+	).Scan(&tradeID)
 	if err != nil {
 		return 0, fmt.Errorf("insert paper_trade: %w", err)
 	}
-	tradeID, err := res.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
 
 	if _, err := tx.Exec(`
 		INSERT INTO paper_trade_events (trade_id, event_type, payload)
@@ -256,7 +255,7 @@ func (s *Store) queryPaperTrades(whereClause string, args ...any) ([]PaperTrade,
 	return out, rows.Err()
 }
 
-func (s *Store) scanOnePaperTradeTx(tx *sql.Tx, id int64) (PaperTrade, error) {
+func (s *Store) scanOnePaperTradeTx(tx *txn, id int64) (PaperTrade, error) {
 	row := tx.QueryRow(`
 		SELECT id, account_id, sketch_id, symbol, instrument_type, multiplier, side,
 		       entry_price, stop_price, target_price, size,
@@ -288,7 +287,8 @@ func scanAccount(r rowScanner) (PaperAccount, error) {
 // parseSQLiteTime handles both SQLite's CURRENT_TIMESTAMP format
 // ("2006-01-02 15:04:05") and the mattn/go-sqlite3 driver's time.Time
 // serialization ("2006-01-02 15:04:05.999999999-07:00"), plus RFC3339 as a
-// fallback. Returns zero time only if all attempts fail.
+// fallback — which is also how a Postgres timestamp reads into a string.
+// Returns zero time only if all attempts fail.
 func parseSQLiteTime(s string) time.Time {
 	if s == "" {
 		return time.Time{}
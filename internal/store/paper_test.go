@@ -87,12 +87,12 @@ func TestParseSQLiteTime(t *testing.T) {
 	}
 }
 
-// TestPaperLifecycleRoundtrip exercises the full open → close path through a
-// real SQLite store. Catches driver-format drift: if a future sqlite3 release
+// testPaperLifecycleRoundtrip exercises the full open → close path through a
+// real store. Catches driver-format drift: if a future driver release
 // changes how it serializes time.Time, this test fails loudly because the
 // round-tripped datetime won't be parseable back into a non-zero time.
-func TestPaperLifecycleRoundtrip(t *testing.T) {
-	store := newTestStore(t)
+func testPaperLifecycleRoundtrip(t *testing.T, newStore openStore) {
+	store := newStore(t)
 	defer store.Close()
 
 	accountID, err := store.CreatePaperAccount("Test", "USD", 10000, 0.02)
@@ -0,0 +1,173 @@
+package store
+
+import "time"
+
+// The repositories below split the store by domain. Packages that need one
+// slice of it take the narrow interface, so they don't care which backend
+// — or which fake, in a test — is behind it. *Store implements them all.
+
+// IntelligenceRepository holds the agents' generated company and sector
+// analysis, and the prompt/completion pairs they learn from.
+type IntelligenceRepository interface {
+	Get(symbol string) (*CompanyIntelligence, error)
+	Put(ci *CompanyIntelligence) error
+	IsFresh(symbol string, maxAge time.Duration) bool
+	Rename(oldSymbol, newSymbol string) error
+
+	GetSector(sector string) (*SectorIntelligence, error)
+	PutSector(si *SectorIntelligence) error
+	IsSectorFresh(sector string, maxAge time.Duration) bool
+
+	AddTrainingPair(symbol, prompt, completion, source string, quality float64) error
+	ExportTrainingData(minQuality float64) ([]TrainingPair, error)
+	TrainingDataCount() (int, error)
+}
+
+// WatchlistRepository holds the named symbol lists, static and
+// screener-backed.
+type WatchlistRepository interface {
+	GetWatchlists() ([]Watchlist, error)
+	GetWatchlist(id int64) (*Watchlist, error)
+	GetDynamicWatchlists() ([]Watchlist, error)
+	CreateWatchlist(name string) (*Watchlist, error)
+	CreateDynamicWatchlist(name, query string) (*Watchlist, error)
+	SetWatchlistColor(id int64, color string) error
+	SetWatchlistQuery(id int64, query string) error
+	SetWatchlistColumns(id int64, columns []string) error
+	SetWatchlistMembers(id int64, symbols []string) (joined, left []string, err error)
+	AddToWatchlist(watchlistID int64, symbol string) error
+	RemoveFromWatchlist(watchlistID int64, symbol string) error
+	GetSymbolWatchlists(symbol string) ([]Watchlist, error)
+	GetAllWatchedSymbols() ([]string, error)
+}
+
+// SketchRepository holds the ideas sketchpad's saved comparison charts.
+type SketchRepository interface {
+	CreateSketch(ownerID int64, name string) (int64, error)
+	RenameSketch(id int64, name string) error
+	UpdateSketchNotes(id int64, notes string) error
+	DeleteSketch(id int64) error
+	ListSketches(ownerID int64) ([]Sketch, error)
+	GetSketch(id int64) (*Sketch, error)
+	AddSketchMetric(m SketchMetric) (int64, error)
+	RemoveSketchMetric(id int64) error
+}
+
+// ScenarioRepository holds named three-statement model scenarios.
+type ScenarioRepository interface {
+	CreateScenario(sc ModelingScenario) (int64, error)
+	UpdateScenario(sc ModelingScenario) error
+	CloneScenario(userID, id int64, name string) (int64, error)
+	ListScenarios(userID int64, symbol string) ([]ModelingScenario, error)
+	ListSketchScenarios(sketchID int64) ([]ModelingScenario, error)
+	GetScenario(userID, id int64) (*ModelingScenario, error)
+	DeleteScenario(userID, id int64) error
+}
+
+// PaperRepository holds paper trading accounts and their trades.
+type PaperRepository interface {
+	CreatePaperAccount(name, currency string, startingBalance, riskPct float64) (int64, error)
+	GetPaperAccounts() ([]PaperAccount, error)
+	GetPaperAccount(id int64) (*PaperAccount, error)
+	SetPaperAccountRisk(id int64, riskPct float64) error
+	SetPaperAccountSettled(id int64, settled bool) error
+	OpenPaperTrade(t PaperTrade) (int64, error)
+	GetOpenPaperTrades(accountID int64) ([]PaperTrade, error)
+	GetClosedPaperTrades(accountID int64, limit, offset int) ([]PaperTrade, error)
+	ClosePaperTrade(tradeID int64, exitPrice float64, reason string) error
+}
+
+// EconRepository caches economic series.
+type EconRepository interface {
+	PutEconomicSeries(es *EconomicSeries) error
+	GetEconomicSeries(code string) (*EconomicSeries, error)
+	IsEconomicFresh(code string, ttl time.Duration) bool
+}
+
+// SECRepository caches SEC filings and the form types tracked.
+type SECRepository interface {
+	PutSECFilings(filings []SECFiling) error
+	GetSECFilings(symbol, formType string, limit int) ([]SECFiling, error)
+	IsSECFresh(symbol string, maxAge time.Duration) bool
+	GetUnprocessedSECFilings(symbol, formType string, limit int) ([]SECFiling, error)
+	MarkSECFilingProcessedForPeople(symbol, link string) error
+	GetSECFormTypes() ([]SECFormType, error)
+}
+
+// PeopleRepository holds leadership snapshots and personnel events
+// extracted from filings.
+type PeopleRepository interface {
+	PutKeyPerson(kp KeyPerson) error
+	ReplaceCurrentKeyPeople(symbol, formType string, people []KeyPerson) error
+	GetKeyPeople(symbol string) ([]KeyPerson, error)
+}
+
+// AlertRepository holds news watches and the inbox they fill.
+type AlertRepository interface {
+	CreateNewsWatch(w NewsWatch) (int64, error)
+	ListNewsWatches(userID int64) ([]NewsWatch, error)
+	GetEnabledNewsWatches() ([]NewsWatch, error)
+	SetNewsWatchEnabled(userID, id int64, enabled bool) error
+	DeleteNewsWatch(userID, id int64) error
+	PutInboxItem(it InboxItem) (bool, error)
+	GetInbox(userID int64, unreadOnly bool, limit int) ([]InboxItem, error)
+	MarkInboxRead(userID, id int64) error
+}
+
+// ReferenceRepository holds reference data: SIC codes, security types and
+// corporate actions.
+type ReferenceRepository interface {
+	PopulateSIC(codes []SICCode) error
+	GetSICCode(code string) (*SICCode, error)
+	GetAllSICCodes() ([]SICCode, error)
+	SICCodeCount() int
+	GetSecurityType(symbol string) string
+	PutSecurityType(symbol, typ string) error
+	PutCorporateActions(a CorporateActions) error
+	GetCorporateActions(symbol string) (*CorporateActions, error)
+}
+
+// UniverseRepository holds the local screener's nightly universe
+// snapshot.
+type UniverseRepository interface {
+	PutUniverseRow(r UniverseRow) error
+	GetUniverse() ([]UniverseRow, error)
+	PruneUniverse(keep []string) (int, error)
+}
+
+// ScreenRepository holds saved screens and their run history.
+type ScreenRepository interface {
+	CreateSavedScreen(sc SavedScreen) (int64, error)
+	UpdateSavedScreen(sc SavedScreen) error
+	ListSavedScreens(userID int64) ([]SavedScreen, error)
+	GetSavedScreen(userID, id int64) (*SavedScreen, error)
+	GetScheduledScreens() ([]SavedScreen, error)
+	DeleteSavedScreen(userID, id int64) error
+	AddScreenRun(run ScreenRun) (int64, error)
+	GetScreenRuns(screenID int64, limit int) ([]ScreenRun, error)
+	GetLastScreenResults(screenID int64) (*ScreenRun, error)
+}
+
+// Repository is the whole store.
+type Repository interface {
+	IntelligenceRepository
+	WatchlistRepository
+	SketchRepository
+	PaperRepository
+	EconRepository
+	SECRepository
+	PeopleRepository
+	AlertRepository
+	ReferenceRepository
+	UniverseRepository
+	ScreenRepository
+	ScenarioRepository
+
+	Backup() (*Bundle, error)
+	Restore(b *Bundle, mode RestoreMode) (*RestoreResult, error)
+	SchemaVersion() (int, error)
+	Driver() string
+	Close() error
+}
+
+var _ Repository = (*Store)(nil)
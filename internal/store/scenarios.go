@@ -0,0 +1,149 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ModelingScenario is a named set of three-statement model inputs for a
+// symbol — a bull, base or bear case. Assumptions is the modeling POST
+// body as JSON (per-year driver overrides plus optional DCF inputs); the
+// store doesn't interpret it. SketchID, when set, attaches the scenario
+// to an ideas sketch.
+type ModelingScenario struct {
+	ID          int64           `json:"id"`
+	UserID      int64           `json:"userId"`
+	Symbol      string          `json:"symbol"`
+	Name        string          `json:"name"`
+	Notes       string          `json:"notes"`
+	Assumptions json.RawMessage `json:"assumptions"`
+	SketchID    *int64          `json:"sketchId,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+const scenarioColumns = `id, user_id, symbol, name, notes, assumptions, sketch_id, created_at, updated_at`
+
+// CreateScenario inserts a scenario and returns its id. Names are unique
+// per user and symbol.
+func (s *Store) CreateScenario(sc ModelingScenario) (int64, error) {
+	if sc.UserID == 0 {
+		sc.UserID = 1
+	}
+	var id int64
+	err := s.db.QueryRow(`
+		INSERT INTO modeling_scenarios (user_id, symbol, name, notes, assumptions, sketch_id)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		sc.UserID, strings.ToUpper(sc.Symbol), sc.Name, sc.Notes, scenarioJSON(sc.Assumptions), sc.SketchID).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert modeling_scenario: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateScenario rewrites a scenario's name, notes, assumptions and
+// sketch.
+func (s *Store) UpdateScenario(sc ModelingScenario) error {
+	if sc.UserID == 0 {
+		sc.UserID = 1
+	}
+	res, err := s.db.Exec(`
+		UPDATE modeling_scenarios SET name = ?, notes = ?, assumptions = ?, sketch_id = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?`,
+		sc.Name, sc.Notes, scenarioJSON(sc.Assumptions), sc.SketchID, sc.ID, sc.UserID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("scenario %d not found", sc.ID)
+	}
+	return nil
+}
+
+// CloneScenario copies a scenario under a new name and returns the
+// copy's id. The copy isn't attached to the original's sketch.
+func (s *Store) CloneScenario(userID, id int64, name string) (int64, error) {
+	src, err := s.GetScenario(userID, id)
+	if err != nil {
+		return 0, err
+	}
+	if src == nil {
+		return 0, fmt.Errorf("scenario %d not found", id)
+	}
+	src.Name = name
+	src.SketchID = nil
+	return s.CreateScenario(*src)
+}
+
+// ListScenarios returns a user's scenarios for a symbol by name.
+func (s *Store) ListScenarios(userID int64, symbol string) ([]ModelingScenario, error) {
+	if userID == 0 {
+		userID = 1
+	}
+	return s.queryScenarios(`WHERE user_id = ? AND symbol = ? ORDER BY name`, userID, strings.ToUpper(symbol))
+}
+
+// ListSketchScenarios returns the scenarios attached to a sketch.
+func (s *Store) ListSketchScenarios(sketchID int64) ([]ModelingScenario, error) {
+	return s.queryScenarios(`WHERE sketch_id = ? ORDER BY symbol, name`, sketchID)
+}
+
+// GetScenario returns one of a user's scenarios, or nil.
+func (s *Store) GetScenario(userID, id int64) (*ModelingScenario, error) {
+	if userID == 0 {
+		userID = 1
+	}
+	out, err := s.queryScenarios(`WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+// DeleteScenario removes a scenario.
+func (s *Store) DeleteScenario(userID, id int64) error {
+	if userID == 0 {
+		userID = 1
+	}
+	_, err := s.db.Exec(`DELETE FROM modeling_scenarios WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+func (s *Store) queryScenarios(whereClause string, args ...any) ([]ModelingScenario, error) {
+	rows, err := s.db.Query(`SELECT `+scenarioColumns+` FROM modeling_scenarios `+whereClause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ModelingScenario
+	for rows.Next() {
+		var sc ModelingScenario
+		var assumptions string
+		var sketchID sql.NullInt64
+		var createdAt, updatedAt string
+		if err := rows.Scan(&sc.ID, &sc.UserID, &sc.Symbol, &sc.Name, &sc.Notes, &assumptions,
+			&sketchID, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		sc.Assumptions = json.RawMessage(assumptions)
+		if sketchID.Valid {
+			sc.SketchID = &sketchID.Int64
+		}
+		sc.CreatedAt = parseSQLiteTime(createdAt)
+		sc.UpdatedAt = parseSQLiteTime(updatedAt)
+		out = append(out, sc)
+	}
+	return out, rows.Err()
+}
+
+func scenarioJSON(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "{}"
+	}
+	return string(raw)
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func testScenarios(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	sk, err := s.CreateSketch(0, "AAPL cases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bull := json.RawMessage(`{"revenueGrowth":[0.12,0.11,0.1,0.09,0.08],"dcf":{"terminalGrowth":0.03}}`)
+	id, err := s.CreateScenario(ModelingScenario{Symbol: "aapl", Name: "Bull", Notes: "services mix", Assumptions: bull, SketchID: &sk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateScenario(ModelingScenario{Symbol: "AAPL", Name: "Bull"}); err == nil {
+		t.Error("duplicate name accepted")
+	}
+	if _, err := s.CreateScenario(ModelingScenario{Symbol: "MSFT", Name: "Bull"}); err != nil {
+		t.Errorf("same name on another symbol: %v", err)
+	}
+
+	bear, err := s.CloneScenario(0, id, "Bear")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.ListScenarios(0, "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Name != "Bear" || list[1].Name != "Bull" || list[0].SketchID != nil {
+		t.Fatalf("list = %+v", list)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(list[0].Assumptions, &got); err != nil || got["dcf"] == nil || list[0].Notes != "services mix" {
+		t.Errorf("clone lost its inputs: %s, %q", list[0].Assumptions, list[0].Notes)
+	}
+
+	sc, _ := s.GetScenario(0, bear)
+	sc.Notes = "China slowdown"
+	sc.SketchID = &sk
+	if err := s.UpdateScenario(*sc); err != nil {
+		t.Fatal(err)
+	}
+	attached, err := s.ListSketchScenarios(sk)
+	if err != nil || len(attached) != 2 {
+		t.Fatalf("sketch scenarios = %+v, %v", attached, err)
+	}
+
+	if err := s.DeleteSketch(sk); err != nil {
+		t.Fatal(err)
+	}
+	if sc, _ := s.GetScenario(0, id); sc == nil || sc.SketchID != nil {
+		t.Errorf("deleting the sketch should detach its scenarios, got %+v", sc)
+	}
+
+	if err := s.DeleteScenario(0, id); err != nil {
+		t.Fatal(err)
+	}
+	if sc, err := s.GetScenario(0, id); err != nil || sc != nil {
+		t.Errorf("deleted scenario = %+v, %v", sc, err)
+	}
+	if err := s.UpdateScenario(ModelingScenario{ID: id, Name: "Gone"}); err == nil {
+		t.Error("updating a deleted scenario should fail")
+	}
+}
@@ -0,0 +1,294 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SavedScreen is a named screener query rerun on a cron schedule. Query
+// is canonical /api/screener params; SortBy names the result field runs
+// are ranked by, empty keeping the screener's own order. Schedule is a
+// five-field cron expression read in Timezone.
+type SavedScreen struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"userId"`
+	Name       string     `json:"name"`
+	Query      string     `json:"query"`
+	SortBy     string     `json:"sortBy"`
+	Descending bool       `json:"descending"`
+	Schedule   string     `json:"schedule"`
+	Timezone   string     `json:"timezone"`
+	WebhookURL string     `json:"webhookUrl"`
+	Enabled    bool       `json:"enabled"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastRunAt  *time.Time `json:"lastRunAt,omitempty"`
+}
+
+// ScreenRow is one symbol in a run's result set. Rank is 1-based; Value
+// is the sort field's value when the screen has one.
+type ScreenRow struct {
+	Rank   int      `json:"rank"`
+	Symbol string   `json:"symbol"`
+	Name   string   `json:"name"`
+	Value  *float64 `json:"value,omitempty"`
+}
+
+// ScreenDiff is how a run's result set differs from the previous one.
+type ScreenDiff struct {
+	Entrants []ScreenRow  `json:"entrants"`
+	Dropouts []ScreenRow  `json:"dropouts"` // ranks are the previous run's
+	Moves    []ScreenMove `json:"moves"`
+}
+
+// Empty reports whether nothing changed.
+func (d ScreenDiff) Empty() bool {
+	return len(d.Entrants) == 0 && len(d.Dropouts) == 0 && len(d.Moves) == 0
+}
+
+// ScreenMove is a symbol in both runs at a different rank.
+type ScreenMove struct {
+	Symbol string `json:"symbol"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+}
+
+// ScreenRun is one execution of a saved screen. A failed run has Error
+// set and no results; Diff is nil for the first successful run.
+type ScreenRun struct {
+	ID       int64       `json:"id"`
+	ScreenID int64       `json:"screenId"`
+	RanAt    time.Time   `json:"ranAt"`
+	Results  []ScreenRow `json:"results"`
+	Diff     *ScreenDiff `json:"diff,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// screenRunHistory is how many runs are kept per screen.
+const screenRunHistory = 250
+
+const savedScreenColumns = `id, user_id, name, query, sort_by, descending, schedule, timezone,
+	webhook_url, enabled, created_at, last_run_at`
+
+// CreateSavedScreen inserts a screen and returns its id. UserID 0 maps to
+// the global user, same as news watches.
+func (s *Store) CreateSavedScreen(sc SavedScreen) (int64, error) {
+	if sc.UserID == 0 {
+		sc.UserID = 1
+	}
+	var id int64
+	err := s.db.QueryRow(`
+		INSERT INTO saved_screens (user_id, name, query, sort_by, descending, schedule, timezone, webhook_url, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		sc.UserID, sc.Name, sc.Query, sc.SortBy, boolInt(sc.Descending), sc.Schedule, sc.Timezone,
+		sc.WebhookURL, boolInt(sc.Enabled)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert saved_screen: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateSavedScreen rewrites a screen's definition. Its run history is
+// kept, so the next run diffs against the last result of the old one.
+func (s *Store) UpdateSavedScreen(sc SavedScreen) error {
+	if sc.UserID == 0 {
+		sc.UserID = 1
+	}
+	res, err := s.db.Exec(`
+		UPDATE saved_screens SET name = ?, query = ?, sort_by = ?, descending = ?, schedule = ?,
+			timezone = ?, webhook_url = ?, enabled = ?
+		WHERE id = ? AND user_id = ?`,
+		sc.Name, sc.Query, sc.SortBy, boolInt(sc.Descending), sc.Schedule, sc.Timezone,
+		sc.WebhookURL, boolInt(sc.Enabled), sc.ID, sc.UserID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("saved screen %d not found", sc.ID)
+	}
+	return nil
+}
+
+// ListSavedScreens returns a user's screens by name.
+func (s *Store) ListSavedScreens(userID int64) ([]SavedScreen, error) {
+	if userID == 0 {
+		userID = 1
+	}
+	return s.querySavedScreens(`WHERE user_id = ? ORDER BY name`, userID)
+}
+
+// GetSavedScreen returns one of a user's screens, or nil.
+func (s *Store) GetSavedScreen(userID, id int64) (*SavedScreen, error) {
+	if userID == 0 {
+		userID = 1
+	}
+	out, err := s.querySavedScreens(`WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+// GetScheduledScreens returns every enabled screen with a schedule,
+// across all users. The scheduler calls this each tick.
+func (s *Store) GetScheduledScreens() ([]SavedScreen, error) {
+	return s.querySavedScreens(`WHERE enabled = 1 AND schedule != '' ORDER BY id`)
+}
+
+// DeleteSavedScreen removes a screen and its run history.
+func (s *Store) DeleteSavedScreen(userID, id int64) error {
+	if userID == 0 {
+		userID = 1
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`DELETE FROM saved_screens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(`DELETE FROM screen_runs WHERE screen_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddScreenRun records a run, stamps the screen's last run time and
+// trims its history to the newest screenRunHistory runs.
+func (s *Store) AddScreenRun(run ScreenRun) (int64, error) {
+	results, err := json.Marshal(nonNilRows(run.Results))
+	if err != nil {
+		return 0, fmt.Errorf("encode results: %w", err)
+	}
+	diff := ""
+	if run.Diff != nil {
+		b, err := json.Marshal(run.Diff)
+		if err != nil {
+			return 0, fmt.Errorf("encode diff: %w", err)
+		}
+		diff = string(b)
+	}
+	if run.RanAt.IsZero() {
+		run.RanAt = time.Now()
+	}
+	ranAt := run.RanAt.UTC()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var id int64
+	if err := tx.QueryRow(`
+		INSERT INTO screen_runs (screen_id, ran_at, results, diff, error)
+		VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		run.ScreenID, ranAt, string(results), diff, run.Error).Scan(&id); err != nil {
+		return 0, fmt.Errorf("insert screen_run: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE saved_screens SET last_run_at = ? WHERE id = ?`, ranAt, run.ScreenID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM screen_runs WHERE screen_id = ? AND id NOT IN (
+			SELECT id FROM screen_runs WHERE screen_id = ? ORDER BY id DESC LIMIT ?)`,
+		run.ScreenID, run.ScreenID, screenRunHistory); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// GetScreenRuns returns a screen's runs, newest first.
+func (s *Store) GetScreenRuns(screenID int64, limit int) ([]ScreenRun, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return s.queryScreenRuns(`WHERE screen_id = ? ORDER BY id DESC LIMIT ?`, screenID, limit)
+}
+
+// GetLastScreenResults returns a screen's most recent successful run, or
+// nil if it has none — what the next run is diffed against.
+func (s *Store) GetLastScreenResults(screenID int64) (*ScreenRun, error) {
+	out, err := s.queryScreenRuns(`WHERE screen_id = ? AND error = '' ORDER BY id DESC LIMIT 1`, screenID)
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+func (s *Store) querySavedScreens(whereClause string, args ...any) ([]SavedScreen, error) {
+	rows, err := s.db.Query(`SELECT `+savedScreenColumns+` FROM saved_screens `+whereClause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SavedScreen
+	for rows.Next() {
+		var sc SavedScreen
+		var descending, enabled int
+		var createdAt string
+		var lastRun sql.NullString
+		if err := rows.Scan(&sc.ID, &sc.UserID, &sc.Name, &sc.Query, &sc.SortBy, &descending,
+			&sc.Schedule, &sc.Timezone, &sc.WebhookURL, &enabled, &createdAt, &lastRun); err != nil {
+			return nil, err
+		}
+		sc.Descending = descending != 0
+		sc.Enabled = enabled != 0
+		sc.CreatedAt = parseSQLiteTime(createdAt)
+		if lastRun.Valid {
+			t := parseSQLiteTime(lastRun.String)
+			sc.LastRunAt = &t
+		}
+		out = append(out, sc)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) queryScreenRuns(whereClause string, args ...any) ([]ScreenRun, error) {
+	rows, err := s.db.Query(`SELECT id, screen_id, ran_at, results, diff, error FROM screen_runs `+whereClause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ScreenRun
+	for rows.Next() {
+		var run ScreenRun
+		var ranAt, results, diff string
+		if err := rows.Scan(&run.ID, &run.ScreenID, &ranAt, &results, &diff, &run.Error); err != nil {
+			return nil, err
+		}
+		run.RanAt = parseSQLiteTime(ranAt)
+		if err := json.Unmarshal([]byte(results), &run.Results); err != nil {
+			return nil, fmt.Errorf("run %d results: %w", run.ID, err)
+		}
+		if diff != "" {
+			run.Diff = &ScreenDiff{}
+			if err := json.Unmarshal([]byte(diff), run.Diff); err != nil {
+				return nil, fmt.Errorf("run %d diff: %w", run.ID, err)
+			}
+		}
+		out = append(out, run)
+	}
+	return out, rows.Err()
+}
+
+func nonNilRows(rows []ScreenRow) []ScreenRow {
+	if rows == nil {
+		return []ScreenRow{}
+	}
+	return rows
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
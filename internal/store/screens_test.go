@@ -0,0 +1,83 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func testSavedScreens(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	id, err := s.CreateSavedScreen(SavedScreen{
+		Name: "Gappers", Query: "changeFromOpenMin=2", SortBy: "changeFromOpen", Descending: true,
+		Schedule: "35 9 * * 1-5", Timezone: "America/New_York", Enabled: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateSavedScreen(SavedScreen{Name: "Gappers", Query: "sector=Energy"}); err == nil {
+		t.Error("duplicate name accepted")
+	}
+	s.CreateSavedScreen(SavedScreen{Name: "Adhoc", Query: "sector=Energy", Enabled: true})
+
+	scheduled, err := s.GetScheduledScreens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scheduled) != 1 || scheduled[0].ID != id || !scheduled[0].Descending || scheduled[0].LastRunAt != nil {
+		t.Fatalf("scheduled = %+v", scheduled)
+	}
+
+	if last, err := s.GetLastScreenResults(id); err != nil || last != nil {
+		t.Fatalf("last before any run = %+v, %v", last, err)
+	}
+	v := 4.5
+	first := ScreenRun{ScreenID: id, RanAt: time.Date(2026, 10, 16, 13, 35, 0, 0, time.UTC),
+		Results: []ScreenRow{{Rank: 1, Symbol: "NVDA", Name: "NVIDIA", Value: &v}, {Rank: 2, Symbol: "AMD"}}}
+	if _, err := s.AddScreenRun(first); err != nil {
+		t.Fatal(err)
+	}
+	s.AddScreenRun(ScreenRun{ScreenID: id, Error: "upstream down"})
+
+	last, err := s.GetLastScreenResults(id)
+	if err != nil || last == nil {
+		t.Fatalf("last = %+v, %v", last, err)
+	}
+	if len(last.Results) != 2 || *last.Results[0].Value != 4.5 || last.Diff != nil || !last.RanAt.Equal(first.RanAt) {
+		t.Errorf("last successful run = %+v", last)
+	}
+
+	diff := &ScreenDiff{Entrants: []ScreenRow{{Rank: 2, Symbol: "SMCI"}}, Moves: []ScreenMove{{Symbol: "AMD", From: 2, To: 1}}}
+	s.AddScreenRun(ScreenRun{ScreenID: id, Results: []ScreenRow{{Rank: 1, Symbol: "AMD"}, {Rank: 2, Symbol: "SMCI"}}, Diff: diff})
+	runs, err := s.GetScreenRuns(id, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 || runs[0].Diff == nil || runs[0].Diff.Moves[0].To != 1 || runs[1].Error != "upstream down" || len(runs[1].Results) != 0 {
+		t.Fatalf("runs = %+v", runs)
+	}
+
+	sc, err := s.GetSavedScreen(0, id)
+	if err != nil || sc == nil || sc.LastRunAt == nil {
+		t.Fatalf("screen after runs = %+v, %v", sc, err)
+	}
+	sc.Enabled = false
+	if err := s.UpdateSavedScreen(*sc); err != nil {
+		t.Fatal(err)
+	}
+	if scheduled, _ := s.GetScheduledScreens(); len(scheduled) != 0 {
+		t.Errorf("disabled screen still scheduled: %+v", scheduled)
+	}
+	if other, _ := s.GetSavedScreen(2, id); other != nil {
+		t.Error("another user's screen returned")
+	}
+
+	if err := s.DeleteSavedScreen(0, id); err != nil {
+		t.Fatal(err)
+	}
+	if runs, _ := s.GetScreenRuns(id, 10); len(runs) != 0 {
+		t.Errorf("runs survived delete: %d", len(runs))
+	}
+	if list, _ := s.ListSavedScreens(0); len(list) != 1 || list[0].Name != "Adhoc" {
+		t.Errorf("list = %+v", list)
+	}
+}
@@ -4,10 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"strings"
+	"sort"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // CompanyIntelligence holds AI-generated analysis for a security.
@@ -39,13 +37,22 @@ type TrainingPair struct {
 	QualityScore float64   `json:"qualityScore"`
 }
 
-// Store manages the SQLite database for company intelligence.
+// Store is stocktopus's database: SQLite by default, or Postgres for a
+// shared instance. It implements Repository.
 type Store struct {
-	db *sql.DB
+	db *conn
 }
 
+// New opens the SQLite database at dbPath, creating it if needed.
 func New(dbPath string) (*Store, error) {
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL")
+	return Open(DriverSQLite, dbPath)
+}
+
+// Open connects to the backend named by driver — a file path for sqlite,
+// a connection string (URL or key=value) for postgres — and migrates its
+// schema to the latest version.
+func Open(driver, dsn string) (*Store, error) {
+	db, err := openDB(driver, dsn, false)
 	if err != nil {
 		return nil, fmt.Errorf("open db: %w", err)
 	}
@@ -59,248 +66,19 @@ func New(dbPath string) (*Store, error) {
 	return s, nil
 }
 
+// Driver names the backend the store runs on.
+func (s *Store) Driver() string {
+	return s.db.dialect.String()
+}
+
 func (s *Store) Close() error {
 	return s.db.Close()
 }
 
 func (s *Store) migrate() error {
-	_, err := s.db.Exec(`
-		CREATE TABLE IF NOT EXISTS company_intelligence (
-			symbol TEXT PRIMARY KEY,
-			company_name TEXT,
-			sector TEXT,
-			analysis JSON,
-			sentiment REAL DEFAULT 0,
-			risk_score REAL DEFAULT 0,
-			summary TEXT DEFAULT '',
-			key_risks JSON DEFAULT '[]',
-			opportunities JSON DEFAULT '[]',
-			competitors JSON DEFAULT '[]',
-			sources JSON DEFAULT '[]',
-			generated_at DATETIME,
-			model_version TEXT DEFAULT '',
-			confidence REAL DEFAULT 0,
-			raw_data JSON DEFAULT '{}'
-		);
-
-		CREATE TABLE IF NOT EXISTS training_data (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			symbol TEXT,
-			prompt TEXT,
-			completion TEXT,
-			source TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			quality_score REAL DEFAULT 0
-		);
-
-		CREATE INDEX IF NOT EXISTS idx_training_symbol ON training_data(symbol);
-
-		CREATE TABLE IF NOT EXISTS watchlists (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL UNIQUE,
-			color TEXT NOT NULL DEFAULT '#ff8800',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS watchlist_symbols (
-			watchlist_id INTEGER NOT NULL,
-			symbol TEXT NOT NULL,
-			added_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			PRIMARY KEY (watchlist_id, symbol),
-			FOREIGN KEY (watchlist_id) REFERENCES watchlists(id) ON DELETE CASCADE
-		);
-
-		INSERT OR IGNORE INTO watchlists (name, color) VALUES ('Default', '#ff8800');
-
-		CREATE TABLE IF NOT EXISTS sic_codes (
-			sic_code TEXT PRIMARY KEY,
-			industry_title TEXT NOT NULL,
-			office TEXT DEFAULT ''
-		);
-
-		CREATE TABLE IF NOT EXISTS sector_intelligence (
-			sector TEXT PRIMARY KEY,
-			industry TEXT DEFAULT '',
-			peers JSON DEFAULT '[]',
-			news JSON DEFAULT '[]',
-			performance JSON DEFAULT '{}',
-			generated_at DATETIME,
-			model_version TEXT DEFAULT ''
-		);
-
-		CREATE TABLE IF NOT EXISTS sec_filings (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			symbol TEXT NOT NULL,
-			cik TEXT NOT NULL DEFAULT '',
-			form_type TEXT NOT NULL,
-			filing_date TEXT NOT NULL,
-			accepted_date TEXT DEFAULT '',
-			link TEXT DEFAULT '',
-			final_link TEXT DEFAULT '',
-			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE(symbol, form_type, filing_date, link)
-		);
-		CREATE INDEX IF NOT EXISTS idx_sec_symbol ON sec_filings(symbol);
-		CREATE INDEX IF NOT EXISTS idx_sec_form ON sec_filings(form_type);
-
-		CREATE TABLE IF NOT EXISTS sec_form_types (
-			form_type TEXT PRIMARY KEY,
-			title TEXT NOT NULL DEFAULT '',
-			purpose TEXT NOT NULL DEFAULT '',
-			timing TEXT NOT NULL DEFAULT '',
-			category TEXT NOT NULL DEFAULT ''
-		);
-
-		CREATE TABLE IF NOT EXISTS key_people (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			symbol TEXT NOT NULL,
-			name TEXT NOT NULL,
-			title TEXT DEFAULT '',
-			event_type TEXT DEFAULT '',
-			event_date TEXT DEFAULT '',
-			source TEXT DEFAULT '',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-		CREATE INDEX IF NOT EXISTS idx_people_symbol ON key_people(symbol);
-
-		CREATE TABLE IF NOT EXISTS users (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			handle TEXT NOT NULL UNIQUE,
-			display_name TEXT NOT NULL DEFAULT '',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-
-		-- Single global user for now — every sketch belongs to this row.
-		-- Refactored to per-user later by adding more rows + auth.
-		INSERT OR IGNORE INTO users (id, handle, display_name) VALUES (1, 'global', 'Global');
-
-		CREATE TABLE IF NOT EXISTS sketches (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			owner_id INTEGER NOT NULL DEFAULT 1,
-			name TEXT NOT NULL DEFAULT '',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (owner_id) REFERENCES users(id)
-		);
-		CREATE INDEX IF NOT EXISTS idx_sketches_owner ON sketches(owner_id);
-
-		CREATE TABLE IF NOT EXISTS sketch_metrics (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			sketch_id INTEGER NOT NULL,
-			kind TEXT NOT NULL,        -- 'price' | 'financial' | 'commodity' | 'forex' | 'crypto' | 'index' | 'economic'
-			identifier TEXT NOT NULL,  -- 'AAPL' | 'AAPL.revenue' | 'GCUSD' | 'EURUSD' | 'BTCUSD' | 'SPX' | 'UNRATE'
-			label TEXT NOT NULL DEFAULT '',
-			color TEXT NOT NULL DEFAULT '',
-			position INTEGER NOT NULL DEFAULT 0,
-			FOREIGN KEY (sketch_id) REFERENCES sketches(id) ON DELETE CASCADE
-		);
-		CREATE INDEX IF NOT EXISTS idx_sketch_metrics_sketch ON sketch_metrics(sketch_id);
-
-		CREATE TABLE IF NOT EXISTS economic_series (
-			code TEXT PRIMARY KEY,        -- FRED series ID (e.g. UNRATE)
-			title TEXT NOT NULL DEFAULT '',
-			category TEXT NOT NULL DEFAULT '',
-			frequency TEXT NOT NULL DEFAULT '', -- D / W / M / Q / A — from FRED
-			units TEXT NOT NULL DEFAULT '',
-			observations TEXT NOT NULL DEFAULT '[]', -- JSON [{date, value}, ...] in ascending date order
-			source_updated_at TEXT NOT NULL DEFAULT '', -- FRED's last_updated (string, parse on display)
-			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS security_types (
-			symbol TEXT PRIMARY KEY,
-			type TEXT NOT NULL,           -- stock / crypto / forex / index / etf
-			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS paper_accounts (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL,
-			base_currency TEXT NOT NULL DEFAULT 'USD',
-			starting_balance REAL NOT NULL,
-			cash_balance REAL NOT NULL,
-			risk_pct REAL NOT NULL DEFAULT 0.02,
-			settled INTEGER NOT NULL DEFAULT 0,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS paper_trades (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			account_id INTEGER NOT NULL,
-			sketch_id INTEGER,
-			symbol TEXT NOT NULL,
-			instrument_type TEXT NOT NULL,
-			multiplier REAL NOT NULL DEFAULT 1.0,
-			side TEXT NOT NULL,
-			entry_price REAL NOT NULL,
-			stop_price REAL NOT NULL,
-			target_price REAL,
-			size REAL NOT NULL,
-			risk_pct_at_entry REAL NOT NULL,
-			risk_amount REAL NOT NULL,
-			status TEXT NOT NULL DEFAULT 'open',
-			opened_at DATETIME NOT NULL,
-			closed_at DATETIME,
-			exit_price REAL,
-			realized_pnl REAL,
-			thesis TEXT NOT NULL DEFAULT '',
-			notes TEXT NOT NULL DEFAULT '',
-			FOREIGN KEY (account_id) REFERENCES paper_accounts(id),
-			FOREIGN KEY (sketch_id) REFERENCES sketches(id) ON DELETE SET NULL
-		);
-		CREATE INDEX IF NOT EXISTS idx_paper_trades_account ON paper_trades(account_id);
-		CREATE INDEX IF NOT EXISTS idx_paper_trades_status ON paper_trades(status);
-
-		CREATE TABLE IF NOT EXISTS paper_trade_events (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			trade_id INTEGER NOT NULL,
-			event_type TEXT NOT NULL,
-			payload TEXT NOT NULL DEFAULT '{}',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (trade_id) REFERENCES paper_trades(id) ON DELETE CASCADE
-		);
-		CREATE INDEX IF NOT EXISTS idx_paper_trade_events_trade ON paper_trade_events(trade_id);
-	`)
-	if err != nil {
-		return err
-	}
-
-	// Idempotent column adds — ignore "duplicate column" errors on existing DBs.
-	if _, err := s.db.Exec(`ALTER TABLE sec_filings ADD COLUMN processed_for_people INTEGER NOT NULL DEFAULT 0`); err != nil &&
-		!strings.Contains(err.Error(), "duplicate column") {
-		return err
-	}
-
-	// Adding is_current is the upgrade signal from the broken-Python-fetcher era
-	// to the SEC-compliant Go fetcher: if the column doesn't yet exist, reset the
-	// processed flag so previously-attempted filings get a real extraction pass.
-	upgradedToSECFetcher := false
-	if _, err := s.db.Exec(`ALTER TABLE key_people ADD COLUMN is_current INTEGER NOT NULL DEFAULT 0`); err != nil {
-		if !strings.Contains(err.Error(), "duplicate column") {
-			return err
-		}
-	} else {
-		upgradedToSECFetcher = true
-	}
-	if _, err := s.db.Exec(`ALTER TABLE key_people ADD COLUMN as_of_date TEXT DEFAULT ''`); err != nil &&
-		!strings.Contains(err.Error(), "duplicate column") {
-		return err
-	}
-	if _, err := s.db.Exec(`ALTER TABLE key_people ADD COLUMN form_type TEXT DEFAULT ''`); err != nil &&
-		!strings.Contains(err.Error(), "duplicate column") {
+	if err := s.applyMigrations(); err != nil {
 		return err
 	}
-	if _, err := s.db.Exec(`ALTER TABLE sketches ADD COLUMN notes TEXT NOT NULL DEFAULT ''`); err != nil &&
-		!strings.Contains(err.Error(), "duplicate column") {
-		return err
-	}
-	if upgradedToSECFetcher {
-		if _, err := s.db.Exec(`UPDATE sec_filings SET processed_for_people = 0`); err != nil {
-			return err
-		}
-	}
-
 	return s.seedSECFormTypes()
 }
 
@@ -332,7 +110,7 @@ func (s *Store) Get(symbol string) (*CompanyIntelligence, error) {
 	json.Unmarshal(opportunities, &ci.Opportunities)
 	json.Unmarshal(competitors, &ci.Competitors)
 	json.Unmarshal(sources, &ci.Sources)
-	ci.GeneratedAt, _ = time.Parse("2006-01-02 15:04:05Z", generatedAt+"Z")
+	ci.GeneratedAt = parseSQLiteTime(generatedAt)
 
 	return &ci, nil
 }
@@ -419,7 +197,7 @@ func (s *Store) ExportTrainingData(minQuality float64) ([]TrainingPair, error) {
 		if err != nil {
 			return nil, err
 		}
-		tp.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
+		tp.CreatedAt = parseSQLiteTime(createdAt)
 		pairs = append(pairs, tp)
 	}
 	return pairs, nil
@@ -434,12 +212,38 @@ func (s *Store) TrainingDataCount() (int, error) {
 
 // ── Watchlists ──
 
-// Watchlist represents a named watchlist with a color.
+// Watchlist represents a named watchlist with a color. A watchlist with a
+// Query is dynamic: its symbols are what that saved screener query last
+// returned, and they change only when it's re-evaluated. Columns are the
+// computed column specs the watchlist shows alongside its quotes.
 type Watchlist struct {
-	ID      int64    `json:"id"`
-	Name    string   `json:"name"`
-	Color   string   `json:"color"`
-	Symbols []string `json:"symbols"`
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Color       string     `json:"color"`
+	Symbols     []string   `json:"symbols"`
+	Query       string     `json:"query,omitempty"`
+	Dynamic     bool       `json:"dynamic"`
+	EvaluatedAt *time.Time `json:"evaluatedAt,omitempty"`
+	Columns     []string   `json:"columns,omitempty"`
+}
+
+const watchlistColumns = `id, name, color, query, evaluated_at, columns`
+
+func scanWatchlist(row interface{ Scan(...any) error }) (Watchlist, error) {
+	var w Watchlist
+	var evaluated sql.NullString
+	var columns string
+	if err := row.Scan(&w.ID, &w.Name, &w.Color, &w.Query, &evaluated, &columns); err != nil {
+		return w, err
+	}
+	if columns != "" {
+		json.Unmarshal([]byte(columns), &w.Columns)
+	}
+	w.Dynamic = w.Query != ""
+	if t := parseSQLiteTime(evaluated.String); !t.IsZero() {
+		w.EvaluatedAt = &t
+	}
+	return w, nil
 }
 
 // Preset colors for new watchlists
@@ -450,7 +254,26 @@ var watchlistColors = []string{
 
 // GetWatchlists returns all watchlists with their symbols.
 func (s *Store) GetWatchlists() ([]Watchlist, error) {
-	rows, err := s.db.Query(`SELECT id, name, color FROM watchlists ORDER BY id`)
+	return s.queryWatchlists(`SELECT ` + watchlistColumns + ` FROM watchlists ORDER BY id`)
+}
+
+// GetDynamicWatchlists returns the watchlists backed by a screener query.
+func (s *Store) GetDynamicWatchlists() ([]Watchlist, error) {
+	return s.queryWatchlists(`SELECT ` + watchlistColumns + ` FROM watchlists WHERE query <> '' ORDER BY id`)
+}
+
+// GetWatchlist returns one watchlist with its symbols, or nil if there's
+// no such list.
+func (s *Store) GetWatchlist(id int64) (*Watchlist, error) {
+	lists, err := s.queryWatchlists(`SELECT `+watchlistColumns+` FROM watchlists WHERE id = ?`, id)
+	if err != nil || len(lists) == 0 {
+		return nil, err
+	}
+	return &lists[0], nil
+}
+
+func (s *Store) queryWatchlists(query string, args ...any) ([]Watchlist, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -458,8 +281,8 @@ func (s *Store) GetWatchlists() ([]Watchlist, error) {
 
 	var lists []Watchlist
 	for rows.Next() {
-		var w Watchlist
-		if err := rows.Scan(&w.ID, &w.Name, &w.Color); err != nil {
+		w, err := scanWatchlist(rows)
+		if err != nil {
 			return nil, err
 		}
 		lists = append(lists, w)
@@ -467,21 +290,27 @@ func (s *Store) GetWatchlists() ([]Watchlist, error) {
 
 	// Load symbols for each
 	for i := range lists {
-		symRows, err := s.db.Query(`SELECT symbol FROM watchlist_symbols WHERE watchlist_id = ? ORDER BY added_at`, lists[i].ID)
-		if err != nil {
-			continue
-		}
-		for symRows.Next() {
-			var sym string
-			symRows.Scan(&sym)
-			lists[i].Symbols = append(lists[i].Symbols, sym)
-		}
-		symRows.Close()
+		lists[i].Symbols, _ = s.watchlistSymbols(lists[i].ID)
 	}
 
 	return lists, nil
 }
 
+func (s *Store) watchlistSymbols(id int64) ([]string, error) {
+	rows, err := s.db.Query(`SELECT symbol FROM watchlist_symbols WHERE watchlist_id = ? ORDER BY added_at`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var syms []string
+	for rows.Next() {
+		var sym string
+		rows.Scan(&sym)
+		syms = append(syms, sym)
+	}
+	return syms, rows.Err()
+}
+
 // CreateWatchlist creates a new watchlist with an auto-assigned color.
 func (s *Store) CreateWatchlist(name string) (*Watchlist, error) {
 	// Count existing for color assignment
@@ -489,20 +318,128 @@ func (s *Store) CreateWatchlist(name string) (*Watchlist, error) {
 	s.db.QueryRow(`SELECT COUNT(*) FROM watchlists`).Scan(&count)
 	color := watchlistColors[count%len(watchlistColors)]
 
-	res, err := s.db.Exec(`INSERT INTO watchlists (name, color) VALUES (?, ?)`, name, color)
+	var id int64
+	err := s.db.QueryRow(`INSERT INTO watchlists (name, color) VALUES (?, ?) RETURNING id`, name, color).Scan(&id)
 	if err != nil {
 		return nil, err
 	}
-	id, _ := res.LastInsertId()
 	return &Watchlist{ID: id, Name: name, Color: color}, nil
 }
 
+// SetWatchlistColor changes a watchlist's colour.
+func (s *Store) SetWatchlistColor(id int64, color string) error {
+	_, err := s.db.Exec(`UPDATE watchlists SET color = ? WHERE id = ?`, color, id)
+	return err
+}
+
+// SetWatchlistColumns replaces the computed column specs a watchlist shows.
+func (s *Store) SetWatchlistColumns(id int64, columns []string) error {
+	raw := ""
+	if len(columns) > 0 {
+		b, err := json.Marshal(columns)
+		if err != nil {
+			return err
+		}
+		raw = string(b)
+	}
+	res, err := s.db.Exec(`UPDATE watchlists SET columns = ? WHERE id = ?`, raw, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("watchlist %d not found", id)
+	}
+	return nil
+}
+
+// CreateDynamicWatchlist creates a watchlist whose membership follows the
+// screener query. It starts empty; SetWatchlistMembers fills it.
+func (s *Store) CreateDynamicWatchlist(name, query string) (*Watchlist, error) {
+	wl, err := s.CreateWatchlist(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SetWatchlistQuery(wl.ID, query); err != nil {
+		return nil, err
+	}
+	wl.Query, wl.Dynamic = query, query != ""
+	return wl, nil
+}
+
+// SetWatchlistQuery changes the screener query behind a watchlist. An
+// empty query makes it static again, keeping its current symbols.
+func (s *Store) SetWatchlistQuery(id int64, query string) error {
+	res, err := s.db.Exec(`UPDATE watchlists SET query = ?, evaluated_at = NULL WHERE id = ?`, query, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("watchlist %d not found", id)
+	}
+	return nil
+}
+
+// SetWatchlistMembers replaces a watchlist's symbols with the given set in
+// one transaction, stamps evaluated_at, and reports which symbols joined
+// and which left. Symbols that stay keep their added_at.
+func (s *Store) SetWatchlistMembers(id int64, symbols []string) (joined, left []string, err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	current := map[string]bool{}
+	rows, err := tx.Query(`SELECT symbol FROM watchlist_symbols WHERE watchlist_id = ?`, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var sym string
+		if err := rows.Scan(&sym); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		current[sym] = true
+	}
+	rows.Close()
+
+	want := map[string]bool{}
+	for _, sym := range symbols {
+		if sym == "" || want[sym] {
+			continue
+		}
+		want[sym] = true
+		if !current[sym] {
+			if _, err := tx.Exec(`INSERT INTO watchlist_symbols (watchlist_id, symbol) VALUES (?, ?)`, id, sym); err != nil {
+				return nil, nil, err
+			}
+			joined = append(joined, sym)
+		}
+	}
+	for sym := range current {
+		if !want[sym] {
+			if _, err := tx.Exec(`DELETE FROM watchlist_symbols WHERE watchlist_id = ? AND symbol = ?`, id, sym); err != nil {
+				return nil, nil, err
+			}
+			left = append(left, sym)
+		}
+	}
+	sort.Strings(left)
+
+	now := time.Now().UTC().Format("2006-01-02 15:04:05")
+	if _, err := tx.Exec(`UPDATE watchlists SET evaluated_at = ? WHERE id = ?`, now, id); err != nil {
+		return nil, nil, err
+	}
+	return joined, left, tx.Commit()
+}
+
 // AddToWatchlist adds a symbol to a watchlist. Uses default watchlist if watchlistID is 0.
 func (s *Store) AddToWatchlist(watchlistID int64, symbol string) error {
 	if watchlistID == 0 {
 		s.db.QueryRow(`SELECT id FROM watchlists ORDER BY id LIMIT 1`).Scan(&watchlistID)
 	}
-	_, err := s.db.Exec(`INSERT OR IGNORE INTO watchlist_symbols (watchlist_id, symbol) VALUES (?, ?)`,
+	_, err := s.db.Exec(`INSERT INTO watchlist_symbols (watchlist_id, symbol) VALUES (?, ?) ON CONFLICT DO NOTHING`,
 		watchlistID, symbol)
 	return err
 }
@@ -517,7 +454,7 @@ func (s *Store) RemoveFromWatchlist(watchlistID int64, symbol string) error {
 // GetSymbolWatchlists returns which watchlists a symbol belongs to.
 func (s *Store) GetSymbolWatchlists(symbol string) ([]Watchlist, error) {
 	rows, err := s.db.Query(`
-		SELECT w.id, w.name, w.color FROM watchlists w
+		SELECT w.id, w.name, w.color, w.query, w.evaluated_at, w.columns FROM watchlists w
 		JOIN watchlist_symbols ws ON w.id = ws.watchlist_id
 		WHERE ws.symbol = ?`, symbol)
 	if err != nil {
@@ -527,8 +464,7 @@ func (s *Store) GetSymbolWatchlists(symbol string) ([]Watchlist, error) {
 
 	var lists []Watchlist
 	for rows.Next() {
-		var w Watchlist
-		rows.Scan(&w.ID, &w.Name, &w.Color)
+		w, _ := scanWatchlist(rows)
 		lists = append(lists, w)
 	}
 	return lists, nil
@@ -556,7 +492,7 @@ func (s *Store) GetSector(sector string) (*SectorIntelligence, error) {
 	if err != nil {
 		return nil, err
 	}
-	si.GeneratedAt, _ = time.Parse("2006-01-02 15:04:05Z", genAt+"Z")
+	si.GeneratedAt = parseSQLiteTime(genAt)
 	return &si, nil
 }
 
@@ -597,7 +533,7 @@ func (s *Store) PopulateSIC(codes []SICCode) error {
 	if err != nil {
 		return err
 	}
-	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO sic_codes (sic_code, industry_title, office) VALUES (?, ?, ?)`)
+	stmt, err := tx.Prepare(`INSERT INTO sic_codes (sic_code, industry_title, office) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -723,7 +659,7 @@ func (s *Store) seedSECFormTypes() error {
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO sec_form_types (form_type, title, purpose, timing, category) VALUES (?, ?, ?, ?, ?)`)
+	stmt, err := tx.Prepare(`INSERT INTO sec_form_types (form_type, title, purpose, timing, category) VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`)
 	if err != nil {
 		return err
 	}
@@ -743,7 +679,7 @@ func (s *Store) PutSECFilings(filings []SECFiling) error {
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO sec_filings (symbol, cik, form_type, filing_date, accepted_date, link, final_link) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	stmt, err := tx.Prepare(`INSERT INTO sec_filings (symbol, cik, form_type, filing_date, accepted_date, link, final_link) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`)
 	if err != nil {
 		return err
 	}
@@ -788,12 +724,12 @@ func (s *Store) GetSECFilings(symbol, formType string, limit int) ([]SECFiling,
 
 // IsSECFresh checks if we have filings cached recently enough.
 func (s *Store) IsSECFresh(symbol string, maxAge time.Duration) bool {
-	var fetchedAt time.Time
+	var fetchedAt sql.NullString
 	err := s.db.QueryRow(`SELECT MAX(fetched_at) FROM sec_filings WHERE symbol = ?`, symbol).Scan(&fetchedAt)
-	if err != nil {
+	if err != nil || !fetchedAt.Valid {
 		return false
 	}
-	return time.Since(fetchedAt) < maxAge
+	return time.Since(parseSQLiteTime(fetchedAt.String)) < maxAge
 }
 
 // GetUnprocessedSECFilings returns filings that have not yet had key-people extraction attempted.
@@ -944,11 +880,9 @@ func (s *Store) CreateSketch(ownerID int64, name string) (int64, error) {
 	if ownerID == 0 {
 		ownerID = 1
 	}
-	res, err := s.db.Exec(`INSERT INTO sketches (owner_id, name) VALUES (?, ?)`, ownerID, name)
-	if err != nil {
-		return 0, err
-	}
-	return res.LastInsertId()
+	var id int64
+	err := s.db.QueryRow(`INSERT INTO sketches (owner_id, name) VALUES (?, ?) RETURNING id`, ownerID, name).Scan(&id)
+	return id, err
 }
 
 // RenameSketch updates an existing sketch's name and bumps updated_at.
@@ -963,9 +897,13 @@ func (s *Store) UpdateSketchNotes(id int64, notes string) error {
 	return err
 }
 
-// DeleteSketch removes a sketch and (cascade) its metrics.
+// DeleteSketch removes a sketch and (cascade) its metrics, and detaches
+// any modeling scenarios from it.
 func (s *Store) DeleteSketch(id int64) error {
-	_, err := s.db.Exec(`DELETE FROM sketches WHERE id = ?`, id)
+	if _, err := s.db.Exec(`DELETE FROM sketches WHERE id = ?`, id); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`UPDATE modeling_scenarios SET sketch_id = NULL WHERE sketch_id = ?`, id)
 	return err
 }
 
@@ -1018,15 +956,16 @@ func (s *Store) AddSketchMetric(m SketchMetric) (int64, error) {
 		_ = s.db.QueryRow(`SELECT COUNT(*) FROM sketch_metrics WHERE sketch_id = ?`, m.SketchID).Scan(&n)
 		m.Position = n
 	}
-	res, err := s.db.Exec(
-		`INSERT INTO sketch_metrics (sketch_id, kind, identifier, label, color, position) VALUES (?, ?, ?, ?, ?, ?)`,
+	var id int64
+	err := s.db.QueryRow(
+		`INSERT INTO sketch_metrics (sketch_id, kind, identifier, label, color, position) VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
 		m.SketchID, m.Kind, m.Identifier, m.Label, m.Color, m.Position,
-	)
+	).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
 	_, _ = s.db.Exec(`UPDATE sketches SET updated_at = CURRENT_TIMESTAMP WHERE id = ?`, m.SketchID)
-	return res.LastInsertId()
+	return id, nil
 }
 
 // RemoveSketchMetric drops a metric row by id.
@@ -1096,7 +1035,7 @@ func (s *Store) GetEconomicSeries(code string) (*EconomicSeries, error) {
 	if err := json.Unmarshal([]byte(obsJSON), &es.Observations); err != nil {
 		return nil, err
 	}
-	es.FetchedAt, _ = time.Parse("2006-01-02 15:04:05Z", fetchedAt+"Z")
+	es.FetchedAt = parseSQLiteTime(fetchedAt)
 	return &es, nil
 }
 
@@ -1107,8 +1046,8 @@ func (s *Store) IsEconomicFresh(code string, ttl time.Duration) bool {
 	if err != nil {
 		return false
 	}
-	t, err := time.Parse("2006-01-02 15:04:05Z", fetchedAt+"Z")
-	if err != nil {
+	t := parseSQLiteTime(fetchedAt)
+	if t.IsZero() {
 		return false
 	}
 	return time.Since(t) < ttl
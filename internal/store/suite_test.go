@@ -0,0 +1,380 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// openStore returns a fresh, migrated store for one test. Cases call it
+// once per store they need.
+type openStore func(t *testing.T) *Store
+
+// storeSuite is the behaviour every backend has to share.
+var storeSuite = []struct {
+	name string
+	run  func(t *testing.T, newStore openStore)
+}{
+	{"FreshDatabase", testMigrateFreshDatabase},
+	{"Intelligence", testIntelligence},
+	{"Watchlists", testWatchlists},
+	{"Sketches", testSketches},
+	{"PaperLifecycle", testPaperLifecycleRoundtrip},
+	{"Econ", testEcon},
+	{"SECAndPeople", testSECAndPeople},
+	{"Alerts", testAlerts},
+	{"CorporateActions", testCorporateActionsRoundtrip},
+	{"UniverseSnapshot", testUniverseSnapshot},
+	{"SavedScreens", testSavedScreens},
+	{"Scenarios", testScenarios},
+	{"BackupExcludesMarketData", testBackupExcludesMarketData},
+	{"RestoreReplace", testRestoreReplaceIntoFreshStore},
+	{"RestoreMergeIdempotent", testRestoreMergeIsIdempotentAndRemapsIDs},
+	{"RestoreMergeNewerIntelligence", testRestoreMergeKeepsNewerIntelligence},
+	{"RestoreRejectsIncompatible", testRestoreRejectsIncompatibleBundles},
+}
+
+func runSuite(t *testing.T, newStore openStore) {
+	for _, c := range storeSuite {
+		t.Run(c.name, func(t *testing.T) { c.run(t, newStore) })
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	runSuite(t, newTestStore)
+}
+
+// TestPostgres runs the suite against STOCKTOPUS_TEST_POSTGRES when it's
+// set, else against an embedded server (its binaries are downloaded and
+// cached on first use). Each store gets its own schema.
+func TestPostgres(t *testing.T) {
+	if testing.Short() {
+		t.Skip("postgres suite skipped in -short mode")
+	}
+	dsn := os.Getenv("STOCKTOPUS_TEST_POSTGRES")
+	if dsn == "" {
+		dsn = startEmbeddedPostgres(t)
+	}
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	if err := admin.Ping(); err != nil {
+		t.Fatalf("postgres at STOCKTOPUS_TEST_POSTGRES: %v", err)
+	}
+
+	var n atomic.Int64
+	runSuite(t, func(t *testing.T) *Store {
+		t.Helper()
+		schema := fmt.Sprintf("stocktopus_test_%d_%d", os.Getpid(), n.Add(1))
+		if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+		s, err := Open(DriverPostgres, withSearchPath(dsn, schema))
+		if err != nil {
+			t.Fatalf("open postgres store: %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
+
+func startEmbeddedPostgres(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no free port for embedded postgres: %v", err)
+	}
+	port := uint32(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	dir := t.TempDir()
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(port).
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		StartTimeout(time.Minute).
+		Logger(nil))
+	if err := pg.Start(); err != nil {
+		t.Skipf("embedded postgres unavailable (set STOCKTOPUS_TEST_POSTGRES to use a server): %v", err)
+	}
+	t.Cleanup(func() { pg.Stop() })
+	return fmt.Sprintf("host=127.0.0.1 port=%d user=postgres password=postgres dbname=postgres sslmode=disable", port)
+}
+
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
+
+func testIntelligence(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	gen := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	if err := s.Put(&CompanyIntelligence{
+		Symbol: "AAPL", CompanyName: "Apple", Sector: "Technology", Analysis: []byte(`{"moat":"ecosystem"}`),
+		Sentiment: 0.4, KeyRisks: []string{"China"}, GeneratedAt: gen, Confidence: 0.8,
+	}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	ci, err := s.Get("AAPL")
+	if err != nil || ci == nil {
+		t.Fatalf("get = %+v, %v", ci, err)
+	}
+	if !ci.GeneratedAt.Equal(gen) || ci.KeyRisks[0] != "China" || string(ci.Analysis) != `{"moat":"ecosystem"}` {
+		t.Errorf("round trip = %+v", ci)
+	}
+	if !s.IsFresh("AAPL", 2*time.Hour) || s.IsFresh("AAPL", time.Minute) {
+		t.Error("IsFresh should compare against generated_at")
+	}
+	if err := s.Rename("AAPL", "APPL"); err != nil {
+		t.Fatal(err)
+	}
+	if ci, _ := s.Get("APPL"); ci == nil || ci.CompanyName != "Apple" {
+		t.Errorf("renamed = %+v", ci)
+	}
+	if ci, err := s.Get("MSFT"); ci != nil || err != nil {
+		t.Errorf("missing symbol = %+v, %v", ci, err)
+	}
+
+	if err := s.PutSector(&SectorIntelligence{Sector: "Energy", Peers: []byte(`[]`), News: []byte(`[]`), Performance: []byte(`{}`), GeneratedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsSectorFresh("Energy", time.Minute) || s.IsSectorFresh("Utilities", time.Hour) {
+		t.Error("IsSectorFresh")
+	}
+
+	s.AddTrainingPair("AAPL", "p1", "c1", "test", 0.9)
+	s.AddTrainingPair("AAPL", "p2", "c2", "test", 0.2)
+	pairs, err := s.ExportTrainingData(0.5)
+	if err != nil || len(pairs) != 1 || pairs[0].CreatedAt.IsZero() {
+		t.Errorf("export = %+v, %v", pairs, err)
+	}
+	if n, _ := s.TrainingDataCount(); n != 2 {
+		t.Errorf("count = %d", n)
+	}
+}
+
+func testWatchlists(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	wl, err := s.CreateWatchlist("Banks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateWatchlist("Banks"); err == nil {
+		t.Error("duplicate watchlist name should fail")
+	}
+	s.AddToWatchlist(wl.ID, "JPM")
+	s.AddToWatchlist(wl.ID, "JPM")
+	s.AddToWatchlist(0, "AAPL") // default list
+	s.AddToWatchlist(wl.ID, "AAPL")
+
+	lists, _ := s.GetWatchlists()
+	if len(lists) != 2 || lists[0].Name != "Default" || len(lists[1].Symbols) != 2 {
+		t.Fatalf("lists = %+v", lists)
+	}
+	if in, _ := s.GetSymbolWatchlists("AAPL"); len(in) != 2 {
+		t.Errorf("AAPL in %+v", in)
+	}
+	s.RemoveFromWatchlist(wl.ID, "AAPL")
+	if syms, _ := s.GetAllWatchedSymbols(); strings.Join(syms, ",") != "AAPL,JPM" {
+		t.Errorf("watched = %v", syms)
+	}
+
+	dyn, err := s.CreateDynamicWatchlist("Gappers", "changeFromOpenMin=3")
+	if err != nil || !dyn.Dynamic {
+		t.Fatalf("dynamic = %+v, %v", dyn, err)
+	}
+	joined, left, err := s.SetWatchlistMembers(dyn.ID, []string{"NVDA", "AMD", "NVDA"})
+	if err != nil || strings.Join(joined, ",") != "NVDA,AMD" || len(left) != 0 {
+		t.Fatalf("first eval = %v / %v, %v", joined, left, err)
+	}
+	joined, left, _ = s.SetWatchlistMembers(dyn.ID, []string{"AMD", "TSLA"})
+	if strings.Join(joined, ",") != "TSLA" || strings.Join(left, ",") != "NVDA" {
+		t.Errorf("second eval = %v / %v", joined, left)
+	}
+	got, err := s.GetWatchlist(dyn.ID)
+	if err != nil || got.Query != "changeFromOpenMin=3" || got.EvaluatedAt == nil || len(got.Symbols) != 2 {
+		t.Fatalf("GetWatchlist = %+v, %v", got, err)
+	}
+	if list, _ := s.GetDynamicWatchlists(); len(list) != 1 || list[0].ID != dyn.ID {
+		t.Errorf("dynamic lists = %+v", list)
+	}
+	s.SetWatchlistQuery(dyn.ID, "")
+	if got, _ := s.GetWatchlist(dyn.ID); got.Dynamic || len(got.Symbols) != 2 {
+		t.Errorf("made static = %+v", got)
+	}
+	if err := s.SetWatchlistQuery(999, "x=1"); err == nil {
+		t.Error("query on a missing watchlist should fail")
+	}
+
+	if err := s.SetWatchlistColumns(wl.ID, []string{"rsi(14)", "sma(200)"}); err != nil {
+		t.Fatal(err)
+	}
+	if in, _ := s.GetSymbolWatchlists("JPM"); len(in) != 1 || strings.Join(in[0].Columns, ",") != "rsi(14),sma(200)" {
+		t.Errorf("columns = %+v", in)
+	}
+	s.SetWatchlistColumns(wl.ID, nil)
+	if got, _ := s.GetWatchlist(wl.ID); len(got.Columns) != 0 {
+		t.Errorf("cleared columns = %v", got.Columns)
+	}
+}
+
+func testSketches(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	id, err := s.CreateSketch(0, "Oil vs airlines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddSketchMetric(SketchMetric{SketchID: id, Kind: "commodity", Identifier: "CLUSD"}); err != nil {
+		t.Fatal(err)
+	}
+	m2, _ := s.AddSketchMetric(SketchMetric{SketchID: id, Kind: "price", Identifier: "DAL"})
+	s.RenameSketch(id, "Crude vs airlines")
+	s.UpdateSketchNotes(id, "fuel hedges")
+
+	sk, err := s.GetSketch(id)
+	if err != nil || sk.Name != "Crude vs airlines" || sk.Notes != "fuel hedges" || sk.OwnerID != 1 {
+		t.Fatalf("sketch = %+v, %v", sk, err)
+	}
+	if len(sk.Metrics) != 2 || sk.Metrics[1].Position != 1 || sk.CreatedAt.IsZero() {
+		t.Errorf("metrics = %+v", sk.Metrics)
+	}
+	s.RemoveSketchMetric(m2)
+	if list, _ := s.ListSketches(1); len(list) != 1 {
+		t.Errorf("list = %+v", list)
+	}
+	if err := s.DeleteSketch(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetSketch(id); err == nil {
+		t.Error("deleted sketch still readable")
+	}
+}
+
+func testEcon(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	if es, err := s.GetEconomicSeries("UNRATE"); es != nil || err != nil {
+		t.Fatalf("uncached = %+v, %v", es, err)
+	}
+	err := s.PutEconomicSeries(&EconomicSeries{
+		Code: "UNRATE", Title: "Unemployment Rate", Frequency: "M",
+		Observations: []EconomicObservation{{Date: "2026-01-01", Value: 4.1}, {Date: "2026-02-01", Value: 4.2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := s.GetEconomicSeries("UNRATE")
+	if err != nil || len(es.Observations) != 2 || es.Observations[1].Value != 4.2 {
+		t.Fatalf("series = %+v, %v", es, err)
+	}
+	if time.Since(es.FetchedAt) > time.Minute {
+		t.Errorf("fetchedAt = %v", es.FetchedAt)
+	}
+	if !s.IsEconomicFresh("UNRATE", time.Hour) || s.IsEconomicFresh("GDP", time.Hour) {
+		t.Error("IsEconomicFresh")
+	}
+
+	s.PutSecurityType("BTCUSD", "crypto")
+	s.PutSecurityType("BTCUSD", "crypto")
+	if got := s.GetSecurityType("BTCUSD"); got != "crypto" {
+		t.Errorf("security type = %q", got)
+	}
+}
+
+func testSECAndPeople(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	filings := []SECFiling{
+		{Symbol: "JPM", FormType: "8-K", FilingDate: "2026-02-01", Link: "a"},
+		{Symbol: "JPM", FormType: "10-K", FilingDate: "2026-01-15", Link: "b"},
+	}
+	s.PutSECFilings(filings)
+	s.PutSECFilings(filings) // duplicates ignored
+	if got, _ := s.GetSECFilings("JPM", "", 0); len(got) != 2 || got[0].Link != "a" {
+		t.Errorf("filings = %+v", got)
+	}
+	if !s.IsSECFresh("JPM", time.Hour) || s.IsSECFresh("WFC", time.Hour) {
+		t.Error("IsSECFresh")
+	}
+	s.MarkSECFilingProcessedForPeople("JPM", "a")
+	if todo, _ := s.GetUnprocessedSECFilings("JPM", "", 0); len(todo) != 1 || todo[0].Link != "b" {
+		t.Errorf("unprocessed = %+v", todo)
+	}
+	if types, _ := s.GetSECFormTypes(); len(types) == 0 {
+		t.Error("form types not seeded")
+	}
+
+	s.PutKeyPerson(KeyPerson{Symbol: "JPM", Name: "A. Banker", EventType: "appointed", EventDate: "2026-02-01"})
+	s.ReplaceCurrentKeyPeople("JPM", "10-K", []KeyPerson{{Symbol: "JPM", Name: "J. Dimon", Title: "CEO"}})
+	s.ReplaceCurrentKeyPeople("JPM", "10-K", []KeyPerson{{Symbol: "JPM", Name: "J. Dimon", Title: "Chair & CEO"}})
+	people, err := s.GetKeyPeople("JPM")
+	if err != nil || len(people) != 2 {
+		t.Fatalf("people = %+v, %v", people, err)
+	}
+	if !people[0].IsCurrent || people[0].Title != "Chair & CEO" || people[0].FormType != "10-K" || people[1].IsCurrent {
+		t.Errorf("people = %+v", people)
+	}
+}
+
+func testAlerts(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	id, err := s.CreateNewsWatch(NewsWatch{Name: "Banks", Kind: "symbols", Expr: "JPM,WFC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetNewsWatchEnabled(0, id, false); err != nil {
+		t.Fatal(err)
+	}
+	if on, _ := s.GetEnabledNewsWatches(); len(on) != 0 {
+		t.Errorf("paused watch still enabled: %+v", on)
+	}
+	s.SetNewsWatchEnabled(0, id, true)
+	if all, _ := s.ListNewsWatches(0); len(all) != 1 || !all[0].Enabled || all[0].CreatedAt.IsZero() {
+		t.Errorf("watches = %+v", all)
+	}
+
+	item := InboxItem{StoryKey: "example.com/jpm", WatchIDs: []int64{id}, Title: "JPM beats", PublishedAt: time.Now().Add(-time.Minute)}
+	if ok, err := s.PutInboxItem(item); !ok || err != nil {
+		t.Fatalf("first put = %v, %v", ok, err)
+	}
+	if ok, _ := s.PutInboxItem(item); ok {
+		t.Error("same story filed twice")
+	}
+	s.PutInboxItem(InboxItem{StoryKey: "example.com/undated", Title: "No date"})
+	inbox, err := s.GetInbox(0, true, 0)
+	if err != nil || len(inbox) != 2 {
+		t.Fatalf("inbox = %+v, %v", inbox, err)
+	}
+	var dated InboxItem
+	for _, it := range inbox {
+		if it.StoryKey == item.StoryKey {
+			dated = it
+		}
+	}
+	if dated.PublishedAt.IsZero() || len(dated.WatchIDs) != 1 {
+		t.Errorf("inbox item = %+v", dated)
+	}
+	s.MarkInboxRead(0, dated.ID)
+	if unread, _ := s.GetInbox(0, true, 0); len(unread) != 1 {
+		t.Errorf("unread = %+v", unread)
+	}
+	if err := s.DeleteNewsWatch(0, id); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"stocktopus/internal/model"
+)
+
+// UniverseRow is one symbol in the local screener's universe snapshot.
+// Fields holds the numeric fundamentals by name (peRatio, roe,
+// revenueGrowth3y, ...); Bars are daily, oldest first.
+type UniverseRow struct {
+	Symbol      string             `json:"symbol"`
+	Name        string             `json:"name"`
+	Sector      string             `json:"sector"`
+	Industry    string             `json:"industry"`
+	Exchange    string             `json:"exchange"`
+	Fields      map[string]float64 `json:"fields"`
+	Bars        []model.OHLCV      `json:"bars"`
+	RefreshedAt time.Time          `json:"refreshedAt"`
+}
+
+// PutUniverseRow inserts or replaces a symbol's snapshot row and stamps
+// its refresh time.
+func (s *Store) PutUniverseRow(r UniverseRow) error {
+	fields, err := json.Marshal(r.Fields)
+	if err != nil {
+		return fmt.Errorf("encode fields: %w", err)
+	}
+	bars, err := json.Marshal(r.Bars)
+	if err != nil {
+		return fmt.Errorf("encode bars: %w", err)
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO universe_snapshot (symbol, name, sector, industry, exchange, fields, bars, refreshed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(symbol) DO UPDATE SET
+			name = excluded.name, sector = excluded.sector, industry = excluded.industry,
+			exchange = excluded.exchange, fields = excluded.fields, bars = excluded.bars,
+			refreshed_at = CURRENT_TIMESTAMP`,
+		strings.ToUpper(r.Symbol), r.Name, r.Sector, r.Industry, r.Exchange, string(fields), string(bars))
+	return err
+}
+
+// GetUniverse returns the whole snapshot, ordered by symbol.
+func (s *Store) GetUniverse() ([]UniverseRow, error) {
+	rows, err := s.db.Query(`
+		SELECT symbol, name, sector, industry, exchange, fields, bars, refreshed_at
+		FROM universe_snapshot ORDER BY symbol`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []UniverseRow
+	for rows.Next() {
+		var r UniverseRow
+		var fields, bars, refreshed string
+		if err := rows.Scan(&r.Symbol, &r.Name, &r.Sector, &r.Industry, &r.Exchange, &fields, &bars, &refreshed); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(fields), &r.Fields); err != nil {
+			return nil, fmt.Errorf("%s fields: %w", r.Symbol, err)
+		}
+		if err := json.Unmarshal([]byte(bars), &r.Bars); err != nil {
+			return nil, fmt.Errorf("%s bars: %w", r.Symbol, err)
+		}
+		r.RefreshedAt = parseSQLiteTime(refreshed)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// PruneUniverse drops snapshot rows for symbols not in keep and returns
+// how many went.
+func (s *Store) PruneUniverse(keep []string) (int, error) {
+	want := make(map[string]bool, len(keep))
+	for _, sym := range keep {
+		want[strings.ToUpper(sym)] = true
+	}
+	rows, err := s.db.Query(`SELECT symbol FROM universe_snapshot`)
+	if err != nil {
+		return 0, err
+	}
+	var drop []string
+	for rows.Next() {
+		var sym string
+		if err := rows.Scan(&sym); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !want[sym] {
+			drop = append(drop, sym)
+		}
+	}
+	rows.Close()
+	for _, sym := range drop {
+		if _, err := s.db.Exec(`DELETE FROM universe_snapshot WHERE symbol = ?`, sym); err != nil {
+			return 0, err
+		}
+	}
+	return len(drop), nil
+}
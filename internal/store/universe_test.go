@@ -0,0 +1,38 @@
+package store
+
+import (
+	"testing"
+
+	"stocktopus/internal/model"
+)
+
+func testUniverseSnapshot(t *testing.T, newStore openStore) {
+	s := newStore(t)
+	for _, r := range []UniverseRow{
+		{Symbol: "msft", Name: "Microsoft", Sector: "Technology", Fields: map[string]float64{"peRatio": 35}},
+		{Symbol: "XOM", Sector: "Energy", Fields: map[string]float64{"peRatio": 12}, Bars: []model.OHLCV{{Date: "2026-10-16", Close: 110, Volume: 5}}},
+	} {
+		if err := s.PutUniverseRow(r); err != nil {
+			t.Fatalf("put %s: %v", r.Symbol, err)
+		}
+	}
+	s.PutUniverseRow(UniverseRow{Symbol: "MSFT", Name: "Microsoft", Fields: map[string]float64{"peRatio": 30}})
+
+	rows, err := s.GetUniverse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Symbol != "MSFT" || rows[0].Fields["peRatio"] != 30 || rows[0].RefreshedAt.IsZero() {
+		t.Fatalf("universe = %+v", rows)
+	}
+	if len(rows[1].Bars) != 1 || rows[1].Bars[0].Close != 110 {
+		t.Errorf("bars = %+v", rows[1].Bars)
+	}
+
+	if n, err := s.PruneUniverse([]string{"xom"}); err != nil || n != 1 {
+		t.Errorf("prune = %d, %v", n, err)
+	}
+	if rows, _ := s.GetUniverse(); len(rows) != 1 || rows[0].Symbol != "XOM" {
+		t.Errorf("after prune = %+v", rows)
+	}
+}
@@ -0,0 +1,384 @@
+// Package ticks records every quote the poller fetches so stocktopus
+// builds its own intraday history from data it has already paid for.
+//
+// Storage is one directory per symbol with one file per UTC day. The
+// current day is an append-only row log (SYMBOL/2026-10-18.log); once the
+// day is over it is sealed into a compact columnar segment
+// (SYMBOL/2026-10-18.seg). Days older than the retention window are
+// deleted.
+package ticks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"stocktopus/internal/model"
+)
+
+const dayLayout = "2006-01-02"
+
+// DefaultRetention keeps a month of ticks.
+const DefaultRetention = 30 * 24 * time.Hour
+
+// Recorder persists quotes to per-symbol, per-day files under dir.
+type Recorder struct {
+	dir       string
+	retention time.Duration
+	logger    *slog.Logger
+
+	mu     sync.Mutex
+	logs   map[string]*os.File  // "SYMBOL/day" → open row log
+	last   map[string]Tick      // symbol → last recorded tick, for dedupe
+	sealed map[string]time.Time // "SYMBOL/day" → last sealed tick's time
+}
+
+// New opens (creating if needed) a tick store rooted at dir. retention <= 0
+// uses DefaultRetention.
+func New(dir string, retention time.Duration, logger *slog.Logger) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create tick dir: %w", err)
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Recorder{
+		dir:       dir,
+		retention: retention,
+		logger:    logger.With("component", "ticks"),
+		logs:      make(map[string]*os.File),
+		last:      make(map[string]Tick),
+		sealed:    make(map[string]time.Time),
+	}, nil
+}
+
+// Record appends quotes to their symbol's log for the quote's UTC day.
+// Repeats of the last tick (same timestamp, price and volume — a closed
+// market re-polled) are dropped, as are ticks at or before the end of an
+// already sealed day: after a restart the in-memory last tick is gone,
+// and a weekend re-poll still carries Friday's timestamp. Quotes without
+// a timestamp are stamped with the receive time.
+func (r *Recorder) Record(quotes []*model.Quote) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UTC()
+	for _, q := range quotes {
+		if q == nil || q.Symbol == "" || q.Price <= 0 {
+			continue
+		}
+		ts := q.Timestamp
+		if ts.IsZero() {
+			ts = now
+		}
+		t := Tick{Time: ts.UTC().Truncate(time.Millisecond), Price: q.Price, Bid: q.Bid, Ask: q.Ask, Volume: q.Volume}
+		if prev, ok := r.last[q.Symbol]; ok && prev.Time.Equal(t.Time) && prev.Price == t.Price && prev.Volume == t.Volume {
+			continue
+		}
+		if !t.Time.After(r.sealedThrough(q.Symbol, t.Time.Format(dayLayout))) {
+			continue
+		}
+		if err := r.append(q.Symbol, t); err != nil {
+			r.logger.Warn("tick write failed", "symbol", q.Symbol, "error", err)
+			continue
+		}
+		r.last[q.Symbol] = t
+	}
+}
+
+func (r *Recorder) append(symbol string, t Tick) error {
+	day := t.Time.Format(dayLayout)
+	key := symbol + "/" + day
+	f := r.logs[key]
+	if f == nil {
+		dir := filepath.Join(r.dir, symbolDir(symbol))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		var err error
+		f, err = os.OpenFile(filepath.Join(dir, day+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		r.logs[key] = f
+	}
+	_, err := f.Write(encodeRow(t))
+	return err
+}
+
+// sealedThrough returns the time of the last tick in a symbol's sealed
+// segment for day, or the zero time when the day isn't sealed. Cached
+// per day; Seal and Prune keep the cache current. Caller holds r.mu.
+func (r *Recorder) sealedThrough(symbol, day string) time.Time {
+	key := symbol + "/" + day
+	if t, ok := r.sealed[key]; ok {
+		return t
+	}
+	var last time.Time
+	if data, err := os.ReadFile(filepath.Join(r.dir, symbolDir(symbol), day+".seg")); err == nil {
+		if ticks, err := decodeSegment(data); err == nil && len(ticks) > 0 {
+			last = ticks[len(ticks)-1].Time
+		}
+	}
+	r.sealed[key] = last
+	return last
+}
+
+// Ticks returns a symbol's ticks in [from, to), oldest first.
+func (r *Recorder) Ticks(symbol string, from, to time.Time) ([]Tick, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []Tick
+	for day := from.UTC().Truncate(24 * time.Hour); day.Before(to); day = day.Add(24 * time.Hour) {
+		ticks, err := r.readDay(symbol, day.Format(dayLayout))
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range ticks {
+			if !t.Time.Before(from) && t.Time.Before(to) {
+				out = append(out, t)
+			}
+		}
+	}
+	return out, nil
+}
+
+// readDay merges a day's sealed segment and row log. Caller holds r.mu.
+func (r *Recorder) readDay(symbol, day string) ([]Tick, error) {
+	base := filepath.Join(r.dir, symbolDir(symbol), day)
+	var ticks []Tick
+	if data, err := os.ReadFile(base + ".seg"); err == nil {
+		seg, err := decodeSegment(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", symbol, day, err)
+		}
+		ticks = seg
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if data, err := os.ReadFile(base + ".log"); err == nil {
+		ticks = append(ticks, decodeRows(data)...)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	sort.SliceStable(ticks, func(i, j int) bool { return ticks[i].Time.Before(ticks[j].Time) })
+	return ticks, nil
+}
+
+// Bars resamples ticks in [from, to) into OHLCV bars of the given width,
+// aligned to UTC interval boundaries. Bar volume is the increase in the
+// provider's cumulative session volume across the bar; a reset (new
+// session) starts counting from the reset value.
+func (r *Recorder) Bars(symbol string, from, to time.Time, interval time.Duration) ([]model.OHLCV, error) {
+	ticks, err := r.Ticks(symbol, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return Resample(ticks, interval), nil
+}
+
+// Resample buckets ticks (oldest first) into bars. Dates are RFC 3339 UTC
+// bar-open times.
+func Resample(ticks []Tick, interval time.Duration) []model.OHLCV {
+	var bars []model.OHLCV
+	var cur *model.OHLCV
+	var curStart time.Time
+	var prevVol int64 = -1
+	for _, t := range ticks {
+		start := t.Time.Truncate(interval)
+		if cur == nil || !start.Equal(curStart) {
+			bars = append(bars, model.OHLCV{
+				Date: start.Format(time.RFC3339),
+				Open: t.Price, High: t.Price, Low: t.Price, Close: t.Price,
+			})
+			cur = &bars[len(bars)-1]
+			curStart = start
+		}
+		cur.High = max(cur.High, t.Price)
+		cur.Low = min(cur.Low, t.Price)
+		cur.Close = t.Price
+		switch {
+		case prevVol < 0:
+		case t.Volume >= prevVol:
+			cur.Volume += t.Volume - prevVol
+		default:
+			cur.Volume += t.Volume
+		}
+		prevVol = t.Volume
+	}
+	return bars
+}
+
+// Symbols lists every symbol with recorded ticks.
+func (r *Recorder) Symbols() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if sym, err := url.PathUnescape(e.Name()); err == nil {
+			out = append(out, sym)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Run seals finished days and applies retention at startup and hourly
+// until ctx is cancelled, then closes open logs.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	r.maintain(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			r.Close()
+			return
+		case <-ticker.C:
+			r.maintain(time.Now())
+		}
+	}
+}
+
+func (r *Recorder) maintain(now time.Time) {
+	if err := r.Seal(now); err != nil {
+		r.logger.Warn("tick seal failed", "error", err)
+	}
+	if err := r.Prune(now); err != nil {
+		r.logger.Warn("tick prune failed", "error", err)
+	}
+}
+
+// Seal rewrites every row log from before now's UTC day into a columnar
+// segment, merging with any segment already sealed for that day.
+func (r *Recorder) Seal(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	today := now.UTC().Format(dayLayout)
+
+	return r.walkDays(func(symbol, day, path string) error {
+		if !strings.HasSuffix(path, ".log") || day >= today {
+			return nil
+		}
+		if f := r.logs[symbol+"/"+day]; f != nil {
+			f.Close()
+			delete(r.logs, symbol+"/"+day)
+		}
+		ticks, err := r.readDay(symbol, day)
+		if err != nil {
+			return err
+		}
+		ticks = dedupeTicks(ticks)
+		seg := strings.TrimSuffix(path, ".log") + ".seg"
+		tmp := seg + ".tmp"
+		if err := os.WriteFile(tmp, encodeSegment(ticks), 0o644); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, seg); err != nil {
+			return err
+		}
+		if len(ticks) > 0 {
+			r.sealed[symbol+"/"+day] = ticks[len(ticks)-1].Time
+		}
+		return os.Remove(path)
+	})
+}
+
+// dedupeTicks drops ticks (oldest first) that repeat the previous one's
+// timestamp, price and volume, so a day re-sealed after a restart doesn't
+// keep a re-polled duplicate.
+func dedupeTicks(ticks []Tick) []Tick {
+	out := ticks[:0]
+	for i, t := range ticks {
+		if i > 0 {
+			prev := out[len(out)-1]
+			if prev.Time.Equal(t.Time) && prev.Price == t.Price && prev.Volume == t.Volume {
+				continue
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// Prune deletes day files that have aged out of the retention window.
+func (r *Recorder) Prune(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cutoff := now.UTC().Add(-r.retention).Format(dayLayout)
+
+	return r.walkDays(func(symbol, day, path string) error {
+		if day >= cutoff {
+			return nil
+		}
+		if f := r.logs[symbol+"/"+day]; f != nil {
+			f.Close()
+			delete(r.logs, symbol+"/"+day)
+		}
+		delete(r.sealed, symbol+"/"+day)
+		return os.Remove(path)
+	})
+}
+
+// walkDays calls fn for every .log/.seg file. Caller holds r.mu.
+func (r *Recorder) walkDays(fn func(symbol, day, path string) error) error {
+	dirs, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		symbol, err := url.PathUnescape(d.Name())
+		if err != nil {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(r.dir, d.Name()))
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			ext := filepath.Ext(f.Name())
+			if ext != ".log" && ext != ".seg" {
+				continue
+			}
+			day := strings.TrimSuffix(f.Name(), ext)
+			if err := fn(symbol, day, filepath.Join(r.dir, d.Name(), f.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close flushes and closes every open row log.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var firstErr error
+	for key, f := range r.logs {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(r.logs, key)
+	}
+	return firstErr
+}
+
+// symbolDir escapes a symbol for use as a directory name ("BRK/B", "^GSPC").
+func symbolDir(symbol string) string {
+	return url.PathEscape(strings.ToUpper(symbol))
+}
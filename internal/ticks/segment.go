@@ -0,0 +1,154 @@
+package ticks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Tick is one recorded quote observation.
+type Tick struct {
+	Time   time.Time `json:"t"`
+	Price  float64   `json:"price"`
+	Bid    float64   `json:"bid,omitempty"`
+	Ask    float64   `json:"ask,omitempty"`
+	Volume int64     `json:"volume"` // cumulative session volume as reported by the provider
+}
+
+// ── Active log ──
+//
+// Today's ticks are appended to a row log: fixed 40-byte little-endian
+// records (unix ms, price, bid, ask, volume). A torn final row from a
+// crash is simply ignored on read.
+
+const rowSize = 40
+
+func encodeRow(t Tick) []byte {
+	var b [rowSize]byte
+	binary.LittleEndian.PutUint64(b[0:], uint64(t.Time.UnixMilli()))
+	binary.LittleEndian.PutUint64(b[8:], math.Float64bits(t.Price))
+	binary.LittleEndian.PutUint64(b[16:], math.Float64bits(t.Bid))
+	binary.LittleEndian.PutUint64(b[24:], math.Float64bits(t.Ask))
+	binary.LittleEndian.PutUint64(b[32:], uint64(t.Volume))
+	return b[:]
+}
+
+func decodeRows(data []byte) []Tick {
+	n := len(data) / rowSize
+	out := make([]Tick, 0, n)
+	for i := 0; i < n; i++ {
+		b := data[i*rowSize:]
+		out = append(out, Tick{
+			Time:   time.UnixMilli(int64(binary.LittleEndian.Uint64(b[0:]))).UTC(),
+			Price:  math.Float64frombits(binary.LittleEndian.Uint64(b[8:])),
+			Bid:    math.Float64frombits(binary.LittleEndian.Uint64(b[16:])),
+			Ask:    math.Float64frombits(binary.LittleEndian.Uint64(b[24:])),
+			Volume: int64(binary.LittleEndian.Uint64(b[32:])),
+		})
+	}
+	return out
+}
+
+// ── Sealed segment ──
+//
+// A finished day is rewritten column by column:
+//
+//	"STKS" | version | uvarint count | 5 × (uvarint length | column bytes)
+//
+// Timestamps and volumes are zigzag varint deltas; price, bid and ask are
+// varints of each value's bits XORed with the previous value's, so an
+// unchanged price costs one byte. A day of 15-second polls for one symbol
+// typically seals to a few KB.
+
+var segMagic = []byte("STKS")
+
+const segVersion = 1
+
+func encodeSegment(ticks []Tick) []byte {
+	var ts, price, bid, ask, vol []byte
+	var prevT, prevV int64
+	var prevP, prevB, prevA uint64
+	for _, t := range ticks {
+		ms := t.Time.UnixMilli()
+		ts = binary.AppendVarint(ts, ms-prevT)
+		prevT = ms
+
+		p, b, a := math.Float64bits(t.Price), math.Float64bits(t.Bid), math.Float64bits(t.Ask)
+		price = binary.AppendUvarint(price, p^prevP)
+		bid = binary.AppendUvarint(bid, b^prevB)
+		ask = binary.AppendUvarint(ask, a^prevA)
+		prevP, prevB, prevA = p, b, a
+
+		vol = binary.AppendVarint(vol, t.Volume-prevV)
+		prevV = t.Volume
+	}
+
+	out := append([]byte{}, segMagic...)
+	out = append(out, segVersion)
+	out = binary.AppendUvarint(out, uint64(len(ticks)))
+	for _, col := range [][]byte{ts, price, bid, ask, vol} {
+		out = binary.AppendUvarint(out, uint64(len(col)))
+		out = append(out, col...)
+	}
+	return out
+}
+
+var errCorrupt = errors.New("ticks: corrupt segment")
+
+func decodeSegment(data []byte) ([]Tick, error) {
+	if len(data) < len(segMagic)+1 || !bytes.Equal(data[:len(segMagic)], segMagic) {
+		return nil, errCorrupt
+	}
+	if v := data[len(segMagic)]; v != segVersion {
+		return nil, fmt.Errorf("ticks: unsupported segment version %d", v)
+	}
+	r := bytes.NewReader(data[len(segMagic)+1:])
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errCorrupt
+	}
+
+	cols := make([]*bytes.Reader, 5)
+	for i := range cols {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return nil, errCorrupt
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, errCorrupt
+		}
+		cols[i] = bytes.NewReader(buf)
+	}
+
+	out := make([]Tick, 0, count)
+	var prevT, prevV int64
+	var prevP, prevB, prevA uint64
+	for i := uint64(0); i < count; i++ {
+		dt, err1 := binary.ReadVarint(cols[0])
+		xp, err2 := binary.ReadUvarint(cols[1])
+		xb, err3 := binary.ReadUvarint(cols[2])
+		xa, err4 := binary.ReadUvarint(cols[3])
+		dv, err5 := binary.ReadVarint(cols[4])
+		if err := errors.Join(err1, err2, err3, err4, err5); err != nil {
+			return nil, errCorrupt
+		}
+		prevT += dt
+		prevP ^= xp
+		prevB ^= xb
+		prevA ^= xa
+		prevV += dv
+		out = append(out, Tick{
+			Time:   time.UnixMilli(prevT).UTC(),
+			Price:  math.Float64frombits(prevP),
+			Bid:    math.Float64frombits(prevB),
+			Ask:    math.Float64frombits(prevA),
+			Volume: prevV,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,156 @@
+package ticks
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stocktopus/internal/model"
+)
+
+func TestSegmentRoundTrip(t *testing.T) {
+	base := time.Date(2026, 10, 16, 14, 30, 0, 0, time.UTC)
+	in := []Tick{
+		{Time: base, Price: 231.12, Bid: 231.1, Ask: 231.14, Volume: 1_000_000},
+		{Time: base.Add(15 * time.Second), Price: 231.12, Bid: 231.1, Ask: 231.14, Volume: 1_004_200},
+		{Time: base.Add(30 * time.Second), Price: 230.98, Bid: 230.97, Ask: 231.0, Volume: 1_010_000},
+		{Time: base.Add(45 * time.Second), Price: 0.00001234, Volume: 0}, // crypto-scale price, volume reset
+	}
+	enc := encodeSegment(in)
+	if len(enc) >= len(in)*rowSize {
+		t.Errorf("segment is %d bytes, want smaller than row log (%d)", len(enc), len(in)*rowSize)
+	}
+	out, err := decodeSegment(enc)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out) != len(in) {
+		t.Fatalf("got %d ticks, want %d", len(out), len(in))
+	}
+	for i := range in {
+		if !out[i].Time.Equal(in[i].Time) || out[i].Price != in[i].Price || out[i].Bid != in[i].Bid ||
+			out[i].Ask != in[i].Ask || out[i].Volume != in[i].Volume {
+			t.Errorf("tick %d: got %+v, want %+v", i, out[i], in[i])
+		}
+	}
+
+	if _, err := decodeSegment(enc[:len(enc)-3]); err == nil {
+		t.Error("truncated segment should fail to decode")
+	}
+}
+
+func TestResample(t *testing.T) {
+	base := time.Date(2026, 10, 16, 14, 30, 0, 0, time.UTC)
+	ticks := []Tick{
+		{Time: base.Add(5 * time.Second), Price: 10, Volume: 100},
+		{Time: base.Add(20 * time.Second), Price: 12, Volume: 150},
+		{Time: base.Add(50 * time.Second), Price: 9, Volume: 180},
+		{Time: base.Add(65 * time.Second), Price: 11, Volume: 200},
+	}
+	bars := Resample(ticks, time.Minute)
+	if len(bars) != 2 {
+		t.Fatalf("got %d bars, want 2: %+v", len(bars), bars)
+	}
+	want := model.OHLCV{Date: "2026-10-16T14:30:00Z", Open: 10, High: 12, Low: 9, Close: 9, Volume: 80}
+	if bars[0] != want {
+		t.Errorf("bar 0 = %+v, want %+v", bars[0], want)
+	}
+	if bars[1].Open != 11 || bars[1].Volume != 20 {
+		t.Errorf("bar 1 = %+v", bars[1])
+	}
+}
+
+// TestRecorderRestartAfterSeal: a restarted recorder has no in-memory
+// last tick, and a closed-market re-poll still carries the sealed day's
+// timestamp. It must not reopen that day's log with a duplicate.
+func TestRecorderRestartAfterSeal(t *testing.T) {
+	dir := t.TempDir()
+	friday := time.Date(2026, 10, 16, 19, 59, 0, 0, time.UTC)
+	sunday := friday.Add(48 * time.Hour)
+	quote := []*model.Quote{{Symbol: "AAPL", Price: 231, Volume: 50, Timestamp: friday}}
+
+	r, err := New(dir, 0, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Record(quote)
+	if err := r.Seal(sunday); err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	r.Close()
+
+	for restart := 0; restart < 2; restart++ {
+		r, err = New(dir, 0, slog.Default())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Record(quote)
+		if _, err := os.Stat(filepath.Join(dir, symbolDir("AAPL"), "2026-10-16.log")); !os.IsNotExist(err) {
+			t.Errorf("restart %d reopened the sealed day's log (stat err %v)", restart, err)
+		}
+		if err := r.Seal(sunday); err != nil {
+			t.Fatalf("seal: %v", err)
+		}
+		r.Close()
+	}
+
+	r, _ = New(dir, 0, slog.Default())
+	defer r.Close()
+	ticks, err := r.Ticks("AAPL", friday.Add(-time.Hour), sunday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ticks) != 1 {
+		t.Errorf("got %d ticks after restarts, want 1: %+v", len(ticks), ticks)
+	}
+}
+
+func TestRecorderSealQueryPrune(t *testing.T) {
+	dir := t.TempDir()
+	r, err := New(dir, 48*time.Hour, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	day1 := time.Date(2026, 10, 15, 15, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	r.Record([]*model.Quote{{Symbol: "BRK.B", Price: 450, Volume: 10, Timestamp: day1}})
+	r.Record([]*model.Quote{{Symbol: "BRK.B", Price: 450, Volume: 10, Timestamp: day1}}) // duplicate poll
+	r.Record([]*model.Quote{{Symbol: "BRK.B", Price: 451, Volume: 20, Timestamp: day1.Add(time.Minute)}})
+	r.Record([]*model.Quote{{Symbol: "BRK.B", Price: 452, Volume: 5, Timestamp: day2}})
+
+	if err := r.Seal(day2); err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	symDir := filepath.Join(dir, symbolDir("BRK.B"))
+	if _, err := os.Stat(filepath.Join(symDir, "2026-10-15.seg")); err != nil {
+		t.Errorf("day 1 should be sealed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(symDir, "2026-10-16.log")); err != nil {
+		t.Errorf("today's log should stay open: %v", err)
+	}
+
+	ticks, err := r.Ticks("BRK.B", day1.Add(-time.Hour), day2.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ticks) != 3 || ticks[0].Price != 450 || ticks[2].Price != 452 {
+		t.Errorf("ticks = %+v, want 3 across sealed + open days", ticks)
+	}
+
+	if err := r.Prune(day2.Add(48 * time.Hour)); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	ticks, _ = r.Ticks("BRK.B", day1.Add(-time.Hour), day2.Add(time.Hour))
+	if len(ticks) != 1 {
+		t.Errorf("after prune got %d ticks, want only day 2", len(ticks))
+	}
+
+	syms, _ := r.Symbols()
+	if len(syms) != 1 || syms[0] != "BRK.B" {
+		t.Errorf("Symbols() = %v", syms)
+	}
+}
@@ -0,0 +1,412 @@
+package watchlists
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"stocktopus/internal/indicators"
+	"stocktopus/internal/model"
+)
+
+// Benchmark is the symbol the vsSPY column measures against.
+const Benchmark = "SPY"
+
+// ColumnKind describes one computed column a watchlist can show.
+type ColumnKind struct {
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	// Period is the default look-back; 0 means the column takes none.
+	Period int `json:"period,omitempty"`
+}
+
+var columnKinds = []ColumnKind{
+	{"high52w", "52W HIGH %", "distance below the 52-week high, percent", 0},
+	{"low52w", "52W LOW %", "distance above the 52-week low, percent", 0},
+	{"rsi", "RSI", "relative strength index", 14},
+	{"atrPct", "ATR %", "average true range as a percent of price", 14},
+	{"sma", "SMA %", "price above (+) or below (-) the moving average, percent", 50},
+	{"relVolume", "REL VOL", "last volume over the average of the prior N days", 20},
+	{"vsSPY", "VS SPY", "N-day performance minus SPY's, percentage points", 21},
+	{"daysToEarnings", "EARN", "calendar days to the next scheduled earnings report", 0},
+}
+
+// ColumnKinds lists the computed columns on offer.
+func ColumnKinds() []ColumnKind { return columnKinds }
+
+// maxColumnPeriod bounds look-backs to the year of bars fetched.
+const maxColumnPeriod = 252
+
+// Column is a parsed column spec such as "rsi(14)" or "high52w".
+type Column struct {
+	Spec   string `json:"spec"`
+	Kind   string `json:"kind"`
+	Label  string `json:"label"`
+	Period int    `json:"period,omitempty"`
+}
+
+// ParseColumn parses a column spec, filling in the kind's default period,
+// and returns it in canonical form so "RSI" and "rsi(14)" are the same
+// column.
+func ParseColumn(spec string) (Column, error) {
+	s := strings.TrimSpace(spec)
+	name, arg := s, ""
+	if i := strings.IndexByte(s, '('); i >= 0 {
+		if !strings.HasSuffix(s, ")") {
+			return Column{}, fmt.Errorf("column %q: missing )", spec)
+		}
+		name, arg = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:len(s)-1])
+	}
+	for _, k := range columnKinds {
+		if !strings.EqualFold(k.Name, name) {
+			continue
+		}
+		col := Column{Kind: k.Name, Label: k.Label, Period: k.Period}
+		if arg != "" {
+			if k.Period == 0 {
+				return Column{}, fmt.Errorf("column %q takes no period", spec)
+			}
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 || n > maxColumnPeriod {
+				return Column{}, fmt.Errorf("column %q: period must be 1-%d", spec, maxColumnPeriod)
+			}
+			col.Period = n
+		}
+		col.Spec = col.Kind
+		if col.Period > 0 {
+			col.Spec = fmt.Sprintf("%s(%d)", col.Kind, col.Period)
+			col.Label = fmt.Sprintf("%s %d", col.Label, col.Period)
+		}
+		return col, nil
+	}
+	return Column{}, fmt.Errorf("unknown column %q", spec)
+}
+
+// ParseColumns parses and de-duplicates a list of column specs.
+func ParseColumns(specs []string) ([]Column, error) {
+	var cols []Column
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		col, err := ParseColumn(spec)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[col.Spec] {
+			seen[col.Spec] = true
+			cols = append(cols, col)
+		}
+	}
+	return cols, nil
+}
+
+// Filter keeps rows whose column value compares true against Value. Rows
+// without a value for the column never match.
+type Filter struct {
+	Column Column  `json:"column"`
+	Op     string  `json:"op"`
+	Value  float64 `json:"value"`
+}
+
+var filterOps = []string{"<=", ">=", "!=", "<", ">", "="}
+
+// ParseFilter parses "column op number", e.g. "rsi(14) < 30".
+func ParseFilter(s string) (Filter, error) {
+	for _, op := range filterOps {
+		i := strings.Index(s, op)
+		if i < 0 {
+			continue
+		}
+		col, err := ParseColumn(s[:i])
+		if err != nil {
+			return Filter{}, err
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(s[i+len(op):]), 64)
+		if err != nil {
+			return Filter{}, fmt.Errorf("filter %q: want a number after %s", s, op)
+		}
+		return Filter{Column: col, Op: op, Value: v}, nil
+	}
+	return Filter{}, fmt.Errorf("filter %q: want column, operator and number", s)
+}
+
+func (f Filter) match(r Row) bool {
+	p := r.Values[f.Column.Spec]
+	if p == nil {
+		return false
+	}
+	v := *p
+	switch f.Op {
+	case "<":
+		return v < f.Value
+	case "<=":
+		return v <= f.Value
+	case ">":
+		return v > f.Value
+	case ">=":
+		return v >= f.Value
+	case "=":
+		return v == f.Value
+	case "!=":
+		return v != f.Value
+	}
+	return false
+}
+
+// Row is one symbol's computed values keyed by column spec. A nil value
+// means there wasn't enough data for it.
+type Row struct {
+	Symbol string              `json:"symbol"`
+	Values map[string]*float64 `json:"values"`
+}
+
+// FilterRows returns the rows matching every filter.
+func FilterRows(rows []Row, filters []Filter) []Row {
+	if len(filters) == 0 {
+		return rows
+	}
+	var out []Row
+next:
+	for _, r := range rows {
+		for _, f := range filters {
+			if !f.match(r) {
+				continue next
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// SortRows orders rows by "symbol" or a column spec, keeping rows without
+// a value last whichever way the sort runs.
+func SortRows(rows []Row, key string, desc bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if key == "symbol" {
+			if desc {
+				return rows[i].Symbol > rows[j].Symbol
+			}
+			return rows[i].Symbol < rows[j].Symbol
+		}
+		a, b := rows[i].Values[key], rows[j].Values[key]
+		switch {
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		case desc:
+			return *a > *b
+		default:
+			return *a < *b
+		}
+	})
+}
+
+// BarSource supplies the history the columns are computed from.
+type BarSource interface {
+	GetHistoricalEOD(ctx context.Context, symbol, from, to string) ([]model.OHLCV, error)
+	GetEarnings(ctx context.Context, symbol string, limit int) (json.RawMessage, error)
+}
+
+const (
+	barsTTL           = time.Hour
+	earningsTTL       = 12 * time.Hour
+	columnConcurrency = 8
+	// barsLookback covers 252 sessions plus slack for holidays.
+	barsLookback = 400 * 24 * time.Hour
+)
+
+type cachedBars struct {
+	bars    []model.OHLCV
+	fetched time.Time
+}
+
+type cachedEarnings struct {
+	next    time.Time // zero when none is scheduled
+	fetched time.Time
+}
+
+// Calculator computes watchlist columns from daily bars, caching each
+// symbol's history for an hour and its earnings date for half a day.
+type Calculator struct {
+	src    BarSource
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	bars     map[string]cachedBars
+	earnings map[string]cachedEarnings
+}
+
+// NewCalculator returns a Calculator reading from src.
+func NewCalculator(src BarSource, logger *slog.Logger) *Calculator {
+	return &Calculator{
+		src:      src,
+		logger:   logger,
+		now:      time.Now,
+		bars:     map[string]cachedBars{},
+		earnings: map[string]cachedEarnings{},
+	}
+}
+
+// Rows computes cols for each symbol, in the order given. A symbol whose
+// history can't be fetched gets a row of nil values.
+func (c *Calculator) Rows(ctx context.Context, symbols []string, cols []Column) []Row {
+	needBars, needSPY, needEarnings := false, false, false
+	for _, col := range cols {
+		switch col.Kind {
+		case "daysToEarnings":
+			needEarnings = true
+		case "vsSPY":
+			needSPY, needBars = true, true
+		default:
+			needBars = true
+		}
+	}
+	var spy []model.OHLCV
+	if needSPY {
+		spy = c.history(ctx, Benchmark)
+	}
+
+	rows := make([]Row, len(symbols))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, columnConcurrency)
+	for i, sym := range symbols {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, sym string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			var bars []model.OHLCV
+			if needBars {
+				bars = c.history(ctx, sym)
+			}
+			var next time.Time
+			if needEarnings {
+				next = c.nextEarnings(ctx, sym)
+			}
+			rows[i] = Row{Symbol: sym, Values: computeRow(cols, bars, spy, next, c.now())}
+		}(i, sym)
+	}
+	wg.Wait()
+	return rows
+}
+
+func (c *Calculator) history(ctx context.Context, sym string) []model.OHLCV {
+	now := c.now()
+	c.mu.Lock()
+	hit, ok := c.bars[sym]
+	c.mu.Unlock()
+	if ok && now.Sub(hit.fetched) < barsTTL {
+		return hit.bars
+	}
+	bars, err := c.src.GetHistoricalEOD(ctx, sym, now.Add(-barsLookback).Format("2006-01-02"), now.Format("2006-01-02"))
+	if err != nil {
+		c.logger.Warn("watchlist columns: bars", "symbol", sym, "error", err)
+		return hit.bars
+	}
+	c.mu.Lock()
+	c.bars[sym] = cachedBars{bars: bars, fetched: now}
+	c.mu.Unlock()
+	return bars
+}
+
+func (c *Calculator) nextEarnings(ctx context.Context, sym string) time.Time {
+	now := c.now()
+	c.mu.Lock()
+	hit, ok := c.earnings[sym]
+	c.mu.Unlock()
+	if ok && now.Sub(hit.fetched) < earningsTTL {
+		return hit.next
+	}
+	raw, err := c.src.GetEarnings(ctx, sym, 8)
+	if err != nil {
+		c.logger.Warn("watchlist columns: earnings", "symbol", sym, "error", err)
+		return hit.next
+	}
+	var reports []struct {
+		Date string `json:"date"`
+	}
+	json.Unmarshal(raw, &reports)
+	today := now.Format("2006-01-02")
+	var next time.Time
+	for _, r := range reports {
+		if r.Date < today {
+			continue
+		}
+		if d, err := time.Parse("2006-01-02", r.Date); err == nil && (next.IsZero() || d.Before(next)) {
+			next = d
+		}
+	}
+	c.mu.Lock()
+	c.earnings[sym] = cachedEarnings{next: next, fetched: now}
+	c.mu.Unlock()
+	return next
+}
+
+// computeRow works out each column from chronological daily bars. Values
+// needing more history than there is are left nil.
+func computeRow(cols []Column, bars, spy []model.OHLCV, nextEarnings, now time.Time) map[string]*float64 {
+	values := make(map[string]*float64, len(cols))
+	closes := indicators.Closes(bars)
+	var last float64
+	if len(bars) > 0 {
+		last = bars[len(bars)-1].Close
+	}
+	set := func(col Column, v float64) {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			v = math.Round(v*100) / 100
+			values[col.Spec] = &v
+		}
+	}
+	for _, col := range cols {
+		values[col.Spec] = nil
+		switch col.Kind {
+		case "high52w", "low52w":
+			if len(bars) == 0 || last == 0 {
+				continue
+			}
+			high, low := indicators.HighLow(bars, maxColumnPeriod)
+			if col.Kind == "high52w" && high > 0 {
+				set(col, (last/high-1)*100)
+			} else if col.Kind == "low52w" && low > 0 {
+				set(col, (last/low-1)*100)
+			}
+		case "rsi":
+			if len(closes) > col.Period {
+				set(col, indicators.RSI(closes, col.Period))
+			}
+		case "atrPct":
+			if len(bars) > col.Period && last > 0 {
+				set(col, indicators.ATR(bars, col.Period)/last*100)
+			}
+		case "sma":
+			if len(closes) >= col.Period {
+				if avg := indicators.SMA(closes, col.Period); avg > 0 {
+					set(col, (last/avg-1)*100)
+				}
+			}
+		case "relVolume":
+			if rv := indicators.RelativeVolume(bars, col.Period); rv > 0 {
+				set(col, rv)
+			}
+		case "vsSPY":
+			if len(closes) > col.Period && len(spy) > col.Period {
+				set(col, indicators.PctChange(closes, col.Period)-indicators.PctChange(indicators.Closes(spy), col.Period))
+			}
+		case "daysToEarnings":
+			if !nextEarnings.IsZero() {
+				today, _ := time.Parse("2006-01-02", now.Format("2006-01-02"))
+				set(col, math.Round(nextEarnings.Sub(today).Hours()/24))
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,162 @@
+package watchlists
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"stocktopus/internal/model"
+)
+
+func TestParseColumn(t *testing.T) {
+	tests := []struct {
+		spec, want string
+		ok         bool
+	}{
+		{"rsi", "rsi(14)", true},
+		{" RSI( 7 ) ", "rsi(7)", true},
+		{"sma(200)", "sma(200)", true},
+		{"high52w", "high52w", true},
+		{"vsspy(63)", "vsSPY(63)", true},
+		{"high52w(3)", "", false},
+		{"sma(0)", "", false},
+		{"sma(500)", "", false},
+		{"sma(50", "", false},
+		{"macd", "", false},
+	}
+	for _, tt := range tests {
+		col, err := ParseColumn(tt.spec)
+		if (err == nil) != tt.ok || col.Spec != tt.want {
+			t.Errorf("ParseColumn(%q) = %q, %v; want %q ok=%v", tt.spec, col.Spec, err, tt.want, tt.ok)
+		}
+	}
+	cols, err := ParseColumns([]string{"rsi", "rsi(14)", "atrPct"})
+	if err != nil || len(cols) != 2 {
+		t.Errorf("ParseColumns = %+v, %v", cols, err)
+	}
+
+	f, err := ParseFilter("rsi(14) <= 30")
+	if err != nil || f.Column.Spec != "rsi(14)" || f.Op != "<=" || f.Value != 30 {
+		t.Errorf("ParseFilter = %+v, %v", f, err)
+	}
+	for _, bad := range []string{"rsi(14)", "rsi < low", "foo > 1"} {
+		if _, err := ParseFilter(bad); err == nil {
+			t.Errorf("ParseFilter(%q) should fail", bad)
+		}
+	}
+}
+
+// ramp makes n daily bars closing at start, start+step, ...
+func ramp(n int, start, step float64, volume int64) []model.OHLCV {
+	bars := make([]model.OHLCV, n)
+	for i := range bars {
+		c := start + step*float64(i)
+		bars[i] = model.OHLCV{Open: c, High: c + 1, Low: c - 1, Close: c, Volume: volume}
+	}
+	return bars
+}
+
+type fakeBars struct {
+	bars     map[string][]model.OHLCV
+	earnings map[string]string
+	calls    int
+}
+
+func (f *fakeBars) GetHistoricalEOD(ctx context.Context, symbol, from, to string) ([]model.OHLCV, error) {
+	f.calls++
+	b, ok := f.bars[symbol]
+	if !ok {
+		return nil, errors.New("no data")
+	}
+	return b, nil
+}
+
+func (f *fakeBars) GetEarnings(ctx context.Context, symbol string, limit int) (json.RawMessage, error) {
+	return json.RawMessage(f.earnings[symbol]), nil
+}
+
+func TestCalculatorRows(t *testing.T) {
+	up := ramp(260, 100, 1, 1000)
+	up[len(up)-1].Volume = 3000
+	src := &fakeBars{
+		bars: map[string][]model.OHLCV{
+			"UP":   up,
+			"FLAT": ramp(260, 50, 0, 1000),
+			"SPY":  ramp(260, 100, 0, 1000),
+		},
+		earnings: map[string]string{
+			"UP": `[{"date":"2026-11-05","epsActual":null},{"date":"2026-07-30","epsActual":1.2}]`,
+		},
+	}
+	c := NewCalculator(src, slog.Default())
+	c.now = func() time.Time { return time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC) }
+
+	cols, _ := ParseColumns([]string{"high52w", "low52w", "rsi", "sma(50)", "relVolume", "vsSPY(10)", "daysToEarnings", "atrPct"})
+	rows := c.Rows(context.Background(), []string{"UP", "FLAT", "GONE"}, cols)
+	if len(rows) != 3 || rows[0].Symbol != "UP" || rows[2].Symbol != "GONE" {
+		t.Fatalf("rows = %+v", rows)
+	}
+	val := func(r Row, spec string) float64 {
+		t.Helper()
+		if r.Values[spec] == nil {
+			t.Fatalf("%s %s is nil", r.Symbol, spec)
+		}
+		return *r.Values[spec]
+	}
+
+	last := 359.0 // 100 + 259
+	up0 := rows[0]
+	if got := val(up0, "high52w"); got != -0.28 { // high is 360
+		t.Errorf("high52w = %v", got)
+	}
+	if got := val(up0, "rsi(14)"); got != 100 {
+		t.Errorf("rsi = %v", got)
+	}
+	if got := val(up0, "sma(50)"); got <= 0 {
+		t.Errorf("uptrend should sit above its SMA: %v", got)
+	}
+	if got := val(up0, "relVolume(20)"); got != 3 {
+		t.Errorf("relVolume = %v", got)
+	}
+	if got, want := val(up0, "vsSPY(10)"), (last/(last-10)-1)*100; got-want > 0.01 || want-got > 0.01 {
+		t.Errorf("vsSPY = %v, want %v", got, want)
+	}
+	if got := val(up0, "daysToEarnings"); got != 18 {
+		t.Errorf("daysToEarnings = %v", got)
+	}
+	if got := val(rows[1], "sma(50)"); got != 0 {
+		t.Errorf("flat sma = %v", got)
+	}
+	if rows[1].Values["daysToEarnings"] != nil || rows[2].Values["rsi(14)"] != nil {
+		t.Errorf("missing data should be nil: %+v %+v", rows[1].Values, rows[2].Values)
+	}
+
+	// Bars are cached: a second pass fetches only what failed before.
+	calls := src.calls
+	c.Rows(context.Background(), []string{"UP", "FLAT"}, cols)
+	if src.calls != calls {
+		t.Errorf("cached rows refetched %d times", src.calls-calls)
+	}
+
+	f, _ := ParseFilter("sma(50)>0")
+	kept := FilterRows(rows, []Filter{f})
+	if len(kept) != 1 || kept[0].Symbol != "UP" {
+		t.Errorf("filtered = %+v", kept)
+	}
+	SortRows(rows, "sma(50)", false)
+	var order []string
+	for _, r := range rows {
+		order = append(order, r.Symbol)
+	}
+	if strings.Join(order, ",") != "FLAT,UP,GONE" {
+		t.Errorf("sorted = %v", order)
+	}
+	SortRows(rows, "sma(50)", true)
+	if rows[0].Symbol != "UP" || rows[2].Symbol != "GONE" {
+		t.Errorf("desc sort should keep nil last: %v", rows)
+	}
+}
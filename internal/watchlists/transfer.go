@@ -0,0 +1,527 @@
+package watchlists
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"stocktopus/internal/news"
+	"stocktopus/internal/store"
+)
+
+// Import and export formats.
+const (
+	FormatCSV         = "csv"         // symbol per cell, or a header with symbol[,watchlist] columns
+	FormatTradingView = "tradingview" // ###Section,EXCHANGE:SYMBOL,... as TradingView exports lists
+	FormatJSON        = "json"        // names, colours and symbols
+)
+
+// Formats lists the import/export formats.
+func Formats() []string { return []string{FormatCSV, FormatTradingView, FormatJSON} }
+
+// jsonFormat tags a JSON export so an import can tell it from other JSON.
+const jsonFormat = "stocktopus-watchlists"
+
+// Entry is one symbol as written in an import file.
+type Entry struct {
+	Input    string `json:"input"`              // as written, e.g. "NASDAQ:AAPL"
+	Symbol   string `json:"symbol"`             // ticker part, upper-cased
+	Exchange string `json:"exchange,omitempty"` // exchange prefix, if any
+}
+
+// List is a named group of entries. An empty Name means the file didn't
+// name one and the import's target list gets them.
+type List struct {
+	Name    string  `json:"name"`
+	Color   string  `json:"color,omitempty"`
+	Entries []Entry `json:"entries"`
+}
+
+type jsonBundle struct {
+	Format     string     `json:"format"`
+	Version    int        `json:"version"`
+	Watchlists []jsonList `json:"watchlists"`
+}
+
+type jsonList struct {
+	Name    string   `json:"name"`
+	Color   string   `json:"color,omitempty"`
+	Symbols []string `json:"symbols"`
+}
+
+// DetectFormat guesses an import's format from its content.
+func DetectFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		return FormatJSON
+	case bytes.HasPrefix(trimmed, []byte("###")) || tvPrefixed.Match(trimmed):
+		return FormatTradingView
+	}
+	return FormatCSV
+}
+
+var tvPrefixed = regexp.MustCompile(`(?m)(^|,)\s*[A-Za-z_]+:[A-Za-z0-9.\-!]+`)
+
+// Parse reads an import file. Blank cells and repeated symbols within a
+// list are dropped.
+func Parse(format string, data []byte) ([]List, error) {
+	if format == "" {
+		format = DetectFormat(data)
+	}
+	var lists []List
+	var err error
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		lists, err = parseCSV(data)
+	case FormatTradingView:
+		lists = parseTradingView(data)
+	case FormatJSON:
+		lists, err = parseJSON(data)
+	default:
+		return nil, fmt.Errorf("unknown format %q (want %s)", format, strings.Join(Formats(), ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := lists[:0]
+	for _, l := range lists {
+		l.Entries = dedupeEntries(l.Entries)
+		if len(l.Entries) > 0 {
+			out = append(out, l)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no symbols found")
+	}
+	return out, nil
+}
+
+func parseCSV(data []byte) ([]List, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	symCol, listCol := -1, -1
+	for i, cell := range rows[0] {
+		switch strings.ToLower(strings.TrimSpace(cell)) {
+		case "symbol", "ticker":
+			symCol = i
+		case "watchlist", "list":
+			listCol = i
+		}
+	}
+	if symCol < 0 {
+		// No header: every cell is a symbol.
+		var l List
+		for _, row := range rows {
+			for _, cell := range row {
+				l.Entries = append(l.Entries, newEntry(cell))
+			}
+		}
+		return []List{l}, nil
+	}
+
+	var lists []List
+	index := map[string]int{}
+	for _, row := range rows[1:] {
+		if symCol >= len(row) {
+			continue
+		}
+		name := ""
+		if listCol >= 0 && listCol < len(row) {
+			name = strings.TrimSpace(row[listCol])
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(lists)
+			index[name] = i
+			lists = append(lists, List{Name: name})
+		}
+		lists[i].Entries = append(lists[i].Entries, newEntry(row[symCol]))
+	}
+	return lists, nil
+}
+
+func parseTradingView(data []byte) []List {
+	lists := []List{{}}
+	for _, line := range strings.Split(string(data), "\n") {
+		for _, cell := range strings.Split(line, ",") {
+			cell = strings.TrimSpace(cell)
+			if name, ok := strings.CutPrefix(cell, "###"); ok {
+				lists = append(lists, List{Name: strings.TrimSpace(name)})
+				continue
+			}
+			lists[len(lists)-1].Entries = append(lists[len(lists)-1].Entries, newEntry(cell))
+		}
+	}
+	return lists
+}
+
+func parseJSON(data []byte) ([]List, error) {
+	var b jsonBundle
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &b.Watchlists); err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+	} else {
+		if err := json.Unmarshal(trimmed, &b); err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+		if b.Format != "" && b.Format != jsonFormat {
+			return nil, fmt.Errorf("json: format %q is not %s", b.Format, jsonFormat)
+		}
+	}
+	lists := make([]List, 0, len(b.Watchlists))
+	for _, jl := range b.Watchlists {
+		l := List{Name: strings.TrimSpace(jl.Name), Color: jl.Color}
+		for _, s := range jl.Symbols {
+			l.Entries = append(l.Entries, newEntry(s))
+		}
+		lists = append(lists, l)
+	}
+	return lists, nil
+}
+
+func newEntry(raw string) Entry {
+	in := strings.TrimSpace(raw)
+	sym := strings.TrimPrefix(in, "$")
+	exch := ""
+	if i := strings.IndexByte(sym, ':'); i > 0 {
+		exch, sym = strings.ToUpper(sym[:i]), sym[i+1:]
+	}
+	return Entry{Input: in, Symbol: strings.ToUpper(strings.TrimSpace(sym)), Exchange: exch}
+}
+
+func dedupeEntries(in []Entry) []Entry {
+	seen := map[string]bool{}
+	out := in[:0]
+	for _, e := range in {
+		if e.Symbol == "" || seen[e.Symbol] {
+			continue
+		}
+		seen[e.Symbol] = true
+		out = append(out, e)
+	}
+	return out
+}
+
+// Export writes watchlists in the given format. Dynamic lists are written
+// as their current members.
+func Export(format string, w io.Writer, lists []store.Watchlist) error {
+	switch strings.ToLower(format) {
+	case FormatCSV, "":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"watchlist", "symbol"})
+		for _, l := range lists {
+			for _, s := range l.Symbols {
+				cw.Write([]string{l.Name, s})
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatTradingView:
+		for _, l := range lists {
+			cells := append([]string{"###" + strings.ReplaceAll(l.Name, ",", " ")}, l.Symbols...)
+			if _, err := fmt.Fprintln(w, strings.Join(cells, ",")); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON:
+		b := jsonBundle{Format: jsonFormat, Version: 1, Watchlists: make([]jsonList, len(lists))}
+		for i, l := range lists {
+			b.Watchlists[i] = jsonList{Name: l.Name, Color: l.Color, Symbols: l.Symbols}
+			if b.Watchlists[i].Symbols == nil {
+				b.Watchlists[i].Symbols = []string{}
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(b)
+	}
+	return fmt.Errorf("unknown format %q (want %s)", format, strings.Join(Formats(), ", "))
+}
+
+// Searcher looks symbols up; *news.Client is one.
+type Searcher interface {
+	SearchSymbol(ctx context.Context, query string, limit int) ([]news.SearchResult, error)
+}
+
+// Resolution states.
+const (
+	Resolved   = "resolved"
+	Unresolved = "unresolved"
+	Ambiguous  = "ambiguous"
+)
+
+// Resolution is what an import entry resolved to.
+type Resolution struct {
+	Input      string              `json:"input"`
+	List       string              `json:"list"`
+	Status     string              `json:"status"`
+	Symbol     string              `json:"symbol,omitempty"`
+	Candidates []news.SearchResult `json:"candidates,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// resolve looks an entry up. An exact ticker match (allowing BRK.B for
+// BRK-B) resolves; so does a single listing on the entry's exchange. Any
+// other hits are candidates for the user to pick from.
+func resolve(ctx context.Context, s Searcher, e Entry) Resolution {
+	res := Resolution{Input: e.Input}
+	hits, err := s.SearchSymbol(ctx, e.Symbol, 10)
+	if err != nil {
+		res.Status, res.Error = Unresolved, err.Error()
+		return res
+	}
+	for _, want := range symbolVariants(e.Symbol) {
+		for _, h := range hits {
+			if strings.EqualFold(h.Symbol, want) {
+				res.Status, res.Symbol = Resolved, h.Symbol
+				return res
+			}
+		}
+	}
+	if e.Exchange != "" {
+		var onExchange []news.SearchResult
+		for _, h := range hits {
+			if strings.EqualFold(h.Exchange, e.Exchange) && strings.HasPrefix(strings.ToUpper(h.Symbol), e.Symbol+".") {
+				onExchange = append(onExchange, h)
+			}
+		}
+		if len(onExchange) == 1 {
+			res.Status, res.Symbol = Resolved, onExchange[0].Symbol
+			return res
+		}
+	}
+	if len(hits) == 0 {
+		res.Status = Unresolved
+		return res
+	}
+	if len(hits) > 5 {
+		hits = hits[:5]
+	}
+	res.Status, res.Candidates = Ambiguous, hits
+	return res
+}
+
+func symbolVariants(sym string) []string {
+	out := []string{sym}
+	if strings.Contains(sym, ".") {
+		out = append(out, strings.ReplaceAll(sym, ".", "-"))
+	}
+	if strings.Contains(sym, "/") {
+		out = append(out, strings.ReplaceAll(sym, "/", "-"))
+	}
+	return out
+}
+
+// ImportOptions controls an import.
+type ImportOptions struct {
+	Format string
+	// Target receives entries the file doesn't assign to a named list.
+	// 0 is the default watchlist.
+	Target int64
+	// Choices settles entries by their Input: the symbol to use, or "" to
+	// skip. They come from the user confirming a previous report.
+	Choices map[string]string
+	// DryRun resolves and reports without writing.
+	DryRun bool
+}
+
+// ListReport is one watchlist's share of an import.
+type ListReport struct {
+	Name        string   `json:"name"`
+	WatchlistID int64    `json:"watchlistId,omitempty"`
+	Created     bool     `json:"created"`
+	Added       []string `json:"added"`
+	Existing    []string `json:"existing"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// ImportReport is the outcome of an import. Unresolved and ambiguous
+// entries were not added; resubmitting with Choices settles them.
+type ImportReport struct {
+	Format     string       `json:"format"`
+	DryRun     bool         `json:"dryRun"`
+	Lists      []ListReport `json:"lists"`
+	Unresolved []Resolution `json:"unresolved"`
+	Ambiguous  []Resolution `json:"ambiguous"`
+}
+
+// importConcurrency bounds the symbol searches in flight.
+const importConcurrency = 8
+
+// Import parses data, resolves every symbol through search and adds the
+// resolved ones to their lists, creating named lists that don't exist.
+// Symbols already on a list are reported as existing, so importing the
+// same file twice changes nothing.
+func Import(ctx context.Context, st store.WatchlistRepository, search Searcher, data []byte, opt ImportOptions) (*ImportReport, error) {
+	format := opt.Format
+	if format == "" {
+		format = DetectFormat(data)
+	}
+	lists, err := Parse(format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve each distinct input once.
+	resolved := map[string]Resolution{}
+	var pending []Entry
+	for _, l := range lists {
+		for _, e := range l.Entries {
+			if _, ok := resolved[e.Input]; ok {
+				continue
+			}
+			if sym, ok := opt.Choices[e.Input]; ok {
+				r := Resolution{Input: e.Input, Status: Resolved, Symbol: strings.ToUpper(strings.TrimSpace(sym))}
+				if r.Symbol == "" {
+					r.Status = Unresolved
+				}
+				resolved[e.Input] = r
+				continue
+			}
+			resolved[e.Input] = Resolution{}
+			pending = append(pending, e)
+		}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, importConcurrency)
+	for _, e := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(e Entry) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r := resolve(ctx, search, e)
+			mu.Lock()
+			resolved[e.Input] = r
+			mu.Unlock()
+		}(e)
+	}
+	wg.Wait()
+
+	existing, err := st.GetWatchlists()
+	if err != nil {
+		return nil, err
+	}
+	report := &ImportReport{Format: format, DryRun: opt.DryRun, Unresolved: []Resolution{}, Ambiguous: []Resolution{}}
+	for _, l := range lists {
+		lr := ListReport{Name: l.Name, Added: []string{}, Existing: []string{}}
+		wl := findList(existing, l.Name, opt.Target)
+		switch {
+		case wl != nil:
+			lr.Name, lr.WatchlistID = wl.Name, wl.ID
+		case l.Name == "":
+			lr.Error = fmt.Sprintf("watchlist %d not found", opt.Target)
+		default:
+			lr.Created = true
+		}
+
+		have := map[string]bool{}
+		if wl != nil {
+			for _, s := range wl.Symbols {
+				have[s] = true
+			}
+		}
+		for _, e := range l.Entries {
+			r := resolved[e.Input]
+			r.List = lr.Name
+			switch r.Status {
+			case Resolved:
+				if have[r.Symbol] {
+					lr.Existing = append(lr.Existing, r.Symbol)
+				} else if !contains(lr.Added, r.Symbol) {
+					lr.Added = append(lr.Added, r.Symbol)
+				}
+			case Ambiguous:
+				report.Ambiguous = append(report.Ambiguous, r)
+			default:
+				if _, chosen := opt.Choices[e.Input]; !chosen {
+					report.Unresolved = append(report.Unresolved, r)
+				}
+			}
+		}
+		if wl != nil && wl.Dynamic && len(lr.Added) > 0 {
+			lr.Error = fmt.Sprintf("%q follows a screener query", wl.Name)
+			lr.Added = []string{}
+		}
+
+		if !opt.DryRun && lr.Error == "" && len(lr.Added) > 0 {
+			if err := applyList(st, &lr, l); err != nil {
+				lr.Error = err.Error()
+			} else if lr.Created {
+				existing = append(existing, store.Watchlist{ID: lr.WatchlistID, Name: lr.Name, Symbols: lr.Added})
+			}
+		}
+		report.Lists = append(report.Lists, lr)
+	}
+	sort.SliceStable(report.Ambiguous, func(i, j int) bool { return report.Ambiguous[i].Input < report.Ambiguous[j].Input })
+	return report, nil
+}
+
+func applyList(st store.WatchlistRepository, lr *ListReport, l List) error {
+	if lr.Created {
+		wl, err := st.CreateWatchlist(l.Name)
+		if err != nil {
+			return err
+		}
+		lr.WatchlistID = wl.ID
+		if validColor(l.Color) {
+			if err := st.SetWatchlistColor(wl.ID, l.Color); err != nil {
+				return err
+			}
+		}
+	}
+	for _, sym := range lr.Added {
+		if err := st.AddToWatchlist(lr.WatchlistID, sym); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findList matches a named list case-insensitively; an unnamed one is the
+// target, or the first (default) list when target is 0.
+func findList(lists []store.Watchlist, name string, target int64) *store.Watchlist {
+	for i := range lists {
+		if name == "" && (lists[i].ID == target || target == 0) {
+			return &lists[i]
+		}
+		if name != "" && strings.EqualFold(lists[i].Name, name) {
+			return &lists[i]
+		}
+	}
+	return nil
+}
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func validColor(c string) bool { return colorPattern.MatchString(c) }
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package watchlists
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"stocktopus/internal/news"
+	"stocktopus/internal/store"
+)
+
+func TestParseFormats(t *testing.T) {
+	tests := []struct {
+		name, format, data string
+		want               string // list:SYM,SYM;...
+	}{
+		{"plain csv", "", "aapl,msft\nNVDA\n\n$tsla,AAPL", ":AAPL,MSFT,NVDA,TSLA"},
+		{"csv header", FormatCSV, "Ticker,Watchlist,Note\nJPM,Banks,x\nXOM,Energy,\nWFC,Banks,", "Banks:JPM,WFC;Energy:XOM"},
+		{"tradingview", "", "###Tech,NASDAQ:AAPL,NASDAQ:MSFT\n###Canada,TSX:SHOP,NYSE:BRK.B", "Tech:AAPL,MSFT;Canada:SHOP,BRK.B"},
+		{"tradingview unsectioned", FormatTradingView, "NASDAQ:AAPL,AMEX:SPY", ":AAPL,SPY"},
+		{"json", "", `{"format":"stocktopus-watchlists","version":1,"watchlists":[{"name":"Core","color":"#4499ff","symbols":["AAPL"]}]}`, "Core:AAPL"},
+		{"json array", "", `[{"name":"Core","symbols":["aapl","AAPL"]}]`, "Core:AAPL"},
+	}
+	for _, tt := range tests {
+		lists, err := Parse(tt.format, []byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var parts []string
+		for _, l := range lists {
+			var syms []string
+			for _, e := range l.Entries {
+				syms = append(syms, e.Symbol)
+			}
+			parts = append(parts, l.Name+":"+strings.Join(syms, ","))
+		}
+		if got := strings.Join(parts, ";"); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := Parse(FormatJSON, []byte(`{"format":"other"}`)); err == nil {
+		t.Error("foreign JSON should be rejected")
+	}
+	if _, err := Parse(FormatCSV, []byte("\n,\n")); err == nil {
+		t.Error("an empty file should be an error")
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	lists := []store.Watchlist{
+		{Name: "Core", Color: "#4499ff", Symbols: []string{"AAPL", "BRK-B"}},
+		{Name: "Banks, US", Color: "#ff4444", Symbols: []string{"JPM"}},
+	}
+	for _, format := range Formats() {
+		var buf bytes.Buffer
+		if err := Export(format, &buf, lists); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		got, err := Parse("", buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: parse back: %v\n%s", format, err, buf.String())
+		}
+		if len(got) != 2 || len(got[0].Entries) != 2 || got[1].Entries[0].Symbol != "JPM" {
+			t.Errorf("%s: round trip = %+v", format, got)
+		}
+		if format == FormatJSON && got[1].Color != "#ff4444" {
+			t.Errorf("json lost the colour: %+v", got[1])
+		}
+	}
+}
+
+type fakeSearch map[string][]news.SearchResult
+
+func (f fakeSearch) SearchSymbol(ctx context.Context, q string, limit int) ([]news.SearchResult, error) {
+	return f[q], nil
+}
+
+func TestImportResolvesAndIsIdempotent(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "wl.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer st.Close()
+	st.AddToWatchlist(0, "AAPL")
+
+	search := fakeSearch{
+		"AAPL":      {{Symbol: "AAPL", Exchange: "NASDAQ"}},
+		"BRK.B":     {{Symbol: "BRK-B", Exchange: "NYSE"}},
+		"SHOP":      {{Symbol: "SHOP", Exchange: "NYSE"}, {Symbol: "SHOP.TO", Exchange: "TSX"}},
+		"RY":        {{Symbol: "RY.TO", Exchange: "TSX"}},
+		"MICROSOFT": {{Symbol: "MSFT", Name: "Microsoft"}},
+	}
+	file := []byte("AAPL,BRK.B,TSX:RY,MICROSOFT,ZZZZ\n###Canada,TSX:SHOP,TSX:RY")
+
+	dry, err := Import(context.Background(), st, search, file, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dry.Lists) != 2 || !dry.Lists[1].Created {
+		t.Fatalf("dry run lists = %+v", dry.Lists)
+	}
+	main := dry.Lists[0]
+	if strings.Join(main.Added, ",") != "BRK-B,RY.TO" || strings.Join(main.Existing, ",") != "AAPL" {
+		t.Errorf("default list = %+v", main)
+	}
+	if strings.Join(dry.Lists[1].Added, ",") != "SHOP,RY.TO" {
+		t.Errorf("canada = %+v", dry.Lists[1])
+	}
+	if len(dry.Ambiguous) != 1 || dry.Ambiguous[0].Input != "MICROSOFT" || dry.Ambiguous[0].Candidates[0].Symbol != "MSFT" {
+		t.Errorf("ambiguous = %+v", dry.Ambiguous)
+	}
+	if len(dry.Unresolved) != 1 || dry.Unresolved[0].Input != "ZZZZ" {
+		t.Errorf("unresolved = %+v", dry.Unresolved)
+	}
+	if lists, _ := st.GetWatchlists(); len(lists) != 1 || len(lists[0].Symbols) != 1 {
+		t.Fatalf("dry run wrote: %+v", lists)
+	}
+
+	// Confirm: pick MSFT, skip ZZZZ.
+	opt := ImportOptions{Choices: map[string]string{"MICROSOFT": "MSFT", "ZZZZ": ""}}
+	rep, err := Import(context.Background(), st, search, file, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Ambiguous)+len(rep.Unresolved) != 0 {
+		t.Errorf("still open: %+v %+v", rep.Ambiguous, rep.Unresolved)
+	}
+	lists, _ := st.GetWatchlists()
+	if len(lists) != 2 || len(lists[0].Symbols) != 4 || lists[1].Name != "Canada" || len(lists[1].Symbols) != 2 {
+		t.Fatalf("after import = %+v", lists)
+	}
+
+	again, _ := Import(context.Background(), st, search, file, opt)
+	for _, l := range again.Lists {
+		if len(l.Added) != 0 || l.Created {
+			t.Errorf("second import changed %q: %+v", l.Name, l)
+		}
+	}
+	if lists, _ := st.GetWatchlists(); len(lists) != 2 {
+		t.Errorf("second import created lists: %+v", lists)
+	}
+}
+
+func TestImportJSONKeepsColour(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "wl.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer st.Close()
+	search := fakeSearch{"XOM": {{Symbol: "XOM"}}}
+	data := []byte(`{"format":"stocktopus-watchlists","version":1,"watchlists":[{"name":"Energy","color":"#123abc","symbols":["XOM"]}]}`)
+	if _, err := Import(context.Background(), st, search, data, ImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	lists, _ := st.GetWatchlists()
+	if len(lists) != 2 || lists[1].Color != "#123abc" || lists[1].Symbols[0] != "XOM" {
+		t.Errorf("lists = %+v", lists)
+	}
+}
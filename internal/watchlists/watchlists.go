@@ -0,0 +1,192 @@
+// Package watchlists keeps dynamic watchlists in step with the screener
+// queries that define them, moves watchlists in and out of other tools,
+// and computes the per-list indicator columns.
+package watchlists
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"stocktopus/internal/budget"
+	"stocktopus/internal/hub"
+	"stocktopus/internal/store"
+)
+
+// Topic carries membership changes for every dynamic watchlist.
+const Topic = "watchlists"
+
+// MsgMembership is the hub message type for a membership change.
+const MsgMembership = "watchlist_membership"
+
+// Screener runs a saved screener query and returns the matching symbols
+// in rank order.
+type Screener func(ctx context.Context, query url.Values) ([]string, error)
+
+// Publisher is the part of the hub the refresher needs.
+type Publisher interface {
+	Publish(topic string, data []byte)
+}
+
+// Change is one evaluation's effect on a watchlist.
+type Change struct {
+	WatchlistID int64     `json:"watchlistId"`
+	Name        string    `json:"name"`
+	Joined      []string  `json:"joined"`
+	Left        []string  `json:"left"`
+	Symbols     []string  `json:"symbols"`
+	EvaluatedAt time.Time `json:"evaluatedAt"`
+}
+
+// Refresher re-runs each dynamic watchlist's query on an interval and
+// publishes who joined and who left.
+type Refresher struct {
+	store    store.WatchlistRepository
+	hub      Publisher
+	screen   Screener
+	logger   *slog.Logger
+	interval time.Duration
+
+	mu   sync.RWMutex
+	wake chan struct{} // interval changed; reset the ticker
+	busy sync.Mutex    // one evaluation at a time
+}
+
+func New(st store.WatchlistRepository, h Publisher, screen Screener, interval time.Duration, logger *slog.Logger) *Refresher {
+	return &Refresher{
+		store:    st,
+		hub:      h,
+		screen:   screen,
+		logger:   logger.With("component", "watchlists"),
+		interval: interval,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// SetInterval changes how often the queries are re-run.
+func (r *Refresher) SetInterval(d time.Duration) {
+	r.mu.Lock()
+	r.interval = d
+	r.mu.Unlock()
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Refresher) currentInterval() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.interval
+}
+
+// Run evaluates every dynamic watchlist now and then on each tick.
+func (r *Refresher) Run(ctx context.Context) {
+	ctx = budget.WithCaller(ctx, "watchlists", budget.Background)
+	ticker := time.NewTicker(r.currentInterval())
+	defer ticker.Stop()
+	r.logger.Info("dynamic watchlists started", "interval", r.currentInterval())
+
+	r.RefreshAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("dynamic watchlists stopped")
+			return
+		case <-ticker.C:
+			r.RefreshAll(ctx)
+		case <-r.wake:
+			ticker.Reset(r.currentInterval())
+		}
+	}
+}
+
+// RefreshAll evaluates every dynamic watchlist. A failing query is logged
+// and leaves that list as it was.
+func (r *Refresher) RefreshAll(ctx context.Context) {
+	lists, err := r.store.GetDynamicWatchlists()
+	if err != nil {
+		r.logger.Error("list dynamic watchlists", "error", err)
+		return
+	}
+	for _, wl := range lists {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := r.refresh(ctx, wl); err != nil {
+			r.logger.Warn("dynamic watchlist not refreshed", "watchlist", wl.Name, "error", err)
+		}
+	}
+}
+
+// Refresh evaluates one dynamic watchlist now.
+func (r *Refresher) Refresh(ctx context.Context, id int64) (*Change, error) {
+	wl, err := r.store.GetWatchlist(id)
+	if err != nil {
+		return nil, err
+	}
+	if wl == nil {
+		return nil, fmt.Errorf("watchlist %d not found", id)
+	}
+	if !wl.Dynamic {
+		return nil, fmt.Errorf("watchlist %q has no screener query", wl.Name)
+	}
+	return r.refresh(ctx, *wl)
+}
+
+func (r *Refresher) refresh(ctx context.Context, wl store.Watchlist) (*Change, error) {
+	r.busy.Lock()
+	defer r.busy.Unlock()
+
+	query, err := url.ParseQuery(wl.Query)
+	if err != nil {
+		return nil, fmt.Errorf("bad query: %w", err)
+	}
+	symbols, err := r.screen(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	joined, left, err := r.store.SetWatchlistMembers(wl.ID, symbols)
+	if err != nil {
+		return nil, err
+	}
+	ch := &Change{
+		WatchlistID: wl.ID,
+		Name:        wl.Name,
+		Joined:      nonNil(joined),
+		Left:        nonNil(left),
+		Symbols:     nonNil(symbols),
+		EvaluatedAt: time.Now().UTC(),
+	}
+	if len(joined) > 0 || len(left) > 0 {
+		r.logger.Info("dynamic watchlist changed", "watchlist", wl.Name, "joined", joined, "left", left)
+		r.publish(ch)
+	}
+	return ch, nil
+}
+
+func (r *Refresher) publish(ch *Change) {
+	if r.hub == nil {
+		return
+	}
+	payload, _ := json.Marshal(ch)
+	msg, err := json.Marshal(hub.OutboundMessage{
+		Type:    MsgMembership,
+		Topic:   Topic,
+		Payload: payload,
+	})
+	if err == nil {
+		r.hub.Publish(Topic, msg)
+	}
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
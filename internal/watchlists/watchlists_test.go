@@ -0,0 +1,93 @@
+package watchlists
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"stocktopus/internal/hub"
+	"stocktopus/internal/store"
+)
+
+type recorder struct{ msgs []hub.OutboundMessage }
+
+func (r *recorder) Publish(topic string, data []byte) {
+	var m hub.OutboundMessage
+	json.Unmarshal(data, &m)
+	r.msgs = append(r.msgs, m)
+}
+
+func TestRefreshPublishesMembershipChanges(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "wl.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer st.Close()
+
+	static, _ := st.CreateWatchlist("Core")
+	st.AddToWatchlist(static.ID, "AAPL")
+	wl, err := st.CreateDynamicWatchlist("Gappers", "changeFromOpenMin=2&sector=Technology")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []string{"NVDA", "AMD"}
+	var seen url.Values
+	screen := func(ctx context.Context, q url.Values) ([]string, error) {
+		seen = q
+		if results == nil {
+			return nil, errors.New("upstream down")
+		}
+		return results, nil
+	}
+	pub := &recorder{}
+	r := New(st, pub, screen, 0, slog.Default())
+
+	r.RefreshAll(context.Background())
+	if seen.Get("changeFromOpenMin") != "2" || seen.Get("sector") != "Technology" {
+		t.Errorf("screen got %v", seen)
+	}
+	if len(pub.msgs) != 1 || pub.msgs[0].Type != MsgMembership || pub.msgs[0].Topic != Topic {
+		t.Fatalf("messages = %+v", pub.msgs)
+	}
+	var ch Change
+	json.Unmarshal(pub.msgs[0].Payload, &ch)
+	if ch.WatchlistID != wl.ID || strings.Join(ch.Joined, ",") != "NVDA,AMD" || len(ch.Left) != 0 {
+		t.Errorf("first change = %+v", ch)
+	}
+
+	// Same result: nothing to announce.
+	r.RefreshAll(context.Background())
+	if len(pub.msgs) != 1 {
+		t.Errorf("unchanged screen published %d messages", len(pub.msgs)-1)
+	}
+
+	results = []string{"AMD", "SMCI"}
+	got, err := r.Refresh(context.Background(), wl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got.Joined, ",") != "SMCI" || strings.Join(got.Left, ",") != "NVDA" {
+		t.Errorf("second change = %+v", got)
+	}
+
+	// A failing screen leaves the list alone.
+	results = nil
+	if _, err := r.Refresh(context.Background(), wl.ID); err == nil {
+		t.Error("want screen error")
+	}
+	if after, _ := st.GetWatchlist(wl.ID); strings.Join(after.Symbols, ",") != "AMD,SMCI" {
+		t.Errorf("members after failed screen = %v", after.Symbols)
+	}
+	if _, err := r.Refresh(context.Background(), static.ID); err == nil {
+		t.Error("refreshing a static watchlist should fail")
+	}
+	if core, _ := st.GetWatchlist(static.ID); strings.Join(core.Symbols, ",") != "AAPL" {
+		t.Errorf("static list touched: %v", core.Symbols)
+	}
+}
@@ -0,0 +1,92 @@
+// Package webhook delivers JSON event payloads to user-configured HTTP
+// endpoints. Delivery is fire-and-forget: the caller hands over a payload
+// and moves on, failures are logged and retried a couple of times with a
+// short backoff, never surfaced back to the publisher.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Event is the envelope POSTed to every webhook. Type mirrors the hub
+// message type ("news_alert", "screen_diff", …) so one receiver can fan
+// out on a single field.
+type Event struct {
+	Type    string    `json:"type"`
+	SentAt  time.Time `json:"sentAt"`
+	Payload any       `json:"payload"`
+}
+
+type Sender struct {
+	http     *http.Client
+	logger   *slog.Logger
+	attempts int
+}
+
+func New(logger *slog.Logger) *Sender {
+	return &Sender{
+		http:     &http.Client{Timeout: 10 * time.Second},
+		logger:   logger.With("component", "webhook"),
+		attempts: 3,
+	}
+}
+
+// Send posts the event in the background. Empty URLs are ignored so
+// callers can pass a watch's optional webhook field straight through.
+func (s *Sender) Send(url, eventType string, payload any) {
+	if s == nil || strings.TrimSpace(url) == "" {
+		return
+	}
+	body, err := json.Marshal(Event{Type: eventType, SentAt: time.Now().UTC(), Payload: payload})
+	if err != nil {
+		s.logger.Error("webhook marshal failed", "type", eventType, "error", err)
+		return
+	}
+	go s.deliver(url, eventType, body)
+}
+
+func (s *Sender) deliver(url, eventType string, body []byte) {
+	backoff := 500 * time.Millisecond
+	for attempt := 1; attempt <= s.attempts; attempt++ {
+		err := s.post(url, body)
+		if err == nil {
+			s.logger.Debug("webhook delivered", "type", eventType, "url", url, "attempt", attempt)
+			return
+		}
+		if attempt == s.attempts {
+			s.logger.Warn("webhook delivery failed", "type", eventType, "url", url, "error", err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (s *Sender) post(url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "stocktopus-webhook/1")
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+// Package xlsx writes minimal Office Open XML spreadsheets: numbers, text
+// and formulas in a handful of fixed styles, frozen panes, column widths
+// and a conditional highlight. It's enough to hand a model to Excel with
+// its formulas live, without a third-party library.
+//
+// Formulas are written without cached values and the workbook asks for a
+// full recalculation on load, so Excel, LibreOffice and Numbers compute
+// every cell when the file is opened.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Style is a cell format. The set is fixed; see styleSheet.
+type Style int
+
+const (
+	StyleDefault  Style = iota
+	StyleBold           // labels of total rows
+	StyleMoney          // #,##0 with negatives in parentheses
+	StyleMoneyTot       // StyleMoney, bold
+	StylePctInput       // 0.0%, blue: a driver the user is meant to edit
+	StyleNumInput       // #,##0, blue
+	StyleHeader         // bold on grey, centred
+	StyleNumber         // 0.00
+)
+
+// Cell is one cell's content: a number, text or a formula (without the
+// leading "=").
+type Cell struct {
+	Number  *float64
+	Text    string
+	Formula string
+	Style   Style
+}
+
+// Sheet is one worksheet. Rows and columns are 1-based, as in Excel.
+type Sheet struct {
+	name       string
+	cells      map[[2]int]Cell
+	widths     map[int]float64
+	freezeRow  int
+	freezeCol  int
+	highlights []highlight
+	maxRow     int
+	maxCol     int
+}
+
+// highlight flags cells outside [lo, hi] red and inside it green.
+type highlight struct {
+	ref    string
+	lo, hi float64
+}
+
+// Workbook is a set of sheets written in the order they were added.
+type Workbook struct {
+	sheets []*Sheet
+}
+
+// New returns an empty workbook.
+func New() *Workbook { return &Workbook{} }
+
+// AddSheet appends a sheet. Excel limits names to 31 characters and
+// forbids []:*?/\ — callers pass fixed names that fit.
+func (wb *Workbook) AddSheet(name string) *Sheet {
+	s := &Sheet{name: name, cells: map[[2]int]Cell{}, widths: map[int]float64{}}
+	wb.sheets = append(wb.sheets, s)
+	return s
+}
+
+// Sheet returns the named sheet, or nil.
+func (wb *Workbook) Sheet(name string) *Sheet {
+	for _, s := range wb.sheets {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Name returns the sheet's name.
+func (s *Sheet) Name() string { return s.name }
+
+// Ref returns the sheet-qualified reference of a cell, for formulas on
+// other sheets: 'Balance Sheet'!C12.
+func (s *Sheet) Ref(row, col int) string {
+	return "'" + strings.ReplaceAll(s.name, "'", "''") + "'!" + Ref(row, col)
+}
+
+// SetNumber writes a number. NaN and ±Inf leave the cell empty.
+func (s *Sheet) SetNumber(row, col int, v float64, style Style) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		s.set(row, col, Cell{Style: style})
+		return
+	}
+	s.set(row, col, Cell{Number: &v, Style: style})
+}
+
+// SetText writes a string.
+func (s *Sheet) SetText(row, col int, v string, style Style) {
+	s.set(row, col, Cell{Text: v, Style: style})
+}
+
+// SetFormula writes a formula, e.g. "B4*(1+Assumptions!C3)".
+func (s *Sheet) SetFormula(row, col int, f string, style Style) {
+	s.set(row, col, Cell{Formula: f, Style: style})
+}
+
+// Get returns the cell at row, col.
+func (s *Sheet) Get(row, col int) (Cell, bool) {
+	c, ok := s.cells[[2]int{row, col}]
+	return c, ok
+}
+
+// SetWidth sets a column's width in characters.
+func (s *Sheet) SetWidth(col int, w float64) { s.widths[col] = w }
+
+// Freeze keeps the rows above row and the columns left of col in view.
+func (s *Sheet) Freeze(row, col int) { s.freezeRow, s.freezeCol = row, col }
+
+// Highlight adds conditional formatting to a range such as "C22:J22":
+// values outside [lo, hi] are filled red, values inside it go green.
+func (s *Sheet) Highlight(ref string, lo, hi float64) {
+	s.highlights = append(s.highlights, highlight{ref, lo, hi})
+}
+
+func (s *Sheet) set(row, col int, c Cell) {
+	if row < 1 || col < 1 {
+		return
+	}
+	s.cells[[2]int{row, col}] = c
+	s.maxRow = max(s.maxRow, row)
+	s.maxCol = max(s.maxCol, col)
+}
+
+// ColName returns a column's letters: 1 → A, 27 → AA.
+func ColName(col int) string {
+	var b []byte
+	for col > 0 {
+		col--
+		b = append([]byte{byte('A' + col%26)}, b...)
+		col /= 26
+	}
+	return string(b)
+}
+
+// Ref returns a cell reference: Ref(3, 2) → "B3".
+func Ref(row, col int) string { return ColName(col) + strconv.Itoa(row) }
+
+// Write writes the workbook as an .xlsx file.
+func (wb *Workbook) Write(w io.Writer) error {
+	if len(wb.sheets) == 0 {
+		return fmt.Errorf("xlsx: workbook has no sheets")
+	}
+	z := zip.NewWriter(w)
+	parts := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", wb.contentTypes()},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", wb.workbookXML()},
+		{"xl/_rels/workbook.xml.rels", wb.workbookRels()},
+		{"xl/styles.xml", styleSheet},
+	}
+	for i, s := range wb.sheets {
+		parts = append(parts, struct {
+			name string
+			body string
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), s.xml()})
+	}
+	for _, p := range parts {
+		f, err := z.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xml.Header+p.body); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+const (
+	nsMain = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	nsRel  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	nsPkg  = "http://schemas.openxmlformats.org/package/2006/relationships"
+)
+
+const rootRels = `<Relationships xmlns="` + nsPkg + `">` +
+	`<Relationship Id="rId1" Type="` + nsRel + `/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+func (wb *Workbook) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (wb *Workbook) workbookXML() string {
+	var b strings.Builder
+	b.WriteString(`<workbook xmlns="` + nsMain + `" xmlns:r="` + nsRel + `"><sheets>`)
+	for i, s := range wb.sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(s.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets><calcPr calcId="0" fullCalcOnLoad="1"/></workbook>`)
+	return b.String()
+}
+
+func (wb *Workbook) workbookRels() string {
+	var b strings.Builder
+	b.WriteString(`<Relationships xmlns="` + nsPkg + `">`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="%s/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, nsRel, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="%s/styles" Target="styles.xml"/>`, len(wb.sheets)+1, nsRel)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (s *Sheet) xml() string {
+	var b strings.Builder
+	b.WriteString(`<worksheet xmlns="` + nsMain + `" xmlns:r="` + nsRel + `">`)
+	if s.freezeRow > 1 || s.freezeCol > 1 {
+		top := Ref(max(s.freezeRow, 1), max(s.freezeCol, 1))
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane`)
+		if s.freezeCol > 1 {
+			fmt.Fprintf(&b, ` xSplit="%d"`, s.freezeCol-1)
+		}
+		if s.freezeRow > 1 {
+			fmt.Fprintf(&b, ` ySplit="%d"`, s.freezeRow-1)
+		}
+		fmt.Fprintf(&b, ` topLeftCell="%s" activePane="bottomRight" state="frozen"/></sheetView></sheetViews>`, top)
+	}
+	if len(s.widths) > 0 {
+		cols := make([]int, 0, len(s.widths))
+		for c := range s.widths {
+			cols = append(cols, c)
+		}
+		sort.Ints(cols)
+		b.WriteString(`<cols>`)
+		for _, c := range cols {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, c, c, s.widths[c])
+		}
+		b.WriteString(`</cols>`)
+	}
+
+	b.WriteString(`<sheetData>`)
+	for row := 1; row <= s.maxRow; row++ {
+		wrote := false
+		for col := 1; col <= s.maxCol; col++ {
+			c, ok := s.cells[[2]int{row, col}]
+			if !ok {
+				continue
+			}
+			if !wrote {
+				fmt.Fprintf(&b, `<row r="%d">`, row)
+				wrote = true
+			}
+			ref := Ref(row, col)
+			switch {
+			case c.Formula != "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><f>%s</f></c>`, ref, c.Style, escape(c.Formula))
+			case c.Number != nil:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, c.Style, strconv.FormatFloat(*c.Number, 'g', -1, 64))
+			case c.Text != "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, c.Style, escape(c.Text))
+			default:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"/>`, ref, c.Style)
+			}
+		}
+		if wrote {
+			b.WriteString(`</row>`)
+		}
+	}
+	b.WriteString(`</sheetData>`)
+
+	pri := 1
+	for _, h := range s.highlights {
+		lo, hi := strconv.FormatFloat(h.lo, 'g', -1, 64), strconv.FormatFloat(h.hi, 'g', -1, 64)
+		fmt.Fprintf(&b, `<conditionalFormatting sqref="%s">`, escape(h.ref))
+		fmt.Fprintf(&b, `<cfRule type="cellIs" dxfId="0" priority="%d" operator="notBetween"><formula>%s</formula><formula>%s</formula></cfRule>`, pri, lo, hi)
+		fmt.Fprintf(&b, `<cfRule type="cellIs" dxfId="1" priority="%d" operator="between"><formula>%s</formula><formula>%s</formula></cfRule>`, pri+1, lo, hi)
+		b.WriteString(`</conditionalFormatting>`)
+		pri += 2
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+// styleSheet defines the cellXfs in Style order, and the two
+// differential formats Highlight uses: 0 red (out of range), 1 green.
+const styleSheet = `<styleSheet xmlns="` + nsMain + `">` +
+	`<numFmts count="3">` +
+	`<numFmt numFmtId="164" formatCode="#,##0;(#,##0)"/>` +
+	`<numFmt numFmtId="165" formatCode="0.0%"/>` +
+	`<numFmt numFmtId="166" formatCode="0.00"/>` +
+	`</numFmts>` +
+	`<fonts count="3">` +
+	`<font><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><b/><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><sz val="11"/><color rgb="FF0000FF"/><name val="Calibri"/></font>` +
+	`</fonts>` +
+	`<fills count="3">` +
+	`<fill><patternFill patternType="none"/></fill>` +
+	`<fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFE7E6E6"/><bgColor indexed="64"/></patternFill></fill>` +
+	`</fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="8">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+	`<xf numFmtId="165" fontId="2" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="2" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1" applyAlignment="1"><alignment horizontal="center"/></xf>` +
+	`<xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`<dxfs count="2">` +
+	`<dxf><font><color rgb="FF9C0006"/></font><fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill></dxf>` +
+	`<dxf><font><color rgb="FF006100"/></font><fill><patternFill><bgColor rgb="FFC6EFCE"/></patternFill></fill></dxf>` +
+	`</dxfs>` +
+	`</styleSheet>`
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
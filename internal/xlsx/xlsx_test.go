@@ -0,0 +1,80 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestColName(t *testing.T) {
+	for col, want := range map[int]string{1: "A", 26: "Z", 27: "AA", 52: "AZ", 703: "AAA"} {
+		if got := ColName(col); got != want {
+			t.Errorf("ColName(%d) = %q, want %q", col, got, want)
+		}
+	}
+	if got := Ref(12, 3); got != "C12" {
+		t.Errorf("Ref = %q", got)
+	}
+}
+
+// TestWrite writes a two-sheet workbook and checks every part is there
+// and well-formed, and that formulas, text and the highlight survive.
+func TestWrite(t *testing.T) {
+	wb := New()
+	a := wb.AddSheet("Inputs")
+	a.SetNumber(1, 1, 0.05, StylePctInput)
+	b := wb.AddSheet("Bob's Sheet")
+	b.SetText(1, 1, "R&D <net>", StyleBold)
+	b.SetFormula(1, 2, "IF("+a.Ref(1, 1)+">0,1,0)", StyleMoney)
+	b.Freeze(2, 2)
+	b.Highlight("B1:C1", -0.5, 0.5)
+
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	for _, f := range z.File {
+		rc, _ := f.Open()
+		raw, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(raw)
+		d := xml.NewDecoder(bytes.NewReader(raw))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	s2 := parts["xl/worksheets/sheet2.xml"]
+	for _, want := range []string{
+		`R&amp;D &lt;net&gt;`,
+		`<f>IF(&#39;Inputs&#39;!A1&gt;0,1,0)</f>`,
+		`operator="notBetween"`,
+		`state="frozen"`,
+	} {
+		if !strings.Contains(s2, want) {
+			t.Errorf("sheet2 lacks %s:\n%s", want, s2)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `fullCalcOnLoad="1"`) {
+		t.Error("workbook should ask for a recalculation on load")
+	}
+	if wb.Sheet("Inputs") != a || wb.Sheet("nope") != nil {
+		t.Error("Sheet lookup")
+	}
+}
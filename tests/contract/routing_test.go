@@ -0,0 +1,105 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"stocktopus/internal/model"
+	"stocktopus/internal/provider"
+)
+
+// symbolProvider answers every symbol with its own name so tests can see
+// which sub-provider served a quote.
+type symbolProvider struct {
+	*MockProvider
+	calls [][]string
+}
+
+func (s *symbolProvider) GetQuotes(ctx context.Context, symbols []string) ([]*model.Quote, error) {
+	s.calls = append(s.calls, append([]string(nil), symbols...))
+	if s.QuoteError != nil {
+		return nil, s.QuoteError
+	}
+	out := make([]*model.Quote, len(symbols))
+	for i, sym := range symbols {
+		out[i] = &model.Quote{Symbol: sym, Price: 1}
+	}
+	return out, nil
+}
+
+func newSymbolProvider(name string) *symbolProvider {
+	m := NewMockProvider()
+	m.NameValue = name
+	return &symbolProvider{MockProvider: m}
+}
+
+func classes(m map[string]string) provider.AssetClassifier {
+	return func(sym string) string { return m[sym] }
+}
+
+func TestRoutingProvider_SplitsAndPreservesOrder(t *testing.T) {
+	equities := newSymbolProvider("fmp")
+	crypto := newSymbolProvider("cryptofeed")
+	router := provider.NewRoutingProvider(equities, classes(map[string]string{
+		"AAPL": "stock", "BTCUSD": "crypto", "SPY": "etf", "ETHUSD": "crypto",
+	})).Route("crypto", crypto)
+
+	symbols := []string{"BTCUSD", "AAPL", "ETHUSD", "SPY", "NEWIPO"}
+	quotes, err := router.GetQuotes(context.Background(), symbols)
+	if err != nil {
+		t.Fatalf("GetQuotes: %v", err)
+	}
+	if len(quotes) != len(symbols) {
+		t.Fatalf("got %d quotes, want %d", len(quotes), len(symbols))
+	}
+	for i, q := range quotes {
+		if q == nil || q.Symbol != symbols[i] {
+			t.Fatalf("quote %d = %+v, want %s", i, q, symbols[i])
+		}
+	}
+	if len(crypto.calls) != 1 || len(crypto.calls[0]) != 2 {
+		t.Errorf("crypto calls = %v, want one batch of 2", crypto.calls)
+	}
+	// Unclassified symbols go to the fallback.
+	if len(equities.calls) != 1 || len(equities.calls[0]) != 3 {
+		t.Errorf("equity calls = %v, want one batch of 3", equities.calls)
+	}
+}
+
+func TestRoutingProvider_PartialFailure(t *testing.T) {
+	equities := newSymbolProvider("fmp")
+	crypto := newSymbolProvider("cryptofeed")
+	crypto.QuoteError = provider.ErrCircuitOpen
+	router := provider.NewRoutingProvider(equities, classes(map[string]string{"BTCUSD": "crypto"})).
+		Route("crypto", crypto)
+
+	quotes, err := router.GetQuotes(context.Background(), []string{"AAPL", "BTCUSD", "MSFT"})
+	if err == nil {
+		t.Fatal("expected a routing error")
+	}
+	var rerr *provider.RoutingError
+	if !errors.As(err, &rerr) || len(rerr.Failed) != 1 || rerr.Failed[0].Provider != "cryptofeed" {
+		t.Errorf("error = %v, want RoutingError naming cryptofeed", err)
+	}
+	if !errors.Is(err, provider.ErrCircuitOpen) {
+		t.Error("errors.Is should see the sub-provider's error")
+	}
+	if quotes == nil || quotes[0] == nil || quotes[1] != nil || quotes[2] == nil {
+		t.Errorf("quotes = %v, want AAPL and MSFT with a nil gap for BTCUSD", quotes)
+	}
+}
+
+func TestRoutingProvider_SingleRoutePassesThrough(t *testing.T) {
+	equities := newSymbolProvider("fmp")
+	equities.QuoteError = provider.ErrRateLimitExceeded
+	router := provider.NewRoutingProvider(equities, nil)
+
+	quotes, err := router.GetQuotes(context.Background(), []string{"AAPL"})
+	if !errors.Is(err, provider.ErrRateLimitExceeded) || quotes != nil {
+		t.Errorf("got (%v, %v), want the fallback's own error", quotes, err)
+	}
+	if q, _ := router.GetQuotes(context.Background(), nil); q == nil || len(q) != 0 {
+		t.Errorf("empty batch = %v, want empty slice", q)
+	}
+}
@@ -0,0 +1,135 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"stocktopus/internal/model"
+	"stocktopus/internal/provider"
+)
+
+// scriptedProvider returns whatever quotes the test sets for each symbol.
+type scriptedProvider struct {
+	*MockProvider
+	quotes map[string]model.Quote
+}
+
+func newScriptedProvider(name string) *scriptedProvider {
+	m := NewMockProvider()
+	m.NameValue = name
+	return &scriptedProvider{MockProvider: m, quotes: map[string]model.Quote{}}
+}
+
+func (s *scriptedProvider) GetQuote(ctx context.Context, symbol string) (*model.Quote, error) {
+	q, ok := s.quotes[symbol]
+	if !ok {
+		return nil, provider.ErrSymbolNotFound
+	}
+	return &q, nil
+}
+
+func (s *scriptedProvider) GetQuotes(ctx context.Context, symbols []string) ([]*model.Quote, error) {
+	out := make([]*model.Quote, len(symbols))
+	for i, sym := range symbols {
+		if q, ok := s.quotes[sym]; ok {
+			out[i] = &q
+		}
+	}
+	return out, nil
+}
+
+func TestValidation_RejectsRuleViolations(t *testing.T) {
+	now := time.Now().UTC()
+	base := newScriptedProvider("mock")
+	base.quotes = map[string]model.Quote{
+		"AAPL":  {Symbol: "AAPL", Price: 230, Volume: 10, Timestamp: now},
+		"BRK.B": {Symbol: "BRK.B", Price: 450, Timestamp: now},
+		"ZERO":  {Symbol: "ZERO", Price: 0, Timestamp: now},
+		"NEGV":  {Symbol: "NEGV", Price: 5, Volume: -1, Timestamp: now},
+		"FUT":   {Symbol: "FUT", Price: 5, Timestamp: now.Add(time.Hour)},
+		"lower": {Symbol: "lower", Price: 5, Timestamp: now},
+	}
+	p := provider.NewProviderBuilder(base).WithValidation(provider.DefaultValidationConfig()).Build()
+
+	symbols := []string{"AAPL", "ZERO", "BRK.B", "NEGV", "FUT", "lower"}
+	quotes, err := p.GetQuotes(context.Background(), symbols)
+	if err != nil {
+		t.Fatalf("a bad row must not fail the batch: %v", err)
+	}
+	for i, sym := range symbols {
+		valid := sym == "AAPL" || sym == "BRK.B"
+		if (quotes[i] != nil) != valid {
+			t.Errorf("%s: got %+v, want valid=%v", sym, quotes[i], valid)
+		}
+	}
+
+	_, err = p.GetQuote(context.Background(), "ZERO")
+	var perr *provider.ProviderError
+	if !errors.Is(err, provider.ErrInvalidQuote) || !errors.As(err, &perr) || perr.IsRetryable() {
+		t.Errorf("GetQuote(ZERO) err = %v, want non-retryable ErrInvalidQuote", err)
+	}
+}
+
+func TestValidation_FlagsStaleAndJumps(t *testing.T) {
+	now := time.Now().UTC()
+	base := newScriptedProvider("mock")
+	cfg := provider.DefaultValidationConfig()
+	open := true
+	cfg.IsOpen = func(string, time.Time) bool { return open }
+	p := provider.NewValidatingProvider(base, cfg)
+	ctx := context.Background()
+
+	base.quotes["AAPL"] = model.Quote{Symbol: "AAPL", Price: 100, Timestamp: now.Add(-time.Hour)}
+	q, _ := p.GetQuote(ctx, "AAPL")
+	if !slices.Equal(q.Flags, []model.QuoteFlag{model.FlagStale}) {
+		t.Errorf("hour-old quote in session: flags = %v, want [stale]", q.Flags)
+	}
+
+	open = false
+	q, _ = p.GetQuote(ctx, "AAPL")
+	if len(q.Flags) != 0 {
+		t.Errorf("closed market: flags = %v, want none", q.Flags)
+	}
+
+	base.quotes["AAPL"] = model.Quote{Symbol: "AAPL", Price: 150, Timestamp: now}
+	q, _ = p.GetQuote(ctx, "AAPL")
+	if !slices.Equal(q.Flags, []model.QuoteFlag{model.FlagJump}) {
+		t.Errorf("+50%% move: flags = %v, want [jump]", q.Flags)
+	}
+	// The cache advanced; the new level is not flagged again.
+	q, _ = p.GetQuote(ctx, "AAPL")
+	if len(q.Flags) != 0 {
+		t.Errorf("second poll at new level: flags = %v, want none", q.Flags)
+	}
+}
+
+func TestConsensus_FlagsDisagreement(t *testing.T) {
+	primary := newScriptedProvider("fmp")
+	secondary := newScriptedProvider("polygon")
+	primary.quotes["AAPL"] = model.Quote{Symbol: "AAPL", Price: 230.00}
+	secondary.quotes["AAPL"] = model.Quote{Symbol: "AAPL", Price: 230.50}
+	primary.quotes["MSFT"] = model.Quote{Symbol: "MSFT", Price: 410}
+	secondary.quotes["MSFT"] = model.Quote{Symbol: "MSFT", Price: 441}
+	primary.quotes["TSLA"] = model.Quote{Symbol: "TSLA", Price: 250} // secondary lacks it
+
+	p := provider.NewProviderBuilder(primary).WithConsensus(secondary, 0.01).Build()
+	quotes, err := p.GetQuotes(context.Background(), []string{"AAPL", "MSFT", "TSLA"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotes[0].Flags) != 0 || quotes[0].Price != 230.00 {
+		t.Errorf("AAPL within tolerance: %+v", quotes[0])
+	}
+	if !slices.Equal(quotes[1].Flags, []model.QuoteFlag{model.FlagDisagree}) || quotes[1].Price != 410 {
+		t.Errorf("MSFT 7.5%% apart: %+v, want primary price flagged disagree", quotes[1])
+	}
+	if len(quotes[2].Flags) != 0 {
+		t.Errorf("TSLA unchecked: flags = %v", quotes[2].Flags)
+	}
+	if p.Name() != "fmp" {
+		t.Errorf("Name() = %q, want primary's", p.Name())
+	}
+}
@@ -132,6 +132,26 @@ func TestSmoke_HistoricalSectorBeta(t *testing.T) {
 	}
 }
 
+// :add AAPL.marketLoading — the market leg of the rolling multi-factor
+// regression. Exercises the six-ETF fetch and the alignment end to end.
+func TestSmoke_HistoricalFactorLoading(t *testing.T) {
+	resp := get(t, "/api/historical/financial/AAPL.marketLoading")
+	defer resp.Body.Close()
+	assertStatus(t, resp, 200)
+	var rows []map[string]any
+	json.NewDecoder(resp.Body).Decode(&rows)
+	if len(rows) == 0 {
+		t.Fatal("expected marketLoading data points")
+	}
+	first, ok := rows[0]["value"].(float64)
+	if !ok {
+		t.Fatalf("expected numeric marketLoading value, got %T", rows[0]["value"])
+	}
+	if first < 0 || first > 2.5 {
+		t.Errorf("marketLoading outside expected range [0, 2.5]: %f", first)
+	}
+}
+
 func trim(b []byte) string {
 	s := string(b)
 	if len(s) > 200 {
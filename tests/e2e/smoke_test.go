@@ -5,17 +5,20 @@ package e2e
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/coder/websocket"
 
+	"stocktopus/internal/cassette"
 	"stocktopus/internal/hub"
 	"stocktopus/internal/news"
 	"stocktopus/internal/newspoller"
@@ -30,10 +33,45 @@ var (
 	apiKey     string
 )
 
+// cassetteDir holds the committed HTTP fixtures. Record them with
+// `make smoke-record`; without STOCK_API_KEY the suite replays them.
+const cassetteDir = "testdata/cassettes"
+
+// haveCassettes reports whether any fixtures are committed.
+func haveCassettes() bool {
+	entries, err := os.ReadDir(cassetteDir)
+	return err == nil && len(entries) > 0
+}
+
 func TestMain(m *testing.M) {
 	apiKey = os.Getenv("STOCK_API_KEY")
-	if apiKey == "" {
-		os.Exit(0)
+	mode, err := cassette.ParseMode(os.Getenv("CASSETTE_MODE"))
+	if err != nil {
+		panic(err.Error())
+	}
+	if os.Getenv("CASSETTE_MODE") == "" && apiKey == "" {
+		if !haveCassettes() {
+			os.Exit(0) // no key and no fixtures: nothing to run against
+		}
+		mode = cassette.Replay
+	}
+	// An explicit replay (CI) must not pass vacuously when the fixtures
+	// were never recorded or committed.
+	if mode == cassette.Replay && !haveCassettes() {
+		fmt.Fprintf(os.Stderr, "e2e: CASSETTE_MODE=replay but no cassettes under %s; record them with `make smoke-record`\n", cassetteDir)
+		os.Exit(1)
+	}
+	if mode == cassette.Replay && apiKey == "" {
+		apiKey = cassette.Redacted
+	}
+	dir, _ := filepath.Abs(cassetteDir)
+	if err := cassette.Default.Configure(cassette.Config{
+		Mode: mode,
+		Dir:  dir,
+		// News and chart windows are derived from the wall clock.
+		IgnoreParams: []string{"from", "to"},
+	}); err != nil {
+		panic(err.Error())
 	}
 
 	// Ensure CWD is project root so agents/ scripts are found